@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// notifier показывает уведомление средствами системы: notify-send (libnotify) в Linux и BSD,
+// osascript в macOS. Без них -- строка в stdout, чтобы уведомления не терялись молча.
+type notifier struct {
+	command string // "" -- печатать в stdout
+}
+
+func newNotifier(printOnly bool) *notifier {
+	if printOnly {
+		return &notifier{}
+	}
+	name := "notify-send"
+	if runtime.GOOS == "darwin" {
+		name = "osascript"
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		log.Printf("%s not found, notifications go to stdout", name)
+		return &notifier{}
+	}
+	return &notifier{command: name}
+}
+
+// Notify показывает уведомление. Ошибка показа -- в лог и в stdout: агент продолжает работать.
+func (n *notifier) Notify(title, body string) {
+	var cmd *exec.Cmd
+	switch n.command {
+	case "notify-send":
+		cmd = exec.Command("notify-send", "--app-name=task-manager", title, body)
+	case "osascript":
+		// strconv.Quote экранирует кавычки и переводы строк так, как их понимает AppleScript.
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	}
+	if cmd != nil {
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			return
+		}
+		log.Printf("%s: %v %s", n.command, err, out)
+	}
+	fmt.Printf("%s  %s: %s\n", time.Now().Format(time.TimeOnly), title, body)
+}
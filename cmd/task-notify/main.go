@@ -0,0 +1,255 @@
+// task-notify -- агент уведомлений на рабочем столе: слушает ленту изменений задач
+// (GET /api/v1/changes) и показывает системные уведомления (libnotify в Linux, Центр уведомлений
+// в macOS), когда задачу назначили на вас и когда подходит её срок.
+//
+// Адрес сервера и токен -- как у taskctl:
+//
+//	TASKCTL_URL   -- базовый адрес сервера (по умолчанию http://localhost:8080)
+//	TASKCTL_TOKEN -- JWT, полученный через `taskctl login`
+//
+// Пример:
+//
+//	export TASKCTL_TOKEN=$(taskctl login -u Папа -p secret)
+//	task-notify -before 30m
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"task-manager/pkg/client"
+)
+
+func main() {
+	before := flag.Duration("before", 15*time.Minute, "за сколько до срока напоминать о задаче")
+	printOnly := flag.Bool("print", false, "печатать уведомления в stdout вместо системных")
+	flag.Parse()
+
+	base := os.Getenv("TASKCTL_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	token := os.Getenv("TASKCTL_TOKEN")
+	userID, err := userIDFromToken(token)
+	if err != nil {
+		log.Fatalf("TASKCTL_TOKEN: %v (get one with `taskctl login`)", err)
+	}
+	c, err := client.New(base, client.WithToken(token), client.WithUserAgent("task-notify"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	n := newNotifier(*printOnly)
+	a := &agent{c: c, me: userID, before: *before, notify: n.Notify}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := a.run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
+
+// taskState -- то, что агент помнит о задаче между изменениями.
+type taskState struct {
+	Title      string
+	AssignedTo int
+	Done       bool
+	Due        *time.Time
+	remindedAt *time.Time // срок, о котором уже напомнили; срок поменялся -- напомним снова
+}
+
+type agent struct {
+	c      *client.Client
+	me     int
+	before time.Duration
+	notify func(title, body string)
+
+	tasks  map[int]*taskState
+	cursor string
+}
+
+// run читает ленту изменений, пока не отменят ctx. Между ответами ленты (не реже раза в 25s)
+// проверяет сроки. Сеть и ошибки сервера -- повтор с паузой; устаревший курсор -- перечитать задачи.
+func (a *agent) run(ctx context.Context) error {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		if a.tasks == nil {
+			if err := a.load(ctx); err != nil {
+				if fatal(err) {
+					return err
+				}
+				log.Printf("load tasks: %v; retrying in %s", err, backoff)
+				sleep(ctx, backoff)
+				backoff = min(backoff*2, time.Minute)
+				continue
+			}
+		}
+
+		b, err := a.c.Changes(ctx, a.cursor, 25*time.Second)
+		switch {
+		case client.IsCursorExpired(err):
+			log.Printf("changes cursor expired, reloading tasks")
+			a.tasks = nil
+			continue
+		case err != nil:
+			if fatal(err) {
+				return err
+			}
+			if ctx.Err() == nil {
+				log.Printf("changes: %v; retrying in %s", err, backoff)
+				sleep(ctx, backoff)
+				backoff = min(backoff*2, time.Minute)
+			}
+			continue
+		}
+		backoff = time.Second
+		for _, ch := range b.Changes {
+			a.apply(ch)
+		}
+		a.cursor = b.Cursor
+		a.checkDue(time.Now())
+	}
+	return nil
+}
+
+// load перечитывает задачи и берёт курсор "с этого момента". Просроченные к запуску задачи
+// не сыплются уведомлениями по одной -- одно общее.
+func (a *agent) load(ctx context.Context) error {
+	b, err := a.c.Changes(ctx, "", 0)
+	if err != nil {
+		return err
+	}
+	list, err := a.c.ListTasks(ctx, nil)
+	if err != nil {
+		return err
+	}
+	a.tasks = make(map[int]*taskState, len(list))
+	a.cursor = b.Cursor
+
+	now := time.Now()
+	overdue := 0
+	for _, t := range list {
+		st := newTaskState(t)
+		if a.mine(st) && st.Due != nil && st.Due.Before(now) {
+			st.remindedAt = st.Due
+			overdue++
+		}
+		a.tasks[t.ID] = st
+	}
+	if overdue > 0 {
+		a.notify("Просроченные задачи", fmt.Sprintf("Просрочено задач: %d", overdue))
+	}
+	log.Printf("watching %d tasks of user %d", len(list), a.me)
+	return nil
+}
+
+// apply учитывает изменение: назначение на меня -- уведомление сразу.
+func (a *agent) apply(ch client.Change) {
+	prev := a.tasks[ch.TaskID]
+	if ch.Type == "deleted" || ch.Task == nil {
+		delete(a.tasks, ch.TaskID)
+		return
+	}
+	// Лента общая для всех пользователей: чужие задачи не запоминаем.
+	if ch.Task.AssignedTo != a.me && ch.Task.UserID != a.me {
+		delete(a.tasks, ch.TaskID)
+		return
+	}
+	st := newTaskState(*ch.Task)
+	if prev != nil && timeEqual(prev.Due, st.Due) {
+		st.remindedAt = prev.remindedAt
+	}
+	a.tasks[ch.TaskID] = st
+
+	wasMine := prev != nil && prev.AssignedTo == a.me
+	if st.AssignedTo == a.me && !wasMine && ch.Task.UserID != a.me && !st.Done {
+		body := st.Title
+		if st.Due != nil {
+			body += "\nСрок: " + st.Due.Local().Format("02.01 15:04")
+			// Срок уже близко -- он есть в этом уведомлении, отдельное напоминание не нужно.
+			if time.Until(*st.Due) <= a.before {
+				st.remindedAt = st.Due
+			}
+		}
+		a.notify(fmt.Sprintf("Вам назначена задача #%d", ch.TaskID), body)
+	}
+}
+
+// checkDue напоминает о моих невыполненных задачах, до срока которых осталось меньше before.
+func (a *agent) checkDue(now time.Time) {
+	for id, st := range a.tasks {
+		if !a.mine(st) || st.Due == nil || st.remindedAt != nil || st.Due.Sub(now) > a.before {
+			continue
+		}
+		st.remindedAt = st.Due
+		title := fmt.Sprintf("Срок задачи #%d", id)
+		if st.Due.Before(now) {
+			title = fmt.Sprintf("Задача #%d просрочена", id)
+		}
+		a.notify(title, st.Title+"\nСрок: "+st.Due.Local().Format("02.01 15:04"))
+	}
+}
+
+func (a *agent) mine(st *taskState) bool {
+	return st.AssignedTo == a.me && !st.Done
+}
+
+func newTaskState(t client.Task) *taskState {
+	return &taskState{Title: t.Title, AssignedTo: t.AssignedTo, Done: t.Done, Due: t.Due}
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// fatal -- ошибка, которую повтор не исправит: токен истёк или недействителен.
+func fatal(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// userIDFromToken достаёт user_id из JWT. Подпись не проверяется -- это проверит сервер;
+// агенту ID нужен только чтобы отличать свои задачи.
+func userIDFromToken(token string) (int, error) {
+	if token == "" {
+		return 0, errors.New("not set")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, errors.New("not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("decode JWT payload: %w", err)
+	}
+	var claims struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID <= 0 {
+		return 0, errors.New("JWT has no user_id")
+	}
+	return claims.UserID, nil
+}
@@ -1,24 +1,54 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/netip"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
+	"task-manager/internal/admin"
+	"task-manager/internal/authz"
+	"task-manager/internal/caldav"
 	// Импорт пакета config: Подключаем наш новый модуль настроек
 	"task-manager/internal/config"
+	"task-manager/internal/daemon"
+	"task-manager/internal/demo"
+	"task-manager/internal/health"
+	"task-manager/internal/httpclient"
+	"task-manager/internal/importers"
+	"task-manager/internal/ldap"
+	"task-manager/internal/logging"
+	"task-manager/internal/metrics"
 	"task-manager/internal/middleware" // Подключаем наш пакет middleware
+	"task-manager/internal/migrate"
+	"task-manager/internal/pdf"
+	"task-manager/internal/restart"
+	"task-manager/internal/scim"
+	"task-manager/internal/script"
+	"task-manager/internal/secrets"
 	"task-manager/internal/tasks"
+	"task-manager/migrations"
+	"task-manager/web"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware" // Алиас, чтобы не конфликтовать с internal/middleware
@@ -29,28 +59,148 @@ import (
 // - настройка middleware;
 // - запуск HTTP-сервера.
 func main() {
+	// --selftest: прогнать самопроверку, напечатать отчёт (JSON) и выйти с кодом 1 при ошибке -- для CI/CD.
+	selfTestOnly := flag.Bool("selftest", false, "run startup self-test, print report and exit (non-zero on failure)")
+	// --demo: хранилище в памяти со сгенерированными задачами, при перезапуске всё сбрасывается.
+	demoMode := flag.Bool("demo", false, "start with generated demo data in memory (reset on restart)")
+	// --config: настройки KEY=VALUE из файла или stdin ("-") -- секреты без переменных окружения контейнера.
+	configPath := flag.String("config", "", "read KEY=VALUE settings from file (- for stdin); they override the environment")
+	// --healthcheck: запросить /healthz своего сервера и выйти (0 -- жив) -- HEALTHCHECK в образе без curl.
+	healthcheck := flag.Bool("healthcheck", false, "probe /healthz of the running server and exit (non-zero if unhealthy)")
+	// --extract-assets: выложить встроенные файлы (интерфейс, миграции, шаблоны, пример настроек) и выйти.
+	extractDir := flag.String("extract-assets", "", "write embedded web UI, migrations, templates and example config to `dir` and exit")
+	// --service install|uninstall: зарегистрировать сервер службой Windows (с текущими флагами) или удалить её.
+	serviceCmd := flag.String("service", "", "install or uninstall the Windows service (other flags become its arguments) and exit")
+	serviceName := flag.String("service-name", daemon.DefaultName, "Windows service name for --service")
+	flag.Parse()
+	started := time.Now()
+
+	if *extractDir != "" {
+		if err := extractAssets(*extractDir); err != nil {
+			log.Fatalf("--extract-assets: %v", err)
+		}
+		return
+	}
+
+	switch *serviceCmd {
+	case "":
+	case "install":
+		args, err := serviceArgs()
+		if err == nil {
+			err = daemon.Install(*serviceName, args)
+		}
+		if err != nil {
+			log.Fatalf("--service install: %v", err)
+		}
+		fmt.Printf("service %s installed; start it with: sc start %s\n", *serviceName, *serviceName)
+		return
+	case "uninstall":
+		if err := daemon.Uninstall(*serviceName); err != nil {
+			log.Fatalf("--service uninstall: %v", err)
+		}
+		fmt.Printf("service %s removed\n", *serviceName)
+		return
+	default:
+		log.Fatalf("--service: unknown command %q (want install or uninstall)", *serviceCmd)
+	}
+
+	// Запущены SCM как служба Windows -- докладываем ему о запуске и остановке (до конфига:
+	// рабочий каталог меняется на каталог бинарника).
+	if _, err := daemon.RunService(*serviceName); err != nil {
+		log.Fatalf("windows service: %v", err)
+	}
+	defer daemon.Exit()
+
+	if *configPath != "" {
+		if err := loadConfigFile(*configPath); err != nil {
+			log.Fatalf("--config %s: %v", *configPath, err)
+		}
+	}
 	if err := godotenv.Load(); err != nil {
 		log.Println("Предупреждение: .env файл не найден, используются системные переменные")
 	}
 
 	// Инициализация конфига: Читаем переменные окружения при старте
 	cfg := config.Load()
-	log.Printf("!!! ТЕКУЩИЙ DSN ДЛЯ ПОДКЛЮЧЕНИЯ: %s", cfg.DSN())
+
+	if *healthcheck {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := health.Probe(ctx, "http://127.0.0.1:"+cfg.Port+"/healthz"); err != nil {
+			fmt.Fprintln(os.Stderr, "unhealthy:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Куда пишутся логи приложения (LOG_OUTPUT): stderr, stdout, файл с ротацией или syslog
+	closeLog, err := logging.Setup(cfg.Logging())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer closeLog()
 
 	// Создаем основной контекст приложения.
 	// Его отмена должна "доезжать" до всех in-flight запросов
 	// через http.Server.BaseContext.
-	appCtx, appCancel := context.WithCancel(context.Background())
-	defer appCancel()
+	// Отменяется с причиной middleware.ErrShuttingDown -- по ней обработчики отличают остановку от ушедшего клиента.
+	appCtx, appCancel := context.WithCancelCause(context.Background())
+	defer appCancel(nil)
 
 	// Наш контекст, который отменяется при Ctrl+C / SIGTERM
 	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Исходящие запросы (webhook, интеграции, провайдер секретов): прокси и ограничения частоты.
+	if err := httpclient.SetProxy(cfg.OutboundProxy); err != nil {
+		log.Fatalf("OUTBOUND_PROXY: %v", err)
+	}
+	outboundLimits, err := cfg.OutboundRateLimitMap()
+	if err != nil {
+		log.Fatalf("OUTBOUND_RATE_LIMITS: %v", err)
+	}
+	httpclient.SetRateLimits(outboundLimits)
+	if err := httpclient.SetPrivateAllowlist(cfg.OutboundAllowPrivate); err != nil {
+		log.Fatalf("OUTBOUND_ALLOW_PRIVATE: %v", err)
+	}
+
+	// Секреты (ключ JWT, ключ админки, инвайт-код, пароль БД, ключи интеграций) -- из SECRETS_PROVIDER.
+	// Пароль БД и ключи интеграций читаются один раз, остальное перечитывается на лету.
+	secretProvider, err := secrets.New(cfg.Secrets())
+	if err != nil {
+		log.Fatalf("Ошибка настройки провайдера секретов: %v", err)
+	}
+	creds, err := loadCredentials(appCtx, cfg, secretProvider)
+	if err != nil {
+		log.Fatalf("Ошибка чтения секретов (%s): %v", secretProvider.Name(), err)
+	}
+	log.Printf("Секреты: провайдер %s", secretProvider.Name())
+
 	// Объявляем переменную для интерфейса
 	var repo tasks.TaskRepository
+	var failover *tasks.FailoverStore // резервирование PostgreSQL (FAILOVER_REPLICA_PATH)
 
-	if cfg.StoragePath == "postgres" {
+	if *demoMode {
+		store := tasks.NewMemoryStore()
+		seed := uint64(cfg.DemoSeed)
+		if seed == 0 {
+			seed = uint64(time.Now().UnixNano())
+		}
+		if err := demo.Seed(appCtx, store, demo.Options{Seed: seed, Tasks: cfg.DemoTasks}); err != nil {
+			log.Fatalf("Ошибка генерации демо-данных: %v", err)
+		}
+		// Демо должно запускаться без настройки: секрет для токенов генерируем сами.
+		if creds.jwt.Current() == "" {
+			creds.jwt = secrets.Static("JWT_SECRET", rand.Text())
+			cfg.JWTSecret = creds.jwt.Current()
+		}
+		cfg.StoragePath, cfg.StorageGit = "memory", false
+		// Сбои хранилища для проверки устойчивости (скрытый /api/v1/admin/faults); без правил их нет.
+		repo = tasks.NewFaultStore(store)
+		log.Printf("ДЕМО-РЕЖИМ: данные в памяти и сбросятся при перезапуске (задач: %d, seed: %d)", cfg.DemoTasks, seed)
+		log.Printf("Демо-пользователи: %s, пароль: %s", strings.Join(demo.Usernames(), ", "), demo.Password)
+	} else if cfg.StoragePath == "postgres" {
+		log.Printf("Подключение к PostgreSQL: %s", cfg.RedactedDSN())
 		db, err := sql.Open("postgres", cfg.DSN())
 		if err != nil {
 			log.Fatalf("Ошибка подключения к БД: %v", err)
@@ -69,27 +219,434 @@ func main() {
 			time.Sleep(2 * time.Second) // Ждем 2 секунды перед следующей попыткой
 		}
 
-		if pingErr != nil {
+		if pingErr != nil && cfg.FailoverReplicaPath == "" {
 			log.Fatalf("БД так и не ответила после 5 попыток: %v", pingErr)
 		}
 
+		// Встроенные миграции: бинарнику не нужны файлы migrations/ рядом.
+		if cfg.DBMigrate && pingErr == nil {
+			applied, err := migrate.Up(appCtx, db, assetFS(cfg, "migrations", migrations.FS))
+			if err != nil {
+				log.Fatalf("Ошибка миграции БД: %v", err)
+			}
+			for _, m := range applied {
+				log.Printf("Миграция применена: %s", m.Name)
+			}
+		}
+
 		repo = tasks.NewPostgresRepository(db)
 		log.Println("Приложение запущено с хранилищем PostgreSQL")
+
+		if cfg.FailoverReplicaPath != "" {
+			// Резервирование: при аварии БД читаем из локальной реплики, изменения -- 503.
+			failover, err = tasks.NewFailoverStore(repo, cfg.FailoverReplicaPath, db.PingContext, cfg.FailoverSyncInterval)
+			if err != nil {
+				log.Fatalf("Ошибка чтения реплики %s: %v", cfg.FailoverReplicaPath, err)
+			}
+			failover.Check(appCtx)
+			go failover.Run(appCtx, cfg.FailoverCheckInterval)
+			repo = failover
+			log.Printf("Резервирование хранилища: реплика %s (проверка БД каждые %s, снимок каждые %s)",
+				cfg.FailoverReplicaPath, cfg.FailoverCheckInterval, cfg.FailoverSyncInterval)
+		}
+	} else if cfg.StorageGit {
+		// Файловый стор с историей: каждая мутация -- отдельный git-коммит
+		gitRepo, err := tasks.NewGitStore(cfg.StoragePath)
+		if err != nil {
+			log.Fatalf("Ошибка инициализации git-хранилища: %v", err)
+		}
+		gitRepo.SetEncoding(tasks.StorageEncoding(cfg.StorageEncoding))
+		repo = gitRepo
+		log.Printf("Приложение запущено с хранилищем JSON + git-история: %s (формат %s)", cfg.StoragePath, cfg.StorageEncoding)
 	} else {
 		// Если в конфиге указан путь к файлу, запускаем старый файловый стор
-		repo = tasks.NewTaskStore(cfg.StoragePath)
-		log.Println("Приложение запущено с хранилищем JSON:", cfg.StoragePath)
+		store := tasks.NewTaskStore(cfg.StoragePath)
+		store.SetEncoding(tasks.StorageEncoding(cfg.StorageEncoding))
+		repo = store
+		log.Printf("Приложение запущено с хранилищем JSON: %s (формат %s)", cfg.StoragePath, cfg.StorageEncoding)
 	}
 
 	// Передаем выбранный репозиторий в сервис
 	svc := tasks.NewService(repo)
+	svc.SetInviteCode(creds.inviteCode)
+	middleware.SetJWTSecret(creds.jwt)
+	go secrets.Watch(appCtx, cfg.SecretsRefreshInterval, creds.jwt, creds.adminKey, creds.inviteCode, creds.scimToken)
+
+	fieldDefs, err := tasks.ParseFieldDefs(cfg.CustomFields)
+	if err != nil {
+		log.Fatalf("CUSTOM_FIELDS: %v", err)
+	}
+	svc.SetFieldDefs(fieldDefs)
+
+	// Ограничения входных данных (длина названия, описания, теги): валидатор собирается из них
+	limits := tasks.ValidationLimits{
+		TitleMax:       cfg.TitleMaxLength,
+		DescriptionMax: cfg.DescriptionMaxLength,
+		TagsMax:        cfg.TagsMax,
+		TagMax:         cfg.TagMaxLength,
+	}
+	if err := svc.SetValidationLimits(limits); err != nil {
+		log.Fatalf("TITLE_MAX_LENGTH: %v", err)
+	}
+
+	// Шкала приоритетов: сохранённая через API, а если её нет -- из TASK_PRIORITIES.
+	// Задачи с приоритетами вне шкалы переводятся в её уровни.
+	priorityScale, err := tasks.ParsePriorityScale(cfg.TaskPriorities)
+	if err != nil {
+		log.Fatalf("TASK_PRIORITIES: %v", err)
+	}
+	if err := svc.LoadPriorityScale(appCtx, priorityScale); err != nil {
+		log.Fatalf("Шкала приоритетов: %v", err)
+	}
+	// Процесс (статусы и переходы), сохранённый через API; без него -- todo, in_progress, done.
+	if err := svc.LoadWorkflow(appCtx); err != nil {
+		log.Fatalf("Процесс статусов: %v", err)
+	}
+	if err := svc.LoadProjectACLs(appCtx); err != nil {
+		log.Fatalf("Закрытые проекты: %v", err)
+	}
+
+	// Сжатие JSON-хранилища при запуске: формат файла задач и мёртвые надгробия слитых задач
+	if cfg.StorageCompactOnStart {
+		res, err := svc.CompactStorage(appCtx)
+		switch {
+		case errors.Is(err, tasks.ErrCompactUnsupported):
+			log.Println("STORAGE_COMPACT_ON_START: хранилище не поддерживает сжатие, пропускаем")
+		case err != nil:
+			log.Fatalf("Ошибка сжатия хранилища: %v", err)
+		default:
+			log.Printf("Хранилище сжато: %d -> %d байт (задач %d, убрано надгробий %d)",
+				res.BeforeBytes, res.AfterBytes, res.Tasks, res.RedirectsRemoved)
+		}
+	}
+
+	// Самопроверка: хранилище, конфиг, часы, порт. Результат -- в лог и на /readyz.
+	readiness := health.NewReadiness()
+	report := health.Run(appCtx, selfTestChecks(cfg, svc)...)
+	if *selfTestOnly {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+		// Самопроверка сняла отметку чистой остановки -- возвращаем её, данные она не меняла.
+		if err := svc.Close(appCtx); err != nil {
+			log.Printf("store close error: %v", err)
+		}
+		if !report.OK {
+			os.Exit(1)
+		}
+		return
+	}
+	report.Log()
+	readiness.SetReport(report)
 
 	// Инициализируем HTTP-обработчики задач.
+	// Источники учётных записей (AUTH_PROVIDERS): свои пользователи и/или каталог LDAP
+	providers, err := newIdentityProviders(cfg)
+	if err != nil {
+		log.Fatalf("AUTH_PROVIDERS: %v", err)
+	}
+	if len(providers) > 1 || providers[0] != tasks.LocalUsers {
+		if !svc.SupportsProvisioning() {
+			log.Fatalf("AUTH_PROVIDERS: хранилище %q не хранит пользователей каталога, нужен PostgreSQL", cfg.StoragePath)
+		}
+		svc.SetIdentityProviders(providers...)
+		log.Printf("Вход: %s", strings.Join(cfg.AuthProviderList(), ", "))
+	}
+
+	// Пароли локальных пользователей: политика для новых паролей, argon2id, сброс по почте
+	svc.SetPasswordPolicy(tasks.PasswordPolicy{
+		MinLength:    cfg.PasswordMinLength,
+		MaxLength:    cfg.PasswordMaxLength,
+		MinClasses:   cfg.PasswordMinClasses,
+		RejectCommon: cfg.PasswordRejectCommon,
+	})
+	if err := svc.SetPasswordHashing(tasks.Argon2Params{
+		Memory:      uint32(cfg.PasswordHashMemory),
+		Iterations:  uint32(cfg.PasswordHashIterations),
+		Parallelism: uint8(min(cfg.PasswordHashParallelism, 255)),
+	}); err != nil {
+		log.Fatalf("PASSWORD_HASH_*: %v", err)
+	}
+	svc.SetPasswordReset(cfg.PasswordResetTTL, cfg.PasswordResetURL)
+
+	// Сессии: каждый вход -- отзываемая запись с IP и User-Agent (нет в файловом хранилище)
+	if svc.SupportsSessions() {
+		middleware.SetSessionCheck(svc.SessionActive)
+	}
+
 	handler := tasks.NewHandler(svc)
 
+	// Ключи для no-code интеграций (Zapier/IFTTT)
+	handler.SetAPIKeys(middleware.ParseAPIKeys(cfg.IntegrationAPIKeys))
+	handler.SetPriorityFormat(cfg.PriorityNumeric)
+	workspaceAdmins, err := cfg.WorkspaceAdminIDs()
+	if err != nil {
+		log.Fatalf("WORKSPACE_ADMINS: %v", err)
+	}
+	handler.SetWorkspace(cfg.AuthzWorkspace, workspaceAdmins)
+
+	// Анонимный доступ на чтение (табло-киоск): остальное API по-прежнему требует токен
+	anonymousRoutes, err := middleware.ParseAnonymousRoutes(cfg.AnonymousRoutes)
+	if err != nil {
+		log.Fatalf("AUTH_ANONYMOUS_ROUTES: %v", err)
+	}
+	if len(anonymousRoutes) > 0 && cfg.AnonymousUserID > 0 {
+		handler.SetAnonymousAccess(cfg.AnonymousUserID, anonymousRoutes)
+		log.Printf("Анонимный доступ на чтение от имени пользователя %d: %s", cfg.AnonymousUserID, cfg.AnonymousRoutes)
+	}
+	handler.SetPublicCache(cfg.PublicCacheTTL)
+
+	// Внешний движок политик (AUTHZ_ENGINE): решает, можно ли пользователю запрос, после аутентификации
+	if cfg.AuthzEngine != "" {
+		authorizer, err := newAuthorizer(cfg)
+		if err != nil {
+			log.Fatalf("AUTHZ_ENGINE: %v", err)
+		}
+		handler.SetAuthorization(authorizer.Middleware)
+		log.Printf("Авторизация: движок политик %s, пространство %q", authorizer.Name(), cfg.AuthzWorkspace)
+	}
+
+	// Блокировка перебора паролей: общая для JWT-логина и Basic-авторизации CalDAV
+	authGuard := middleware.NewAuthGuard(middleware.AuthGuardConfig{
+		MaxFailures:   cfg.AuthMaxFailures,
+		MaxFailuresIP: cfg.AuthMaxFailuresIP,
+		BaseCooldown:  cfg.AuthLockoutBase,
+		MaxCooldown:   cfg.AuthLockoutMax,
+	})
+	handler.SetAuthGuard(authGuard)
+	handler.SetChangesMaxWait(cfg.ChangesMaxWait)
+
+	// Коннекторы импорта из внешних систем. Без ключей API коннектор вернёт понятную ошибку.
+	statusMap := importers.ParseStatusMap(cfg.ImportStatusMap)
+	handler.RegisterImporter("trello", importers.NewTrello(cfg.TrelloAPIKey, cfg.TrelloToken, statusMap))
+	handler.RegisterImporter("jira", importers.NewJira(cfg.JiraBaseURL, cfg.JiraEmail, cfg.JiraAPIToken, statusMap))
+
+	// Почта для отчётов по расписанию
+	if cfg.SMTPAddr != "" {
+		svc.SetMailer(&tasks.SMTPMailer{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword})
+	}
+	// Ссылки действий в письмах ("выполнено", "отложить") и уведомления письмом
+	svc.SetActionLinks(cfg.PublicURL, cfg.ActionLinkTTL)
+	// Демо-режим: письма и webhook не уходят наружу, а видны в GET /api/v1/demo/outbox.
+	if *demoMode {
+		outbox := tasks.NewOutbox()
+		svc.SetMailer(outbox)
+		httpclient.SetSink(outbox, "automation", "report", "quota")
+		handler.SetOutbox(outbox)
+		cfg.NotifyEmail = true
+		log.Println("ДЕМО-РЕЖИМ: письма и webhook перехватываются, смотрите GET /api/v1/demo/outbox")
+	}
+	if cfg.NotifyEmail {
+		if cfg.SMTPAddr == "" && !*demoMode {
+			log.Fatal("NOTIFY_EMAIL: нужен SMTP_ADDR")
+		}
+		svc.SetNotifier(svc.MailNotifier())
+		if cfg.PublicURL == "" {
+			log.Println("NOTIFY_EMAIL: PUBLIC_URL не задан, письма уйдут без ссылок действий")
+		}
+	}
+
+	// Скрипты на событиях задач
+	svc.SetScriptLimits(script.Limits{MaxSteps: cfg.ScriptMaxSteps, MaxMemory: cfg.ScriptMaxMemory, Timeout: cfg.ScriptTimeout})
+
+	// Стратегия конфликтов синхронизации по умолчанию
+	svc.SetSyncStrategy(cfg.SyncConflictStrategy)
+
+	// Шрифт PDF-распечаток: без него кириллица уйдёт транслитом
+	if cfg.PDFFont != "" {
+		font, err := pdf.LoadFont(cfg.PDFFont)
+		if err != nil {
+			log.Printf("PDF_FONT %s: %v; PDF will use Helvetica with transliteration", cfg.PDFFont, err)
+		}
+		handler.SetPDFFont(font)
+	} else if font, path, err := pdf.FindSystemFont(); err == nil {
+		log.Printf("PDF font: %s", path)
+		handler.SetPDFFont(font)
+	} else {
+		log.Printf("PDF font not found (set PDF_FONT): PDF will use Helvetica with transliteration")
+	}
+
 	// Собираем роутер.
 	// Роуты переехали в internal/tasks (HTTP-слой), main только подключает.
-	r := chiWithMiddleware(handler.Router())
+	// Сначала middleware (chi требует объявлять их до маршрутов), затем маршруты.
+	mux := chi.NewRouter()
+	if cfg.MetricsEnabled {
+		// Метрики: счётчики HTTP + SLO на каждый запрос, gauge по задачам -- фоновым рефрешером
+		slo := metrics.NewSLO(cfg.SLOTarget, cfg.SLOLatency)
+		mux.Use(middleware.MetricsMiddleware(slo))
+		go svc.RunMetricsRefresher(appCtx, cfg.MetricsRefreshInterval, slo)
+		log.Printf("Метрики включены: /metrics (SLO %.3f, порог латентности %s)", cfg.SLOTarget, cfg.SLOLatency)
+	}
+
+	// Форма JSON-ответов API (snake/camel, конверт) -- глобально и по заголовкам запроса.
+	mux.Use(middleware.ResponseShape{Naming: cfg.ResponseNaming, Envelope: cfg.ResponseEnvelope}.Middleware)
+
+	// Во время остановки новые запросы получают 503 + Connection: close, принятые доделываются.
+	drain := middleware.NewDrain()
+	mux.Use(drain.Middleware)
+
+	// Нагрузка на публичный порт (/api/v1/admin/stats) и ограничения MAX_CONNECTIONS, MAX_CONCURRENT_REQUESTS.
+	// Служебный API, проверки живости и долгие ожидания ленты изменений под ограничение запросов не попадают.
+	serverLimits := middleware.NewServerLimits(cfg.MaxConnections, cfg.MaxConcurrentRequests)
+	serverLimits.AddSubscribers("changes", svc.ChangeWaiters)
+	mux.Use(serverLimits.Middleware("/api/v1/admin/", "/api/v1/changes", "/metrics", "/healthz", "/readyz"))
+
+	// Режим обслуживания: изменения отклоняются (503), чтение работает.
+	// Админский API и вход в систему не блокируются -- иначе режим не выключить и не почитать задачи.
+	maintenance := middleware.NewMaintenance()
+	if cfg.MaintenanceMode {
+		maintenance.Set(true, "", 0)
+		log.Println("Сервер запущен в режиме обслуживания (MAINTENANCE_MODE)")
+	}
+	mux.Use(maintenance.Middleware("/api/v1/admin/", "/api/v1/auth/login"))
+	readiness.AddInfo("maintenance", func() any { return maintenance.State() })
+
+	// Режим только для чтения (зеркало, демо): изменения отклоняются с 405, исключения -- как у обслуживания.
+	readOnly := middleware.NewReadOnly()
+	if cfg.ReadOnly {
+		readOnly.Set(true, "")
+		log.Println("Сервер запущен в режиме только для чтения (READ_ONLY)")
+	}
+	mux.Use(readOnly.Middleware("/api/v1/admin/", "/api/v1/auth/login"))
+	readiness.AddInfo("read_only", func() any { return readOnly.State() })
+
+	// Диск перестал принимать запись (место, права, read-only ФС): сразу в режим только для чтения.
+	// Подсказка, что чинить, -- в лог, /readyz и уведомление; клиентам -- без путей на сервере.
+	// Выключается админом (/api/v1/admin/read-only), когда диск починен.
+	alertUsers, _ := cfg.StorageAlertUserIDs() // формат уже проверен самопроверкой
+	svc.SetStorageAlert(alertUsers, func(se tasks.StorageError) {
+		readOnly.Set(true, fmt.Sprintf("Storage does not accept changes (%s), administrators have been notified", se.Kind))
+	})
+
+	// Правила эскалации, расписания и уборщик: не трогаем задачи, пока изменения запрещены.
+	writesPaused := func() bool {
+		return readOnly.State().Enabled || maintenance.State().Enabled || svc.StorageDegraded() != ""
+	}
+	if cfg.RulesInterval > 0 {
+		go svc.RunRules(appCtx, cfg.RulesInterval, writesPaused)
+	}
+	if cfg.SchedulesInterval > 0 {
+		go svc.RunSchedules(appCtx, cfg.SchedulesInterval, writesPaused)
+		go svc.RunReports(appCtx, cfg.SchedulesInterval, writesPaused)
+	}
+	if cfg.RetentionInterval > 0 {
+		go svc.RunRetention(appCtx, cfg.RetentionInterval, writesPaused)
+	}
+
+	// Мягкие квоты: только предупреждения, запись не запрещается
+	svc.SetUsageQuota(tasks.UsageQuota{
+		Tasks:        cfg.QuotaWarnTasks,
+		StorageBytes: int64(cfg.QuotaWarnStorageMB) << 20,
+		Webhook:      cfg.QuotaWebhookURL,
+	})
+	if cfg.UsageCheckInterval > 0 {
+		go svc.RunUsageWatch(appCtx, cfg.UsageCheckInterval)
+	}
+	readiness.AddDegradation("storage", svc.StorageDegraded)
+	if failover != nil {
+		readiness.AddInfo("storage", func() any { return failover.State() })
+	}
+
+	// Backpressure: не больше MUTATION_WORKERS мутаций параллельно, остальные -- в очередь или 503.
+	if cfg.MutationWorkers > 0 {
+		mux.Use(middleware.NewBackpressure(cfg.MutationWorkers, cfg.MutationQueue, cfg.MutationQueueWait).Middleware)
+	}
+
+	// Хаос (CHAOS_ENABLED): задержки и ошибки по правилам для демонстраций устойчивости.
+	// Админский API, метрики и /readyz не трогаются -- иначе не разобраться, что происходит.
+	if cfg.ChaosEnabled {
+		chaosRules, err := middleware.ParseChaosRules(cfg.ChaosRules)
+		if err != nil {
+			log.Fatalf("CHAOS_RULES: %v", err)
+		}
+		mux.Use(middleware.ChaosMiddleware(chaosRules, "/api/v1/admin/", "/metrics", "/readyz"))
+		log.Printf("ВНИМАНИЕ: включён хаос (CHAOS_ENABLED), правил: %d; запросы будут медленными и падать", len(chaosRules))
+	}
+
+	mux.Handle("/readyz", readiness)
+	mux.Handle("/healthz", health.Liveness(started))
+
+	// Служебные маршруты: метрики, профилирование и админский API. С ADMIN_ADDR -- на отдельном
+	// (внутреннем) порту, и публичный порт их не знает; без него -- на публичном, но без /debug.
+	mountOps := func(r chi.Router, debug bool) {
+		if cfg.MetricsEnabled {
+			r.Handle("/metrics", metrics.Handler())
+		}
+		if debug {
+			r.HandleFunc("/debug/pprof/*", pprof.Index)
+			r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+			r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+			r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+			r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		}
+		r.Route("/api/v1/admin", func(r chi.Router) {
+			r.Use(middleware.AdminKeyMiddleware(creds.adminKey))
+			r.Mount("/reports", handler.AdminReportsRouter())
+			r.Mount("/scripts", handler.AdminScriptsRouter())
+			r.Mount("/retention", handler.AdminRetentionRouter())
+			r.Mount("/usage", handler.AdminUsageRouter())
+			r.Mount("/storage", handler.AdminStorageRouter())
+			if *demoMode {
+				r.Mount("/faults", handler.AdminFaultsRouter())
+			}
+			r.Mount("/", admin.NewHandler(maintenance, readOnly, authGuard, serverLimits).Router())
+		})
+	}
+	var opsMux *chi.Mux
+	switch cfg.AdminAddr {
+	case "":
+		mountOps(mux, false)
+	case config.AdminAddrOff:
+		log.Println("Служебные маршруты выключены (ADMIN_ADDR=off): /metrics и /api/v1/admin недоступны")
+	default:
+		// Свой набор middleware: режимы обслуживания и только для чтения, backpressure и хаос
+		// служебный порт не касаются, а его запросы не портят метрики и SLO публичного API.
+		opsMux = chi.NewRouter()
+		opsMux.Use(middleware.ResponseShape{Naming: cfg.ResponseNaming, Envelope: cfg.ResponseEnvelope}.Middleware)
+		opsMux.Use(drain.Middleware)
+		opsMux.Handle("/readyz", readiness)
+		opsMux.Handle("/healthz", health.Liveness(started))
+		mountOps(opsMux, true)
+	}
+	if cfg.CalDAVEnabled {
+		// CalDAV живёт рядом с REST API и ходит в тот же сервис
+		dav := caldav.NewHandler(svc)
+		dav.SetAuthGuard(authGuard)
+		dav.Mount(mux)
+		log.Println("CalDAV включен: /caldav/")
+		handler.SetFeature("caldav", true)
+	}
+	if cfg.SCIMEnabled {
+		if !svc.SupportsProvisioning() {
+			log.Fatalf("SCIM_ENABLED: хранилище %q не умеет менять пользователей, нужен PostgreSQL", cfg.StoragePath)
+		}
+		if creds.scimToken.Current() == "" {
+			log.Fatal("SCIM_ENABLED: не задан SCIM_TOKEN")
+		}
+		scim.NewHandler(svc, creds.scimToken).Mount(mux)
+		// Заблокированный каталогом пользователь теряет доступ сразу, не дожидаясь конца токена.
+		middleware.SetUserCheck(svc.UserActive)
+		log.Println("SCIM включен: /scim/v2/")
+		handler.SetFeature("scim", true)
+	}
+	// Веб-интерфейс из бинарника (или из ASSETS_DIR/web) -- без отдельного nginx.
+	if cfg.UIEnabled {
+		mux.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+		mux.Handle("/ui/*", http.StripPrefix("/ui/", http.FileServerFS(assetFS(cfg, "web", web.FS))))
+	}
+	handler.SetFeature("ui", cfg.UIEnabled)
+	mux.Mount("/", handler.Router())
+
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	accessLog, closeAccessLog, err := openAccessLog(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка настройки журнала доступа: %v", err)
+	}
+	defer closeAccessLog()
+	r := chiWithMiddleware(mux, trustedProxies, accessLog)
 
 	// Запускаем сервер через http.Server (а не http.ListenAndServe),
 	// чтобы поддержать graceful shutdown + таймауты сервера.
@@ -101,7 +658,7 @@ func main() {
 		// Понятные таймауты сервера (без усложнений).
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       15 * time.Second,
-		WriteTimeout:      15 * time.Second,
+		WriteTimeout:      max(15*time.Second, cfg.ChangesMaxWait+5*time.Second, tasks.ImportTimeout+5*time.Second), // long polling в /api/v1/changes, импорт из Trello и Jira
 		IdleTimeout:       60 * time.Second,
 
 		// Корневой контекст для всех соединений/запросов.
@@ -110,76 +667,474 @@ func main() {
 			return appCtx
 		},
 	}
+	// Ждущие изменений (long polling) отпускаются сразу, а не держат остановку до конца ожидания.
+	srv.RegisterOnShutdown(svc.StopChanges)
 
-	ln, err := net.Listen("tcp", srv.Addr)
+	// Сокет либо унаследован от предыдущего процесса (перезапуск по SIGHUP), либо открывается заново.
+	ln, err := restart.Listen(srv.Addr, cfg.ReusePort)
 	if err != nil {
 		log.Fatalf("listen error: %v", err)
 	}
+	listeners := map[string]net.Listener{srv.Addr: ln}
 
 	// Логирование конфига: визуализируем настройки для удобства DevOps
 	log.Printf("Server running on port %s (Storage %s)", cfg.Port, cfg.StoragePath)
 
-	serverErrCh := make(chan error, 1)
-	go func() {
+	serverErrCh := make(chan error, 2)
+	serve := func(srv *http.Server, ln net.Listener) {
 		err := srv.Serve(ln)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErrCh <- err
 			return
 		}
 		serverErrCh <- nil
-	}()
+	}
+	go serve(srv, serverLimits.Listener(ln))
 
-	// Ждём либо сигнал, либо фатальную ошибку сервера.
-	select {
-	case <-sigCtx.Done():
-		log.Printf("shutdown signal received")
-	case err := <-serverErrCh:
+	var opsSrv *http.Server
+	if opsMux != nil {
+		opsSrv = &http.Server{
+			Addr:              cfg.AdminAddr,
+			Handler:           chiWithMiddleware(opsMux, trustedProxies, accessLog),
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      2 * time.Minute, // /debug/pprof/profile?seconds=60 пишет ответ после снятия профиля
+			IdleTimeout:       60 * time.Second,
+			BaseContext: func(net.Listener) context.Context {
+				return appCtx
+			},
+		}
+		opsLn, err := restart.Listen(opsSrv.Addr, cfg.ReusePort)
 		if err != nil {
-			log.Printf("server error: %v", err)
+			log.Fatalf("listen error (ADMIN_ADDR): %v", err)
 		}
-		// Если сервер неожиданно остановился без ошибки -- просто выходим.
-		if err == nil {
-			return
+		listeners[opsSrv.Addr] = opsLn
+		log.Printf("Служебный порт %s: /metrics, /debug/pprof, /api/v1/admin", opsSrv.Addr)
+		go serve(opsSrv, opsLn)
+	}
+
+	// Если нас запустил предыдущий процесс -- сообщаем, что готовы принять нагрузку.
+	restart.Ready()
+	// systemd (Type=notify) и SCM узнают, что сервер готов, только сейчас -- а не при старте процесса.
+	daemon.Ready(restart.Inherited())
+	daemon.Status("serving on port " + cfg.Port)
+
+	// SIGHUP: запустить новую версию бинарника с тем же сокетом и уйти на покой.
+	upgradeCh := make(chan os.Signal, 1)
+	if len(restart.UpgradeSignals) > 0 {
+		signal.Notify(upgradeCh, restart.UpgradeSignals...)
+	}
+
+	// Ждём либо сигнал, либо фатальную ошибку сервера.
+	stopIn := cfg.ShutdownDelay + cfg.ShutdownTimeout + cfg.ShutdownHardTimeout
+	upgraded := false
+wait:
+	for {
+		select {
+		case <-sigCtx.Done():
+			// Второй сигнал -- немедленный выход (обработка по умолчанию), если остановка затянулась.
+			stop()
+			log.Printf("shutdown signal received: stopping within %s (delay %s, drain %s, hard %s); send it again to exit immediately",
+				stopIn, cfg.ShutdownDelay, cfg.ShutdownTimeout, cfg.ShutdownHardTimeout)
+			daemon.Stopping(stopIn)
+			break wait
+		case <-daemon.StopRequested():
+			log.Printf("service stop requested: stopping within %s", stopIn)
+			daemon.Stopping(stopIn)
+			break wait
+		case <-upgradeCh:
+			log.Printf("upgrade signal received, starting new process")
+			daemon.Reloading()
+			if err := restart.Upgrade(listeners, restart.DefaultReadyTimeout); err != nil {
+				// Новая версия не поднялась -- продолжаем работать на старой.
+				log.Printf("upgrade failed: %v", err)
+				daemon.Ready(false)
+				continue
+			}
+			log.Printf("new process is ready, draining in-flight requests")
+			upgraded = true
+			break wait
+		case err := <-serverErrCh:
+			if err != nil {
+				log.Printf("server error: %v", err)
+			}
+			// Если сервер неожиданно остановился без ошибки -- просто выходим.
+			if err == nil {
+				return
+			}
+			break wait
 		}
 	}
 
-	// ВАЖНО: отменяем корневой контекст приложения.
-	// Это "протекает" сверху вниз: handler -> service -> store,
-	// и позволяет in-flight запросам корректно завершиться по ctx.Done().
-	appCancel()
+	// Graceful shutdown в два этапа.
+	// 1. Мягкий: новые запросы получают 503, принятые (в том числе записи) доделываются сами --
+	//    их контексты не отменяются, хранилище закрывается только после них.
+	// 2. Жёсткий: если за ShutdownTimeout не успели, отменяем контексты запросов (причина -- ErrShuttingDown,
+	//    клиенты получат 503 вместо оборванного ответа) и ждём ещё ShutdownHardTimeout, затем рвём соединения.
+	// Объявляем остановку: /readyz -- 503, и ещё ShutdownDelay обслуживаем запросы, пока
+	// балансировщик выводит экземпляр. При замене процессом по SIGHUP ждать некого.
+	readiness.SetDraining(true)
+	if !upgraded && cfg.ShutdownDelay > 0 {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+	drain.Start()
+	servers := []*http.Server{srv}
+	if opsSrv != nil {
+		servers = append(servers, opsSrv)
+	}
+	if err := shutdown(servers, cfg.ShutdownTimeout, cfg.ShutdownHardTimeout, appCancel); err != nil {
+		log.Printf("shutdown error: %v", err)
+	}
+
+	if upgraded {
+		// Хранилище не закрываем: отметка чистой остановки означала бы, что файл больше никто не меняет,
+		// а новый процесс уже работает с ним. Все принятые записи к этому моменту сохранены.
+		log.Printf("server stopped (replaced by new process)")
+		return
+	}
+
+	// Запросов больше нет -- закрываем хранилище и отмечаем чистую остановку.
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), cfg.ShutdownHardTimeout)
+	defer cancelClose()
+	if err := svc.Close(closeCtx); err != nil {
+		log.Printf("store close error: %v", err)
+	}
+	log.Printf("server stopped")
+}
+
+// shutdown останавливает серверы (API и служебный порт -- одновременно): сначала ждёт in-flight
+// запросы soft, затем отменяет корневой контекст и ждёт ещё hard. Корневой контекст в любом случае
+// отменяется (фоновые задачи тоже должны завершиться).
+func shutdown(srvs []*http.Server, soft, hard time.Duration, appCancel context.CancelCauseFunc) error {
+	defer appCancel(middleware.ErrShuttingDown)
 
-	// Graceful shutdown с таймаутом.
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	softCtx, cancel := context.WithTimeout(context.Background(), soft)
 	defer cancel()
+	err := shutdownAll(softCtx, srvs)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		closeAll(srvs)
+		return err
+	}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		// Если graceful не успел -- закрываем жёстко.
-		log.Printf("shutdown error: %v", err)
+	log.Printf("shutdown: in-flight requests not finished after %s, cancelling them", soft)
+	appCancel(middleware.ErrShuttingDown)
+
+	hardCtx, cancelHard := context.WithTimeout(context.Background(), hard)
+	defer cancelHard()
+	if err := shutdownAll(hardCtx, srvs); err != nil {
+		closeAll(srvs)
+		return err
+	}
+	return nil
+}
+
+// shutdownAll вызывает Shutdown у всех серверов параллельно. Уже остановленный сервер
+// возвращает nil сразу, поэтому повторный вызов ждёт только недоделавшие.
+func shutdownAll(ctx context.Context, srvs []*http.Server) error {
+	errs := make(chan error, len(srvs))
+	for _, srv := range srvs {
+		go func() { errs <- srv.Shutdown(ctx) }()
+	}
+	var all []error
+	for range srvs {
+		all = append(all, <-errs)
+	}
+	return errors.Join(all...)
+}
+
+func closeAll(srvs []*http.Server) {
+	for _, srv := range srvs {
 		_ = srv.Close()
 	}
+}
 
-	log.Printf("server stopped")
+// selfTestChecks собирает проверки самопроверки при старте.
+func selfTestChecks(cfg *config.Config, svc *tasks.Service) []health.Check {
+	// Эталон времени: явный URL, иначе часы СУБД (если хранилище умеет их сообщать).
+	clockRef := func(ctx context.Context) (time.Time, error) {
+		if reason := svc.StorageDegraded(); reason != "" {
+			return time.Time{}, health.Skip(reason)
+		}
+		t, err := svc.StoreTime(ctx)
+		if errors.Is(err, tasks.ErrClockUnavailable) {
+			return t, health.Skip("no reference clock (set SELFTEST_CLOCK_URL)")
+		}
+		return t, err
+	}
+	if cfg.SelfTestClockURL != "" {
+		clockRef = health.HTTPDate(cfg.SelfTestClockURL)
+	}
+
+	checks := []health.Check{
+		{Name: "config", Fn: func(context.Context) (string, error) { return "", cfg.Validate() }},
+		{Name: "store", Fn: func(ctx context.Context) (string, error) {
+			if reason := svc.StorageDegraded(); reason != "" {
+				return "", health.Skip(reason)
+			}
+			return fmt.Sprintf("storage=%s", cfg.StoragePath), svc.SelfTestStore(ctx)
+		}},
+		{Name: "integrity", Fn: integrityCheck(svc)},
+		{Name: "clock_skew", Fn: health.ClockSkew(clockRef, cfg.MaxClockSkew)},
+		{Name: "port", Fn: portCheck(cfg, ":"+cfg.Port)},
+	}
+	if cfg.AdminAddr != "" && cfg.AdminAddr != config.AdminAddrOff {
+		checks = append(checks, health.Check{Name: "admin_port", Fn: portCheck(cfg, cfg.AdminAddr)})
+	}
+	return checks
+}
+
+// integrityCheck проверяет целостность данных, если прошлый запуск завершился некорректно.
+// При перезапуске по SIGHUP предыдущий процесс ещё работает, отметки чистой остановки нет законно.
+func integrityCheck(svc *tasks.Service) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if restart.Inherited() {
+			return "", health.Skip("started by zero-downtime restart")
+		}
+		unclean, err := svc.UncleanShutdown(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !unclean {
+			return "", health.Skip("previous shutdown was clean")
+		}
+
+		log.Printf("previous shutdown was not clean, checking data integrity")
+		problems, err := svc.CheckIntegrity(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				log.Printf("integrity problem: %s", p)
+			}
+			return strings.Join(problems, "; "), fmt.Errorf("%d integrity problem(s) found", len(problems))
+		}
+		return "unclean shutdown, data is consistent", nil
+	}
+}
+
+// portCheck проверяет, что порт свободен. При перезапуске сокет занят нами же
+// (унаследован), а с SO_REUSEPORT порт законно слушают несколько процессов.
+func portCheck(cfg *config.Config, addr string) func(ctx context.Context) (string, error) {
+	switch {
+	case restart.Inherited():
+		return func(context.Context) (string, error) {
+			return "", health.Skip("listener inherited from previous process")
+		}
+	case cfg.ReusePort:
+		return func(context.Context) (string, error) { return "", health.Skip("SO_REUSEPORT enabled") }
+	}
+	return health.PortAvailable(addr)
+}
+
+// credentials -- секреты, которые могут смениться на лету (ротация в хранилище секретов).
+type credentials struct {
+	jwt        *secrets.Value
+	adminKey   *secrets.Value
+	inviteCode *secrets.Value
+	scimToken  *secrets.Value
+}
+
+// loadCredentials читает секреты у провайдера. Секреты, нужные только при старте
+// (пароль БД, ключи интеграций), записываются прямо в cfg, если провайдер их знает.
+func loadCredentials(ctx context.Context, cfg *config.Config, p secrets.Provider) (credentials, error) {
+	var c credentials
+	var err error
+	if c.jwt, err = secrets.Load(ctx, p, "JWT_SECRET", cfg.SecretsRotationGrace); err != nil {
+		return c, err
+	}
+	if c.adminKey, err = secrets.Load(ctx, p, "ADMIN_KEY", cfg.SecretsRotationGrace); err != nil {
+		return c, err
+	}
+	if c.inviteCode, err = secrets.Load(ctx, p, "REGISTRATION_INVITE_CODE", cfg.SecretsRotationGrace); err != nil {
+		return c, err
+	}
+	if c.scimToken, err = secrets.Load(ctx, p, "SCIM_TOKEN", cfg.SecretsRotationGrace); err != nil {
+		return c, err
+	}
+	cfg.JWTSecret, cfg.AdminKey = c.jwt.Current(), c.adminKey.Current()
+
+	for name, dst := range map[string]*string{
+		"DB_PASSWORD":          &cfg.DBPassword,
+		"INTEGRATION_API_KEYS": &cfg.IntegrationAPIKeys,
+		"LDAP_BIND_PASSWORD":   &cfg.LDAPBindPassword,
+	} {
+		v, err := secrets.Get(ctx, p, name)
+		if err != nil {
+			return c, err
+		}
+		if v != "" {
+			*dst = v
+		}
+	}
+	return c, nil
+}
+
+// newIdentityProviders собирает цепочку проверки паролей по AUTH_PROVIDERS.
+func newIdentityProviders(cfg *config.Config) ([]tasks.IdentityProvider, error) {
+	var out []tasks.IdentityProvider
+	for _, name := range cfg.AuthProviderList() {
+		switch name {
+		case "local":
+			out = append(out, tasks.LocalUsers)
+		case "ldap":
+			roles, err := ldap.ParseGroupRoles(cfg.LDAPGroupRoles)
+			if err != nil {
+				return nil, err
+			}
+			p, err := ldap.NewProvider(ldap.Config{
+				URL: cfg.LDAPURL, StartTLS: cfg.LDAPStartTLS, CAFile: cfg.LDAPCAFile,
+				BindDN: cfg.LDAPBindDN, BindPassword: cfg.LDAPBindPassword,
+				UserBase: cfg.LDAPUserBase, UserFilter: cfg.LDAPUserFilter,
+				GroupAttr: cfg.LDAPGroupAttr, GroupBase: cfg.LDAPGroupBase, GroupFilter: cfg.LDAPGroupFilter,
+				GroupRoles: roles, RequireRole: cfg.LDAPRequireRole, Timeout: cfg.LDAPTimeout,
+			})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p)
+		default:
+			return nil, fmt.Errorf("unknown provider %q (want local or ldap)", name)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no providers configured")
+	}
+	return out, nil
+}
+
+// newAuthorizer создаёт Authorizer поверх движка из AUTHZ_ENGINE.
+func newAuthorizer(cfg *config.Config) (*authz.Authorizer, error) {
+	acfg := authz.Config{Workspace: cfg.AuthzWorkspace, CacheTTL: cfg.AuthzCacheTTL, DryRun: cfg.AuthzDryRun}
+	switch cfg.AuthzEngine {
+	case "opa":
+		return authz.New(authz.NewOPA(cfg.AuthzOPAURL), acfg), nil
+	case "casbin":
+		var a *authz.Authorizer
+		// Файл политики перечитывается на лету -- закэшированные решения по старой политике забываются.
+		engine, err := authz.NewPolicyFile(cfg.AuthzPolicyFile, func() { a.Flush() })
+		if err != nil {
+			return nil, err
+		}
+		a = authz.New(engine, acfg)
+		return a, nil
+	}
+	return nil, fmt.Errorf("unknown engine %q (want opa or casbin)", cfg.AuthzEngine)
+}
 
+// assetFS -- встроенные файлы или, если задан ASSETS_DIR, его подкаталог name.
+func assetFS(cfg *config.Config, name string, embedded fs.FS) fs.FS {
+	if cfg.AssetsDir == "" {
+		return embedded
+	}
+	return os.DirFS(filepath.Join(cfg.AssetsDir, name))
+}
+
+// extractAssets выкладывает встроенные файлы в dir: web/, migrations/, templates/ и task-manager.env.
+// Существующие файлы не перезаписываются -- ошибка.
+func extractAssets(dir string) error {
+	for name, fsys := range map[string]fs.FS{
+		"web":        web.FS,
+		"migrations": migrations.FS,
+		"templates":  tasks.Templates(),
+	} {
+		if err := os.CopyFS(filepath.Join(dir, name), fsys); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "task-manager.env"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(config.Example); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("assets written to %s: web/, migrations/, templates/, task-manager.env\n", dir)
+	return nil
+}
+
+// serviceArgs -- аргументы службы Windows: флаги, с которыми вызвали --service install, кроме флагов
+// самой установки. Путь --config делается абсолютным: служба стартует не из текущего каталога.
+func serviceArgs() ([]string, error) {
+	var args []string
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "service", "service-name":
+			return
+		case "config":
+			if value == "-" {
+				err = errors.New("a service has no stdin: pass --config with a file")
+				return
+			}
+			if value, err = filepath.Abs(value); err != nil {
+				return
+			}
+		}
+		args = append(args, "-"+f.Name+"="+value)
+	})
+	return args, err
 }
 
-// !У нас получился не классический Graceful shutdown
-// Для это стоило сделать так:
-// 1. Сначала сигнализируем серверу о завершении (он перестанет принимать новые запросы)
-/*if err := srv.Shutdown(shutdownCtx); err != nil {
-    log.Printf("shutdown error: %v", err)
+// loadConfigFile читает настройки KEY=VALUE (формат .env) из файла или stdin ("-") и выставляет их
+// переменными окружения поверх уже заданных. Так оркестратор может передать секреты через stdin,
+// не оставляя их в окружении процесса, видном в /proc и docker inspect.
+func loadConfigFile(path string) error {
+	var r io.Reader = os.Stdin
+	source := "stdin"
+	if path != "-" {
+		source = path
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	vars, err := godotenv.Parse(r)
+	if err != nil {
+		return err
+	}
+	for k, v := range vars {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	log.Printf("Настройки из %s: %d переменных", source, len(vars))
+	return nil
 }
 
-// 2. И только потом отменяем корневой контекст, если кто-то еще его слушает
-appCancel()*/
-// В нашем случае получается приоритет на скорость и предсказуемость
-// Но есть вероятность того, что некоторые процессы, которые могли бы успешно завершиться, будут прерваны
-// Уточняю, потому что мы сделали полезно для учебных целей
-// Но в реальной практике могут быть другие приоритеты
+// openAccessLog открывает журнал доступа по настройкам ACCESS_LOG_*. Если формат не задан,
+// журнал выключен (nil). Возвращаемую функцию закрытия нужно вызвать при остановке.
+func openAccessLog(cfg *config.Config) (*middleware.AccessLog, func() error, error) {
+	if cfg.AccessLogFormat == "" {
+		return nil, func() error { return nil }, nil
+	}
+	w, closeFn, err := logging.Open(cfg.AccessLog())
+	if err != nil {
+		return nil, nil, fmt.Errorf("ACCESS_LOG_FILE: %w", err)
+	}
+	al, err := middleware.NewAccessLog(cfg.AccessLogFormat, w)
+	if err != nil {
+		_ = closeFn()
+		return nil, nil, err
+	}
+	log.Printf("Журнал доступа: формат %s, %s", cfg.AccessLogFormat, cmp.Or(cfg.AccessLogFile, "stdout"))
+	return al, closeFn, nil
+}
 
 // chiWithMiddleware навешивает базовые middleware на уже собранный роутер.
 //
 //	Вынесено в отдельную функцию, чтобы main был читаемым и "про запуск".
-func chiWithMiddleware(h http.Handler) http.Handler {
+func chiWithMiddleware(h http.Handler, trustedProxies []netip.Prefix, accessLog *middleware.AccessLog) http.Handler {
 	// Используем chi.Router, чтобы навесить middleware, не меняя роуты модуля.
 	// Это позволяет internal/tasks оставаться независимым от общесервисных middleware.
 	r := chi.NewRouter()
@@ -194,6 +1149,17 @@ func chiWithMiddleware(h http.Handler) http.Handler {
 	// request-id должен быть доступен всем нижним слоям и логам (проброс через context + header)
 	r.Use(middleware.RequestIDMiddleware)
 
+	// След W3C (traceparent) -- продолжается в исходящих webhook и запросах к интеграциям
+	r.Use(middleware.TraceMiddleware)
+
+	// Настоящий адрес клиента за обратным прокси (TRUSTED_PROXIES) -- для логов и блокировки перебора паролей
+	r.Use(middleware.ClientIPMiddleware(trustedProxies))
+
+	// Журнал доступа (ACCESS_LOG_FORMAT) -- снаружи остальных middleware, чтобы видеть итоговый статус
+	if accessLog != nil {
+		r.Use(accessLog.Middleware)
+	}
+
 	// Recoverer ставим "внутрь" логгера, чтобы паника превращалась в 500 ДО логирования статуса
 	r.Use(chiMiddleware.Recoverer)
 
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Скрипты автодополнения: команды и флаги taskctl. При новой команде или флаге -- дописать сюда.
+
+const bashCompletion = `# taskctl: автодополнение для bash. Подключить: source <(taskctl completion bash)
+_taskctl() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "login import watch tui completion help" -- "$cur"))
+        return
+    fi
+    case ${COMP_WORDS[1]} in
+    login)      COMPREPLY=($(compgen -W "-u -p" -- "$cur")) ;;
+    import)
+        if [[ $prev == -format ]]; then
+            COMPREPLY=($(compgen -W "taskwarrior trello jira" -- "$cur"))
+        elif [[ $cur == -* ]]; then
+            COMPREPLY=($(compgen -W "-format -source" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -f -- "$cur"))
+        fi ;;
+    watch)      COMPREPLY=($(compgen -W "-json -since -wait" -- "$cur")) ;;
+    tui)
+        if [[ $prev == -sort ]]; then
+            COMPREPLY=($(compgen -W "priority -priority due -due urgency -urgency" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "-sort" -- "$cur"))
+        fi ;;
+    completion) COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")) ;;
+    esac
+}
+complete -F _taskctl taskctl
+`
+
+const zshCompletion = `#compdef taskctl
+# taskctl: автодополнение для zsh. Подключить: source <(taskctl completion zsh)
+# или сохранить как _taskctl в каталог из $fpath.
+_taskctl() {
+    local -a commands
+    commands=(
+        'login:получить JWT'
+        'import:импортировать задачи'
+        'watch:печатать изменения задач'
+        'tui:интерактивный список задач'
+        'completion:скрипт автодополнения'
+        'help:справка'
+    )
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+    case $words[2] in
+    login)      _arguments '-u[имя пользователя]:username:' '-p[пароль]:password:' ;;
+    import)     _arguments '-format[формат]:format:(taskwarrior trello jira)' '-source[доска Trello или проект Jira]:source:' '*:file:_files' ;;
+    watch)      _arguments '-json[JSON по объекту на строку]' '-since[курсор]:cursor:' '-wait[ожидание сервера]:duration:' ;;
+    tui)        _arguments '-sort[сортировка]:sort:(priority -priority due -due urgency -urgency)' ;;
+    completion) _arguments '1:shell:(bash zsh fish)' ;;
+    esac
+}
+compdef _taskctl taskctl
+`
+
+const fishCompletion = `# taskctl: автодополнение для fish. Подключить: taskctl completion fish | source
+set -l commands login import watch tui completion help
+complete -c taskctl -f
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a login -d 'получить JWT'
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a import -d 'импортировать задачи'
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a watch -d 'печатать изменения задач'
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a tui -d 'интерактивный список задач'
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a completion -d 'скрипт автодополнения'
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a help -d 'справка'
+complete -c taskctl -n "__fish_seen_subcommand_from login" -o u -r -d 'имя пользователя'
+complete -c taskctl -n "__fish_seen_subcommand_from login" -o p -r -d 'пароль'
+complete -c taskctl -n "__fish_seen_subcommand_from import" -o format -x -a 'taskwarrior trello jira'
+complete -c taskctl -n "__fish_seen_subcommand_from import" -o source -r -d 'доска Trello или проект Jira'
+complete -c taskctl -n "__fish_seen_subcommand_from import" -F
+complete -c taskctl -n "__fish_seen_subcommand_from watch" -o json -d 'JSON по объекту на строку'
+complete -c taskctl -n "__fish_seen_subcommand_from watch" -o since -r -d 'курсор'
+complete -c taskctl -n "__fish_seen_subcommand_from watch" -o wait -r -d 'ожидание сервера'
+complete -c taskctl -n "__fish_seen_subcommand_from tui" -o sort -x -a 'priority -priority due -due urgency -urgency'
+complete -c taskctl -n "__fish_seen_subcommand_from completion" -x -a 'bash zsh fish'
+`
+
+// runCompletion печатает скрипт автодополнения для оболочки.
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected shell: bash, zsh or fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		return fmt.Errorf("unknown shell %q (want bash, zsh or fish)", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// taskctl -- консольный клиент для API task-manager.
+//
+// Адрес сервера и токен берутся из окружения:
+//
+//	TASKCTL_URL   -- базовый адрес сервера (по умолчанию http://localhost:8080)
+//	TASKCTL_TOKEN -- JWT, полученный через `taskctl login`
+//
+// Примеры:
+//
+//	export TASKCTL_TOKEN=$(taskctl login -u Папа -p secret)
+//	task export | taskctl import -format taskwarrior -
+//	taskctl watch -json | jq -r 'select(.type == "created") | .task.title'
+//	source <(taskctl completion bash)
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	c := newClient()
+
+	var err error
+	switch os.Args[1] {
+	case "login":
+		err = runLogin(c, os.Args[2:])
+	case "import":
+		err = runImport(c, os.Args[2:])
+	case "watch":
+		err = runWatch(c, os.Args[2:])
+	case "tui":
+		err = runTUI(c, os.Args[2:])
+	case "completion":
+		err = runCompletion(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: taskctl <command> [flags]
+
+commands:
+  login   -u <username> -p <password>     получить JWT (печатается в stdout)
+  import  -format taskwarrior <file|->    импортировать задачи из файла или stdin
+  import  -format trello|jira -source X   импортировать доску Trello / проект Jira по API
+  watch   [-json] [-since cursor]         печатать изменения задач по мере появления (Ctrl+C -- выход)
+  tui     [-sort priority]                интерактивный список: выбор, выполнено, название
+  completion bash|zsh|fish                скрипт автодополнения (source <(taskctl completion bash))`)
+}
+
+// client -- минимальная обёртка над HTTP API.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient() *client {
+	base := os.Getenv("TASKCTL_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return &client{
+		baseURL: strings.TrimRight(base, "/"),
+		token:   os.Getenv("TASKCTL_TOKEN"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError -- ответ 4xx/5xx. Status и Code нужны командам, которые по-разному реагируют на ошибки.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+}
+
+func (e *apiError) Error() string {
+	if e.Details != nil {
+		return fmt.Sprintf("%s: %s (%v)", e.Code, e.Message, e.Details)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// do отправляет запрос и декодирует JSON-ответ в out (если out != nil).
+// Ответы 4xx/5xx превращаются в ошибку с сообщением из единого формата ошибок API.
+func (c *client) do(method, path string, body io.Reader, out any) error {
+	return c.doContext(context.Background(), method, path, body, out)
+}
+
+// doContext -- do с контекстом: отмена прерывает запрос (Ctrl+C в watch).
+func (c *client) doContext(ctx context.Context, method, path string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Details any    `json:"details"`
+			} `json:"api_error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error.Message == "" {
+			return &apiError{Status: resp.StatusCode, Code: method + " " + path, Message: resp.Status}
+		}
+		return &apiError{Status: resp.StatusCode, Code: apiErr.Error.Code, Message: apiErr.Error.Message,
+			Details: apiErr.Error.Details}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func runLogin(c *client, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	username := fs.String("u", "", "имя пользователя")
+	password := fs.String("p", "", "пароль")
+	_ = fs.Parse(args)
+
+	if *username == "" || *password == "" {
+		return fmt.Errorf("-u and -p are required")
+	}
+
+	body, _ := json.Marshal(map[string]string{"username": *username, "password": *password})
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(body), &resp); err != nil {
+		return err
+	}
+	fmt.Println(resp.Token)
+	return nil
+}
+
+func runImport(c *client, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "taskwarrior", "формат: taskwarrior (файл), trello, jira (по API)")
+	source := fs.String("source", "", "ID доски Trello или ключ проекта Jira")
+	_ = fs.Parse(args)
+
+	q := url.Values{"format": {*format}}
+
+	var in io.Reader
+	if *format == "taskwarrior" {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("expected exactly one file argument (use - for stdin)")
+		}
+		in = os.Stdin
+		if name := fs.Arg(0); name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+	} else {
+		// Коннекторы сами ходят во внешнюю систему -- нужен только источник.
+		if *source == "" {
+			return fmt.Errorf("-source is required for format %q", *format)
+		}
+		q.Set("source", *source)
+	}
+
+	var res struct {
+		Imported int `json:"imported"`
+		Skipped  int `json:"skipped"`
+	}
+	if err := c.do(http.MethodPost, "/api/v1/tasks/import?"+q.Encode(), in, &res); err != nil {
+		return err
+	}
+	fmt.Printf("imported: %d, skipped: %d\n", res.Imported, res.Skipped)
+	return nil
+}
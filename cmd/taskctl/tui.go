@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+
+	apiclient "task-manager/pkg/client"
+)
+
+// Интерактивный режим: список задач с управлением с клавиатуры. Внешних библиотек нет --
+// терминал переключается в raw-режим через stty, экран рисуется ANSI-последовательностями,
+// поэтому работает в терминалах Linux и macOS (не в Windows cmd).
+
+const tuiHelp = "↑/k ↓/j -- выбор  space/x -- выполнено  e -- название  r -- обновить  q -- выход"
+
+// Клавиши, которые разбирает readKey, кроме обычных символов.
+const (
+	keyUp = iota + utf8.MaxRune + 1
+	keyDown
+	keyHome
+	keyEnd
+	keyPgUp
+	keyPgDn
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyCtrlC
+)
+
+type tui struct {
+	c      *apiclient.Client
+	tty    *os.File
+	in     *bufio.Reader
+	tasks  []apiclient.Task
+	cur    int // выбранная задача
+	top    int // первая видимая строка списка
+	status string
+	rows   int
+	cols   int
+}
+
+// runTUI открывает интерактивный список задач текущего пользователя.
+func runTUI(c *client, args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	sortBy := fs.String("sort", "", "сортировка списка, как у GET /api/v1/tasks (priority, -due, urgency)")
+	_ = fs.Parse(args)
+
+	api, err := apiclient.New(c.baseURL, apiclient.WithToken(c.token), apiclient.WithUserAgent("taskctl"))
+	if err != nil {
+		return err
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("tui needs a terminal: %w", err)
+	}
+	defer tty.Close()
+
+	restore, err := rawMode(tty)
+	if err != nil {
+		return err
+	}
+	// Альтернативный экран и скрытый курсор; при выходе -- всё как было.
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	t := &tui{c: api, tty: tty, in: bufio.NewReader(tty)}
+	return t.run(context.Background(), &apiclient.ListOptions{Sort: *sortBy})
+}
+
+func (t *tui) run(ctx context.Context, opts *apiclient.ListOptions) error {
+	t.reload(ctx, opts)
+	for {
+		t.render()
+		k, err := t.readKey()
+		if err != nil {
+			return err
+		}
+		switch k {
+		case 'q', keyCtrlC:
+			return nil
+		case keyUp, 'k':
+			t.move(-1)
+		case keyDown, 'j':
+			t.move(1)
+		case keyPgUp:
+			t.move(-t.pageSize())
+		case keyPgDn:
+			t.move(t.pageSize())
+		case keyHome, 'g':
+			t.move(-len(t.tasks))
+		case keyEnd, 'G':
+			t.move(len(t.tasks))
+		case 'r':
+			t.reload(ctx, opts)
+		case ' ', 'x':
+			t.toggleDone(ctx)
+		case 'e':
+			t.editTitle(ctx)
+		}
+	}
+}
+
+func (t *tui) reload(ctx context.Context, opts *apiclient.ListOptions) {
+	t.rows, t.cols = terminalSize(t.tty)
+	tasks, err := t.c.ListTasks(ctx, opts)
+	if err != nil {
+		t.status = "ошибка: " + err.Error()
+		return
+	}
+	t.tasks = tasks
+	t.move(0)
+	t.status = fmt.Sprintf("задач: %d", len(tasks))
+}
+
+// pageSize -- сколько строк списка помещается на экране (заголовок, статус и подсказка -- по строке).
+func (t *tui) pageSize() int {
+	return max(t.rows-3, 1)
+}
+
+func (t *tui) move(delta int) {
+	t.cur = min(max(t.cur+delta, 0), max(len(t.tasks)-1, 0))
+	if t.cur < t.top {
+		t.top = t.cur
+	}
+	if t.cur >= t.top+t.pageSize() {
+		t.top = t.cur - t.pageSize() + 1
+	}
+}
+
+func (t *tui) selected() *apiclient.Task {
+	if len(t.tasks) == 0 {
+		return nil
+	}
+	return &t.tasks[t.cur]
+}
+
+func (t *tui) toggleDone(ctx context.Context) {
+	task := t.selected()
+	if task == nil {
+		return
+	}
+	req := task.UpdateRequest()
+	req.Done = !task.Done
+	req.Status = apiclient.StatusTodo
+	if req.Done {
+		req.Status = apiclient.StatusDone
+	}
+	t.save(ctx, task, req)
+}
+
+func (t *tui) editTitle(ctx context.Context) {
+	task := t.selected()
+	if task == nil {
+		return
+	}
+	title, ok := t.prompt("Название: ", task.Title)
+	if !ok || title == task.Title {
+		t.status = "без изменений"
+		return
+	}
+	if strings.TrimSpace(title) == "" {
+		t.status = "название не может быть пустым"
+		return
+	}
+	req := task.UpdateRequest()
+	req.Title = title
+	t.save(ctx, task, req)
+}
+
+// save отправляет изменение и заменяет задачу в списке ответом сервера.
+func (t *tui) save(ctx context.Context, task *apiclient.Task, req apiclient.UpdateTaskRequest) {
+	updated, err := t.c.UpdateTask(ctx, task.ID, req)
+	if err != nil {
+		t.status = fmt.Sprintf("#%d: ошибка: %v", task.ID, err)
+		return
+	}
+	*task = updated
+	t.status = fmt.Sprintf("#%d сохранена", task.ID)
+}
+
+// prompt -- строка ввода внизу экрана. Enter -- готово, Esc/Ctrl+C -- отмена.
+func (t *tui) prompt(label, value string) (string, bool) {
+	buf := []rune(value)
+	for {
+		fmt.Fprintf(t.tty, "\x1b[%d;1H\x1b[2K%s%s\x1b[?25h", t.rows, label, string(buf))
+		k, err := t.readKey()
+		fmt.Fprint(t.tty, "\x1b[?25l")
+		if err != nil {
+			return "", false
+		}
+		switch k {
+		case keyEnter:
+			return string(buf), true
+		case keyEsc, keyCtrlC:
+			return "", false
+		case keyBackspace:
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+			}
+		default:
+			if k >= ' ' && k <= utf8.MaxRune {
+				buf = append(buf, rune(k))
+			}
+		}
+	}
+}
+
+func (t *tui) render() {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString("\x1b[1m" + fit(fmt.Sprintf("taskctl -- %s", t.status), t.cols) + "\x1b[0m\r\n")
+	for i := t.top; i < len(t.tasks) && i < t.top+t.pageSize(); i++ {
+		task := t.tasks[i]
+		mark := "[ ]"
+		if task.Done {
+			mark = "[x]"
+		}
+		line := fit(fmt.Sprintf("%s %5d  %-8s %s", mark, task.ID, task.Priority, task.Title), t.cols)
+		if i == t.cur {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H\x1b[2m%s\x1b[0m", t.rows, fit(tuiHelp, t.cols))
+	fmt.Fprint(t.tty, b.String())
+}
+
+// fit обрезает строку по ширине экрана (в символах, а не байтах).
+func fit(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
+
+// readKey читает одну клавишу: символ UTF-8 или одну из key* для стрелок и управляющих клавиш.
+func (t *tui) readKey() (rune, error) {
+	r, _, err := t.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch r {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 0x7f, 0x08:
+		return keyBackspace, nil
+	case 0x03:
+		return keyCtrlC, nil
+	case 0x1b:
+		// Одиночный Esc от начала последовательности (стрелки: ESC [ A) отличаем по тому,
+		// пришло ли продолжение вместе с ним.
+		if t.in.Buffered() == 0 {
+			return keyEsc, nil
+		}
+		seq := make([]byte, 0, 4)
+		for t.in.Buffered() > 0 && len(seq) < cap(seq) {
+			c, _ := t.in.ReadByte()
+			seq = append(seq, c)
+			if len(seq) > 1 && (c >= 'A' && c <= 'Z' || c == '~') { // первый байт -- '[' или 'O'
+				break
+			}
+		}
+		switch strings.TrimLeft(string(seq), "[O") {
+		case "A":
+			return keyUp, nil
+		case "B":
+			return keyDown, nil
+		case "H", "1~":
+			return keyHome, nil
+		case "F", "4~":
+			return keyEnd, nil
+		case "5~":
+			return keyPgUp, nil
+		case "6~":
+			return keyPgDn, nil
+		}
+		return 0, nil
+	}
+	return r, nil
+}
+
+// rawMode переводит терминал в raw-режим (клавиши приходят сразу и без эха) и возвращает
+// функцию, которая возвращает прежние настройки.
+func rawMode(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("tui needs stty: %w", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { _, _ = stty(tty, strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize -- строки и столбцы терминала; если узнать не удалось -- 24x80.
+func terminalSize(tty *os.File) (int, int) {
+	out, err := stty(tty, "size")
+	var rows, cols int
+	if err != nil {
+		return 24, 80
+	}
+	if _, err := fmt.Sscan(out, &rows, &cols); err != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", fmt.Errorf("stty %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// watchChange -- поля изменения, нужные для печати; с -json изменение печатается как пришло.
+type watchChange struct {
+	Type   string    `json:"type"`
+	TaskID int       `json:"task_id"`
+	At     time.Time `json:"at"`
+	Task   *struct {
+		Title  string `json:"title"`
+		Status string `json:"status"`
+	} `json:"task"`
+}
+
+// runWatch печатает изменения задач из ленты GET /api/v1/changes (long polling), пока его не остановят.
+// С -json -- по объекту изменения на строку (для jq и while read). Курсор для продолжения
+// печатается в stderr при выходе; устаревший курсор -- предупреждение и продолжение "с этого момента".
+func runWatch(c *client, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "печатать изменения как JSON, по одному на строку")
+	since := fs.String("since", "", "курсор, с которого продолжить (печатается при выходе)")
+	wait := fs.Duration("wait", 25*time.Second, "сколько сервер держит запрос без изменений")
+	_ = fs.Parse(args)
+
+	// Запрос не должен пережить таймаут HTTP-клиента.
+	*wait = min(*wait, c.http.Timeout-5*time.Second)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cursor := *since
+	enc := json.NewEncoder(os.Stdout)
+	backoff := time.Second
+	for {
+		q := url.Values{}
+		if cursor != "" {
+			q.Set("since", cursor)
+			q.Set("wait", wait.String())
+		}
+		var batch struct {
+			Changes []json.RawMessage `json:"changes"`
+			Cursor  string            `json:"cursor"`
+		}
+		err := c.doContext(ctx, http.MethodGet, "/api/v1/changes?"+q.Encode(), nil, &batch)
+		if ctx.Err() != nil {
+			if cursor != "" {
+				fmt.Fprintf(os.Stderr, "continue with: taskctl watch -since %s\n", cursor)
+			}
+			return nil
+		}
+
+		var apiErr *apiError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Code == "cursor_expired":
+			fmt.Fprintln(os.Stderr, "warning: cursor expired, changes since it may be missed; watching from now")
+			cursor = ""
+			continue
+		case errors.As(err, &apiErr) && apiErr.Status < 500 && apiErr.Status != http.StatusTooManyRequests:
+			return err // токен, курсор чужого формата -- повторять бессмысленно
+		case err != nil:
+			// Сеть, перезапуск сервера, 5xx: ждём и пробуем снова с тем же курсором.
+			fmt.Fprintf(os.Stderr, "warning: %v; retrying in %s\n", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+
+		for _, raw := range batch.Changes {
+			if *asJSON {
+				_ = enc.Encode(raw)
+				continue
+			}
+			var ch watchChange
+			if err := json.Unmarshal(raw, &ch); err != nil {
+				return fmt.Errorf("decode change: %w", err)
+			}
+			printChange(ch)
+		}
+		cursor = batch.Cursor
+	}
+}
+
+// printChange печатает изменение одной строкой: "15:04:05 updated  #41 Купить молоко [in_progress]".
+func printChange(ch watchChange) {
+	line := fmt.Sprintf("%s %-8s #%d", ch.At.Local().Format(time.TimeOnly), ch.Type, ch.TaskID)
+	if ch.Task != nil {
+		line += fmt.Sprintf(" %s [%s]", ch.Task.Title, ch.Task.Status)
+	}
+	fmt.Println(line)
+}
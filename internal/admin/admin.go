@@ -0,0 +1,184 @@
+// Package admin -- служебный API для эксплуатации (/api/v1/admin/*).
+//
+// Доступ -- по ключу X-Admin-Key (ADMIN_KEY), проверка ключа навешивается при подключении в main.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// Handler -- HTTP-слой служебного API.
+type Handler struct {
+	maintenance *appMiddleware.Maintenance
+	readOnly    *appMiddleware.ReadOnly
+	authGuard   *appMiddleware.AuthGuard // nil -- блокировка перебора паролей выключена
+	limits      *appMiddleware.ServerLimits
+	validate    *validator.Validate
+}
+
+// NewHandler создаёт Handler.
+func NewHandler(maintenance *appMiddleware.Maintenance, readOnly *appMiddleware.ReadOnly, authGuard *appMiddleware.AuthGuard,
+	limits *appMiddleware.ServerLimits) *Handler {
+	return &Handler{
+		maintenance: maintenance,
+		readOnly:    readOnly,
+		authGuard:   authGuard,
+		limits:      limits,
+		validate:    validator.New(),
+	}
+}
+
+// AuthFailuresResponse -- ответ GET /api/v1/admin/auth/failures.
+type AuthFailuresResponse struct {
+	Enabled bool                        `json:"enabled"`
+	Recent  []appMiddleware.AuthFailure `json:"recent"` // новые первыми
+	Locks   []appMiddleware.AuthLock    `json:"locks"`  // действующие блокировки
+}
+
+// MaintenanceRequest -- тело PUT /api/v1/admin/maintenance.
+type MaintenanceRequest struct {
+	Enabled    *bool  `json:"enabled" validate:"required"`
+	Message    string `json:"message" validate:"max=500"`
+	RetryAfter int    `json:"retry_after_seconds" validate:"min=0,max=86400"`
+}
+
+// ReadOnlyRequest -- тело PUT /api/v1/admin/read-only.
+type ReadOnlyRequest struct {
+	Enabled *bool  `json:"enabled" validate:"required"`
+	Message string `json:"message" validate:"max=500"`
+}
+
+// Router возвращает маршруты относительно /api/v1/admin.
+func (h *Handler) Router() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/maintenance", h.getMaintenance)
+	r.Put("/maintenance", h.setMaintenance)
+	r.Get("/read-only", h.getReadOnly)
+	r.Put("/read-only", h.setReadOnly)
+	r.Get("/auth/failures", h.getAuthFailures)
+	r.Delete("/auth/locks/{kind}/{value}", h.deleteAuthLock)
+	r.Get("/stats", h.getStats)
+
+	return r
+}
+
+// getMaintenance обрабатывает GET /api/v1/admin/maintenance
+func (h *Handler) getMaintenance(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(h.maintenance.State())
+}
+
+// setMaintenance обрабатывает PUT /api/v1/admin/maintenance
+//
+// {"enabled": true, "message": "Бэкап, вернёмся через 10 минут", "retry_after_seconds": 600}
+func (h *Handler) setMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	st := h.maintenance.Set(*req.Enabled, req.Message, req.RetryAfter)
+	appMiddleware.LogAdminAction(r, "maintenance enabled=%t", st.Enabled)
+	_ = json.NewEncoder(w).Encode(st)
+}
+
+// getReadOnly обрабатывает GET /api/v1/admin/read-only
+func (h *Handler) getReadOnly(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(h.readOnly.State())
+}
+
+// setReadOnly обрабатывает PUT /api/v1/admin/read-only
+//
+// {"enabled": true, "message": "Это зеркало только для чтения, основной сервер -- tasks.example.com"}
+func (h *Handler) setReadOnly(w http.ResponseWriter, r *http.Request) {
+	var req ReadOnlyRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	st := h.readOnly.Set(*req.Enabled, req.Message)
+	appMiddleware.LogAdminAction(r, "read-only enabled=%t", st.Enabled)
+	_ = json.NewEncoder(w).Encode(st)
+}
+
+// getStats обрабатывает GET /api/v1/admin/stats -- соединения, запросы, горутины и подписчики
+// с их ограничениями (MAX_CONNECTIONS, MAX_CONCURRENT_REQUESTS).
+func (h *Handler) getStats(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(h.limits.Stats())
+}
+
+// getAuthFailures обрабатывает GET /api/v1/admin/auth/failures?limit=50
+// -- последние неудачные попытки входа и действующие блокировки.
+func (h *Handler) getAuthFailures(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "limit must be between 1 and 200",
+				map[string]any{"limit": v})
+			return
+		}
+		limit = n
+	}
+	_ = json.NewEncoder(w).Encode(AuthFailuresResponse{
+		Enabled: h.authGuard != nil,
+		Recent:  h.authGuard.Recent(limit),
+		Locks:   h.authGuard.Locks(time.Now()),
+	})
+}
+
+// deleteAuthLock обрабатывает DELETE /api/v1/admin/auth/locks/{kind}/{value}
+// -- снять блокировку логина (kind=user) или адреса (kind=ip) досрочно.
+func (h *Handler) deleteAuthLock(w http.ResponseWriter, r *http.Request) {
+	kind, value := chi.URLParam(r, "kind"), chi.URLParam(r, "value")
+	if kind != "user" && kind != "ip" {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "kind must be user or ip",
+			map[string]any{"kind": kind})
+		return
+	}
+	if !h.authGuard.Unlock(kind, value) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "No failed attempts recorded",
+			map[string]any{"kind": kind, "value": value})
+		return
+	}
+	appMiddleware.LogAdminAction(r, "auth lock cleared %s=%q", kind, value)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decode читает JSON строго (неизвестные поля -- ошибка) и валидирует его.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		msg := "Invalid JSON"
+		if errors.Is(err, io.EOF) {
+			msg = "Empty body"
+		}
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", msg, nil)
+		return false
+	}
+	if err := h.validate.Struct(dst); err != nil {
+		// Формат деталей -- как в API задач: [{"field": ..., "rule": ...}]
+		var details []map[string]string
+		var verrs validator.ValidationErrors
+		if errors.As(err, &verrs) {
+			for _, fe := range verrs {
+				details = append(details, map[string]string{"field": fe.Field(), "rule": fe.Tag()})
+			}
+		}
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed", details)
+		return false
+	}
+	return true
+}
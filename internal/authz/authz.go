@@ -0,0 +1,188 @@
+// Package authz -- делегирование решений об авторизации внешнему движку политик (OPA или файл
+// политики в формате casbin). Движок получает вход (пользователь, действие, ресурс, пространство)
+// и отвечает "можно" или "нельзя"; решения кэшируются, а в пробном режиме запреты только пишутся
+// в лог -- политику можно обкатать на живом трафике, никому не отказывая.
+package authz
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+	"task-manager/internal/middleware"
+)
+
+var decisions = metrics.NewCounterVec("authz_decisions_total",
+	"Policy engine decisions by result: allow, deny, would_deny (dry run), error; cached ones included.", "result")
+
+// Input -- вход движка политик. Поля JSON -- то, что видит политика OPA как input.
+type Input struct {
+	User      int      `json:"user"`
+	Roles     []string `json:"roles,omitempty"` // роли из групп каталога (LDAP), если есть
+	Action    string   `json:"action"`          // read, create, update, delete
+	Resource  string   `json:"resource"`        // путь без /api/v1/: "tasks/42/subtasks"
+	Workspace string   `json:"workspace"`       // AUTHZ_WORKSPACE: один сервер -- одна семья или команда
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+}
+
+// Decision -- ответ движка. Reason -- необязательное объяснение запрета (уходит клиенту и в лог).
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Engine -- движок политик.
+type Engine interface {
+	Decide(ctx context.Context, in Input) (Decision, error)
+	Name() string
+}
+
+// Config -- настройки Authorizer.
+type Config struct {
+	Workspace string
+	// CacheTTL -- сколько помнить решение для той же пары (пользователь, метод, путь); 0 -- не кэшировать.
+	CacheTTL time.Duration
+	// DryRun -- не отказывать, а писать в лог, кому было бы отказано.
+	DryRun bool
+}
+
+// maxCacheEntries ограничивает кэш: при переполнении он очищается целиком.
+const maxCacheEntries = 10000
+
+type cached struct {
+	d       Decision
+	expires time.Time
+}
+
+// Authorizer спрашивает движок о каждом запросе к API и применяет ответ.
+type Authorizer struct {
+	engine Engine
+	cfg    Config
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// New создаёт Authorizer поверх движка.
+func New(engine Engine, cfg Config) *Authorizer {
+	if cfg.Workspace == "" {
+		cfg.Workspace = "default"
+	}
+	return &Authorizer{engine: engine, cfg: cfg, cache: make(map[string]cached)}
+}
+
+// Name -- имя движка и режим, для лога при старте.
+func (a *Authorizer) Name() string {
+	if a.cfg.DryRun {
+		return a.engine.Name() + " (dry run)"
+	}
+	return a.engine.Name()
+}
+
+// Flush забывает закэшированные решения (политика поменялась).
+func (a *Authorizer) Flush() {
+	a.mu.Lock()
+	clear(a.cache)
+	a.mu.Unlock()
+}
+
+// Decide возвращает решение для входа, из кэша или от движка. Ошибки движка не кэшируются.
+func (a *Authorizer) Decide(ctx context.Context, in Input) (Decision, error) {
+	key := ""
+	if a.cfg.CacheTTL > 0 {
+		// Роли -- часть ключа: после смены групп в каталоге новый токен не получит старое решение.
+		key = strings.Join([]string{strconv.Itoa(in.User), strings.Join(in.Roles, ","), in.Method, in.Path}, " ")
+		a.mu.Lock()
+		c, ok := a.cache[key]
+		a.mu.Unlock()
+		if ok && time.Now().Before(c.expires) {
+			return c.d, nil
+		}
+	}
+	d, err := a.engine.Decide(ctx, in)
+	if err != nil {
+		return Decision{}, err
+	}
+	if key != "" {
+		a.mu.Lock()
+		if len(a.cache) >= maxCacheEntries {
+			clear(a.cache)
+		}
+		a.cache[key] = cached{d: d, expires: time.Now().Add(a.cfg.CacheTTL)}
+		a.mu.Unlock()
+	}
+	return d, nil
+}
+
+// Middleware спрашивает политику о запросе аутентифицированного пользователя (ставится после
+// политики аутентификации). Запросы без пользователя -- вход, регистрация -- пропускаются.
+// Запрет -- 403; движок недоступен -- 503: пропускать запросы без ответа политики нельзя.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		in := Input{
+			User:      userID,
+			Roles:     middleware.RolesFromContext(r.Context()),
+			Action:    Action(r.Method),
+			Resource:  strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/"),
+			Workspace: a.cfg.Workspace,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+		}
+		d, err := a.Decide(r.Context(), in)
+		switch {
+		case err != nil && errors.Is(err, context.Canceled):
+			return // клиент ушёл
+		case err != nil:
+			decisions.WithLabelValues("error").Inc()
+			log.Printf("request_id=%s authz %s: %v", middleware.GetRequestID(r.Context()), a.engine.Name(), err)
+			if a.cfg.DryRun {
+				break
+			}
+			middleware.WriteError(w, r, http.StatusServiceUnavailable, "authz_unavailable",
+				"Authorization service is unavailable, try again later", nil)
+			return
+		case d.Allow:
+			decisions.WithLabelValues("allow").Inc()
+		case a.cfg.DryRun:
+			decisions.WithLabelValues("would_deny").Inc()
+			log.Printf("authz dry run: would deny user=%d action=%s resource=%s workspace=%s reason=%q",
+				in.User, in.Action, in.Resource, in.Workspace, d.Reason)
+		default:
+			decisions.WithLabelValues("deny").Inc()
+			var details any
+			if d.Reason != "" {
+				details = map[string]string{"reason": d.Reason}
+			}
+			middleware.WriteError(w, r, http.StatusForbidden, "forbidden", "Access denied by policy", details)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Action -- действие по методу HTTP: read, create, update или delete.
+func Action(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return strings.ToLower(method)
+}
@@ -0,0 +1,227 @@
+package authz
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// policyRecheck -- как часто проверять, не изменился ли файл политики.
+const policyRecheck = 5 * time.Second
+
+// PolicyFile -- движок на файле политики в формате casbin (CSV) с моделью "RBAC с доменами":
+//
+//	# p, субъект, пространство, ресурс, действие[, allow|deny]
+//	p, role:parent, default, tasks/*, *
+//	p, role:child,  default, tasks/*, read
+//	p, user:7,      *,       reports/*, read, deny
+//	# g, пользователь или роль, роль[, пространство]
+//	g, user:1, role:parent, default
+//	g, user:3, role:child
+//
+// Субъект запроса -- user:<ID> и role:<роль> для ролей из групп каталога (LDAP);
+// роли наследуются (g транзитивно). Пространство и действие
+// совпадают точно или "*". Ресурс -- как keyMatch2: :id или {id} -- один сегмент, * в конце --
+// любой остаток. Разрешено, если подошло хоть одно allow и ни одного deny. Файл перечитывается
+// при изменении; с ошибкой -- остаётся прежняя политика.
+type PolicyFile struct {
+	path string
+
+	mu       sync.RWMutex
+	rules    []policyRule
+	roles    []roleLink
+	modTime  time.Time
+	checked  time.Time
+	onReload func()
+}
+
+type policyRule struct {
+	sub, dom, obj, act string
+	deny               bool
+}
+
+type roleLink struct {
+	member, role, dom string
+}
+
+// NewPolicyFile читает файл политики. onReload вызывается после перечитывания (сбросить кэш решений).
+func NewPolicyFile(path string, onReload func()) (*PolicyFile, error) {
+	p := &PolicyFile{path: path, onReload: onReload}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *PolicyFile) Name() string { return "casbin policy " + p.path }
+
+func (p *PolicyFile) Decide(_ context.Context, in Input) (Decision, error) {
+	p.maybeReload()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	subjects := p.subjects(in)
+	allowed := false
+	for _, r := range p.rules {
+		if !subjects[r.sub] || !anyOr(r.dom, in.Workspace) || !anyOr(r.act, in.Action) || !keyMatch(in.Resource, r.obj) {
+			continue
+		}
+		if r.deny {
+			return Decision{Reason: fmt.Sprintf("denied by policy for %s", r.sub)}, nil
+		}
+		allowed = true
+	}
+	if !allowed {
+		return Decision{Reason: "no matching policy"}, nil
+	}
+	return Decision{Allow: true}, nil
+}
+
+// subjects -- пользователь и все его роли в пространстве запроса (с наследованием):
+// из строк g и из групп каталога (role:<имя> для каждой роли во входе).
+func (p *PolicyFile) subjects(in Input) map[string]bool {
+	user, dom := "user:"+strconv.Itoa(in.User), in.Workspace
+	seen := map[string]bool{user: true}
+	queue := []string{user}
+	for _, r := range in.Roles {
+		if role := "role:" + r; !seen[role] {
+			seen[role] = true
+			queue = append(queue, role)
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, l := range p.roles {
+			if l.member == cur && !seen[l.role] && (l.dom == "" || anyOr(l.dom, dom)) {
+				seen[l.role] = true
+				queue = append(queue, l.role)
+			}
+		}
+	}
+	return seen
+}
+
+func anyOr(pattern, value string) bool {
+	return pattern == "*" || pattern == value
+}
+
+// keyMatch сопоставляет ресурс с шаблоном: "tasks/:id", "tasks/{id}/subtasks", "tasks/*", "*".
+func keyMatch(resource, pattern string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(resource, "/"), "/")
+	for i, seg := range want {
+		if seg == "*" && i == len(want)-1 {
+			return true
+		}
+		if i >= len(got) {
+			return false
+		}
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+		if seg != got[i] {
+			return false
+		}
+	}
+	return len(got) == len(want)
+}
+
+// maybeReload перечитывает файл, если он изменился (проверка не чаще policyRecheck).
+func (p *PolicyFile) maybeReload() {
+	p.mu.RLock()
+	due := time.Since(p.checked) >= policyRecheck
+	p.mu.RUnlock()
+	if !due {
+		return
+	}
+	st, err := os.Stat(p.path)
+	p.mu.Lock()
+	p.checked = time.Now()
+	changed := err == nil && !st.ModTime().Equal(p.modTime)
+	p.mu.Unlock()
+	if !changed {
+		return
+	}
+	if err := p.load(); err != nil {
+		log.Printf("authz: reload %s: %v (keeping previous policy)", p.path, err)
+		return
+	}
+	log.Printf("authz: policy %s reloaded", p.path)
+	if p.onReload != nil {
+		p.onReload()
+	}
+}
+
+func (p *PolicyFile) load() error {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	rules, roles, err := parsePolicy(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.path, err)
+	}
+	p.mu.Lock()
+	p.rules, p.roles, p.modTime, p.checked = rules, roles, st.ModTime(), time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func parsePolicy(r io.Reader) ([]policyRule, []roleLink, error) {
+	var rules []policyRule
+	var roles []roleLink
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cr := csv.NewReader(strings.NewReader(line))
+		cr.TrimLeadingSpace = true
+		cr.FieldsPerRecord = -1
+		rec, err := cr.Read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		switch {
+		case rec[0] == "p" && (len(rec) == 5 || len(rec) == 6):
+			rule := policyRule{sub: rec[1], dom: rec[2], obj: rec[3], act: rec[4]}
+			if len(rec) == 6 {
+				switch rec[5] {
+				case "allow":
+				case "deny":
+					rule.deny = true
+				default:
+					return nil, nil, fmt.Errorf("line %d: effect must be allow or deny, got %q", n, rec[5])
+				}
+			}
+			rules = append(rules, rule)
+		case rec[0] == "g" && (len(rec) == 3 || len(rec) == 4):
+			link := roleLink{member: rec[1], role: rec[2]}
+			if len(rec) == 4 {
+				link.dom = rec[3]
+			}
+			roles = append(roles, link)
+		default:
+			return nil, nil, fmt.Errorf("line %d: want \"p, sub, dom, obj, act[, eft]\" or \"g, member, role[, dom]\"", n)
+		}
+	}
+	return rules, roles, sc.Err()
+}
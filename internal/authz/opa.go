@@ -0,0 +1,72 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"task-manager/internal/httpclient"
+)
+
+// opaTimeout -- сколько ждать ответа OPA; дольше -- запрос получает 503.
+const opaTimeout = 2 * time.Second
+
+// OPA спрашивает Open Policy Agent через REST API: POST url {"input": ...}. url -- адрес
+// документа решения, например http://opa:8181/v1/data/taskmanager/allow. Документ -- либо
+// true/false, либо объект {"allow": bool, "reason": string}. Неопределённый документ (правило не
+// сработало) -- запрет.
+type OPA struct {
+	url    string
+	client *http.Client
+}
+
+// NewOPA создаёт движок OPA.
+func NewOPA(url string) *OPA {
+	return &OPA{url: url, client: httpclient.New(httpclient.Options{Name: "opa", Timeout: opaTimeout, Retries: 1})}
+}
+
+func (o *OPA) Name() string { return "opa" }
+
+func (o *OPA) Decide(ctx context.Context, in Input) (Decision, error) {
+	body, err := json.Marshal(map[string]Input{"input": in})
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Decision{}, fmt.Errorf("opa: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Decision{}, fmt.Errorf("opa: decode response: %w", err)
+	}
+	if len(out.Result) == 0 {
+		return Decision{Reason: "no policy decision"}, nil
+	}
+	var allow bool
+	if err := json.Unmarshal(out.Result, &allow); err == nil {
+		return Decision{Allow: allow}, nil
+	}
+	var d Decision
+	if err := json.Unmarshal(out.Result, &d); err != nil {
+		return Decision{}, fmt.Errorf("opa: result must be a boolean or {\"allow\": bool}, got %s", out.Result)
+	}
+	return d, nil
+}
@@ -0,0 +1,82 @@
+package caldav
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// authCacheTTL -- сколько помнится удачная проверка Basic-пароля. Клиент CalDAV за одну
+// синхронизацию шлёт десятки запросов (PROPFIND, REPORT, GET каждой задачи), и без кэша каждый
+// из них считал бы хэш argon2id. Дольше не держим: сменённый пароль продолжает работать
+// в CalDAV не дольше этого срока (отключённый пользователь -- нет, см. basicAuth).
+const authCacheTTL = time.Minute
+
+// authCacheMax -- больше записей не храним: при переполнении выбрасываются истёкшие, а если
+// не помогло -- все (перебор паролей в кэш не попадает, так что это редкость).
+const authCacheMax = 1024
+
+// authCache -- удачные проверки логина и пароля. Ключ -- HMAC-SHA256 логина и пароля на случайном
+// ключе процесса: сам пароль в памяти не остаётся, а по ключу кэша его не подобрать без ключа HMAC.
+type authCache struct {
+	mu      sync.Mutex
+	secret  []byte
+	entries map[[sha256.Size]byte]authEntry
+}
+
+type authEntry struct {
+	userID  int
+	expires time.Time
+}
+
+func newAuthCache() *authCache {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret) // crypto/rand.Read не возвращает ошибок
+	return &authCache{secret: secret, entries: make(map[[sha256.Size]byte]authEntry)}
+}
+
+func (c *authCache) key(username, password string) [sha256.Size]byte {
+	m := hmac.New(sha256.New, c.secret)
+	m.Write([]byte(username))
+	m.Write([]byte{0})
+	m.Write([]byte(password))
+	var k [sha256.Size]byte
+	m.Sum(k[:0])
+	return k
+}
+
+// get возвращает пользователя, если этот логин с этим паролем недавно прошёл проверку.
+func (c *authCache) get(username, password string, now time.Time) (int, bool) {
+	k := c.key(username, password)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return 0, false
+	}
+	if !now.Before(e.expires) {
+		delete(c.entries, k)
+		return 0, false
+	}
+	return e.userID, true
+}
+
+// put запоминает удачную проверку на authCacheTTL.
+func (c *authCache) put(username, password string, userID int, now time.Time) {
+	k := c.key(username, password)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= authCacheMax {
+		for key, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= authCacheMax {
+			clear(c.entries)
+		}
+	}
+	c.entries[k] = authEntry{userID: userID, expires: now.Add(authCacheTTL)}
+}
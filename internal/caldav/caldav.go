@@ -0,0 +1,447 @@
+// Package caldav -- минимальный CalDAV-сервер (RFC 4791) поверх tasks.Service.
+//
+// Задачи публикуются одной коллекцией VTODO, чтобы их могли синхронизировать
+// Tasks.org, Apple Reminders, Thunderbird и другие CalDAV-клиенты:
+//
+//	/caldav/          -- principal и calendar-home-set
+//	/caldav/tasks/    -- коллекция задач (PROPFIND, REPORT)
+//	/caldav/tasks/X.ics -- отдельная задача (GET, PUT, DELETE)
+//
+// Клиенты CalDAV не умеют JWT, поэтому здесь используется Basic-авторизация
+// с теми же логином/паролем, что и у веб-интерфейса.
+package caldav
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath       = "/caldav/"
+	collectionPath = "/caldav/tasks/"
+	realm          = "task-manager"
+)
+
+func init() {
+	// chi по умолчанию знает только стандартные HTTP-методы.
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+}
+
+// Handler -- HTTP-слой CalDAV.
+type Handler struct {
+	svc *tasks.Service
+
+	// authGuard -- блокировка перебора паролей (общая с JWT-логином; nil -- выключена)
+	authGuard *appMiddleware.AuthGuard
+	// authCache -- недавние удачные проверки пароля (см. auth_cache.go)
+	authCache *authCache
+}
+
+// NewHandler создаёт CalDAV-обработчик.
+func NewHandler(svc *tasks.Service) *Handler {
+	return &Handler{svc: svc, authCache: newAuthCache()}
+}
+
+// SetAuthGuard подключает блокировку перебора паролей к Basic-авторизации.
+// Вызывать до Mount().
+func (h *Handler) SetAuthGuard(g *appMiddleware.AuthGuard) {
+	h.authGuard = g
+}
+
+// Mount подключает CalDAV к корневому роутеру сервера:
+// /.well-known/caldav (автообнаружение) и всё дерево /caldav.
+func (h *Handler) Mount(r chi.Router) {
+	// Автообнаружение (RFC 6764): клиенты сначала стучатся сюда.
+	r.HandleFunc("/.well-known/caldav", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, basePath, http.StatusMovedPermanently)
+	})
+	r.Mount("/caldav", h.routes())
+}
+
+func (h *Handler) routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(appMiddleware.LoggingMiddleware)
+	r.Use(appMiddleware.BodyLimitMiddleware(1 << 20))
+	r.Use(h.basicAuth)
+
+	r.Options("/*", h.options)
+	r.MethodFunc("PROPFIND", "/", h.propfindRoot)
+	r.MethodFunc("PROPFIND", "/tasks", h.propfindCollection)
+	r.MethodFunc("PROPFIND", "/tasks/", h.propfindCollection)
+	r.MethodFunc("REPORT", "/tasks", h.report)
+	r.MethodFunc("REPORT", "/tasks/", h.report)
+	r.MethodFunc("PROPFIND", "/tasks/{name}", h.propfindItem)
+	r.Get("/tasks/{name}", h.getItem)
+	r.Put("/tasks/{name}", h.putItem)
+	r.Delete("/tasks/{name}", h.deleteItem)
+
+	return r
+}
+
+// basicAuth проверяет Basic-авторизацию и кладёт user_id в контекст
+// (тот же ключ, что и у JWT-middleware, чтобы слои ниже не различали транспорт).
+// Удачная проверка пароля кэшируется на authCacheTTL; неудачные не кэшируются и каждая
+// учитывается AuthGuard, как на /api/v1/auth/login. Блокировка действует и на кэшированные пароли.
+func (h *Handler) basicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			h.unauthorized(w)
+			return
+		}
+
+		ip := appMiddleware.ClientIP(r)
+		if wait, locked := h.authGuard.Check(username, ip, "caldav", time.Now()); locked {
+			w.Header().Set("Retry-After", appMiddleware.RetryAfterHeader(wait))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		if id, ok := h.authCache.get(username, password, time.Now()); ok && h.svc.UserActive(r.Context(), id) {
+			next.ServeHTTP(w, r.WithContext(appMiddleware.WithUserID(r.Context(), id)))
+			return
+		}
+
+		u, err := h.svc.Authenticate(r.Context(), username, password)
+		if err != nil {
+			if errors.Is(err, tasks.ErrInvalidCredentials) {
+				h.authGuard.Fail(username, ip, "caldav", time.Now())
+			} else if !errors.Is(err, tasks.ErrUserDisabled) {
+				log.Printf("request_id=%s caldav auth error: %v", appMiddleware.GetRequestID(r.Context()), err)
+			}
+			h.unauthorized(w)
+			return
+		}
+		h.authGuard.Succeed(username)
+		h.authCache.put(username, password, u.ID, time.Now())
+
+		ctx := appMiddleware.WithUserID(r.Context(), u.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+func (h *Handler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// propfindRoot описывает principal: где искать календари пользователя.
+// Для простоты principal и calendar-home-set совпадают с /caldav/.
+func (h *Handler) propfindRoot(w http.ResponseWriter, r *http.Request) {
+	ms := newMultistatus()
+	ms.add(basePath, prop{
+		ResourceType:         &resourceType{Collection: &empty{}},
+		DisplayName:          "task-manager",
+		CurrentUserPrincipal: &href{Href: basePath},
+		PrincipalURL:         &href{Href: basePath},
+		CalendarHomeSet:      &href{Href: basePath},
+	})
+
+	if r.Header.Get("Depth") == "1" {
+		list, err := h.list(r)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		ms.add(collectionPath, collectionProp(list))
+	}
+
+	writeMultistatus(w, ms)
+}
+
+// propfindCollection описывает коллекцию задач; при Depth: 1 -- ещё и все её элементы.
+func (h *Handler) propfindCollection(w http.ResponseWriter, r *http.Request) {
+	list, err := h.list(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ms := newMultistatus()
+	ms.add(collectionPath, collectionProp(list))
+	if r.Header.Get("Depth") == "1" {
+		for i := range list {
+			ms.add(itemHref(&list[i]), prop{
+				ResourceType:   &resourceType{},
+				GetETag:        etag(&list[i]),
+				GetContentType: "text/calendar; charset=utf-8; component=VTODO",
+			})
+		}
+	}
+
+	writeMultistatus(w, ms)
+}
+
+func (h *Handler) propfindItem(w http.ResponseWriter, r *http.Request) {
+	t, err := h.find(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ms := newMultistatus()
+	ms.add(itemHref(t), prop{
+		ResourceType:   &resourceType{},
+		GetETag:        etag(t),
+		GetContentType: "text/calendar; charset=utf-8; component=VTODO",
+	})
+	writeMultistatus(w, ms)
+}
+
+// reportRequest -- тело REPORT. calendar-multiget содержит список href,
+// для calendar-query фильтры не разбираем и отдаём все задачи (у нас только VTODO).
+type reportRequest struct {
+	XMLName xml.Name
+	Hrefs   []string `xml:"DAV: href"`
+}
+
+// report обрабатывает calendar-query и calendar-multiget: отдаёт etag + calendar-data.
+func (h *Handler) report(w http.ResponseWriter, r *http.Request) {
+	var req reportRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid REPORT body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.list(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ms := newMultistatus()
+
+	if req.XMLName.Local == "calendar-multiget" {
+		byHref := make(map[string]*tasks.Task, len(list))
+		for i := range list {
+			byHref[itemHref(&list[i])] = &list[i]
+		}
+		for _, hr := range req.Hrefs {
+			t, ok := byHref[hr]
+			if !ok {
+				ms.addStatus(hr, http.StatusNotFound)
+				continue
+			}
+			ms.add(hr, prop{GetETag: etag(t), CalendarData: encodeVTodo(t)})
+		}
+		writeMultistatus(w, ms)
+		return
+	}
+
+	for i := range list {
+		ms.add(itemHref(&list[i]), prop{GetETag: etag(&list[i]), CalendarData: encodeVTodo(&list[i])})
+	}
+	writeMultistatus(w, ms)
+}
+
+func (h *Handler) getItem(w http.ResponseWriter, r *http.Request) {
+	t, err := h.find(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", etag(t))
+	_, _ = io.WriteString(w, encodeVTodo(t))
+}
+
+// putItem создаёт или обновляет задачу из присланного VTODO.
+// Поддерживаются условные заголовки If-Match / If-None-Match: * (защита от потери изменений).
+func (h *Handler) putItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+	name := chi.URLParam(r, "name")
+
+	v, err := decodeVTodo(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid calendar object: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.find(r, name)
+	if err != nil && !errors.Is(err, tasks.ErrTaskNotFound) {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if existing == nil {
+		if match := r.Header.Get("If-Match"); match != "" {
+			http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+			return
+		}
+
+		t := tasks.Task{UserID: userID, AssignedTo: userID}
+		v.apply(&t)
+		// Длинный SUMMARY обрезается до TITLE_MAX_LENGTH, полный текст -- в начало описания.
+		h.svc.FitTitle(&t)
+		// UID клиента сохраняем, чтобы ресурс находился по тому же имени.
+		t.UUID = v.UID
+		if t.UUID == "" {
+			t.UUID = strings.TrimSuffix(name, ".ics")
+		}
+
+		if err := h.svc.CreateTask(ctx, &t); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", etag(&t))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if r.Header.Get("If-None-Match") == "*" {
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != etag(existing) {
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	v.apply(existing)
+	h.svc.FitTitle(existing)
+	if err := h.svc.UpdateTask(ctx, existing, userID); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Перечитываем, чтобы ETag соответствовал тому, что реально сохранено.
+	if saved, err := h.svc.GetTaskByID(ctx, existing.ID, userID); err == nil {
+		existing = saved
+	}
+	w.Header().Set("ETag", etag(existing))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+
+	t, err := h.find(r, chi.URLParam(r, "name"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != etag(t) {
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := h.svc.DeleteTask(ctx, t.ID, userID); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list возвращает задачи пользователя в стабильном порядке (по ID).
+func (h *Handler) list(r *http.Request) ([]tasks.Task, error) {
+	userID, _ := appMiddleware.UserIDFromContext(r.Context())
+	list, err := h.svc.GetAllTasks(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// find ищет задачу по имени ресурса: "<uuid>.ics" для задач с внешним UID
+// или "<id>.ics" для задач, созданных в самом сервисе.
+func (h *Handler) find(r *http.Request, name string) (*tasks.Task, error) {
+	name = strings.TrimSuffix(name, ".ics")
+
+	list, err := h.list(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if itemName(&list[i]) == name {
+			return &list[i], nil
+		}
+	}
+	return nil, tasks.ErrTaskNotFound
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, tasks.ErrTaskNotFound) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	// Отказ хука -- 403 с его текстом: клиенты календарей показывают его пользователю.
+	var he *tasks.HookError
+	if errors.As(err, &he) {
+		http.Error(w, he.Err.Error(), http.StatusForbidden)
+		return
+	}
+	// Процесс пространства не разрешает такой смены статуса -- тоже 403 с объяснением.
+	var te *tasks.TransitionError
+	if errors.As(err, &te) {
+		http.Error(w, fmt.Sprintf("Status %s -> %s is not allowed by the workflow", te.From, te.To), http.StatusForbidden)
+		return
+	}
+	log.Printf("request_id=%s caldav error: %v", appMiddleware.GetRequestID(r.Context()), err)
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+func itemName(t *tasks.Task) string {
+	if t.UUID != "" {
+		return t.UUID
+	}
+	return strconv.Itoa(t.ID)
+}
+
+func itemHref(t *tasks.Task) string {
+	return collectionPath + itemName(t) + ".ics"
+}
+
+// etag -- хэш содержимого задачи: меняется при любом изменении полей.
+func etag(t *tasks.Task) string {
+	data, _ := json.Marshal(t)
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// collectionProp -- свойства коллекции. CTag меняется при изменении любой задачи,
+// по нему клиенты понимают, что пора пересинхронизироваться.
+func collectionProp(list []tasks.Task) prop {
+	h := sha1.New()
+	for i := range list {
+		fmt.Fprint(h, etag(&list[i]))
+	}
+	ctag := `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+
+	return prop{
+		ResourceType: &resourceType{Collection: &empty{}, Calendar: &empty{}},
+		DisplayName:  "Задачи",
+		SupportedComponents: &componentSet{
+			Comp: []component{{Name: "VTODO"}},
+		},
+		GetCTag: ctag,
+		GetETag: ctag,
+	}
+}
@@ -0,0 +1,99 @@
+package caldav_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"task-manager/internal/caldav"
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// countingStore считает проверки пароля: каждая начинается с поиска пользователя по логину.
+type countingStore struct {
+	*tasks.MemoryStore
+	lookups atomic.Int32
+}
+
+func (s *countingStore) GetUserByUsername(ctx context.Context, username string) (*tasks.User, error) {
+	s.lookups.Add(1)
+	return s.MemoryStore.GetUserByUsername(ctx, username)
+}
+
+func newCalDAV(t *testing.T) (*httptest.Server, *countingStore) {
+	t.Helper()
+	mem := tasks.NewMemoryStore()
+	if err := taskstest.Seed(context.Background(), mem, taskstest.Users(), nil); err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStore{MemoryStore: mem}
+	h := caldav.NewHandler(tasks.NewService(store))
+	h.SetAuthGuard(appMiddleware.NewAuthGuard(appMiddleware.AuthGuardConfig{
+		MaxFailures:  3,
+		BaseCooldown: time.Minute,
+		MaxCooldown:  time.Hour,
+	}))
+	r := chi.NewRouter()
+	h.Mount(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func propfind(t *testing.T, srv *httptest.Server, username, password string) int {
+	t.Helper()
+	req, err := http.NewRequest("PROPFIND", srv.URL+"/caldav/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// TestBasicAuthCache -- повторные запросы с тем же паролем не проверяют его заново,
+// а неудачи идут в AuthGuard и блокируют логин даже для закэшированного пароля.
+func TestBasicAuthCache(t *testing.T) {
+	srv, store := newCalDAV(t)
+
+	for range 5 {
+		if code := propfind(t, srv, "mom", taskstest.Password); code != http.StatusMultiStatus {
+			t.Fatalf("PROPFIND with valid password: %d", code)
+		}
+	}
+	if n := store.lookups.Load(); n != 1 {
+		t.Errorf("password checked %d times for 5 requests, want 1", n)
+	}
+
+	// Неверный пароль не кэшируется: каждая попытка проверяется и считается.
+	for range 3 {
+		if code := propfind(t, srv, "mom", "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("PROPFIND with wrong password: %d", code)
+		}
+	}
+	if n := store.lookups.Load(); n != 4 {
+		t.Errorf("password checked %d times, want 4", n)
+	}
+	if code := propfind(t, srv, "mom", taskstest.Password); code != http.StatusTooManyRequests {
+		t.Errorf("locked out user with cached password: %d, want 429", code)
+	}
+
+	// Другой пользователь не задет и проверяется сам по себе.
+	if code := propfind(t, srv, "dad", taskstest.Password); code != http.StatusMultiStatus {
+		t.Errorf("other user: %d", code)
+	}
+	if code := propfind(t, srv, "dad", "password2"); code != http.StatusUnauthorized {
+		t.Errorf("other user, wrong password: %d", code)
+	}
+}
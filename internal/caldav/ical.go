@@ -0,0 +1,339 @@
+package caldav
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/tasks"
+)
+
+// Форматы дат iCalendar (RFC 5545).
+const (
+	icalUTC      = "20060102T150405Z"
+	icalFloating = "20060102T150405"
+	icalDate     = "20060102"
+)
+
+var errNoVTodo = errors.New("calendar object does not contain a VTODO")
+
+// vtodo -- распарсенные свойства VTODO, которые мы умеем хранить.
+type vtodo struct {
+	UID         string
+	Summary     string
+	Description string
+	Status      string
+	Priority    int
+	Due         *time.Time
+	Categories  []string
+}
+
+// taskUID возвращает UID задачи для iCalendar.
+// Задачи, пришедшие через CalDAV/импорт, сохраняют свой UID в Task.UUID.
+func taskUID(t *tasks.Task) string {
+	if t.UUID != "" {
+		return t.UUID
+	}
+	return fmt.Sprintf("task-%d@task-manager", t.ID)
+}
+
+// encodeVTodo сериализует задачу в VCALENDAR с одним VTODO.
+func encodeVTodo(t *tasks.Task) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(fold(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//task-manager//CalDAV//RU")
+	line("BEGIN:VTODO")
+	line("UID:" + escapeText(taskUID(t)))
+	line("DTSTAMP:" + time.Now().UTC().Format(icalUTC))
+	line("SUMMARY:" + escapeText(t.Title))
+	if t.Description != "" {
+		line("DESCRIPTION:" + escapeText(t.Description))
+	}
+	switch {
+	case t.Done:
+		line("STATUS:COMPLETED")
+	case tasks.StatusCategory(t.Status) == tasks.StatusInProgress:
+		line("STATUS:IN-PROCESS")
+	default:
+		line("STATUS:NEEDS-ACTION")
+	}
+	line("PRIORITY:" + strconv.Itoa(icalPriority(t.Priority)))
+	if t.Due != nil {
+		line("DUE:" + t.Due.UTC().Format(icalUTC))
+	}
+	if len(t.Tags) > 0 {
+		escaped := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			escaped[i] = escapeText(tag)
+		}
+		line("CATEGORIES:" + strings.Join(escaped, ","))
+	}
+	line("END:VTODO")
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// decodeVTodo разбирает первый VTODO из iCalendar-объекта.
+// Неизвестные свойства и компоненты (VALARM, VTIMEZONE) игнорируются.
+func decodeVTodo(r io.Reader) (*vtodo, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		v      vtodo
+		inTodo bool
+		found  bool
+		depth  int // Вложенные компоненты внутри VTODO (например, VALARM)
+	)
+
+	for _, l := range lines {
+		name, params, value, ok := splitProperty(l)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VTODO" && !found:
+			inTodo, found = true, true
+			continue
+		case name == "END" && value == "VTODO" && inTodo && depth == 0:
+			inTodo = false
+			continue
+		case !inTodo:
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			v.UID = unescapeText(value)
+		case "SUMMARY":
+			v.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			v.Description = unescapeText(value)
+		case "STATUS":
+			v.Status = strings.ToUpper(value)
+		case "PRIORITY":
+			v.Priority, _ = strconv.Atoi(value)
+		case "DUE":
+			due, err := parseICalTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("DUE: %w", err)
+			}
+			v.Due = &due
+		case "CATEGORIES":
+			for _, c := range splitEscaped(value) {
+				if c = strings.TrimSpace(unescapeText(c)); c != "" {
+					v.Categories = append(v.Categories, c)
+				}
+			}
+		}
+	}
+
+	if !found {
+		return nil, errNoVTodo
+	}
+	return &v, nil
+}
+
+// apply переносит свойства VTODO в задачу.
+func (v *vtodo) apply(t *tasks.Task) {
+	t.Title = tasks.NormalizeText(v.Summary)
+	t.Description = v.Description
+	if t.Title == "" {
+		t.Title = "(без названия)"
+	}
+	status := tasks.StatusTodo
+	switch v.Status {
+	case "COMPLETED":
+		status = tasks.StatusDone
+	case "IN-PROCESS":
+		status = tasks.StatusInProgress
+	}
+	// У iCalendar только категории статусов: свой статус той же категории ("review") не трогаем.
+	if tasks.StatusCategory(t.Status) != status {
+		t.Status = status
+	}
+	t.Done = status == tasks.StatusDone
+	t.Priority = taskPriority(v.Priority)
+	t.Due = v.Due
+	t.Tags = v.Categories
+}
+
+// icalPriority: 1-4 -- высокий, 5 -- средний, 6-9 -- низкий (RFC 5545, 3.8.1.9).
+// critical -- самый высокий (1), high -- остальная "высокая" часть шкалы.
+// Для своей шкалы приоритетов -- по классу уровня; обратно -- встроенный уровень, его переводит Service.
+func icalPriority(p tasks.Priority) int {
+	switch p.Class() {
+	case tasks.PriorityCritical:
+		return 1
+	case tasks.PriorityHigh:
+		return 3
+	case tasks.PriorityLow:
+		return 9
+	default:
+		return 5
+	}
+}
+
+func taskPriority(p int) tasks.Priority {
+	switch {
+	case p >= 1 && p <= 2:
+		return tasks.PriorityCritical
+	case p >= 3 && p <= 4:
+		return tasks.PriorityHigh
+	case p >= 6:
+		return tasks.PriorityLow
+	default:
+		return tasks.PriorityMedium
+	}
+}
+
+// parseICalTime понимает UTC, "плавающее" время, TZID и VALUE=DATE.
+func parseICalTime(value string, params map[string]string) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalUTC, value)
+	}
+	if params["VALUE"] == "DATE" || len(value) == len(icalDate) {
+		return time.Parse(icalDate, value)
+	}
+
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(icalFloating, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// unfold читает строки iCalendar и склеивает "свёрнутые" продолжения (строки, начинающиеся с пробела/таба).
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var lines []string
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, sc.Err()
+}
+
+// splitProperty разбирает строку вида NAME;PARAM=V;PARAM2=V2:VALUE.
+func splitProperty(l string) (name string, params map[string]string, value string, ok bool) {
+	// Двоеточие внутри кавычек параметров не является разделителем.
+	inQuotes := false
+	colon := -1
+	for i, r := range l {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	head := strings.Split(l[:colon], ";")
+	name = strings.ToUpper(head[0])
+	params = make(map[string]string, len(head)-1)
+	for _, p := range head[1:] {
+		if k, v, found := strings.Cut(p, "="); found {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return name, params, l[colon+1:], true
+}
+
+// fold сворачивает строку длиннее 75 октетов (RFC 5545, 3.1), не разрывая UTF-8 символы.
+func fold(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitEscaped делит список по запятым, не трогая экранированные "\,".
+func splitEscaped(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
@@ -0,0 +1,91 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+// Структуры multistatus-ответа WebDAV. Префиксы пространств имён заданы прямо в тегах,
+// а сами пространства объявлены атрибутами корня -- так encoding/xml выдаёт привычный клиентам XML.
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	XmlnsD    string     `xml:"xmlns:D,attr"`
+	XmlnsC    string     `xml:"xmlns:C,attr"`
+	XmlnsCS   string     `xml:"xmlns:CS,attr"`
+	Responses []response `xml:"D:response"`
+}
+
+type response struct {
+	Href     string    `xml:"D:href"`
+	Propstat *propstat `xml:"D:propstat,omitempty"`
+	Status   string    `xml:"D:status,omitempty"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"D:prop"`
+	Status string `xml:"D:status"`
+}
+
+type prop struct {
+	ResourceType         *resourceType `xml:"D:resourcetype,omitempty"`
+	DisplayName          string        `xml:"D:displayname,omitempty"`
+	CurrentUserPrincipal *href         `xml:"D:current-user-principal,omitempty"`
+	PrincipalURL         *href         `xml:"D:principal-URL,omitempty"`
+	CalendarHomeSet      *href         `xml:"C:calendar-home-set,omitempty"`
+	SupportedComponents  *componentSet `xml:"C:supported-calendar-component-set,omitempty"`
+	GetCTag              string        `xml:"CS:getctag,omitempty"`
+	GetETag              string        `xml:"D:getetag,omitempty"`
+	GetContentType       string        `xml:"D:getcontenttype,omitempty"`
+	CalendarData         string        `xml:"C:calendar-data,omitempty"`
+}
+
+type resourceType struct {
+	Collection *empty `xml:"D:collection,omitempty"`
+	Calendar   *empty `xml:"C:calendar,omitempty"`
+}
+
+type empty struct{}
+
+type href struct {
+	Href string `xml:"D:href"`
+}
+
+type componentSet struct {
+	Comp []component `xml:"C:comp"`
+}
+
+type component struct {
+	Name string `xml:"name,attr"`
+}
+
+func newMultistatus() *multistatus {
+	return &multistatus{
+		XmlnsD:  "DAV:",
+		XmlnsC:  "urn:ietf:params:xml:ns:caldav",
+		XmlnsCS: "http://calendarserver.org/ns/",
+	}
+}
+
+func (ms *multistatus) add(path string, p prop) {
+	ms.Responses = append(ms.Responses, response{
+		Href:     path,
+		Propstat: &propstat{Prop: p, Status: statusLine(http.StatusOK)},
+	})
+}
+
+func (ms *multistatus) addStatus(path string, code int) {
+	ms.Responses = append(ms.Responses, response{Href: path, Status: statusLine(code)})
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// writeMultistatus отдаёт 207 Multi-Status.
+func writeMultistatus(w http.ResponseWriter, ms *multistatus) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(ms)
+}
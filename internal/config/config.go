@@ -3,28 +3,383 @@
 package config
 
 import (
+	"cmp"
+	_ "embed"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/logging"
+	"task-manager/internal/secrets"
 )
 
+// AdminAddrOff -- значение ADMIN_ADDR, выключающее служебные маршруты.
+const AdminAddrOff = "off"
+
 // Config содержит базовые настройки приложения
 type Config struct {
 	Port        string
 	StoragePath string
 
+	// StorageGit включает git-историю для JSON-хранилища:
+	// каждая мутация коммитится в локальный репозиторий рядом с файлом задач.
+	StorageGit bool
+	// StorageCompactJSON -- писать файл задач JSON-хранилища без отступов (меньше и быстрее, хуже читается).
+	// StorageCompactOnStart -- сжать хранилище при запуске (см. POST /api/v1/admin/storage/compact).
+	StorageCompactJSON    bool
+	StorageCompactOnStart bool
+	// StorageEncoding -- формат файла задач: pretty, compact или gzip. Не задан -- gzip для пути *.gz,
+	// compact при STORAGE_COMPACT_JSON, иначе pretty. Читается файл в любом формате.
+	StorageEncoding string
+	// StorageAlertUsers -- ID пользователей через запятую, которых уведомлять, когда диск перестал
+	// принимать запись (место, права, read-only ФС). Пусто -- уведомление без адресата (в лог).
+	StorageAlertUsers string
+
+	// FailoverReplicaPath включает резервирование PostgreSQL: снимок задач и пользователей
+	// периодически пишется в этот JSON-файл, и при недоступности БД сервер читает из него
+	// (изменения отклоняются с 503). FailoverCheckInterval -- как часто проверяется связь с БД,
+	// FailoverSyncInterval -- как часто обновляется снимок.
+	FailoverReplicaPath   string
+	FailoverCheckInterval time.Duration
+	FailoverSyncInterval  time.Duration
+
+	// CalDAVEnabled публикует задачи как VTODO по CalDAV (/caldav, Basic-авторизация).
+	CalDAVEnabled bool
+
+	// Коннекторы импорта из внешних систем (POST /api/v1/tasks/import?format=trello|jira).
+	TrelloAPIKey string
+	TrelloToken  string
+	JiraBaseURL  string
+	JiraEmail    string
+	JiraAPIToken string
+	// ImportStatusMap -- явный маппинг списков/статусов в наш статус: "Done=done;Doing=in_progress".
+	ImportStatusMap string
+
+	// CustomFields -- пользовательские поля задач: "customer:string;ticket:number;billable:bool;signed:date".
+	CustomFields string
+
+	// Ограничения входных данных задач; из них при запуске собирается валидатор. Длины -- в символах.
+	// TaskPriorities -- начальная шкала приоритетов, пока её не сохранили через API: уровни от низшего
+	// к высшему через запятую, у своих уровней через ":" -- класс ("p4:low,p3:low,p2:medium,p1:high,p0:critical").
+	TitleMaxLength       int
+	DescriptionMaxLength int
+	TagsMax              int
+	TagMaxLength         int
+	TaskPriorities       string
+
+	// IntegrationAPIKeys -- ключи для /api/v1/integrations (Zapier/IFTTT): "ключ=ID_пользователя,...".
+	IntegrationAPIKeys string
+
+	// AnonymousRoutes -- маршруты API, доступные без токена только на чтение (табло-киоск):
+	// "GET /api/v1/tasks,GET /api/v1/agenda". Выполняются от имени пользователя AnonymousUserID.
+	AnonymousRoutes string
+	AnonymousUserID int
+	// PublicCacheTTL -- сколько помнить ответ публичной доски и анонимного (киоск) запроса; 0 -- не кэшировать.
+	PublicCacheTTL time.Duration
+
+	// Внешний движок политик (internal/authz): AuthzEngine -- "" (нет), opa или casbin.
+	// AuthzOPAURL -- документ решения OPA, AuthzPolicyFile -- файл политики casbin.
+	// AuthzDryRun -- не отказывать, а писать в лог, кому было бы отказано.
+	AuthzEngine     string
+	AuthzOPAURL     string
+	AuthzPolicyFile string
+	AuthzWorkspace  string
+	AuthzCacheTTL   time.Duration
+	AuthzDryRun     bool
+
+	// WorkspaceAdmins -- ID администраторов пространства через запятую ("1,2"): они меняют процесс
+	// (статусы и переходы, /api/v1/workspaces/{id}/workflow). ID пространства -- AuthzWorkspace.
+	WorkspaceAdmins string
+
+	// AuthProviders -- источники учётных записей по порядку проверки: "local" (свои пользователи)
+	// и "ldap" (каталог LDAP/Active Directory), например "ldap,local".
+	AuthProviders string
+
+	// Вход через LDAP (AUTH_PROVIDERS с ldap). В фильтрах {username} -- логин, {dn} -- DN пользователя.
+	// LDAPGroupRoles -- роли по группам: "parent=CN=Parents,OU=Groups,DC=corp,DC=local;child=Kids".
+	LDAPURL          string
+	LDAPStartTLS     bool
+	LDAPCAFile       string
+	LDAPBindDN       string
+	LDAPBindPassword string
+	LDAPUserBase     string
+	LDAPUserFilter   string
+	LDAPGroupAttr    string
+	LDAPGroupBase    string
+	LDAPGroupFilter  string
+	LDAPGroupRoles   string
+	LDAPRequireRole  bool
+	LDAPTimeout      time.Duration
+
+	// Пароли локальных пользователей: политика для новых паролей, стоимость argon2id
+	// (память в КиБ) и сброс по почте (PasswordResetURL -- ссылка в письме, {token} -- токен).
+	PasswordMinLength       int
+	PasswordMaxLength       int
+	PasswordMinClasses      int
+	PasswordRejectCommon    bool
+	PasswordHashMemory      int
+	PasswordHashIterations  int
+	PasswordHashParallelism int
+	PasswordResetTTL        time.Duration
+	PasswordResetURL        string
+
+	// SCIMEnabled -- корпоративный провижининг пользователей по SCIM 2.0 (/scim/v2, токен SCIM_TOKEN
+	// из провайдера секретов). Нужен PostgreSQL: файловое хранилище пользователей не хранит.
+	SCIMEnabled bool
+
+	// Почта для отчётов по расписанию (/api/v1/admin/reports). SMTPAddr пустой -- почта выключена.
+	SMTPAddr     string // host:port
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// PublicURL -- внешний адрес сервера для ссылок в письмах (https://tasks.example.com).
+	// ActionLinkTTL -- срок жизни подписанных ссылок действий ("выполнено", "отложить").
+	// NotifyEmail -- отправлять уведомления письмом (нужен SMTP_ADDR), а не в лог.
+	PublicURL     string
+	ActionLinkTTL time.Duration
+	NotifyEmail   bool
+
+	// PDFFont -- TrueType-шрифт для PDF-распечаток. Пусто -- ищем DejaVu/Liberation/Noto в системе.
+	PDFFont string
+
+	// Ограничения одного запуска скрипта на событиях задач (/api/v1/admin/scripts).
+	ScriptTimeout   time.Duration
+	ScriptMaxSteps  int
+	ScriptMaxMemory int // байты
+
+	// ChangesMaxWait -- предел ожидания в GET /api/v1/changes?wait=... (long polling).
+	// WriteTimeout сервера main поднимает выше него, иначе долгий ответ оборвётся.
+	ChangesMaxWait time.Duration
+
+	// SyncConflictStrategy -- стратегия конфликтов POST /api/v1/sync, когда клиент её не указал:
+	// server_wins, client_wins, last_write_wins или manual (очередь /api/v1/conflicts).
+	SyncConflictStrategy string
+
+	// Метрики Prometheus на /metrics.
+	MetricsEnabled bool
+	// MetricsRefreshInterval -- как часто пересчитываются gauge по задачам и burn rate SLO.
+	MetricsRefreshInterval time.Duration
+	// SLOTarget -- целевая доля "хороших" запросов (0.99 = 99%).
+	SLOTarget float64
+	// SLOLatency -- запрос дольше этого порога считается плохим, даже если он успешен.
+	SLOLatency time.Duration
+
+	// RulesInterval -- как часто проверяются правила эскалации (/api/v1/rules); 0 -- не проверять.
+	RulesInterval time.Duration
+	// SchedulesInterval -- как часто проверяются расписания (/api/v1/schedules); 0 -- не проверять.
+	SchedulesInterval time.Duration
+	// RetentionInterval -- как часто уборщик чистит записи по политике хранения
+	// (/api/v1/admin/retention); 0 -- не чистить.
+	RetentionInterval time.Duration
+
+	// Мягкие квоты (/api/v1/admin/usage): пороги числа задач и места в хранилище (0 -- без порога),
+	// как часто их проверять (0 -- только по запросу) и куда слать события о пересечении порога.
+	QuotaWarnTasks     int
+	QuotaWarnStorageMB int
+	QuotaWebhookURL    string
+	UsageCheckInterval time.Duration
+
+	// AdminAddr -- отдельный служебный адрес (":9090", "127.0.0.1:9090") для /metrics, /debug/pprof
+	// и /api/v1/admin: на публичном порту их тогда нет. Пусто -- всё на публичном порту (без /debug),
+	// AdminAddrOff -- служебные маршруты не раздаются вовсе.
+	AdminAddr string
+
+	// ReusePort открывает сокет с SO_REUSEPORT: несколько экземпляров могут слушать один порт
+	// (поднять новый, потом погасить старый). Перезапуск по SIGHUP работает и без этого.
+	ReusePort bool
+
+	// Ограничение параллельных изменяющих запросов (backpressure).
+	// MutationWorkers = 0 -- без ограничения.
+	MutationWorkers   int
+	MutationQueue     int
+	MutationQueueWait time.Duration
+
+	// Ограничения публичного порта для маленьких серверов (0 -- без ограничения):
+	// MaxConnections -- открытых соединений (лишние ждут приёма), MaxConcurrentRequests --
+	// запросов одновременно (лишние получают 503). Нагрузка видна в /api/v1/admin/stats.
+	MaxConnections        int
+	MaxConcurrentRequests int
+
+	// Исходящие запросы к интеграциям (internal/httpclient): OutboundProxy -- прокси для всех
+	// (пусто -- HTTPS_PROXY/HTTP_PROXY/NO_PROXY), OutboundRateLimits -- "jira=5,trello=10",
+	// запросов в секунду на интеграцию вместо встроенных значений. OutboundAllowPrivate -- адреса
+	// и сети внутренней сети ("10.0.0.0/8,192.168.1.5"), куда можно webhook автоматизаций и отчётов.
+	OutboundProxy        string
+	OutboundRateLimits   string
+	OutboundAllowPrivate string
+
+	// AdminKey -- ключ служебного API /api/v1/admin (заголовок X-Admin-Key). Пусто -- API выключен.
+	AdminKey string
+	// JWTSecret -- ключ подписи JWT. Как и AdminKey, после старта main перечитывает его у провайдера секретов.
+	JWTSecret string
+
+	// Провайдер секретов (env, file, vault, aws) и его параметры, см. internal/secrets.
+	// Секреты перечитываются каждые SecretsRefreshInterval; после ротации прежнее значение
+	// принимается ещё SecretsRotationGrace (по умолчанию -- срок жизни JWT).
+	SecretsProvider        string
+	SecretsDir             string
+	VaultAddr              string
+	VaultToken             string
+	VaultSecretPath        string
+	AWSRegion              string
+	AWSSecretID            string
+	AWSAccessKeyID         string
+	AWSSecretAccessKey     string
+	AWSSessionToken        string
+	SecretsRefreshInterval time.Duration
+	SecretsRotationGrace   time.Duration
+	// MaintenanceMode -- стартовать сразу в режиме обслуживания (только чтение).
+	MaintenanceMode bool
+	// ReadOnly -- стартовать в режиме только для чтения (публичное зеркало, демо): изменения -- 405.
+	ReadOnly bool
+
+	// ChaosEnabled включает хаос для демонстраций устойчивости: задержки и ошибки по правилам
+	// ChaosRules ("GET /api/v1/tasks=latency:100ms-800ms,error:5%", см. middleware.ParseChaosRules)
+	// и параметр ?delay=. Только для демо и стендов, по умолчанию выключен.
+	ChaosEnabled bool
+	ChaosRules   string
+
+	// Логи приложения: stderr, stdout, file (с ротацией по размеру и/или времени) или syslog;
+	// формат text или json. Не заданы -- в терминале stderr и text, иначе (контейнер, systemd)
+	// stdout и json: сборщику логов не нужно разбирать текст.
+	LogOutput     string
+	LogFormat     string
+	LogFile       string
+	LogMaxSizeMB  int
+	LogMaxAge     time.Duration
+	LogMaxBackups int
+	SyslogAddr    string
+	SyslogTag     string
+
+	// Журнал доступа (отдельно от логов приложения): формат json, common или combined
+	// (пусто -- выключен), файл ("" -- stdout) и ротация по размеру и/или времени.
+	AccessLogFormat     string
+	AccessLogFile       string
+	AccessLogMaxSizeMB  int
+	AccessLogMaxAge     time.Duration
+	AccessLogMaxBackups int
+
+	// TrustedProxies -- адреса и подсети обратных прокси (через запятую), которым можно верить
+	// в X-Forwarded-For/X-Real-IP. Пусто -- адрес клиента берётся из соединения.
+	TrustedProxies string
+
+	// Блокировка перебора паролей: после AuthMaxFailures неудач подряд на логин (AuthMaxFailuresIP -- на адрес)
+	// вход блокируется на AuthLockoutBase, каждая следующая блокировка -- вдвое дольше, до AuthLockoutMax.
+	// 0 в пороге выключает счётчик.
+	AuthMaxFailures   int
+	AuthMaxFailuresIP int
+	AuthLockoutBase   time.Duration
+	AuthLockoutMax    time.Duration
+
+	// Демо-режим (--demo): сколько задач сгенерировать и seed генератора (0 -- новый при каждом запуске).
+	DemoTasks int
+	DemoSeed  int
+
+	// Форма JSON-ответов API: именование полей (snake/camel) и конверт {"data", "meta"}.
+	// Клиент может переопределить их заголовками X-Response-Naming и X-Response-Envelope.
+	ResponseNaming   string
+	ResponseEnvelope bool
+	// PriorityNumeric -- отдавать приоритет числом 1..4 (low..critical), заголовок X-Priority-Format переопределяет.
+	PriorityNumeric bool
+
+	// Остановка сервера. ShutdownTimeout (мягкий) -- сколько ждём, пока принятые запросы доделаются сами;
+	// после него контексты запросов отменяются, и ещё ShutdownHardTimeout ждём, пока они это заметят.
+	ShutdownTimeout     time.Duration
+	ShutdownHardTimeout time.Duration
+	// ShutdownDelay -- сколько после SIGTERM ещё обслуживать запросы с /readyz = 503 (0 -- не ждать):
+	// балансировщик (Kubernetes, облачный LB) успевает вывести экземпляр, и новые запросы не получают 503.
+	ShutdownDelay time.Duration
+
+	// SelfTestClockURL -- эталон времени для самопроверки (берётся заголовок Date ответа).
+	// Если не задан, часы сверяются с PostgreSQL, а для файлового хранилища проверка пропускается.
+	SelfTestClockURL string
+	// MaxClockSkew -- допустимое расхождение часов.
+	MaxClockSkew time.Duration
+
 	// Поля для SQL:
 	DBHost     string
 	DBPort     int
 	DBUser     string
 	DBPassword string
 	DBName     string
+	// DBMigrate -- применить при старте встроенные миграции (migrations/*.up.sql), которых ещё нет в базе.
+	DBMigrate bool
+
+	// UIEnabled -- раздавать встроенный веб-интерфейс на /ui/. AssetsDir -- брать интерфейс и миграции
+	// не из бинарника, а из каталога (подкаталоги web и migrations, как после --extract-assets).
+	UIEnabled bool
+	AssetsDir string
+}
+
+// Example -- пример файла настроек (для --extract-assets и --config).
+//
+//go:embed example.env
+var Example []byte
+
+// Logging возвращает назначение логов приложения.
+func (cfg *Config) Logging() logging.Config {
+	return logging.Config{
+		Output:     cfg.LogOutput,
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxAge:     cfg.LogMaxAge,
+		MaxBackups: cfg.LogMaxBackups,
+		SyslogAddr: cfg.SyslogAddr,
+		SyslogTag:  cfg.SyslogTag,
+		Format:     cfg.LogFormat,
+	}
+}
+
+// AccessLog возвращает назначение журнала доступа: файл с ротацией или stdout.
+func (cfg *Config) AccessLog() logging.Config {
+	if cfg.AccessLogFile == "" {
+		return logging.Config{Output: "stdout"}
+	}
+	return logging.Config{
+		Output:     "file",
+		File:       cfg.AccessLogFile,
+		MaxSizeMB:  cfg.AccessLogMaxSizeMB,
+		MaxAge:     cfg.AccessLogMaxAge,
+		MaxBackups: cfg.AccessLogMaxBackups,
+	}
+}
+
+// Secrets возвращает параметры провайдера секретов.
+func (cfg *Config) Secrets() secrets.Config {
+	return secrets.Config{
+		Provider:           cfg.SecretsProvider,
+		Dir:                cfg.SecretsDir,
+		VaultAddr:          cfg.VaultAddr,
+		VaultToken:         cfg.VaultToken,
+		VaultPath:          cfg.VaultSecretPath,
+		AWSRegion:          cfg.AWSRegion,
+		AWSSecretID:        cfg.AWSSecretID,
+		AWSAccessKeyID:     cfg.AWSAccessKeyID,
+		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+		AWSSessionToken:    cfg.AWSSessionToken,
+	}
 }
 
 // DSN возвращает строку подключения к PostgreSQL.
 func (cfg *Config) DSN() string {
+	return cfg.dsn(cfg.DBPassword)
+}
+
+// RedactedDSN -- строка подключения для логов: пароль заменён звёздочками.
+func (cfg *Config) RedactedDSN() string {
+	return cfg.dsn("***")
+}
+
+func (cfg *Config) dsn(password string) string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, password, cfg.DBName)
 }
 
 // Load считывает конфигурацию.
@@ -38,9 +393,88 @@ func Load() *Config {
 		DBPort: 5432,
 		DBUser: "postgres",
 		DBName: "taskmanager",
+
+		MetricsEnabled:         true,
+		UIEnabled:              true,
+		MetricsRefreshInterval: 30 * time.Second,
+		SLOTarget:              0.99,
+		SLOLatency:             500 * time.Millisecond,
+
+		RulesInterval:     time.Minute,
+		SchedulesInterval: 30 * time.Second,
+		RetentionInterval: time.Hour,
+
+		UsageCheckInterval: 5 * time.Minute,
+
+		TitleMaxLength:       100,
+		DescriptionMaxLength: 10000,
+		TagsMax:              20,
+		TagMaxLength:         50,
+		TaskPriorities:       "low,medium,high,critical",
+
+		SMTPFrom:      "task-manager@localhost",
+		ActionLinkTTL: 72 * time.Hour,
+
+		ScriptTimeout:   100 * time.Millisecond,
+		ScriptMaxSteps:  100_000,
+		ScriptMaxMemory: 1 << 20,
+
+		ChangesMaxWait:       30 * time.Second,
+		SyncConflictStrategy: "server_wins",
+
+		PublicCacheTTL: 5 * time.Second,
+		AuthzWorkspace: "default",
+		AuthzCacheTTL:  30 * time.Second,
+		AuthProviders:  "local",
+		LDAPUserFilter: "(uid={username})",
+		LDAPGroupAttr:  "memberOf",
+		LDAPTimeout:    5 * time.Second,
+
+		PasswordMinLength:       8,
+		PasswordMaxLength:       128,
+		PasswordMinClasses:      2,
+		PasswordRejectCommon:    true,
+		PasswordHashMemory:      19 * 1024,
+		PasswordHashIterations:  2,
+		PasswordHashParallelism: 1,
+		PasswordResetTTL:        time.Hour,
+
+		MaxClockSkew: 30 * time.Second,
+
+		FailoverCheckInterval: 5 * time.Second,
+		FailoverSyncInterval:  time.Minute,
+
+		MutationWorkers:   8,
+		MutationQueue:     64,
+		MutationQueueWait: time.Second,
+
+		SecretsProvider:        "env",
+		SecretsDir:             "/run/secrets",
+		SecretsRefreshInterval: 5 * time.Minute,
+		SecretsRotationGrace:   24 * time.Hour,
+
+		LogMaxSizeMB:  100,
+		LogMaxBackups: 5,
+		SyslogTag:     "task-manager",
+
+		AccessLogMaxSizeMB:  100,
+		AccessLogMaxBackups: 5,
+
+		AuthMaxFailures:   5,
+		AuthMaxFailuresIP: 20,
+		AuthLockoutBase:   30 * time.Second,
+		AuthLockoutMax:    time.Hour,
+
+		DemoTasks: 40,
+
+		ResponseNaming: "snake",
+
+		ShutdownTimeout:     5 * time.Second,
+		ShutdownHardTimeout: 2 * time.Second,
 	}
 
-	if port := os.Getenv("HTTP_PORT"); port != "" {
+	// PORT -- общепринятая переменная платформ контейнеров (Heroku, Cloud Run); HTTP_PORT важнее.
+	if port := cmp.Or(os.Getenv("HTTP_PORT"), os.Getenv("PORT")); port != "" {
 		cfg.Port = port
 	}
 
@@ -48,6 +482,199 @@ func Load() *Config {
 		cfg.StoragePath = path
 	}
 
+	boolEnv("STORAGE_GIT", &cfg.StorageGit)
+	boolEnv("STORAGE_COMPACT_JSON", &cfg.StorageCompactJSON)
+	boolEnv("STORAGE_COMPACT_ON_START", &cfg.StorageCompactOnStart)
+	stringEnv("STORAGE_ENCODING", &cfg.StorageEncoding)
+	stringEnv("STORAGE_ALERT_USERS", &cfg.StorageAlertUsers)
+	if cfg.StorageEncoding == "" {
+		switch {
+		case strings.HasSuffix(cfg.StoragePath, ".gz"):
+			cfg.StorageEncoding = "gzip"
+		case cfg.StorageCompactJSON:
+			cfg.StorageEncoding = "compact"
+		default:
+			cfg.StorageEncoding = "pretty"
+		}
+	}
+	stringEnv("FAILOVER_REPLICA_PATH", &cfg.FailoverReplicaPath)
+	durationEnv("FAILOVER_CHECK_INTERVAL", &cfg.FailoverCheckInterval)
+	durationEnv("FAILOVER_SYNC_INTERVAL", &cfg.FailoverSyncInterval)
+	boolEnv("CALDAV_ENABLED", &cfg.CalDAVEnabled)
+
+	// Коннекторы импорта (Trello / Jira)
+	stringEnv("TRELLO_API_KEY", &cfg.TrelloAPIKey)
+	stringEnv("TRELLO_TOKEN", &cfg.TrelloToken)
+	stringEnv("JIRA_BASE_URL", &cfg.JiraBaseURL)
+	stringEnv("JIRA_EMAIL", &cfg.JiraEmail)
+	stringEnv("JIRA_API_TOKEN", &cfg.JiraAPIToken)
+	stringEnv("IMPORT_STATUS_MAP", &cfg.ImportStatusMap)
+	stringEnv("CUSTOM_FIELDS", &cfg.CustomFields)
+	intEnv("TITLE_MAX_LENGTH", &cfg.TitleMaxLength)
+	intEnv("DESCRIPTION_MAX_LENGTH", &cfg.DescriptionMaxLength)
+	intEnv("TAGS_MAX", &cfg.TagsMax)
+	intEnv("TAG_MAX_LENGTH", &cfg.TagMaxLength)
+	stringEnv("TASK_PRIORITIES", &cfg.TaskPriorities)
+	stringEnv("INTEGRATION_API_KEYS", &cfg.IntegrationAPIKeys)
+	stringEnv("AUTH_ANONYMOUS_ROUTES", &cfg.AnonymousRoutes)
+	intEnv("AUTH_ANONYMOUS_USER_ID", &cfg.AnonymousUserID)
+	durationEnv("PUBLIC_CACHE_TTL", &cfg.PublicCacheTTL)
+	stringEnv("AUTHZ_ENGINE", &cfg.AuthzEngine)
+	stringEnv("AUTHZ_OPA_URL", &cfg.AuthzOPAURL)
+	stringEnv("AUTHZ_POLICY_FILE", &cfg.AuthzPolicyFile)
+	stringEnv("AUTHZ_WORKSPACE", &cfg.AuthzWorkspace)
+	durationEnv("AUTHZ_CACHE_TTL", &cfg.AuthzCacheTTL)
+	boolEnv("AUTHZ_DRY_RUN", &cfg.AuthzDryRun)
+	stringEnv("WORKSPACE_ADMINS", &cfg.WorkspaceAdmins)
+	stringEnv("AUTH_PROVIDERS", &cfg.AuthProviders)
+	stringEnv("LDAP_URL", &cfg.LDAPURL)
+	boolEnv("LDAP_START_TLS", &cfg.LDAPStartTLS)
+	stringEnv("LDAP_CA_FILE", &cfg.LDAPCAFile)
+	stringEnv("LDAP_BIND_DN", &cfg.LDAPBindDN)
+	stringEnv("LDAP_BIND_PASSWORD", &cfg.LDAPBindPassword)
+	stringEnv("LDAP_USER_BASE", &cfg.LDAPUserBase)
+	stringEnv("LDAP_USER_FILTER", &cfg.LDAPUserFilter)
+	stringEnv("LDAP_GROUP_ATTR", &cfg.LDAPGroupAttr)
+	stringEnv("LDAP_GROUP_BASE", &cfg.LDAPGroupBase)
+	stringEnv("LDAP_GROUP_FILTER", &cfg.LDAPGroupFilter)
+	stringEnv("LDAP_GROUP_ROLES", &cfg.LDAPGroupRoles)
+	boolEnv("LDAP_REQUIRE_ROLE", &cfg.LDAPRequireRole)
+	durationEnv("LDAP_TIMEOUT", &cfg.LDAPTimeout)
+	intEnv("PASSWORD_MIN_LENGTH", &cfg.PasswordMinLength)
+	intEnv("PASSWORD_MAX_LENGTH", &cfg.PasswordMaxLength)
+	intEnv("PASSWORD_MIN_CLASSES", &cfg.PasswordMinClasses)
+	boolEnv("PASSWORD_REJECT_COMMON", &cfg.PasswordRejectCommon)
+	intEnv("PASSWORD_HASH_MEMORY_KB", &cfg.PasswordHashMemory)
+	intEnv("PASSWORD_HASH_ITERATIONS", &cfg.PasswordHashIterations)
+	intEnv("PASSWORD_HASH_PARALLELISM", &cfg.PasswordHashParallelism)
+	durationEnv("PASSWORD_RESET_TTL", &cfg.PasswordResetTTL)
+	stringEnv("PASSWORD_RESET_URL", &cfg.PasswordResetURL)
+	boolEnv("SCIM_ENABLED", &cfg.SCIMEnabled)
+	stringEnv("SMTP_ADDR", &cfg.SMTPAddr)
+	stringEnv("SMTP_FROM", &cfg.SMTPFrom)
+	stringEnv("SMTP_USERNAME", &cfg.SMTPUsername)
+	stringEnv("SMTP_PASSWORD", &cfg.SMTPPassword)
+	stringEnv("PUBLIC_URL", &cfg.PublicURL)
+	durationEnv("ACTION_LINK_TTL", &cfg.ActionLinkTTL)
+	boolEnv("NOTIFY_EMAIL", &cfg.NotifyEmail)
+	stringEnv("PDF_FONT", &cfg.PDFFont)
+	durationEnv("SCRIPT_TIMEOUT", &cfg.ScriptTimeout)
+	intEnv("SCRIPT_MAX_STEPS", &cfg.ScriptMaxSteps)
+	intEnv("SCRIPT_MAX_MEMORY", &cfg.ScriptMaxMemory)
+	durationEnv("CHANGES_MAX_WAIT", &cfg.ChangesMaxWait)
+	stringEnv("SYNC_CONFLICT_STRATEGY", &cfg.SyncConflictStrategy)
+
+	// Метрики и SLO
+	boolEnv("METRICS_ENABLED", &cfg.MetricsEnabled)
+	boolEnv("DB_MIGRATE", &cfg.DBMigrate)
+	boolEnv("UI_ENABLED", &cfg.UIEnabled)
+	stringEnv("ASSETS_DIR", &cfg.AssetsDir)
+	durationEnv("METRICS_REFRESH_INTERVAL", &cfg.MetricsRefreshInterval)
+	durationEnv("SLO_LATENCY", &cfg.SLOLatency)
+	intervalEnv("RULES_INTERVAL", &cfg.RulesInterval)
+	intervalEnv("SCHEDULES_INTERVAL", &cfg.SchedulesInterval)
+	intervalEnv("RETENTION_INTERVAL", &cfg.RetentionInterval)
+	intEnv("QUOTA_WARN_TASKS", &cfg.QuotaWarnTasks)
+	intEnv("QUOTA_WARN_STORAGE_MB", &cfg.QuotaWarnStorageMB)
+	stringEnv("QUOTA_WEBHOOK_URL", &cfg.QuotaWebhookURL)
+	intervalEnv("USAGE_CHECK_INTERVAL", &cfg.UsageCheckInterval)
+	if v := os.Getenv("SLO_TARGET"); v != "" {
+		if val, err := strconv.ParseFloat(v, 64); err == nil && val > 0 && val < 1 {
+			cfg.SLOTarget = val
+		}
+	}
+
+	boolEnv("REUSE_PORT", &cfg.ReusePort)
+	stringEnv("ADMIN_ADDR", &cfg.AdminAddr)
+
+	// Backpressure для изменяющих запросов
+	intEnv("MUTATION_WORKERS", &cfg.MutationWorkers)
+	intEnv("MUTATION_QUEUE", &cfg.MutationQueue)
+	durationEnv("MUTATION_QUEUE_WAIT", &cfg.MutationQueueWait)
+	intEnv("MAX_CONNECTIONS", &cfg.MaxConnections)
+	intEnv("MAX_CONCURRENT_REQUESTS", &cfg.MaxConcurrentRequests)
+	stringEnv("OUTBOUND_PROXY", &cfg.OutboundProxy)
+	stringEnv("OUTBOUND_RATE_LIMITS", &cfg.OutboundRateLimits)
+	stringEnv("OUTBOUND_ALLOW_PRIVATE", &cfg.OutboundAllowPrivate)
+
+	// Служебный API
+	stringEnv("ADMIN_KEY", &cfg.AdminKey)
+	stringEnv("JWT_SECRET", &cfg.JWTSecret)
+
+	// Провайдер секретов
+	stringEnv("SECRETS_PROVIDER", &cfg.SecretsProvider)
+	stringEnv("SECRETS_DIR", &cfg.SecretsDir)
+	stringEnv("VAULT_ADDR", &cfg.VaultAddr)
+	stringEnv("VAULT_TOKEN", &cfg.VaultToken)
+	stringEnv("VAULT_SECRET_PATH", &cfg.VaultSecretPath)
+	stringEnv("AWS_REGION", &cfg.AWSRegion)
+	stringEnv("AWS_SECRET_ID", &cfg.AWSSecretID)
+	stringEnv("AWS_ACCESS_KEY_ID", &cfg.AWSAccessKeyID)
+	stringEnv("AWS_SECRET_ACCESS_KEY", &cfg.AWSSecretAccessKey)
+	stringEnv("AWS_SESSION_TOKEN", &cfg.AWSSessionToken)
+	durationEnv("SECRETS_REFRESH_INTERVAL", &cfg.SecretsRefreshInterval)
+	durationEnv("SECRETS_ROTATION_GRACE", &cfg.SecretsRotationGrace)
+	boolEnv("MAINTENANCE_MODE", &cfg.MaintenanceMode)
+	boolEnv("READ_ONLY", &cfg.ReadOnly)
+	boolEnv("CHAOS_ENABLED", &cfg.ChaosEnabled)
+	stringEnv("CHAOS_RULES", &cfg.ChaosRules)
+
+	stringEnv("TRUSTED_PROXIES", &cfg.TrustedProxies)
+
+	// Логи приложения
+	stringEnv("LOG_OUTPUT", &cfg.LogOutput)
+	stringEnv("LOG_FORMAT", &cfg.LogFormat)
+	if cfg.LogOutput == "" {
+		cfg.LogOutput = "stderr"
+		if !logging.IsTerminal(os.Stdout) {
+			cfg.LogOutput = "stdout"
+		}
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "text"
+		if out := cfg.LogOutput; out == "stdout" && !logging.IsTerminal(os.Stdout) ||
+			out == "stderr" && !logging.IsTerminal(os.Stderr) {
+			cfg.LogFormat = "json"
+		}
+	}
+	stringEnv("LOG_FILE", &cfg.LogFile)
+	intEnv("LOG_MAX_SIZE_MB", &cfg.LogMaxSizeMB)
+	durationEnv("LOG_MAX_AGE", &cfg.LogMaxAge)
+	intEnv("LOG_MAX_BACKUPS", &cfg.LogMaxBackups)
+	stringEnv("SYSLOG_ADDR", &cfg.SyslogAddr)
+	stringEnv("SYSLOG_TAG", &cfg.SyslogTag)
+
+	// Журнал доступа
+	stringEnv("ACCESS_LOG_FORMAT", &cfg.AccessLogFormat)
+	stringEnv("ACCESS_LOG_FILE", &cfg.AccessLogFile)
+	intEnv("ACCESS_LOG_MAX_SIZE_MB", &cfg.AccessLogMaxSizeMB)
+	durationEnv("ACCESS_LOG_MAX_AGE", &cfg.AccessLogMaxAge)
+	intEnv("ACCESS_LOG_MAX_BACKUPS", &cfg.AccessLogMaxBackups)
+
+	// Блокировка перебора паролей
+	intEnv("AUTH_MAX_FAILURES", &cfg.AuthMaxFailures)
+	intEnv("AUTH_MAX_FAILURES_IP", &cfg.AuthMaxFailuresIP)
+	durationEnv("AUTH_LOCKOUT_BASE", &cfg.AuthLockoutBase)
+	durationEnv("AUTH_LOCKOUT_MAX", &cfg.AuthLockoutMax)
+
+	// Форма ответов API
+	stringEnv("RESPONSE_NAMING", &cfg.ResponseNaming)
+	boolEnv("RESPONSE_ENVELOPE", &cfg.ResponseEnvelope)
+	boolEnv("PRIORITY_NUMERIC", &cfg.PriorityNumeric)
+
+	// Остановка сервера
+	durationEnv("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	durationEnv("SHUTDOWN_HARD_TIMEOUT", &cfg.ShutdownHardTimeout)
+	intervalEnv("SHUTDOWN_DELAY", &cfg.ShutdownDelay)
+
+	// Демо-режим
+	intEnv("DEMO_TASKS", &cfg.DemoTasks)
+	intEnv("DEMO_SEED", &cfg.DemoSeed)
+
+	// Самопроверка при старте
+	stringEnv("SELFTEST_CLOCK_URL", &cfg.SelfTestClockURL)
+	durationEnv("MAX_CLOCK_SKEW", &cfg.MaxClockSkew)
+
 	// Считываем новые переменные для работы с PostgreSQL
 	if dbHost := os.Getenv("DB_HOST"); dbHost != "" {
 		cfg.DBHost = dbHost
@@ -74,3 +701,276 @@ func Load() *Config {
 
 	return cfg
 }
+
+// StorageAlertUserIDs разбирает STORAGE_ALERT_USERS ("1,2") в список ID.
+func (cfg *Config) StorageAlertUserIDs() ([]int, error) {
+	return parseUserIDs(cfg.StorageAlertUsers)
+}
+
+// WorkspaceAdminIDs разбирает WORKSPACE_ADMINS ("1,2") в список ID.
+func (cfg *Config) WorkspaceAdminIDs() ([]int, error) {
+	return parseUserIDs(cfg.WorkspaceAdmins)
+}
+
+// OutboundRateLimitMap разбирает OUTBOUND_RATE_LIMITS ("jira=5,trello=0.5") в имя интеграции -> запросов в секунду.
+func (cfg *Config) OutboundRateLimitMap() (map[string]float64, error) {
+	limits := make(map[string]float64)
+	for _, part := range strings.Split(cfg.OutboundRateLimits, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(part, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if !ok || strings.TrimSpace(name) == "" || err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid limit %q: want name=requests_per_second", part)
+		}
+		limits[strings.TrimSpace(name)] = rate
+	}
+	return limits, nil
+}
+
+// parseUserIDs разбирает список ID пользователей через запятую.
+func parseUserIDs(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid user ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AuthProviderList -- AUTH_PROVIDERS списком, без пробелов и повторов.
+func (cfg *Config) AuthProviderList() []string {
+	var out []string
+	for _, p := range strings.Split(cfg.AuthProviders, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Validate проверяет согласованность настроек (используется самопроверкой при старте).
+// Возвращает все найденные проблемы разом, чтобы не чинить их по одной.
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("HTTP_PORT: invalid port %q", cfg.Port))
+	}
+	if strings.TrimSpace(cfg.AnonymousRoutes) != "" && cfg.AnonymousUserID <= 0 {
+		errs = append(errs, errors.New("AUTH_ANONYMOUS_ROUTES needs AUTH_ANONYMOUS_USER_ID: anonymous requests act as that user"))
+	}
+	if cfg.MaxConnections < 0 {
+		errs = append(errs, fmt.Errorf("MAX_CONNECTIONS: want >= 0, got %d", cfg.MaxConnections))
+	}
+	if cfg.MaxConcurrentRequests < 0 {
+		errs = append(errs, fmt.Errorf("MAX_CONCURRENT_REQUESTS: want >= 0, got %d", cfg.MaxConcurrentRequests))
+	}
+	if cfg.PublicCacheTTL < 0 || cfg.PublicCacheTTL > 5*time.Minute {
+		errs = append(errs, fmt.Errorf("PUBLIC_CACHE_TTL: want 0..5m, got %s", cfg.PublicCacheTTL))
+	}
+	switch cfg.AuthzEngine {
+	case "":
+	case "opa":
+		if u, err := url.Parse(cfg.AuthzOPAURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("AUTHZ_OPA_URL: want http(s) URL of the decision document, got %q", cfg.AuthzOPAURL))
+		}
+	case "casbin":
+		if cfg.AuthzPolicyFile == "" {
+			errs = append(errs, errors.New("AUTHZ_POLICY_FILE is required for AUTHZ_ENGINE=casbin"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("AUTHZ_ENGINE: want opa or casbin, got %q", cfg.AuthzEngine))
+	}
+	providers := cfg.AuthProviderList()
+	if len(providers) == 0 {
+		errs = append(errs, errors.New("AUTH_PROVIDERS: at least one of local, ldap is required"))
+	}
+	for _, p := range providers {
+		switch p {
+		case "local":
+		case "ldap":
+			if u, err := url.Parse(cfg.LDAPURL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("LDAP_URL: want ldap:// or ldaps:// URL, got %q", cfg.LDAPURL))
+			}
+			if cfg.LDAPUserBase == "" {
+				errs = append(errs, errors.New("LDAP_USER_BASE is required for AUTH_PROVIDERS=ldap"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("AUTH_PROVIDERS: unknown provider %q (want local or ldap)", p))
+		}
+	}
+	if cfg.PasswordMinLength < 1 || cfg.PasswordMaxLength < cfg.PasswordMinLength {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_LENGTH/PASSWORD_MAX_LENGTH: want 1 <= min <= max, got %d and %d",
+			cfg.PasswordMinLength, cfg.PasswordMaxLength))
+	}
+	if cfg.PasswordMinClasses > 4 {
+		errs = append(errs, fmt.Errorf("PASSWORD_MIN_CLASSES: at most 4 classes (lower, upper, digit, other), got %d", cfg.PasswordMinClasses))
+	}
+	if cfg.PasswordHashIterations < 1 || cfg.PasswordHashParallelism < 1 || cfg.PasswordHashParallelism > 255 ||
+		cfg.PasswordHashMemory < 8*cfg.PasswordHashParallelism || cfg.PasswordHashMemory > 4<<20 {
+		errs = append(errs, fmt.Errorf("PASSWORD_HASH_*: want iterations >= 1, parallelism 1..255 and 8*parallelism <= memory <= 4194304 KiB, got t=%d p=%d m=%d",
+			cfg.PasswordHashIterations, cfg.PasswordHashParallelism, cfg.PasswordHashMemory))
+	}
+	if cfg.PasswordResetURL != "" && !strings.Contains(cfg.PasswordResetURL, "{token}") {
+		errs = append(errs, fmt.Errorf("PASSWORD_RESET_URL must contain {token}: %q", cfg.PasswordResetURL))
+	}
+	if cfg.PublicURL != "" {
+		if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("PUBLIC_URL: want http(s) URL of the server, got %q", cfg.PublicURL))
+		}
+	}
+	if cfg.ActionLinkTTL > 30*24*time.Hour {
+		errs = append(errs, fmt.Errorf("ACTION_LINK_TTL: at most 720h, got %s", cfg.ActionLinkTTL))
+	}
+	if cfg.TitleMaxLength < 1 || cfg.TitleMaxLength > 500 {
+		errs = append(errs, fmt.Errorf("TITLE_MAX_LENGTH: want 1..500, got %d", cfg.TitleMaxLength))
+	}
+	if cfg.DescriptionMaxLength < 0 || cfg.TagsMax < 0 || cfg.TagMaxLength < 1 {
+		errs = append(errs, fmt.Errorf("DESCRIPTION_MAX_LENGTH, TAGS_MAX and TAG_MAX_LENGTH must be positive, got %d, %d, %d",
+			cfg.DescriptionMaxLength, cfg.TagsMax, cfg.TagMaxLength))
+	}
+	levels := 0
+	for _, item := range strings.Split(cfg.TaskPriorities, ",") {
+		id, class, _ := strings.Cut(strings.ToLower(strings.TrimSpace(item)), ":")
+		if id == "" {
+			continue
+		}
+		if class == "" {
+			class = id
+		}
+		levels++
+		if !slices.Contains([]string{"low", "medium", "high", "critical"}, strings.TrimSpace(class)) {
+			errs = append(errs, fmt.Errorf("TASK_PRIORITIES: level %q needs a class low, medium, high or critical (%q)", id, item))
+		}
+	}
+	if levels < 2 {
+		errs = append(errs, fmt.Errorf("TASK_PRIORITIES needs at least 2 levels, got %q", cfg.TaskPriorities))
+	}
+	if cfg.NotifyEmail && cfg.SMTPAddr == "" {
+		errs = append(errs, errors.New("NOTIFY_EMAIL needs SMTP_ADDR"))
+	}
+	if cfg.AdminAddr != "" && cfg.AdminAddr != AdminAddrOff {
+		_, port, err := net.SplitHostPort(cfg.AdminAddr)
+		if n, perr := strconv.Atoi(port); err != nil || perr != nil || n < 1 || n > 65535 {
+			errs = append(errs, fmt.Errorf("ADMIN_ADDR: want host:port, :port or %q, got %q", AdminAddrOff, cfg.AdminAddr))
+		} else if port == cfg.Port {
+			errs = append(errs, fmt.Errorf("ADMIN_ADDR: port %s is the public HTTP_PORT", port))
+		}
+	}
+	if cfg.StoragePath == "" {
+		errs = append(errs, errors.New("STORAGE_PATH is empty"))
+	}
+	if cfg.StoragePath == "postgres" {
+		if cfg.DBHost == "" || cfg.DBUser == "" || cfg.DBName == "" {
+			errs = append(errs, errors.New("DB_HOST, DB_USER and DB_NAME are required for postgres storage"))
+		}
+		if cfg.StorageGit {
+			errs = append(errs, errors.New("STORAGE_GIT works only with JSON storage"))
+		}
+	} else if cfg.FailoverReplicaPath != "" {
+		errs = append(errs, errors.New("FAILOVER_REPLICA_PATH works only with postgres storage"))
+	}
+	switch cfg.StorageEncoding {
+	case "pretty", "compact", "gzip":
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_ENCODING: must be pretty, compact or gzip, got %q", cfg.StorageEncoding))
+	}
+	if _, err := cfg.StorageAlertUserIDs(); err != nil {
+		errs = append(errs, fmt.Errorf("STORAGE_ALERT_USERS: %w", err))
+	}
+	if _, err := cfg.WorkspaceAdminIDs(); err != nil {
+		errs = append(errs, fmt.Errorf("WORKSPACE_ADMINS: %w", err))
+	}
+	if _, err := cfg.OutboundRateLimitMap(); err != nil {
+		errs = append(errs, fmt.Errorf("OUTBOUND_RATE_LIMITS: %w", err))
+	}
+	if cfg.FailoverReplicaPath != "" && (cfg.FailoverCheckInterval <= 0 || cfg.FailoverSyncInterval <= 0) {
+		errs = append(errs, errors.New("FAILOVER_CHECK_INTERVAL and FAILOVER_SYNC_INTERVAL must be positive"))
+	}
+	// Пустой секрет -- любой может подписать токен.
+	if cfg.JWTSecret == "" {
+		errs = append(errs, fmt.Errorf("JWT_SECRET is not set (secrets provider %q)", cfg.SecretsProvider))
+	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT: must be text or json, got %q", cfg.LogFormat))
+	}
+	if cfg.ResponseNaming != "snake" && cfg.ResponseNaming != "camel" {
+		errs = append(errs, fmt.Errorf("RESPONSE_NAMING: must be snake or camel, got %q", cfg.ResponseNaming))
+	}
+	switch cfg.SyncConflictStrategy {
+	case "server_wins", "client_wins", "last_write_wins", "manual":
+	default:
+		errs = append(errs, fmt.Errorf("SYNC_CONFLICT_STRATEGY: must be server_wins, client_wins, last_write_wins or manual, got %q",
+			cfg.SyncConflictStrategy))
+	}
+	if cfg.JiraBaseURL != "" {
+		if u, err := url.Parse(cfg.JiraBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("JIRA_BASE_URL: invalid URL %q", cfg.JiraBaseURL))
+		}
+	}
+	if cfg.QuotaWebhookURL != "" {
+		if u, err := url.Parse(cfg.QuotaWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("QUOTA_WEBHOOK_URL: invalid URL %q", cfg.QuotaWebhookURL))
+		}
+	}
+	if cfg.SelfTestClockURL != "" {
+		if u, err := url.Parse(cfg.SelfTestClockURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("SELFTEST_CLOCK_URL: invalid URL %q", cfg.SelfTestClockURL))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stringEnv перезаписывает dst, если переменная окружения задана.
+func stringEnv(name string, dst *string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = v
+	}
+}
+
+// boolEnv перезаписывает dst, если переменная задана и парсится как bool (true/false/1/0).
+func boolEnv(name string, dst *bool) {
+	if v := os.Getenv(name); v != "" {
+		if val, err := strconv.ParseBool(v); err == nil {
+			*dst = val
+		}
+	}
+}
+
+// intEnv перезаписывает dst, если переменная задана и это неотрицательное целое.
+func intEnv(name string, dst *int) {
+	if v := os.Getenv(name); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val >= 0 {
+			*dst = val
+		}
+	}
+}
+
+// durationEnv перезаписывает dst, если переменная задана в формате time.ParseDuration (30s, 5m) и больше нуля.
+func durationEnv(name string, dst *time.Duration) {
+	if v := os.Getenv(name); v != "" {
+		if val, err := time.ParseDuration(v); err == nil && val > 0 {
+			*dst = val
+		}
+	}
+}
+
+// intervalEnv -- как durationEnv, но принимает и 0 ("фоновая задача выключена").
+func intervalEnv(name string, dst *time.Duration) {
+	if v := os.Getenv(name); v != "" {
+		if val, err := time.ParseDuration(v); err == nil && val >= 0 {
+			*dst = val
+		}
+	}
+}
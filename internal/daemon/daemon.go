@@ -0,0 +1,46 @@
+// Package daemon -- жизненный цикл сервера под менеджером служб: уведомления systemd (sd_notify)
+// и служба Windows (SCM). Сервер сообщает о своих этапах через Ready, Reloading и Stopping,
+// а пакет переводит их на язык того менеджера, под которым процесс запущен. Без менеджера
+// все вызовы ничего не делают.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultName -- имя службы Windows по умолчанию.
+const DefaultName = "task-manager"
+
+// ErrUnsupported -- установка службы не поддерживается на этой платформе.
+var ErrUnsupported = errors.New("daemon: windows service is not supported on this platform (use a systemd unit)")
+
+// Ready сообщает, что сервер принимает запросы: systemd -- READY=1, SCM -- служба запущена.
+// Процесс, получивший сокет при перезапуске по SIGHUP, заодно сообщает systemd свой PID как главный.
+func Ready(inherited bool) {
+	state := "READY=1"
+	if inherited {
+		state += fmt.Sprintf("\nMAINPID=%d", os.Getpid())
+	}
+	notify(state)
+	startWatchdog()
+	serviceRunning()
+}
+
+// Reloading сообщает о перезапуске по SIGHUP: systemd ждёт READY=1 от нового процесса.
+func Reloading() {
+	notify(fmt.Sprintf("RELOADING=1\nMONOTONIC_USEC=%d", monotonicUsec()))
+}
+
+// Stopping сообщает о начале остановки; stopIn -- сколько она займёт, SCM ждёт не меньше.
+func Stopping(stopIn time.Duration) {
+	notify("STOPPING=1")
+	serviceStopping(stopIn)
+}
+
+// Status -- строка состояния для `systemctl status`.
+func Status(s string) {
+	notify("STATUS=" + s)
+}
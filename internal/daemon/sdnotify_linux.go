@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// notify отправляет состояние systemd в сокет NOTIFY_SOCKET (протокол sd_notify).
+// Вне systemd (или без Type=notify) переменной нет -- ничего не делаем.
+func notify(state string) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return
+	}
+	if path[0] == '@' { // абстрактный сокет Linux
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		log.Printf("sd_notify: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("sd_notify: %v", err)
+	}
+}
+
+var watchdogOnce sync.Once
+
+// startWatchdog шлёт WATCHDOG=1 вдвое чаще, чем требует WatchdogSec юнита. Зависший процесс
+// (не отвечает даже горутина таймера) systemd перезапустит. WATCHDOG_PID другого процесса --
+// сторож ждёт не нас (при перезапуске по SIGHUP restart убирает переменную из окружения нового процесса).
+func startWatchdog() {
+	watchdogOnce.Do(func() {
+		usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+		if err != nil || usec <= 0 {
+			return
+		}
+		if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+			return
+		}
+		go func() {
+			t := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+			defer t.Stop()
+			for range t.C {
+				notify("WATCHDOG=1")
+			}
+		}()
+	})
+}
+
+// monotonicUsec -- CLOCK_MONOTONIC в микросекундах, его ждёт systemd вместе с RELOADING=1.
+func monotonicUsec() int64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0
+	}
+	return ts.Nano() / 1000
+}
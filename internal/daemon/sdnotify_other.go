@@ -0,0 +1,8 @@
+//go:build !linux
+
+package daemon
+
+// systemd есть только в Linux.
+func notify(string)        {}
+func startWatchdog()       {}
+func monotonicUsec() int64 { return 0 }
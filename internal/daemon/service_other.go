@@ -0,0 +1,23 @@
+//go:build !windows
+
+package daemon
+
+import "time"
+
+// RunService: службы Windows здесь нет, процессом управляет systemd или оболочка.
+func RunService(string) (bool, error) { return false, nil }
+
+// StopRequested -- nil: остановку просят сигналом.
+func StopRequested() <-chan struct{} { return nil }
+
+// Exit ничего не делает.
+func Exit() {}
+
+// Install не поддерживается.
+func Install(string, []string) error { return ErrUnsupported }
+
+// Uninstall не поддерживается.
+func Uninstall(string) error { return ErrUnsupported }
+
+func serviceRunning()               {}
+func serviceStopping(time.Duration) {}
@@ -0,0 +1,190 @@
+//go:build windows
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// startWaitHint -- сколько SCM ждёт Ready, прежде чем счесть запуск зависшим
+// (подключение к БД с повторами, самопроверка).
+const startWaitHint = 2 * time.Minute
+
+// service -- связь с SCM, пока процесс работает как служба.
+var service struct {
+	running  chan struct{}      // закрывается в Ready
+	stopping chan time.Duration // Stopping: сколько займёт остановка
+	stop     chan struct{}      // закрывается, когда SCM просит остановиться
+	exit     chan struct{}      // закрывается в Exit: доложить SCM, что служба остановлена
+	done     chan struct{}      // закрывается, когда svc.Run вернул управление
+
+	runningOnce, stopOnce, exitOnce sync.Once
+}
+
+// RunService подключает процесс к SCM, если его запустили как службу Windows, и возвращает true.
+// Рабочий каталог меняется на каталог бинарника: служба стартует из System32, а .env,
+// файл задач и логи с относительными путями ищутся рядом с программой.
+func RunService(name string) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return true, err
+	}
+	if err := os.Chdir(filepath.Dir(exe)); err != nil {
+		return true, err
+	}
+
+	service.running = make(chan struct{})
+	service.stopping = make(chan time.Duration, 1)
+	service.stop = make(chan struct{})
+	service.exit = make(chan struct{})
+	service.done = make(chan struct{})
+	go func() {
+		defer close(service.done)
+		// Run блокируется до возврата из Execute; ошибка -- процесс не служба (проверено выше) или SCM недоступен.
+		_ = svc.Run(name, handler{})
+	}()
+	return true, nil
+}
+
+// StopRequested закрывается, когда SCM просит службу остановиться (Stop или выключение системы).
+// Вне службы -- nil: select по нему никогда не срабатывает.
+func StopRequested() <-chan struct{} {
+	return service.stop
+}
+
+// Exit докладывает SCM, что служба остановлена, и ждёт, пока он это примет. Вызывать последним.
+func Exit() {
+	if service.exit == nil {
+		return
+	}
+	service.exitOnce.Do(func() { close(service.exit) })
+	<-service.done
+}
+
+func serviceRunning() {
+	if service.running != nil {
+		service.runningOnce.Do(func() { close(service.running) })
+	}
+}
+
+func serviceStopping(stopIn time.Duration) {
+	if service.stopping == nil {
+		return
+	}
+	select {
+	case service.stopping <- stopIn:
+	default:
+	}
+}
+
+type handler struct{}
+
+// Execute переводит этапы сервера в состояния службы и команды SCM -- в StopRequested.
+func (handler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending, WaitHint: uint32(startWaitHint / time.Millisecond)}
+	running := service.running
+	for {
+		select {
+		case <-running:
+			running = nil
+			status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+		case d := <-service.stopping:
+			status <- svc.Status{State: svc.StopPending, WaitHint: uint32(d / time.Millisecond)}
+		case <-service.exit:
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				service.stopOnce.Do(func() { close(service.stop) })
+			}
+		}
+	}
+}
+
+// Install регистрирует текущий бинарник службой name с автозапуском и аргументами args.
+// Упавшую службу SCM перезапускает: через 5 секунд, 30 секунд, затем раз в минуту; счётчик сбоев
+// обнуляется за сутки.
+func Install(name string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "Task Manager",
+		Description: "Family Task Manager HTTP API",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	recovery := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}
+	if err := s.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("set recovery actions: %w", err)
+	}
+	return nil
+}
+
+// Uninstall останавливает службу name, если она работает, и удаляет её.
+func Uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	st, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if st.State != svc.Stopped {
+		if st, err = s.Control(svc.Stop); err != nil {
+			return fmt.Errorf("stop service: %w", err)
+		}
+		deadline := time.Now().Add(time.Minute)
+		for st.State != svc.Stopped {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("service %s did not stop within a minute", name)
+			}
+			time.Sleep(300 * time.Millisecond)
+			if st, err = s.Query(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Delete()
+}
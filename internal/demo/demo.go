@@ -0,0 +1,112 @@
+// Package demo -- правдоподобные сгенерированные данные для демо-режима (--demo).
+//
+// Данные живут в tasks.MemoryStore и пропадают при перезапуске: каждый запуск -- с чистого листа.
+// "Проекты" в модели задач -- это метки (tags): у каждой задачи одна метка-проект и, возможно, ещё пара.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"task-manager/internal/tasks"
+)
+
+// Password -- пароль всех демо-пользователей.
+const Password = "demo1234"
+
+// Options -- сколько и каких данных сгенерировать.
+type Options struct {
+	Seed  uint64    // одинаковый Seed -- одинаковые данные
+	Tasks int       // сколько задач
+	Now   time.Time // от чего отсчитываются сроки
+}
+
+var (
+	demoUsers = []string{"mama", "papa", "masha", "petya", "babushka"}
+
+	projects = []string{"дом", "дача", "школа", "ремонт", "здоровье", "покупки", "отпуск"}
+
+	titles = []string{"Купить продукты на неделю", "Починить кран на кухне", "Забрать посылку с почты",
+		"Записаться к стоматологу", "Оплатить коммуналку", "Разобрать шкаф в прихожей", "Продлить страховку машины",
+		"Проверить дневник", "Поменять шины на зимние", "Убрать балкон", "Купить лекарства в аптеке",
+		"Заказать билеты на поезд", "Выбрать подарок бабушке", "Смазать цепь велосипеда", "Записаться в секцию плавания"}
+	checklist = []string{"Составить список", "Сравнить цены", "Позвонить заранее", "Взять паспорт", "Сохранить чек",
+		"Спросить у соседей", "Отметиться в общем чате"}
+	details = []string{"", "", "До выходных, иначе не успеем.", "Список в холодильнике.", "Спросить скидку.",
+		"Чек сохранить.", "Можно вместе с детьми."}
+	extraTags  = []string{"срочно", "вдвоём", "на выходных", "онлайн"}
+	priorities = []tasks.Priority{tasks.PriorityLow, tasks.PriorityMedium, tasks.PriorityMedium, tasks.PriorityHigh,
+		tasks.PriorityCritical}
+)
+
+// Seed заполняет store демо-пользователями (пароль -- Password) и opts.Tasks задачами.
+func Seed(ctx context.Context, store *tasks.MemoryStore, opts Options) error {
+	rnd := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	now := opts.Now.UTC().Truncate(time.Hour)
+
+	hash, err := tasks.HashPassword(Password)
+	if err != nil {
+		return err
+	}
+	for _, name := range demoUsers {
+		if err := store.CreateUser(ctx, &tasks.User{Username: name, PasswordHash: hash, Email: name + "@example.com"}); err != nil {
+			return fmt.Errorf("demo user %q: %w", name, err)
+		}
+	}
+
+	for i := 0; i < opts.Tasks; i++ {
+		t := tasks.Task{
+			UserID:      1 + rnd.IntN(len(demoUsers)),
+			AssignedTo:  1 + rnd.IntN(len(demoUsers)),
+			Title:       pick(rnd, titles),
+			Description: pick(rnd, details),
+			Priority:    pick(rnd, priorities),
+			Status:      tasks.StatusTodo,
+			Tags:        []string{pick(rnd, projects)},
+		}
+		if rnd.IntN(3) == 0 {
+			t.Tags = append(t.Tags, pick(rnd, extraTags))
+		}
+		// Примерно у двух третей задач есть срок: от недели назад (просрочено) до месяца вперёд.
+		if rnd.IntN(3) > 0 {
+			due := now.Add(time.Duration(rnd.IntN(37*24)-7*24) * time.Hour)
+			t.Due = &due
+		}
+		switch rnd.IntN(5) {
+		case 0:
+			at := now.Add(-time.Duration(1+rnd.IntN(7*24)) * time.Hour)
+			t.Status, t.Done, t.CompletedAt = tasks.StatusDone, true, &at
+		case 1:
+			t.Status = tasks.StatusInProgress
+		}
+
+		// Задачи "создавались" раз в 17 часов, первые -- раньше всех. Без rnd, чтобы данные по seed не менялись.
+		created := now.Add(-time.Duration(opts.Tasks-i) * 17 * time.Hour)
+		t.CreatedAt = &created
+
+		if err := store.Create(ctx, &t); err != nil {
+			return fmt.Errorf("demo task %q: %w", t.Title, err)
+		}
+		for n := rnd.IntN(4); n > 0; n-- {
+			sub := tasks.SubTask{TaskID: t.ID, Title: pick(rnd, checklist), Done: t.Done || rnd.IntN(2) == 0}
+			if err := store.CreateSubtask(ctx, &sub); err != nil {
+				return fmt.Errorf("demo subtask: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Usernames -- имена демо-пользователей (для подсказки при старте).
+func Usernames() []string {
+	return append([]string(nil), demoUsers...)
+}
+
+func pick[T any](rnd *rand.Rand, list []T) T {
+	return list[rnd.IntN(len(list))]
+}
@@ -0,0 +1,282 @@
+// Package health -- самопроверка при старте, эндпоинт готовности /readyz и живости /healthz.
+//
+// Проверки -- простые функции; Run выполняет их по очереди и собирает отчёт.
+// Отчёт пишется в лог, отдаётся на /readyz и используется флагом --selftest (CI/CD gate).
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"task-manager/internal/httpclient"
+)
+
+// Status -- итог одной проверки.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip" // проверка неприменима в текущей конфигурации
+)
+
+// Check -- одна проверка. Fn возвращает необязательную подробность (например, величину расхождения часов).
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) (string, error)
+}
+
+// skipError помечает проверку как пропущенную.
+type skipError struct{ reason string }
+
+func (e skipError) Error() string { return e.reason }
+
+// Skip возвращается из Check.Fn, когда проверка неприменима (например, не задан эталон времени).
+func Skip(reason string) error { return skipError{reason} }
+
+// Result -- результат одной проверки.
+type Result struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report -- результат всей самопроверки.
+type Report struct {
+	OK     bool      `json:"ok"`
+	RanAt  time.Time `json:"ran_at"`
+	Checks []Result  `json:"checks"`
+}
+
+// checkTimeout -- сколько даём одной проверке, чтобы зависшая СУБД не подвесила старт.
+const checkTimeout = 5 * time.Second
+
+// Run выполняет проверки по порядку. Отчёт OK, если ни одна проверка не упала.
+func Run(ctx context.Context, checks ...Check) Report {
+	rep := Report{OK: true, RanAt: time.Now().UTC()}
+	for _, c := range checks {
+		cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+		start := time.Now()
+		detail, err := c.Fn(cctx)
+		cancel()
+
+		res := Result{Name: c.Name, Status: StatusOK, Detail: detail, Duration: time.Since(start).Round(time.Microsecond).String()}
+		var skip skipError
+		switch {
+		case errors.As(err, &skip):
+			res.Status, res.Detail = StatusSkip, skip.reason
+		case err != nil:
+			res.Status, res.Detail = StatusFail, err.Error()
+			rep.OK = false
+		}
+		rep.Checks = append(rep.Checks, res)
+	}
+	return rep
+}
+
+// Log пишет отчёт в лог построчно (по одной строке на проверку).
+func (rep Report) Log() {
+	for _, c := range rep.Checks {
+		log.Printf("selftest check=%s status=%s duration=%s detail=%q", c.Name, c.Status, c.Duration, c.Detail)
+	}
+	if rep.OK {
+		log.Printf("selftest passed")
+	} else {
+		log.Printf("selftest FAILED")
+	}
+}
+
+// Readiness хранит последний отчёт самопроверки и отдаёт его на /readyz:
+// 200, если всё в порядке, и 503, если хоть одна проверка упала.
+// Дополнительные состояния (например, режим обслуживания) подключаются через AddInfo,
+// частичные отказы (например, работа из реплики хранилища) -- через AddDegradation.
+type Readiness struct {
+	mu       sync.RWMutex
+	draining bool
+	report   *Report
+	info     map[string]func() any
+	degraded map[string]func() string
+}
+
+// NewReadiness создаёт Readiness. До первого SetReport сервис считается неготовым.
+func NewReadiness() *Readiness {
+	return &Readiness{info: make(map[string]func() any), degraded: make(map[string]func() string)}
+}
+
+// AddInfo добавляет в ответ /readyz поле name со значением fn() на момент запроса.
+// На код ответа не влияет.
+func (rd *Readiness) AddInfo(name string, fn func() any) {
+	rd.mu.Lock()
+	rd.info[name] = fn
+	rd.mu.Unlock()
+}
+
+// AddDegradation подключает источник частичного отказа: fn возвращает причину деградации
+// или "", если всё в порядке. При непустой причине /readyz отвечает "status": "degraded"
+// и перечисляет причины в "degraded", но код ответа остаётся 200 -- сервис работает, хоть и
+// не полностью, и выводить его из балансировки нельзя.
+func (rd *Readiness) AddDegradation(name string, fn func() string) {
+	rd.mu.Lock()
+	rd.degraded[name] = fn
+	rd.mu.Unlock()
+}
+
+// SetDraining объявляет о скорой остановке: /readyz отвечает 503 со "status": "draining",
+// чтобы балансировщик перестал слать запросы, пока сервер их ещё обслуживает.
+func (rd *Readiness) SetDraining(draining bool) {
+	rd.mu.Lock()
+	rd.draining = draining
+	rd.mu.Unlock()
+}
+
+// SetReport публикует свежий отчёт самопроверки.
+func (rd *Readiness) SetReport(rep Report) {
+	rd.mu.Lock()
+	rd.report = &rep
+	rd.mu.Unlock()
+}
+
+func (rd *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	rd.mu.RLock()
+	rep, draining := rd.report, rd.draining
+	body := make(map[string]any, len(rd.info)+5)
+	for name, fn := range rd.info {
+		body[name] = fn()
+	}
+	degraded := make(map[string]string)
+	for name, fn := range rd.degraded {
+		if reason := fn(); reason != "" {
+			degraded[name] = reason
+		}
+	}
+	rd.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	status := http.StatusOK
+	if rep == nil {
+		status = http.StatusServiceUnavailable
+		body["ok"], body["checks"] = false, []Result{}
+	} else {
+		if !rep.OK {
+			status = http.StatusServiceUnavailable
+		}
+		body["ok"], body["ran_at"], body["checks"] = rep.OK, rep.RanAt, rep.Checks
+	}
+	if draining {
+		status = http.StatusServiceUnavailable
+	}
+	switch {
+	case draining:
+		body["status"] = "draining"
+	case status != http.StatusOK:
+		body["status"] = "fail"
+	case len(degraded) > 0:
+		body["status"], body["degraded"] = "degraded", degraded
+	default:
+		body["status"] = "ok"
+	}
+
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// ClockSkew сравнивает локальные часы с эталоном ref и падает, если расхождение больше max.
+// Неверные часы ломают JWT (exp), сроки задач и completed_at.
+func ClockSkew(ref func(ctx context.Context) (time.Time, error), max time.Duration) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		before := time.Now()
+		remote, err := ref(ctx)
+		if err != nil {
+			return "", err
+		}
+		// Берём середину запроса, чтобы сеть не считалась расхождением.
+		local := before.Add(time.Since(before) / 2)
+
+		skew := local.Sub(remote)
+		if skew < 0 {
+			skew = -skew
+		}
+		detail := "skew " + skew.Round(time.Millisecond).String()
+		if skew > max {
+			return detail, fmt.Errorf("clock skew %s exceeds %s", skew.Round(time.Millisecond), max)
+		}
+		return detail, nil
+	}
+}
+
+// clockClient -- запросы к эталону времени (HTTPDate): без повторов, иначе задержка исказит разницу часов.
+var clockClient = httpclient.New(httpclient.Options{Name: "clock", Timeout: 10 * time.Second})
+
+// HTTPDate -- эталон времени по заголовку Date ответа HTTP-сервера (точность -- секунда).
+func HTTPDate(url string) func(ctx context.Context) (time.Time, error) {
+	return func(ctx context.Context) (time.Time, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp, err := clockClient.Do(req)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp.Body.Close()
+		return http.ParseTime(resp.Header.Get("Date"))
+	}
+}
+
+// PortAvailable проверяет, что адрес можно занять (порт не занят другим процессом).
+// Порт сразу освобождается -- вызывать до того, как сервер начнёт слушать.
+func PortAvailable(addr string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var lc net.ListenConfig
+		ln, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return "", err
+		}
+		return ln.Addr().String(), ln.Close()
+	}
+}
+
+// Liveness -- обработчик /healthz для проверок живости (HEALTHCHECK Docker, livenessProbe Kubernetes):
+// 200, пока процесс отвечает на HTTP. Хранилище и самопроверку не трогает -- их отказ чинится
+// не перезапуском контейнера, для них есть /readyz.
+func Liveness(started time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "ok",
+			"uptime": time.Since(started).Round(time.Second).String(),
+		})
+	})
+}
+
+// probeClient -- проверка своего сервера (Probe): тайм-аут задаёт ctx вызывающего.
+var probeClient = httpclient.New(httpclient.Options{Name: "probe"})
+
+// Probe запрашивает url (обычно /healthz своего же сервера) -- для `task-server -healthcheck`
+// в образах без curl. nil -- ответ 200.
+func Probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
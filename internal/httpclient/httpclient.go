@@ -0,0 +1,324 @@
+// Package httpclient -- исходящие HTTP-запросы к интеграциям (webhook, Jira, Trello, OPA, Vault, AWS).
+//
+// Все клиенты делят один пул соединений и настройки прокси, а каждая интеграция получает свои
+// тайм-аут, повторы и ограничение частоты. Клиент -- обычный *http.Client: коннекторы и тесты
+// могут подменить его своим.
+//
+// Прокси и ограничения частоты задаются в main (SetProxy, SetRateLimits) и действуют и на клиенты,
+// созданные раньше, -- например, в переменных пакетов.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var (
+	clientRequests = metrics.NewCounterVec("http_client_requests_total",
+		"Outbound HTTP requests by integration and result (2xx, 3xx, 4xx, 5xx, error, captured).", "integration", "result")
+	clientRetries = metrics.NewCounterVec("http_client_retries_total",
+		"Outbound HTTP requests repeated after a network error, 429 or 5xx, by integration.", "integration")
+	clientThrottled = metrics.NewCounterVec("http_client_throttled_total",
+		"Outbound HTTP requests delayed by the per-integration rate limit.", "integration")
+)
+
+// Options -- настройки клиента одной интеграции.
+type Options struct {
+	// Name -- имя интеграции: метка метрик и ключ OUTBOUND_RATE_LIMITS.
+	Name string
+	// Timeout -- на весь вызов Do вместе с повторами и ожиданием лимита.
+	Timeout time.Duration
+	// Retries -- сколько раз повторить запрос после сетевой ошибки, 429 или 5xx. Повторяются только
+	// запросы, тело которых можно прочитать заново (http.NewRequest с bytes.Reader и т.п.).
+	// POST повторяется тоже: получатель webhook должен переносить повтор (одинаковый X-Request-ID).
+	Retries int
+	// RateLimit -- не больше стольких запросов в секунду; 0 -- без ограничения.
+	// OUTBOUND_RATE_LIMITS заменяет значение по имени интеграции.
+	RateLimit float64
+	// PublicOnly -- адрес получателя задаёт пользователь (webhook автоматизаций и отчётов): соединяться
+	// только с публичными адресами, иначе ErrPrivateAddress (см. public.go и SetPrivateAllowlist).
+	PublicOnly bool
+}
+
+// Пауза между повторами: retryBase, 2*retryBase, ... но не больше retryMax (Retry-After тоже не дольше).
+const (
+	retryBase = 200 * time.Millisecond
+	retryMax  = 10 * time.Second
+)
+
+// shared -- общий транспорт: пул соединений на все интеграции.
+var shared = &http.Transport{
+	Proxy: proxyFunc,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   10,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// proxy -- прокси из SetProxy; nil -- из окружения (HTTPS_PROXY, HTTP_PROXY, NO_PROXY).
+var proxy atomic.Pointer[url.URL]
+
+// proxyFunc выбирает прокси запроса. Свой сервер (localhost, 127.0.0.1) всегда напрямую --
+// как и у прокси из окружения.
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	u := proxy.Load()
+	if u == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	host := req.URL.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// SetProxy направляет все исходящие запросы через прокси raw (http://, https:// или socks5://).
+// Пустая строка -- прокси из окружения, как у net/http.
+func SetProxy(raw string) error {
+	if raw == "" {
+		proxy.Store(nil)
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("proxy %q: want scheme://host:port", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("proxy %q: scheme must be http, https or socks5", raw)
+	}
+	proxy.Store(u)
+	return nil
+}
+
+// limiters -- ограничители частоты по имени интеграции (общие для всех её клиентов).
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*limiter)
+	overrides  = make(map[string]float64) // из SetRateLimits
+)
+
+// SetRateLimits заменяет ограничения частоты интеграций: имя -> запросов в секунду (0 -- без ограничения).
+// Интеграции, которых нет в limits, возвращаются к значению из Options.
+func SetRateLimits(limits map[string]float64) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	overrides = limits
+	for name, l := range limiters {
+		rate, ok := limits[name]
+		if !ok {
+			rate = l.defaultRate
+		}
+		l.setRate(rate)
+	}
+}
+
+func limiterFor(name string, rate float64) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[name]
+	if !ok {
+		l = &limiter{defaultRate: rate}
+		if r, ok := overrides[name]; ok {
+			rate = r
+		}
+		l.setRate(rate)
+		limiters[name] = l
+	}
+	return l
+}
+
+// Sink принимает запросы вместо сети (демо-режим): ответ Deliver вызывающий получает как ответ получателя.
+type Sink interface {
+	Deliver(integration string, req *http.Request) (*http.Response, error)
+}
+
+type sinkRoute struct {
+	sink  Sink
+	names map[string]bool
+}
+
+var sink atomic.Pointer[sinkRoute]
+
+// SetSink направляет запросы интеграций names в s, а не в сеть. nil -- обычная отправка.
+func SetSink(s Sink, names ...string) {
+	if s == nil {
+		sink.Store(nil)
+		return
+	}
+	route := &sinkRoute{sink: s, names: make(map[string]bool, len(names))}
+	for _, name := range names {
+		route.names[name] = true
+	}
+	sink.Store(route)
+}
+
+// New создаёт клиент интеграции.
+func New(opts Options) *http.Client {
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &transport{
+			name:       opts.Name,
+			retries:    opts.Retries,
+			limit:      limiterFor(opts.Name, opts.RateLimit),
+			publicOnly: opts.PublicOnly,
+		},
+	}
+}
+
+// transport -- ограничение частоты и повторы поверх общего транспорта.
+type transport struct {
+	name       string
+	retries    int
+	limit      *limiter
+	publicOnly bool
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if route := sink.Load(); route != nil && route.names[t.name] {
+		clientRequests.WithLabelValues(t.name, "captured").Inc()
+		return route.sink.Deliver(t.name, req)
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			clientRetries.WithLabelValues(t.name).Inc()
+		}
+		if t.limit.wait(ctx) {
+			clientThrottled.WithLabelValues(t.name).Inc()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		try := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try = req.Clone(ctx)
+			try.Body = body
+		}
+		var resp *http.Response
+		var err error
+		if t.publicOnly {
+			resp, err = publicRoundTrip(try)
+		} else {
+			resp, err = shared.RoundTrip(try)
+		}
+		clientRequests.WithLabelValues(t.name, result(resp, err)).Inc()
+
+		if attempt >= t.retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		delay := backoff(attempt, resp)
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10)) // соединение вернётся в пул
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryable -- стоит ли повторить: сетевая ошибка (не отмена), 429 или 5xx, и тело можно прочитать заново.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrPrivateAddress)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff -- пауза перед повтором: Retry-After в секундах, если получатель его прислал,
+// иначе экспоненциально с разбросом.
+func backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+			return min(time.Duration(s)*time.Second, retryMax)
+		}
+	}
+	d := min(retryBase<<attempt, retryMax)
+	return d/2 + rand.N(d/2+1)
+}
+
+func result(resp *http.Response, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode/100) + "xx"
+}
+
+// limiter пропускает запросы не чаще rate в секунду: каждый следующий -- через 1/rate после предыдущего.
+type limiter struct {
+	defaultRate float64
+
+	mu    sync.Mutex
+	every time.Duration // 0 -- без ограничения
+	next  time.Time
+}
+
+func (l *limiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.every = 0
+	if rate > 0 {
+		l.every = time.Duration(float64(time.Second) / rate)
+	}
+}
+
+// wait ждёт своей очереди (или отмены ctx). true -- пришлось ждать.
+func (l *limiter) wait(ctx context.Context) bool {
+	l.mu.Lock()
+	if l.every == 0 {
+		l.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.every)
+	l.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress -- клиент PublicOnly не соединяется с адресами внутренней сети.
+var ErrPrivateAddress = errors.New("destination is not a public address")
+
+// allowPrivate -- сети из SetPrivateAllowlist, куда клиентам PublicOnly можно.
+var allowPrivate atomic.Pointer[[]netip.Prefix]
+
+// SetPrivateAllowlist разрешает клиентам PublicOnly адреса внутренней сети из списка
+// ("10.1.2.0/24,192.168.0.10"): например, свой сервис, который принимает webhook.
+// Пустая строка -- только публичные адреса.
+func SetPrivateAllowlist(raw string) error {
+	var nets []netip.Prefix
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return fmt.Errorf("allowlist entry %q: want an IP address or CIDR", part)
+			}
+			nets = append(nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return fmt.Errorf("allowlist entry %q: want an IP address or CIDR", part)
+		}
+		nets = append(nets, p.Masked())
+	}
+	allowPrivate.Store(&nets)
+	return nil
+}
+
+// checkPublic -- можно ли клиенту PublicOnly соединиться с addr: адрес публичный или разрешён
+// SetPrivateAllowlist. Запрещены loopback, частные сети, link-local, multicast и 0.0.0.0/::.
+func checkPublic(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsGlobalUnicast() && !addr.IsPrivate() {
+		return nil
+	}
+	if nets := allowPrivate.Load(); nets != nil {
+		for _, p := range *nets {
+			if p.Contains(addr) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+}
+
+// publicControl проверяет адрес уже после разрешения DNS, перед каждым соединением: имя,
+// которое резолвится во внутренний адрес (или меняет адрес между проверкой и запросом), не пройдёт.
+func publicControl(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	return checkPublic(ap.Addr())
+}
+
+// publicDirect -- транспорт клиентов PublicOnly без прокси: проверка в Dialer.Control.
+var publicDirect = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicControl,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   10,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// publicRoundTrip отправляет запрос клиента PublicOnly. Напрямую -- через publicDirect. Через прокси
+// соединяется прокси, поэтому адреса получателя проверяются по DNS до запроса (а прокси -- не проверяется:
+// его задал оператор).
+func publicRoundTrip(req *http.Request) (*http.Response, error) {
+	u, err := proxyFunc(req)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return publicDirect.RoundTrip(req)
+	}
+	if err := checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return shared.RoundTrip(req)
+}
+
+// checkHost проверяет все адреса, в которые резолвится host.
+func checkHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkPublic(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := checkPublic(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPublicURL -- ранняя проверка адреса, который задаёт пользователь: localhost и IP-адрес
+// внутренней сети в URL отклоняются сразу, при сохранении. Имена не резолвятся -- их адреса
+// проверяются при каждом соединении.
+func CheckPublicURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkPublic(addr)
+	}
+	return nil
+}
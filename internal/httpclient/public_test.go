@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newReceiver(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// TestPublicOnlyRejectsPrivate -- клиент PublicOnly не соединяется с loopback ни по IP,
+// ни по имени, которое в него резолвится; обычный клиент соединяется.
+func TestPublicOnlyRejectsPrivate(t *testing.T) {
+	srv, hits := newReceiver(t)
+	client := New(Options{Name: "test-public", Retries: 2, PublicOnly: true})
+	byName := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	for _, u := range []string{srv.URL, byName} {
+		resp, err := client.Post(u, "application/json", strings.NewReader(`{}`))
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("POST %s: %v, want ErrPrivateAddress", u, err)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("receiver got %d requests, want 0", n)
+	}
+
+	resp, err := New(Options{Name: "test-internal"}).Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("client without PublicOnly: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// TestPublicOnlyAllowlist -- сеть из SetPrivateAllowlist клиенту PublicOnly доступна.
+func TestPublicOnlyAllowlist(t *testing.T) {
+	srv, hits := newReceiver(t)
+	client := New(Options{Name: "test-public", PublicOnly: true})
+	if err := SetPrivateAllowlist("10.0.0.0/8, 127.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetPrivateAllowlist("") })
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("allowed network: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || hits.Load() != 1 {
+		t.Errorf("allowed network: status %d, %d requests", resp.StatusCode, hits.Load())
+	}
+
+	if err := SetPrivateAllowlist("10.0.0.0/33"); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestCheckPublicURL(t *testing.T) {
+	for raw, public := range map[string]bool{
+		"https://hooks.example.com/x":        true,
+		"http://93.184.216.34/hook":          true,
+		"http://[2606:4700::1111]/":          true,
+		"http://localhost:8080/":             false,
+		"http://api.localhost/":              false,
+		"http://127.0.0.1/":                  false,
+		"http://10.1.2.3/":                   false,
+		"http://192.168.0.1/":                false,
+		"http://169.254.169.254/latest/meta": false,
+		"http://0.0.0.0/":                    false,
+		"http://[::1]/":                      false,
+		"http://[::ffff:127.0.0.1]/":         false,
+		"http://[fd00::1]/":                  false,
+	} {
+		err := CheckPublicURL(raw)
+		if public && err != nil {
+			t.Errorf("%s: %v, want public", raw, err)
+		}
+		if !public && !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: %v, want ErrPrivateAddress", raw, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package importers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/httpclient"
+	"task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+)
+
+// jiraPageSize -- сколько задач Jira забираем за один запрос поиска.
+const jiraPageSize = 100
+
+// Jira забирает задачи проекта Jira (Cloud или Server) через REST API v2.
+//
+// Маппинг: summary -> title, description -> description, duedate -> due, labels -> tags,
+// priority Highest/Blocker/Critical -> critical, High -> high, Low/Lowest -> low, остальное -> medium.
+// Статус определяется сначала по имени (StatusMap), затем по категории статуса Jira
+// (new -> todo, indeterminate -> in_progress, done -> done).
+type Jira struct {
+	BaseURL  string // Например, https://company.atlassian.net
+	Email    string
+	APIToken string
+	Statuses StatusMap
+
+	Client *http.Client
+}
+
+// NewJira создаёт коннектор Jira. Авторизация -- Basic email:api_token.
+func NewJira(baseURL, email, apiToken string, statuses StatusMap) *Jira {
+	return &Jira{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Email:    email,
+		APIToken: apiToken,
+		Statuses: statuses,
+		Client:   httpclient.New(httpclient.Options{Name: "jira", Timeout: 30 * time.Second, Retries: 3, RateLimit: 5}),
+	}
+}
+
+type jiraSearchResponse struct {
+	StartAt int         `json:"startAt"`
+	Total   int         `json:"total"`
+	Issues  []jiraIssue `json:"issues"`
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		DueDate     string   `json:"duedate"` // YYYY-MM-DD
+		Labels      []string `json:"labels"`
+		Estimate    *int     `json:"timeoriginalestimate"` // секунды
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+	} `json:"fields"`
+}
+
+// Fetch загружает все задачи проекта projectKey (постранично).
+func (j *Jira) Fetch(ctx context.Context, projectKey string, userID int) ([]tasks.Task, error) {
+	if j.BaseURL == "" || j.Email == "" || j.APIToken == "" {
+		return nil, errors.New("jira is not configured (JIRA_BASE_URL, JIRA_EMAIL, JIRA_API_TOKEN)")
+	}
+
+	var out []tasks.Task
+	for startAt := 0; ; {
+		page, err := j.search(ctx, projectKey, startAt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range page.Issues {
+			t, err := j.mapIssue(issue, userID)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, t)
+		}
+
+		startAt += len(page.Issues)
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (j *Jira) mapIssue(issue jiraIssue, userID int) (tasks.Task, error) {
+	f := issue.Fields
+	t := tasks.Task{
+		UserID:     userID,
+		AssignedTo: userID,
+		UUID:       "jira:" + issue.Key,
+		Tags:       f.Labels,
+		Priority:   tasks.PriorityMedium,
+	}
+	t.Title, t.Description = fitTitle(issue.Key+" "+f.Summary, f.Description)
+
+	if f.Priority != nil {
+		switch strings.ToLower(f.Priority.Name) {
+		case "highest", "blocker", "critical":
+			t.Priority = tasks.PriorityCritical
+		case "high":
+			t.Priority = tasks.PriorityHigh
+		case "low", "lowest", "minor", "trivial":
+			t.Priority = tasks.PriorityLow
+		}
+	}
+
+	if _, ok := j.Statuses[strings.ToLower(f.Status.Name)]; ok {
+		t.Status = j.Statuses.lookup(f.Status.Name)
+	} else {
+		switch f.Status.StatusCategory.Key {
+		case "done":
+			t.Status = tasks.StatusDone
+		case "indeterminate":
+			t.Status = tasks.StatusInProgress
+		default:
+			t.Status = j.Statuses.lookup(f.Status.Name)
+		}
+	}
+
+	if f.DueDate != "" {
+		due, err := time.Parse("2006-01-02", f.DueDate)
+		if err != nil {
+			return t, fmt.Errorf("jira %s: invalid duedate %q", issue.Key, f.DueDate)
+		}
+		t.Due = &due
+	}
+	if f.Estimate != nil && *f.Estimate > 0 {
+		t.EstimateMinutes = (*f.Estimate + 59) / 60
+	}
+	return t, nil
+}
+
+func (j *Jira) search(ctx context.Context, projectKey string, startAt int) (*jiraSearchResponse, error) {
+	q := url.Values{
+		"jql":        {fmt.Sprintf(`project = "%s" ORDER BY created ASC`, strings.ReplaceAll(projectKey, `"`, ""))},
+		"startAt":    {strconv.Itoa(startAt)},
+		"maxResults": {strconv.Itoa(jiraPageSize)},
+		"fields":     {"summary,description,duedate,labels,status,priority,timeoriginalestimate"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.BaseURL+"/rest/api/2/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(j.Email, j.APIToken)
+	req.Header.Set("Accept", "application/json")
+	middleware.PropagateTrace(req) // в журнале Jira видно, какой импорт сделал запрос
+
+	resp, err := j.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("jira search: %s: %s", resp.Status, body)
+	}
+
+	var page jiraSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
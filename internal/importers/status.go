@@ -0,0 +1,60 @@
+// Package importers -- коннекторы, которые забирают задачи из внешних систем (Trello, Jira)
+// по их REST API и маппят в модель tasks.Task.
+//
+// Коннекторы реализуют tasks.RemoteImporter и подключаются в main через Handler.RegisterImporter.
+package importers
+
+import (
+	"strings"
+
+	"task-manager/internal/tasks"
+)
+
+// StatusMap -- явный маппинг названий списков Trello / статусов Jira в наш статус.
+// Ключи сравниваются без учёта регистра.
+type StatusMap map[string]string
+
+// ParseStatusMap разбирает строку вида "Done=done;Doing=in_progress;Бэклог=todo".
+// Неизвестные значения статуса игнорируются.
+func ParseStatusMap(s string) StatusMap {
+	m := make(StatusMap)
+	for _, pair := range strings.Split(s, ";") {
+		name, status, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		status = strings.TrimSpace(status)
+		switch status {
+		case tasks.StatusTodo, tasks.StatusInProgress, tasks.StatusDone:
+			m[strings.ToLower(strings.TrimSpace(name))] = status
+		}
+	}
+	return m
+}
+
+// lookup возвращает статус для имени из явного маппинга или угадывает его по типичным названиям.
+func (m StatusMap) lookup(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if status, ok := m[key]; ok {
+		return status
+	}
+
+	switch key {
+	case "done", "complete", "completed", "closed", "resolved", "готово", "сделано", "выполнено":
+		return tasks.StatusDone
+	case "doing", "in progress", "in review", "review", "в работе", "в процессе":
+		return tasks.StatusInProgress
+	default:
+		return tasks.StatusTodo
+	}
+}
+
+// fitTitle нормализует внешнее название. В лимит TITLE_MAX_LENGTH его укладывает импорт
+// (Service.FitTitle): полный текст при обрезке переносится в описание.
+func fitTitle(title, description string) (string, string) {
+	title = tasks.NormalizeText(title)
+	if title == "" {
+		title = "(без названия)"
+	}
+	return title, description
+}
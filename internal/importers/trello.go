@@ -0,0 +1,141 @@
+package importers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"task-manager/internal/httpclient"
+	"task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+)
+
+// trelloBaseURL -- REST API Trello.
+const trelloBaseURL = "https://api.trello.com/1"
+
+// Trello забирает карточки доски Trello.
+//
+// Маппинг: карточка -> задача, список карточки -> статус (через StatusMap),
+// desc -> description, due -> due, labels -> tags, dueComplete -> done.
+// Архивные карточки (closed) пропускаются.
+type Trello struct {
+	APIKey   string
+	Token    string
+	Statuses StatusMap
+
+	// BaseURL и Client можно подменить (например, в демо-режиме).
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewTrello создаёт коннектор Trello с ключом и токеном API.
+func NewTrello(apiKey, token string, statuses StatusMap) *Trello {
+	return &Trello{
+		APIKey:   apiKey,
+		Token:    token,
+		Statuses: statuses,
+		BaseURL:  trelloBaseURL,
+		Client:   httpclient.New(httpclient.Options{Name: "trello", Timeout: 30 * time.Second, Retries: 3, RateLimit: 10}),
+	}
+}
+
+type trelloList struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type trelloCard struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Desc        string     `json:"desc"`
+	Due         *time.Time `json:"due"`
+	DueComplete bool       `json:"dueComplete"`
+	IDList      string     `json:"idList"`
+	Closed      bool       `json:"closed"`
+	Labels      []struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+}
+
+// Fetch загружает карточки доски boardID.
+func (t *Trello) Fetch(ctx context.Context, boardID string, userID int) ([]tasks.Task, error) {
+	if t.APIKey == "" || t.Token == "" {
+		return nil, errors.New("trello is not configured (TRELLO_API_KEY, TRELLO_TOKEN)")
+	}
+
+	var lists []trelloList
+	if err := t.get(ctx, "/boards/"+url.PathEscape(boardID)+"/lists", &lists); err != nil {
+		return nil, err
+	}
+	listNames := make(map[string]string, len(lists))
+	for _, l := range lists {
+		listNames[l.ID] = l.Name
+	}
+
+	var cards []trelloCard
+	if err := t.get(ctx, "/boards/"+url.PathEscape(boardID)+"/cards", &cards); err != nil {
+		return nil, err
+	}
+
+	out := make([]tasks.Task, 0, len(cards))
+	for _, c := range cards {
+		if c.Closed {
+			continue
+		}
+
+		task := tasks.Task{
+			UserID:     userID,
+			AssignedTo: userID,
+			UUID:       "trello:" + c.ID,
+			Status:     t.Statuses.lookup(listNames[c.IDList]),
+			Priority:   tasks.PriorityMedium,
+			Due:        c.Due,
+		}
+		if c.DueComplete {
+			task.Status = tasks.StatusDone
+		}
+		task.Title, task.Description = fitTitle(c.Name, c.Desc)
+
+		for _, l := range c.Labels {
+			// У меток Trello может не быть имени -- тогда берём цвет.
+			name := l.Name
+			if name == "" {
+				name = l.Color
+			}
+			if name != "" {
+				task.Tags = append(task.Tags, name)
+			}
+		}
+		out = append(out, task)
+	}
+	return out, nil
+}
+
+// get выполняет GET к API Trello с авторизацией через key/token в query.
+func (t *Trello) get(ctx context.Context, path string, out any) error {
+	q := url.Values{"key": {t.APIKey}, "token": {t.Token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.BaseURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	middleware.PropagateTrace(req)
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("trello %s: %s: %s", path, resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
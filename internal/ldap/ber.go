@@ -0,0 +1,184 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER (X.690) в объёме, нужном LDAPv3: однобайтовые теги, определённая длина.
+
+// Теги универсального класса.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// Операции LDAP (RFC 4511, раздел 4): класс APPLICATION.
+const (
+	appBindRequest      = 0x60
+	appBindResponse     = 0x61
+	appUnbindRequest    = 0x42
+	appSearchRequest    = 0x63
+	appSearchEntry      = 0x64
+	appSearchDone       = 0x65
+	appSearchReference  = 0x73
+	appExtendedRequest  = 0x77
+	appExtendedResponse = 0x78
+
+	ctxSimpleAuth   = 0x80 // BindRequest.authentication: simple [0]
+	ctxExtendedName = 0x80 // ExtendedRequest.requestName [0]
+)
+
+const (
+	constructedBit = 0x20
+	longFormBit    = 0x80
+	maxMessageSize = 16 << 20 // больше -- считаем ответ испорченным
+)
+
+// element -- разобранный элемент BER. У составного (constructed) заполнены children.
+type element struct {
+	tag      byte
+	value    []byte
+	children []element
+}
+
+// tlv кодирует элемент: тег, длина, содержимое.
+func tlv(tag byte, parts ...[]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	out := append([]byte{tag}, encodeLength(n)...)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func encodeLength(n int) []byte {
+	if n < longFormBit {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{longFormBit | byte(len(b))}, b...)
+}
+
+func berInt(tag byte, v int) []byte {
+	// Минимальное дополнение до двух: старший бит первого байта -- знак.
+	b := []byte{byte(v)}
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return tlv(tag, b)
+}
+
+func berString(tag byte, s string) []byte { return tlv(tag, []byte(s)) }
+
+func berBool(v bool) []byte {
+	if v {
+		return tlv(tagBoolean, []byte{0xff})
+	}
+	return tlv(tagBoolean, []byte{0x00})
+}
+
+// readElement читает из потока один элемент верхнего уровня.
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	n, err := readLength(r)
+	if err != nil {
+		return element{}, err
+	}
+	if n > maxMessageSize {
+		return element{}, fmt.Errorf("ldap: message of %d bytes is too large", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return element{}, err
+	}
+	return parseElement(tag, buf)
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b&longFormBit == 0 {
+		return int(b), nil
+	}
+	count := int(b &^ longFormBit)
+	if count == 0 || count > 4 {
+		return 0, errors.New("ldap: unsupported BER length")
+	}
+	n := 0
+	for range count {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
+
+// parseElement разбирает содержимое элемента; составные -- рекурсивно.
+func parseElement(tag byte, value []byte) (element, error) {
+	e := element{tag: tag, value: value}
+	if tag&constructedBit == 0 {
+		return e, nil
+	}
+	for rest := value; len(rest) > 0; {
+		if len(rest) < 2 {
+			return e, errors.New("ldap: truncated BER element")
+		}
+		childTag := rest[0]
+		n, hdr := int(rest[1]), 2
+		if rest[1]&longFormBit != 0 {
+			count := int(rest[1] &^ longFormBit)
+			if count == 0 || count > 4 || len(rest) < 2+count {
+				return e, errors.New("ldap: bad BER length")
+			}
+			n = 0
+			for _, b := range rest[2 : 2+count] {
+				n = n<<8 | int(b)
+			}
+			hdr += count
+		}
+		if n < 0 || len(rest) < hdr+n {
+			return e, errors.New("ldap: truncated BER element")
+		}
+		child, err := parseElement(childTag, rest[hdr:hdr+n])
+		if err != nil {
+			return e, err
+		}
+		e.children = append(e.children, child)
+		rest = rest[hdr+n:]
+	}
+	return e, nil
+}
+
+// int разбирает INTEGER или ENUMERATED.
+func (e element) int() int {
+	v := 0
+	for i, b := range e.value {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+func (e element) str() string { return string(e.value) }
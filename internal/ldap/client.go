@@ -0,0 +1,280 @@
+// Package ldap -- минимальный клиент LDAPv3 (RFC 4511) и проверка паролей по каталогу
+// (OpenLDAP, Active Directory, FreeIPA).
+//
+// Клиент умеет ровно то, что нужно для входа: простую привязку (bind), поиск и StartTLS.
+// Операции выполняются по одной на соединение; соединение живёт одну попытку входа.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Коды результата LDAP, которые различает вызывающий код.
+const (
+	ResultSuccess            = 0
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+)
+
+// startTLSOID -- расширенная операция StartTLS (RFC 4511, 4.14).
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Scope -- глубина поиска.
+type Scope int
+
+const (
+	ScopeBase    Scope = 0
+	ScopeOne     Scope = 1
+	ScopeSubtree Scope = 2
+)
+
+const (
+	derefAlways   = 3 // разыменовывать алиасы при поиске
+	searchNoLimit = 0 // без ограничения времени поиска на стороне сервера
+)
+
+// Error -- неуспешный результат операции LDAP.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// IsCode сообщает, что err -- ответ сервера с кодом code.
+func IsCode(err error, code int) bool {
+	var le *Error
+	return errors.As(err, &le) && le.Code == code
+}
+
+// Entry -- найденная запись: DN и атрибуты (имена -- как вернул сервер).
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get возвращает первое значение атрибута (имя без учёта регистра).
+func (e *Entry) Get(attr string) string {
+	if v := e.Values(attr); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Values возвращает все значения атрибута (имя без учёта регистра).
+func (e *Entry) Values(attr string) []string {
+	for name, v := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Conn -- соединение с сервером каталога.
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	nextID  int
+	timeout time.Duration
+}
+
+// Dial подключается к ldap://host[:389] или ldaps://host[:636]. С startTLS соединение ldap://
+// сразу переводится на TLS. tlsConfig может быть nil (системные корневые сертификаты).
+func Dial(ctx context.Context, rawURL string, startTLS bool, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: bad URL %q: %w", rawURL, err)
+	}
+	host := u.Hostname()
+	port := u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("ldap: unsupported scheme %q (want ldap:// or ldaps://)", u.Scheme)
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	d := net.Dialer{Timeout: timeout}
+	raw, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "ldaps" {
+		raw = tls.Client(raw, tlsConfig)
+	}
+	c := &Conn{conn: raw, r: bufio.NewReader(raw), timeout: timeout}
+	if startTLS && u.Scheme == "ldap" {
+		if err := c.startTLS(ctx, tlsConfig); err != nil {
+			raw.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close вежливо прощается с сервером (Unbind) и закрывает соединение.
+func (c *Conn) Close() error {
+	c.nextID++
+	_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = c.conn.Write(tlv(tagSequence, berInt(tagInteger, c.nextID), tlv(appUnbindRequest)))
+	return c.conn.Close()
+}
+
+// Bind -- простая привязка. Пустой пароль запрещён: сервер принял бы его как
+// анонимную привязку (RFC 4513, 5.1.2), и любой пароль "" считался бы верным.
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultInvalidCredentials, Message: "empty password"}
+	}
+	op := tlv(appBindRequest,
+		berInt(tagInteger, 3),
+		berString(tagOctetString, dn),
+		berString(ctxSimpleAuth, password),
+	)
+	resp, err := c.roundTrip(ctx, op, appBindResponse)
+	if err != nil {
+		return err
+	}
+	return resultError(resp[len(resp)-1])
+}
+
+// Search ищет записи и возвращает их вместе с запрошенными атрибутами.
+func (c *Conn) Search(ctx context.Context, baseDN string, scope Scope, filter string, attrs []string, sizeLimit int) ([]Entry, error) {
+	f, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	attrList := make([][]byte, 0, len(attrs))
+	for _, a := range attrs {
+		attrList = append(attrList, berString(tagOctetString, a))
+	}
+	op := tlv(appSearchRequest,
+		berString(tagOctetString, baseDN),
+		berInt(tagEnumerated, int(scope)),
+		berInt(tagEnumerated, derefAlways),
+		berInt(tagInteger, sizeLimit),
+		berInt(tagInteger, searchNoLimit),
+		berBool(false),
+		f,
+		tlv(tagSequence, attrList...),
+	)
+	resp, err := c.roundTrip(ctx, op, appSearchDone)
+	if err != nil {
+		return nil, err
+	}
+	if err := resultError(resp[len(resp)-1]); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, m := range resp[:len(resp)-1] {
+		if m.tag != appSearchEntry || len(m.children) < 2 {
+			continue // ссылки на другие серверы (referral) не проходим
+		}
+		e := Entry{DN: m.children[0].str(), Attributes: map[string][]string{}}
+		for _, a := range m.children[1].children {
+			if len(a.children) < 2 {
+				continue
+			}
+			name := a.children[0].str()
+			for _, v := range a.children[1].children {
+				e.Attributes[name] = append(e.Attributes[name], v.str())
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (c *Conn) startTLS(ctx context.Context, cfg *tls.Config) error {
+	op := tlv(appExtendedRequest, berString(ctxExtendedName, startTLSOID))
+	resp, err := c.roundTrip(ctx, op, appExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(resp[len(resp)-1]); err != nil {
+		return fmt.Errorf("ldap: StartTLS: %w", err)
+	}
+	tc := tls.Client(c.conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.conn, c.r = tc, bufio.NewReader(tc)
+	return nil
+}
+
+// roundTrip отправляет операцию и читает ответы до завершающего (тег final).
+// Возвращает protocolOp всех ответов; завершающий -- последним.
+func (c *Conn) roundTrip(ctx context.Context, op []byte, final byte) ([]element, error) {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	c.nextID++
+	id := c.nextID
+	if _, err := c.conn.Write(tlv(tagSequence, berInt(tagInteger, id), op)); err != nil {
+		return nil, err
+	}
+
+	var out []element
+	for {
+		msg, err := readElement(c.r)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: read response: %w", err)
+		}
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			return nil, errors.New("ldap: malformed response")
+		}
+		if msg.children[0].int() != id {
+			continue // например, Notice of Disconnection (ID 0) -- дочитаем до ошибки чтения
+		}
+		resp := msg.children[1]
+		out = append(out, resp)
+		if resp.tag == final {
+			return out, nil
+		}
+		if resp.tag != appSearchEntry && resp.tag != appSearchReference {
+			return nil, fmt.Errorf("ldap: unexpected response tag 0x%02x", resp.tag)
+		}
+	}
+}
+
+// resultError разбирает LDAPResult: resultCode, matchedDN, diagnosticMessage.
+func resultError(res element) error {
+	if len(res.children) < 3 {
+		return errors.New("ldap: malformed result")
+	}
+	if code := res.children[0].int(); code != ResultSuccess {
+		return &Error{Code: code, Message: res.children[2].str()}
+	}
+	return nil
+}
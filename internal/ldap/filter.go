@@ -0,0 +1,213 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Теги фильтра поиска (RFC 4511, 4.5.1): класс CONTEXT.
+const (
+	filterAnd        = 0xa0
+	filterOr         = 0xa1
+	filterNot        = 0xa2
+	filterEquality   = 0xa3
+	filterSubstrings = 0xa4
+	filterGreater    = 0xa5
+	filterLess       = 0xa6
+	filterPresent    = 0x87
+	filterApprox     = 0xa8
+	filterExtensible = 0xa9
+)
+
+// EscapeFilter экранирует значение для подстановки в фильтр (RFC 4515, 3):
+// без этого логин вида "*)(uid=*" менял бы смысл фильтра.
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter переводит строковый фильтр ("(&(objectClass=person)(uid=anna))") в BER.
+// Поддерживаются &, |, !, =, ~=, >=, <=, присутствие (attr=*), подстроки (cn=an*a)
+// и extensible-сравнения ("(member:1.2.840.113556.1.4.1941:=...)" -- вложенные группы AD).
+func compileFilter(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		s = "(" + s + ")"
+	}
+	out, rest, err := parseFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("ldap filter: unexpected %q", rest)
+	}
+	return out, nil
+}
+
+func parseFilter(s string) (out []byte, rest string, err error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("ldap filter: want '(' at %q", s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("ldap filter: unexpected end")
+	}
+	switch s[0] {
+	case '&', '|':
+		op, tag := s[0], byte(filterAnd)
+		if op == '|' {
+			tag = filterOr
+		}
+		var parts [][]byte
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			var part []byte
+			if part, s, err = parseFilter(s); err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part)
+		}
+		if !strings.HasPrefix(s, ")") || len(parts) == 0 {
+			return nil, "", fmt.Errorf("ldap filter: bad %c list", op)
+		}
+		return tlv(tag, parts...), s[1:], nil
+	case '!':
+		var part []byte
+		if part, s, err = parseFilter(s[1:]); err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("ldap filter: want ')' after !")
+		}
+		return tlv(filterNot, part), s[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("ldap filter: unclosed item %q", s)
+	}
+	item, rest := s[:end], s[end+1:]
+	out, err = compileItem(item)
+	return out, rest, err
+}
+
+// compileItem -- простое сравнение "attr<op>value".
+func compileItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("ldap filter: bad item %q", item)
+	}
+	attr, raw := item[:eq], item[eq+1:]
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '~':
+		tag, attr = filterApprox, attr[:len(attr)-1]
+	case '>':
+		tag, attr = filterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLess, attr[:len(attr)-1]
+	case ':':
+		return compileExtensible(attr[:len(attr)-1], raw)
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("ldap filter: bad item %q", item)
+	}
+	if tag == filterEquality && raw == "*" {
+		return berString(filterPresent, attr), nil
+	}
+	if tag == filterEquality && strings.Contains(raw, "*") {
+		return compileSubstrings(attr, raw)
+	}
+	value, err := unescapeFilter(raw)
+	if err != nil {
+		return nil, err
+	}
+	return tlv(tag, berString(tagOctetString, attr), berString(tagOctetString, value)), nil
+}
+
+func compileSubstrings(attr, raw string) ([]byte, error) {
+	pieces := strings.Split(raw, "*")
+	var subs [][]byte
+	for i, p := range pieces {
+		if p == "" {
+			continue
+		}
+		v, err := unescapeFilter(p)
+		if err != nil {
+			return nil, err
+		}
+		tag := byte(0x81) // any
+		switch i {
+		case 0:
+			tag = 0x80 // initial
+		case len(pieces) - 1:
+			tag = 0x82 // final
+		}
+		subs = append(subs, berString(tag, v))
+	}
+	return tlv(filterSubstrings, berString(tagOctetString, attr), tlv(tagSequence, subs...)), nil
+}
+
+// compileExtensible -- "attr:dn:rule" перед ":=" (любая часть может отсутствовать).
+func compileExtensible(spec, raw string) ([]byte, error) {
+	value, err := unescapeFilter(raw)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(spec, ":")
+	var attr, rule string
+	dnAttrs := false
+	attr, parts = parts[0], parts[1:]
+	for _, p := range parts {
+		if strings.EqualFold(p, "dn") {
+			dnAttrs = true
+		} else {
+			rule = p
+		}
+	}
+	var body [][]byte
+	if rule != "" {
+		body = append(body, berString(0x81, rule))
+	}
+	if attr != "" {
+		body = append(body, berString(0x82, attr))
+	}
+	body = append(body, berString(0x83, value))
+	if dnAttrs {
+		body = append(body, tlv(0x84, []byte{0xff}))
+	}
+	return tlv(filterExtensible, body...), nil
+}
+
+// unescapeFilter снимает экранирование \XX.
+func unescapeFilter(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("ldap filter: bad escape in %q", s)
+		}
+		v, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap filter: bad escape in %q", s)
+		}
+		b.Write(v)
+		i += 2
+	}
+	return b.String(), nil
+}
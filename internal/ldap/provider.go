@@ -0,0 +1,251 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"task-manager/internal/metrics"
+	"task-manager/internal/tasks"
+)
+
+var logins = metrics.NewCounterVec("ldap_logins_total",
+	"LDAP password checks by result: ok, invalid (unknown user, wrong password, no mapped group), error.", "result")
+
+// Config -- настройки входа через каталог (LDAP_*).
+type Config struct {
+	URL      string // ldap://dc1.corp.local или ldaps://ldap.example.com
+	StartTLS bool   // перейти на TLS по ldap:// до передачи паролей
+	CAFile   string // PEM с корневым сертификатом внутреннего УЦ (пусто -- системные)
+
+	// Учётка для поиска пользователей; пустой BindDN -- анонимный поиск.
+	BindDN       string
+	BindPassword string
+
+	// Где и как искать пользователя; {username} заменяется экранированным логином.
+	UserBase   string
+	UserFilter string
+
+	// Группы: атрибут записи пользователя (memberOf в AD) и/или отдельный поиск
+	// (GroupFilter с {dn} -- например, "(member={dn})" для groupOfNames в OpenLDAP).
+	GroupAttr   string
+	GroupBase   string
+	GroupFilter string
+
+	// GroupRoles -- какие группы дают какие роли; RequireRole -- без роли не пускать.
+	GroupRoles  []GroupRole
+	RequireRole bool
+
+	Timeout time.Duration
+}
+
+// GroupRole -- роль для членов группы. Group -- DN группы или только её имя (CN).
+type GroupRole struct {
+	Role  string
+	Group string
+}
+
+// ParseGroupRoles разбирает LDAP_GROUP_ROLES: "роль=группа;роль=группа".
+// Группа -- DN ("CN=Parents,OU=Groups,DC=corp,DC=local") или имя ("Parents").
+func ParseGroupRoles(s string) ([]GroupRole, error) {
+	var out []GroupRole
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		role, group, ok := strings.Cut(item, "=")
+		role, group = strings.TrimSpace(role), strings.TrimSpace(group)
+		if !ok || role == "" || group == "" {
+			return nil, fmt.Errorf("LDAP_GROUP_ROLES: want role=group, got %q", item)
+		}
+		out = append(out, GroupRole{Role: role, Group: group})
+	}
+	return out, nil
+}
+
+// Provider проверяет пароли по каталогу и переводит группы в роли.
+// Реализует tasks.IdentityProvider.
+type Provider struct {
+	cfg Config
+	tls *tls.Config
+}
+
+// NewProvider проверяет настройки и читает сертификат УЦ. К каталогу не подключается:
+// его недоступность при старте не должна мешать локальным пользователям.
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.URL == "" || cfg.UserBase == "" {
+		return nil, errors.New("LDAP_URL and LDAP_USER_BASE are required")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid={username})"
+	}
+	if !strings.Contains(cfg.UserFilter, "{username}") {
+		return nil, fmt.Errorf("LDAP_USER_FILTER must contain {username}: %q", cfg.UserFilter)
+	}
+	if _, err := compileFilter(strings.ReplaceAll(cfg.UserFilter, "{username}", "x")); err != nil {
+		return nil, fmt.Errorf("LDAP_USER_FILTER: %w", err)
+	}
+	if cfg.GroupFilter != "" {
+		if _, err := compileFilter(strings.ReplaceAll(cfg.GroupFilter, "{dn}", "x")); err != nil {
+			return nil, fmt.Errorf("LDAP_GROUP_FILTER: %w", err)
+		}
+	}
+	if cfg.RequireRole && len(cfg.GroupRoles) == 0 {
+		return nil, errors.New("LDAP_REQUIRE_ROLE needs LDAP_GROUP_ROLES")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	p := &Provider{cfg: cfg, tls: &tls.Config{MinVersion: tls.VersionTLS12}}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("LDAP_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("LDAP_CA_FILE: no certificates in %s", cfg.CAFile)
+		}
+		p.tls.RootCAs = pool
+	}
+	return p, nil
+}
+
+func (p *Provider) Name() string { return "ldap" }
+
+// Authenticate ищет пользователя служебной учёткой, собирает его группы и проверяет
+// пароль привязкой от его имени. Локальный логин -- в нижнем регистре: каталоги
+// сравнивают логины без учёта регистра, и "Anna" с "anna" -- один человек.
+func (p *Provider) Authenticate(ctx context.Context, username, password string) (*tasks.Identity, error) {
+	id, err := p.authenticate(ctx, strings.TrimSpace(username), password)
+	switch {
+	case err == nil:
+		logins.WithLabelValues("ok").Inc()
+	case errors.Is(err, tasks.ErrInvalidCredentials):
+		logins.WithLabelValues("invalid").Inc()
+	default:
+		logins.WithLabelValues("error").Inc()
+	}
+	return id, err
+}
+
+func (p *Provider) authenticate(ctx context.Context, username, password string) (*tasks.Identity, error) {
+	if username == "" || password == "" {
+		return nil, tasks.ErrInvalidCredentials
+	}
+	conn, err := Dial(ctx, p.cfg.URL, p.cfg.StartTLS, p.tls, p.cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: connect: %w", err)
+	}
+	defer conn.Close()
+
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(ctx, p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service bind: %w", err)
+		}
+	}
+	filter := strings.ReplaceAll(p.cfg.UserFilter, "{username}", EscapeFilter(username))
+	attrs := []string{"displayName", "cn", "mail"}
+	if p.cfg.GroupAttr != "" {
+		attrs = append(attrs, p.cfg.GroupAttr)
+	}
+	entries, err := conn.Search(ctx, p.cfg.UserBase, ScopeSubtree, filter, attrs, 2)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search user: %w", err)
+	}
+	switch len(entries) {
+	case 0:
+		return nil, tasks.ErrInvalidCredentials
+	case 1:
+	default:
+		return nil, fmt.Errorf("ldap: filter %s matches several entries", filter)
+	}
+	user := entries[0]
+
+	// Группы ищем до привязки пользователем: у него самого может не быть прав на поиск.
+	groups := user.Values(p.cfg.GroupAttr)
+	if p.cfg.GroupFilter != "" {
+		base := p.cfg.GroupBase
+		if base == "" {
+			base = p.cfg.UserBase
+		}
+		gf := strings.ReplaceAll(p.cfg.GroupFilter, "{dn}", EscapeFilter(user.DN))
+		found, err := conn.Search(ctx, base, ScopeSubtree, gf, []string{"cn"}, 0)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: search groups: %w", err)
+		}
+		for _, g := range found {
+			groups = append(groups, g.DN)
+		}
+	}
+
+	if err := conn.Bind(ctx, user.DN, password); err != nil {
+		if IsCode(err, ResultInvalidCredentials) {
+			return nil, tasks.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: user bind: %w", err)
+	}
+
+	roles := p.roles(groups)
+	if p.cfg.RequireRole && len(roles) == 0 {
+		log.Printf("ldap: %q is not in any group from LDAP_GROUP_ROLES, login refused", username)
+		return nil, tasks.ErrInvalidCredentials
+	}
+	displayName := user.Get("displayName")
+	if displayName == "" {
+		displayName = user.Get("cn")
+	}
+	return &tasks.Identity{
+		Username:    strings.ToLower(username),
+		DisplayName: displayName,
+		Email:       user.Get("mail"),
+		Roles:       roles,
+	}, nil
+}
+
+// roles -- роли по группам пользователя, в порядке LDAP_GROUP_ROLES, без повторов.
+func (p *Provider) roles(groups []string) []string {
+	var out []string
+	for _, gr := range p.cfg.GroupRoles {
+		if slices.Contains(out, gr.Role) {
+			continue
+		}
+		for _, g := range groups {
+			if groupMatches(gr.Group, g) {
+				out = append(out, gr.Role)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// groupMatches сравнивает группу из настроек с DN группы пользователя: целиком
+// (без учёта регистра и пробелов после запятых) или по имени -- значению первого RDN.
+func groupMatches(want, dn string) bool {
+	if strings.EqualFold(normalizeDN(want), normalizeDN(dn)) {
+		return true
+	}
+	if strings.Contains(want, "=") {
+		return false
+	}
+	rdn, _, _ := strings.Cut(dn, ",")
+	_, name, ok := strings.Cut(rdn, "=")
+	return ok && strings.EqualFold(strings.TrimSpace(name), want)
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonWriter превращает каждую запись пакета log в строку JSON:
+//
+//	{"time":"2026-01-02T15:04:05.123Z","level":"info","msg":"server started"}
+//
+// Сборщики логов контейнеров (Docker, Kubernetes, Loki) разбирают такие строки без регулярок.
+// Пакет log вызывает Write один раз на запись, поэтому запись -- это ровно один вызов.
+type jsonWriter struct {
+	w io.Writer
+}
+
+func (jw jsonWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	line, err := json.Marshal(struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}{time.Now().UTC().Format(time.RFC3339Nano), levelOf(msg), msg})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := jw.w.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// levelOf угадывает уровень по началу сообщения: уровней у пакета log нет, но ошибки
+// и предупреждения в проекте пишутся с префиксами ERROR/WARN (или "Предупреждение").
+func levelOf(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "ERROR"), strings.HasPrefix(msg, "FATAL"):
+		return "error"
+	case strings.HasPrefix(msg, "WARN"), strings.HasPrefix(msg, "Предупреждение"), strings.HasPrefix(msg, "ВНИМАНИЕ"):
+		return "warn"
+	}
+	return "info"
+}
+
+// IsTerminal сообщает, подключён ли f к терминалу (а не к файлу, каналу или сборщику логов контейнера).
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
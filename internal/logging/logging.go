@@ -0,0 +1,69 @@
+// Package logging -- куда пишутся логи приложения (пакет log) и журнал доступа:
+// stderr, stdout, файл с ротацией по размеру и времени или syslog. Без внешних обёрток
+// вроде logrotate и multilog.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Config -- назначение логов.
+type Config struct {
+	Output     string        // stderr (по умолчанию), stdout, file, syslog
+	File       string        // для file
+	MaxSizeMB  int           // для file: ротация по размеру (0 -- выключена)
+	MaxAge     time.Duration // для file: ротация по времени (0 -- выключена)
+	MaxBackups int           // для file: сколько старых файлов хранить
+	SyslogAddr string        // для syslog: "" -- локальный демон, иначе udp://host:514 или tcp://host:514
+	SyslogTag  string        // для syslog
+	Format     string        // text (по умолчанию) или json -- по объекту на строку
+}
+
+// Open создаёт писатель по конфигу. close закрывает файл или соединение с syslog.
+func Open(cfg Config) (w io.Writer, close func() error, err error) {
+	noop := func() error { return nil }
+	switch cfg.Output {
+	case "", "stderr":
+		return os.Stderr, noop, nil
+	case "stdout":
+		return os.Stdout, noop, nil
+	case "file":
+		if cfg.File == "" {
+			return nil, nil, fmt.Errorf("file output requires a file path")
+		}
+		rf, err := OpenRotatingFile(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.MaxAge, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rf, rf.Close, nil
+	case "syslog":
+		return openSyslog(cfg.SyslogAddr, cfg.SyslogTag)
+	}
+	return nil, nil, fmt.Errorf("unknown output %q (want stderr, stdout, file or syslog)", cfg.Output)
+}
+
+// Setup направляет стандартный логгер (пакет log) по конфигу. В syslog время и так
+// проставляет демон, а в JSON оно -- отдельное поле, поэтому там флаги даты отключаются.
+func Setup(cfg Config) (close func() error, err error) {
+	w, closeFn, err := Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("LOG_OUTPUT: %w", err)
+	}
+	switch cfg.Format {
+	case "", "text":
+	case "json":
+		w = jsonWriter{w}
+	default:
+		_ = closeFn()
+		return nil, fmt.Errorf("LOG_FORMAT: unknown format %q (want text or json)", cfg.Format)
+	}
+	log.SetOutput(w)
+	if cfg.Output == "syslog" || cfg.Format == "json" {
+		log.SetFlags(0)
+	}
+	return closeFn, nil
+}
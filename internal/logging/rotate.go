@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RotatingFile -- файл журнала с ротацией по размеру и/или по времени: когда файл превысит MaxSize
+// или станет старше MaxAge, он становится path.1, path.1 -- path.2 и так далее;
+// хранится не больше Backups старых файлов.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	backups int
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingFile открывает (дописывает) файл журнала. maxSize <= 0 и maxAge <= 0 -- без ротации
+// по соответствующему признаку.
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, backups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, backups: backups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	// Возраст дописываемого файла считаем от его последнего изменения: после перезапуска
+	// вчерашний журнал ротируется при первой записи, а не через сутки.
+	rf.f, rf.size, rf.opened = f, st.Size(), time.Now()
+	if st.Size() > 0 {
+		rf.opened = st.ModTime()
+	}
+	return nil
+}
+
+// Write дописывает p, при необходимости сначала ротируя файл.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.size > 0 && (rf.maxSize > 0 && rf.size+int64(len(p)) > rf.maxSize ||
+		rf.maxAge > 0 && time.Since(rf.opened) >= rf.maxAge) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate сдвигает старые файлы и начинает новый. Вызывающий держит rf.mu.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	if rf.backups <= 0 {
+		_ = os.Remove(rf.path)
+	} else {
+		_ = os.Remove(rf.backup(rf.backups))
+		for i := rf.backups - 1; i >= 1; i-- {
+			_ = os.Rename(rf.backup(i), rf.backup(i+1))
+		}
+		if err := os.Rename(rf.path, rf.backup(1)); err != nil {
+			return err
+		}
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.opened = time.Now()
+	return nil
+}
+
+func (rf *RotatingFile) backup(i int) string {
+	return rf.path + "." + strconv.Itoa(i)
+}
+
+// Close закрывает файл.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.f.Close()
+}
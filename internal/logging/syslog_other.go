@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog -- на этой платформе пакета log/syslog нет.
+func openSyslog(addr, tag string) (io.Writer, func() error, error) {
+	return nil, nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,30 @@
+//go:build !(windows || plan9)
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+	"strings"
+)
+
+// openSyslog подключается к syslog: к локальному демону (addr == "") или по сети
+// (udp://host:514, tcp://host:514). Сообщения уходят с уровнем INFO, facility DAEMON.
+func openSyslog(addr, tag string) (io.Writer, func() error, error) {
+	if tag == "" {
+		tag = "task-manager"
+	}
+	network, raddr := "", ""
+	if addr != "" {
+		var ok bool
+		network, raddr, ok = strings.Cut(addr, "://")
+		if !ok {
+			network, raddr = "udp", addr
+		}
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, w.Close, nil
+}
@@ -0,0 +1,283 @@
+// Package markdown -- небольшой рендерер markdown в HTML без внешних зависимостей,
+// чтобы тонким клиентам не нужна была своя библиотека.
+//
+// Поддерживается то, что реально пишут в описаниях задач: заголовки (#), абзацы, списки
+// (маркированные и нумерованные, с чекбоксами [ ]/[x]), цитаты (>), блоки кода (```),
+// горизонтальная черта, а в строке -- `код`, **жирный**, *курсив*/_курсив_, ~~зачёркнутый~~
+// и ссылки [текст](url).
+//
+// Результат безопасен по построению: весь текст экранируется, сырой HTML из markdown
+// не пропускается, ссылки -- только http(s), mailto и относительные (rel="nofollow noopener").
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Render переводит markdown в HTML-фрагмент.
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	r := renderer{lines: strings.Split(src, "\n")}
+	r.blocks()
+	return r.out.String()
+}
+
+type renderer struct {
+	lines []string
+	pos   int
+	out   strings.Builder
+}
+
+func (r *renderer) blocks() {
+	for r.pos < len(r.lines) {
+		line := r.lines[r.pos]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			r.pos++
+		case strings.HasPrefix(trimmed, "```"):
+			r.codeBlock()
+		case isRule(trimmed):
+			r.out.WriteString("<hr>\n")
+			r.pos++
+		case headingLevel(trimmed) > 0:
+			n := headingLevel(trimmed)
+			text := strings.TrimRight(strings.TrimSpace(trimmed[n:]), "#")
+			r.out.WriteString("<h" + strconv.Itoa(n) + ">" + inline(strings.TrimSpace(text)) + "</h" + strconv.Itoa(n) + ">\n")
+			r.pos++
+		case strings.HasPrefix(trimmed, ">"):
+			r.quote()
+		case listItem(trimmed) != "":
+			r.list(listItem(trimmed))
+		default:
+			r.paragraph()
+		}
+	}
+}
+
+// codeBlock -- ``` ... ```. Язык после ``` становится классом language-xxx.
+func (r *renderer) codeBlock() {
+	lang := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(r.lines[r.pos]), "```"))
+	r.pos++
+	var body []string
+	for r.pos < len(r.lines) && !strings.HasPrefix(strings.TrimSpace(r.lines[r.pos]), "```") {
+		body = append(body, r.lines[r.pos])
+		r.pos++
+	}
+	r.pos++ // закрывающие ``` (или конец текста)
+
+	r.out.WriteString("<pre><code")
+	if lang != "" && safeLang(lang) {
+		r.out.WriteString(` class="language-` + lang + `"`)
+	}
+	r.out.WriteString(">")
+	r.out.WriteString(html.EscapeString(strings.Join(body, "\n")))
+	r.out.WriteString("</code></pre>\n")
+}
+
+// quote -- подряд идущие строки с ">" рендерятся рекурсивно внутри <blockquote>.
+func (r *renderer) quote() {
+	var inner []string
+	for r.pos < len(r.lines) {
+		t := strings.TrimSpace(r.lines[r.pos])
+		if !strings.HasPrefix(t, ">") {
+			break
+		}
+		inner = append(inner, strings.TrimPrefix(strings.TrimPrefix(t, ">"), " "))
+		r.pos++
+	}
+	r.out.WriteString("<blockquote>\n")
+	r.out.WriteString(Render(strings.Join(inner, "\n")))
+	r.out.WriteString("</blockquote>\n")
+}
+
+// list -- подряд идущие пункты одного вида ("ul" или "ol"). Вложенность не поддерживается.
+func (r *renderer) list(kind string) {
+	r.out.WriteString("<" + kind + ">\n")
+	for r.pos < len(r.lines) {
+		t := strings.TrimSpace(r.lines[r.pos])
+		if listItem(t) != kind {
+			break
+		}
+		text := itemText(t)
+		switch {
+		case strings.HasPrefix(text, "[ ] "):
+			r.out.WriteString(`<li><input type="checkbox" disabled> ` + inline(text[4:]) + "</li>\n")
+		case strings.HasPrefix(text, "[x] "), strings.HasPrefix(text, "[X] "):
+			r.out.WriteString(`<li><input type="checkbox" checked disabled> ` + inline(text[4:]) + "</li>\n")
+		default:
+			r.out.WriteString("<li>" + inline(text) + "</li>\n")
+		}
+		r.pos++
+	}
+	r.out.WriteString("</" + kind + ">\n")
+}
+
+// paragraph -- строки до пустой строки или начала другого блока. Перевод строки внутри абзаца -- <br>.
+func (r *renderer) paragraph() {
+	var parts []string
+	for r.pos < len(r.lines) {
+		t := strings.TrimSpace(r.lines[r.pos])
+		if t == "" || strings.HasPrefix(t, "```") || strings.HasPrefix(t, ">") || isRule(t) ||
+			headingLevel(t) > 0 || listItem(t) != "" {
+			break
+		}
+		parts = append(parts, inline(t))
+		r.pos++
+	}
+	r.out.WriteString("<p>" + strings.Join(parts, "<br>\n") + "</p>\n")
+}
+
+func headingLevel(t string) int {
+	n := 0
+	for n < len(t) && n < 7 && t[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (len(t) > n && t[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+func isRule(t string) bool {
+	if len(t) < 3 {
+		return false
+	}
+	c := t[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	return strings.Count(strings.ReplaceAll(t, " ", ""), string(c)) == len(strings.ReplaceAll(t, " ", ""))
+}
+
+// listItem возвращает "ul", "ol" или "" для строки.
+func listItem(t string) string {
+	if len(t) >= 2 && (t[0] == '-' || t[0] == '*' || t[0] == '+') && t[1] == ' ' {
+		return "ul"
+	}
+	i := 0
+	for i < len(t) && t[i] >= '0' && t[i] <= '9' {
+		i++
+	}
+	if i > 0 && i < 10 && i+1 < len(t) && (t[i] == '.' || t[i] == ')') && t[i+1] == ' ' {
+		return "ol"
+	}
+	return ""
+}
+
+func itemText(t string) string {
+	if listItem(t) == "ul" {
+		return strings.TrimSpace(t[2:])
+	}
+	return strings.TrimSpace(t[strings.IndexAny(t, ".)")+1:])
+}
+
+func safeLang(lang string) bool {
+	for _, c := range lang {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '+') {
+			return false
+		}
+	}
+	return len(lang) <= 20
+}
+
+// inline рендерит разметку внутри строки. Текст экранируется по мере разбора.
+func inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()~#>-+.!", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "__"):
+			if n, ok := wrap(&b, s[i:], s[i:i+2], "strong"); ok {
+				i += n
+				continue
+			}
+		case strings.HasPrefix(s[i:], "~~"):
+			if n, ok := wrap(&b, s[i:], "~~", "del"); ok {
+				i += n
+				continue
+			}
+		case s[i] == '*' || s[i] == '_':
+			// _ внутри слова (snake_case) -- не курсив.
+			if s[i] == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			if n, ok := wrap(&b, s[i:], s[i:i+1], "em"); ok {
+				i += n
+				continue
+			}
+		case s[i] == '[':
+			if n, ok := link(&b, s[i:]); ok {
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// wrap ищет закрывающий marker и оборачивает содержимое в tag. Возвращает длину разобранного.
+func wrap(b *strings.Builder, s, marker, tag string) (int, bool) {
+	rest := s[len(marker):]
+	end := strings.Index(rest, marker)
+	if end <= 0 || rest[0] == ' ' || rest[end-1] == ' ' {
+		return 0, false
+	}
+	b.WriteString("<" + tag + ">" + inline(rest[:end]) + "</" + tag + ">")
+	return len(marker)*2 + end, true
+}
+
+// link разбирает [текст](url). Небезопасные ссылки (javascript: и т.п.) остаются текстом.
+func link(b *strings.Builder, s string) (int, bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return 0, false
+	}
+	closeURL := strings.IndexByte(s[closeText+2:], ')')
+	if closeURL < 0 {
+		return 0, false
+	}
+	text := s[1:closeText]
+	href := strings.TrimSpace(s[closeText+2 : closeText+2+closeURL])
+	if !safeURL(href) {
+		return 0, false
+	}
+	b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + inline(text) + "</a>")
+	return closeText + 2 + closeURL + 1, true
+}
+
+func safeURL(href string) bool {
+	if href == "" || strings.ContainsAny(href, " \t\n<>\"'`") {
+		return false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	case "":
+		// Относительная ссылка; "//host" -- ссылка на чужой хост без схемы, её не пропускаем.
+		return !strings.HasPrefix(href, "//")
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,349 @@
+// Package metrics -- минимальная реализация метрик в формате Prometheus (text exposition 0.0.4)
+// без внешних зависимостей: счётчики, gauge и гистограммы с метками.
+//
+// Метрики регистрируются в Default при создании и отдаются через Handler() на /metrics.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets -- границы гистограмм по умолчанию (секунды), как у client_golang.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector -- то, что умеет записать себя в формате Prometheus.
+type collector interface {
+	write(w io.Writer)
+	metricName() string
+}
+
+// Registry хранит зарегистрированные метрики.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry создаёт пустой реестр.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default -- реестр процесса, в него регистрируют метрики все пакеты.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.collectors[c.metricName()]; dup {
+		panic("metrics: duplicate metric " + c.metricName())
+	}
+	r.collectors[c.metricName()] = c
+}
+
+// Write пишет все метрики в текстовом формате Prometheus (в алфавитном порядке).
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cs := make([]collector, 0, len(names))
+	for _, name := range names {
+		cs = append(cs, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler отдаёт метрики Default-реестра.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Default.Write(w)
+	})
+}
+
+// vec -- общая часть всех *Vec: имя, метки и серии по значениям меток.
+type vec[T any] struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.RWMutex
+	series map[string]*T
+	keys   map[string][]string // ключ серии -> значения меток
+	newT   func() *T
+}
+
+func (v *vec[T]) metricName() string { return v.name }
+
+func (v *vec[T]) with(values ...string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s = v.newT()
+	v.series[key] = s
+	v.keys[key] = append([]string(nil), values...)
+	return s
+}
+
+// Reset удаляет все серии (нужно gauge, которые пересчитываются целиком, например по исполнителям).
+func (v *vec[T]) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.series = make(map[string]*T)
+	v.keys = make(map[string][]string)
+}
+
+// each обходит серии в стабильном порядке.
+func (v *vec[T]) each(fn func(values []string, s *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type item struct {
+		values []string
+		s      *T
+	}
+	items := make([]item, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, item{v.keys[k], v.series[k]})
+	}
+	v.mu.RUnlock()
+
+	for _, it := range items {
+		fn(it.values, it.s)
+	}
+}
+
+func (v *vec[T]) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+}
+
+func newVec[T any](name, help, kind string, labels []string, newT func() *T) *vec[T] {
+	return &vec[T]{
+		name: name, help: help, kind: kind, labels: labels,
+		series: make(map[string]*T), keys: make(map[string][]string), newT: newT,
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Counter
+// ---------------------------------------------------------------------------
+
+// Counter -- монотонно растущий счётчик.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add увеличивает счётчик. Отрицательные значения игнорируются (счётчик не убывает).
+func (c *Counter) Add(d float64) {
+	if d < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.v += d
+	c.mu.Unlock()
+}
+
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+// CounterVec -- семейство счётчиков с метками.
+type CounterVec struct{ *vec[Counter] }
+
+// NewCounterVec создаёт и регистрирует счётчик.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{newVec(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	Default.register(cv)
+	return cv
+}
+
+func (cv *CounterVec) WithLabelValues(values ...string) *Counter { return cv.with(values...) }
+
+func (cv *CounterVec) write(w io.Writer) {
+	cv.header(w)
+	cv.each(func(values []string, c *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", cv.name, labelString(cv.labels, values, "", ""), formatFloat(c.Value()))
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Gauge
+// ---------------------------------------------------------------------------
+
+// Gauge -- значение, которое может расти и убывать.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (g *Gauge) Set(v float64) { g.mu.Lock(); g.v = v; g.mu.Unlock() }
+func (g *Gauge) Add(d float64) { g.mu.Lock(); g.v += d; g.mu.Unlock() }
+func (g *Gauge) Inc()          { g.Add(1) }
+func (g *Gauge) Dec()          { g.Add(-1) }
+
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// GaugeVec -- семейство gauge с метками.
+type GaugeVec struct{ *vec[Gauge] }
+
+// NewGaugeVec создаёт и регистрирует gauge.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	gv := &GaugeVec{newVec(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	Default.register(gv)
+	return gv
+}
+
+func (gv *GaugeVec) WithLabelValues(values ...string) *Gauge { return gv.with(values...) }
+
+func (gv *GaugeVec) write(w io.Writer) {
+	gv.header(w)
+	gv.each(func(values []string, g *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", gv.name, labelString(gv.labels, values, "", ""), formatFloat(g.Value()))
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Histogram
+// ---------------------------------------------------------------------------
+
+// Histogram -- распределение наблюдений по корзинам (кумулятивные счётчики, как в Prometheus).
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Observe добавляет наблюдение.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, le := range h.buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// HistogramVec -- семейство гистограмм с метками.
+type HistogramVec struct{ *vec[Histogram] }
+
+// NewHistogramVec создаёт и регистрирует гистограмму. buckets == nil -- DefaultBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	hv := &HistogramVec{newVec(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: b, counts: make([]uint64, len(b))}
+	})}
+	Default.register(hv)
+	return hv
+}
+
+func (hv *HistogramVec) WithLabelValues(values ...string) *Histogram { return hv.with(values...) }
+
+func (hv *HistogramVec) write(w io.Writer) {
+	hv.header(w)
+	hv.each(func(values []string, h *Histogram) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, labelString(hv.labels, values, "le", formatFloat(le)), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, labelString(hv.labels, values, "le", "+Inf"), h.count)
+		base := labelString(hv.labels, values, "", "")
+		fmt.Fprintf(w, "%s_sum%s %s\n", hv.name, base, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", hv.name, base, h.count)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Форматирование
+// ---------------------------------------------------------------------------
+
+func labelString(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", n, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// escapeLabel оставляет только то, что %q не экранирует сам в стиле Prometheus.
+func escapeLabel(s string) string {
+	return strings.ToValidUTF8(s, "?")
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// SLOWindows -- окна, по которым считается burn rate (схема multi-window из SRE Workbook:
+// быстрый алерт -- 5m и 1h, медленный -- 30m и 6h).
+var SLOWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+// SLO отслеживает долю "хороших" HTTP-запросов и скорость расхода бюджета ошибок.
+//
+// Запрос плохой, если ответ 5xx или обработка дольше LatencyThreshold.
+// Burn rate = доля плохих запросов за окно / (1 - Target): 1 -- бюджет тратится ровно
+// в срок, 14.4 за час -- месячный бюджет кончится за двое суток.
+type SLO struct {
+	Target           float64
+	LatencyThreshold time.Duration
+
+	mu      sync.Mutex
+	total   uint64
+	bad     uint64
+	samples []sloSample // снимки счётчиков от Refresh, самые старые первыми
+
+	requests *CounterVec
+	burn     *GaugeVec
+	target   *GaugeVec
+}
+
+type sloSample struct {
+	at         time.Time
+	total, bad uint64
+}
+
+// NewSLO создаёт и регистрирует метрики SLO:
+//
+//	slo_requests_total{result="good|bad"}, slo_error_budget_burn_rate{window}, slo_target.
+func NewSLO(target float64, latencyThreshold time.Duration) *SLO {
+	s := &SLO{
+		Target:           target,
+		LatencyThreshold: latencyThreshold,
+		requests:         NewCounterVec("slo_requests_total", "HTTP requests counted against the SLO, by result.", "result"),
+		burn:             NewGaugeVec("slo_error_budget_burn_rate", "Error budget burn rate over the window (1 = budget spent exactly over the SLO period).", "window"),
+		target:           NewGaugeVec("slo_target", "SLO target ratio of good requests."),
+	}
+	s.target.WithLabelValues().Set(target)
+	return s
+}
+
+// Observe учитывает один завершённый запрос.
+func (s *SLO) Observe(status int, duration time.Duration) {
+	bad := status >= 500 || (s.LatencyThreshold > 0 && duration > s.LatencyThreshold)
+
+	s.mu.Lock()
+	s.total++
+	if bad {
+		s.bad++
+	}
+	s.mu.Unlock()
+
+	if bad {
+		s.requests.WithLabelValues("bad").Inc()
+	} else {
+		s.requests.WithLabelValues("good").Inc()
+	}
+}
+
+// Refresh снимает срез счётчиков и пересчитывает burn rate по всем окнам.
+// Вызывается периодически; точность окна -- интервал между вызовами.
+func (s *SLO) Refresh(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := sloSample{at: now, total: s.total, bad: s.bad}
+	s.samples = append(s.samples, cur)
+
+	// Храним историю только на самое длинное окно (+ один срез до его начала).
+	longest := SLOWindows[len(SLOWindows)-1]
+	drop := 0
+	for drop+1 < len(s.samples) && now.Sub(s.samples[drop+1].at) >= longest {
+		drop++
+	}
+	s.samples = s.samples[drop:]
+
+	budget := 1 - s.Target
+	for _, window := range SLOWindows {
+		base := s.samples[0]
+		for _, sm := range s.samples {
+			if now.Sub(sm.at) < window {
+				break
+			}
+			base = sm
+		}
+
+		rate := 0.0
+		if total := cur.total - base.total; total > 0 && budget > 0 {
+			rate = float64(cur.bad-base.bad) / float64(total) / budget
+		}
+		s.burn.WithLabelValues(formatWindow(window)).Set(rate)
+	}
+}
+
+// formatWindow печатает окно в стиле PromQL: 5m, 1h, 6h.
+func formatWindow(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return formatFloat(d.Hours()) + "h"
+	}
+	return formatFloat(d.Minutes()) + "m"
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Форматы журнала доступа (ACCESS_LOG_FORMAT).
+const (
+	AccessLogJSON     = "json"     // одна JSON-строка на запрос
+	AccessLogCommon   = "common"   // Common Log Format (CLF)
+	AccessLogCombined = "combined" // CLF + Referer и User-Agent (как у nginx/Apache)
+)
+
+// AccessLog пишет по строке на каждый запрос в отдельный от логов приложения поток
+// (файл или stdout), чтобы его можно было отдать в привычные инструменты: GoAccess, AWStats, fluentd.
+type AccessLog struct {
+	format string
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewAccessLog создаёт журнал доступа в формате format (json, common, combined).
+func NewAccessLog(format string, out io.Writer) (*AccessLog, error) {
+	switch format {
+	case AccessLogJSON, AccessLogCommon, AccessLogCombined:
+	default:
+		return nil, fmt.Errorf("ACCESS_LOG_FORMAT: must be json, common or combined, got %q", format)
+	}
+	return &AccessLog{format: format, out: out}, nil
+}
+
+// accessLogEntry -- запись журнала в формате json.
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	TraceID    string    `json:"trace_id,omitempty"` // след W3C, см. TraceMiddleware
+	ClientIP   string    `json:"client_ip"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int       `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// Middleware пишет запись после обработки запроса. Адрес клиента -- из ClientIPMiddleware,
+// поэтому журнал подключается после него.
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		tc, _ := GetTrace(r.Context())
+		e := accessLogEntry{
+			Time:       start,
+			RequestID:  GetRequestID(r.Context()),
+			TraceID:    tc.TraceID,
+			ClientIP:   ClientIP(r),
+			Method:     r.Method,
+			URI:        r.URL.RequestURI(),
+			Proto:      r.Proto,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		}
+		a.write(e)
+	})
+}
+
+func (a *AccessLog) write(e accessLogEntry) {
+	var line []byte
+	switch a.format {
+	case AccessLogJSON:
+		line, _ = json.Marshal(e)
+	default:
+		// host ident authuser [date] "request" status bytes ["referer" "user-agent"]
+		// authuser всегда "-": пользователь определяется глубже, в обработчиках маршрутов.
+		bytes := "-"
+		if e.Bytes > 0 {
+			bytes = strconv.Itoa(e.Bytes)
+		}
+		s := fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %s`, e.ClientIP, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			e.Method, clfEscape(e.URI), e.Proto, e.Status, bytes)
+		if a.format == AccessLogCombined {
+			s += fmt.Sprintf(` "%s" "%s"`, clfField(e.Referer), clfField(e.UserAgent))
+		}
+		line = []byte(s)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, _ = a.out.Write(line)
+}
+
+// clfEscape экранирует кавычки и управляющие символы, чтобы запрос не разорвал строку журнала.
+func clfEscape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteString(`\` + string(c))
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return clfEscape(s)
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"task-manager/internal/secrets"
+)
+
+// AdminKeyHeader -- заголовок с ключом администратора (ADMIN_KEY).
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminKeyMiddleware пускает к служебным эндпоинтам только с правильным X-Admin-Key.
+// Если ключ не задан, админский API выключен целиком (404, чтобы не светить его наличие).
+// Ключ проверяется на каждом запросе, поэтому ротация в хранилище секретов подхватывается на лету.
+func AdminKeyMiddleware(key *secrets.Value) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key.Current() == "" {
+				WriteError(w, r, http.StatusNotFound, "not_found", "Route not found",
+					map[string]any{"path": r.URL.Path})
+				return
+			}
+			if !key.Accepts(r.Header.Get(AdminKeyHeader), time.Now()) {
+				WriteError(w, r, http.StatusUnauthorized, "unauthorized", "Invalid admin key", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LogAdminAction пишет в лог действие администратора вместе с request_id и адресом клиента.
+func LogAdminAction(r *http.Request, format string, args ...any) {
+	log.Printf("request_id=%s admin action from %s: %s", GetRequestID(r.Context()), ClientIP(r), fmt.Sprintf(format, args...))
+}
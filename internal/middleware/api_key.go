@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIKeyHeader -- заголовок с ключом интеграции. Для no-code платформ, которые не умеют
+// заголовки, ключ также принимается в query-параметре api_key.
+const APIKeyHeader = "X-API-Key"
+
+// ParseAPIKeys разбирает строку вида "key1=1,key2=3" (ключ=ID пользователя).
+// Действия, выполненные по ключу, совершаются от имени этого пользователя.
+func ParseAPIKeys(s string) map[string]int {
+	keys := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		key, id, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			continue
+		}
+		userID, err := strconv.Atoi(id)
+		if err != nil || userID <= 0 {
+			continue
+		}
+		keys[key] = userID
+	}
+	return keys
+}
+
+// APIKeyMiddleware авторизует запросы интеграций по статическому ключу
+// и кладёт ID связанного пользователя в контекст (как AuthMiddleware).
+func APIKeyMiddleware(keys map[string]int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(APIKeyHeader)
+			if provided == "" {
+				provided = r.URL.Query().Get("api_key")
+			}
+
+			userID, ok := lookupAPIKey(keys, provided)
+			if !ok {
+				WriteError(w, r, http.StatusUnauthorized, "unauthorized", "Invalid API key", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// lookupAPIKey сравнивает ключ со всеми известными за постоянное время,
+// чтобы время ответа не подсказывало, насколько ключ "похож" на настоящий.
+func lookupAPIKey(keys map[string]int, provided string) (int, bool) {
+	if provided == "" {
+		return 0, false
+	}
+	found, userID := 0, 0
+	for key, id := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(provided)) == 1 {
+			found, userID = 1, id
+		}
+	}
+	return userID, found == 1
+}
@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var authFailures = metrics.NewCounterVec("auth_failures_total",
+	"Failed password authentication attempts by source (login, caldav) and reason (invalid_credentials, locked_out).",
+	"source", "reason")
+
+// recentAuthFailures -- сколько последних неудач помнит AuthGuard для админского API.
+const recentAuthFailures = 200
+
+// maxAuthKeys -- потолок отслеживаемых ключей: перебор случайных логинов и адресов не раздувает
+// память. На потолке сначала вычищаются давние записи без блокировки (prune), а если их не хватило --
+// вытесняются самые старые (evict).
+const maxAuthKeys = 10000
+
+// AuthGuardConfig -- настройки блокировки. Ноль в MaxFailures/MaxFailuresIP выключает
+// соответствующий счётчик.
+type AuthGuardConfig struct {
+	MaxFailures   int           // неудач подряд на один логин до блокировки
+	MaxFailuresIP int           // неудач подряд с одного адреса до блокировки (за NAT сидит вся семья -- порог выше)
+	BaseCooldown  time.Duration // первая блокировка; каждая следующая -- вдвое дольше
+	MaxCooldown   time.Duration // потолок блокировки
+}
+
+// AuthFailure -- запись о неудачной попытке входа.
+type AuthFailure struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+	IP       string    `json:"ip"`
+	Source   string    `json:"source"` // login, caldav
+	Reason   string    `json:"reason"` // invalid_credentials, locked_out
+}
+
+// AuthLock -- текущая блокировка логина или адреса.
+type AuthLock struct {
+	Kind        string    `json:"kind"` // user, ip
+	Value       string    `json:"value"`
+	LockedUntil time.Time `json:"locked_until"`
+	Lockouts    int       `json:"lockouts"` // сколько раз подряд блокировался (от этого растёт срок)
+}
+
+type authKey struct {
+	kind, value string
+}
+
+type authCounter struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// AuthGuard считает неудачные попытки входа по паролю (JWT-логин, Basic в CalDAV) на логин
+// и на адрес клиента и временно блокирует их после серии неудач. Срок блокировки растёт
+// экспоненциально: BaseCooldown, 2×, 4×, ... до MaxCooldown. Пока логин или адрес
+// заблокирован, пароль не проверяется вовсе.
+//
+// Состояние живёт в памяти процесса: после перезапуска счётчики начинаются заново.
+// Методы безопасны для nil (защита выключена).
+type AuthGuard struct {
+	cfg AuthGuardConfig
+
+	mu       sync.Mutex
+	counters map[authKey]*authCounter
+	recent   []AuthFailure // кольцо, next -- место следующей записи
+	next     int
+}
+
+// NewAuthGuard создаёт AuthGuard. Если оба порога нулевые, возвращает nil (защита выключена).
+func NewAuthGuard(cfg AuthGuardConfig) *AuthGuard {
+	if cfg.MaxFailures <= 0 && cfg.MaxFailuresIP <= 0 {
+		return nil
+	}
+	if cfg.BaseCooldown <= 0 {
+		cfg.BaseCooldown = 30 * time.Second
+	}
+	if cfg.MaxCooldown < cfg.BaseCooldown {
+		cfg.MaxCooldown = cfg.BaseCooldown
+	}
+	return &AuthGuard{cfg: cfg, counters: make(map[authKey]*authCounter)}
+}
+
+// Check говорит, заблокированы ли логин или адрес, и сколько ещё ждать.
+// Попытка при блокировке тоже попадает в журнал неудач.
+func (g *AuthGuard) Check(username, ip, source string, now time.Time) (retryAfter time.Duration, locked bool) {
+	if g == nil {
+		return 0, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, k := range g.keys(username, ip) {
+		if c := g.counters[k]; c != nil && now.Before(c.lockedUntil) {
+			retryAfter = max(retryAfter, c.lockedUntil.Sub(now))
+		}
+	}
+	if retryAfter <= 0 {
+		return 0, false
+	}
+	g.record(AuthFailure{Time: now.UTC(), Username: username, IP: ip, Source: source, Reason: "locked_out"})
+	return retryAfter, true
+}
+
+// Fail учитывает неудачную попытку и при достижении порога блокирует логин и/или адрес.
+func (g *AuthGuard) Fail(username, ip, source string, now time.Time) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.record(AuthFailure{Time: now.UTC(), Username: username, IP: ip, Source: source, Reason: "invalid_credentials"})
+	keys := g.keys(username, ip)
+	if len(g.counters) > maxAuthKeys-len(keys) {
+		g.prune(now)
+		g.evict(now, keys)
+	}
+	for _, k := range keys {
+		c := g.counters[k]
+		if c == nil {
+			c = &authCounter{}
+			g.counters[k] = c
+		}
+		// Серия забывается, если с конца последней блокировки (или с последней неудачи) прошло больше MaxCooldown.
+		if !c.lastFailure.IsZero() && now.Sub(c.lastFailure) > g.cfg.MaxCooldown+g.cooldown(c.lockouts) {
+			c.failures, c.lockouts = 0, 0
+		}
+		c.failures++
+		c.lastFailure = now
+		if c.failures >= g.limit(k.kind) {
+			c.lockouts++
+			c.failures = 0
+			c.lockedUntil = now.Add(g.cooldown(c.lockouts))
+		}
+	}
+}
+
+// Succeed сбрасывает счётчик логина после успешного входа. Счётчик адреса не сбрасывается:
+// иначе, зная один пароль, можно было бы бесконечно перебирать чужие.
+func (g *AuthGuard) Succeed(username string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.counters, authKey{"user", username})
+}
+
+// Recent возвращает последние неудачи, новые первыми.
+func (g *AuthGuard) Recent(limit int) []AuthFailure {
+	out := []AuthFailure{}
+	if g == nil {
+		return out
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := len(g.recent)
+	for i := 0; i < n && (limit <= 0 || len(out) < limit); i++ {
+		out = append(out, g.recent[(g.next-1-i+n)%n])
+	}
+	return out
+}
+
+// Locks возвращает действующие блокировки, самые долгие первыми.
+func (g *AuthGuard) Locks(now time.Time) []AuthLock {
+	out := []AuthLock{}
+	if g == nil {
+		return out
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for k, c := range g.counters {
+		if now.Before(c.lockedUntil) {
+			out = append(out, AuthLock{Kind: k.kind, Value: k.value, LockedUntil: c.lockedUntil.UTC(), Lockouts: c.lockouts})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LockedUntil.After(out[j].LockedUntil) })
+	return out
+}
+
+// Unlock снимает блокировку и сбрасывает счётчик (kind -- user или ip). Возвращает false,
+// если такого счётчика нет.
+func (g *AuthGuard) Unlock(kind, value string) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	k := authKey{kind, value}
+	if _, ok := g.counters[k]; !ok {
+		return false
+	}
+	delete(g.counters, k)
+	return true
+}
+
+// RetryAfterHeader -- значение заголовка Retry-After в целых секундах (с округлением вверх).
+func RetryAfterHeader(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+func (g *AuthGuard) keys(username, ip string) []authKey {
+	keys := make([]authKey, 0, 2)
+	if g.cfg.MaxFailures > 0 && username != "" {
+		keys = append(keys, authKey{"user", username})
+	}
+	if g.cfg.MaxFailuresIP > 0 && ip != "" {
+		keys = append(keys, authKey{"ip", ip})
+	}
+	return keys
+}
+
+func (g *AuthGuard) limit(kind string) int {
+	if kind == "ip" {
+		return g.cfg.MaxFailuresIP
+	}
+	return g.cfg.MaxFailures
+}
+
+// cooldown -- срок n-й блокировки подряд: BaseCooldown * 2^(n-1), не больше MaxCooldown.
+func (g *AuthGuard) cooldown(n int) time.Duration {
+	d := g.cfg.BaseCooldown
+	for i := 1; i < n && d < g.cfg.MaxCooldown; i++ {
+		d *= 2
+	}
+	return min(d, g.cfg.MaxCooldown)
+}
+
+// record пишет неудачу в кольцо и в метрики. Вызывающий держит g.mu.
+func (g *AuthGuard) record(f AuthFailure) {
+	authFailures.WithLabelValues(f.Source, f.Reason).Inc()
+	if len(g.recent) < recentAuthFailures {
+		g.recent = append(g.recent, f)
+		g.next = len(g.recent) % recentAuthFailures
+		return
+	}
+	g.recent[g.next] = f
+	g.next = (g.next + 1) % recentAuthFailures
+}
+
+// evict освобождает место под ключи текущей неудачи keep, если prune не помог (перебор идёт быстрее, чем
+// забываются серии). Выбрасываются самые давние: сначала незаблокированные, затем заблокированные --
+// память важнее, а заблокированный перебором логин отделается досрочным концом блокировки.
+// Сами ключи keep не вытесняются, иначе перебор сбрасывал бы счётчик атакуемого логина.
+// Освобождается десятая часть потолка, чтобы не сортировать счётчики на каждой неудаче.
+// Вызывающий держит g.mu.
+func (g *AuthGuard) evict(now time.Time, keep []authKey) {
+	if len(g.counters) <= maxAuthKeys-len(keep) {
+		return
+	}
+	type entry struct {
+		key    authKey
+		locked bool
+		last   time.Time
+	}
+	list := make([]entry, 0, len(g.counters))
+	for k, c := range g.counters {
+		if !slices.Contains(keep, k) {
+			list = append(list, entry{k, now.Before(c.lockedUntil), c.lastFailure})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].locked != list[j].locked {
+			return !list[i].locked
+		}
+		return list[i].last.Before(list[j].last)
+	})
+	n := len(g.counters) - (maxAuthKeys - maxAuthKeys/10)
+	for _, e := range list[:n] {
+		delete(g.counters, e.key)
+	}
+}
+
+// prune выбрасывает незаблокированные счётчики, по которым давно не было неудач. Вызывающий держит g.mu.
+func (g *AuthGuard) prune(now time.Time) {
+	for k, c := range g.counters {
+		if !now.Before(c.lockedUntil) && now.Sub(c.lastFailure) > g.cfg.MaxCooldown {
+			delete(g.counters, k)
+		}
+	}
+}
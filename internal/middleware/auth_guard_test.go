@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestAuthGuardKeysBounded -- перебор уникальных логинов и адресов не раздувает память сверх maxAuthKeys,
+// даже когда prune вычистить нечего; заблокированный логин при этом остаётся заблокированным.
+func TestAuthGuardKeysBounded(t *testing.T) {
+	g := NewAuthGuard(AuthGuardConfig{MaxFailures: 3, MaxFailuresIP: 50, BaseCooldown: time.Minute, MaxCooldown: time.Hour})
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	for range 3 {
+		g.Fail("mom", "198.51.100.1", "login", now)
+	}
+	if _, locked := g.Check("mom", "", "login", now); !locked {
+		t.Fatal("mom is not locked after 3 failures")
+	}
+
+	for i := range 3 * maxAuthKeys {
+		now = now.Add(time.Millisecond)
+		g.Fail("user"+strconv.Itoa(i), "10.0."+strconv.Itoa(i/250%250)+"."+strconv.Itoa(i%250), "login", now)
+		if n := len(g.counters); n > maxAuthKeys {
+			t.Fatalf("after %d failures tracking %d keys, cap is %d", i+1, n, maxAuthKeys)
+		}
+	}
+	if _, locked := g.Check("mom", "", "login", now); !locked {
+		t.Error("flood of unlocked keys evicted a locked login")
+	}
+
+	// Последняя неудача учтена: её ключи не вытесняются.
+	last := "user" + strconv.Itoa(3*maxAuthKeys-1)
+	if c := g.counters[authKey{"user", last}]; c == nil || c.failures != 1 {
+		t.Errorf("counter of the latest failure: %+v", c)
+	}
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Политика аутентификации API: одно место, где для каждого маршрута решается, нужен ли токен,
+// ключ интеграции или ничего. Маршрут, не описанный в политике, требует JWT -- новый маршрут
+// не окажется открытым по забывчивости.
+
+// AuthMode -- способ аутентификации маршрута.
+type AuthMode int
+
+const (
+	// AuthJWT -- Bearer-токен пользователя (по умолчанию).
+	AuthJWT AuthMode = iota
+	// AuthPublic -- без аутентификации (вход, регистрация).
+	AuthPublic
+	// AuthAPIKey -- ключ интеграции (X-API-Key или ?api_key=).
+	AuthAPIKey
+)
+
+// RoutePattern -- метод и шаблон пути: "GET /api/v1/tasks", "/api/v1/tasks/{id}", "/api/v1/integrations/*".
+// {name} -- один любой сегмент, * в конце -- любой остаток пути. Метод "" -- любой.
+type RoutePattern struct {
+	Method string
+	Path   string
+}
+
+// ParseRoutePattern разбирает "METHOD /path" или "/path".
+func ParseRoutePattern(s string) (RoutePattern, error) {
+	s = strings.TrimSpace(s)
+	var p RoutePattern
+	if method, path, ok := strings.Cut(s, " "); ok {
+		p.Method, s = strings.ToUpper(method), strings.TrimSpace(path)
+	}
+	if !strings.HasPrefix(s, "/") {
+		return p, fmt.Errorf("route %q: path must start with /", s)
+	}
+	if i := strings.Index(s, "*"); i >= 0 && i != len(s)-1 {
+		return p, fmt.Errorf("route %q: * is allowed only at the end", s)
+	}
+	p.Path = s
+	return p, nil
+}
+
+// ParseAnonymousRoutes разбирает список маршрутов для анонимного доступа через запятую:
+// "GET /api/v1/tasks, /api/v1/agenda". Анонимный доступ -- только чтение: метод по умолчанию
+// GET, другие, кроме HEAD, -- ошибка.
+func ParseAnonymousRoutes(s string) ([]RoutePattern, error) {
+	var routes []RoutePattern
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		p, err := ParseRoutePattern(part)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Method {
+		case "":
+			p.Method = http.MethodGet
+		case http.MethodGet, http.MethodHead:
+		default:
+			return nil, fmt.Errorf("route %q: anonymous access is read-only (GET or HEAD), got %s", part, p.Method)
+		}
+		routes = append(routes, p)
+	}
+	return routes, nil
+}
+
+// Match сообщает, подходит ли запрос под шаблон: HEAD подходит к GET-шаблону, хвостовой "/" пути не важен.
+func (p RoutePattern) Match(method, path string) bool {
+	if p.Method != "" && p.Method != method && !(p.Method == http.MethodGet && method == http.MethodHead) {
+		return false
+	}
+	want := strings.Split(strings.Trim(p.Path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range want {
+		if seg == "*" {
+			return true
+		}
+		if i >= len(got) {
+			return false
+		}
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != got[i] {
+			return false
+		}
+	}
+	return len(got) == len(want)
+}
+
+type authRoute struct {
+	pattern RoutePattern
+	mode    AuthMode
+}
+
+// AuthPolicy решает, как аутентифицировать запрос. Заполняется до начала обслуживания.
+type AuthPolicy struct {
+	routes        []authRoute
+	apiKeys       map[string]int
+	anonymous     []RoutePattern
+	anonymousUser int
+}
+
+// NewAuthPolicy создаёт политику, в которой любой маршрут требует JWT.
+func NewAuthPolicy() *AuthPolicy {
+	return &AuthPolicy{}
+}
+
+// Route задаёт способ аутентификации маршрутов (шаблоны как у ParseRoutePattern). Действует
+// первое подходящее правило. Неверный шаблон -- ошибка программиста, паника.
+func (p *AuthPolicy) Route(mode AuthMode, patterns ...string) *AuthPolicy {
+	for _, s := range patterns {
+		rp, err := ParseRoutePattern(s)
+		if err != nil {
+			panic(err)
+		}
+		p.routes = append(p.routes, authRoute{pattern: rp, mode: mode})
+	}
+	return p
+}
+
+// SetAPIKeys задаёт ключи интеграций для маршрутов AuthAPIKey (ключ -> ID пользователя).
+func (p *AuthPolicy) SetAPIKeys(keys map[string]int) *AuthPolicy {
+	p.apiKeys = keys
+	return p
+}
+
+// AllowAnonymous разрешает запросы без токена к routes (только чтение): они выполняются
+// от имени пользователя userID, например табло-киоск со списком задач семьи. Запрос с токеном
+// по-прежнему проверяется и выполняется от имени владельца токена.
+func (p *AuthPolicy) AllowAnonymous(userID int, routes []RoutePattern) *AuthPolicy {
+	p.anonymousUser, p.anonymous = userID, routes
+	return p
+}
+
+func (p *AuthPolicy) mode(r *http.Request) AuthMode {
+	for _, rt := range p.routes {
+		if rt.pattern.Match(r.Method, r.URL.Path) {
+			return rt.mode
+		}
+	}
+	return AuthJWT
+}
+
+func (p *AuthPolicy) anonymousAllowed(r *http.Request) bool {
+	if p.anonymousUser <= 0 {
+		return false
+	}
+	for _, rp := range p.anonymous {
+		if rp.Match(r.Method, r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware применяет политику и кладёт ID пользователя в контекст (UserIDKey).
+func (p *AuthPolicy) Middleware(next http.Handler) http.Handler {
+	jwtAuth := AuthMiddleware(next)
+	apiKeyAuth := APIKeyMiddleware(p.apiKeys)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p.mode(r) {
+		case AuthPublic:
+			next.ServeHTTP(w, r)
+		case AuthAPIKey:
+			apiKeyAuth.ServeHTTP(w, r)
+		default:
+			if r.Header.Get("Authorization") == "" && p.anonymousAllowed(r) {
+				next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), p.anonymousUser)))
+				return
+			}
+			jwtAuth.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var (
+	mutationsInFlight = metrics.NewGaugeVec("mutation_inflight",
+		"Mutating requests currently being processed.")
+	mutationQueueDepth = metrics.NewGaugeVec("mutation_queue_depth",
+		"Mutating requests waiting for a free slot.")
+	mutationsRejected = metrics.NewCounterVec("mutation_rejected_total",
+		"Mutating requests rejected with 503 by backpressure, by reason.", "reason")
+)
+
+// Backpressure ограничивает число одновременно выполняемых изменяющих запросов.
+//
+// Без ограничения под нагрузкой все мутации одновременно ждут блокировок в сервисе/хранилище,
+// и латентность растёт у всех. Здесь не больше workers запросов выполняются параллельно,
+// ещё до queue ждут в очереди (не дольше wait), остальные сразу получают 503 с Retry-After.
+// Чтение не ограничивается.
+type Backpressure struct {
+	slots   chan struct{}
+	queue   int64
+	wait    time.Duration
+	waiting atomic.Int64
+}
+
+// NewBackpressure создаёт ограничитель: workers -- параллельных мутаций,
+// queue -- длина очереди ожидания, wait -- максимальное время в очереди.
+func NewBackpressure(workers, queue int, wait time.Duration) *Backpressure {
+	return &Backpressure{
+		slots: make(chan struct{}, workers),
+		queue: int64(queue),
+		wait:  wait,
+	}
+}
+
+// Middleware применяет ограничение к изменяющим запросам.
+func (b *Backpressure) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Быстрый путь: свободный слот есть -- очередь не нужна.
+		select {
+		case b.slots <- struct{}{}:
+		default:
+			if !b.enqueue(w, r) {
+				return
+			}
+		}
+
+		mutationsInFlight.WithLabelValues().Inc()
+		defer func() {
+			<-b.slots
+			mutationsInFlight.WithLabelValues().Dec()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enqueue ждёт слот в очереди. false -- запрос отклонён, ответ уже записан.
+func (b *Backpressure) enqueue(w http.ResponseWriter, r *http.Request) bool {
+	if b.waiting.Add(1) > b.queue {
+		b.waiting.Add(-1)
+		b.reject(w, r, "queue_full")
+		return false
+	}
+	mutationQueueDepth.WithLabelValues().Set(float64(b.waiting.Load()))
+	defer func() {
+		mutationQueueDepth.WithLabelValues().Set(float64(b.waiting.Add(-1)))
+	}()
+
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		b.reject(w, r, "queue_timeout")
+	case <-r.Context().Done():
+		// Клиент ушёл или сервер останавливается -- отвечать уже некому, но метрику учтём.
+		b.reject(w, r, "canceled")
+	}
+	return false
+}
+
+func (b *Backpressure) reject(w http.ResponseWriter, r *http.Request, reason string) {
+	mutationsRejected.WithLabelValues(reason).Inc()
+	w.Header().Set("Retry-After", strconv.Itoa(1))
+	WriteError(w, r, http.StatusServiceUnavailable, "overloaded", "Server is busy, retry later",
+		map[string]any{"reason": reason})
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+// Хаос для демонстраций устойчивости: случайная задержка и ошибки с заданной вероятностью
+// по маршрутам. Включается только явно (CHAOS_ENABLED) -- в рабочей конфигурации его нет.
+
+var chaosInjected = metrics.NewCounterVec("chaos_injected_total",
+	"Requests affected by the chaos middleware, by kind (latency or error).", "kind")
+
+// maxChaosDelay ограничивает ?delay, чтобы один запрос не держал соединение бесконечно.
+const maxChaosDelay = 30 * time.Second
+
+// ChaosRule -- хаос для запросов с методом Method ("" -- любой) и путём с префиксом Prefix.
+type ChaosRule struct {
+	Method     string
+	Prefix     string
+	LatencyMin time.Duration
+	LatencyMax time.Duration
+	ErrorRate  float64 // доля запросов (0..1), которые получат ErrorCode вместо ответа
+	ErrorCode  int     // по умолчанию 503
+}
+
+// ParseChaosRules разбирает правила вида
+//
+//	"GET /api/v1/tasks=latency:100ms-800ms,error:5%;/api/v1/=latency:50ms;*=error:1%,status:500"
+//
+// Правила через ";", слева от "=" -- метод (необязательно) и префикс пути ("*" -- любой путь),
+// справа -- параметры через ",": latency (одна длительность или диапазон), error (процент
+// запросов с ошибкой), status (код ошибки, 5xx). Действует первое подходящее правило.
+func ParseChaosRules(s string) ([]ChaosRule, error) {
+	var rules []ChaosRule
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		route, opts, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q: expected ROUTE=OPTIONS", part)
+		}
+		rule := ChaosRule{ErrorCode: http.StatusServiceUnavailable}
+		route = strings.TrimSpace(route)
+		if method, prefix, ok := strings.Cut(route, " "); ok {
+			rule.Method, route = strings.ToUpper(method), strings.TrimSpace(prefix)
+		}
+		if route != "*" {
+			if !strings.HasPrefix(route, "/") {
+				return nil, fmt.Errorf("rule %q: path must start with / or be *", part)
+			}
+			rule.Prefix = route
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(opt), ":")
+			var err error
+			switch key {
+			case "latency":
+				rule.LatencyMin, rule.LatencyMax, err = parseLatencyRange(val)
+			case "error":
+				rule.ErrorRate, err = parsePercent(val)
+			case "status":
+				rule.ErrorCode, err = strconv.Atoi(val)
+				if err == nil && (rule.ErrorCode < 500 || rule.ErrorCode > 599) {
+					err = fmt.Errorf("status must be 5xx, got %d", rule.ErrorCode)
+				}
+			default:
+				err = fmt.Errorf("unknown option %q (want latency, error or status)", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", part, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseLatencyRange разбирает "200ms" или "100ms-1s".
+func parseLatencyRange(s string) (time.Duration, time.Duration, error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	minD, err := time.ParseDuration(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("latency: %w", err)
+	}
+	maxD := minD
+	if isRange {
+		if maxD, err = time.ParseDuration(hi); err != nil {
+			return 0, 0, fmt.Errorf("latency: %w", err)
+		}
+	}
+	if minD < 0 || maxD < minD || maxD > maxChaosDelay {
+		return 0, 0, fmt.Errorf("latency: invalid range %q (0..%s)", s, maxChaosDelay)
+	}
+	return minD, maxD, nil
+}
+
+// parsePercent разбирает "5%" или "5" в долю 0.05.
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || v < 0 || v > 100 {
+		return 0, fmt.Errorf("error: invalid percent %q", s)
+	}
+	return v / 100, nil
+}
+
+func (cr ChaosRule) matches(r *http.Request) bool {
+	return (cr.Method == "" || cr.Method == r.Method) && strings.HasPrefix(r.URL.Path, cr.Prefix)
+}
+
+// ChaosMiddleware портит запросы по правилам: ждёт случайную задержку из диапазона правила,
+// затем с вероятностью ErrorRate отвечает ошибкой, не вызывая обработчик. Запрос может сам
+// попросить задержку параметром ?delay=250ms (до 30s) -- она заменяет задержку правила.
+// Пути с префиксами из exempt не трогаются (админский API, метрики, /readyz).
+func ChaosMiddleware(rules []ChaosRule, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasAnyPrefix(r.URL.Path, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var rule ChaosRule
+			for _, cr := range rules {
+				if cr.matches(r) {
+					rule = cr
+					break
+				}
+			}
+
+			delay := rule.LatencyMin
+			if rule.LatencyMax > rule.LatencyMin {
+				delay += rand.N(rule.LatencyMax - rule.LatencyMin)
+			}
+			if v := r.URL.Query().Get("delay"); v != "" {
+				if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+					delay = min(d, maxChaosDelay)
+				}
+			}
+			if delay > 0 {
+				chaosInjected.WithLabelValues("latency").Inc()
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-r.Context().Done():
+					// Клиент ушёл или истёк таймаут запроса: дальше обработчик сам ответит ошибкой контекста.
+					t.Stop()
+				}
+			}
+
+			if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+				chaosInjected.WithLabelValues("error").Inc()
+				WriteError(w, r, rule.ErrorCode, "chaos_injected", "Injected failure (chaos mode)", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type ctxKeyClientIP struct{}
+
+// ParseTrustedProxies разбирает TRUSTED_PROXIES: адреса и подсети через запятую
+// ("10.0.0.0/8, 127.0.0.1, ::1"). Ошибка в списке -- ошибка конфигурации, а не пропуск записи:
+// лишний доверенный адрес позволит клиентам подделывать свой IP.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			p, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+			}
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+// ClientIPMiddleware определяет адрес клиента и кладёт его в контекст (см. ClientIP).
+//
+// Заголовкам X-Forwarded-For и X-Real-IP верим, только если соединение пришло от доверенного прокси.
+// X-Forwarded-For разбирается справа налево: каждый прокси дописывает адрес, от которого
+// получил запрос, поэтому клиент -- первый адрес справа, не принадлежащий доверенным прокси.
+// Всё, что левее, клиент мог написать сам. Без доверенных прокси заголовки игнорируются.
+func ClientIPMiddleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyClientIP{}, ip)))
+		})
+	}
+}
+
+// ClientIP -- адрес клиента: определённый ClientIPMiddleware, а без него -- адрес соединения без порта.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxKeyClientIP{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// ClientIPFromContext -- адрес клиента, положенный ClientIPMiddleware (для слоёв без *http.Request).
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKeyClientIP{}).(string)
+	return ip
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteHost(r)
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		// Несколько заголовков -- один список (RFC 7230, 3.2.2).
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, ok := parseIP(hops[i])
+			if !ok {
+				// Мусор в цепочке: дальше влево верить нельзя, останавливаемся на последнем надёжном адресе.
+				return peer
+			}
+			if !isTrusted(hop, trusted) {
+				return hop
+			}
+			peer = hop
+		}
+		// Вся цепочка из доверенных прокси -- клиентом считаем самый левый.
+		return peer
+	}
+	if ip, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+		return ip
+	}
+	return peer
+}
+
+// remoteHost -- адрес соединения без порта.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// parseIP нормализует адрес из заголовка (IPv4 в IPv6 -- как IPv4). Порт, если прокси его дописал, отрезается.
+func parseIP(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap().String(), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,15 @@ package middleware
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"task-manager/internal/secrets"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,8 +21,84 @@ type contextKey string
 // Константа, по которой мы (и хендлеры) будем доставать ID пользователя
 const UserIDKey contextKey = "user_id"
 
+// RolesKey -- роли пользователя из токена (группы внешнего каталога, см. LDAP).
+const RolesKey contextKey = "roles"
+
+// SessionIDKey -- ID сессии из токена (claim sid); у токенов без учёта сессий его нет.
+const SessionIDKey contextKey = "session_id"
+
 const prefix string = "Bearer "
 
+// jwtSecret -- ключ подписи JWT из провайдера секретов (см. SetJWTSecret).
+// Пока он не задан, ключ берётся из переменной окружения JWT_SECRET.
+var jwtSecret atomic.Pointer[secrets.Value]
+
+// SetJWTSecret задаёт ключ подписи JWT. После ротации токены со старым ключом
+// принимаются, пока не истечёт grace ключа.
+func SetJWTSecret(v *secrets.Value) {
+	jwtSecret.Store(v)
+}
+
+// userCheck -- проверка, что владелец действующего токена не заблокирован и не удалён (см. SetUserCheck).
+var userCheck atomic.Pointer[func(ctx context.Context, userID int) bool]
+
+// SetUserCheck подключает проверку пользователя из токена на каждом запросе
+// (при провижининге через SCIM: заблокированный пользователь теряет доступ сразу, а не когда истечёт токен).
+func SetUserCheck(fn func(ctx context.Context, userID int) bool) {
+	userCheck.Store(&fn)
+}
+
+// sessionCheck -- проверка, что сессия токена не отозвана (см. SetSessionCheck).
+var sessionCheck atomic.Pointer[func(ctx context.Context, userID int, sessionID string) (bool, error)]
+
+// SetSessionCheck подключает проверку сессии на каждом запросе с токеном, у которого есть sid:
+// отозванная сессия (выход с устройства) перестаёт пускать сразу, а не когда истечёт токен.
+// Ошибка проверки не пускает запрос (503): без хранилища сессий не узнать, не отозван ли токен.
+// nil выключает проверку.
+func SetSessionCheck(fn func(ctx context.Context, userID int, sessionID string) (bool, error)) {
+	if fn == nil {
+		sessionCheck.Store(nil)
+		return
+	}
+	sessionCheck.Store(&fn)
+}
+
+// JWTSigningKey -- текущий ключ, которым подписываются новые токены.
+func JWTSigningKey() []byte {
+	if v := jwtSecret.Load(); v != nil {
+		return []byte(v.Current())
+	}
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// JWTVerifyKeys -- ключи, подпись которыми сейчас принимается: текущий и предыдущие в пределах grace.
+// Ими же проверяются другие подписи сервера (например, ссылки действий из писем).
+func JWTVerifyKeys() []string {
+	return jwtVerifyKeys()
+}
+
+func jwtVerifyKeys() []string {
+	if v := jwtSecret.Load(); v != nil {
+		return v.Candidates(time.Now())
+	}
+	return []string{os.Getenv("JWT_SECRET")}
+}
+
+// parseJWT проверяет подпись токена каждым принимаемым ключом.
+func parseJWT(tokenString string) (*jwt.Token, error) {
+	var lastErr error = jwt.ErrTokenSignatureInvalid
+	for _, key := range jwtVerifyKeys() {
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return []byte(key), nil
+		})
+		if err == nil {
+			return token, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -25,9 +107,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, prefix)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		token, err := parseJWT(tokenString)
 		if err != nil || !token.Valid {
 			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "Invalid token", nil)
 			return
@@ -50,6 +130,25 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		// Превращаем float64 в привычный int
 		userID := int(userIDFloat)
+		if check := userCheck.Load(); check != nil && !(*check)(r.Context(), userID) {
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "User is disabled", nil)
+			return
+		}
+
+		sessionID, _ := claims["sid"].(string)
+		if check := sessionCheck.Load(); check != nil && sessionID != "" {
+			active, err := (*check)(r.Context(), userID, sessionID)
+			if err != nil {
+				log.Printf("request_id=%s session check failed: %v", GetRequestID(r.Context()), err)
+				w.Header().Set("Retry-After", strconv.Itoa(1))
+				WriteError(w, r, http.StatusServiceUnavailable, "session_unavailable", "Cannot verify session, retry later", nil)
+				return
+			}
+			if !active {
+				WriteError(w, r, http.StatusUnauthorized, "unauthorized", "Session revoked", nil)
+				return
+			}
+		}
 
 		// Берем текущий контекст, который прилетел вместе с запросом r
 		ctx := r.Context()
@@ -57,9 +156,51 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// Создаем на его основе новый контекст, положив туда пару Ключ -> Значение
 		// Для ключа мы используем специальную константу UserIDKey
 		newCtx := context.WithValue(ctx, UserIDKey, userID)
+		if roles := stringClaims(claims["roles"]); len(roles) > 0 {
+			newCtx = context.WithValue(newCtx, RolesKey, roles)
+		}
+		if sessionID != "" {
+			newCtx = context.WithValue(newCtx, SessionIDKey, sessionID)
+		}
 
 		// Пробрасываем запрос дальше, обернув его в этот новый контекст
 		next.ServeHTTP(w, r.WithContext(newCtx))
 
 	})
 }
+
+// WithUserID кладёт ID аутентифицированного пользователя в контекст.
+// Нужен транспортам со своей схемой авторизации (например, Basic в CalDAV).
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, UserIDKey, userID)
+}
+
+// UserIDFromContext достаёт ID пользователя, положенный AuthMiddleware/WithUserID.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(UserIDKey).(int)
+	return userID, ok
+}
+
+// RolesFromContext достаёт роли, положенные AuthMiddleware (nil -- ролей нет).
+func RolesFromContext(ctx context.Context) []string {
+	roles, _ := ctx.Value(RolesKey).([]string)
+	return roles
+}
+
+// SessionIDFromContext достаёт ID сессии токена ("" -- токен без сессии).
+func SessionIDFromContext(ctx context.Context) string {
+	sid, _ := ctx.Value(SessionIDKey).(string)
+	return sid
+}
+
+// stringClaims разбирает массив строк из claims (JSON даёт []interface{}).
+func stringClaims(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
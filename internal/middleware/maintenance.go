@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var maintenanceGauge = metrics.NewGaugeVec("maintenance_mode",
+	"1 if the API is in maintenance mode (mutations are rejected).")
+
+// MaintenanceState -- текущее состояние режима обслуживания.
+type MaintenanceState struct {
+	Enabled    bool       `json:"enabled"`
+	Message    string     `json:"message,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+	RetryAfter int        `json:"retry_after_seconds,omitempty"` // подсказка клиентам (заголовок Retry-After)
+}
+
+// Maintenance -- переключатель режима обслуживания (бэкап, миграция).
+// Пока он включён, изменяющие запросы получают 503, а чтение продолжает работать.
+type Maintenance struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+}
+
+// NewMaintenance создаёт выключенный переключатель.
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Set включает или выключает режим обслуживания.
+func (m *Maintenance) Set(enabled bool, message string, retryAfter int) MaintenanceState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !enabled {
+		m.state = MaintenanceState{}
+		maintenanceGauge.WithLabelValues().Set(0)
+		return m.state
+	}
+	// Повторное включение не сбрасывает время начала.
+	since := m.state.Since
+	if since == nil {
+		now := time.Now().UTC()
+		since = &now
+	}
+	m.state = MaintenanceState{Enabled: true, Message: message, Since: since, RetryAfter: retryAfter}
+	maintenanceGauge.WithLabelValues().Set(1)
+	return m.state
+}
+
+// State возвращает копию текущего состояния.
+func (m *Maintenance) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Middleware отклоняет изменяющие запросы, пока включён режим обслуживания.
+// Чтение (GET/HEAD/OPTIONS и читающие методы WebDAV) проходит всегда,
+// как и пути с префиксами из exempt (админский API, вход в систему).
+func (m *Maintenance) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			st := m.State()
+			if !st.Enabled || isReadMethod(r.Method) || hasAnyPrefix(r.URL.Path, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if st.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(st.RetryAfter))
+			}
+			msg := st.Message
+			if msg == "" {
+				msg = "Service is in maintenance mode, changes are temporarily disabled"
+			}
+			WriteError(w, r, http.StatusServiceUnavailable, "maintenance", msg, st)
+		})
+	}
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+		return true
+	}
+	return false
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("http_requests_total",
+		"HTTP requests by method, route template and status code.", "method", "route", "code")
+	httpDuration = metrics.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by method and route template.", nil, "method", "route")
+)
+
+// unmatchedRoute -- метка запросов, не дошедших ни до одного маршрута (404 и сканеры путей).
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware считает запросы и латентность для /metrics и передаёт каждый запрос в трекер SLO
+// (если он задан). Ставится на корневой chi-роутер: метка route -- шаблон маршрута из chi.RouteContext
+// (/api/v1/tasks/{id}), а не сам URL, иначе каждая задача заводила бы свои ряды метрик.
+func MetricsMiddleware(slo *metrics.SLO) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+			next.ServeHTTP(rec, r)
+			elapsed := time.Since(start)
+
+			route := routeLabel(r)
+			httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+			httpDuration.WithLabelValues(r.Method, route).Observe(elapsed.Seconds())
+			if slo != nil {
+				slo.Observe(rec.status, elapsed)
+			}
+		})
+	}
+}
+
+// routeLabel -- шаблон маршрута, которым chi обслужил запрос. Читается после обработки: вложенные
+// роутеры дописывают свои части шаблона в общий контекст по ходу маршрутизации. Запрос, отвергнутый
+// middleware группы (401 в /api/v1), получает шаблон группы -- /api/v1/*.
+func routeLabel(r *http.Request) string {
+	route := chi.RouteContext(r.Context()).RoutePattern()
+	if route == "" || route == "/*" {
+		return unmatchedRoute
+	}
+	return route
+}
@@ -25,7 +25,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// request-id добавляет связность "клиент ↔ логи"
 		reqID := GetRequestID(r.Context())
 
-		log.Printf("request_id=%s method=%s path=%s status=%d bytes=%d server_in=%v", reqID, r.Method, r.URL.Path, rec.status, rec.bytes, time.Since(start))
+		log.Printf("request_id=%s client_ip=%s method=%s path=%s status=%d bytes=%d server_in=%v", reqID, ClientIP(r), r.Method, r.URL.Path, rec.status, rec.bytes, time.Since(start))
 	})
 }
 
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var readOnlyGauge = metrics.NewGaugeVec("read_only_mode",
+	"1 if the server is in read-only mode (mutations are not allowed).")
+
+// ReadOnlyState -- текущее состояние режима только для чтения.
+type ReadOnlyState struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// ReadOnly -- переключатель режима только для чтения (публичное зеркало, демо, миграция данных).
+//
+// В отличие от режима обслуживания это не временная пауза: изменяющие запросы получают 405
+// с заголовком Allow, без Retry-After -- клиенту незачем повторять запрос.
+type ReadOnly struct {
+	mu    sync.RWMutex
+	state ReadOnlyState
+}
+
+// NewReadOnly создаёт выключенный переключатель.
+func NewReadOnly() *ReadOnly {
+	return &ReadOnly{}
+}
+
+// Set включает или выключает режим только для чтения.
+func (ro *ReadOnly) Set(enabled bool, message string) ReadOnlyState {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+
+	if !enabled {
+		ro.state = ReadOnlyState{}
+		readOnlyGauge.WithLabelValues().Set(0)
+		return ro.state
+	}
+	// Повторное включение не сбрасывает время начала.
+	since := ro.state.Since
+	if since == nil {
+		now := time.Now().UTC()
+		since = &now
+	}
+	ro.state = ReadOnlyState{Enabled: true, Message: message, Since: since}
+	readOnlyGauge.WithLabelValues().Set(1)
+	return ro.state
+}
+
+// State возвращает копию текущего состояния.
+func (ro *ReadOnly) State() ReadOnlyState {
+	ro.mu.RLock()
+	defer ro.mu.RUnlock()
+	return ro.state
+}
+
+// Middleware отклоняет изменяющие запросы, пока включён режим только для чтения.
+// Чтение и пути с префиксами из exempt (админский API, вход в систему) проходят, как в Maintenance.
+func (ro *ReadOnly) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			st := ro.State()
+			if !st.Enabled || isReadMethod(r.Method) || hasAnyPrefix(r.URL.Path, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			msg := st.Message
+			if msg == "" {
+				msg = "Server is read-only, changes are not allowed"
+			}
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			WriteError(w, r, http.StatusMethodNotAllowed, "read_only", msg, st)
+		})
+	}
+}
@@ -13,9 +13,16 @@ import (
 //
 // Важно: это НЕ "магический убийца" хендлеров.
 // Таймаут сработает только если нижние слои реально проверяют ctx.Done()/ctx.Err().
-func RequestTimeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+//
+// Пути с префиксами из exempt таймаут не получают: это долгие запросы (long polling),
+// которые ограничивают себя сами.
+func RequestTimeoutMiddleware(d time.Duration, exempt ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasAnyPrefix(r.URL.Path, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), d)
 			defer cancel()
 
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var responseCacheRequests = metrics.NewCounterVec("response_cache_requests_total",
+	"Requests to cached public views by result (hit, miss, wait).", "result")
+
+// maxCachedResponses ограничивает кэш: при переполнении выбрасываются истёкшие записи, а если
+// их нет -- кэш очищается целиком (как в internal/authz).
+const maxCachedResponses = 1000
+
+// maxCachedBody -- ответы больше этого не кэшируются.
+const maxCachedBody = 1 << 20
+
+// ResponseCache -- кэш ответов в памяти процесса с коротким сроком жизни для публичных видов
+// (доска по ссылке, табло-киоск): десятки обновлений одной страницы за TTL доходят до сервиса
+// один раз. Одновременные промахи по одному ключу не идут в сервис каждый: первый запрос
+// считает ответ, остальные ждут его (защита от "набега" после истечения записи).
+//
+// Кэшируются только ответы 200 на GET; HEAD отвечается из кэша, но его не заполняет.
+type ResponseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+	flights map[string]*responseFlight
+}
+
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// responseFlight -- ответ, который сейчас считается; done закрывается, когда он готов.
+type responseFlight struct {
+	done chan struct{}
+	res  cachedResponse
+	ok   bool // ответ можно отдать ждущим
+}
+
+// NewResponseCache создаёт кэш со сроком жизни записей ttl. При ttl <= 0 возвращает nil:
+// Middleware у nil-кэша пропускает запросы без кэширования.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ResponseCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedResponse),
+		flights: make(map[string]*responseFlight),
+	}
+}
+
+// Flush забывает все ответы (например, доску отозвали).
+func (c *ResponseCache) Flush() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// Middleware отвечает на GET и HEAD из кэша по ключу "путь?запрос". Ставить только на маршруты,
+// ответ которых не зависит от того, кто спрашивает: ключ не учитывает заголовки запроса.
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.URL.RequestURI()
+		now := time.Now()
+
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+			c.mu.Unlock()
+			responseCacheRequests.WithLabelValues("hit").Inc()
+			e.write(w)
+			return
+		}
+		if f, ok := c.flights[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-r.Context().Done():
+				return
+			}
+			if f.ok {
+				responseCacheRequests.WithLabelValues("wait").Inc()
+				f.res.write(w)
+				return
+			}
+			// Первый запрос получил не 200 (ошибка, таймаут) -- каждый спрашивает сам.
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			c.mu.Unlock()
+			next.ServeHTTP(w, r)
+			return
+		}
+		f := &responseFlight{done: make(chan struct{})}
+		c.flights[key] = f
+		c.mu.Unlock()
+		responseCacheRequests.WithLabelValues("miss").Inc()
+
+		rec := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		defer func() {
+			c.mu.Lock()
+			delete(c.flights, key)
+			if f.ok {
+				c.store(key, f.res, now)
+			}
+			c.mu.Unlock()
+			close(f.done)
+		}()
+		next.ServeHTTP(rec, r)
+
+		f.res = cachedResponse{header: rec.header, body: rec.body.Bytes(), expires: now.Add(c.ttl)}
+		f.ok = rec.status == http.StatusOK && len(f.res.body) <= maxCachedBody
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-Cache", "MISS")
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(f.res.body)
+	})
+}
+
+// store кладёт ответ в кэш; вызывается под c.mu.
+func (c *ResponseCache) store(key string, res cachedResponse, now time.Time) {
+	if len(c.entries) >= maxCachedResponses {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedResponses {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = res
+}
+
+// write отдаёт сохранённый ответ с заголовком X-Cache: HIT.
+func (e cachedResponse) write(w http.ResponseWriter) {
+	for k, v := range e.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Content-Length", strconv.Itoa(len(e.body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(e.body)
+}
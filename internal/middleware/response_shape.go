@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Заголовки, которыми клиент переопределяет форму ответа для одного запроса.
+const (
+	NamingHeader   = "X-Response-Naming"   // snake | camel
+	EnvelopeHeader = "X-Response-Envelope" // true | false
+)
+
+// Стили именования полей JSON.
+const (
+	NamingSnake = "snake" // как в структурах: assigned_to (по умолчанию)
+	NamingCamel = "camel" // assignedTo
+)
+
+// ResponseShape -- глобальные настройки формы JSON-ответов.
+type ResponseShape struct {
+	Naming   string // NamingSnake или NamingCamel
+	Envelope bool   // заворачивать успешные ответы в {"data": ..., "meta": ...}
+}
+
+// Envelope -- обёртка успешного ответа.
+type Envelope struct {
+	Data any          `json:"data"`
+	Meta EnvelopeMeta `json:"meta"`
+}
+
+// EnvelopeMeta -- служебные сведения об ответе.
+type EnvelopeMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+	Count     *int   `json:"count,omitempty"` // число элементов, если data -- список
+}
+
+// Middleware перестраивает JSON-ответы REST API (/api/...): переименовывает поля в нужный стиль и (для 2xx) заворачивает
+// в конверт. Ошибки в конверт не заворачиваются -- их формат единый ({"api_error": ...}), меняется
+// только именование полей. Ответы не в JSON (CalDAV, /metrics) проходят как есть.
+//
+// Если итоговая форма совпадает со стандартной (snake, без конверта), ответ не буферизуется.
+func (s ResponseShape) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shape := s.forRequest(r)
+		if !strings.HasPrefix(r.URL.Path, "/api/") || shape.Naming != NamingCamel && !shape.Envelope {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &bufferedResponse{header: w.Header().Clone(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if strings.HasPrefix(rec.header.Get("Content-Type"), "application/json") && len(bytes.TrimSpace(body)) > 0 {
+			if shaped, err := shape.apply(body, rec.status, rec.header.Get("X-Request-ID")); err == nil {
+				body = shaped
+				rec.header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+
+		rec.flush(w, body)
+	})
+}
+
+// forRequest применяет переопределения из заголовков запроса. Неизвестные значения игнорируются.
+func (s ResponseShape) forRequest(r *http.Request) ResponseShape {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(NamingHeader))) {
+	case NamingSnake:
+		s.Naming = NamingSnake
+	case NamingCamel:
+		s.Naming = NamingCamel
+	}
+	if v, err := strconv.ParseBool(strings.TrimSpace(r.Header.Get(EnvelopeHeader))); err == nil {
+		s.Envelope = v
+	}
+	return s
+}
+
+func (s ResponseShape) apply(body []byte, status int, requestID string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // числа не должны терять точность при перекодировании
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if s.Envelope && status >= 200 && status < 300 {
+		meta := EnvelopeMeta{RequestID: requestID}
+		if list, ok := v.([]any); ok {
+			n := len(list)
+			meta.Count = &n
+		}
+		// Конверт проходит через то же переименование, что и данные.
+		raw, err := json.Marshal(Envelope{Data: v, Meta: meta})
+		if err != nil {
+			return nil, err
+		}
+		dec = json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+	if s.Naming == NamingCamel {
+		v = renameKeys(v, snakeToCamel)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RewriteJSON -- middleware, которое пропускает JSON-ответ через функцию, выбранную для запроса.
+// pick возвращает nil, если ответ этого запроса трогать не нужно (тогда он не буферизуется).
+// Функция получает разобранное тело (числа -- json.Number) и возвращает новое.
+func RewriteJSON(pick func(r *http.Request) func(v any) any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fn := pick(r)
+			if fn == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &bufferedResponse{header: w.Header().Clone(), status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			body := rec.body.Bytes()
+			if strings.HasPrefix(rec.header.Get("Content-Type"), "application/json") && len(bytes.TrimSpace(body)) > 0 {
+				dec := json.NewDecoder(bytes.NewReader(body))
+				dec.UseNumber()
+				var v any
+				var buf bytes.Buffer
+				if dec.Decode(&v) == nil && json.NewEncoder(&buf).Encode(fn(v)) == nil {
+					body = buf.Bytes()
+					rec.header.Set("Content-Length", strconv.Itoa(len(body)))
+				}
+			}
+			rec.flush(w, body)
+		})
+	}
+}
+
+// renameKeys рекурсивно переименовывает ключи всех объектов.
+func renameKeys(v any, rename func(string) string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[rename(k)] = renameKeys(val, rename)
+		}
+		return out
+	case []any:
+		for i := range t {
+			t[i] = renameKeys(t[i], rename)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// snakeToCamel: assigned_to -> assignedTo, completed_at -> completedAt.
+func snakeToCamel(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	upper := false
+	for i, c := range s {
+		switch {
+		case c == '_' && i > 0:
+			upper = true
+		case upper && c >= 'a' && c <= 'z':
+			b.WriteRune(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(c)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// bufferedResponse копит ответ целиком, чтобы его можно было перестроить.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status, b.wroteHeader = status, true
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// flush отдаёт накопленный ответ (с подменённым телом) настоящему ResponseWriter.
+func (b *bufferedResponse) flush(w http.ResponseWriter, body []byte) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+var (
+	openConnections = metrics.NewGaugeVec("http_open_connections",
+		"Open client connections to the public port.")
+	requestsInFlight = metrics.NewGaugeVec("http_requests_inflight",
+		"Requests to the public port currently being processed.")
+	requestsRejected = metrics.NewCounterVec("http_requests_rejected_total",
+		"Requests rejected with 503 by the concurrent request limit.")
+)
+
+// ServerLimits считает нагрузку на публичный порт (открытые соединения, выполняемые запросы)
+// и ограничивает её для маленьких серверов: не больше maxConns соединений -- лишние ждут
+// в очереди ядра, пока не освободится место, и не больше maxRequests запросов одновременно --
+// лишние сразу получают 503. 0 -- без ограничения, счётчики работают всё равно.
+//
+// Backpressure ограничивает только изменения; здесь -- все запросы, включая чтение.
+type ServerLimits struct {
+	maxConns    int
+	maxRequests int
+	started     time.Time
+
+	connSlots    chan struct{} // nil -- соединения не ограничены
+	requestSlots chan struct{} // nil -- запросы не ограничены
+
+	conns    atomic.Int64
+	requests atomic.Int64
+	rejected atomic.Int64
+
+	mu          sync.Mutex
+	subscribers map[string]func() int
+}
+
+// ServerStats -- ответ GET /api/v1/admin/stats.
+type ServerStats struct {
+	UptimeSeconds int64           `json:"uptime_seconds"`
+	Goroutines    int             `json:"goroutines"`
+	HeapBytes     uint64          `json:"heap_bytes"`
+	Connections   ConnectionStats `json:"connections"`
+	Requests      RequestStats    `json:"requests"`
+	Subscribers   map[string]int  `json:"subscribers"` // ждущие событий: источник -> сколько
+}
+
+// ConnectionStats -- соединения с публичным портом; Max 0 -- без ограничения.
+type ConnectionStats struct {
+	Open int64 `json:"open"`
+	Max  int   `json:"max"`
+}
+
+// RequestStats -- запросы к публичному порту; Rejected -- отклонённые ограничением с запуска.
+type RequestStats struct {
+	Active   int64 `json:"active"`
+	Max      int   `json:"max"`
+	Rejected int64 `json:"rejected"`
+}
+
+// NewServerLimits создаёт счётчики с ограничениями maxConns соединений и maxRequests запросов.
+func NewServerLimits(maxConns, maxRequests int) *ServerLimits {
+	l := &ServerLimits{
+		maxConns:    maxConns,
+		maxRequests: maxRequests,
+		started:     time.Now(),
+		subscribers: make(map[string]func() int),
+	}
+	if maxConns > 0 {
+		l.connSlots = make(chan struct{}, maxConns)
+	}
+	if maxRequests > 0 {
+		l.requestSlots = make(chan struct{}, maxRequests)
+	}
+	return l
+}
+
+// AddSubscribers добавляет в статистику источник подписчиков (например, ждущих ленту изменений).
+func (l *ServerLimits) AddSubscribers(name string, count func() int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers[name] = count
+}
+
+// Stats -- текущая нагрузка на сервер.
+func (l *ServerLimits) Stats() ServerStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	l.mu.Lock()
+	sources := maps.Clone(l.subscribers)
+	l.mu.Unlock()
+	subs := make(map[string]int, len(sources))
+	for name, count := range sources {
+		subs[name] = count()
+	}
+
+	return ServerStats{
+		UptimeSeconds: int64(time.Since(l.started).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapBytes:     mem.HeapAlloc,
+		Connections:   ConnectionStats{Open: l.conns.Load(), Max: l.maxConns},
+		Requests:      RequestStats{Active: l.requests.Load(), Max: l.maxRequests, Rejected: l.rejected.Load()},
+		Subscribers:   subs,
+	}
+}
+
+// Listener оборачивает сокет сервера: считает соединения и не принимает новое, пока открыто maxConns.
+// Для передачи сокета при перезапуске (restart.Upgrade) нужен исходный ln, а не обёртка.
+func (l *ServerLimits) Listener(ln net.Listener) net.Listener {
+	return &limitListener{Listener: ln, limits: l, done: make(chan struct{})}
+}
+
+type limitListener struct {
+	net.Listener
+	limits    *ServerLimits
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (ll *limitListener) Accept() (net.Conn, error) {
+	slots := ll.limits.connSlots
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ll.done:
+			return nil, net.ErrClosed
+		}
+	}
+	c, err := ll.Listener.Accept()
+	if err != nil {
+		if slots != nil {
+			<-slots
+		}
+		return nil, err
+	}
+	ll.limits.conns.Add(1)
+	openConnections.WithLabelValues().Inc()
+	return &limitConn{Conn: c, release: sync.OnceFunc(func() {
+		ll.limits.conns.Add(-1)
+		openConnections.WithLabelValues().Dec()
+		if slots != nil {
+			<-slots
+		}
+	})}, nil
+}
+
+// Close закрывает сокет и будит Accept, ждущий свободного места.
+func (ll *limitListener) Close() error {
+	ll.closeOnce.Do(func() { close(ll.done) })
+	return ll.Listener.Close()
+}
+
+// limitConn освобождает место при первом Close (http.Server может закрыть соединение дважды).
+type limitConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
+
+// Middleware считает выполняемые запросы и отклоняет лишние сверх maxRequests с 503.
+// Пути с префиксами exempt считаются, но не ограничиваются: служебный API и проверки живости
+// должны отвечать и под перегрузкой, а долгие ожидания ленты изменений заняли бы все места.
+func (l *ServerLimits) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.requestSlots != nil && !hasAnyPrefix(r.URL.Path, exempt) {
+				select {
+				case l.requestSlots <- struct{}{}:
+					defer func() { <-l.requestSlots }()
+				default:
+					l.rejected.Add(1)
+					requestsRejected.WithLabelValues().Inc()
+					w.Header().Set("Retry-After", strconv.Itoa(1))
+					WriteError(w, r, http.StatusServiceUnavailable, "overloaded", "Server is busy, retry later",
+						map[string]any{"reason": "request_limit"})
+					return
+				}
+			}
+
+			l.requests.Add(1)
+			requestsInFlight.WithLabelValues().Inc()
+			defer func() {
+				l.requests.Add(-1)
+				requestsInFlight.WithLabelValues().Dec()
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrShuttingDown -- причина отмены корневого контекста при остановке сервера
+// (context.WithCancelCause). По ней обработчики отличают остановку сервера от ушедшего клиента.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// shutdownRetryAfter -- через сколько секунд клиенту стоит повторить запрос (новый процесс к тому времени поднимется).
+const shutdownRetryAfter = "5"
+
+// WriteShuttingDown отвечает 503 с Retry-After и закрывает соединение:
+// клиент должен переподключиться, скорее всего уже к новому процессу.
+func WriteShuttingDown(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Connection", "close")
+	w.Header().Set("Retry-After", shutdownRetryAfter)
+	WriteError(w, r, http.StatusServiceUnavailable, "shutting_down", "Server is shutting down, retry later", nil)
+}
+
+// Drain -- флаг остановки сервера. После Start новые запросы получают 503 (WriteShuttingDown),
+// а уже принятые спокойно доделываются.
+//
+// http.Server.Shutdown сам перестаёт принимать соединения, но запрос, пришедший по уже открытому
+// keep-alive соединению, иначе был бы принят в работу и, возможно, оборван на середине.
+type Drain struct {
+	draining atomic.Bool
+}
+
+// NewDrain создаёт флаг в состоянии "работаем".
+func NewDrain() *Drain {
+	return &Drain{}
+}
+
+// Start включает режим остановки. Повторные вызовы безопасны.
+func (d *Drain) Start() {
+	d.draining.Store(true)
+}
+
+// Draining сообщает, идёт ли остановка.
+func (d *Drain) Draining() bool {
+	return d.draining.Load()
+}
+
+// Middleware отклоняет новые запросы во время остановки.
+func (d *Drain) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			WriteShuttingDown(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
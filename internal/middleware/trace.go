@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Контекст трассировки W3C Trace Context (https://www.w3.org/TR/trace-context/).
+// Сервер не пишет собственных спанов: он только передаёт след дальше, чтобы запрос клиента,
+// вызванный им webhook и ответ интеграции можно было связать в логах разных систем.
+
+// TraceContext -- след запроса: TraceID от вызвавшей системы (или новый), SpanID -- участок этого сервера.
+type TraceContext struct {
+	TraceID string // 32 hex-символа
+	SpanID  string // 16 hex-символов
+	Flags   string // 2 hex-символа, 01 -- след записывается
+	State   string // tracestate как пришёл, передаётся без изменений
+}
+
+type ctxKeyTrace struct{}
+
+// TraceMiddleware принимает заголовок traceparent: с корректным продолжает след клиента,
+// без него (или с испорченным) начинает новый. Ставить рядом с RequestIDMiddleware.
+func TraceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, ok := parseTraceparent(r.Header.Get("traceparent"))
+		if ok {
+			tc.State = r.Header.Get("tracestate")
+		} else {
+			tc = TraceContext{TraceID: randomHex(16), Flags: "01"}
+		}
+		tc.SpanID = randomHex(8)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyTrace{}, tc)))
+	})
+}
+
+// GetTrace возвращает след запроса из контекста (если был установлен middleware).
+func GetTrace(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(ctxKeyTrace{}).(TraceContext)
+	return tc, ok
+}
+
+// PropagateTrace ставит исходящему запросу (webhook, интеграция) traceparent, tracestate и
+// X-Request-ID запроса, который его вызвал; всё берётся из req.Context(). Фоновая работа без
+// входящего запроса (расписания, правила) получает новый след и уходит без X-Request-ID.
+func PropagateTrace(req *http.Request) {
+	ctx := req.Context()
+	tc, ok := GetTrace(ctx)
+	if !ok {
+		tc = TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+	}
+	req.Header.Set("traceparent", "00-"+tc.TraceID+"-"+tc.SpanID+"-"+tc.Flags)
+	if tc.State != "" {
+		req.Header.Set("tracestate", tc.State)
+	}
+	if id := GetRequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+}
+
+// parseTraceparent разбирает "версия-trace_id-parent_id-флаги". Версии новее 00 читаются
+// по тем же первым четырём полям, как требует спецификация; ff и нулевые ID -- ошибка.
+func parseTraceparent(h string) (TraceContext, bool) {
+	h = strings.TrimSpace(h)
+	parts := strings.Split(h, "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(traceID, 32) || !isLowerHex(parentID, 16) || !isLowerHex(flags, 2) ||
+		strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceID: traceID, Flags: flags}, true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex -- n случайных байт в hex (ID следа и участка).
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
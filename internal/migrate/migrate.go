@@ -0,0 +1,118 @@
+// Package migrate применяет SQL-миграции PostgreSQL при старте сервера.
+//
+// Учёт -- в таблице schema_migrations в том же формате, что у golang-migrate (одна строка:
+// version и dirty), поэтому базу, которую вели через migrate CLI, можно перевести на встроенные
+// миграции и обратно.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrDirty -- прошлая миграция упала на середине: схему нужно проверить и починить вручную,
+// затем исправить строку schema_migrations.
+var ErrDirty = errors.New("database is dirty after a failed migration")
+
+// Migration -- один файл NNNNNN_name.up.sql.
+type Migration struct {
+	Version int64
+	Name    string
+}
+
+// List возвращает миграции из fsys по возрастанию версии.
+func List(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	var list []Migration
+	for _, name := range names {
+		num, _, ok := strings.Cut(name, "_")
+		v, err := strconv.ParseInt(num, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", name)
+		}
+		list = append(list, Migration{Version: v, Name: name})
+	}
+	slices.SortFunc(list, func(a, b Migration) int { return int(a.Version - b.Version) })
+	for i := 1; i < len(list); i++ {
+		if list[i].Version == list[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", list[i-1].Name, list[i].Name)
+		}
+	}
+	return list, nil
+}
+
+// Up применяет миграции новее текущей версии базы, каждую в своей транзакции, и возвращает
+// применённые. Упавшая миграция откатывается целиком, версия остаётся прежней.
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS) ([]Migration, error) {
+	list, err := List(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	current, err := Version(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range list {
+		if m.Version <= current {
+			continue
+		}
+		body, err := fs.ReadFile(fsys, m.Name)
+		if err != nil {
+			return applied, err
+		}
+		if err := apply(ctx, db, m, string(body)); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Version -- текущая версия схемы (0 -- миграций ещё не было).
+func Version(ctx context.Context, db *sql.DB) (int64, error) {
+	var v int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("read schema_migrations: %w", err)
+	case dirty:
+		return v, fmt.Errorf("%w (version %d)", ErrDirty, v)
+	}
+	return v, nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration, body string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, m.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
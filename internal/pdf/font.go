@@ -0,0 +1,254 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBadFont -- файл не похож на TrueType-шрифт или в нём нет нужных таблиц.
+var ErrBadFont = errors.New("pdf: unsupported or corrupted TrueType font")
+
+// SystemFontPaths -- где обычно лежит шрифт с кириллицей (Debian/Ubuntu, Fedora, Alpine, macOS).
+var SystemFontPaths = []string{
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/ttf-dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/truetype/liberation/LiberationSans-Regular.ttf",
+	"/usr/share/fonts/liberation-sans/LiberationSans-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSans-Regular.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+}
+
+// Font -- TrueType-шрифт, который встраивается в документ целиком.
+// Нужен для всего, что не помещается в WinAnsi (кириллица, символы).
+type Font struct {
+	name       string
+	data       []byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	bbox       [4]int
+	widths     []uint16 // ширина по номеру глифа, в единицах шрифта
+	glyphs     map[rune]uint16
+}
+
+// LoadFont читает TrueType-шрифт из файла.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ParseFont(name, data)
+}
+
+// FindSystemFont загружает первый найденный шрифт из SystemFontPaths.
+// Возвращает путь к нему; ни одного нет -- os.ErrNotExist.
+func FindSystemFont() (*Font, string, error) {
+	for _, p := range SystemFontPaths {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		f, err := LoadFont(p)
+		if err != nil {
+			return nil, p, err
+		}
+		return f, p, nil
+	}
+	return nil, "", os.ErrNotExist
+}
+
+// ParseFont разбирает TrueType-шрифт: метрики, ширины глифов и таблицу символ -> глиф.
+// Шрифты с CFF-контурами (OpenType .otf) не поддерживаются.
+func ParseFont(name string, data []byte) (*Font, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 && string(data[:4]) != "true" {
+		return nil, ErrBadFont
+	}
+	tables := make(map[string][]byte)
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, ErrBadFont
+		}
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, ErrBadFont
+		}
+		tables[string(data[rec:rec+4])] = data[off : off+length]
+	}
+	head, hhea, maxp, hmtx, cmap := tables["head"], tables["hhea"], tables["maxp"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 || cmap == nil || tables["glyf"] == nil {
+		return nil, ErrBadFont
+	}
+
+	f := &Font{
+		name:       sanitizeName(name),
+		data:       data,
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		ascent:     int(int16(binary.BigEndian.Uint16(hhea[4:]))),
+		descent:    int(int16(binary.BigEndian.Uint16(hhea[6:]))),
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	if f.unitsPerEm == 0 {
+		return nil, ErrBadFont
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numMetrics == 0 || numMetrics > numGlyphs || len(hmtx) < 4*numMetrics {
+		return nil, ErrBadFont
+	}
+	f.widths = make([]uint16, numGlyphs)
+	for g := range f.widths {
+		if g < numMetrics {
+			f.widths[g] = binary.BigEndian.Uint16(hmtx[4*g:])
+		} else {
+			f.widths[g] = f.widths[numMetrics-1]
+		}
+	}
+
+	glyphs, err := parseCmap(cmap, numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+	return f, nil
+}
+
+// Name -- имя шрифта в документе.
+func (f *Font) Name() string { return f.name }
+
+// glyph -- номер глифа для символа; 0 (.notdef) -- символа в шрифте нет.
+func (f *Font) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// width -- ширина глифа в тысячных долях кегля.
+func (f *Font) width(g uint16) int {
+	if int(g) >= len(f.widths) {
+		return 0
+	}
+	return int(f.widths[g]) * 1000 / f.unitsPerEm
+}
+
+// scale переводит единицы шрифта в тысячные доли кегля.
+func (f *Font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// parseCmap строит таблицу символ -> глиф из подтаблицы Unicode (формат 12 или 4).
+func parseCmap(cmap []byte, numGlyphs int) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, ErrBadFont
+	}
+	var best []byte
+	bestRank := 0
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			return nil, ErrBadFont
+		}
+		platform := binary.BigEndian.Uint16(cmap[rec:])
+		encoding := binary.BigEndian.Uint16(cmap[rec+2:])
+		off := int(binary.BigEndian.Uint32(cmap[rec+4:]))
+		if off+4 > len(cmap) {
+			continue
+		}
+		sub := cmap[off:]
+		format := binary.BigEndian.Uint16(sub)
+		rank := 0
+		switch {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			rank = 3
+		case format == 4 && platform == 3 && encoding == 1:
+			rank = 2
+		case format == 4 && platform == 0:
+			rank = 1
+		}
+		if rank > bestRank {
+			best, bestRank = sub, rank
+		}
+	}
+	if best == nil {
+		return nil, ErrBadFont
+	}
+
+	glyphs := make(map[rune]uint16)
+	add := func(r rune, g int) {
+		if g > 0 && g < numGlyphs {
+			glyphs[r] = uint16(g)
+		}
+	}
+	if binary.BigEndian.Uint16(best) == 12 {
+		if len(best) < 16 {
+			return nil, ErrBadFont
+		}
+		groups := int(binary.BigEndian.Uint32(best[12:]))
+		if 16+12*groups > len(best) {
+			return nil, ErrBadFont
+		}
+		for i := 0; i < groups; i++ {
+			g := best[16+12*i:]
+			start, end, gid := binary.BigEndian.Uint32(g), binary.BigEndian.Uint32(g[4:]), binary.BigEndian.Uint32(g[8:])
+			if end < start || end > 0x10FFFF {
+				continue
+			}
+			for c := start; c <= end; c++ {
+				add(rune(c), int(gid+c-start))
+			}
+		}
+		return glyphs, nil
+	}
+
+	if len(best) < 14 {
+		return nil, ErrBadFont
+	}
+	segs := int(binary.BigEndian.Uint16(best[6:])) / 2
+	ends, starts := 14, 16+2*segs
+	deltas, ranges := starts+2*segs, starts+4*segs
+	if ranges+2*segs > len(best) {
+		return nil, ErrBadFont
+	}
+	u16 := func(off int) int {
+		if off+2 > len(best) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(best[off:]))
+	}
+	for i := 0; i < segs; i++ {
+		start, end := u16(starts+2*i), u16(ends+2*i)
+		delta, ro := u16(deltas+2*i), u16(ranges+2*i)
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			if ro == 0 {
+				add(rune(c), (c+delta)&0xFFFF)
+				continue
+			}
+			if g := u16(ranges + 2*i + ro + 2*(c-start)); g != 0 {
+				add(rune(c), (g+delta)&0xFFFF)
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// sanitizeName оставляет в имени шрифта только то, что допустимо в имени PDF без экранирования.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "EmbeddedFont"
+	}
+	return b.String()
+}
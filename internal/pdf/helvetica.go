@@ -0,0 +1,55 @@
+package pdf
+
+import "strings"
+
+// helveticaWidths -- ширины символов ASCII 32..126 стандартной Helvetica (из AFM), в тысячных кегля.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // ' '../
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0..?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @..O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P.._
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // `..o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p..~
+}
+
+func helveticaWidth(c byte) int {
+	if c < 32 || c > 126 {
+		return 556
+	}
+	return helveticaWidths[c-32]
+}
+
+// translit -- кириллица латиницей (упрощённая ГОСТ 7.79-2000, схема Б).
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "cz", 'ч': "ch", 'ш': "sh", 'щ': "shh",
+	'ъ': "``", 'ы': "y'", 'ь': "`", 'э': "e`", 'ю': "yu", 'я': "ya",
+	'№': "No", '—': "-", '–': "-", '«': "\"", '»': "\"", '…': "...", '·': "-",
+}
+
+// transliterate сводит строку к печатному ASCII для Helvetica: кириллицу -- латиницей,
+// прочее за пределами ASCII -- знаком вопроса.
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteByte(' ')
+		default:
+			lower := []rune(strings.ToLower(string(r)))[0]
+			t, ok := translit[lower]
+			if !ok {
+				b.WriteByte('?')
+				continue
+			}
+			if lower != r && t != "" {
+				t = strings.ToUpper(t[:1]) + t[1:]
+			}
+			b.WriteString(t)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,411 @@
+// Package pdf -- минимальный генератор PDF без внешних зависимостей: страницы A4 с текстом
+// в одну колонку, заголовками и чекбоксами. Хватает для распечаток (план на день, сводка по проекту).
+//
+// Текст набирается встроенным TrueType-шрифтом (см. Font), иначе кириллица не отобразится.
+// Без шрифта документ всё равно собирается -- стандартной Helvetica, а кириллица
+// транслитерируется латиницей: лучше читаемая распечатка, чем никакой.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Размеры страницы A4 и поля, в пунктах.
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginLeft   = 50.0
+	marginRight  = 50.0
+	marginTop    = 56.0
+	marginBottom = 60.0
+	contentWidth = pageWidth - marginLeft - marginRight
+)
+
+// Кегли и отступы элементов.
+const (
+	titleSize   = 18.0
+	sectionSize = 13.0
+	textSize    = 10.5
+	smallSize   = 8.5
+	lineGap     = 1.3 // межстрочный интервал относительно кегля
+	indentStep  = 16.0
+	boxSize     = 8.0
+)
+
+// Document -- документ, который собирается сверху вниз: каждый вызов добавляет блок
+// под предыдущим и переносит его на новую страницу, если место кончилось.
+type Document struct {
+	font    *Font
+	title   string
+	footer  string
+	created time.Time
+
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // базовая линия следующей строки
+
+	used map[uint16]rune // глифы, попавшие в документ (для ширин и ToUnicode)
+}
+
+// New начинает документ. font == nil -- Helvetica с транслитерацией.
+// title попадает в свойства файла, footer -- внизу каждой страницы рядом с номером.
+func New(font *Font, title, footer string, created time.Time) *Document {
+	d := &Document{font: font, title: title, footer: footer, created: created, used: make(map[uint16]rune)}
+	d.newPage()
+	return d
+}
+
+// Title -- крупный заголовок документа.
+func (d *Document) Title(text string) {
+	d.lines(0, titleSize, 0, text)
+	d.y -= 2
+}
+
+// Subtitle -- серая строка под заголовком (дата, фильтры).
+func (d *Document) Subtitle(text string) {
+	d.lines(0, textSize, 0.4, text)
+}
+
+// Section -- заголовок раздела с чертой под ним.
+func (d *Document) Section(text string) {
+	d.Space(10)
+	// Заголовок не должен остаться последней строкой страницы.
+	d.ensure(sectionSize*lineGap + textSize*lineGap*2)
+	d.lines(0, sectionSize, 0, text)
+	d.y -= 4
+	fmt.Fprintf(d.page, "0.6 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", marginLeft, d.y, pageWidth-marginRight, d.y)
+	d.y -= 2
+}
+
+// Paragraph -- обычный текст; переводы строк сохраняются, длинные строки переносятся.
+func (d *Document) Paragraph(text string) {
+	d.lines(0, textSize, 0, text)
+}
+
+// Muted -- мелкий серый текст (пояснения, пустые разделы).
+func (d *Document) Muted(text string) {
+	d.lines(0, smallSize, 0.45, text)
+}
+
+// Item -- пункт с чекбоксом: отмеченным, если done. detail -- мелкая серая строка под текстом
+// (срок, приоритет, метки). indent -- уровень вложенности (подзадачи).
+func (d *Document) Item(done bool, text, detail string, indent int) {
+	x := float64(indent) * indentStep
+	textX := x + boxSize + 6
+	wrapped := d.wrap(text, textSize, contentWidth-textX)
+	need := textSize * lineGap
+	if detail != "" {
+		need += smallSize * lineGap
+	}
+	d.ensure(need)
+
+	// Чекбокс выровнен по первой строке текста.
+	bx, by := marginLeft+x, d.y-textSize*lineGap-0.5
+	fmt.Fprintf(d.page, "0 G 0.7 w %.2f %.2f %.2f %.2f re S\n", bx, by, boxSize, boxSize)
+	if done {
+		fmt.Fprintf(d.page, "1.2 w %.2f %.2f m %.2f %.2f l %.2f %.2f l S\n",
+			bx+1.6, by+4.2, bx+3.4, by+1.8, bx+6.8, by+6.8)
+	}
+	gray := 0.0
+	if done {
+		gray = 0.45
+	}
+	for i, l := range wrapped {
+		if i > 0 {
+			d.ensure(textSize * lineGap)
+		}
+		d.line(textX, textSize, gray, l)
+	}
+	if detail != "" {
+		d.lines(textX, smallSize, 0.45, detail)
+	}
+	d.y -= 3
+}
+
+// Space -- вертикальный отступ.
+func (d *Document) Space(pt float64) {
+	d.y -= pt
+}
+
+// Bytes собирает готовый файл.
+func (d *Document) Bytes() []byte {
+	// Номера страниц известны только в конце -- дописываем подвал сейчас.
+	for i, p := range d.pages {
+		d.page = p
+		d.y = marginBottom - 28
+		label := fmt.Sprintf("%d / %d", i+1, len(d.pages))
+		d.text(pageWidth-marginRight-d.measure(label, smallSize), smallSize, 0.5, label)
+		if d.footer != "" {
+			d.text(marginLeft, smallSize, 0.5, d.footer)
+		}
+	}
+
+	w := &writer{}
+	catalog, pages, info := w.reserve(), w.reserve(), w.reserve()
+	font := w.reserve()
+	pageIDs := make([]int, len(d.pages))
+	for i := range pageIDs {
+		pageIDs[i] = w.reserve()
+	}
+
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %.2f %.2f] >>",
+		strings.Join(kids, " "), len(pageIDs), pageWidth, pageHeight))
+	w.object(info, fmt.Sprintf("<< /Title %s /Producer (task-manager) /CreationDate (D:%s) >>",
+		textString(d.title), d.created.UTC().Format("20060102150405Z")))
+	d.writeFont(w, font)
+	for i, p := range d.pages {
+		content := w.reserve()
+		w.object(pageIDs[i], fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, font, content))
+		w.stream(content, "", p.Bytes())
+	}
+	return w.finish(catalog, info)
+}
+
+func (d *Document) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - marginTop
+}
+
+// ensure начинает новую страницу, если до нижнего поля осталось меньше h.
+func (d *Document) ensure(h float64) {
+	if d.y-h < marginBottom {
+		d.newPage()
+	}
+}
+
+// lines печатает текст с переносами строк.
+func (d *Document) lines(x, size, gray float64, text string) {
+	for _, l := range d.wrap(text, size, contentWidth-x) {
+		d.ensure(size * lineGap)
+		d.line(x, size, gray, l)
+	}
+}
+
+// line опускается на строку и печатает её.
+func (d *Document) line(x, size, gray float64, text string) {
+	d.y -= size * lineGap
+	d.text(marginLeft+x, size, gray, text)
+}
+
+// text печатает строку на текущей базовой линии без переноса.
+func (d *Document) text(x, size, gray float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(d.page, "BT /F1 %.2f Tf %.2f g %.2f %.2f Td %s Tj ET 0 g\n", size, gray, x, d.y, d.encode(s))
+}
+
+// wrap разбивает текст на строки не шире width. Пустые строки текста сохраняются.
+func (d *Document) wrap(text string, size, width float64) []string {
+	var out []string
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			out = append(out, "")
+			continue
+		}
+		cur := ""
+		for _, word := range words {
+			next := word
+			if cur != "" {
+				next = cur + " " + word
+			}
+			if d.measure(next, size) <= width {
+				cur = next
+				continue
+			}
+			if cur != "" {
+				out = append(out, cur)
+			}
+			// Слово длиннее строки (ссылка, хэш) режем по символам.
+			for d.measure(word, size) > width && len([]rune(word)) > 1 {
+				r := []rune(word)
+				n := len(r) - 1
+				for n > 1 && d.measure(string(r[:n]), size) > width {
+					n--
+				}
+				out = append(out, string(r[:n]))
+				word = string(r[n:])
+			}
+			cur = word
+		}
+		out = append(out, cur)
+	}
+	return out
+}
+
+// measure -- ширина строки в пунктах.
+func (d *Document) measure(s string, size float64) float64 {
+	total := 0
+	if d.font != nil {
+		for _, r := range s {
+			total += d.font.width(d.glyph(r))
+		}
+	} else {
+		for _, c := range []byte(transliterate(s)) {
+			total += helveticaWidth(c)
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// glyph -- глиф символа; если в шрифте его нет -- знак вопроса.
+func (d *Document) glyph(r rune) uint16 {
+	if g := d.font.glyph(r); g != 0 {
+		return g
+	}
+	return d.font.glyph('?')
+}
+
+// encode превращает строку в операнд Tj: номера глифов для встроенного шрифта,
+// строку WinAnsi для Helvetica.
+func (d *Document) encode(s string) string {
+	if d.font == nil {
+		var b strings.Builder
+		b.WriteByte('(')
+		for _, c := range []byte(transliterate(s)) {
+			if c == '(' || c == ')' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte(')')
+		return b.String()
+	}
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range s {
+		g := d.glyph(r)
+		if _, ok := d.used[g]; !ok {
+			d.used[g] = r
+		}
+		fmt.Fprintf(&b, "%04X", g)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// writeFont пишет объекты шрифта под номером id.
+func (d *Document) writeFont(w *writer, id int) {
+	if d.font == nil {
+		w.object(id, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+		return
+	}
+	f := d.font
+	cid, desc, file, cmap := w.reserve(), w.reserve(), w.reserve(), w.reserve()
+	w.object(id, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cid, cmap))
+
+	glyphs := make([]uint16, 0, len(d.used))
+	for g := range d.used {
+		glyphs = append(glyphs, g)
+	}
+	slices.Sort(glyphs)
+	var widths strings.Builder
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", g, f.width(g))
+	}
+	w.object(cid, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", f.name, desc, widths.String()))
+	w.object(desc, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.ascent), file))
+	w.stream(file, fmt.Sprintf("/Length1 %d", len(f.data)), f.data)
+
+	// ToUnicode: без неё текст из PDF не скопировать и не найти поиском.
+	var cm bytes.Buffer
+	cm.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for len(glyphs) > 0 {
+		n := min(len(glyphs), 100) // не больше 100 записей в блоке
+		fmt.Fprintf(&cm, "%d beginbfchar\n", n)
+		for _, g := range glyphs[:n] {
+			fmt.Fprintf(&cm, "<%04X> <", g)
+			for _, u := range utf16.Encode([]rune{d.used[g]}) {
+				fmt.Fprintf(&cm, "%04X", u)
+			}
+			cm.WriteString(">\n")
+		}
+		cm.WriteString("endbfchar\n")
+		glyphs = glyphs[n:]
+	}
+	cm.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	w.stream(cmap, "", cm.Bytes())
+}
+
+// textString -- строка PDF в UTF-16BE с BOM (для свойств документа).
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// writer раскладывает объекты по номерам и собирает таблицу перекрёстных ссылок.
+type writer struct {
+	objects [][]byte
+}
+
+// reserve выдаёт номер под объект, который будет записан позже.
+func (w *writer) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+func (w *writer) object(id int, body string) {
+	w.objects[id-1] = []byte(body)
+}
+
+// stream записывает поток, сжатый Flate. extra -- дополнительные ключи словаря.
+func (w *writer) stream(id int, extra string, data []byte) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< /Length %d /Filter /FlateDecode %s>>\nstream\n", z.Len(), extra+" ")
+	b.Write(z.Bytes())
+	b.WriteString("\nendstream")
+	w.objects[id-1] = b.Bytes()
+}
+
+func (w *writer) finish(root, info int) []byte {
+	var b bytes.Buffer
+	// Двоичный комментарий во второй строке -- подсказка программам, что файл не текстовый.
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, obj := range w.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(obj)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.objects)+1, root, info, xref)
+	return b.Bytes()
+}
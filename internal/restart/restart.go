@@ -0,0 +1,77 @@
+// Package restart -- перезапуск без простоя: новый бинарник получает уже открытый
+// слушающий сокет от старого процесса (передача FD, как в tableflip), а старый
+// дорабатывает in-flight запросы через graceful shutdown и завершается.
+//
+// Схема:
+//  1. старому процессу приходит SIGHUP;
+//  2. Upgrade запускает os.Executable() с теми же аргументами и передаёт ему сокеты (fd 3 и далее,
+//     по одному на адрес: API, служебный порт) и канал готовности (следующий fd);
+//  3. новый процесс берёт сокеты через Listen по тем же адресам, начинает обслуживать и вызывает Ready;
+//  4. старый процесс получает сигнал готовности и уходит в graceful shutdown.
+//
+// Сокет не закрывается ни на миг, поэтому новые соединения не получают отказ.
+// Альтернатива -- SO_REUSEPORT: несколько процессов слушают один порт одновременно.
+package restart
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Переменные окружения, через которые родитель сообщает дочернему процессу номера дескрипторов.
+// Сокеты -- списком "fd=адрес" через запятую: "3=:8080,4=127.0.0.1:9090". Прежние версии передавали
+// один сокет API просто номером ("3") -- его получает первый вызов Listen.
+const (
+	envListenFD = "TASK_SERVER_LISTEN_FD"
+	envReadyFD  = "TASK_SERVER_READY_FD"
+)
+
+// DefaultReadyTimeout -- сколько ждём готовности нового процесса, прежде чем считать обновление неудачным.
+const DefaultReadyTimeout = 30 * time.Second
+
+// ErrUnsupported -- передача сокета не поддерживается на этой платформе.
+var ErrUnsupported = errors.New("restart: listener handoff is not supported on this platform")
+
+// Inherited сообщает, что процесс запущен через Upgrade и получил сокет от предыдущего.
+func Inherited() bool {
+	return os.Getenv(envListenFD) != ""
+}
+
+// legacyTaken -- сокет, переданный прежней версией без адреса, уже отдан.
+var legacyTaken bool
+
+// inheritedFD -- номер унаследованного дескриптора сокета для addr.
+func inheritedFD(addr string) (int, bool, error) {
+	for _, item := range strings.Split(os.Getenv(envListenFD), ",") {
+		fd, a, ok := strings.Cut(item, "=")
+		if !ok && item != "" && !legacyTaken {
+			legacyTaken = true
+		} else if !ok || a != addr {
+			continue
+		}
+		n, err := strconv.Atoi(fd)
+		if err != nil {
+			return 0, false, fmt.Errorf("restart: invalid %s=%q", envListenFD, os.Getenv(envListenFD))
+		}
+		return n, true, nil
+	}
+	return 0, false, nil
+}
+
+// childEnv -- окружение дочернего процесса без наших переменных (их выставит Upgrade)
+// и без WATCHDOG_PID: новый процесс станет главным для systemd и сам будет слать сторожу WATCHDOG=1.
+func childEnv() []string {
+	env := os.Environ()
+	out := env[:0:0]
+	for _, kv := range env {
+		if strings.HasPrefix(kv, envListenFD+"=") || strings.HasPrefix(kv, envReadyFD+"=") || strings.HasPrefix(kv, "WATCHDOG_PID=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd)
+
+package restart
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// UpgradeSignals пуст: на этой платформе перезапуск с передачей сокета недоступен.
+var UpgradeSignals []os.Signal
+
+// Listen открывает обычный сокет. SO_REUSEPORT и унаследованные сокеты не поддерживаются.
+func Listen(addr string, _ bool) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// Ready ничего не делает.
+func Ready() {}
+
+// Upgrade не поддерживается.
+func Upgrade(map[string]net.Listener, time.Duration) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,143 @@
+//go:build linux || darwin || freebsd
+
+package restart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UpgradeSignals -- сигналы, по которым сервер запускает Upgrade.
+var UpgradeSignals = []os.Signal{syscall.SIGHUP}
+
+// Listen возвращает унаследованный от предыдущего процесса сокет для addr, если он есть,
+// иначе открывает новый. reusePort включает SO_REUSEPORT.
+func Listen(addr string, reusePort bool) (net.Listener, error) {
+	fd, ok, err := inheritedFD(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		f := os.NewFile(uintptr(fd), "inherited-listener")
+		defer f.Close() // FileListener делает dup, исходный дескриптор больше не нужен
+		return net.FileListener(f)
+	}
+
+	var lc net.ListenConfig
+	if reusePort {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+			}); err != nil {
+				return err
+			}
+			return serr
+		}
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+var readyOnce sync.Once
+
+// Ready сообщает родителю, что новый процесс начал обслуживать запросы.
+// Вне Upgrade ничего не делает; повторные вызовы безопасны.
+func Ready() {
+	readyOnce.Do(func() {
+		s := os.Getenv(envReadyFD)
+		if s == "" {
+			return
+		}
+		fd, err := strconv.Atoi(s)
+		if err != nil {
+			return
+		}
+		f := os.NewFile(uintptr(fd), "ready")
+		_, _ = f.Write([]byte{1})
+		_ = f.Close()
+	})
+}
+
+// Upgrade запускает новую копию бинарника, передаёт ей сокеты lns (ключ -- адрес, с которым
+// новый процесс вызовет Listen) и ждёт Ready не дольше timeout.
+// При ошибке новый процесс убивается, а текущий продолжает работать как ни в чём не бывало.
+func Upgrade(lns map[string]net.Listener, timeout time.Duration) error {
+	// ExtraFiles[i] становится fd 3+i в дочернем процессе.
+	var files []*os.File
+	var fds []string
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for addr, ln := range lns {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("restart: listener %T cannot be handed off", ln)
+		}
+		lf, err := fl.File()
+		if err != nil {
+			return err
+		}
+		fds = append(fds, fmt.Sprintf("%d=%s", 3+len(files), addr))
+		files = append(files, lf)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		readyW.Close()
+		return err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files[:len(files):len(files)], readyW)
+	cmd.Env = append(childEnv(),
+		envListenFD+"="+strings.Join(fds, ","),
+		fmt.Sprintf("%s=%d", envReadyFD, 3+len(files)))
+
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return err
+	}
+	// Наш экземпляр конца записи закрываем: если ребёнок умрёт, Read вернёт EOF.
+	readyW.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return errors.New("restart: new process exited before becoming ready")
+		}
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("restart: new process not ready after %s", timeout)
+	}
+
+	// Дальше новый процесс живёт сам по себе.
+	return cmd.Process.Release()
+}
@@ -0,0 +1,6 @@
+//go:build darwin || freebsd
+
+package restart
+
+// soReusePort -- SO_REUSEPORT из <sys/socket.h>.
+const soReusePort = 0x200
@@ -0,0 +1,4 @@
+package restart
+
+// soReusePort -- SO_REUSEPORT из <asm-generic/socket.h> (в пакете syscall для linux его нет).
+const soReusePort = 0xf
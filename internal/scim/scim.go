@@ -0,0 +1,335 @@
+// Package scim -- провижининг пользователей по SCIM 2.0 (RFC 7643, RFC 7644) поверх tasks.Service.
+//
+// Каталог компании (Okta, Entra ID, OneLogin и т.п.) сам заводит, меняет, блокирует и удаляет
+// пользователей:
+//
+//	/scim/v2/ServiceProviderConfig -- что умеет сервер
+//	/scim/v2/ResourceTypes         -- единственный тип ресурса: User
+//	/scim/v2/Users                 -- список (фильтр eq, постранично) и создание
+//	/scim/v2/Users/{id}            -- GET, PUT, PATCH, DELETE
+//
+// Каталог авторизуется отдельным bearer-токеном (SCIM_TOKEN), а не JWT пользователя.
+// Группы не поддерживаются: ролей в task-manager нет.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/secrets"
+	"task-manager/internal/tasks"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	basePath    = "/scim/v2"
+	contentType = "application/scim+json"
+
+	schemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaList         = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaConfig       = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	// maxResults -- больше пользователей за одну страницу списка не отдаётся.
+	maxResults = 200
+)
+
+// Handler -- HTTP-слой SCIM.
+type Handler struct {
+	svc *tasks.Service
+
+	// token -- bearer-токен каталога; пустой -- SCIM отвечает 401 на всё
+	token *secrets.Value
+}
+
+// NewHandler создаёт SCIM-обработчик. Токен проверяется на каждом запросе,
+// поэтому ротация в хранилище секретов подхватывается на лету.
+func NewHandler(svc *tasks.Service, token *secrets.Value) *Handler {
+	return &Handler{svc: svc, token: token}
+}
+
+// Mount подключает дерево /scim/v2 к корневому роутеру сервера.
+func (h *Handler) Mount(r chi.Router) {
+	r.Mount(basePath, h.routes())
+}
+
+func (h *Handler) routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(appMiddleware.LoggingMiddleware)
+	r.Use(appMiddleware.BodyLimitMiddleware(1 << 20))
+	r.Use(h.bearerAuth)
+
+	r.Get("/ServiceProviderConfig", h.serviceProviderConfig)
+	r.Get("/ResourceTypes", h.resourceTypes)
+	r.Get("/Users", h.listUsers)
+	r.Post("/Users", h.createUser)
+	r.Get("/Users/{id}", h.getUser)
+	r.Put("/Users/{id}", h.replaceUser)
+	r.Patch("/Users/{id}", h.patchUser)
+	r.Delete("/Users/{id}", h.deleteUser)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "", "Resource not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+	})
+
+	return r
+}
+
+// bearerAuth пускает только с токеном каталога.
+func (h *Handler) bearerAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || h.token.Current() == "" || !h.token.Accepts(token, time.Now()) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(w, http.StatusUnauthorized, "", "Invalid SCIM token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]any { return map[string]any{"supported": ok} }
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schemas":        []string{schemaConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": maxResults},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Static bearer token from SCIM_TOKEN",
+			"primary":     true,
+		}},
+		"meta": map[string]any{"resourceType": "ServiceProviderConfig", "location": basePath + "/ServiceProviderConfig"},
+	})
+}
+
+func (h *Handler) resourceTypes(w http.ResponseWriter, r *http.Request) {
+	user := map[string]any{
+		"schemas":     []string{schemaResourceType},
+		"id":          "User",
+		"name":        "User",
+		"endpoint":    "/Users",
+		"description": "User account",
+		"schema":      schemaUser,
+		"meta":        map[string]any{"resourceType": "ResourceType", "location": basePath + "/ResourceTypes/User"},
+	}
+	writeJSON(w, http.StatusOK, listResponse{
+		Schemas: []string{schemaList}, TotalResults: 1, StartIndex: 1, ItemsPerPage: 1, Resources: []any{user},
+	})
+}
+
+// listResponse -- ответ на запрос списка (RFC 7644, 3.4.2).
+type listResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// listUsers -- GET /Users?filter=userName eq "anna"&startIndex=1&count=100.
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	match, err := parseFilter(q.Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	start, count := 1, maxResults
+	if v := q.Get("startIndex"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 1 {
+			start = n
+		}
+	}
+	if v := q.Get("count"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < maxResults {
+			count = n
+		}
+	}
+
+	users, err := h.svc.GetAllUsers(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	var matched []tasks.User
+	for _, u := range users {
+		if match(u) {
+			matched = append(matched, u)
+		}
+	}
+	resp := listResponse{Schemas: []string{schemaList}, TotalResults: len(matched), StartIndex: start, Resources: []any{}}
+	for i := start - 1; i < len(matched) && len(resp.Resources) < count; i++ {
+		resp.Resources = append(resp.Resources, toResource(matched[i]))
+	}
+	resp.ItemsPerPage = len(resp.Resources)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toResource(*u))
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var in userResource
+	if !decode(w, r, &in) {
+		return
+	}
+	u := tasks.User{}
+	in.applyTo(&u)
+	if err := h.svc.ProvisionUser(r.Context(), &u, in.Password); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	logAction(r, "created user id=%d username=%q active=%t", u.ID, u.Username, !u.Disabled)
+	res := toResource(u)
+	w.Header().Set("Location", res.Meta.Location)
+	writeJSON(w, http.StatusCreated, res)
+}
+
+// replaceUser -- PUT: ресурс заменяется целиком, не переданные поля очищаются.
+func (h *Handler) replaceUser(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	var in userResource
+	if !decode(w, r, &in) {
+		return
+	}
+	u := tasks.User{ID: current.ID}
+	in.applyTo(&u)
+	h.saveUser(w, r, current, &u, in.Password)
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	var req patchRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	u := *current
+	password, err := req.apply(&u)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	h.saveUser(w, r, current, &u, password)
+}
+
+func (h *Handler) saveUser(w http.ResponseWriter, r *http.Request, current, u *tasks.User, password string) {
+	if err := h.svc.UpdateProvisionedUser(r.Context(), u, password); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	switch {
+	case u.Disabled && !current.Disabled:
+		logAction(r, "deactivated user id=%d username=%q", u.ID, u.Username)
+	case !u.Disabled && current.Disabled:
+		logAction(r, "reactivated user id=%d username=%q", u.ID, u.Username)
+	default:
+		logAction(r, "updated user id=%d username=%q", u.ID, u.Username)
+	}
+	writeJSON(w, http.StatusOK, toResource(*u))
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteUser(r.Context(), u.ID); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	logAction(r, "deleted user id=%d username=%q", u.ID, u.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUser достаёт пользователя по {id}; при ошибке ответ уже записан.
+func (h *Handler) loadUser(w http.ResponseWriter, r *http.Request) (*tasks.User, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusNotFound, "", "User not found")
+		return nil, false
+	}
+	u, err := h.svc.UserByID(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return nil, false
+	}
+	return u, true
+}
+
+func (h *Handler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, tasks.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "", "User not found")
+	case errors.Is(err, tasks.ErrUserAlreadyExists):
+		writeError(w, http.StatusConflict, "uniqueness", "userName is already taken")
+	case errors.Is(err, tasks.ErrInvalidUsername), errors.Is(err, tasks.ErrInvalidPassword):
+		writeError(w, http.StatusBadRequest, "invalidValue", err.Error())
+	case errors.Is(err, tasks.ErrProvisioningUnsupported):
+		writeError(w, http.StatusNotImplemented, "", "User provisioning needs PostgreSQL storage")
+	default:
+		log.Printf("request_id=%s scim error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		writeError(w, http.StatusInternalServerError, "", "Internal server error")
+	}
+}
+
+// logAction пишет в лог изменение пользователя каталогом.
+func logAction(r *http.Request, format string, args ...any) {
+	log.Printf("request_id=%s scim from %s: %s", appMiddleware.GetRequestID(r.Context()), appMiddleware.ClientIP(r), fmt.Sprintf(format, args...))
+}
+
+// decode читает тело запроса; при ошибке ответ уже записан.
+func decode(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidSyntax", "Invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError пишет ошибку в формате SCIM (RFC 7644, 3.12): status -- строкой, scimType -- по желанию.
+func writeError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]any{
+		"schemas": []string{schemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,251 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"task-manager/internal/tasks"
+)
+
+// userResource -- пользователь в схеме urn:ietf:params:scim:schemas:core:2.0:User.
+// Хранится только то, что есть у tasks.User; остальные атрибуты каталога молча игнорируются.
+type userResource struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Password    string   `json:"password,omitempty"` // только на запись, в ответах не бывает
+	Meta        *meta    `json:"meta,omitempty"`
+}
+
+type name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+// toResource переводит пользователя в ресурс SCIM.
+func toResource(u tasks.User) userResource {
+	active := !u.Disabled
+	res := userResource{
+		Schemas:     []string{schemaUser},
+		ID:          strconv.Itoa(u.ID),
+		ExternalID:  u.ExternalID,
+		UserName:    u.Username,
+		DisplayName: u.DisplayName,
+		Active:      &active,
+		Meta:        &meta{ResourceType: "User", Location: basePath + "/Users/" + strconv.Itoa(u.ID)},
+	}
+	if u.DisplayName != "" {
+		res.Name = &name{Formatted: u.DisplayName}
+	}
+	if u.Email != "" {
+		res.Emails = []email{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return res
+}
+
+// applyTo переносит атрибуты ресурса в пользователя. Не переданный active -- активен.
+func (res userResource) applyTo(u *tasks.User) {
+	u.Username = strings.TrimSpace(res.UserName)
+	u.ExternalID = res.ExternalID
+	u.DisplayName = res.DisplayName
+	if u.DisplayName == "" && res.Name != nil {
+		u.DisplayName = res.Name.display()
+	}
+	u.Email = primaryEmail(res.Emails)
+	u.Disabled = res.Active != nil && !*res.Active
+}
+
+// display -- имя для показа: formatted или "имя фамилия".
+func (n name) display() string {
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+// primaryEmail -- адрес с primary: true, иначе первый.
+func primaryEmail(emails []email) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// parseFilter разбирает фильтр списка. Поддерживается то, чем каталоги ищут пользователя
+// перед созданием: `userName eq "..."`, `externalId eq "..."` и `id eq "..."`.
+func parseFilter(filter string) (func(tasks.User) bool, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return func(tasks.User) bool { return true }, nil
+	}
+	fields := strings.SplitN(filter, " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "eq") {
+		return nil, fmt.Errorf("unsupported filter %q: only <attribute> eq \"value\" is supported", filter)
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(fields[2]))
+	if err != nil {
+		return nil, fmt.Errorf("filter value must be a quoted string: %s", fields[2])
+	}
+	switch strings.ToLower(fields[0]) {
+	case "username":
+		// userName в SCIM регистронезависим (caseExact: false)
+		return func(u tasks.User) bool { return strings.EqualFold(u.Username, value) }, nil
+	case "externalid":
+		return func(u tasks.User) bool { return u.ExternalID == value }, nil
+	case "id":
+		return func(u tasks.User) bool { return strconv.Itoa(u.ID) == value }, nil
+	}
+	return nil, fmt.Errorf("filtering by %q is not supported", fields[0])
+}
+
+// patchRequest -- тело PATCH (RFC 7644, 3.5.2).
+type patchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []patchOp `json:"Operations"`
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// apply применяет операции к пользователю и возвращает новый пароль, если его задали.
+// Операция без path несёт объект атрибутов (так блокирует Entra ID: {"active": false}).
+func (req patchRequest) apply(u *tasks.User) (password string, err error) {
+	if len(req.Operations) == 0 {
+		return "", errors.New("no operations")
+	}
+	for _, op := range req.Operations {
+		kind := strings.ToLower(op.Op)
+		switch kind {
+		case "add", "replace", "remove":
+		default:
+			return "", fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if op.Path == "" {
+			if kind == "remove" {
+				return "", errors.New("remove requires a path")
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return "", errors.New("value must be an object when path is omitted")
+			}
+			for path, v := range attrs {
+				if err := setAttr(u, &password, path, v); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if kind == "remove" {
+			if err := removeAttr(u, op.Path); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := setAttr(u, &password, op.Path, op.Value); err != nil {
+			return "", err
+		}
+	}
+	return password, nil
+}
+
+// setAttr присваивает атрибут по его пути. Незнакомые атрибуты игнорируются.
+func setAttr(u *tasks.User, password *string, path string, raw json.RawMessage) error {
+	p := strings.ToLower(path)
+	switch {
+	case p == "active":
+		active, err := parseBool(raw)
+		if err != nil {
+			return err
+		}
+		u.Disabled = !active
+	case p == "username":
+		return unmarshalString(raw, path, &u.Username)
+	case p == "displayname", p == "name.formatted":
+		return unmarshalString(raw, path, &u.DisplayName)
+	case p == "externalid":
+		return unmarshalString(raw, path, &u.ExternalID)
+	case p == "password":
+		return unmarshalString(raw, path, password)
+	case p == "name":
+		var n name
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return fmt.Errorf("invalid value for %s", path)
+		}
+		u.DisplayName = n.display()
+	case p == "emails":
+		var emails []email
+		if err := json.Unmarshal(raw, &emails); err != nil {
+			return fmt.Errorf("invalid value for %s", path)
+		}
+		u.Email = primaryEmail(emails)
+	case strings.HasPrefix(p, "emails[") && strings.HasSuffix(p, "].value"):
+		return unmarshalString(raw, path, &u.Email)
+	}
+	return nil
+}
+
+// removeAttr очищает необязательный атрибут.
+func removeAttr(u *tasks.User, path string) error {
+	p := strings.ToLower(path)
+	switch {
+	case p == "displayname", p == "name", p == "name.formatted":
+		u.DisplayName = ""
+	case p == "externalid":
+		u.ExternalID = ""
+	case p == "emails", strings.HasPrefix(p, "emails["):
+		u.Email = ""
+	case p == "username", p == "active":
+		return fmt.Errorf("%s cannot be removed", path)
+	}
+	return nil
+}
+
+func unmarshalString(raw json.RawMessage, path string, dst *string) error {
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("invalid value for %s: expected a string", path)
+	}
+	return nil
+}
+
+// parseBool принимает true/false и строки "True"/"False" (их шлёт Entra ID).
+func parseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, errors.New("invalid value for active: expected a boolean")
+}
@@ -0,0 +1,560 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// universe -- встроенные функции, доступные любому скрипту.
+var universe map[string]*Builtin
+
+func init() {
+	universe = map[string]*Builtin{}
+	for name, fn := range map[string]func(th *thread, args []Value) (Value, error){
+		"len":    builtinLen,
+		"str":    builtinStr,
+		"int":    builtinInt,
+		"bool":   builtinBool,
+		"type":   builtinType,
+		"range":  builtinRange,
+		"sorted": builtinSorted,
+		"min":    func(th *thread, args []Value) (Value, error) { return minMax("min", args, -1) },
+		"max":    func(th *thread, args []Value) (Value, error) { return minMax("max", args, 1) },
+		"any":    func(th *thread, args []Value) (Value, error) { return anyAll("any", args, true) },
+		"all":    func(th *thread, args []Value) (Value, error) { return anyAll("all", args, false) },
+		"print":  builtinPrint,
+		"fail":   builtinFail,
+	} {
+		universe[name] = &Builtin{Name: name, fn: fn}
+	}
+}
+
+// wantArgs проверяет число аргументов: от lo до hi включительно.
+func wantArgs(name string, args []Value, lo, hi int) error {
+	switch {
+	case len(args) < lo || len(args) > hi:
+		if lo == hi {
+			return fmt.Errorf("%s() takes %d argument(s), got %d", name, lo, len(args))
+		}
+		return fmt.Errorf("%s() takes %d to %d arguments, got %d", name, lo, hi, len(args))
+	}
+	return nil
+}
+
+func argString(name string, v Value) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s(): want string, got %s", name, TypeName(v))
+	}
+	return s, nil
+}
+
+func builtinLen(_ *thread, args []Value) (Value, error) {
+	if err := wantArgs("len", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch x := args[0].(type) {
+	case string:
+		return int64(utf8.RuneCountInString(x)), nil
+	case *List:
+		return int64(len(x.Elems)), nil
+	case Tuple:
+		return int64(len(x)), nil
+	case *Dict:
+		return int64(x.Len()), nil
+	}
+	return nil, fmt.Errorf("len(): %s has no length", TypeName(args[0]))
+}
+
+func builtinStr(th *thread, args []Value) (Value, error) {
+	if err := wantArgs("str", args, 1, 1); err != nil {
+		return nil, err
+	}
+	s := String(args[0])
+	return s, th.alloc(len(s))
+}
+
+func builtinInt(_ *thread, args []Value) (Value, error) {
+	if err := wantArgs("int", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch x := args[0].(type) {
+	case int64:
+		return x, nil
+	case bool:
+		if x {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("int(): invalid literal %q", x)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("int(): cannot convert %s", TypeName(args[0]))
+}
+
+func builtinBool(_ *thread, args []Value) (Value, error) {
+	if err := wantArgs("bool", args, 0, 1); err != nil {
+		return nil, err
+	}
+	return len(args) == 1 && Truth(args[0]), nil
+}
+
+func builtinType(_ *thread, args []Value) (Value, error) {
+	if err := wantArgs("type", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return TypeName(args[0]), nil
+}
+
+func builtinRange(th *thread, args []Value) (Value, error) {
+	if err := wantArgs("range", args, 1, 3); err != nil {
+		return nil, err
+	}
+	var nums [3]int64
+	for i, a := range args {
+		n, ok := a.(int64)
+		if !ok {
+			return nil, fmt.Errorf("range(): want int, got %s", TypeName(a))
+		}
+		nums[i] = n
+	}
+	start, stop, step := int64(0), nums[0], int64(1)
+	if len(args) > 1 {
+		start, stop = nums[0], nums[1]
+	}
+	if len(args) > 2 {
+		step = nums[2]
+	}
+	if step == 0 {
+		return nil, errors.New("range(): step must not be zero")
+	}
+	// Разность границ и шаг считаем в uint64: stop - start в int64 переполняется,
+	// например для range(-2**63, 2**63-1), а -step -- для шага -2**63.
+	var count uint64
+	switch {
+	case step > 0 && stop > start:
+		count = (uint64(stop)-uint64(start)-1)/uint64(step) + 1
+	case step < 0 && stop < start:
+		count = (uint64(start)-uint64(stop)-1)/(uint64(-(step+1))+1) + 1
+	}
+	if count > uint64(th.maxMem/sizeElem) {
+		return nil, ErrMemoryLimit
+	}
+	if err := th.alloc(sizeContainer + sizeElem*int(count)); err != nil {
+		return nil, err
+	}
+	out := make([]Value, count)
+	for i := range out {
+		out[i] = start + int64(i)*step
+	}
+	return &List{Elems: out}, nil
+}
+
+func builtinSorted(th *thread, args []Value) (Value, error) {
+	if err := wantArgs("sorted", args, 1, 1); err != nil {
+		return nil, err
+	}
+	elems, err := iterate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	elems = slices.Clone(elems)
+	if err := th.alloc(sizeContainer + sizeElem*len(elems)); err != nil {
+		return nil, err
+	}
+	var cmpErr error
+	slices.SortStableFunc(elems, func(a, b Value) int {
+		c, err := compare(a, b)
+		if err != nil && cmpErr == nil {
+			cmpErr = err
+		}
+		return c
+	})
+	if cmpErr != nil {
+		return nil, fmt.Errorf("sorted(): %w", cmpErr)
+	}
+	return &List{Elems: elems}, nil
+}
+
+// minMax -- min/max по одной последовательности или по нескольким аргументам. sign -- -1 для min, 1 для max.
+func minMax(name string, args []Value, sign int) (Value, error) {
+	elems := args
+	if len(args) == 1 {
+		var err error
+		if elems, err = iterate(args[0]); err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("%s(): empty sequence", name)
+	}
+	best := elems[0]
+	for _, e := range elems[1:] {
+		c, err := compare(e, best)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+		if c*sign > 0 {
+			best = e
+		}
+	}
+	return best, nil
+}
+
+// anyAll -- any (want == true: есть истинный элемент) или all (want == false: нет ложного).
+func anyAll(name string, args []Value, want bool) (Value, error) {
+	if err := wantArgs(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	elems, err := iterate(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", name, err)
+	}
+	for _, e := range elems {
+		if Truth(e) == want {
+			return want, nil
+		}
+	}
+	return !want, nil
+}
+
+func builtinPrint(th *thread, args []Value) (Value, error) {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = String(a)
+	}
+	if th.print != nil {
+		msg := strings.Join(parts, " ")
+		if err := th.alloc(len(msg)); err != nil {
+			return nil, err
+		}
+		th.print(msg)
+	}
+	return nil, nil
+}
+
+// builtinFail прерывает скрипт с ошибкой -- как fail() в Starlark.
+func builtinFail(_ *thread, args []Value) (Value, error) {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = String(a)
+	}
+	return nil, errors.New("fail: " + strings.Join(parts, " "))
+}
+
+// ---------------------------------------------------------------------------
+// Методы строк, списков и словарей
+// ---------------------------------------------------------------------------
+
+type methodKey struct{ typ, name string }
+
+type methodFunc func(th *thread, recv Value, args []Value) (Value, error)
+
+var methods map[methodKey]methodFunc
+
+func init() {
+	methods = map[methodKey]methodFunc{
+		{"string", "lower"}: stringFunc("lower", strings.ToLower),
+		{"string", "upper"}: stringFunc("upper", strings.ToUpper),
+		{"string", "strip"}: stripFunc("strip", strings.TrimSpace, strings.Trim),
+		{"string", "lstrip"}: stripFunc("lstrip", func(s string) string { return strings.TrimLeft(s, " \t\r\n") },
+			strings.TrimLeft),
+		{"string", "rstrip"}: stripFunc("rstrip", func(s string) string { return strings.TrimRight(s, " \t\r\n") },
+			strings.TrimRight),
+		{"string", "startswith"}: stringPredicate("startswith", strings.HasPrefix),
+		{"string", "endswith"}:   stringPredicate("endswith", strings.HasSuffix),
+		{"string", "split"}:      stringSplit,
+		{"string", "join"}:       stringJoin,
+		{"string", "replace"}:    stringReplace,
+		{"string", "find"}:       stringFind,
+		{"string", "count"}:      stringCount,
+
+		{"list", "append"}: listAppend,
+		{"list", "extend"}: listExtend,
+		{"list", "remove"}: listRemove,
+		{"list", "index"}:  listIndex,
+		{"list", "pop"}:    listPop,
+
+		{"dict", "get"}:    dictGet,
+		{"dict", "keys"}:   dictKeys,
+		{"dict", "values"}: dictValues,
+		{"dict", "items"}:  dictItems,
+		{"dict", "pop"}:    dictPop,
+	}
+}
+
+func stringFunc(name string, fn func(string) string) methodFunc {
+	return func(th *thread, recv Value, args []Value) (Value, error) {
+		if err := wantArgs(name, args, 0, 0); err != nil {
+			return nil, err
+		}
+		s := fn(recv.(string))
+		return s, th.alloc(len(s))
+	}
+}
+
+func stripFunc(name string, space func(string) string, cutset func(string, string) string) methodFunc {
+	return func(th *thread, recv Value, args []Value) (Value, error) {
+		if err := wantArgs(name, args, 0, 1); err != nil {
+			return nil, err
+		}
+		if len(args) == 0 || args[0] == nil {
+			return space(recv.(string)), nil
+		}
+		chars, err := argString(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return cutset(recv.(string), chars), nil
+	}
+}
+
+func stringPredicate(name string, fn func(s, prefix string) bool) methodFunc {
+	return func(_ *thread, recv Value, args []Value) (Value, error) {
+		if err := wantArgs(name, args, 1, 1); err != nil {
+			return nil, err
+		}
+		// Как в Python, можно передать кортеж вариантов: title.startswith(("BUG", "FIX")).
+		if t, ok := args[0].(Tuple); ok {
+			for _, v := range t {
+				s, err := argString(name, v)
+				if err != nil {
+					return nil, err
+				}
+				if fn(recv.(string), s) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		s, err := argString(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(recv.(string), s), nil
+	}
+}
+
+func stringSplit(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("split", args, 0, 1); err != nil {
+		return nil, err
+	}
+	var parts []string
+	if len(args) == 0 || args[0] == nil {
+		parts = strings.Fields(recv.(string))
+	} else {
+		sep, err := argString("split", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if sep == "" {
+			return nil, errors.New("split(): empty separator")
+		}
+		parts = strings.Split(recv.(string), sep)
+	}
+	if err := th.alloc(sizeContainer + sizeElem*len(parts) + len(recv.(string))); err != nil {
+		return nil, err
+	}
+	out := make([]Value, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return &List{Elems: out}, nil
+}
+
+func stringJoin(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("join", args, 1, 1); err != nil {
+		return nil, err
+	}
+	elems, err := iterate(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("join(): %w", err)
+	}
+	parts := make([]string, len(elems))
+	size := 0
+	for i, e := range elems {
+		if parts[i], err = argString("join", e); err != nil {
+			return nil, err
+		}
+		size += len(parts[i]) + len(recv.(string))
+	}
+	if err := th.alloc(size); err != nil {
+		return nil, err
+	}
+	return strings.Join(parts, recv.(string)), nil
+}
+
+func stringReplace(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("replace", args, 2, 2); err != nil {
+		return nil, err
+	}
+	old, err := argString("replace", args[0])
+	if err != nil {
+		return nil, err
+	}
+	repl, err := argString("replace", args[1])
+	if err != nil {
+		return nil, err
+	}
+	s := recv.(string)
+	if n := strings.Count(s, old); n > 0 && len(repl) > len(old) {
+		if err := th.alloc(len(s) + n*(len(repl)-len(old))); err != nil {
+			return nil, err
+		}
+	}
+	return strings.ReplaceAll(s, old, repl), nil
+}
+
+func stringFind(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("find", args, 1, 1); err != nil {
+		return nil, err
+	}
+	sub, err := argString("find", args[0])
+	if err != nil {
+		return nil, err
+	}
+	s := recv.(string)
+	return runeIndex(s, strings.Index(s, sub)), nil
+}
+
+func stringCount(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("count", args, 1, 1); err != nil {
+		return nil, err
+	}
+	sub, err := argString("count", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if sub == "" {
+		return int64(utf8.RuneCountInString(recv.(string)) + 1), nil
+	}
+	return int64(strings.Count(recv.(string), sub)), nil
+}
+
+func listAppend(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("append", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if err := th.alloc(sizeElem); err != nil {
+		return nil, err
+	}
+	l := recv.(*List)
+	l.Elems = append(l.Elems, args[0])
+	return nil, nil
+}
+
+func listExtend(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("extend", args, 1, 1); err != nil {
+		return nil, err
+	}
+	return nil, th.extend(recv.(*List), args[0])
+}
+
+func listRemove(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("remove", args, 1, 1); err != nil {
+		return nil, err
+	}
+	l := recv.(*List)
+	for i, e := range l.Elems {
+		if equal(e, args[0]) {
+			l.Elems = slices.Delete(l.Elems, i, i+1)
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("remove(): %s not in list", String(args[0]))
+}
+
+func listIndex(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("index", args, 1, 1); err != nil {
+		return nil, err
+	}
+	for i, e := range recv.(*List).Elems {
+		if equal(e, args[0]) {
+			return int64(i), nil
+		}
+	}
+	return nil, fmt.Errorf("index(): %s not in list", String(args[0]))
+}
+
+func listPop(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("pop", args, 0, 1); err != nil {
+		return nil, err
+	}
+	l := recv.(*List)
+	var i Value = int64(-1)
+	if len(args) == 1 {
+		i = args[0]
+	}
+	n, err := index(i, len(l.Elems))
+	if err != nil {
+		return nil, fmt.Errorf("pop(): %w", err)
+	}
+	v := l.Elems[n]
+	l.Elems = slices.Delete(l.Elems, n, n+1)
+	return v, nil
+}
+
+func dictGet(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("get", args, 1, 2); err != nil {
+		return nil, err
+	}
+	if v, ok := recv.(*Dict).Get(args[0]); ok {
+		return v, nil
+	}
+	if len(args) == 2 {
+		return args[1], nil
+	}
+	return nil, nil
+}
+
+func dictKeys(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("keys", args, 0, 0); err != nil {
+		return nil, err
+	}
+	d := recv.(*Dict)
+	return &List{Elems: d.Keys()}, th.alloc(sizeContainer + sizeElem*d.Len())
+}
+
+func dictValues(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("values", args, 0, 0); err != nil {
+		return nil, err
+	}
+	d := recv.(*Dict)
+	return &List{Elems: append([]Value(nil), d.vals...)}, th.alloc(sizeContainer + sizeElem*d.Len())
+}
+
+func dictItems(th *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("items", args, 0, 0); err != nil {
+		return nil, err
+	}
+	d := recv.(*Dict)
+	if err := th.alloc(sizeContainer + (sizeContainer+2*sizeElem)*d.Len()); err != nil {
+		return nil, err
+	}
+	out := make([]Value, d.Len())
+	for i, k := range d.keys {
+		out[i] = Tuple{k, d.vals[i]}
+	}
+	return &List{Elems: out}, nil
+}
+
+func dictPop(_ *thread, recv Value, args []Value) (Value, error) {
+	if err := wantArgs("pop", args, 1, 2); err != nil {
+		return nil, err
+	}
+	if v, ok := recv.(*Dict).delete(args[0]); ok {
+		return v, nil
+	}
+	if len(args) == 2 {
+		return args[1], nil
+	}
+	return nil, fmt.Errorf("pop(): key %s not found", String(args[0]))
+}
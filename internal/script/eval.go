@@ -0,0 +1,739 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Оценка памяти: сколько байт считать за элемент контейнера и за сам контейнер.
+const (
+	sizeElem      = 16
+	sizeContainer = 32
+)
+
+type thread struct {
+	ctx      context.Context
+	deadline time.Time
+	steps    int
+	maxSteps int
+	mem      int
+	maxMem   int
+	globals  map[string]Value
+	print    func(string)
+}
+
+type control int
+
+const (
+	ctrlNone control = iota
+	ctrlBreak
+	ctrlContinue
+)
+
+// errorf -- ошибка выполнения в позиции pos.
+func errorf(pos Pos, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// at привязывает ошибку функции или ограничения к позиции pos.
+func at(pos Pos, err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *Error
+	if errors.As(err, &se) {
+		return err
+	}
+	return &Error{Pos: pos, Msg: err.Error(), Err: err}
+}
+
+// step считает шаг и проверяет ограничения по шагам и времени.
+func (th *thread) step(pos Pos) error {
+	th.steps++
+	if th.steps > th.maxSteps {
+		return at(pos, ErrStepLimit)
+	}
+	if th.steps%256 == 0 {
+		if err := th.ctx.Err(); err != nil {
+			return at(pos, err)
+		}
+		if time.Now().After(th.deadline) {
+			return at(pos, ErrTimeout)
+		}
+	}
+	return nil
+}
+
+// alloc учитывает n байт новой строки или контейнера.
+func (th *thread) alloc(n int) error {
+	if n < 0 || th.mem+n > th.maxMem {
+		th.mem = th.maxMem
+		return ErrMemoryLimit
+	}
+	th.mem += n
+	return nil
+}
+
+func (th *thread) execBlock(stmts []stmt) (control, error) {
+	for _, s := range stmts {
+		if err := th.step(s.pos()); err != nil {
+			return ctrlNone, err
+		}
+		switch s := s.(type) {
+		case *exprStmt:
+			if _, err := th.eval(s.x); err != nil {
+				return ctrlNone, err
+			}
+		case *assignStmt:
+			if err := th.assignStmt(s); err != nil {
+				return ctrlNone, err
+			}
+		case *ifStmt:
+			cond, err := th.eval(s.cond)
+			if err != nil {
+				return ctrlNone, err
+			}
+			body := s.els
+			if Truth(cond) {
+				body = s.then
+			}
+			if ctrl, err := th.execBlock(body); ctrl != ctrlNone || err != nil {
+				return ctrl, err
+			}
+		case *forStmt:
+			if err := th.forStmt(s); err != nil {
+				return ctrlNone, err
+			}
+		case *branchStmt:
+			switch s.kind {
+			case "break":
+				return ctrlBreak, nil
+			case "continue":
+				return ctrlContinue, nil
+			}
+		}
+	}
+	return ctrlNone, nil
+}
+
+func (th *thread) assignStmt(s *assignStmt) error {
+	v, err := th.eval(s.rhs)
+	if err != nil {
+		return err
+	}
+	if s.op != "" {
+		cur, err := th.eval(s.lhs)
+		if err != nil {
+			return err
+		}
+		// Для списков x += y дополняет тот же список, как в Python.
+		if l, ok := cur.(*List); ok && s.op == "+" {
+			return at(s.p, th.extend(l, v))
+		}
+		if v, err = th.binary(s.p, s.op, cur, v); err != nil {
+			return err
+		}
+	}
+	return th.assign(s.lhs, v)
+}
+
+func (th *thread) assign(lhs expr, v Value) error {
+	switch lhs := lhs.(type) {
+	case *identExpr:
+		th.globals[lhs.name] = v
+		return nil
+	case *attrExpr:
+		x, err := th.eval(lhs.x)
+		if err != nil {
+			return err
+		}
+		o, ok := x.(*Object)
+		if !ok {
+			return errorf(lhs.p, "cannot assign attribute %q of %s", lhs.name, TypeName(x))
+		}
+		return at(lhs.p, o.setAttr(lhs.name, v))
+	case *indexExpr:
+		x, err := th.eval(lhs.x)
+		if err != nil {
+			return err
+		}
+		i, err := th.eval(lhs.i)
+		if err != nil {
+			return err
+		}
+		switch x := x.(type) {
+		case *List:
+			n, err := index(i, len(x.Elems))
+			if err != nil {
+				return at(lhs.p, err)
+			}
+			x.Elems[n] = v
+			return nil
+		case *Dict:
+			if _, ok := x.Get(i); !ok {
+				if err := th.alloc(2 * sizeElem); err != nil {
+					return at(lhs.p, err)
+				}
+			}
+			return at(lhs.p, x.Set(i, v))
+		}
+		return errorf(lhs.p, "%s does not support item assignment", TypeName(x))
+	}
+	return errorf(lhs.pos(), "cannot assign to this expression")
+}
+
+func (th *thread) forStmt(s *forStmt) error {
+	x, err := th.eval(s.iter)
+	if err != nil {
+		return err
+	}
+	elems, err := iterate(x)
+	if err != nil {
+		return at(s.iter.pos(), err)
+	}
+	for _, e := range elems {
+		if err := th.step(s.p); err != nil {
+			return err
+		}
+		if len(s.vars) == 1 {
+			th.globals[s.vars[0]] = e
+		} else {
+			parts, err := iterate(e)
+			if err != nil || len(parts) != len(s.vars) {
+				return errorf(s.p, "cannot unpack %s into %d variables", TypeName(e), len(s.vars))
+			}
+			for i, name := range s.vars {
+				th.globals[name] = parts[i]
+			}
+		}
+		ctrl, err := th.execBlock(s.body)
+		if err != nil {
+			return err
+		}
+		if ctrl == ctrlBreak {
+			break
+		}
+	}
+	return nil
+}
+
+// iterate возвращает снимок элементов: изменения списка внутри цикла на перебор не влияют.
+func iterate(x Value) ([]Value, error) {
+	switch x := x.(type) {
+	case *List:
+		return append([]Value(nil), x.Elems...), nil
+	case Tuple:
+		return x, nil
+	case *Dict:
+		return x.Keys(), nil
+	case string:
+		return nil, errors.New("string is not iterable, use .split()")
+	}
+	return nil, fmt.Errorf("%s is not iterable", TypeName(x))
+}
+
+func (th *thread) eval(e expr) (Value, error) {
+	if err := th.step(e.pos()); err != nil {
+		return nil, err
+	}
+	switch e := e.(type) {
+	case *literalExpr:
+		return e.v, nil
+	case *identExpr:
+		if v, ok := th.globals[e.name]; ok {
+			return v, nil
+		}
+		if b, ok := universe[e.name]; ok {
+			return b, nil
+		}
+		return nil, errorf(e.p, "undefined: %s", e.name)
+	case *listExpr:
+		elems, err := th.evalAll(e.elems)
+		if err != nil {
+			return nil, err
+		}
+		if err := th.alloc(sizeContainer + sizeElem*len(elems)); err != nil {
+			return nil, at(e.p, err)
+		}
+		return &List{Elems: elems}, nil
+	case *tupleExpr:
+		elems, err := th.evalAll(e.elems)
+		if err != nil {
+			return nil, err
+		}
+		if err := th.alloc(sizeContainer + sizeElem*len(elems)); err != nil {
+			return nil, at(e.p, err)
+		}
+		return Tuple(elems), nil
+	case *dictExpr:
+		d := NewDict()
+		if err := th.alloc(sizeContainer + 2*sizeElem*len(e.keys)); err != nil {
+			return nil, at(e.p, err)
+		}
+		for i := range e.keys {
+			k, err := th.eval(e.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			v, err := th.eval(e.vals[i])
+			if err != nil {
+				return nil, err
+			}
+			if err := d.Set(k, v); err != nil {
+				return nil, at(e.keys[i].pos(), err)
+			}
+		}
+		return d, nil
+	case *unaryExpr:
+		x, err := th.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "not":
+			return !Truth(x), nil
+		case "+", "-":
+			n, ok := x.(int64)
+			if !ok {
+				return nil, errorf(e.p, "unsupported operand type for unary %s: %s", e.op, TypeName(x))
+			}
+			if e.op == "-" {
+				if n == minInt {
+					return nil, errorf(e.p, "integer overflow")
+				}
+				n = -n
+			}
+			return n, nil
+		}
+	case *binaryExpr:
+		x, err := th.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "and":
+			if !Truth(x) {
+				return x, nil
+			}
+			return th.eval(e.y)
+		case "or":
+			if Truth(x) {
+				return x, nil
+			}
+			return th.eval(e.y)
+		}
+		y, err := th.eval(e.y)
+		if err != nil {
+			return nil, err
+		}
+		return th.binary(e.p, e.op, x, y)
+	case *condExpr:
+		cond, err := th.eval(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		if Truth(cond) {
+			return th.eval(e.then)
+		}
+		return th.eval(e.els)
+	case *attrExpr:
+		x, err := th.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		if o, ok := x.(*Object); ok {
+			v, ok := o.attrs[e.name]
+			if !ok {
+				return nil, errorf(e.p, "%s has no attribute %q", o.Type, e.name)
+			}
+			return v, nil
+		}
+		if _, ok := methods[methodKey{TypeName(x), e.name}]; ok {
+			return &method{recv: x, name: e.name}, nil
+		}
+		return nil, errorf(e.p, "%s has no attribute %q", TypeName(x), e.name)
+	case *indexExpr:
+		x, err := th.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		i, err := th.eval(e.i)
+		if err != nil {
+			return nil, err
+		}
+		v, err := getIndex(x, i)
+		return v, at(e.p, err)
+	case *sliceExpr:
+		return th.slice(e)
+	case *callExpr:
+		fn, err := th.eval(e.fn)
+		if err != nil {
+			return nil, err
+		}
+		args, err := th.evalAll(e.args)
+		if err != nil {
+			return nil, err
+		}
+		var v Value
+		switch fn := fn.(type) {
+		case *Builtin:
+			v, err = fn.fn(th, args)
+		case *method:
+			v, err = methods[methodKey{TypeName(fn.recv), fn.name}](th, fn.recv, args)
+		default:
+			return nil, errorf(e.p, "%s is not callable", TypeName(fn))
+		}
+		return v, at(e.p, err)
+	}
+	return nil, errorf(e.pos(), "unsupported expression")
+}
+
+func (th *thread) evalAll(list []expr) ([]Value, error) {
+	out := make([]Value, len(list))
+	for i, e := range list {
+		v, err := th.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+const (
+	maxInt = int64(^uint64(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+func (th *thread) binary(pos Pos, op string, x, y Value) (Value, error) {
+	switch op {
+	case "==":
+		return equal(x, y), nil
+	case "!=":
+		return !equal(x, y), nil
+	case "in", "not in":
+		ok, err := contains(y, x)
+		if err != nil {
+			return nil, at(pos, err)
+		}
+		return ok == (op == "in"), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(x, y)
+		if err != nil {
+			return nil, at(pos, err)
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "/":
+		return nil, errorf(pos, "float division is not supported, use //")
+	}
+
+	if a, ok := x.(int64); ok {
+		if b, ok := y.(int64); ok {
+			return intOp(pos, op, a, b)
+		}
+	}
+	switch op {
+	case "+":
+		switch a := x.(type) {
+		case string:
+			if b, ok := y.(string); ok {
+				if err := th.alloc(len(a) + len(b)); err != nil {
+					return nil, at(pos, err)
+				}
+				return a + b, nil
+			}
+		case *List:
+			if b, ok := y.(*List); ok {
+				if err := th.alloc(sizeContainer + sizeElem*(len(a.Elems)+len(b.Elems))); err != nil {
+					return nil, at(pos, err)
+				}
+				return &List{Elems: append(append([]Value(nil), a.Elems...), b.Elems...)}, nil
+			}
+		case Tuple:
+			if b, ok := y.(Tuple); ok {
+				if err := th.alloc(sizeContainer + sizeElem*(len(a)+len(b))); err != nil {
+					return nil, at(pos, err)
+				}
+				return append(append(Tuple(nil), a...), b...), nil
+			}
+		}
+	case "*":
+		if n, ok := x.(int64); ok {
+			x, y = y, n
+		}
+		n, ok := y.(int64)
+		if !ok {
+			break
+		}
+		n = max(n, 0)
+		switch a := x.(type) {
+		case string:
+			if n > 0 && int64(len(a)) > int64(th.maxMem)/n {
+				return nil, at(pos, ErrMemoryLimit)
+			}
+			if err := th.alloc(len(a) * int(n)); err != nil {
+				return nil, at(pos, err)
+			}
+			return strings.Repeat(a, int(n)), nil
+		case *List:
+			if n > 0 && int64(len(a.Elems)) > int64(th.maxMem)/sizeElem/n {
+				return nil, at(pos, ErrMemoryLimit)
+			}
+			if err := th.alloc(sizeContainer + sizeElem*len(a.Elems)*int(n)); err != nil {
+				return nil, at(pos, err)
+			}
+			out := make([]Value, 0, len(a.Elems)*int(n))
+			for range n {
+				out = append(out, a.Elems...)
+			}
+			return &List{Elems: out}, nil
+		}
+	case "%":
+		if _, ok := x.(string); ok {
+			return nil, errorf(pos, "string formatting is not supported, use + and str()")
+		}
+	}
+	return nil, errorf(pos, "unsupported operand types for %s: %s and %s", op, TypeName(x), TypeName(y))
+}
+
+func intOp(pos Pos, op string, a, b int64) (Value, error) {
+	switch op {
+	case "+":
+		r := a + b
+		if (a > 0 && b > 0 && r < 0) || (a < 0 && b < 0 && r >= 0) {
+			return nil, errorf(pos, "integer overflow")
+		}
+		return r, nil
+	case "-":
+		r := a - b
+		if (a >= 0 && b < 0 && r < 0) || (a < 0 && b > 0 && r >= 0) {
+			return nil, errorf(pos, "integer overflow")
+		}
+		return r, nil
+	case "*":
+		if a == 0 || b == 0 {
+			return int64(0), nil
+		}
+		r := a * b
+		if r/b != a || (a == -1 && b == minInt) || (b == -1 && a == minInt) {
+			return nil, errorf(pos, "integer overflow")
+		}
+		return r, nil
+	case "//", "%":
+		if b == 0 {
+			return nil, errorf(pos, "integer division by zero")
+		}
+		if a == minInt && b == -1 {
+			return nil, errorf(pos, "integer overflow")
+		}
+		// Деление с округлением вниз, как в Python: -7 // 2 == -4, -7 % 2 == 1.
+		q, m := a/b, a%b
+		if m != 0 && (m < 0) != (b < 0) {
+			q--
+			m += b
+		}
+		if op == "//" {
+			return q, nil
+		}
+		return m, nil
+	}
+	return nil, errorf(pos, "unsupported operand types for %s: int and int", op)
+}
+
+func compare(x, y Value) (int, error) {
+	switch a := x.(type) {
+	case int64:
+		if b, ok := y.(int64); ok {
+			switch {
+			case a < b:
+				return -1, nil
+			case a > b:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if b, ok := y.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", TypeName(x), TypeName(y))
+}
+
+func contains(container, x Value) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := x.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires string as left operand, not %s", TypeName(x))
+		}
+		return strings.Contains(c, s), nil
+	case *List:
+		return containsValue(c.Elems, x), nil
+	case Tuple:
+		return containsValue(c, x), nil
+	case *Dict:
+		_, ok := c.Get(x)
+		return ok, nil
+	}
+	return false, fmt.Errorf("'in' is not supported for %s", TypeName(container))
+}
+
+func containsValue(elems []Value, x Value) bool {
+	for _, e := range elems {
+		if equal(e, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// index проверяет индекс последовательности длины n; отрицательный считается с конца.
+func index(i Value, n int) (int, error) {
+	k, ok := i.(int64)
+	if !ok {
+		return 0, fmt.Errorf("index must be int, not %s", TypeName(i))
+	}
+	if k < 0 {
+		k += int64(n)
+	}
+	if k < 0 || k >= int64(n) {
+		return 0, fmt.Errorf("index %d out of range (length %d)", i, n)
+	}
+	return int(k), nil
+}
+
+func getIndex(x, i Value) (Value, error) {
+	switch x := x.(type) {
+	case *List:
+		n, err := index(i, len(x.Elems))
+		if err != nil {
+			return nil, err
+		}
+		return x.Elems[n], nil
+	case Tuple:
+		n, err := index(i, len(x))
+		if err != nil {
+			return nil, err
+		}
+		return x[n], nil
+	case string:
+		// Строки индексируются по символам, а не по байтам: задачи бывают на любом языке.
+		runes := []rune(x)
+		n, err := index(i, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[n]), nil
+	case *Dict:
+		v, ok := x.Get(i)
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", String(i))
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is not indexable", TypeName(x))
+}
+
+// sliceBounds приводит границы среза к [0, n], как в Python.
+func sliceBounds(lo, hi Value, n int) (int, int, error) {
+	bound := func(v Value, def int) (int, error) {
+		if v == nil {
+			return def, nil
+		}
+		k, ok := v.(int64)
+		if !ok {
+			return 0, fmt.Errorf("slice index must be int, not %s", TypeName(v))
+		}
+		if k < 0 {
+			k += int64(n)
+		}
+		return int(min(max(k, 0), int64(n))), nil
+	}
+	a, err := bound(lo, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := bound(hi, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, max(a, b), nil
+}
+
+func (th *thread) slice(e *sliceExpr) (Value, error) {
+	x, err := th.eval(e.x)
+	if err != nil {
+		return nil, err
+	}
+	var lo, hi Value
+	if e.lo != nil {
+		if lo, err = th.eval(e.lo); err != nil {
+			return nil, err
+		}
+	}
+	if e.hi != nil {
+		if hi, err = th.eval(e.hi); err != nil {
+			return nil, err
+		}
+	}
+	switch x := x.(type) {
+	case string:
+		runes := []rune(x)
+		a, b, err := sliceBounds(lo, hi, len(runes))
+		if err != nil {
+			return nil, at(e.p, err)
+		}
+		s := string(runes[a:b])
+		return s, at(e.p, th.alloc(len(s)))
+	case *List:
+		a, b, err := sliceBounds(lo, hi, len(x.Elems))
+		if err != nil {
+			return nil, at(e.p, err)
+		}
+		if err := th.alloc(sizeContainer + sizeElem*(b-a)); err != nil {
+			return nil, at(e.p, err)
+		}
+		return &List{Elems: append([]Value(nil), x.Elems[a:b]...)}, nil
+	case Tuple:
+		a, b, err := sliceBounds(lo, hi, len(x))
+		if err != nil {
+			return nil, at(e.p, err)
+		}
+		return append(Tuple(nil), x[a:b]...), nil
+	}
+	return nil, errorf(e.p, "%s cannot be sliced", TypeName(x))
+}
+
+// extend дописывает в список элементы последовательности.
+func (th *thread) extend(l *List, v Value) error {
+	elems, err := iterate(v)
+	if err != nil {
+		return err
+	}
+	if err := th.alloc(sizeElem * len(elems)); err != nil {
+		return err
+	}
+	l.Elems = append(l.Elems, elems...)
+	return nil
+}
+
+// runeIndex переводит байтовое смещение в s в номер символа.
+func runeIndex(s string, byteIdx int) int64 {
+	if byteIdx < 0 {
+		return -1
+	}
+	return int64(utf8.RuneCountInString(s[:byteIdx]))
+}
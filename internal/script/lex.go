@@ -0,0 +1,226 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pos -- позиция в исходном тексте (строки и столбцы с 1).
+type Pos struct {
+	Line, Col int
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIndent
+	tokDedent
+	tokName
+	tokInt
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // имя или оператор
+	ival int64
+	sval string
+	pos  Pos
+}
+
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "if": true, "elif": true, "else": true,
+	"for": true, "break": true, "continue": true, "pass": true, "True": true, "False": true, "None": true,
+	// Не поддерживаются, но зарезервированы, чтобы дать понятную ошибку.
+	"def": true, "return": true, "while": true, "lambda": true, "load": true, "class": true,
+	"import": true, "from": true, "try": true, "with": true, "del": true, "global": true, "yield": true,
+}
+
+// Операторы, от длинных к коротким.
+var operators = []string{
+	"//=", "==", "!=", "<=", ">=", "//", "+=", "-=", "*=", "%=", "**",
+	"+", "-", "*", "/", "%", "<", ">", "=", "(", ")", "[", "]", "{", "}", ",", ":", ".", ";",
+}
+
+// lex разбивает исходный текст на токены, превращая отступы в INDENT/DEDENT, как в Python.
+func lex(src string) ([]token, error) {
+	var toks []token
+	indents := []int{0}
+	depth := 0 // вложенность скобок: внутри них переводы строк и отступы не значимы
+	line, lineStart := 1, 0
+	atLineStart := true
+
+	errAt := func(i int, format string, args ...any) error {
+		return &Error{Pos: Pos{line, i - lineStart + 1}, Msg: fmt.Sprintf(format, args...)}
+	}
+	emit := func(kind tokenKind, text string, i int) {
+		toks = append(toks, token{kind: kind, text: text, pos: Pos{line, i - lineStart + 1}})
+	}
+
+	i := 0
+	for i < len(src) {
+		if atLineStart && depth == 0 {
+			col, j := 0, i
+			for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+				if src[j] == '\t' {
+					col = col/8*8 + 8
+				} else {
+					col++
+				}
+				j++
+			}
+			if j >= len(src) {
+				break
+			}
+			if c := src[j]; c == '\n' || c == '\r' || c == '#' {
+				// Пустая строка или комментарий: отступ не считается.
+				for j < len(src) && src[j] != '\n' {
+					j++
+				}
+				if j < len(src) {
+					j++
+				}
+				i, line, lineStart = j, line+1, j
+				continue
+			}
+			i, atLineStart = j, false
+			switch top := indents[len(indents)-1]; {
+			case col > top:
+				indents = append(indents, col)
+				emit(tokIndent, "", i)
+			case col < top:
+				for col < indents[len(indents)-1] {
+					indents = indents[:len(indents)-1]
+					emit(tokDedent, "", i)
+				}
+				if col != indents[len(indents)-1] {
+					return nil, errAt(i, "unindent does not match any outer indentation level")
+				}
+			}
+		}
+
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			line, lineStart = line+1, i
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\n':
+			if depth == 0 {
+				emit(tokNewline, "", i)
+				atLineStart = true
+			}
+			i++
+			line, lineStart = line+1, i
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] < utf8.RuneSelf && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])))) {
+				j++
+			}
+			emit(tokName, src[i:j], i)
+			i = j
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '_') {
+				j++
+			}
+			if j < len(src) && (src[j] == '.' || src[j] == 'e' || src[j] == 'E' || src[j] == 'x' || src[j] == 'X') {
+				return nil, errAt(i, "only decimal integers are supported")
+			}
+			n, err := strconv.ParseInt(strings.ReplaceAll(src[i:j], "_", ""), 10, 64)
+			if err != nil {
+				return nil, errAt(i, "invalid integer %s", src[i:j])
+			}
+			emit(tokInt, src[i:j], i)
+			toks[len(toks)-1].ival = n
+			i = j
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, errAt(i, "%v", err)
+			}
+			emit(tokString, src[i:i+n], i)
+			toks[len(toks)-1].sval = s
+			i += n
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, errAt(i, "unexpected character %q", r)
+			}
+			switch op {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+			emit(tokOp, op, i)
+			i += len(op)
+		}
+	}
+
+	if len(toks) > 0 && toks[len(toks)-1].kind != tokNewline {
+		emit(tokNewline, "", i)
+	}
+	for len(indents) > 1 {
+		indents = indents[:len(indents)-1]
+		emit(tokDedent, "", i)
+	}
+	emit(tokEOF, "", i)
+	return toks, nil
+}
+
+// lexString разбирает строковый литерал в начале s (в одинарных или двойных кавычках, в одну строку)
+// и возвращает значение и длину литерала.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, fmt.Errorf("unterminated string literal")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '\'', '"':
+				b.WriteByte(s[i])
+			case '\n':
+				// Перенос строки внутри литерала.
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
@@ -0,0 +1,529 @@
+package script
+
+import "fmt"
+
+// Узлы синтаксического дерева.
+
+type node struct{ p Pos }
+
+func (n node) pos() Pos { return n.p }
+
+type stmt interface{ pos() Pos }
+
+type expr interface{ pos() Pos }
+
+type (
+	exprStmt struct {
+		node
+		x expr
+	}
+	// assignStmt -- x = v или x op= v (op -- бинарный оператор: "+", "-", ...).
+	assignStmt struct {
+		node
+		op       string
+		lhs, rhs expr
+	}
+	ifStmt struct {
+		node
+		cond      expr
+		then, els []stmt
+	}
+	forStmt struct {
+		node
+		vars []string // несколько имён -- распаковка элемента: for k, v in d.items()
+		iter expr
+		body []stmt
+	}
+	// branchStmt -- break, continue или pass.
+	branchStmt struct {
+		node
+		kind string
+	}
+)
+
+type (
+	identExpr struct {
+		node
+		name string
+	}
+	literalExpr struct {
+		node
+		v Value
+	}
+	listExpr struct {
+		node
+		elems []expr
+	}
+	tupleExpr struct {
+		node
+		elems []expr
+	}
+	dictExpr struct {
+		node
+		keys, vals []expr
+	}
+	unaryExpr struct {
+		node
+		op string
+		x  expr
+	}
+	binaryExpr struct {
+		node
+		op   string
+		x, y expr
+	}
+	condExpr struct {
+		node
+		cond, then, els expr
+	}
+	callExpr struct {
+		node
+		fn   expr
+		args []expr
+	}
+	indexExpr struct {
+		node
+		x, i expr
+	}
+	sliceExpr struct {
+		node
+		x, lo, hi expr // lo и hi могут быть nil
+	}
+	attrExpr struct {
+		node
+		x    expr
+		name string
+	}
+)
+
+// Операторы присваивания и соответствующие им бинарные операторы.
+var assignOps = map[string]string{"=": "", "+=": "+", "-=": "-", "*=": "*", "//=": "//", "%=": "%"}
+
+type parser struct {
+	toks  []token
+	i     int
+	loops int // глубина вложенности циклов: break и continue вне цикла -- ошибка
+}
+
+// parseError -- ошибка разбора; парсер бросает её паникой, parse ловит.
+type parseError struct{ err *Error }
+
+func parse(src string) (stmts []stmt, err error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			stmts, err = nil, pe.err
+		}
+	}()
+	for p.peek().kind != tokEOF {
+		stmts = append(stmts, p.stmt()...)
+	}
+	return stmts, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// is -- следующий токен -- оператор или ключевое слово s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.kind == tokOp || t.kind == tokName && keywords[t.text]) && t.text == s
+}
+
+func (p *parser) expect(s string) token {
+	if !p.is(s) {
+		p.fail(p.peek().pos, "expected %q, got %s", s, describe(p.peek()))
+	}
+	return p.next()
+}
+
+func (p *parser) expectKind(kind tokenKind, what string) token {
+	if p.peek().kind != kind {
+		p.fail(p.peek().pos, "expected %s, got %s", what, describe(p.peek()))
+	}
+	return p.next()
+}
+
+func (p *parser) fail(pos Pos, format string, args ...any) {
+	panic(parseError{&Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}})
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of script"
+	case tokNewline:
+		return "end of line"
+	case tokIndent:
+		return "unexpected indent"
+	case tokDedent:
+		return "unindent"
+	case tokString:
+		return "string " + t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// stmt разбирает одну строку: составной оператор или простые операторы через ';'.
+func (p *parser) stmt() []stmt {
+	t := p.peek()
+	switch {
+	case t.kind == tokIndent:
+		p.fail(t.pos, "unexpected indent")
+	case p.is("if"):
+		return []stmt{p.ifStmt()}
+	case p.is("for"):
+		return []stmt{p.forStmt()}
+	case t.kind == tokName && keywords[t.text]:
+		switch t.text {
+		case "def", "return", "while", "lambda", "load", "class", "import", "from", "try", "with", "del", "global", "yield":
+			p.fail(t.pos, "%q is not supported in scripts", t.text)
+		}
+	}
+	var out []stmt
+	for {
+		out = append(out, p.simpleStmt())
+		if !p.is(";") {
+			break
+		}
+		p.next()
+		if p.peek().kind == tokNewline {
+			break
+		}
+	}
+	p.expectKind(tokNewline, "end of line")
+	return out
+}
+
+func (p *parser) simpleStmt() stmt {
+	t := p.peek()
+	if t.kind == tokName {
+		switch t.text {
+		case "pass":
+			p.next()
+			return &branchStmt{node{t.pos}, "pass"}
+		case "break", "continue":
+			if p.loops == 0 {
+				p.fail(t.pos, "%q outside of a loop", t.text)
+			}
+			p.next()
+			return &branchStmt{node{t.pos}, t.text}
+		}
+	}
+	x := p.test()
+	if p.peek().kind == tokOp {
+		if op, ok := assignOps[p.peek().text]; ok {
+			opTok := p.next()
+			switch x.(type) {
+			case *identExpr, *indexExpr, *attrExpr:
+			default:
+				p.fail(opTok.pos, "cannot assign to this expression")
+			}
+			return &assignStmt{node{opTok.pos}, op, x, p.test()}
+		}
+	}
+	return &exprStmt{node{t.pos}, x}
+}
+
+// suite -- тело if/for: простые операторы на той же строке или блок с отступом.
+func (p *parser) suite() []stmt {
+	p.expect(":")
+	if p.peek().kind != tokNewline {
+		return p.stmt()
+	}
+	p.next()
+	p.expectKind(tokIndent, "indented block")
+	var body []stmt
+	for p.peek().kind != tokDedent && p.peek().kind != tokEOF {
+		body = append(body, p.stmt()...)
+	}
+	p.next()
+	return body
+}
+
+func (p *parser) ifStmt() stmt {
+	t := p.next() // if или elif
+	s := &ifStmt{node: node{t.pos}, cond: p.test()}
+	s.then = p.suite()
+	switch {
+	case p.is("elif"):
+		s.els = []stmt{p.ifStmt()}
+	case p.is("else"):
+		p.next()
+		s.els = p.suite()
+	}
+	return s
+}
+
+func (p *parser) forStmt() stmt {
+	t := p.next()
+	s := &forStmt{node: node{t.pos}}
+	for {
+		s.vars = append(s.vars, p.name())
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	p.expect("in")
+	s.iter = p.test()
+	p.loops++
+	s.body = p.suite()
+	p.loops--
+	return s
+}
+
+// name -- идентификатор, не ключевое слово.
+func (p *parser) name() string {
+	t := p.peek()
+	if t.kind != tokName || keywords[t.text] {
+		p.fail(t.pos, "expected a name, got %s", describe(t))
+	}
+	p.next()
+	return t.text
+}
+
+// test -- выражение целиком, включая условное "a if cond else b".
+func (p *parser) test() expr {
+	x := p.orTest()
+	if p.is("if") {
+		t := p.next()
+		cond := p.orTest()
+		p.expect("else")
+		return &condExpr{node{t.pos}, cond, x, p.test()}
+	}
+	return x
+}
+
+func (p *parser) orTest() expr {
+	x := p.andTest()
+	for p.is("or") {
+		t := p.next()
+		x = &binaryExpr{node{t.pos}, "or", x, p.andTest()}
+	}
+	return x
+}
+
+func (p *parser) andTest() expr {
+	x := p.notTest()
+	for p.is("and") {
+		t := p.next()
+		x = &binaryExpr{node{t.pos}, "and", x, p.notTest()}
+	}
+	return x
+}
+
+func (p *parser) notTest() expr {
+	if p.is("not") {
+		t := p.next()
+		return &unaryExpr{node{t.pos}, "not", p.notTest()}
+	}
+	return p.comparison()
+}
+
+func (p *parser) compOp() string {
+	t := p.peek()
+	switch {
+	case t.kind == tokOp:
+		switch t.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return t.text
+		}
+	case p.is("in"):
+		return "in"
+	case p.is("not") && p.toks[p.i+1].kind == tokName && p.toks[p.i+1].text == "in":
+		return "not in"
+	}
+	return ""
+}
+
+func (p *parser) comparison() expr {
+	x := p.arith()
+	op := p.compOp()
+	if op == "" {
+		return x
+	}
+	t := p.next()
+	if op == "not in" {
+		p.next()
+	}
+	x = &binaryExpr{node{t.pos}, op, x, p.arith()}
+	if p.compOp() != "" {
+		p.fail(p.peek().pos, "chained comparisons are not supported, use 'and'")
+	}
+	return x
+}
+
+func (p *parser) arith() expr {
+	x := p.term()
+	for p.is("+") || p.is("-") {
+		t := p.next()
+		x = &binaryExpr{node{t.pos}, t.text, x, p.term()}
+	}
+	return x
+}
+
+func (p *parser) term() expr {
+	x := p.unary()
+	for p.is("*") || p.is("/") || p.is("//") || p.is("%") {
+		t := p.next()
+		x = &binaryExpr{node{t.pos}, t.text, x, p.unary()}
+	}
+	return x
+}
+
+func (p *parser) unary() expr {
+	if p.is("-") || p.is("+") {
+		t := p.next()
+		return &unaryExpr{node{t.pos}, t.text, p.unary()}
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() expr {
+	x := p.operand()
+	for {
+		switch {
+		case p.is("."):
+			t := p.next()
+			x = &attrExpr{node{t.pos}, x, p.name()}
+		case p.is("["):
+			t := p.next()
+			var lo expr
+			if !p.is(":") {
+				lo = p.test()
+			}
+			if p.is(":") {
+				p.next()
+				var hi expr
+				if !p.is("]") {
+					hi = p.test()
+				}
+				if p.is(":") {
+					p.fail(p.peek().pos, "slice steps are not supported")
+				}
+				p.expect("]")
+				x = &sliceExpr{node{t.pos}, x, lo, hi}
+				continue
+			}
+			p.expect("]")
+			x = &indexExpr{node{t.pos}, x, lo}
+		case p.is("("):
+			t := p.next()
+			call := &callExpr{node: node{t.pos}, fn: x}
+			for !p.is(")") {
+				if p.peek().kind == tokName && p.toks[p.i+1].kind == tokOp && p.toks[p.i+1].text == "=" {
+					p.fail(p.peek().pos, "keyword arguments are not supported")
+				}
+				call.args = append(call.args, p.test())
+				if !p.is(",") {
+					break
+				}
+				p.next()
+			}
+			p.expect(")")
+			x = call
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) operand() expr {
+	t := p.peek()
+	switch t.kind {
+	case tokInt:
+		p.next()
+		return &literalExpr{node{t.pos}, t.ival}
+	case tokString:
+		p.next()
+		s := t.sval
+		for p.peek().kind == tokString { // "a" "b" -- склейка соседних литералов
+			s += p.next().sval
+		}
+		return &literalExpr{node{t.pos}, s}
+	case tokName:
+		switch t.text {
+		case "True":
+			p.next()
+			return &literalExpr{node{t.pos}, true}
+		case "False":
+			p.next()
+			return &literalExpr{node{t.pos}, false}
+		case "None":
+			p.next()
+			return &literalExpr{node{t.pos}, nil}
+		}
+		return &identExpr{node{t.pos}, p.name()}
+	case tokOp:
+		switch t.text {
+		case "(":
+			p.next()
+			if p.is(")") {
+				p.next()
+				return &tupleExpr{node: node{t.pos}}
+			}
+			x := p.test()
+			if !p.is(",") {
+				p.expect(")")
+				return x
+			}
+			tuple := &tupleExpr{node{t.pos}, []expr{x}}
+			for p.is(",") {
+				p.next()
+				if p.is(")") {
+					break
+				}
+				tuple.elems = append(tuple.elems, p.test())
+			}
+			p.expect(")")
+			return tuple
+		case "[":
+			p.next()
+			list := &listExpr{node: node{t.pos}}
+			for !p.is("]") {
+				list.elems = append(list.elems, p.test())
+				if p.is("for") {
+					p.fail(p.peek().pos, "comprehensions are not supported, use a for loop")
+				}
+				if !p.is(",") {
+					break
+				}
+				p.next()
+			}
+			p.expect("]")
+			return list
+		case "{":
+			p.next()
+			dict := &dictExpr{node: node{t.pos}}
+			for !p.is("}") {
+				dict.keys = append(dict.keys, p.test())
+				p.expect(":")
+				dict.vals = append(dict.vals, p.test())
+				if !p.is(",") {
+					break
+				}
+				p.next()
+			}
+			p.expect("}")
+			return dict
+		}
+	}
+	p.fail(t.pos, "unexpected %s", describe(t))
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package script -- маленький встроенный язык для пользовательских скриптов: подмножество Starlark
+// (диалекта Python). Скрипт не может ничего, кроме вычислений над переданными ему значениями:
+// нет ввода-вывода, импорта, функций и циклов while, а время, число шагов и память ограничены.
+//
+//	if "срочно" in task.title.lower():
+//	    task.priority = "high"
+//	    task.tags.append("urgent")
+//
+// Поддерживается: int, str, bool, None, списки, кортежи, словари; if/elif/else, for с break и
+// continue, условное выражение, and/or/not, in, срезы, методы строк, списков и словарей и
+// встроенные функции len, str, int, bool, range, sorted, min, max, any, all, type, print, fail.
+// Не поддерживается: float, def, lambda, while, генераторы списков, именованные аргументы.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Ограничения по умолчанию.
+const (
+	DefaultMaxSteps  = 100_000
+	DefaultMaxMemory = 1 << 20
+	DefaultTimeout   = 100 * time.Millisecond
+)
+
+var (
+	// ErrTimeout -- скрипт выполнялся дольше Limits.Timeout.
+	ErrTimeout = errors.New("time limit exceeded")
+	// ErrStepLimit -- скрипт выполнил больше Limits.MaxSteps шагов.
+	ErrStepLimit = errors.New("step limit exceeded")
+	// ErrMemoryLimit -- скрипт создал больше Limits.MaxMemory байт строк и контейнеров.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+	// ErrInternal -- скрипт вызвал панику интерпретатора или функции хоста.
+	ErrInternal = errors.New("internal error")
+)
+
+// Limits -- ограничения одного запуска. Нулевые поля -- значения по умолчанию.
+type Limits struct {
+	MaxSteps  int           // шаги: операторы, вычисления выражений, итерации циклов
+	MaxMemory int           // байты, выделенные под строки и контейнеры (оценка сверху, освобождение не учитывается)
+	Timeout   time.Duration // время выполнения
+}
+
+// Error -- ошибка разбора или выполнения с позицией в скрипте.
+type Error struct {
+	Script string
+	Pos    Pos
+	Msg    string
+	Err    error // причина: ошибка ограничения или функции хоста
+}
+
+func (e *Error) Error() string {
+	if e.Script == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Col, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Script, e.Pos.Line, e.Pos.Col, e.Msg)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Program -- разобранный скрипт. Разбирается один раз и может запускаться многократно,
+// в том числе одновременно.
+type Program struct {
+	name string
+	body []stmt
+}
+
+// Compile разбирает скрипт. name -- имя для сообщений об ошибках.
+func Compile(name, src string) (*Program, error) {
+	body, err := parse(src)
+	if err != nil {
+		var se *Error
+		if errors.As(err, &se) {
+			se.Script = name
+		}
+		return nil, err
+	}
+	return &Program{name: name, body: body}, nil
+}
+
+// Options -- параметры запуска.
+type Options struct {
+	Limits
+	// Print получает строки print() скрипта; nil -- вывод отбрасывается.
+	Print func(msg string)
+}
+
+// Run выполняет скрипт. globals -- значения, доступные скрипту по имени (например, task);
+// присваивания самого скрипта в globals не попадают, изменения объектов и списков -- попадают.
+//
+// Паника внутри интерпретатора или функции хоста возвращается как *Error с ErrInternal:
+// скрипты запускаются и из фоновых расписаний и правил, где её некому перехватить.
+func (p *Program) Run(ctx context.Context, globals map[string]Value, opts Options) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &Error{Script: p.name, Msg: fmt.Sprintf("%v: %v", ErrInternal, r), Err: ErrInternal}
+		}
+	}()
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = DefaultMaxSteps
+	}
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = DefaultMaxMemory
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	th := &thread{
+		ctx:      ctx,
+		deadline: time.Now().Add(opts.Timeout),
+		maxSteps: opts.MaxSteps,
+		maxMem:   opts.MaxMemory,
+		globals:  make(map[string]Value, len(globals)),
+		print:    opts.Print,
+	}
+	for k, v := range globals {
+		th.globals[k] = v
+	}
+	_, err = th.execBlock(p.body)
+	var se *Error
+	if errors.As(err, &se) {
+		se.Script = p.name
+	}
+	return err
+}
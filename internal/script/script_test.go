@@ -0,0 +1,146 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func run(t *testing.T, src string, globals map[string]Value, limits Limits) error {
+	t.Helper()
+	prog, err := Compile("test.star", src)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return prog.Run(context.Background(), globals, Options{Limits: limits})
+}
+
+// minInt64 в скрипте: литерал 9223372036854775808 не помещается в int.
+const minInt64 = "(-9223372036854775807-1)"
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		limits Limits
+		want   error
+	}{
+		{"steps", "r = range(10000)\nfor i in r:\n    pass\n", Limits{MaxSteps: 1000}, ErrStepLimit},
+		{"memory", "s = ''\nfor i in range(100):\n    s = s + 'x' * 1000\n", Limits{MaxMemory: 10_000}, ErrMemoryLimit},
+		{"timeout", "r = range(10000)\nfor i in r:\n    for j in r:\n        pass\n",
+			Limits{MaxSteps: 1 << 40, Timeout: 20 * time.Millisecond}, ErrTimeout},
+		{"defaults", "r = range(1000)\nfor i in r:\n    for j in r:\n        pass\n", Limits{}, ErrStepLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(t, tt.src, nil, tt.limits)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var se *Error
+			if !errors.As(err, &se) || se.Script != "test.star" || se.Pos.Line == 0 {
+				t.Errorf("err = %#v, want *Error with script name and position", err)
+			}
+		})
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	prog, err := Compile("test.star", "r = range(10000)\nfor i in r:\n    for j in r:\n        pass\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = prog.Run(ctx, nil, Options{Limits: Limits{MaxSteps: 1 << 40, Timeout: time.Minute}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want error  // nil -- сравнивается текст ошибки
+		msg  string // подстрока сообщения
+	}{
+		{"range full int64", "x = len(range(" + minInt64 + ", 9223372036854775807))", ErrMemoryLimit, ""},
+		{"range negative full int64", "x = range(9223372036854775807, " + minInt64 + ", -1)", ErrMemoryLimit, ""},
+		{"range huge step", "x = range(" + minInt64 + ", 9223372036854775807, 9223372036854775807)\n" +
+			"if x != [" + minInt64 + ", -1, 9223372036854775806]:\n    fail(str(x))\n", nil, ""},
+		{"range min step", "x = range(5, " + minInt64 + ", " + minInt64 + ")\n" +
+			"if x != [5, " + minInt64 + " + 5]:\n    fail(str(x))\n", nil, ""},
+		{"add", "x = 9223372036854775807 + 1", nil, "integer overflow"},
+		{"sub", "x = " + minInt64 + " - 1", nil, "integer overflow"},
+		{"mul", "x = 4611686018427387904 * 2", nil, "integer overflow"},
+		{"mul min", "x = " + minInt64 + " * -1", nil, "integer overflow"},
+		{"floordiv min", "x = " + minInt64 + " // -1", nil, "integer overflow"},
+		{"div zero", "x = 1 // 0", nil, "division by zero"},
+		{"string repeat", "x = 'ab' * 9223372036854775807", ErrMemoryLimit, ""},
+		{"list repeat", "x = [1, 2] * 4611686018427387904", ErrMemoryLimit, ""},
+		{"index min", "x = [1, 2][" + minInt64 + "]", nil, "out of range"},
+		{"slice min", "x = [1, 2][" + minInt64 + ":9223372036854775807]\nif x != [1, 2]:\n    fail(str(x))\n", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(t, tt.src, nil, Limits{})
+			switch {
+			case tt.want != nil:
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+			case tt.msg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.msg) {
+					t.Fatalf("err = %v, want %q", err, tt.msg)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := map[string]string{
+		"range(4)":         "[0, 1, 2, 3]",
+		"range(2, 5)":      "[2, 3, 4]",
+		"range(0, 10, 3)":  "[0, 3, 6, 9]",
+		"range(10, 0, -3)": "[10, 7, 4, 1]",
+		"range(5, 5)":      "[]",
+		"range(5, 0)":      "[]",
+		"range(0, 5, -1)":  "[]",
+	}
+	for expr, want := range tests {
+		var got string
+		print := func(msg string) { got = msg }
+		prog, err := Compile("test.star", "print(str("+expr+"))")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := prog.Run(context.Background(), nil, Options{Print: print}); err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if got != want {
+			t.Errorf("%s = %s, want %s", expr, got, want)
+		}
+	}
+}
+
+func TestPanicRecovered(t *testing.T) {
+	globals := map[string]Value{
+		"boom": NewBuiltin("boom", func(args []Value) (Value, error) { panic("kaboom") }),
+	}
+	err := run(t, "x = 1\nboom()\n", globals, Limits{})
+	if !errors.Is(err, ErrInternal) {
+		t.Fatalf("err = %v, want ErrInternal", err)
+	}
+	var se *Error
+	if !errors.As(err, &se) || se.Script != "test.star" || !strings.Contains(se.Msg, "kaboom") {
+		t.Errorf("err = %#v, want *Error with the panic message", err)
+	}
+}
@@ -0,0 +1,344 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Value -- значение скрипта. Представлено обычными Go-значениями:
+//
+//	None -> nil, bool -> bool, int -> int64, str -> string,
+//	list -> *List, tuple -> Tuple, dict -> *Dict, объект хоста -> *Object, функция -> *Builtin.
+type Value = any
+
+// List -- изменяемый список.
+type List struct {
+	Elems []Value
+}
+
+// NewList создаёт список из элементов.
+func NewList(elems ...Value) *List {
+	return &List{Elems: elems}
+}
+
+// Tuple -- неизменяемая последовательность (в скриптах -- результат dict.items() и литералы (a, b)).
+type Tuple []Value
+
+// Dict -- словарь с порядком вставки. Ключи -- None, bool, int или str.
+type Dict struct {
+	keys  []Value
+	vals  []Value
+	index map[Value]int
+}
+
+// NewDict создаёт пустой словарь.
+func NewDict() *Dict {
+	return &Dict{index: make(map[Value]int)}
+}
+
+// Len -- число пар.
+func (d *Dict) Len() int { return len(d.keys) }
+
+// Keys -- ключи в порядке вставки.
+func (d *Dict) Keys() []Value { return append([]Value(nil), d.keys...) }
+
+// Get возвращает значение по ключу.
+func (d *Dict) Get(k Value) (Value, bool) {
+	if !hashable(k) {
+		return nil, false
+	}
+	i, ok := d.index[k]
+	if !ok {
+		return nil, false
+	}
+	return d.vals[i], true
+}
+
+// Set добавляет или заменяет пару.
+func (d *Dict) Set(k, v Value) error {
+	if !hashable(k) {
+		return fmt.Errorf("unhashable dict key: %s", TypeName(k))
+	}
+	if i, ok := d.index[k]; ok {
+		d.vals[i] = v
+		return nil
+	}
+	d.index[k] = len(d.keys)
+	d.keys = append(d.keys, k)
+	d.vals = append(d.vals, v)
+	return nil
+}
+
+// delete удаляет пару, сохраняя порядок остальных.
+func (d *Dict) delete(k Value) (Value, bool) {
+	if !hashable(k) {
+		return nil, false
+	}
+	i, ok := d.index[k]
+	if !ok {
+		return nil, false
+	}
+	v := d.vals[i]
+	d.keys = append(d.keys[:i], d.keys[i+1:]...)
+	d.vals = append(d.vals[:i], d.vals[i+1:]...)
+	delete(d.index, k)
+	for j := i; j < len(d.keys); j++ {
+		d.index[d.keys[j]] = j
+	}
+	return v, true
+}
+
+func hashable(v Value) bool {
+	switch v.(type) {
+	case nil, bool, int64, string:
+		return true
+	}
+	return false
+}
+
+// Object -- объект хоста с именованными атрибутами (например, задача). Скрипт читает атрибуты
+// через точку; присваивать может только тем, что заданы через SetWritable, и только значения,
+// прошедшие проверку.
+type Object struct {
+	Type  string
+	attrs map[string]Value
+	check map[string]func(Value) error
+}
+
+// NewObject создаёт объект типа typ (имя типа видно в сообщениях об ошибках).
+func NewObject(typ string) *Object {
+	return &Object{Type: typ, attrs: make(map[string]Value), check: make(map[string]func(Value) error)}
+}
+
+// Set задаёт атрибут только для чтения.
+func (o *Object) Set(name string, v Value) {
+	o.attrs[name] = v
+	delete(o.check, name)
+}
+
+// SetWritable задаёт атрибут, который скрипт может менять. check проверяет новое значение (nil -- любое).
+func (o *Object) SetWritable(name string, v Value, check func(Value) error) {
+	o.Set(name, v)
+	if check == nil {
+		check = func(Value) error { return nil }
+	}
+	o.check[name] = check
+}
+
+// Get возвращает атрибут (nil, если его нет).
+func (o *Object) Get(name string) Value {
+	return o.attrs[name]
+}
+
+func (o *Object) setAttr(name string, v Value) error {
+	if _, ok := o.attrs[name]; !ok {
+		return fmt.Errorf("%s has no attribute %q", o.Type, name)
+	}
+	check, ok := o.check[name]
+	if !ok {
+		return fmt.Errorf("%s.%s is read-only", o.Type, name)
+	}
+	if err := check(v); err != nil {
+		return fmt.Errorf("%s.%s: %w", o.Type, name, err)
+	}
+	o.attrs[name] = v
+	return nil
+}
+
+// Builtin -- функция, доступная скрипту.
+type Builtin struct {
+	Name string
+	fn   func(th *thread, args []Value) (Value, error)
+}
+
+// NewBuiltin оборачивает функцию хоста. Ошибка fn прерывает скрипт; её можно достать
+// из ошибки Run через errors.As.
+func NewBuiltin(name string, fn func(args []Value) (Value, error)) *Builtin {
+	return &Builtin{Name: name, fn: func(_ *thread, args []Value) (Value, error) { return fn(args) }}
+}
+
+// method -- метод строки, списка или словаря, привязанный к значению.
+type method struct {
+	recv Value
+	name string
+}
+
+// TypeName -- имя типа значения, как в Starlark.
+func TypeName(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case *List:
+		return "list"
+	case Tuple:
+		return "tuple"
+	case *Dict:
+		return "dict"
+	case *Object:
+		return v.Type
+	case *Builtin, *method:
+		return "builtin_function_or_method"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Truth -- истинность значения: None, False, 0, пустые строка и контейнеры -- ложь.
+func Truth(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case string:
+		return v != ""
+	case *List:
+		return len(v.Elems) > 0
+	case Tuple:
+		return len(v) > 0
+	case *Dict:
+		return v.Len() > 0
+	}
+	return true
+}
+
+// String -- значение как строка: строки как есть, остальное -- как в исходном коде.
+func String(v Value) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var b strings.Builder
+	writeRepr(&b, v, 0)
+	return b.String()
+}
+
+// maxRepr -- предел длины строкового представления: список может содержать сам себя.
+const maxRepr = 64 << 10
+
+func writeRepr(b *strings.Builder, v Value, depth int) {
+	if depth > 20 || b.Len() > maxRepr {
+		b.WriteString("...")
+		return
+	}
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if v {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case *List:
+		b.WriteByte('[')
+		for i, e := range v.Elems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, e, depth+1)
+		}
+		b.WriteByte(']')
+	case Tuple:
+		b.WriteByte('(')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, e, depth+1)
+		}
+		if len(v) == 1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(')')
+	case *Dict:
+		b.WriteByte('{')
+		for i, k := range v.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, k, depth+1)
+			b.WriteString(": ")
+			writeRepr(b, v.vals[i], depth+1)
+		}
+		b.WriteByte('}')
+	case *Object:
+		b.WriteString("<" + v.Type + ">")
+	case *Builtin:
+		b.WriteString("<built-in function " + v.Name + ">")
+	case *method:
+		b.WriteString("<built-in method " + v.name + " of " + TypeName(v.recv) + ">")
+	default:
+		fmt.Fprintf(b, "%v", v)
+	}
+}
+
+// equal -- сравнение на ==: контейнеры сравниваются по содержимому, объекты -- по ссылке.
+func equal(x, y Value) bool {
+	return equalDepth(x, y, 0)
+}
+
+// equalDepth ограничивает глубину: список может содержать сам себя.
+func equalDepth(x, y Value, depth int) bool {
+	if depth > 100 {
+		return false
+	}
+	switch x := x.(type) {
+	case nil:
+		return y == nil
+	case bool:
+		yv, ok := y.(bool)
+		return ok && x == yv
+	case int64:
+		yv, ok := y.(int64)
+		return ok && x == yv
+	case string:
+		yv, ok := y.(string)
+		return ok && x == yv
+	case *List:
+		yv, ok := y.(*List)
+		return ok && (x == yv || equalSeq(x.Elems, yv.Elems, depth))
+	case Tuple:
+		yv, ok := y.(Tuple)
+		return ok && equalSeq(x, yv, depth)
+	case *Dict:
+		yv, ok := y.(*Dict)
+		if !ok || x.Len() != yv.Len() {
+			return false
+		}
+		if x == yv {
+			return true
+		}
+		for i, k := range x.keys {
+			v, ok := yv.Get(k)
+			if !ok || !equalDepth(x.vals[i], v, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	return x == y
+}
+
+func equalSeq(x, y []Value, depth int) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !equalDepth(x[i], y[i], depth+1) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-manager/internal/httpclient"
+)
+
+// AWS -- секреты из AWS Secrets Manager (GetSecretValue). Значение секрета SecretID --
+// JSON-объект {"JWT_SECRET": "...", "ADMIN_KEY": "..."}, как его создаёт консоль AWS
+// в режиме "ключ/значение".
+//
+// Запрос подписывается Signature V4 вручную, чтобы не тянуть AWS SDK ради одного вызова.
+type AWS struct {
+	Region          string
+	SecretID        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // для временных ключей (STS, роли)
+
+	// Endpoint и Client можно подменить (LocalStack, тесты).
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewAWS создаёт провайдер AWS Secrets Manager.
+func NewAWS(region, secretID, accessKeyID, secretAccessKey, sessionToken string) *AWS {
+	return &AWS{
+		Region:          region,
+		SecretID:        secretID,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+		Endpoint:        "https://secretsmanager." + region + ".amazonaws.com",
+		Client:          httpclient.New(httpclient.Options{Name: "aws", Timeout: 10 * time.Second, Retries: 2}),
+	}
+}
+
+// Name возвращает "aws".
+func (a *AWS) Name() string { return "aws" }
+
+// Lookup читает секрет SecretID и берёт из него поле name.
+func (a *AWS) Lookup(ctx context.Context, name string) (string, bool, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": a.SecretID})
+	if err != nil {
+		return "", false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, body, time.Now().UTC())
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if strings.Contains(string(msg), "ResourceNotFoundException") {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("GetSecretValue %s: %s: %s", a.SecretID, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", false, fmt.Errorf("decode %s: %w", a.SecretID, err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return "", false, fmt.Errorf("secret %s must be a JSON object: %w", a.SecretID, err)
+	}
+	return field(fields, name)
+}
+
+// sign добавляет к запросу подпись AWS Signature V4 (сервис secretsmanager).
+func (a *AWS) sign(req *http.Request, body []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.SessionToken)
+	}
+
+	// Подписываем все заголовки X-Amz-*, Content-Type и Host -- в алфавитном порядке.
+	names := []string{"content-type", "host", "x-amz-date"}
+	if a.SessionToken != "" {
+		names = append(names, "x-amz-security-token")
+	}
+	names = append(names, "x-amz-target")
+	var canonHeaders strings.Builder
+	for _, n := range names {
+		v := req.Header.Get(n)
+		if n == "host" {
+			v = req.URL.Host
+		}
+		canonHeaders.WriteString(n + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"", // query
+		canonHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + a.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.SecretAccessKey), date)
+	key = hmacSHA256(key, a.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+a.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,151 @@
+// Package secrets -- откуда сервер берёт секреты: ключ подписи JWT, ключ служебного API,
+// инвайт-код регистрации, пароль БД, ключи интеграций.
+//
+// Провайдеры: переменные окружения (по умолчанию, с поддержкой NAME_FILE), каталог с файлами
+// (Docker/Kubernetes secrets), HashiCorp Vault (KV) и AWS Secrets Manager. Значения оборачиваются
+// в Value, который умеет перечитываться по таймеру: после ротации старое значение ещё какое-то время
+// принимается, чтобы выданные токены и настроенные клиенты не отвалились разом.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Provider -- источник секретов.
+type Provider interface {
+	// Lookup возвращает значение секрета name (например, "JWT_SECRET").
+	// ok == false -- у провайдера такого секрета нет (это не ошибка).
+	Lookup(ctx context.Context, name string) (value string, ok bool, err error)
+	// Name -- имя провайдера для логов.
+	Name() string
+}
+
+// Env -- секреты из переменных окружения. Если NAME не задана, читается файл из NAME_FILE
+// (соглашение Docker-образов: JWT_SECRET_FILE=/run/secrets/jwt).
+type Env struct{}
+
+// Name возвращает "env".
+func (Env) Name() string { return "env" }
+
+// Lookup читает NAME или файл из NAME_FILE.
+func (Env) Lookup(_ context.Context, name string) (string, bool, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, true, nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", false, nil
+	}
+	return readSecretFile(path)
+}
+
+// Dir -- секреты из каталога: по файлу на секрет, имя файла -- имя секрета
+// (как их монтируют Docker swarm и Kubernetes).
+type Dir struct {
+	Path string
+}
+
+// Name возвращает "file".
+func (d Dir) Name() string { return "file" }
+
+// Lookup читает <Path>/<name>. Нет файла -- нет секрета.
+func (d Dir) Lookup(_ context.Context, name string) (string, bool, error) {
+	return readSecretFile(filepath.Join(d.Path, name))
+}
+
+// readSecretFile читает секрет из файла, отрезая завершающий перевод строки (его оставляют редакторы и echo).
+func readSecretFile(path string) (string, bool, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	v := strings.TrimRight(string(raw), "\r\n")
+	return v, v != "", nil
+}
+
+// Chain опрашивает провайдеров по очереди; побеждает первый, у кого секрет есть.
+// Ошибка провайдера прерывает поиск: молча брать значение из следующего опасно.
+type Chain []Provider
+
+// Name перечисляет провайдеров цепочки.
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Lookup ищет секрет по цепочке.
+func (c Chain) Lookup(ctx context.Context, name string) (string, bool, error) {
+	for _, p := range c {
+		v, ok, err := p.Lookup(ctx, name)
+		if err != nil {
+			return "", false, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		if ok {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Config -- выбор провайдера (переменные SECRETS_*, VAULT_*, AWS_*).
+type Config struct {
+	Provider string // env (по умолчанию), file, vault, aws
+
+	Dir string // для file
+
+	VaultAddr  string // для vault: https://vault:8200
+	VaultToken string
+	VaultPath  string // путь KV без /v1/, например secret/data/task-manager
+
+	AWSRegion          string // для aws
+	AWSSecretID        string // имя или ARN секрета; значение -- JSON-объект {"JWT_SECRET": "..."}
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+}
+
+// New собирает провайдера по конфигу. Переменные окружения всегда идут последними в цепочке:
+// то, чего нет во внешнем хранилище, можно по-прежнему задать через окружение.
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "", "env":
+		return Env{}, nil
+	case "file":
+		if cfg.Dir == "" {
+			return nil, errors.New("secrets: SECRETS_DIR is required for file provider")
+		}
+		return Chain{Dir{Path: cfg.Dir}, Env{}}, nil
+	case "vault":
+		if cfg.VaultAddr == "" || cfg.VaultToken == "" || cfg.VaultPath == "" {
+			return nil, errors.New("secrets: VAULT_ADDR, VAULT_TOKEN and VAULT_SECRET_PATH are required for vault provider")
+		}
+		return Chain{NewVault(cfg.VaultAddr, cfg.VaultToken, cfg.VaultPath), Env{}}, nil
+	case "aws":
+		if cfg.AWSRegion == "" || cfg.AWSSecretID == "" || cfg.AWSAccessKeyID == "" || cfg.AWSSecretAccessKey == "" {
+			return nil, errors.New("secrets: AWS_REGION, AWS_SECRET_ID, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for aws provider")
+		}
+		return Chain{NewAWS(cfg.AWSRegion, cfg.AWSSecretID, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken), Env{}}, nil
+	}
+	return nil, fmt.Errorf("secrets: unknown provider %q (want env, file, vault or aws)", cfg.Provider)
+}
+
+// Get -- разовое чтение секрета (для того, что читается только при старте: пароль БД).
+// Отсутствие секрета -- пустая строка без ошибки.
+func Get(ctx context.Context, p Provider, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	v, _, err := p.Lookup(ctx, name)
+	return v, err
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"sync"
+	"time"
+)
+
+// Value -- секрет, который может смениться на лету (ротация во внешнем хранилище).
+//
+// После смены прежнее значение ещё Grace принимается при проверке (Accepts, Candidates):
+// токены, подписанные старым ключом JWT, и клиенты со старым ключом продолжают работать,
+// пока их не обновят. Подписывается всегда текущим значением (Current).
+// Методы безопасны для nil (секрет не задан).
+type Value struct {
+	name     string
+	provider Provider
+	grace    time.Duration
+
+	mu        sync.RWMutex
+	current   string
+	previous  string
+	rotatedAt time.Time
+}
+
+// Load читает секрет name и возвращает Value, который умеет перечитываться.
+// Отсутствие секрета -- не ошибка: Current() вернёт пустую строку.
+func Load(ctx context.Context, p Provider, name string, grace time.Duration) (*Value, error) {
+	v := &Value{name: name, provider: p, grace: grace}
+	cur, err := Get(ctx, p, name)
+	if err != nil {
+		return nil, err
+	}
+	v.current = cur
+	return v, nil
+}
+
+// Static -- секрет с фиксированным значением (сгенерированный при старте, заданный в тестах).
+func Static(name, value string) *Value {
+	return &Value{name: name, current: value}
+}
+
+// Name -- имя секрета.
+func (v *Value) Name() string {
+	if v == nil {
+		return ""
+	}
+	return v.name
+}
+
+// Current -- действующее значение.
+func (v *Value) Current() string {
+	if v == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.current
+}
+
+// Candidates -- значения, которые сейчас принимаются: текущее и, в течение Grace после ротации, прежнее.
+func (v *Value) Candidates(now time.Time) []string {
+	if v == nil {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	out := make([]string, 0, 2)
+	if v.current != "" {
+		out = append(out, v.current)
+	}
+	if v.previous != "" && now.Sub(v.rotatedAt) < v.grace {
+		out = append(out, v.previous)
+	}
+	return out
+}
+
+// Accepts сравнивает got с принимаемыми значениями за постоянное время.
+func (v *Value) Accepts(got string, now time.Time) bool {
+	ok := false
+	for _, c := range v.Candidates(now) {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(c)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// Refresh перечитывает секрет у провайдера. changed == true -- значение сменилось (ротация).
+// Пустое значение не применяется: пропавший секрет скорее ошибка хранилища, чем отзыв ключа.
+func (v *Value) Refresh(ctx context.Context, now time.Time) (changed bool, err error) {
+	if v == nil || v.provider == nil {
+		return false, nil
+	}
+	cur, err := Get(ctx, v.provider, v.name)
+	if err != nil || cur == "" {
+		return false, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if cur == v.current {
+		return false, nil
+	}
+	v.previous, v.current, v.rotatedAt = v.current, cur, now
+	return true, nil
+}
+
+// Watch перечитывает секреты каждые interval, пока не отменён ctx. Ротации и ошибки пишутся в лог;
+// при ошибке остаются прежние значения.
+func Watch(ctx context.Context, interval time.Duration, values ...*Value) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			changed, err := v.Refresh(ctx, time.Now())
+			switch {
+			case err != nil:
+				log.Printf("secrets: refresh %s: %v", v.Name(), err)
+			case changed:
+				log.Printf("secrets: %s rotated (previous value accepted for %s)", v.Name(), v.grace)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-manager/internal/httpclient"
+)
+
+// Vault -- секреты из HashiCorp Vault (KV v1 или v2). Все секреты сервера лежат
+// одним объектом по Path: {"JWT_SECRET": "...", "ADMIN_KEY": "..."}.
+type Vault struct {
+	Addr  string
+	Token string
+	Path  string // KV v2: secret/data/task-manager, KV v1: secret/task-manager
+
+	// Client можно подменить.
+	Client *http.Client
+}
+
+// NewVault создаёт провайдер Vault.
+func NewVault(addr, token, path string) *Vault {
+	return &Vault{
+		Addr:   strings.TrimRight(addr, "/"),
+		Token:  token,
+		Path:   strings.Trim(path, "/"),
+		Client: httpclient.New(httpclient.Options{Name: "vault", Timeout: 10 * time.Second, Retries: 2}),
+	}
+}
+
+// Name возвращает "vault".
+func (v *Vault) Name() string { return "vault" }
+
+// Lookup читает объект по Path и берёт из него поле name.
+func (v *Vault) Lookup(ctx context.Context, name string) (string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+v.Path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", false, fmt.Errorf("GET %s: %s: %s", v.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", false, fmt.Errorf("decode %s: %w", v.Path, err)
+	}
+	// KV v2 кладёт значения в data.data, KV v1 -- прямо в data.
+	var v2 struct {
+		Data map[string]any `json:"data"`
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(out.Data, &v2); err == nil && v2.Data != nil {
+		fields = v2.Data
+	} else if err := json.Unmarshal(out.Data, &fields); err != nil {
+		return "", false, fmt.Errorf("decode %s: %w", v.Path, err)
+	}
+	return field(fields, name)
+}
+
+// field достаёт строковое поле секрета.
+func field(fields map[string]any, name string) (string, bool, error) {
+	raw, ok := fields[name]
+	if !ok {
+		return "", false, nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%s is not a string", name)
+	}
+	return s, s != "", nil
+}
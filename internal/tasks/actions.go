@@ -0,0 +1,191 @@
+package tasks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Действия по подписанной ссылке (например, из письма-напоминания): без входа в систему,
+// только над одной задачей и только одно действие.
+const (
+	ActionDone   = "done"   // отметить задачу выполненной
+	ActionSnooze = "snooze" // отложить срок на сутки
+)
+
+// actionLabels -- подписи действий в письмах и на странице подтверждения.
+var actionLabels = map[string]string{
+	ActionDone:   "Отметить выполненной",
+	ActionSnooze: "Отложить на день",
+}
+
+const (
+	// DefaultActionLinkTTL -- срок жизни ссылки действия, если он не задан.
+	DefaultActionLinkTTL = 72 * time.Hour
+	// MaxActionLinkTTL -- дольше ссылка не живёт.
+	MaxActionLinkTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidActionLink -- подпись не сошлась, ссылка истекла или уже использована.
+	ErrInvalidActionLink = errors.New("action link is invalid, expired or already used")
+	// ErrUnknownAction -- такого действия по ссылке нет.
+	ErrUnknownAction = errors.New("unknown action, want done or snooze")
+	// ErrInvalidActionTTL -- срок жизни ссылки вне (0, MaxActionLinkTTL].
+	ErrInvalidActionTTL = errors.New(`expires_in must be a duration like "72h", at most 720h (30 days)`)
+	// ErrActionLinksDisabled -- не задан PUBLIC_URL: полную ссылку не собрать.
+	ErrActionLinksDisabled = errors.New("action links need PUBLIC_URL")
+)
+
+// ActionLink -- подписанное действие: кто (UserID) что делает (Action) с какой задачей и до какого момента.
+type ActionLink struct {
+	Action  string
+	TaskID  int
+	UserID  int
+	Expires time.Time
+
+	sig []byte // подпись из ссылки (ParseActionLink)
+}
+
+// ActionLinkRequest -- тело POST /api/v1/tasks/{id}/action-links.
+type ActionLinkRequest struct {
+	Action    string `json:"action" validate:"required,oneof=done snooze"`
+	ExpiresIn string `json:"expires_in" validate:"max=20"` // по умолчанию ACTION_LINK_TTL
+}
+
+// SetActionLinks задаёт адрес сервера для ссылок в письмах (PUBLIC_URL) и срок их жизни.
+func (s *Service) SetActionLinks(publicURL string, ttl time.Duration) {
+	s.publicURL = strings.TrimRight(publicURL, "/")
+	s.actionTTL = ttl
+}
+
+// CreateActionLink подписывает ссылку на действие над задачей от имени userID.
+// expiresIn пустой -- ACTION_LINK_TTL.
+func (s *Service) CreateActionLink(ctx context.Context, userID, taskID int, action, expiresIn string, now time.Time) (string, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	ttl := s.actionTTL
+	if ttl <= 0 {
+		ttl = DefaultActionLinkTTL
+	}
+	if expiresIn != "" {
+		d, err := time.ParseDuration(expiresIn)
+		if err != nil || d <= 0 || d > MaxActionLinkTTL {
+			return "", time.Time{}, ErrInvalidActionTTL
+		}
+		ttl = d
+	}
+	task, err := s.GetTaskByID(ctx, taskID, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	link := ActionLink{Action: action, TaskID: task.ID, UserID: userID, Expires: now.Add(ttl).Truncate(time.Second)}
+	u, err := s.actionURL(link, task)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return u, link.Expires, nil
+}
+
+// actionURL собирает полную подписанную ссылку: PUBLIC_URL/act/{action}/{task}?u=&exp=&sig=.
+func (s *Service) actionURL(link ActionLink, task *Task) (string, error) {
+	if _, ok := actionLabels[link.Action]; !ok {
+		return "", ErrUnknownAction
+	}
+	if s.publicURL == "" {
+		return "", ErrActionLinksDisabled
+	}
+	q := url.Values{}
+	q.Set("u", strconv.Itoa(link.UserID))
+	q.Set("exp", strconv.FormatInt(link.Expires.Unix(), 10))
+	q.Set("sig", base64.RawURLEncoding.EncodeToString(
+		actionMAC(appMiddleware.JWTSigningKey(), link, actionState(link.Action, task))))
+	return fmt.Sprintf("%s/act/%s/%d?%s", s.publicURL, link.Action, link.TaskID, q.Encode()), nil
+}
+
+// ParseActionLink разбирает ссылку /act/{action}/{taskID} без данных задачи: действие, владелец,
+// срок. Подпись проверяет VerifyActionLink -- в неё входит состояние задачи.
+func (s *Service) ParseActionLink(action string, taskID int, q url.Values, now time.Time) (ActionLink, error) {
+	if _, ok := actionLabels[action]; !ok {
+		return ActionLink{}, ErrUnknownAction
+	}
+	userID, err1 := strconv.Atoi(q.Get("u"))
+	exp, err2 := strconv.ParseInt(q.Get("exp"), 10, 64)
+	sig, err3 := base64.RawURLEncoding.DecodeString(q.Get("sig"))
+	if err1 != nil || err2 != nil || err3 != nil || userID <= 0 || len(sig) != sha256.Size {
+		return ActionLink{}, ErrInvalidActionLink
+	}
+	link := ActionLink{Action: action, TaskID: taskID, UserID: userID, Expires: time.Unix(exp, 0), sig: sig}
+	if !now.Before(link.Expires) {
+		return ActionLink{}, ErrInvalidActionLink
+	}
+	return link, nil
+}
+
+// VerifyActionLink проверяет подпись разобранной ссылки для задачи task.
+// Подпись принимается любым действующим ключом JWT (ротация не ломает разосланные письма).
+func (s *Service) VerifyActionLink(link ActionLink, task *Task) error {
+	if task.ID != link.TaskID {
+		return ErrInvalidActionLink
+	}
+	state := actionState(link.Action, task)
+	for _, key := range appMiddleware.JWTVerifyKeys() {
+		if hmac.Equal(link.sig, actionMAC([]byte(key), link, state)) {
+			return nil
+		}
+	}
+	return ErrInvalidActionLink
+}
+
+// ApplyAction выполняет действие по проверенной ссылке от имени её владельца.
+func (s *Service) ApplyAction(ctx context.Context, link ActionLink, task *Task, now time.Time) error {
+	if !s.UserActive(ctx, link.UserID) {
+		return ErrInvalidActionLink
+	}
+	switch link.Action {
+	case ActionDone:
+		task.Done = true
+		task.Status = StatusDone
+	case ActionSnooze:
+		due := now
+		if task.Due != nil && task.Due.After(now) {
+			due = *task.Due
+		}
+		due = due.Add(24 * time.Hour).Truncate(time.Second)
+		task.Due = &due
+	default:
+		return ErrUnknownAction
+	}
+	return s.UpdateTask(ctx, task, link.UserID)
+}
+
+// actionState -- то состояние задачи, которое меняет действие. Оно входит в подпись, поэтому
+// ссылка одноразовая: отложенная задача получает новый срок, и та же ссылка её больше не отложит.
+func actionState(action string, task *Task) string {
+	switch action {
+	case ActionSnooze:
+		if task.Due == nil {
+			return "due=none"
+		}
+		return "due=" + strconv.FormatInt(task.Due.Unix(), 10)
+	case ActionDone:
+		return "done=" + strconv.FormatBool(task.Done)
+	}
+	return ""
+}
+
+func actionMAC(key []byte, link ActionLink, state string) []byte {
+	m := hmac.New(sha256.New, key)
+	fmt.Fprintf(m, "task-action\x00%s\x00%d\x00%d\x00%d\x00%s", link.Action, link.TaskID, link.UserID, link.Expires.Unix(), state)
+	return m.Sum(nil)
+}
@@ -0,0 +1,783 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-manager/internal/httpclient"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// События, на которые подписываются автоматизации.
+const (
+	EventTaskCreated   = "task.created"
+	EventTaskUpdated   = "task.updated"
+	EventTaskCompleted = "task.completed" // вдобавок к task.updated, когда задача перешла в "выполнено"
+	EventTaskCommented = "task.commented" // к задаче добавили комментарий (webhook получает и его)
+)
+
+// automationRunsKept -- сколько последних запусков каждой автоматизации хранится в журнале.
+const automationRunsKept = 100
+
+var (
+	// ErrAutomationsUnsupported -- хранилище не умеет хранить автоматизации.
+	ErrAutomationsUnsupported = errors.New("automations are not supported by this storage")
+	// ErrAutomationNotFound -- автоматизации нет (или она чужая).
+	ErrAutomationNotFound = errors.New("automation not found")
+	// ErrAutomationBadAction -- в шаге "do" задано не ровно одно действие.
+	ErrAutomationBadAction = errors.New("each step of \"do\" must set exactly one of assign, due_in_days, due_in_business_days, set_priority, add_tag, webhook")
+	// ErrAutomationUnknownUser -- действие assign ссылается на несуществующего пользователя.
+	ErrAutomationUnknownUser = errors.New("assign: user not found")
+	// ErrAutomationBadWebhook -- webhook указывает во внутреннюю сеть (localhost, частный IP).
+	ErrAutomationBadWebhook = errors.New("webhook: destination must be a public address")
+)
+
+// automationClient -- HTTP-клиент для действий webhook. Адрес задаёт пользователь, поэтому
+// соединения -- только с публичными адресами (httpclient.Options.PublicOnly).
+var automationClient = httpclient.New(httpclient.Options{Name: "automation", Timeout: 10 * time.Second, Retries: 2, PublicOnly: true})
+
+// Automation -- пользовательская автоматизация "если X, то Y": на событие On с задачей,
+// подходящей под If, выполнить шаги Do. Срабатывает на задачах автора (автор или исполнитель).
+//
+//	{"name": "Баги -- Пете", "on": "task.created", "if": {"tag": "bug"},
+//	 "do": [{"assign": 3}, {"due_in_days": 2}, {"webhook": "https://example.com/hook"}]}
+type Automation struct {
+	ID        int                 `json:"id"`
+	Name      string              `json:"name"`
+	Enabled   bool                `json:"enabled"`
+	On        string              `json:"on"`
+	If        AutomationCondition `json:"if"`
+	Do        []AutomationAction  `json:"do"`
+	CreatedBy int                 `json:"created_by"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// AutomationCondition -- фильтр задачи, условия складываются по "И". Пустой фильтр -- любая задача.
+type AutomationCondition struct {
+	Tag           string   `json:"tag,omitempty" validate:"tag"`
+	Priority      Priority `json:"priority,omitempty" validate:"omitempty,priority"`
+	Status        string   `json:"status,omitempty" validate:"omitempty,status"`
+	TitleContains string   `json:"title_contains,omitempty" validate:"max=100"` // без учёта регистра
+}
+
+// AutomationAction -- один шаг автоматизации; задаётся ровно одно поле.
+type AutomationAction struct {
+	Assign    *int `json:"assign,omitempty" validate:"omitempty,min=1"`              // назначить исполнителя (ID пользователя)
+	DueInDays *int `json:"due_in_days,omitempty" validate:"omitempty,min=0,max=365"` // срок через N суток от события, если срока нет
+	// DueInBusinessDays -- срок через N рабочих дней по рабочему календарю, если срока нет
+	DueInBusinessDays *int     `json:"due_in_business_days,omitempty" validate:"omitempty,min=0,max=365"`
+	SetPriority       Priority `json:"set_priority,omitempty" validate:"omitempty,priority"`
+	AddTag            string   `json:"add_tag,omitempty" validate:"tag"`
+	Webhook           string   `json:"webhook,omitempty" validate:"omitempty,url,startswith=http"` // POST с событием и задачей
+}
+
+// AutomationRequest -- тело POST /api/v1/automations и PUT /api/v1/automations/{automation_id}.
+type AutomationRequest struct {
+	Name    string              `json:"name" validate:"required,max=100"`
+	Enabled *bool               `json:"enabled"` // по умолчанию true
+	On      string              `json:"on" validate:"required,oneof=task.created task.updated task.completed task.commented"`
+	If      AutomationCondition `json:"if"`
+	Do      []AutomationAction  `json:"do" validate:"required,min=1,max=10,dive"`
+}
+
+// AutomationRun -- запись журнала выполнения.
+type AutomationRun struct {
+	ID           int       `json:"id"`
+	AutomationID int       `json:"automation_id"`
+	TaskID       int       `json:"task_id"`
+	Event        string    `json:"event"`
+	At           time.Time `json:"at"`
+	OK           bool      `json:"ok"`
+	Actions      []string  `json:"actions"` // выполненные шаги: "assign:3", "webhook:200" и т.п.
+	Error        string    `json:"error,omitempty"`
+}
+
+// AutomationStore -- опциональная возможность хранилища хранить автоматизации и их журнал.
+type AutomationStore interface {
+	CreateAutomation(ctx context.Context, a *Automation) error
+	UpdateAutomation(ctx context.Context, a *Automation) error
+	// DeleteAutomation удаляет автоматизацию вместе с её журналом.
+	DeleteAutomation(ctx context.Context, id int) error
+	// Automations возвращает все автоматизации (всех пользователей).
+	Automations(ctx context.Context) ([]Automation, error)
+	// AddAutomationRun дописывает запись в журнал, оставляя последние automationRunsKept.
+	AddAutomationRun(ctx context.Context, run *AutomationRun) error
+	// AutomationRuns возвращает последние запуски автоматизации, новые первыми.
+	AutomationRuns(ctx context.Context, automationID, limit int) ([]AutomationRun, error)
+}
+
+func (s *Service) automations() (AutomationStore, error) {
+	as, ok := s.capabilities().(AutomationStore)
+	if !ok {
+		return nil, ErrAutomationsUnsupported
+	}
+	return as, nil
+}
+
+// checkAutomation проверяет то, что не выражается тегами validate.
+func (s *Service) checkAutomation(ctx context.Context, req AutomationRequest) error {
+	var assignees []int
+	for _, step := range req.Do {
+		n := 0
+		for _, set := range []bool{step.Assign != nil, step.DueInDays != nil, step.DueInBusinessDays != nil, step.SetPriority != "", step.AddTag != "", step.Webhook != ""} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return ErrAutomationBadAction
+		}
+		if step.Assign != nil {
+			assignees = append(assignees, *step.Assign)
+		}
+		if step.Webhook != "" && httpclient.CheckPublicURL(step.Webhook) != nil {
+			return ErrAutomationBadWebhook
+		}
+	}
+	if len(assignees) == 0 {
+		return nil
+	}
+	users, err := s.repo.GetAllUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range assignees {
+		if !slices.ContainsFunc(users, func(u User) bool { return u.ID == id }) {
+			return fmt.Errorf("%w: %d", ErrAutomationUnknownUser, id)
+		}
+	}
+	return nil
+}
+
+// CreateAutomation создаёт автоматизацию пользователя userID.
+func (s *Service) CreateAutomation(ctx context.Context, userID int, req AutomationRequest, now time.Time) (Automation, error) {
+	if err := ctx.Err(); err != nil {
+		return Automation{}, err
+	}
+	as, err := s.automations()
+	if err != nil {
+		return Automation{}, err
+	}
+	if err := s.checkAutomation(ctx, req); err != nil {
+		return Automation{}, err
+	}
+	a := Automation{
+		Name:      req.Name,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		On:        req.On,
+		If:        req.If,
+		Do:        req.Do,
+		CreatedBy: userID,
+		CreatedAt: now.UTC(),
+		UpdatedAt: now.UTC(),
+	}
+	if err := as.CreateAutomation(ctx, &a); err != nil {
+		return Automation{}, err
+	}
+	return a, nil
+}
+
+// UserAutomations возвращает автоматизации пользователя.
+func (s *Service) UserAutomations(ctx context.Context, userID int) ([]Automation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	as, err := s.automations()
+	if err != nil {
+		return nil, err
+	}
+	all, err := as.Automations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Automation, 0, len(all))
+	for _, a := range all {
+		if a.CreatedBy == userID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// GetAutomation возвращает автоматизацию пользователя по ID. Чужая -- ErrAutomationNotFound.
+func (s *Service) GetAutomation(ctx context.Context, userID, id int) (Automation, error) {
+	list, err := s.UserAutomations(ctx, userID)
+	if err != nil {
+		return Automation{}, err
+	}
+	for _, a := range list {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return Automation{}, ErrAutomationNotFound
+}
+
+// UpdateAutomation заменяет автоматизацию целиком. Журнал сохраняется.
+func (s *Service) UpdateAutomation(ctx context.Context, userID, id int, req AutomationRequest, now time.Time) (Automation, error) {
+	a, err := s.GetAutomation(ctx, userID, id)
+	if err != nil {
+		return Automation{}, err
+	}
+	if err := s.checkAutomation(ctx, req); err != nil {
+		return Automation{}, err
+	}
+	as, err := s.automations()
+	if err != nil {
+		return Automation{}, err
+	}
+	a.Name, a.On, a.If, a.Do = req.Name, req.On, req.If, req.Do
+	a.Enabled = req.Enabled == nil || *req.Enabled
+	a.UpdatedAt = now.UTC()
+	if err := as.UpdateAutomation(ctx, &a); err != nil {
+		return Automation{}, err
+	}
+	return a, nil
+}
+
+// DeleteAutomation удаляет автоматизацию пользователя вместе с журналом.
+func (s *Service) DeleteAutomation(ctx context.Context, userID, id int) error {
+	if _, err := s.GetAutomation(ctx, userID, id); err != nil {
+		return err
+	}
+	as, err := s.automations()
+	if err != nil {
+		return err
+	}
+	return as.DeleteAutomation(ctx, id)
+}
+
+// AutomationRuns возвращает журнал автоматизации пользователя, новые записи первыми.
+func (s *Service) AutomationRuns(ctx context.Context, userID, id, limit int) ([]AutomationRun, error) {
+	if _, err := s.GetAutomation(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	as, err := s.automations()
+	if err != nil {
+		return nil, err
+	}
+	return as.AutomationRuns(ctx, id, limit)
+}
+
+// matches проверяет задачу на фильтр автоматизации.
+func (c AutomationCondition) matches(t *Task) bool {
+	if c.Tag != "" && !hasTag(t.Tags, c.Tag) {
+		return false
+	}
+	if c.Priority != "" && t.Priority != c.Priority.Resolve() {
+		return false
+	}
+	if !statusMatches(c.Status, t.Status) {
+		return false
+	}
+	if c.TitleContains != "" && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(c.TitleContains)) {
+		return false
+	}
+	return true
+}
+
+// automationCtxKey помечает контекст изменений, сделанных самой автоматизацией:
+// такие изменения не порождают новых событий, иначе две автоматизации зациклят друг друга.
+type automationCtxKey struct{}
+
+// fireAutomations запускает автоматизации, подписанные на событие. Выполняются они в фоне
+// после ответа на запрос: медленный webhook не должен задерживать создание задачи.
+// Ошибки не возвращаются -- они попадают в журнал запусков и в лог.
+func (s *Service) fireAutomations(ctx context.Context, event string, t *Task) {
+	s.fireEvent(ctx, event, t, nil)
+}
+
+// fireEvent -- fireAutomations для события с комментарием (c может быть nil).
+func (s *Service) fireEvent(ctx context.Context, event string, t *Task, c *Comment) {
+	if IsDryRun(ctx) || ctx.Value(automationCtxKey{}) != nil {
+		return
+	}
+	as, err := s.automations()
+	if err != nil {
+		return
+	}
+	all, err := as.Automations(ctx)
+	if err != nil {
+		log.Printf("request_id=%s automations: list error: %v", appMiddleware.GetRequestID(ctx), err)
+		return
+	}
+	all = slices.DeleteFunc(all, func(a Automation) bool { return !a.Enabled || a.On != event })
+	if len(all) == 0 {
+		return
+	}
+
+	// Задача из запроса может быть неполной (PUT без user_id) -- сверяемся с сохранённой.
+	cur, err := s.repo.GetByID(ctx, t.ID)
+	if err != nil {
+		log.Printf("request_id=%s automations: task=%d error: %v", appMiddleware.GetRequestID(ctx), t.ID, err)
+		return
+	}
+	var matched []Automation
+	for _, a := range all {
+		if (cur.UserID == a.CreatedBy || cur.AssignedTo == a.CreatedBy) && a.If.matches(cur) {
+			matched = append(matched, a)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	taskID, at := t.ID, time.Now().UTC()
+	bg := context.WithValue(context.WithoutCancel(ctx), automationCtxKey{}, true)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		for _, a := range matched {
+			actx, cancel := context.WithTimeout(bg, 30*time.Second)
+			run := s.runAutomation(actx, a, event, taskID, at, c)
+			cancel()
+			if err := as.AddAutomationRun(bg, &run); err != nil {
+				log.Printf("automations: automation=%d task=%d journal error: %v", a.ID, taskID, err)
+			}
+		}
+	}()
+}
+
+// runAutomation выполняет шаги автоматизации над задачей taskID. Сначала применяются изменения
+// задачи (одним обновлением), затем вызываются webhook -- они получают уже изменённую задачу
+// и комментарий c, если событие о нём.
+func (s *Service) runAutomation(ctx context.Context, a Automation, event string, taskID int, at time.Time, c *Comment) AutomationRun {
+	run := AutomationRun{AutomationID: a.ID, TaskID: taskID, Event: event, At: at, Actions: []string{}}
+	fail := func(err error) AutomationRun {
+		run.Error = err.Error()
+		log.Printf("request_id=%s automations: automation=%d task=%d event=%s error: %v",
+			appMiddleware.GetRequestID(ctx), a.ID, taskID, event, err)
+		return run
+	}
+
+	cur, err := s.repo.GetByID(ctx, taskID)
+	if err != nil {
+		return fail(err)
+	}
+	t := cloneTask(cur)
+
+	changed := false
+	for _, step := range a.Do {
+		switch {
+		case step.Assign != nil && t.AssignedTo != *step.Assign:
+			t.AssignedTo, changed = *step.Assign, true
+			run.Actions = append(run.Actions, "assign:"+strconv.Itoa(*step.Assign))
+		case step.DueInDays != nil && t.Due == nil:
+			due := at.AddDate(0, 0, *step.DueInDays)
+			t.Due, changed = &due, true
+			run.Actions = append(run.Actions, "due:"+due.Format(time.RFC3339))
+		case step.DueInBusinessDays != nil && t.Due == nil:
+			cal, err := s.Calendar(ctx)
+			if err != nil {
+				return fail(err)
+			}
+			due := cal.DueInBusinessDays(at, *step.DueInBusinessDays)
+			t.Due, changed = &due, true
+			run.Actions = append(run.Actions, "due:"+due.Format(time.RFC3339))
+		case step.SetPriority != "" && t.Priority != step.SetPriority.Resolve():
+			t.Priority, changed = step.SetPriority.Resolve(), true
+			run.Actions = append(run.Actions, "priority:"+string(step.SetPriority))
+		case step.AddTag != "" && !hasTag(t.Tags, step.AddTag):
+			t.Tags, changed = append(t.Tags, step.AddTag), true
+			run.Actions = append(run.Actions, "tag:"+step.AddTag)
+		}
+	}
+	if changed {
+		if err := s.UpdateTask(ctx, t, a.CreatedBy); err != nil {
+			return fail(err)
+		}
+	}
+
+	for _, step := range a.Do {
+		if step.Webhook == "" {
+			continue
+		}
+		status, err := callAutomationWebhook(ctx, step.Webhook, a, event, t, c)
+		if err != nil {
+			return fail(fmt.Errorf("webhook %s: %w", step.Webhook, err))
+		}
+		run.Actions = append(run.Actions, "webhook:"+strconv.Itoa(status))
+	}
+
+	run.OK = true
+	log.Printf("request_id=%s automations: automation=%d %q event=%s task=%d actions=%v",
+		appMiddleware.GetRequestID(ctx), a.ID, a.Name, event, taskID, run.Actions)
+	return run
+}
+
+// automationPayload -- тело запроса webhook.
+type automationPayload struct {
+	AutomationID int      `json:"automation_id"`
+	Automation   string   `json:"automation"`
+	Event        string   `json:"event"`
+	Task         *Task    `json:"task"`
+	Comment      *Comment `json:"comment,omitempty"` // для task.commented
+}
+
+// callAutomationWebhook отправляет POST с событием, задачей и комментарием. Ответ вне 2xx -- ошибка.
+func callAutomationWebhook(ctx context.Context, url string, a Automation, event string, t *Task, c *Comment) (int, error) {
+	body, err := json.Marshal(automationPayload{AutomationID: a.ID, Automation: a.Name, Event: event, Task: t, Comment: c})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-manager-automation")
+	req.Header.Set("X-Automation-Event", event)
+	appMiddleware.PropagateTrace(req)
+
+	resp, err := automationClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// automationData -- содержимое файла автоматизаций.
+type automationData struct {
+	Automations []Automation    `json:"automations"`
+	Runs        []AutomationRun `json:"runs"`
+}
+
+// automationsFile -- автоматизации и журнал JSON-хранилища, рядом с файлом задач.
+type automationsFile struct {
+	once      sync.Once
+	err       error
+	mu        sync.Mutex
+	data      automationData
+	lastID    int
+	lastRunID int
+}
+
+func (ts *TaskStore) automationsPath() string {
+	return ts.filename + ".automations.json"
+}
+
+func (ts *TaskStore) loadAutomations() error {
+	ts.automations.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.automationsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.automations.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.automations.data); err != nil {
+			ts.automations.err = fmt.Errorf("parse %s: %w", ts.automationsPath(), err)
+			return
+		}
+		for _, a := range ts.automations.data.Automations {
+			ts.automations.lastID = max(ts.automations.lastID, a.ID)
+		}
+		for _, run := range ts.automations.data.Runs {
+			ts.automations.lastRunID = max(ts.automations.lastRunID, run.ID)
+		}
+	})
+	return ts.automations.err
+}
+
+// saveAutomations переписывает файл целиком. Вызывающий держит automations.mu.
+func (ts *TaskStore) saveAutomations() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.automations.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.automationsPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.automationsPath())
+}
+
+// withAutomations выполняет fn под блокировкой и сохраняет файл; при ошибке записи
+// данные возвращаются к прежним.
+func (ts *TaskStore) withAutomations(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadAutomations(); err != nil {
+		return err
+	}
+	ts.automations.mu.Lock()
+	defer ts.automations.mu.Unlock()
+
+	prev := automationData{
+		Automations: slices.Clone(ts.automations.data.Automations),
+		Runs:        slices.Clone(ts.automations.data.Runs),
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := ts.saveAutomations(); err != nil {
+		ts.automations.data = prev
+		return err
+	}
+	return nil
+}
+
+// automationIndex ищет автоматизацию по ID. Вызывающий держит automations.mu.
+func (ts *TaskStore) automationIndex(id int) int {
+	return slices.IndexFunc(ts.automations.data.Automations, func(a Automation) bool { return a.ID == id })
+}
+
+// CreateAutomation сохраняет автоматизацию и выдаёт ей ID.
+func (ts *TaskStore) CreateAutomation(ctx context.Context, a *Automation) error {
+	return ts.withAutomations(ctx, func() error {
+		a.ID = ts.automations.lastID + 1
+		ts.automations.data.Automations = append(ts.automations.data.Automations, *a)
+		ts.automations.lastID = a.ID
+		return nil
+	})
+}
+
+// UpdateAutomation заменяет автоматизацию с тем же ID.
+func (ts *TaskStore) UpdateAutomation(ctx context.Context, a *Automation) error {
+	return ts.withAutomations(ctx, func() error {
+		i := ts.automationIndex(a.ID)
+		if i < 0 {
+			return ErrAutomationNotFound
+		}
+		ts.automations.data.Automations[i] = *a
+		return nil
+	})
+}
+
+// DeleteAutomation удаляет автоматизацию и её журнал.
+func (ts *TaskStore) DeleteAutomation(ctx context.Context, id int) error {
+	return ts.withAutomations(ctx, func() error {
+		i := ts.automationIndex(id)
+		if i < 0 {
+			return ErrAutomationNotFound
+		}
+		ts.automations.data.Automations = slices.Delete(ts.automations.data.Automations, i, i+1)
+		ts.automations.data.Runs = slices.DeleteFunc(ts.automations.data.Runs, func(run AutomationRun) bool {
+			return run.AutomationID == id
+		})
+		return nil
+	})
+}
+
+// Automations возвращает все автоматизации.
+func (ts *TaskStore) Automations(ctx context.Context) ([]Automation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadAutomations(); err != nil {
+		return nil, err
+	}
+	ts.automations.mu.Lock()
+	defer ts.automations.mu.Unlock()
+
+	out := make([]Automation, len(ts.automations.data.Automations))
+	for i, a := range ts.automations.data.Automations {
+		a.Do = slices.Clone(a.Do)
+		out[i] = a
+	}
+	return out, nil
+}
+
+// AddAutomationRun дописывает запись в журнал; у автоматизации остаются последние automationRunsKept.
+func (ts *TaskStore) AddAutomationRun(ctx context.Context, run *AutomationRun) error {
+	return ts.withAutomations(ctx, func() error {
+		if ts.automationIndex(run.AutomationID) < 0 {
+			return ErrAutomationNotFound // удалили, пока шёл запуск
+		}
+		run.ID = ts.automations.lastRunID + 1
+		ts.automations.lastRunID = run.ID
+		runs := append(ts.automations.data.Runs, *run)
+
+		kept := 0
+		for i := len(runs) - 1; i >= 0; i-- {
+			if runs[i].AutomationID != run.AutomationID {
+				continue
+			}
+			if kept++; kept > automationRunsKept {
+				runs = slices.Delete(runs, i, i+1)
+			}
+		}
+		ts.automations.data.Runs = runs
+		return nil
+	})
+}
+
+// AutomationRuns возвращает последние limit запусков автоматизации, новые первыми.
+func (ts *TaskStore) AutomationRuns(ctx context.Context, automationID, limit int) ([]AutomationRun, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadAutomations(); err != nil {
+		return nil, err
+	}
+	ts.automations.mu.Lock()
+	defer ts.automations.mu.Unlock()
+
+	out := []AutomationRun{}
+	runs := ts.automations.data.Runs
+	for i := len(runs) - 1; i >= 0 && len(out) < limit; i-- {
+		if runs[i].AutomationID == automationID {
+			run := runs[i]
+			run.Actions = slices.Clone(run.Actions)
+			out = append(out, run)
+		}
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// CreateAutomation сохраняет автоматизацию в automations (migrations/000016_automations.up.sql).
+func (r *PostgresRepository) CreateAutomation(ctx context.Context, a *Automation) error {
+	cond, actions, err := automationJSON(a)
+	if err != nil {
+		return err
+	}
+	return r.q.QueryRowContext(ctx, `INSERT INTO automations (name, enabled, event, condition, actions, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		a.Name, a.Enabled, a.On, cond, actions, a.CreatedBy, a.CreatedAt, a.UpdatedAt).Scan(&a.ID)
+}
+
+// UpdateAutomation заменяет автоматизацию с тем же ID.
+func (r *PostgresRepository) UpdateAutomation(ctx context.Context, a *Automation) error {
+	cond, actions, err := automationJSON(a)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, `UPDATE automations SET name = $1, enabled = $2, event = $3, condition = $4,
+		actions = $5, updated_at = $6 WHERE id = $7`,
+		a.Name, a.Enabled, a.On, cond, actions, a.UpdatedAt, a.ID)
+	return automationAffected(res, err)
+}
+
+// DeleteAutomation удаляет автоматизацию; журнал удаляется каскадом.
+func (r *PostgresRepository) DeleteAutomation(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM automations WHERE id = $1", id)
+	return automationAffected(res, err)
+}
+
+// Automations возвращает все автоматизации.
+func (r *PostgresRepository) Automations(ctx context.Context) ([]Automation, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, name, enabled, event, condition, actions, created_by, created_at, updated_at
+		FROM automations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Automation{}
+	for rows.Next() {
+		var a Automation
+		var cond, actions []byte
+		if err := rows.Scan(&a.ID, &a.Name, &a.Enabled, &a.On, &cond, &actions, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(cond, &a.If); err != nil {
+			return nil, fmt.Errorf("automation %d condition: %w", a.ID, err)
+		}
+		if err := json.Unmarshal(actions, &a.Do); err != nil {
+			return nil, fmt.Errorf("automation %d actions: %w", a.ID, err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// AddAutomationRun дописывает запись в журнал и удаляет записи старше последних automationRunsKept.
+func (r *PostgresRepository) AddAutomationRun(ctx context.Context, run *AutomationRun) error {
+	actions, err := json.Marshal(run.Actions)
+	if err != nil {
+		return err
+	}
+	err = r.q.QueryRowContext(ctx, `INSERT INTO automation_runs (automation_id, task_id, event, at, ok, actions, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		run.AutomationID, run.TaskID, run.Event, run.At, run.OK, actions, run.Error).Scan(&run.ID)
+	if isForeignKeyViolation(err) {
+		return ErrAutomationNotFound // удалили, пока шёл запуск
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `DELETE FROM automation_runs WHERE automation_id = $1 AND id <= (
+		SELECT id FROM automation_runs WHERE automation_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1)`,
+		run.AutomationID, automationRunsKept)
+	return err
+}
+
+// AutomationRuns возвращает последние limit запусков автоматизации, новые первыми.
+func (r *PostgresRepository) AutomationRuns(ctx context.Context, automationID, limit int) ([]AutomationRun, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, automation_id, task_id, event, at, ok, actions, error
+		FROM automation_runs WHERE automation_id = $1 ORDER BY id DESC LIMIT $2`, automationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []AutomationRun{}
+	for rows.Next() {
+		var run AutomationRun
+		var actions []byte
+		if err := rows.Scan(&run.ID, &run.AutomationID, &run.TaskID, &run.Event, &run.At, &run.OK, &actions, &run.Error); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(actions, &run.Actions); err != nil {
+			return nil, fmt.Errorf("automation run %d actions: %w", run.ID, err)
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+// automationJSON -- фильтр и шаги автоматизации для колонок JSONB.
+func automationJSON(a *Automation) (cond, actions []byte, err error) {
+	if cond, err = json.Marshal(a.If); err != nil {
+		return nil, nil, err
+	}
+	if actions, err = json.Marshal(a.Do); err != nil {
+		return nil, nil, err
+	}
+	return cond, actions, nil
+}
+
+func automationAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAutomationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package tasks_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"task-manager/internal/tasks/taskstest"
+)
+
+// TestAutomationWebhookPrivateAddress -- webhook во внутреннюю сеть не сохраняется, на публичный адрес -- да.
+func TestAutomationWebhookPrivateAddress(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := taskstest.NewServer(store, "")
+	token, err := srv.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for webhook, want := range map[string]int{
+		"http://127.0.0.1:8080/hook":               http.StatusBadRequest,
+		"http://localhost/hook":                    http.StatusBadRequest,
+		"http://169.254.169.254/latest/meta-data/": http.StatusBadRequest,
+		"http://10.0.0.5/hook":                     http.StatusBadRequest,
+		"https://hooks.example.com/tasks":          http.StatusCreated,
+	} {
+		body := map[string]any{"name": "hook", "on": "task.created", "do": []map[string]any{{"webhook": webhook}}}
+		code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/automations", token, body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want {
+			t.Errorf("webhook %s: %d, want %d", webhook, code, want)
+		}
+	}
+}
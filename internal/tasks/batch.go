@@ -0,0 +1,129 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// BatchSaver -- опциональная возможность хранилища объединить запись на диск:
+// все изменения, сделанные внутри fn (с переданным ей контекстом), сохраняются одной записью
+// в конце, а не по записи на каждую задачу. Изменения не атомарны -- для этого есть WithTx:
+// каждое применяется сразу и видно читателям, откладывается только запись файла.
+type BatchSaver interface {
+	SaveBatch(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// SaveBatch выполняет fn, объединяя записи хранилища внутри неё в одну.
+// Хранилища без такой возможности выполняют fn как есть.
+func (s *Service) SaveBatch(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if bs, ok := s.capabilities().(BatchSaver); ok {
+		return bs.SaveBatch(ctx, fn)
+	}
+	return fn(ctx)
+}
+
+type saveBatchKey struct{}
+
+// saveBatch -- отложенная запись одного TaskStore. dirty меняется под saveMu.
+type saveBatch struct {
+	ts    *TaskStore
+	dirty bool
+}
+
+// batchFor возвращает пачку, открытую для ts в ctx (nil -- записывать сразу).
+func batchFor(ctx context.Context, ts *TaskStore) *saveBatch {
+	b, _ := ctx.Value(saveBatchKey{}).(*saveBatch)
+	if b == nil || b.ts != ts {
+		return nil
+	}
+	return b
+}
+
+// SaveBatch откладывает запись файла задач до конца fn. Файл пишется один раз, даже если fn
+// вернула ошибку: изменения до ошибки уже в памяти и должны попасть на диск. Если запись
+// не удалась, память перечитывается из файла -- изменения пачки теряются, но память и диск совпадают.
+// Вложенный вызов выполняет fn внутри внешней пачки.
+func (ts *TaskStore) SaveBatch(ctx context.Context, fn func(ctx context.Context) error) error {
+	if batchFor(ctx, ts) != nil {
+		return fn(ctx)
+	}
+	if err := ts.load(); err != nil {
+		return err
+	}
+
+	b := &saveBatch{ts: ts}
+	err := fn(context.WithValue(ctx, saveBatchKey{}, b))
+
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+	if !b.dirty {
+		return err
+	}
+	// Запись не должна зависеть от отмены запроса: изменения уже видны читателям.
+	// Контекст без пачки -- иначе persistLocked снова отложил бы запись.
+	if ferr := ts.persistLocked(context.Background()); ferr != nil {
+		// Откатить изменения пачки по одному нельзя, поэтому память возвращается к файлу:
+		// в нём последнее удачно записанное состояние, включая чужие изменения.
+		if rerr := ts.reloadLocked(context.Background()); rerr != nil {
+			log.Printf("storage: batch flush failed (%v) and reload failed, memory is ahead of disk until the next write: %v", ferr, rerr)
+		} else {
+			log.Printf("storage: batch flush failed, changes of the batch are discarded: %v", ferr)
+		}
+		return errors.Join(err, ferr)
+	}
+	return err
+}
+
+// reloadLocked заменяет задачи в памяти содержимым файла. Вызывающий держит saveMu.
+// Счётчик ID не уменьшается, а UUID, занятые незавершёнными транзакциями, остаются занятыми:
+// иначе ID или UUID выдались бы дважды.
+func (ts *TaskStore) reloadLocked(ctx context.Context) error {
+	disk, err := ts.LoadTasks(ctx)
+	if err != nil {
+		return err
+	}
+	onDisk := make(map[string]bool, len(disk))
+	for i := range disk {
+		onDisk[disk[i].UUID] = true
+	}
+
+	for i := range ts.shards {
+		ts.shards[i].mu.Lock()
+	}
+	maxID := 0
+	for i := range ts.shards {
+		sh := &ts.shards[i]
+		for _, t := range sh.tasks {
+			if !onDisk[t.UUID] {
+				ts.uuids.Delete(t.UUID)
+			}
+		}
+		sh.tasks = make(map[int]*Task)
+	}
+	for i := range disk {
+		t := &disk[i]
+		canonicalPriority(t)
+		ts.shard(t.ID).tasks[t.ID] = t
+		ts.uuids.Store(t.UUID, t.ID)
+		maxID = max(maxID, t.ID)
+	}
+	for i := range ts.shards {
+		ts.shards[i].mu.Unlock()
+	}
+
+	if int64(maxID) > ts.lastID.Load() {
+		ts.lastID.Store(int64(maxID))
+	}
+	snap := ts.all()
+	ts.snapshot.Store(&snap)
+	return nil
+}
+
+// SaveBatch у GitStore не объединяет записи: каждое изменение -- отдельный коммит со своим автором.
+func (gs *GitStore) SaveBatch(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
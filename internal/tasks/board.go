@@ -0,0 +1,415 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"task-manager/internal/markdown"
+)
+
+var (
+	// ErrBoardsUnsupported -- хранилище не умеет хранить доски.
+	ErrBoardsUnsupported = errors.New("public boards are not supported by this storage")
+	// ErrBoardNotFound -- доски нет или она отозвана.
+	ErrBoardNotFound = errors.New("board not found")
+)
+
+// Board -- публичная доска: отфильтрованный вид задач проекта только для чтения.
+// Проект -- это метка задач (tag). Доска живёт, пока её не отзовут; токен, как и у ссылок
+// на задачи, хранится только в виде SHA-256.
+type Board struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Tag         string    `json:"tag"`
+	Status      string    `json:"status,omitempty"` // только задачи в этом статусе
+	IncludeDone bool      `json:"include_done"`     // по умолчанию выполненные скрыты
+	TokenHash   string    `json:"-"`
+	CreatedBy   int       `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateBoardRequest -- тело POST /api/v1/boards.
+type CreateBoardRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Tag         string `json:"tag" validate:"required,tag"`
+	Status      string `json:"status" validate:"omitempty,status"`
+	IncludeDone bool   `json:"include_done"`
+}
+
+// BoardView -- то, что видит посетитель доски. Только то, что нужно для статуса:
+// без ID пользователей, UUID и пользовательских полей.
+type BoardView struct {
+	Name        string      `json:"name"`
+	Tag         string      `json:"tag"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Tasks       []BoardTask `json:"tasks"`
+}
+
+// BoardTask -- задача на публичной доске.
+type BoardTask struct {
+	ID              int            `json:"id"`
+	Title           string         `json:"title"`
+	Status          string         `json:"status"`
+	Done            bool           `json:"done"`
+	Priority        Priority       `json:"priority"`
+	Due             *time.Time     `json:"due,omitempty"`
+	CompletedAt     *time.Time     `json:"completed_at,omitempty"`
+	Assignee        string         `json:"assignee,omitempty"` // имя исполнителя
+	DescriptionHTML string         `json:"description_html,omitempty"`
+	Checklist       []BoardSubTask `json:"checklist"`
+}
+
+// BoardSubTask -- пункт чек-листа на публичной доске.
+type BoardSubTask struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// BoardStore -- опциональная возможность хранилища хранить доски.
+type BoardStore interface {
+	CreateBoard(ctx context.Context, b *Board) error
+	BoardByTokenHash(ctx context.Context, hash string) (Board, bool, error)
+	Boards(ctx context.Context) ([]Board, error)
+	DeleteBoard(ctx context.Context, id int) error
+}
+
+func (s *Service) boards() (BoardStore, error) {
+	bs, ok := s.capabilities().(BoardStore)
+	if !ok {
+		return nil, ErrBoardsUnsupported
+	}
+	return bs, nil
+}
+
+// CreateBoard создаёт доску и возвращает её вместе с токеном (показывается один раз).
+func (s *Service) CreateBoard(ctx context.Context, userID int, req CreateBoardRequest, now time.Time) (Board, string, error) {
+	if err := ctx.Err(); err != nil {
+		return Board{}, "", err
+	}
+	bs, err := s.boards()
+	if err != nil {
+		return Board{}, "", err
+	}
+	token := rand.Text()
+	b := Board{
+		Name:        req.Name,
+		Tag:         req.Tag,
+		Status:      req.Status,
+		IncludeDone: req.IncludeDone,
+		TokenHash:   hashToken(token),
+		CreatedBy:   userID,
+		CreatedAt:   now.UTC(),
+	}
+	if err := bs.CreateBoard(ctx, &b); err != nil {
+		return Board{}, "", err
+	}
+	return b, token, nil
+}
+
+// ResolveBoard находит доску по токену.
+func (s *Service) ResolveBoard(ctx context.Context, token string) (Board, error) {
+	if err := ctx.Err(); err != nil {
+		return Board{}, err
+	}
+	bs, err := s.boards()
+	if err != nil {
+		return Board{}, err
+	}
+	b, ok, err := bs.BoardByTokenHash(ctx, hashToken(token))
+	if err == nil && !ok {
+		err = ErrBoardNotFound
+	}
+	return b, err
+}
+
+// Boards возвращает все доски семьи.
+func (s *Service) Boards(ctx context.Context) ([]Board, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	bs, err := s.boards()
+	if err != nil {
+		return nil, err
+	}
+	return bs.Boards(ctx)
+}
+
+// RevokeBoard отзывает доску: ссылка сразу перестаёт работать.
+func (s *Service) RevokeBoard(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bs, err := s.boards()
+	if err != nil {
+		return err
+	}
+	return bs.DeleteBoard(ctx, id)
+}
+
+// BoardView собирает содержимое доски: задачи с меткой проекта, сначала самые важные.
+func (s *Service) BoardView(ctx context.Context, b Board, now time.Time) (BoardView, error) {
+	list, err := s.GetAllTasks(ctx, b.CreatedBy)
+	if err != nil {
+		return BoardView{}, err
+	}
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return BoardView{}, err
+	}
+	names := make(map[int]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+
+	matched := list[:0]
+	for _, t := range list {
+		if hasTag(t.Tags, b.Tag) && (b.IncludeDone || !t.Done) && statusMatches(b.Status, t.Status) {
+			matched = append(matched, t)
+		}
+	}
+	sortTasksByID(matched)
+	_ = SortTasks(matched, "-"+SortByPriority)
+
+	v := BoardView{Name: b.Name, Tag: b.Tag, GeneratedAt: now.UTC(), Tasks: make([]BoardTask, 0, len(matched))}
+	for _, t := range matched {
+		bt := BoardTask{
+			ID:          t.ID,
+			Title:       t.Title,
+			Status:      t.Status,
+			Done:        t.Done,
+			Priority:    t.Priority,
+			Due:         t.Due,
+			CompletedAt: t.CompletedAt,
+			Assignee:    names[t.AssignedTo],
+			Checklist:   make([]BoardSubTask, 0, len(t.SubTasks)),
+		}
+		if t.Description != "" {
+			bt.DescriptionHTML = markdown.Render(t.Description)
+		}
+		for _, sub := range t.SubTasks {
+			bt.Checklist = append(bt.Checklist, BoardSubTask{Title: sub.Title, Done: sub.Done})
+		}
+		v.Tasks = append(v.Tasks, bt)
+	}
+	return v, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// boardsFile -- доски JSON-хранилища. Лежат рядом с файлом задач, как и ссылки на задачи.
+type boardsFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []boardRecord
+	lastID int
+}
+
+// boardRecord -- Board в файле (у Board хэш токена скрыт от JSON API).
+type boardRecord struct {
+	Board
+	TokenHash string `json:"token_hash"`
+}
+
+func (ts *TaskStore) boardsPath() string {
+	return ts.filename + ".boards.json"
+}
+
+func (ts *TaskStore) loadBoards() error {
+	ts.boards.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.boardsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.boards.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.boards.data); err != nil {
+			ts.boards.err = fmt.Errorf("parse %s: %w", ts.boardsPath(), err)
+			return
+		}
+		for i := range ts.boards.data {
+			rec := &ts.boards.data[i]
+			rec.Board.TokenHash = rec.TokenHash
+			ts.boards.lastID = max(ts.boards.lastID, rec.ID)
+		}
+	})
+	return ts.boards.err
+}
+
+// saveBoards переписывает файл досок целиком. Вызывающий держит boards.mu.
+func (ts *TaskStore) saveBoards() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.boards.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.boardsPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.boardsPath())
+}
+
+// CreateBoard сохраняет доску и выдаёт ей ID.
+func (ts *TaskStore) CreateBoard(ctx context.Context, b *Board) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadBoards(); err != nil {
+		return err
+	}
+	ts.boards.mu.Lock()
+	defer ts.boards.mu.Unlock()
+
+	b.ID = ts.boards.lastID + 1
+	ts.boards.data = append(ts.boards.data, boardRecord{Board: *b, TokenHash: b.TokenHash})
+	if err := ts.saveBoards(); err != nil {
+		ts.boards.data = ts.boards.data[:len(ts.boards.data)-1]
+		return err
+	}
+	ts.boards.lastID = b.ID
+	return nil
+}
+
+// BoardByTokenHash ищет доску по хэшу токена.
+func (ts *TaskStore) BoardByTokenHash(ctx context.Context, hash string) (Board, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Board{}, false, err
+	}
+	if err := ts.loadBoards(); err != nil {
+		return Board{}, false, err
+	}
+	ts.boards.mu.Lock()
+	defer ts.boards.mu.Unlock()
+
+	for _, rec := range ts.boards.data {
+		if rec.TokenHash == hash {
+			return rec.Board, true, nil
+		}
+	}
+	return Board{}, false, nil
+}
+
+// Boards возвращает все доски.
+func (ts *TaskStore) Boards(ctx context.Context) ([]Board, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadBoards(); err != nil {
+		return nil, err
+	}
+	ts.boards.mu.Lock()
+	defer ts.boards.mu.Unlock()
+
+	out := make([]Board, 0, len(ts.boards.data))
+	for _, rec := range ts.boards.data {
+		out = append(out, rec.Board)
+	}
+	return out, nil
+}
+
+// DeleteBoard удаляет доску по ID.
+func (ts *TaskStore) DeleteBoard(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadBoards(); err != nil {
+		return err
+	}
+	ts.boards.mu.Lock()
+	defer ts.boards.mu.Unlock()
+
+	for i, rec := range ts.boards.data {
+		if rec.ID != id {
+			continue
+		}
+		prev := ts.boards.data
+		ts.boards.data = append(prev[:i:i], prev[i+1:]...)
+		if err := ts.saveBoards(); err != nil {
+			ts.boards.data = prev
+			return err
+		}
+		return nil
+	}
+	return ErrBoardNotFound
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+const boardSelect = "SELECT id, name, tag, status, include_done, token_hash, created_by, created_at FROM boards"
+
+func scanBoard(row interface{ Scan(...any) error }) (Board, error) {
+	var b Board
+	var createdBy sql.NullInt64
+	err := row.Scan(&b.ID, &b.Name, &b.Tag, &b.Status, &b.IncludeDone, &b.TokenHash, &createdBy, &b.CreatedAt)
+	b.CreatedBy = int(createdBy.Int64)
+	return b, err
+}
+
+// CreateBoard сохраняет доску в boards (migrations/000013_boards.up.sql).
+func (r *PostgresRepository) CreateBoard(ctx context.Context, b *Board) error {
+	return r.q.QueryRowContext(ctx, `INSERT INTO boards (name, tag, status, include_done, token_hash, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		b.Name, b.Tag, b.Status, b.IncludeDone, b.TokenHash, b.CreatedBy, b.CreatedAt).Scan(&b.ID)
+}
+
+// BoardByTokenHash ищет доску по хэшу токена.
+func (r *PostgresRepository) BoardByTokenHash(ctx context.Context, hash string) (Board, bool, error) {
+	b, err := scanBoard(r.q.QueryRowContext(ctx, boardSelect+" WHERE token_hash = $1", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Board{}, false, nil
+	}
+	if err != nil {
+		return Board{}, false, err
+	}
+	return b, true, nil
+}
+
+// Boards возвращает все доски.
+func (r *PostgresRepository) Boards(ctx context.Context) ([]Board, error) {
+	rows, err := r.q.QueryContext(ctx, boardSelect+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Board{}
+	for rows.Next() {
+		b, err := scanBoard(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// DeleteBoard удаляет доску по ID.
+func (r *PostgresRepository) DeleteBoard(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM boards WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrBoardNotFound
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Границы периода диаграммы сгорания.
+const (
+	DefaultBurndownDays = 14
+	MaxBurndownDays     = 366
+)
+
+// ErrInvalidBurndownRange -- неверные границы периода диаграммы сгорания.
+var ErrInvalidBurndownRange = errors.New("from and to must be YYYY-MM-DD or today, from <= to <= today, at most 366 days")
+
+// Burndown -- ряды для диаграммы сгорания: по точке на каждый день периода.
+// Восстанавливается по created_at и completed_at текущих задач, без чтения истории:
+// удалённые задачи в ряды не попадают, а задачи без created_at считаются существующими с начала периода.
+type Burndown struct {
+	Tag         string          `json:"tag,omitempty"`
+	Assignee    int             `json:"assignee,omitempty"`
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Timezone    string          `json:"timezone"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Points      []BurndownPoint `json:"points"`
+}
+
+// BurndownPoint -- состояние на конец дня.
+type BurndownPoint struct {
+	Date             string `json:"date"`
+	Open             int    `json:"open"`              // открытых задач на конец дня
+	Completed        int    `json:"completed"`         // выполнено за день
+	CompletedTotal   int    `json:"completed_total"`   // выполнено с начала периода
+	Added            int    `json:"added"`             // создано за день (рост объёма работ)
+	RemainingMinutes int    `json:"remaining_minutes"` // сумма оценок открытых задач
+}
+
+// Burndown строит ряды за дни [from, to] (YYYY-MM-DD или "today") в поясе рабочего календаря.
+// Пустой from -- последние DefaultBurndownDays дней по to.
+// tag -- «проект»: только задачи с этой меткой; assignee > 0 -- только задачи исполнителя.
+func (s *Service) Burndown(ctx context.Context, userID int, tag string, assignee int, from, to string, now time.Time) (Burndown, error) {
+	if err := ctx.Err(); err != nil {
+		return Burndown{}, err
+	}
+	cal, err := s.Calendar(ctx)
+	if err != nil {
+		return Burndown{}, err
+	}
+	loc := cal.Location()
+	end, ok2 := reportDay(to, now, loc)
+	start, ok1 := end.AddDate(0, 0, 1-DefaultBurndownDays), true
+	if from != "" {
+		start, ok1 = reportDay(from, now, loc)
+	}
+	today, _ := reportDay("today", now, loc)
+	if !ok1 || !ok2 || end.Before(start) || end.After(today) || start.AddDate(0, 0, MaxBurndownDays).Before(end) {
+		return Burndown{}, ErrInvalidBurndownRange
+	}
+
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return Burndown{}, err
+	}
+	matched := list[:0]
+	for _, t := range list {
+		if (tag == "" || hasTag(t.Tags, tag)) && (assignee == 0 || t.AssignedTo == assignee) {
+			matched = append(matched, t)
+		}
+	}
+
+	b := Burndown{
+		Tag:         tag,
+		Assignee:    assignee,
+		From:        start.Format(time.DateOnly),
+		To:          end.Format(time.DateOnly),
+		Timezone:    loc.String(),
+		GeneratedAt: now.UTC(),
+		Points:      []BurndownPoint{},
+	}
+	completedTotal := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		p := BurndownPoint{Date: day.Format(time.DateOnly)}
+		for _, t := range matched {
+			created := t.CreatedAt == nil || t.CreatedAt.Before(dayEnd)
+			if !created {
+				continue
+			}
+			if t.CreatedAt != nil && !t.CreatedAt.Before(dayStart) {
+				p.Added++
+			}
+			// Задача без completed_at, но выполненная, закрыта в неизвестный момент -- считаем, что до периода.
+			closedBy := t.CompletedAt != nil && t.CompletedAt.Before(dayEnd) || t.Done && t.CompletedAt == nil
+			if !closedBy {
+				p.Open++
+				p.RemainingMinutes += t.EstimateMinutes
+				continue
+			}
+			if t.CompletedAt != nil && !t.CompletedAt.Before(dayStart) {
+				p.Completed++
+			}
+		}
+		completedTotal += p.Completed
+		p.CompletedTotal = completedTotal
+		b.Points = append(b.Points, p)
+	}
+	return b, nil
+}
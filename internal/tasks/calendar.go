@@ -0,0 +1,307 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCalendarUnsupported -- хранилище не умеет хранить рабочий календарь (читается календарь по умолчанию).
+	ErrCalendarUnsupported = errors.New("storage does not support the business calendar")
+	// ErrInvalidWorkHours -- рабочий день заканчивается не позже, чем начинается.
+	ErrInvalidWorkHours = errors.New("work_end must be later than work_start")
+)
+
+// weekdayNames -- дни недели в календаре: "mon".."sun".
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// Calendar -- рабочий календарь сервера (один на всех пользователей): рабочие дни недели,
+// рабочие часы и праздники. По нему считаются сроки "через N рабочих дней", сдвиги "2bd",
+// условия правил эскалации с business_days и расписания с workdays_only.
+type Calendar struct {
+	Timezone  string     `json:"timezone" validate:"required,timezone"`
+	WorkDays  []string   `json:"work_days" validate:"required,min=1,max=7,unique,dive,oneof=mon tue wed thu fri sat sun"`
+	WorkStart string     `json:"work_start" validate:"required,datetime=15:04"` // HH:MM в Timezone
+	WorkEnd   string     `json:"work_end" validate:"required,datetime=15:04"`   // HH:MM; к этому времени ставится срок "через N рабочих дней"
+	Holidays  []Holiday  `json:"holidays" validate:"max=500,dive"`
+	UpdatedBy int        `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Holiday -- нерабочий день (праздник, перенос выходного).
+type Holiday struct {
+	Date string `json:"date" validate:"required,datetime=2006-01-02"`
+	Name string `json:"name,omitempty" validate:"max=100"`
+}
+
+// DefaultCalendar -- календарь, пока его никто не настраивал: пн-пт, 09:00-18:00 UTC, без праздников.
+func DefaultCalendar() Calendar {
+	return Calendar{
+		Timezone:  "UTC",
+		WorkDays:  []string{"mon", "tue", "wed", "thu", "fri"},
+		WorkStart: "09:00",
+		WorkEnd:   "18:00",
+		Holidays:  []Holiday{},
+	}
+}
+
+// CalendarStore -- опциональная возможность хранилища хранить рабочий календарь.
+// GetCalendar возвращает ok == false, если календарь не сохраняли.
+type CalendarStore interface {
+	GetCalendar(ctx context.Context) (c Calendar, ok bool, err error)
+	SaveCalendar(ctx context.Context, c Calendar) error
+}
+
+// Calendar возвращает рабочий календарь или календарь по умолчанию.
+func (s *Service) Calendar(ctx context.Context) (Calendar, error) {
+	if err := ctx.Err(); err != nil {
+		return Calendar{}, err
+	}
+	cs, ok := s.capabilities().(CalendarStore)
+	if !ok {
+		return DefaultCalendar(), nil
+	}
+	c, found, err := cs.GetCalendar(ctx)
+	if err != nil || !found {
+		return DefaultCalendar(), err
+	}
+	return c, nil
+}
+
+// SaveCalendar заменяет рабочий календарь целиком.
+func (s *Service) SaveCalendar(ctx context.Context, userID int, c Calendar, now time.Time) (Calendar, error) {
+	if err := ctx.Err(); err != nil {
+		return Calendar{}, err
+	}
+	if c.WorkStart >= c.WorkEnd { // HH:MM сравниваются как строки
+		return Calendar{}, ErrInvalidWorkHours
+	}
+	cs, ok := s.capabilities().(CalendarStore)
+	if !ok {
+		return Calendar{}, ErrCalendarUnsupported
+	}
+	if c.Holidays == nil {
+		c.Holidays = []Holiday{}
+	}
+	slices.SortFunc(c.Holidays, func(a, b Holiday) int { return strings.Compare(a.Date, b.Date) })
+	at := now.UTC()
+	c.UpdatedBy, c.UpdatedAt = userID, &at
+	if err := cs.SaveCalendar(ctx, c); err != nil {
+		return Calendar{}, err
+	}
+	return c, nil
+}
+
+// Location возвращает часовой пояс календаря (UTC, если пояс не распознан).
+func (c Calendar) Location() *time.Location {
+	if loc, err := time.LoadLocation(c.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// clock возвращает момент HH:MM в день t (в поясе t).
+func clock(t time.Time, hhmm string) time.Time {
+	hm, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		hm = time.Time{}
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, hm.Hour(), hm.Minute(), 0, 0, t.Location())
+}
+
+// IsWorkday сообщает, рабочий ли день t (по поясу календаря).
+func (c Calendar) IsWorkday(t time.Time) bool {
+	t = t.In(c.Location())
+	if !slices.ContainsFunc(c.WorkDays, func(d string) bool { return weekdayNames[d] == t.Weekday() }) {
+		return false
+	}
+	date := t.Format(time.DateOnly)
+	return !slices.ContainsFunc(c.Holidays, func(h Holiday) bool { return h.Date == date })
+}
+
+// InWorkingHours сообщает, приходится ли t на рабочее время.
+func (c Calendar) InWorkingHours(t time.Time) bool {
+	t = t.In(c.Location())
+	return c.IsWorkday(t) && !t.Before(clock(t, c.WorkStart)) && t.Before(clock(t, c.WorkEnd))
+}
+
+// AddBusinessDays сдвигает t на n рабочих дней (n < 0 -- назад), сохраняя время суток.
+// При n == 0 нерабочий день переносится на ближайший следующий рабочий.
+func (c Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	loc := c.Location()
+	d := t.In(loc)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	// Год без единого рабочего дня (все дни -- праздники) -- ошибка настройки; дальше не ищем.
+	for i := 0; i < 366*3 && (n > 0 || !c.IsWorkday(d)); i++ {
+		d = d.AddDate(0, 0, step)
+		if c.IsWorkday(d) {
+			n--
+		}
+	}
+	return d.In(t.Location())
+}
+
+// DueInBusinessDays -- срок "через n рабочих дней" от now: конец рабочего дня (WorkEnd).
+// n == 0 -- конец сегодняшнего рабочего дня, а если он уже прошёл или сегодня выходной -- следующего.
+func (c Calendar) DueInBusinessDays(now time.Time, n int) time.Time {
+	loc := c.Location()
+	local := now.In(loc)
+	if n == 0 && c.IsWorkday(local) && local.Before(clock(local, c.WorkEnd)) {
+		return clock(local, c.WorkEnd).UTC()
+	}
+	if n == 0 {
+		n = 1
+	}
+	return clock(c.AddBusinessDays(local, n).In(loc), c.WorkEnd).UTC()
+}
+
+// BusinessDaysBetween -- сколько рабочих дней прошло от from до to: считаются рабочие дни
+// после дня from до дня to включительно. Если to раньше from -- 0.
+func (c Calendar) BusinessDaysBetween(from, to time.Time) int {
+	loc := c.Location()
+	from, to = from.In(loc), to.In(loc)
+	n := 0
+	day := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, loc)
+	last := time.Date(to.Year(), to.Month(), to.Day(), 12, 0, 0, 0, loc)
+	for i := 0; i < 366*10 && day.Before(last); i++ {
+		day = day.AddDate(0, 0, 1)
+		if c.IsWorkday(day) {
+			n++
+		}
+	}
+	return n
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// calendarFile -- рабочий календарь JSON-хранилища, рядом с файлом задач.
+type calendarFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data *Calendar
+}
+
+func (ts *TaskStore) calendarPath() string {
+	return ts.filename + ".calendar.json"
+}
+
+func (ts *TaskStore) loadCalendar() error {
+	ts.calendar.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.calendarPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.calendar.err = err
+			return
+		}
+		var c Calendar
+		if err := json.Unmarshal(raw, &c); err != nil {
+			ts.calendar.err = fmt.Errorf("parse %s: %w", ts.calendarPath(), err)
+			return
+		}
+		ts.calendar.data = &c
+	})
+	return ts.calendar.err
+}
+
+// GetCalendar возвращает сохранённый календарь.
+func (ts *TaskStore) GetCalendar(ctx context.Context) (Calendar, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Calendar{}, false, err
+	}
+	if err := ts.loadCalendar(); err != nil {
+		return Calendar{}, false, err
+	}
+	ts.calendar.mu.Lock()
+	defer ts.calendar.mu.Unlock()
+
+	if ts.calendar.data == nil {
+		return Calendar{}, false, nil
+	}
+	c := *ts.calendar.data
+	c.WorkDays = slices.Clone(c.WorkDays)
+	c.Holidays = slices.Clone(c.Holidays)
+	return c, true, nil
+}
+
+// SaveCalendar переписывает файл календаря.
+func (ts *TaskStore) SaveCalendar(ctx context.Context, c Calendar) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadCalendar(); err != nil {
+		return err
+	}
+	ts.calendar.mu.Lock()
+	defer ts.calendar.mu.Unlock()
+
+	if ts.filename != "" {
+		raw, err := json.MarshalIndent(c, "", "   ")
+		if err != nil {
+			return err
+		}
+		tmp := ts.calendarPath() + ".tmp"
+		if err := os.WriteFile(tmp, raw, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, ts.calendarPath()); err != nil {
+			return err
+		}
+	}
+	ts.calendar.data = &c
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// GetCalendar читает календарь из business_calendar (migrations/000018_business_calendar.up.sql).
+func (r *PostgresRepository) GetCalendar(ctx context.Context) (Calendar, bool, error) {
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM business_calendar WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Calendar{}, false, nil
+	}
+	if err != nil {
+		return Calendar{}, false, err
+	}
+	var c Calendar
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Calendar{}, false, fmt.Errorf("business calendar: %w", err)
+	}
+	return c, true, nil
+}
+
+// SaveCalendar сохраняет календарь (одна строка с id = 1).
+func (r *PostgresRepository) SaveCalendar(ctx context.Context, c Calendar) error {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO business_calendar (id, data) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, raw)
+	return err
+}
@@ -0,0 +1,184 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Границы отчёта о загрузке.
+const (
+	DefaultCapacityDays = 14
+	MaxCapacityDays     = 92
+)
+
+// ErrInvalidCapacityRange -- неверное начало или длина периода отчёта о загрузке.
+var ErrInvalidCapacityRange = errors.New("from must be YYYY-MM-DD or today, days must be 1-92")
+
+// CapacityReport -- загрузка исполнителей по дням: сумма оценок открытых задач со сроком в этот день
+// против того, сколько исполнитель может сделать за день.
+type CapacityReport struct {
+	From        string             `json:"from"` // первый день периода, YYYY-MM-DD в поясе календаря
+	To          string             `json:"to"`   // последний день периода включительно
+	Timezone    string             `json:"timezone"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Assignees   []CapacityAssignee `json:"assignees"`
+	// UnestimatedTasks -- открытые задачи со сроком в периоде, но без оценки: в загрузку они не попали.
+	UnestimatedTasks int `json:"unestimated_tasks"`
+}
+
+// CapacityAssignee -- загрузка одного исполнителя за период.
+type CapacityAssignee struct {
+	UserID             int           `json:"user_id"`
+	Username           string        `json:"username,omitempty"`
+	PlannedMinutes     int           `json:"planned_minutes"`     // оценки задач со сроком в периоде
+	CapacityMinutes    int           `json:"capacity_minutes"`    // ёмкость за все дни периода
+	OverdueMinutes     int           `json:"overdue_minutes"`     // оценки просроченных задач (срок до начала периода)
+	UnscheduledMinutes int           `json:"unscheduled_minutes"` // оценки задач без срока
+	OverbookedDays     []string      `json:"overbooked_days"`
+	Days               []CapacityDay `json:"days"`
+}
+
+// CapacityDay -- загрузка исполнителя за один день.
+type CapacityDay struct {
+	Date            string `json:"date"`
+	PlannedMinutes  int    `json:"planned_minutes"`
+	CapacityMinutes int    `json:"capacity_minutes"` // 0 в выходные и праздники
+	Overbooked      bool   `json:"overbooked"`       // запланировано больше, чем помещается в день
+	TaskIDs         []int  `json:"task_ids"`
+}
+
+// WorkMinutes -- длина рабочего дня по календарю в минутах.
+func (c Calendar) WorkMinutes() int {
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	return int(clock(day, c.WorkEnd).Sub(clock(day, c.WorkStart)) / time.Minute)
+}
+
+// reportDay разбирает день отчёта (YYYY-MM-DD или "today") в поясе loc. Возвращает полдень этого дня --
+// чтобы AddDate не спотыкался о перевод часов.
+func reportDay(s string, now time.Time, loc *time.Location) (time.Time, bool) {
+	if s == "today" {
+		s = now.In(loc).Format(time.DateOnly)
+	}
+	d, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d.Add(12 * time.Hour), true
+}
+
+// CapacityReport строит отчёт о загрузке на days дней начиная с from (YYYY-MM-DD или "today").
+// Дни считаются в поясе рабочего календаря. assignee > 0 -- только этот исполнитель.
+// Ёмкость исполнителя в рабочий день -- daily_capacity_minutes из его настроек или длина рабочего дня.
+func (s *Service) CapacityReport(ctx context.Context, userID int, from string, days, assignee int, now time.Time) (CapacityReport, error) {
+	if err := ctx.Err(); err != nil {
+		return CapacityReport{}, err
+	}
+	if days < 1 || days > MaxCapacityDays {
+		return CapacityReport{}, ErrInvalidCapacityRange
+	}
+	cal, err := s.Calendar(ctx)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	loc := cal.Location()
+	start, ok := reportDay(from, now, loc)
+	if !ok {
+		return CapacityReport{}, ErrInvalidCapacityRange
+	}
+	dates := make([]string, days)
+	index := make(map[string]int, days)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i).Format(time.DateOnly)
+		index[dates[i]] = i
+	}
+
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+
+	rep := CapacityReport{
+		From:        dates[0],
+		To:          dates[days-1],
+		Timezone:    loc.String(),
+		GeneratedAt: now.UTC(),
+		Assignees:   []CapacityAssignee{},
+	}
+	byUser := make(map[int]*CapacityAssignee)
+	row := func(userID int) *CapacityAssignee {
+		if a, ok := byUser[userID]; ok {
+			return a
+		}
+		a := &CapacityAssignee{UserID: userID, OverbookedDays: []string{}, Days: make([]CapacityDay, days)}
+		for i, d := range dates {
+			a.Days[i] = CapacityDay{Date: d, TaskIDs: []int{}}
+		}
+		byUser[userID] = a
+		return a
+	}
+
+	for _, u := range users {
+		if assignee == 0 || u.ID == assignee {
+			row(u.ID).Username = u.Username
+		}
+	}
+	for _, t := range list {
+		if t.Done || t.AssignedTo <= 0 || (assignee > 0 && t.AssignedTo != assignee) {
+			continue
+		}
+		var date string
+		if t.Due != nil {
+			date = t.Due.In(loc).Format(time.DateOnly)
+		}
+		i, inRange := index[date]
+		if t.EstimateMinutes == 0 {
+			if inRange {
+				rep.UnestimatedTasks++
+			}
+			continue
+		}
+		a := row(t.AssignedTo)
+		switch {
+		case t.Due == nil:
+			a.UnscheduledMinutes += t.EstimateMinutes
+		case inRange:
+			a.Days[i].PlannedMinutes += t.EstimateMinutes
+			a.Days[i].TaskIDs = append(a.Days[i].TaskIDs, t.ID)
+		case date < dates[0]:
+			a.OverdueMinutes += t.EstimateMinutes
+		}
+	}
+
+	for _, a := range byUser {
+		daily := cal.WorkMinutes()
+		prefs, err := s.GetPreferences(ctx, a.UserID)
+		if err != nil {
+			return CapacityReport{}, err
+		}
+		if prefs.DailyCapacityMinutes > 0 {
+			daily = prefs.DailyCapacityMinutes
+		}
+		for i := range a.Days {
+			d := &a.Days[i]
+			if cal.IsWorkday(start.AddDate(0, 0, i)) {
+				d.CapacityMinutes = daily
+			}
+			d.Overbooked = d.PlannedMinutes > d.CapacityMinutes
+			if d.Overbooked {
+				a.OverbookedDays = append(a.OverbookedDays, d.Date)
+			}
+			slices.Sort(d.TaskIDs)
+			a.PlannedMinutes += d.PlannedMinutes
+			a.CapacityMinutes += d.CapacityMinutes
+		}
+		rep.Assignees = append(rep.Assignees, *a)
+	}
+	slices.SortFunc(rep.Assignees, func(a, b CapacityAssignee) int { return a.UserID - b.UserID })
+	return rep, nil
+}
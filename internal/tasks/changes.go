@@ -0,0 +1,239 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Типы записей ленты изменений.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// changeFeedSize -- сколько последних изменений помнит лента. Курсор старше них устарел (ErrCursorExpired).
+const changeFeedSize = 1000
+
+var (
+	// ErrInvalidCursor -- курсор не разобрался или указывает в будущее.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorExpired -- изменения после курсора уже вытеснены из ленты или курсор выдан
+	// до перезапуска сервера: клиенту нужно перечитать задачи целиком и начать с нового курсора.
+	ErrCursorExpired = errors.New("cursor expired")
+)
+
+// Change -- запись ленты изменений. У удалённой задачи Task нет.
+type Change struct {
+	Type   string    `json:"type"`
+	TaskID int       `json:"task_id"`
+	At     time.Time `json:"at"`
+	Task   *Task     `json:"task,omitempty"`
+
+	seq uint64
+}
+
+// ChangeBatch -- ответ GET /api/v1/changes. Cursor передаётся в следующий запрос как since;
+// More -- в ленте есть ещё изменения, не поместившиеся в limit.
+type ChangeBatch struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	More    bool     `json:"more"`
+}
+
+// changeFeed -- кольцо последних изменений задач в памяти процесса. Курсор -- "<epoch>.<seq>":
+// epoch меняется при каждом запуске, поэтому курсор прошлого процесса (или соседнего экземпляра
+// за балансировщиком) честно считается устаревшим, а не молча пропускает изменения.
+type changeFeed struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64   // номер последнего изменения
+	buf     []Change // по возрастанию seq, не длиннее changeFeedSize
+	wake    chan struct{}
+	stopped bool
+	waiters atomic.Int64 // запросы, ждущие изменений (long polling)
+}
+
+func (f *changeFeed) init() {
+	if f.epoch != "" {
+		return
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	f.epoch = hex.EncodeToString(b)
+	f.wake = make(chan struct{})
+}
+
+func (f *changeFeed) cursor(seq uint64) string {
+	return f.epoch + "." + strconv.FormatUint(seq, 10)
+}
+
+// publish добавляет изменение и будит ждущих.
+func (f *changeFeed) publish(typ string, t Task, at time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+
+	f.seq++
+	c := Change{Type: typ, TaskID: t.ID, At: at, seq: f.seq}
+	if typ != ChangeDeleted {
+		c.Task = &t
+	}
+	if len(f.buf) == changeFeedSize {
+		f.buf = append(f.buf[:0], f.buf[1:]...)
+	}
+	f.buf = append(f.buf, c)
+
+	close(f.wake)
+	f.wake = make(chan struct{})
+}
+
+// stop будит всех ждущих и больше никого не держит: сервер останавливается.
+func (f *changeFeed) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	if !f.stopped {
+		f.stopped = true
+		close(f.wake)
+		f.wake = make(chan struct{})
+	}
+}
+
+// parseCursor возвращает номер изменения из курсора.
+func (f *changeFeed) parseCursor(cursor string) (uint64, error) {
+	epoch, num, ok := strings.Cut(cursor, ".")
+	seq, err := strconv.ParseUint(num, 10, 64)
+	if !ok || epoch == "" || err != nil {
+		return 0, ErrInvalidCursor
+	}
+	if epoch != f.epoch {
+		return 0, ErrCursorExpired
+	}
+	if seq > f.seq {
+		return 0, ErrInvalidCursor
+	}
+	// Изменение seq+1 уже вытеснено -- между курсором и лентой дыра.
+	if len(f.buf) > 0 && seq+1 < f.buf[0].seq {
+		return 0, ErrCursorExpired
+	}
+	return seq, nil
+}
+
+// publishChange записывает изменение в ленту, в журнал ревизий синхронизации (sync.go)
+// и в историю версий задачи (versions.go).
+// t -- задача после изменения; у удалённой важны только ID и UUID. При пробном запуске записи
+// не было -- и изменения тоже.
+func (s *Service) publishChange(ctx context.Context, typ string, t Task) {
+	if IsDryRun(ctx) {
+		return
+	}
+	at := time.Now().UTC()
+	s.changes.publish(typ, t, at)
+	s.bumpRevision(ctx, typ, t, at)
+	s.recordVersion(ctx, typ, t, at)
+}
+
+// storedUpdate -- задача, какой её сохранило хранилище после Update: в t только изменяемые поля
+// (UUID, автор, подзадачи и дата создания остаются от old, пустые Fields -- "не менять").
+func storedUpdate(old, t Task) Task {
+	out := old
+	out.Title = t.Title
+	out.Done = t.Done
+	out.Status = t.Status
+	out.Priority = t.Priority
+	out.AssignedTo = t.AssignedTo
+	out.Description = t.Description
+	out.Tags = t.Tags
+	out.Due = t.Due
+	out.EstimateMinutes = t.EstimateMinutes
+	out.CompletedAt = t.CompletedAt
+	if t.Fields != nil {
+		out.Fields = t.Fields
+	}
+	return out
+}
+
+// Changes возвращает изменения задач после курсора since, не больше limit. Пустой since -- "с этого
+// момента": изменений нет, только курсор. Если изменений пока нет, ждёт их не дольше wait
+// (0 -- не ждать) и возвращает пустую пачку с тем же курсором, когда время вышло или сервер
+// останавливается.
+//
+// Лента живёт в памяти процесса: изменения, сделанные другими экземплярами сервера или напрямую
+// в базе, в неё не попадают, а после перезапуска старые курсоры устаревают.
+func (s *Service) Changes(ctx context.Context, userID int, since string, wait time.Duration, limit int) (ChangeBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return ChangeBatch{}, err
+	}
+	f := &s.changes
+
+	var timer <-chan time.Time
+	for {
+		f.mu.Lock()
+		f.init()
+		if since == "" {
+			since = f.cursor(f.seq)
+			wait = 0
+		}
+		seq, err := f.parseCursor(since)
+		if err != nil {
+			f.mu.Unlock()
+			return ChangeBatch{}, err
+		}
+
+		batch := ChangeBatch{Changes: []Change{}, Cursor: since}
+		for _, c := range f.buf {
+			if c.seq <= seq {
+				continue
+			}
+			if len(batch.Changes) == limit {
+				batch.More = true
+				break
+			}
+			if c.Task != nil && !s.canSee(userID, *c.Task) {
+				// Задача чужого закрытого проекта (или только что в него попавшая) -- для пользователя удалена.
+				c = Change{Type: ChangeDeleted, TaskID: c.TaskID, At: c.At, seq: c.seq}
+			}
+			batch.Changes = append(batch.Changes, c)
+			batch.Cursor = f.cursor(c.seq)
+		}
+		wake, stopped := f.wake, f.stopped
+		f.mu.Unlock()
+
+		if len(batch.Changes) > 0 || wait <= 0 || stopped {
+			return batch, nil
+		}
+		if timer == nil {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			timer = t.C
+			f.waiters.Add(1)
+			defer f.waiters.Add(-1)
+		}
+		select {
+		case <-wake:
+		case <-timer:
+			return batch, nil
+		case <-ctx.Done():
+			return ChangeBatch{}, ctx.Err()
+		}
+	}
+}
+
+// ChangeWaiters -- сколько запросов сейчас ждут изменений (GET /api/v1/changes?wait=...).
+func (s *Service) ChangeWaiters() int {
+	return int(s.changes.waiters.Load())
+}
+
+// StopChanges отпускает запросы, ждущие изменений (GET /api/v1/changes?wait=...): при остановке
+// сервера они сразу получают пустой ответ, а не держат её до таймаута.
+func (s *Service) StopChanges() {
+	s.changes.stop()
+}
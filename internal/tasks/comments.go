@@ -0,0 +1,559 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+const (
+	// NotificationMention -- вид уведомления: пользователя упомянули в комментарии.
+	NotificationMention = "mention"
+
+	// notificationsKept -- сколько последних уведомлений хранится у пользователя.
+	notificationsKept = 500
+	// maxCommentMentions -- сколько разных @username из комментария получают уведомление; остальные -- нет.
+	maxCommentMentions = 20
+)
+
+// ErrCommentsUnsupported -- хранилище не умеет хранить комментарии и уведомления.
+var ErrCommentsUnsupported = errors.New("comments are not supported by this storage")
+
+// Comment -- комментарий к задаче. @username в тексте -- упоминание: упомянутый получает уведомление.
+type Comment struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	AuthorID  int       `json:"author_id"`
+	Body      string    `json:"body"`
+	Mentions  []int     `json:"mentions,omitempty"` // кому ушло уведомление об упоминании
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentRequest -- тело POST /api/v1/tasks/{id}/comments.
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// UserNotification -- уведомление во входящих пользователя (GET /api/v1/me/notifications).
+// В отличие от Notification, которое только доставляется, оно хранится, пока его не прочтут.
+type UserNotification struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Kind      string     `json:"kind"` // NotificationMention
+	TaskID    int        `json:"task_id"`
+	CommentID int        `json:"comment_id,omitempty"`
+	ActorID   int        `json:"actor_id"` // кто упомянул
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"` // nil -- не прочитано
+}
+
+// MarkNotificationsReadRequest -- тело POST /api/v1/me/notifications/read.
+type MarkNotificationsReadRequest struct {
+	IDs []int `json:"ids" validate:"max=500,dive,min=1"` // пусто -- все
+}
+
+// CommentStore -- опциональная возможность хранилища хранить комментарии и входящие уведомления.
+type CommentStore interface {
+	// AddComment сохраняет комментарий и выдаёт ему ID.
+	AddComment(ctx context.Context, c *Comment) error
+	// Comments возвращает комментарии задачи по порядку добавления.
+	Comments(ctx context.Context, taskID int) ([]Comment, error)
+	// AddUserNotifications сохраняет уведомления и выдаёт им ID; у каждого получателя
+	// остаются последние notificationsKept.
+	AddUserNotifications(ctx context.Context, list []UserNotification) error
+	// UserNotifications возвращает последние limit уведомлений пользователя, новые первыми.
+	UserNotifications(ctx context.Context, userID int, unreadOnly bool, limit int) ([]UserNotification, error)
+	// MarkNotificationsRead отмечает прочитанными уведомления ids (пусто -- все непрочитанные)
+	// и возвращает, сколько отмечено.
+	MarkNotificationsRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error)
+}
+
+func (s *Service) comments() (CommentStore, error) {
+	cs, ok := s.capabilities().(CommentStore)
+	if !ok {
+		return nil, ErrCommentsUnsupported
+	}
+	return cs, nil
+}
+
+// mentionPattern -- @username в начале текста или после пробела и знаков препинания
+// (адрес mom@example.com -- не упоминание).
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@-])@([\p{L}\p{N}_][\p{L}\p{N}_.-]*)`)
+
+// parseMentions возвращает имена из @username по порядку, без повторов (без учёта регистра).
+// Точка или дефис в конце -- знак препинания, а не часть имени.
+func parseMentions(body string) []string {
+	var out []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.TrimRight(m[1], ".-")
+		if name != "" && !slices.ContainsFunc(out, func(s string) bool { return strings.EqualFold(s, name) }) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// mentionedUsers сопоставляет упоминания с пользователями. Автор, отключённые и те, кому задача
+// не видна (закрытый проект), уведомления не получают.
+func (s *Service) mentionedUsers(ctx context.Context, taskID, authorID int, body string) ([]User, error) {
+	names := parseMentions(body)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []User
+	for _, name := range names {
+		i := slices.IndexFunc(users, func(u User) bool { return strings.EqualFold(u.Username, name) })
+		if i < 0 {
+			continue
+		}
+		u := users[i]
+		if u.ID == authorID || u.Disabled || slices.ContainsFunc(out, func(o User) bool { return o.ID == u.ID }) {
+			continue
+		}
+		if err := s.CheckTaskVisible(ctx, u.ID, taskID); errors.Is(err, ErrTaskNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if out = append(out, u); len(out) == maxCommentMentions {
+			break
+		}
+	}
+	return out, nil
+}
+
+// AddComment добавляет комментарий к задаче от имени userID. Упомянутые пользователи получают
+// уведомление во входящие и через подключённую доставку (почта, см. SetNotifier), а автоматизации
+// с "on": "task.commented" -- событие с комментарием.
+func (s *Service) AddComment(ctx context.Context, taskID, userID int, body string, now time.Time) (Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return Comment{}, err
+	}
+	cs, err := s.comments()
+	if err != nil {
+		return Comment{}, err
+	}
+	task, err := s.GetTaskByID(ctx, taskID, userID)
+	if err != nil {
+		return Comment{}, err
+	}
+	mentioned, err := s.mentionedUsers(ctx, task.ID, userID, body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	c := Comment{TaskID: task.ID, AuthorID: userID, Body: body, CreatedAt: now.UTC()}
+	for _, u := range mentioned {
+		c.Mentions = append(c.Mentions, u.ID)
+	}
+	if err := cs.AddComment(ctx, &c); err != nil {
+		return Comment{}, err
+	}
+
+	if len(mentioned) > 0 {
+		author := "#" + strconv.Itoa(userID)
+		if u, err := s.UserByID(ctx, userID); err == nil {
+			author = "@" + u.Username
+		}
+		text := fmt.Sprintf("%s упоминает вас в задаче #%d «%s»: %s", author, task.ID, task.Title, excerpt(body, 200))
+		list := make([]UserNotification, len(mentioned))
+		for i, u := range mentioned {
+			list[i] = UserNotification{UserID: u.ID, Kind: NotificationMention, TaskID: task.ID,
+				CommentID: c.ID, ActorID: userID, Text: text, CreatedAt: c.CreatedAt}
+		}
+		// Комментарий уже сохранён: без записи во входящих уведомление всё равно доставляется.
+		if err := cs.AddUserNotifications(ctx, list); err != nil {
+			log.Printf("request_id=%s comments: comment=%d notifications error: %v", appMiddleware.GetRequestID(ctx), c.ID, err)
+		}
+		s.deliverNotifications(ctx, list)
+	}
+	s.fireEvent(ctx, EventTaskCommented, task, &c)
+	return c, nil
+}
+
+// deliverNotifications отправляет уведомления через Notifier в фоне: медленная почта
+// не должна задерживать ответ. Ошибки доставки попадают в лог.
+func (s *Service) deliverNotifications(ctx context.Context, list []UserNotification) {
+	bg := context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		for _, n := range list {
+			source := n.Kind + ":comment:" + strconv.Itoa(n.CommentID)
+			if err := s.notify(bg, Notification{UserID: n.UserID, TaskID: n.TaskID, Source: source, Text: n.Text}); err != nil {
+				log.Printf("request_id=%s comments: notify user=%d error: %v", appMiddleware.GetRequestID(bg), n.UserID, err)
+			}
+		}
+	}()
+}
+
+// excerpt -- начало текста не длиннее n символов, переводы строк заменены пробелами.
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+// TaskComments возвращает комментарии задачи, если она видна userID.
+func (s *Service) TaskComments(ctx context.Context, taskID, userID int) ([]Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cs, err := s.comments()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetTaskByID(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+	return cs.Comments(ctx, taskID)
+}
+
+// UserNotifications возвращает входящие уведомления пользователя, новые первыми.
+func (s *Service) UserNotifications(ctx context.Context, userID int, unreadOnly bool, limit int) ([]UserNotification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cs, err := s.comments()
+	if err != nil {
+		return nil, err
+	}
+	return cs.UserNotifications(ctx, userID, unreadOnly, min(max(limit, 1), notificationsKept))
+}
+
+// MarkNotificationsRead отмечает уведомления пользователя прочитанными (ids пусто -- все).
+// Чужие и уже прочитанные ID пропускаются.
+func (s *Service) MarkNotificationsRead(ctx context.Context, userID int, ids []int, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	cs, err := s.comments()
+	if err != nil {
+		return 0, err
+	}
+	return cs.MarkNotificationsRead(ctx, userID, ids, now.UTC())
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// commentData -- содержимое файла комментариев.
+type commentData struct {
+	Comments      []Comment          `json:"comments"`
+	Notifications []UserNotification `json:"notifications"`
+}
+
+// commentsFile -- комментарии и входящие уведомления JSON-хранилища, рядом с файлом задач.
+type commentsFile struct {
+	once               sync.Once
+	err                error
+	mu                 sync.Mutex
+	data               commentData
+	lastID             int
+	lastNotificationID int
+}
+
+func (ts *TaskStore) commentsPath() string {
+	return ts.filename + ".comments.json"
+}
+
+func (ts *TaskStore) loadComments() error {
+	ts.comments.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.commentsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.comments.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.comments.data); err != nil {
+			ts.comments.err = fmt.Errorf("parse %s: %w", ts.commentsPath(), err)
+			return
+		}
+		for _, c := range ts.comments.data.Comments {
+			ts.comments.lastID = max(ts.comments.lastID, c.ID)
+		}
+		for _, n := range ts.comments.data.Notifications {
+			ts.comments.lastNotificationID = max(ts.comments.lastNotificationID, n.ID)
+		}
+	})
+	return ts.comments.err
+}
+
+// saveComments переписывает файл целиком. Вызывающий держит comments.mu.
+func (ts *TaskStore) saveComments() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.comments.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.commentsPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.commentsPath())
+}
+
+// withComments выполняет fn под блокировкой и сохраняет файл; при ошибке записи
+// данные возвращаются к прежним.
+func (ts *TaskStore) withComments(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadComments(); err != nil {
+		return err
+	}
+	ts.comments.mu.Lock()
+	defer ts.comments.mu.Unlock()
+
+	prev := commentData{
+		Comments:      slices.Clone(ts.comments.data.Comments),
+		Notifications: slices.Clone(ts.comments.data.Notifications),
+	}
+	prevID, prevNotificationID := ts.comments.lastID, ts.comments.lastNotificationID
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := ts.saveComments(); err != nil {
+		ts.comments.data = prev
+		ts.comments.lastID, ts.comments.lastNotificationID = prevID, prevNotificationID
+		return err
+	}
+	return nil
+}
+
+// AddComment сохраняет комментарий и выдаёт ему ID.
+func (ts *TaskStore) AddComment(ctx context.Context, c *Comment) error {
+	return ts.withComments(ctx, func() error {
+		c.ID = ts.comments.lastID + 1
+		ts.comments.lastID = c.ID
+		c.Mentions = slices.Clone(c.Mentions)
+		ts.comments.data.Comments = append(ts.comments.data.Comments, *c)
+		return nil
+	})
+}
+
+// Comments возвращает комментарии задачи по порядку добавления.
+func (ts *TaskStore) Comments(ctx context.Context, taskID int) ([]Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadComments(); err != nil {
+		return nil, err
+	}
+	ts.comments.mu.Lock()
+	defer ts.comments.mu.Unlock()
+
+	out := []Comment{}
+	for _, c := range ts.comments.data.Comments {
+		if c.TaskID == taskID {
+			c.Mentions = slices.Clone(c.Mentions)
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// AddUserNotifications сохраняет уведомления; у получателя остаются последние notificationsKept.
+func (ts *TaskStore) AddUserNotifications(ctx context.Context, list []UserNotification) error {
+	return ts.withComments(ctx, func() error {
+		all := ts.comments.data.Notifications
+		for i := range list {
+			list[i].ID = ts.comments.lastNotificationID + 1
+			ts.comments.lastNotificationID = list[i].ID
+			all = append(all, list[i])
+		}
+		kept := make(map[int]int)
+		for i := len(all) - 1; i >= 0; i-- {
+			if kept[all[i].UserID]++; kept[all[i].UserID] > notificationsKept {
+				all = slices.Delete(all, i, i+1)
+			}
+		}
+		ts.comments.data.Notifications = all
+		return nil
+	})
+}
+
+// UserNotifications возвращает последние limit уведомлений пользователя, новые первыми.
+func (ts *TaskStore) UserNotifications(ctx context.Context, userID int, unreadOnly bool, limit int) ([]UserNotification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadComments(); err != nil {
+		return nil, err
+	}
+	ts.comments.mu.Lock()
+	defer ts.comments.mu.Unlock()
+
+	out := []UserNotification{}
+	all := ts.comments.data.Notifications
+	for i := len(all) - 1; i >= 0 && len(out) < limit; i-- {
+		if all[i].UserID == userID && (!unreadOnly || all[i].ReadAt == nil) {
+			out = append(out, all[i])
+		}
+	}
+	return out, nil
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления пользователя.
+func (ts *TaskStore) MarkNotificationsRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error) {
+	marked := 0
+	err := ts.withComments(ctx, func() error {
+		for i, n := range ts.comments.data.Notifications {
+			if n.UserID != userID || n.ReadAt != nil || (len(ids) > 0 && !slices.Contains(ids, n.ID)) {
+				continue
+			}
+			read := at
+			ts.comments.data.Notifications[i].ReadAt = &read
+			marked++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return marked, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// AddComment сохраняет комментарий в task_comments (migrations/000033_comments.up.sql).
+func (r *PostgresRepository) AddComment(ctx context.Context, c *Comment) error {
+	mentions, err := json.Marshal(intsOrEmpty(c.Mentions))
+	if err != nil {
+		return err
+	}
+	err = r.q.QueryRowContext(ctx, `INSERT INTO task_comments (task_id, author_id, body, mentions, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`, c.TaskID, c.AuthorID, c.Body, mentions, c.CreatedAt).Scan(&c.ID)
+	if isForeignKeyViolation(err) {
+		return ErrTaskNotFound
+	}
+	return err
+}
+
+// Comments возвращает комментарии задачи по порядку добавления.
+func (r *PostgresRepository) Comments(ctx context.Context, taskID int) ([]Comment, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, task_id, author_id, body, mentions, created_at
+		FROM task_comments WHERE task_id = $1 ORDER BY id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Comment{}
+	for rows.Next() {
+		var c Comment
+		var author sql.NullInt64
+		var mentions []byte
+		if err := rows.Scan(&c.ID, &c.TaskID, &author, &c.Body, &mentions, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.AuthorID = int(author.Int64)
+		if err := json.Unmarshal(mentions, &c.Mentions); err != nil {
+			return nil, fmt.Errorf("comment %d mentions: %w", c.ID, err)
+		}
+		if len(c.Mentions) == 0 {
+			c.Mentions = nil
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// AddUserNotifications сохраняет уведомления в user_notifications и удаляет у получателей
+// записи старше последних notificationsKept.
+func (r *PostgresRepository) AddUserNotifications(ctx context.Context, list []UserNotification) error {
+	for i := range list {
+		n := &list[i]
+		err := r.q.QueryRowContext(ctx, `INSERT INTO user_notifications (user_id, kind, task_id, comment_id, actor_id, text, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			n.UserID, n.Kind, n.TaskID, n.CommentID, n.ActorID, n.Text, n.CreatedAt).Scan(&n.ID)
+		if err != nil {
+			return err
+		}
+		_, err = r.q.ExecContext(ctx, `DELETE FROM user_notifications WHERE user_id = $1 AND id <= (
+			SELECT id FROM user_notifications WHERE user_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1)`,
+			n.UserID, notificationsKept)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserNotifications возвращает последние limit уведомлений пользователя, новые первыми.
+func (r *PostgresRepository) UserNotifications(ctx context.Context, userID int, unreadOnly bool, limit int) ([]UserNotification, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, user_id, kind, task_id, comment_id, actor_id, text, created_at, read_at
+		FROM user_notifications WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL) ORDER BY id DESC LIMIT $3`,
+		userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserNotification{}
+	for rows.Next() {
+		var n UserNotification
+		var comment, actor sql.NullInt64
+		var read sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.TaskID, &comment, &actor, &n.Text, &n.CreatedAt, &read); err != nil {
+			return nil, err
+		}
+		n.CommentID, n.ActorID = int(comment.Int64), int(actor.Int64)
+		if read.Valid {
+			n.ReadAt = &read.Time
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления пользователя.
+func (r *PostgresRepository) MarkNotificationsRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error) {
+	res, err := r.q.ExecContext(ctx, `UPDATE user_notifications SET read_at = $2
+		WHERE user_id = $1 AND read_at IS NULL AND (cardinality($3::int[]) = 0 OR id = ANY($3::int[]))`,
+		userID, at, pq.Array(intsOrEmpty(ids)))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// intsOrEmpty -- пустой список вместо nil (в JSONB и массив PostgreSQL -- [], а не null).
+func intsOrEmpty(v []int) []int {
+	if v == nil {
+		return []int{}
+	}
+	return v
+}
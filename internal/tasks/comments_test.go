@@ -0,0 +1,143 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"task-manager/internal/httpclient"
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// recordingNotifier запоминает доставленные уведомления.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []tasks.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg tasks.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// TestCommentMentions -- @username из комментария попадает во входящие упомянутого и в доставку;
+// автор, адреса почты, незнакомые имена и те, кому задача не видна, уведомлений не получают.
+func TestCommentMentions(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := &recordingNotifier{}
+	srv := taskstest.NewServer(store, "", func(_ *tasks.Handler, s *tasks.Service) {
+		s.SetNotifier(notifier)
+	})
+	svc := srv.Service
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	c, err := svc.AddComment(ctx, 1, 1, "@DAD и @kid, гляньте. @mom, mom@example.com и @nobody -- мимо", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Mentions, []int{2, 3}) {
+		t.Errorf("mentions %v, want [2 3]", c.Mentions)
+	}
+	if err := svc.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	notifier.mu.Lock()
+	if len(notifier.sent) != 2 || notifier.sent[0].UserID != 2 || notifier.sent[0].TaskID != 1 {
+		t.Errorf("delivered %+v", notifier.sent)
+	}
+	notifier.mu.Unlock()
+
+	list, err := svc.UserNotifications(ctx, 2, true, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].CommentID != c.ID || list[0].ActorID != 1 || list[0].Kind != tasks.NotificationMention {
+		t.Fatalf("dad's inbox: %+v", list)
+	}
+	if own, _ := svc.UserNotifications(ctx, 1, false, 10); len(own) != 0 {
+		t.Errorf("author got notifications: %+v", own)
+	}
+
+	// Чужое уведомление отметить нельзя, своё -- можно, и только один раз.
+	if n, err := svc.MarkNotificationsRead(ctx, 3, []int{list[0].ID}, now); err != nil || n != 0 {
+		t.Errorf("kid marks dad's notification: %d %v", n, err)
+	}
+	if n, err := svc.MarkNotificationsRead(ctx, 2, []int{list[0].ID}, now); err != nil || n != 1 {
+		t.Errorf("dad marks own notification: %d %v", n, err)
+	}
+	if unread, _ := svc.UserNotifications(ctx, 2, true, 10); len(unread) != 0 {
+		t.Errorf("unread after mark: %+v", unread)
+	}
+
+	// Задача 2 (метка bills) в закрытом проекте dad: kid о ней не узнаёт и через упоминание.
+	if _, err := svc.SetProjectACL(ctx, 1, true, "bills", []int{2}, now); err != nil {
+		t.Fatal(err)
+	}
+	c, err = svc.AddComment(ctx, 2, 2, "@kid, @mom", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Mentions) != 0 {
+		t.Errorf("mentions on a private task: %v, want none", c.Mentions)
+	}
+	if _, err := svc.AddComment(ctx, 2, 3, "можно?", now); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Errorf("kid comments on a hidden task: %v, want ErrTaskNotFound", err)
+	}
+}
+
+// TestCommentAutomationWebhook -- автоматизация "on": "task.commented" получает задачу и комментарий.
+func TestCommentAutomationWebhook(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := httpclient.SetPrivateAllowlist("127.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = httpclient.SetPrivateAllowlist("") })
+
+	got := make(chan map[string]any, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		got <- body
+	}))
+	defer hook.Close()
+
+	srv := taskstest.NewServer(store, "")
+	token, err := srv.Token(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auto := map[string]any{"name": "hook", "on": "task.commented", "do": []map[string]any{{"webhook": hook.URL}}}
+	if code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/automations", token, auto, nil); err != nil || code != http.StatusCreated {
+		t.Fatalf("create automation: %d %v", code, err)
+	}
+	if code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/tasks/2/comments", token, map[string]any{"body": "оплачено"}, nil); err != nil || code != http.StatusCreated {
+		t.Fatalf("create comment: %d %v", code, err)
+	}
+
+	select {
+	case body := <-got:
+		comment, _ := body["comment"].(map[string]any)
+		if body["event"] != tasks.EventTaskCommented || comment["body"] != "оплачено" {
+			t.Errorf("webhook payload %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
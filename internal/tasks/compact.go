@@ -0,0 +1,128 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// Сжатие JSON-хранилища (POST /api/v1/admin/storage/compact, STORAGE_COMPACT_ON_START):
+// файл задач переписывается заново в текущем формате (STORAGE_ENCODING, см. encoding.go), из файла надгробий
+// слитых задач убираются записи, цель которых с тех пор удалена, -- по ним всё равно некуда
+// перенаправлять. Журналы версий и синхронизации чистит политика хранения (retention.go).
+
+var ErrCompactUnsupported = errors.New("compaction is not supported by this storage")
+
+// CompactResult -- итог сжатия: размер файла задач и файла надгробий до и после.
+type CompactResult struct {
+	BeforeBytes      int64 `json:"before_bytes"`
+	AfterBytes       int64 `json:"after_bytes"`
+	Tasks            int   `json:"tasks"`
+	RedirectsRemoved int   `json:"redirects_removed"`
+	CompactJSON      bool  `json:"compact_json"`
+
+	Encoding StorageEncoding `json:"encoding"`
+}
+
+// Compactor -- опциональная возможность хранилища сжать свои файлы.
+type Compactor interface {
+	Compact(ctx context.Context) (CompactResult, error)
+}
+
+// CompactStorage сжимает хранилище задач.
+func (s *Service) CompactStorage(ctx context.Context) (CompactResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CompactResult{}, err
+	}
+	c, ok := s.capabilities().(Compactor)
+	if !ok {
+		return CompactResult{}, ErrCompactUnsupported
+	}
+	return c.Compact(ctx)
+}
+
+// SetCompactJSON включает запись файла задач без отступов: меньше места и быстрее запись,
+// но файл неудобно читать глазами и сравнивать. Вызывать до первого обращения к хранилищу.
+// То же, что SetEncoding(EncodingCompact); выключение возвращает формат с отступами.
+func (ts *TaskStore) SetCompactJSON(on bool) {
+	if on {
+		ts.SetEncoding(EncodingCompact)
+	} else {
+		ts.SetEncoding(EncodingPretty)
+	}
+}
+
+// Compact переписывает файл задач в текущем формате и убирает мёртвые надгробия слитых задач.
+// Запись задач на время сжатия ждёт.
+func (ts *TaskStore) Compact(ctx context.Context) (CompactResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CompactResult{}, err
+	}
+	if err := ts.load(); err != nil {
+		return CompactResult{}, err
+	}
+	if err := ts.loadRedirects(); err != nil {
+		return CompactResult{}, err
+	}
+
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+	ts.redirects.mu.Lock()
+	defer ts.redirects.mu.Unlock()
+
+	enc := ts.Encoding()
+	res := CompactResult{BeforeBytes: ts.compactSize(), CompactJSON: enc != EncodingPretty, Encoding: enc}
+	if err := ts.persistLocked(ctx); err != nil {
+		return CompactResult{}, err
+	}
+	res.Tasks = len(*ts.snapshot.Load())
+
+	dead := []int{}
+	for from, to := range ts.redirects.data {
+		if ts.current(to) == nil {
+			dead = append(dead, from)
+		}
+	}
+	if len(dead) > 0 {
+		prev := make(map[int]int, len(dead))
+		for _, from := range dead {
+			prev[from] = ts.redirects.data[from]
+			delete(ts.redirects.data, from)
+		}
+		if err := ts.saveRedirects(); err != nil {
+			for from, to := range prev {
+				ts.redirects.data[from] = to
+			}
+			return CompactResult{}, err
+		}
+		res.RedirectsRemoved = len(dead)
+	}
+	res.AfterBytes = ts.compactSize()
+	return res, nil
+}
+
+// compactSize -- суммарный размер файла задач и файла надгробий (нет файла -- 0).
+func (ts *TaskStore) compactSize() int64 {
+	if ts.filename == "" {
+		return 0
+	}
+	var n int64
+	for _, name := range []string{ts.filename, ts.redirectsPath()} {
+		if fi, err := os.Stat(name); err == nil {
+			n += fi.Size()
+		}
+	}
+	return n
+}
+
+// Compact сжимает файл задач и коммитит результат отдельным коммитом.
+func (gs *GitStore) Compact(ctx context.Context) (CompactResult, error) {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+
+	res, err := gs.TaskStore.Compact(ctx)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	return res, gs.commit(ctx, "system", "compact storage")
+}
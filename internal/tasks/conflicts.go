@@ -0,0 +1,339 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Очередь конфликтов синхронизации (стратегия manual, см. sync.go): мутация клиента, которая
+// разошлась с сервером, не применяется, а ждёт решения пользователя в /api/v1/conflicts.
+
+// Решения конфликта.
+const (
+	ResolveServer = "server" // оставить версию сервера, мутацию отбросить
+	ResolveClient = "client" // применить мутацию клиента поверх сервера
+	ResolveMerged = "merged" // сохранить задачу, присланную вместе с решением (ручное слияние)
+)
+
+var (
+	ErrConflictsUnsupported = errors.New("conflicts queue is not supported by this storage")
+	ErrConflictNotFound     = errors.New("conflict not found")
+)
+
+// Conflict -- мутация клиента, ждущая решения. Server -- версия сервера на момент конфликта
+// (nil -- задача была удалена), ServerRev -- её ревизия.
+type Conflict struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Mutation  SyncMutation `json:"mutation"`
+	ServerRev int64        `json:"server_rev"`
+	Server    *Task        `json:"server,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// ConflictResolution -- тело POST /api/v1/conflicts/{conflict_id}/resolve. Task -- только для merged.
+type ConflictResolution struct {
+	Resolution string             `json:"resolution" validate:"required,oneof=server client merged"`
+	Task       *UpdateTaskRequest `json:"task" validate:"required_if=Resolution merged"`
+}
+
+// ConflictStore -- опциональная возможность хранилища держать очередь конфликтов.
+type ConflictStore interface {
+	// SaveConflict создаёт конфликт (ID == 0, выдаёт ID) или заменяет конфликт с тем же ID.
+	SaveConflict(ctx context.Context, c *Conflict) error
+	DeleteConflict(ctx context.Context, id int) error
+	// Conflicts -- открытые конфликты пользователя, старые первыми.
+	Conflicts(ctx context.Context, userID int) ([]Conflict, error)
+}
+
+func (s *Service) conflicts() (ConflictStore, error) {
+	cs, ok := s.capabilities().(ConflictStore)
+	if !ok {
+		return nil, ErrConflictsUnsupported
+	}
+	return cs, nil
+}
+
+// queueConflict ставит мутацию в очередь. Повторная мутация той же задачи от того же пользователя
+// (клиент синхронизировался снова, не решив конфликт) заменяет прежнюю: актуальна только последняя.
+func (s *Service) queueConflict(ctx context.Context, userID int, m SyncMutation, rec SyncRecord, cur *Task) (Conflict, error) {
+	store, err := s.conflicts()
+	if err != nil {
+		return Conflict{}, err
+	}
+	list, err := store.Conflicts(ctx, userID)
+	if err != nil {
+		return Conflict{}, err
+	}
+	c := Conflict{UserID: userID, Mutation: m, ServerRev: rec.Rev, Server: cur, CreatedAt: time.Now().UTC()}
+	if i := slices.IndexFunc(list, func(o Conflict) bool { return o.Mutation.UUID == m.UUID }); i >= 0 {
+		c.ID = list[i].ID
+	}
+	if err := store.SaveConflict(ctx, &c); err != nil {
+		return Conflict{}, err
+	}
+	return c, nil
+}
+
+// Conflicts возвращает открытые конфликты пользователя.
+func (s *Service) Conflicts(ctx context.Context, userID int) ([]Conflict, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store, err := s.conflicts()
+	if err != nil {
+		return nil, err
+	}
+	return store.Conflicts(ctx, userID)
+}
+
+// GetConflict возвращает конфликт пользователя по ID.
+func (s *Service) GetConflict(ctx context.Context, userID, id int) (Conflict, error) {
+	list, err := s.Conflicts(ctx, userID)
+	if err != nil {
+		return Conflict{}, err
+	}
+	i := slices.IndexFunc(list, func(c Conflict) bool { return c.ID == id })
+	if i < 0 {
+		return Conflict{}, ErrConflictNotFound
+	}
+	return list[i], nil
+}
+
+// ResolveConflict решает конфликт. Применённое решение (и server) закрывает конфликт; отказ проверок
+// (итог rejected) оставляет его открытым. Решение применяется к текущей версии сервера, даже если
+// задачу успели изменить после конфликта: это явный выбор пользователя.
+func (s *Service) ResolveConflict(ctx context.Context, userID, id int, r ConflictResolution) (SyncResult, error) {
+	c, err := s.GetConflict(ctx, userID, id)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	store, ok := s.capabilities().(RevisionStore)
+	if !ok {
+		return SyncResult{}, ErrSyncUnsupported
+	}
+	cs, err := s.conflicts()
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	lockTimed(&s.syncMu, lockSync)
+	defer s.syncMu.Unlock()
+
+	m := c.Mutation
+	rec, cur, err := s.syncState(ctx, store, m.UUID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	var res SyncResult
+	switch r.Resolution {
+	case ResolveServer:
+		res = SyncResult{UUID: m.UUID, Op: m.Op, Status: SyncDiscarded, Rev: rec.Rev, Task: cur, Deleted: cur == nil}
+	case ResolveMerged:
+		m.Op, m.Task = SyncUpsert, r.Task
+		fallthrough
+	default:
+		if res, err = s.applyMutation(ctx, store, userID, m, rec, cur); err != nil {
+			return SyncResult{}, err
+		}
+	}
+	if res.Status == SyncRejected {
+		res.ConflictID = c.ID
+		return res, nil
+	}
+	if err := cs.DeleteConflict(ctx, c.ID); err != nil {
+		return SyncResult{}, err
+	}
+	return res, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// conflictsFile -- очередь конфликтов JSON-хранилища, рядом с файлом задач.
+type conflictsFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []Conflict
+	lastID int
+}
+
+func (ts *TaskStore) conflictsPath() string {
+	return ts.filename + ".conflicts.json"
+}
+
+func (ts *TaskStore) loadConflicts() error {
+	ts.conflicts.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.conflictsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.conflicts.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.conflicts.data); err != nil {
+			ts.conflicts.err = fmt.Errorf("parse %s: %w", ts.conflictsPath(), err)
+			return
+		}
+		for _, c := range ts.conflicts.data {
+			ts.conflicts.lastID = max(ts.conflicts.lastID, c.ID)
+		}
+	})
+	return ts.conflicts.err
+}
+
+// withConflicts выполняет fn под блокировкой и сохраняет файл; при ошибке записи очередь возвращается к прежней.
+func (ts *TaskStore) withConflicts(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadConflicts(); err != nil {
+		return err
+	}
+	ts.conflicts.mu.Lock()
+	defer ts.conflicts.mu.Unlock()
+
+	prev, prevID := slices.Clone(ts.conflicts.data), ts.conflicts.lastID
+	if err := fn(); err != nil {
+		return err
+	}
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.conflicts.data, "", "   ")
+	if err == nil {
+		tmp := ts.conflictsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0600); err == nil {
+			err = os.Rename(tmp, ts.conflictsPath())
+		}
+	}
+	if err != nil {
+		ts.conflicts.data, ts.conflicts.lastID = prev, prevID
+	}
+	return err
+}
+
+// SaveConflict создаёт конфликт или заменяет конфликт с тем же ID.
+func (ts *TaskStore) SaveConflict(ctx context.Context, c *Conflict) error {
+	return ts.withConflicts(ctx, func() error {
+		if c.ID == 0 {
+			c.ID = ts.conflicts.lastID + 1
+			ts.conflicts.lastID = c.ID
+			ts.conflicts.data = append(ts.conflicts.data, *c)
+			return nil
+		}
+		i := slices.IndexFunc(ts.conflicts.data, func(o Conflict) bool { return o.ID == c.ID })
+		if i < 0 {
+			return ErrConflictNotFound
+		}
+		ts.conflicts.data[i] = *c
+		return nil
+	})
+}
+
+// DeleteConflict удаляет конфликт по ID.
+func (ts *TaskStore) DeleteConflict(ctx context.Context, id int) error {
+	return ts.withConflicts(ctx, func() error {
+		i := slices.IndexFunc(ts.conflicts.data, func(c Conflict) bool { return c.ID == id })
+		if i < 0 {
+			return ErrConflictNotFound
+		}
+		ts.conflicts.data = slices.Delete(ts.conflicts.data, i, i+1)
+		return nil
+	})
+}
+
+// Conflicts возвращает конфликты пользователя в порядке появления.
+func (ts *TaskStore) Conflicts(ctx context.Context, userID int) ([]Conflict, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadConflicts(); err != nil {
+		return nil, err
+	}
+	ts.conflicts.mu.Lock()
+	defer ts.conflicts.mu.Unlock()
+	out := []Conflict{}
+	for _, c := range ts.conflicts.data {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// SaveConflict сохраняет конфликт в sync_conflicts (migrations/000023_sync_conflicts.up.sql).
+// Конфликт лежит в data как JSON.
+func (r *PostgresRepository) SaveConflict(ctx context.Context, c *Conflict) error {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if c.ID == 0 {
+		return r.q.QueryRowContext(ctx, `INSERT INTO sync_conflicts (user_id, data) VALUES ($1, $2) RETURNING id`,
+			c.UserID, raw).Scan(&c.ID)
+	}
+	res, err := r.q.ExecContext(ctx, "UPDATE sync_conflicts SET data = $1 WHERE id = $2", raw, c.ID)
+	return conflictAffected(res, err)
+}
+
+// DeleteConflict удаляет конфликт по ID.
+func (r *PostgresRepository) DeleteConflict(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM sync_conflicts WHERE id = $1", id)
+	return conflictAffected(res, err)
+}
+
+// Conflicts возвращает конфликты пользователя в порядке появления.
+func (r *PostgresRepository) Conflicts(ctx context.Context, userID int) ([]Conflict, error) {
+	rows, err := r.q.QueryContext(ctx, "SELECT id, data FROM sync_conflicts WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Conflict{}
+	for rows.Next() {
+		var c Conflict
+		var id int
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("conflict %d: %w", id, err)
+		}
+		c.ID = id
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func conflictAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrConflictNotFound
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCron -- выражение расписания не разобрано.
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// cronSpec -- разобранное cron-выражение из пяти полей: минута, час, день месяца, месяц, день недели.
+// Каждое поле -- битовая маска допустимых значений.
+//
+// Поддерживается: "*", числа, диапазоны "1-5", списки "1,15", шаги "*/15" и "9-17/2",
+// а также сокращения @hourly, @daily, @weekly, @monthly, @yearly. День недели -- 0..7 (0 и 7 -- воскресенье).
+// Как и в классическом cron, если заданы и день месяца, и день недели, достаточно совпадения любого из них.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// parseCron разбирает cron-выражение.
+func parseCron(expr string) (cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return cronSpec{}, fmt.Errorf("%w: want 5 fields (minute hour day month weekday), got %d", ErrInvalidCron, len(f))
+	}
+
+	var spec cronSpec
+	var err error
+	if spec.minute, err = parseCronField(f[0], 0, 59); err != nil {
+		return cronSpec{}, fmt.Errorf("%w: minute: %v", ErrInvalidCron, err)
+	}
+	if spec.hour, err = parseCronField(f[1], 0, 23); err != nil {
+		return cronSpec{}, fmt.Errorf("%w: hour: %v", ErrInvalidCron, err)
+	}
+	if spec.dom, err = parseCronField(f[2], 1, 31); err != nil {
+		return cronSpec{}, fmt.Errorf("%w: day of month: %v", ErrInvalidCron, err)
+	}
+	if spec.month, err = parseCronField(f[3], 1, 12); err != nil {
+		return cronSpec{}, fmt.Errorf("%w: month: %v", ErrInvalidCron, err)
+	}
+	if spec.dow, err = parseCronField(f[4], 0, 7); err != nil {
+		return cronSpec{}, fmt.Errorf("%w: day of week: %v", ErrInvalidCron, err)
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1 // 7 -- тоже воскресенье
+	}
+	spec.domAny = f[2] == "*"
+	spec.dowAny = f[4] == "*"
+	return spec, nil
+}
+
+// parseCronField разбирает одно поле в битовую маску значений из [lo, hi].
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		from, to := lo, hi
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			from, err1 = strconv.Atoi(a)
+			to, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil || from > to {
+				return 0, fmt.Errorf("bad range %q", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q", rng)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = hi // "5/15" -- с 5 до конца с шагом 15
+			}
+		}
+		if from < lo || to > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next возвращает первый момент строго после after, подходящий под выражение, в часовом поясе after.
+// Нулевое время -- выражение не срабатывает в ближайшие годы (например, "0 0 30 2 *").
+func (c cronSpec) next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Шаги -- до следующего месяца, дня, часа или минуты; пяти лет хватает на любое 29 февраля.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, mo, d := t.Date()
+		var to time.Time
+		switch {
+		case c.month&(1<<int(mo)) == 0:
+			to = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			to = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			to = time.Date(y, mo, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			to = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// Локального времени внутри перехода на летнее время нет, и time.Date может вернуть
+		// момент раньше t -- тогда идём поминутно, чтобы не зациклиться.
+		if !to.After(t) {
+			to = t.Add(time.Minute)
+		}
+		t = to
+	}
+	return time.Time{}
+}
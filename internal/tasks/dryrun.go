@@ -0,0 +1,23 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDryRunUnsupported -- хранилище без транзакций не может выполнить изменение вхолостую.
+var ErrDryRunUnsupported = errors.New("dry run is not supported by this storage")
+
+type dryRunKey struct{}
+
+// WithDryRun помечает контекст пробным запуском: изменения проходят все проверки
+// (валидацию, права, конфликты UUID) в транзакции, которая затем откатывается.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun сообщает, что ctx -- пробный запуск.
+func IsDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"errors"
+	"time"
+)
+
+// Относительные фильтры по сроку для GET /api/v1/tasks?due=...
+// Границы дней считаются в часовом поясе пользователя (настройка timezone).
+const (
+	DueOverdue   = "overdue"    // срок прошёл, задача не выполнена
+	DueToday     = "today"      // срок -- сегодня
+	DueTomorrow  = "tomorrow"   // срок -- завтра
+	DueThisWeek  = "this_week"  // текущая неделя, понедельник..воскресенье
+	DueNextWeek  = "next_week"  // следующая неделя
+	DueThisMonth = "this_month" // текущий календарный месяц
+	DueNext7d    = "next_7d"    // от текущего момента на 7 суток вперёд
+	DueNext30d   = "next_30d"   // от текущего момента на 30 суток вперёд
+	DueNone      = "none"       // без срока
+)
+
+// ErrInvalidDueFilter -- неизвестное значение ?due=.
+var ErrInvalidDueFilter = errors.New("due must be one of overdue, today, tomorrow, this_week, next_week, this_month, next_7d, next_30d, none")
+
+// DueRange переводит относительный фильтр в полуинтервал [from, to) на момент now в поясе loc.
+// Для overdue и none интервала нет -- их обрабатывает FilterByDue.
+func DueRange(filter string, now time.Time, loc *time.Location) (from, to time.Time, err error) {
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	// Неделя начинается с понедельника.
+	week := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+
+	switch filter {
+	case DueToday:
+		return day, day.AddDate(0, 0, 1), nil
+	case DueTomorrow:
+		return day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), nil
+	case DueThisWeek:
+		return week, week.AddDate(0, 0, 7), nil
+	case DueNextWeek:
+		return week.AddDate(0, 0, 7), week.AddDate(0, 0, 14), nil
+	case DueThisMonth:
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		return month, month.AddDate(0, 1, 0), nil
+	case DueNext7d:
+		return now, now.AddDate(0, 0, 7), nil
+	case DueNext30d:
+		return now, now.AddDate(0, 0, 30), nil
+	}
+	return time.Time{}, time.Time{}, ErrInvalidDueFilter
+}
+
+// FilterByDue оставляет задачи, подходящие под относительный фильтр срока (см. константы Due*).
+func FilterByDue(list []Task, filter string, now time.Time, loc *time.Location) ([]Task, error) {
+	var match func(t *Task) bool
+	switch filter {
+	case DueOverdue:
+		match = func(t *Task) bool { return !t.Done && t.Due != nil && t.Due.Before(now) }
+	case DueNone:
+		match = func(t *Task) bool { return t.Due == nil }
+	default:
+		from, to, err := DueRange(filter, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		match = func(t *Task) bool { return t.Due != nil && !t.Due.Before(from) && t.Due.Before(to) }
+	}
+
+	out := list[:0]
+	for i := range list {
+		if match(&list[i]) {
+			out = append(out, list[i])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package tasks
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Формат файла задач JSON-хранилища (STORAGE_ENCODING). Читается файл в любом формате:
+// gzip узнаётся по сигнатуре, так что формат можно сменить без ручной конвертации --
+// файл перейдёт на новый при первом изменении или сжатии (compact.go).
+
+// StorageEncoding -- как файл задач пишется на диск.
+type StorageEncoding string
+
+const (
+	EncodingPretty  StorageEncoding = "pretty"  // JSON с отступами: удобно читать и сравнивать
+	EncodingCompact StorageEncoding = "compact" // JSON без отступов
+	EncodingGzip    StorageEncoding = "gzip"    // JSON без отступов, сжатый gzip (обычно tasks.json.gz)
+)
+
+// gzipMagic -- первые байты любого gzip-потока.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ParseStorageEncoding разбирает название формата (pretty, compact, gzip).
+func ParseStorageEncoding(s string) (StorageEncoding, error) {
+	switch enc := StorageEncoding(s); enc {
+	case EncodingPretty, EncodingCompact, EncodingGzip:
+		return enc, nil
+	}
+	return "", fmt.Errorf("unknown storage encoding %q", s)
+}
+
+// SetEncoding задаёт формат записи файла задач. Вызывать до первого обращения к хранилищу.
+func (ts *TaskStore) SetEncoding(enc StorageEncoding) {
+	ts.encoding = enc
+}
+
+// Encoding -- текущий формат записи файла задач (по умолчанию pretty).
+func (ts *TaskStore) Encoding() StorageEncoding {
+	if ts.encoding == "" {
+		return EncodingPretty
+	}
+	return ts.encoding
+}
+
+// encodeTasks сериализует задачи в заданном формате.
+func encodeTasks(tasks []Task, enc StorageEncoding) ([]byte, error) {
+	switch enc {
+	case EncodingCompact:
+		return json.Marshal(tasks)
+	case EncodingGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if err := json.NewEncoder(zw).Encode(tasks); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return json.MarshalIndent(tasks, "", "   ")
+	}
+}
+
+// decodeTasks читает задачи из файла в любом формате: gzip распаковывается, остальное -- обычный JSON.
+// Пустой файл (в том числе пустой gzip) -- не ошибка, просто нет задач.
+func decodeTasks(data []byte) ([]Task, error) {
+	tasks := []Task{}
+	err := streamTasks(bytes.NewReader(data), func(t *Task) error {
+		tasks = append(tasks, *t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// streamTasks разбирает массив задач из r по одной и отдаёт каждую в fn, не держа в памяти
+// ни весь файл, ни весь массив: на 100k+ задач пик памяти при запуске -- сами задачи в шардах.
+// Формат определяется по первым байтам (gzip -- по сигнатуре). Ошибка fn прерывает разбор.
+func streamTasks(r io.Reader, fn func(*Task) error) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	dec := json.NewDecoder(br)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil // пустой файл
+	}
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null -- тоже нет задач
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("tasks file: expected array, got %v", tok)
+	}
+	for dec.More() {
+		var t Task
+		if err := dec.Decode(&t); err != nil {
+			return err
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil { // закрывающая ]
+		return err
+	}
+	return nil
+}
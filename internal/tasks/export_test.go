@@ -0,0 +1,8 @@
+package tasks
+
+// ResetActiveSettings возвращает встроенные шкалу приоритетов и процесс (как до первого
+// сохранения). Они общие для пакета, а тесты из tasks_test их меняют.
+func ResetActiveSettings() {
+	currentPriorities.Store(nil)
+	currentWorkflow.Store(nil)
+}
@@ -0,0 +1,409 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrStorageDegraded -- основное хранилище недоступно, сервер отдаёт данные из реплики только для чтения.
+var ErrStorageDegraded = errors.New("primary storage is unavailable, serving read-only replica")
+
+// FailoverState -- состояние FailoverStore для /readyz.
+type FailoverState struct {
+	Mode         string     `json:"mode"` // primary или degraded
+	Since        *time.Time `json:"since,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	LastSync     *time.Time `json:"last_sync,omitempty"`
+	ReplicaTasks int        `json:"replica_tasks"`
+}
+
+// replicaSnapshot -- содержимое файла реплики.
+type replicaSnapshot struct {
+	SyncedAt time.Time     `json:"synced_at"`
+	Tasks    []Task        `json:"tasks"`
+	Users    []replicaUser `json:"users"`
+}
+
+// replicaUser -- пользователь в реплике вместе с хэшем пароля (у User он скрыт от JSON API):
+// без него во время аварии никто не смог бы войти.
+type replicaUser struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	DisplayName  string `json:"display_name,omitempty"`
+	Disabled     bool   `json:"disabled,omitempty"` // заблокированный каталогом не войдёт и во время аварии
+}
+
+// FailoverStore -- основное хранилище (PostgreSQL) с локальной JSON-репликой на случай аварии.
+//
+// Пока основное хранилище отвечает, все запросы идут в него, а реплика раз в syncEvery
+// перезаписывается его снимком (задачи и пользователи). Если запрос к основному хранилищу
+// падает из-за связи (соединение отвергнуто или оборвано, БД перезапускается), хранилище
+// переходит в режим degraded: задачи и пользователи читаются из реплики, изменения
+// отклоняются с ErrStorageDegraded (503), а фоновая проверка (Run) ждёт, когда основное
+// хранилище снова ответит. Ошибки предметной области (нет задачи, конфликт) режим не меняют.
+//
+// Дополнительные возможности (заметки, связи, ссылки и т.д.) реплика не хранит:
+// Service обращается за ними прямо к основному хранилищу (см. Primary).
+type FailoverStore struct {
+	primary   TaskRepository
+	path      string
+	ping      func(ctx context.Context) error
+	syncEvery time.Duration
+
+	snap atomic.Pointer[replicaSnapshot]
+
+	mu       sync.Mutex
+	degraded bool
+	since    time.Time
+	lastErr  string
+}
+
+// NewFailoverStore оборачивает primary. Реплика хранится в файле path; если он уже есть,
+// его содержимое доступно сразу -- сервер может стартовать, даже если БД лежит.
+// ping -- проверка связи с основным хранилищем (для PostgreSQL -- db.PingContext).
+func NewFailoverStore(primary TaskRepository, path string, ping func(ctx context.Context) error, syncEvery time.Duration) (*FailoverStore, error) {
+	fs := &FailoverStore{primary: primary, path: path, ping: ping, syncEvery: syncEvery}
+	fs.snap.Store(&replicaSnapshot{})
+
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(raw) > 0 {
+		var snap replicaSnapshot
+		if err := json.Unmarshal(raw, &snap); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		fs.snap.Store(&snap)
+	}
+	return fs, nil
+}
+
+// Primary -- основное хранилище (для опциональных возможностей, которых нет у реплики).
+func (fs *FailoverStore) Primary() TaskRepository {
+	return fs.primary
+}
+
+// State возвращает текущий режим и свежесть реплики.
+func (fs *FailoverStore) State() FailoverState {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	snap := fs.snap.Load()
+	st := FailoverState{Mode: "primary", ReplicaTasks: len(snap.Tasks)}
+	if !snap.SyncedAt.IsZero() {
+		synced := snap.SyncedAt
+		st.LastSync = &synced
+	}
+	if fs.degraded {
+		since := fs.since
+		st.Mode, st.Since, st.Error = "degraded", &since, fs.lastErr
+	}
+	return st
+}
+
+// Degraded возвращает причину работы из реплики или "", если основное хранилище доступно.
+func (fs *FailoverStore) Degraded() string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !fs.degraded {
+		return ""
+	}
+	return "primary storage unavailable: " + fs.lastErr
+}
+
+// Run проверяет основное хранилище (Check) каждые interval, пока не отменён ctx: при аварии переключается
+// на реплику, после восстановления возвращается и сразу обновляет реплику.
+func (fs *FailoverStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fs.Check(ctx)
+		}
+	}
+}
+
+// Check один раз проверяет связь с основным хранилищем и переключает режим.
+// При старте вызывается до самопроверки, чтобы сервер сразу знал, откуда читать.
+func (fs *FailoverStore) Check(ctx context.Context) {
+	pctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	err := fs.ping(pctx)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			fs.markDegraded(err)
+		}
+		return
+	}
+
+	fs.mu.Lock()
+	recovered := fs.degraded
+	fs.degraded, fs.lastErr = false, ""
+	fs.mu.Unlock()
+	if recovered {
+		log.Printf("storage failover: primary storage is back, leaving degraded mode")
+	}
+	if recovered || time.Since(fs.snap.Load().SyncedAt) >= fs.syncEvery {
+		if err := fs.Sync(ctx); err != nil {
+			log.Printf("storage failover: replica sync failed: %v", err)
+		}
+	}
+}
+
+// Sync перезаписывает реплику снимком основного хранилища.
+func (fs *FailoverStore) Sync(ctx context.Context) error {
+	list, err := fs.primary.GetAll(ctx, 0)
+	if err != nil {
+		return err
+	}
+	users, err := fs.primary.GetAllUsers(ctx)
+	if err != nil {
+		return err
+	}
+	snap := &replicaSnapshot{SyncedAt: time.Now().UTC(), Tasks: list, Users: make([]replicaUser, 0, len(users))}
+	for _, u := range users {
+		// GetAllUsers хэшей не отдаёт -- добираем по одному.
+		full, err := fs.primary.GetUserByUsername(ctx, u.Username)
+		if err != nil {
+			return err
+		}
+		snap.Users = append(snap.Users, replicaUser{ID: full.ID, Username: full.Username, PasswordHash: full.PasswordHash,
+			DisplayName: full.DisplayName, Disabled: full.Disabled})
+	}
+
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		return err
+	}
+	fs.snap.Store(snap)
+	return nil
+}
+
+func (fs *FailoverStore) markDegraded(err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.lastErr = err.Error()
+	if fs.degraded {
+		return
+	}
+	fs.degraded, fs.since = true, time.Now().UTC()
+	log.Printf("storage failover: primary storage unavailable (%v), serving read-only replica synced at %s",
+		err, fs.snap.Load().SyncedAt.Format(time.RFC3339))
+}
+
+func (fs *FailoverStore) isDegraded() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.degraded
+}
+
+// isOutage отличает отказ связи с хранилищем от ошибок предметной области и отмены запроса.
+func isOutage(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// 08xxx -- ошибки соединения, 57P0x -- сервер останавливается или перезапускается.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "57P0")
+	}
+	return false
+}
+
+// failoverRead выполняет чтение из основного хранилища, а при аварии -- из реплики.
+func failoverRead[T any](fs *FailoverStore, primary func() (T, error), replica func(*replicaSnapshot) (T, error)) (T, error) {
+	if !fs.isDegraded() {
+		v, err := primary()
+		if !isOutage(err) {
+			return v, err
+		}
+		fs.markDegraded(err)
+	}
+	return replica(fs.snap.Load())
+}
+
+// write выполняет изменение в основном хранилище. В режиме degraded изменения отклоняются.
+func (fs *FailoverStore) write(fn func() error) error {
+	if fs.isDegraded() {
+		return ErrStorageDegraded
+	}
+	err := fn()
+	if isOutage(err) {
+		fs.markDegraded(err)
+		return fmt.Errorf("%w: %v", ErrStorageDegraded, err)
+	}
+	return err
+}
+
+func (fs *FailoverStore) GetAll(ctx context.Context, userID int) ([]Task, error) {
+	return failoverRead(fs, func() ([]Task, error) { return fs.primary.GetAll(ctx, userID) },
+		func(snap *replicaSnapshot) ([]Task, error) {
+			out := make([]Task, len(snap.Tasks))
+			for i := range snap.Tasks {
+				out[i] = *cloneTask(&snap.Tasks[i])
+			}
+			return out, nil
+		})
+}
+
+func (fs *FailoverStore) GetByID(ctx context.Context, id int) (*Task, error) {
+	return failoverRead(fs, func() (*Task, error) { return fs.primary.GetByID(ctx, id) },
+		func(snap *replicaSnapshot) (*Task, error) {
+			for i := range snap.Tasks {
+				if snap.Tasks[i].ID == id {
+					return cloneTask(&snap.Tasks[i]), nil
+				}
+			}
+			return nil, ErrTaskNotFound
+		})
+}
+
+func (fs *FailoverStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return failoverRead(fs, func() (*User, error) { return fs.primary.GetUserByUsername(ctx, username) },
+		func(snap *replicaSnapshot) (*User, error) {
+			for _, u := range snap.Users {
+				if u.Username == username {
+					return &User{ID: u.ID, Username: u.Username, PasswordHash: u.PasswordHash,
+						DisplayName: u.DisplayName, Disabled: u.Disabled}, nil
+				}
+			}
+			return nil, ErrUserNotFound
+		})
+}
+
+func (fs *FailoverStore) GetAllUsers(ctx context.Context) ([]User, error) {
+	return failoverRead(fs, func() ([]User, error) { return fs.primary.GetAllUsers(ctx) },
+		func(snap *replicaSnapshot) ([]User, error) {
+			out := make([]User, 0, len(snap.Users))
+			for _, u := range snap.Users {
+				out = append(out, User{ID: u.ID, Username: u.Username, DisplayName: u.DisplayName, Disabled: u.Disabled})
+			}
+			return out, nil
+		})
+}
+
+func (fs *FailoverStore) Create(ctx context.Context, task *Task) error {
+	return fs.write(func() error { return fs.primary.Create(ctx, task) })
+}
+
+func (fs *FailoverStore) Update(ctx context.Context, task *Task, userID int) error {
+	return fs.write(func() error { return fs.primary.Update(ctx, task, userID) })
+}
+
+func (fs *FailoverStore) Delete(ctx context.Context, id int, userID int) error {
+	return fs.write(func() error { return fs.primary.Delete(ctx, id, userID) })
+}
+
+func (fs *FailoverStore) CreateUser(ctx context.Context, user *User) error {
+	return fs.write(func() error { return fs.primary.CreateUser(ctx, user) })
+}
+
+func (fs *FailoverStore) CreateSubtask(ctx context.Context, subtask *SubTask) error {
+	return fs.write(func() error { return fs.primary.CreateSubtask(ctx, subtask) })
+}
+
+func (fs *FailoverStore) UpdateSubTaskStatus(ctx context.Context, subID int, done bool) error {
+	return fs.write(func() error { return fs.primary.UpdateSubTaskStatus(ctx, subID, done) })
+}
+
+// GetByUUID ищет задачу по UUID в основном хранилище, при аварии -- в реплике.
+func (fs *FailoverStore) GetByUUID(ctx context.Context, uuid string) (*Task, error) {
+	return failoverRead(fs, func() (*Task, error) {
+		if f, ok := fs.primary.(UUIDFinder); ok {
+			return f.GetByUUID(ctx, uuid)
+		}
+		list, err := fs.primary.GetAll(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if list[i].UUID == uuid {
+				return &list[i], nil
+			}
+		}
+		return nil, ErrTaskNotFound
+	}, func(snap *replicaSnapshot) (*Task, error) {
+		for i := range snap.Tasks {
+			if snap.Tasks[i].UUID == uuid {
+				return cloneTask(&snap.Tasks[i]), nil
+			}
+		}
+		return nil, ErrTaskNotFound
+	})
+}
+
+// Begin открывает транзакцию основного хранилища. В режиме degraded транзакций нет.
+func (fs *FailoverStore) Begin(ctx context.Context) (Tx, error) {
+	b, ok := fs.primary.(TxBeginner)
+	if !ok {
+		return nil, errors.New("primary storage does not support transactions")
+	}
+	var tx Tx
+	err := fs.write(func() (err error) {
+		tx, err = b.Begin(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &failoverTx{Tx: tx, fs: fs}, nil
+}
+
+// failoverTx -- транзакция основного хранилища; обрыв связи при коммите переводит хранилище в degraded.
+type failoverTx struct {
+	Tx
+	fs *FailoverStore
+}
+
+func (t *failoverTx) Commit() error {
+	return t.fs.write(t.Tx.Commit)
+}
+
+// StorageDegraded возвращает причину, по которой хранилище работает только на чтение,
+// или "", если хранилище без резервирования или основное хранилище доступно.
+func (s *Service) StorageDegraded() string {
+	if f := s.StorageFault(); f != nil {
+		return f.Hint()
+	}
+	if fs, ok := s.repo.(*FailoverStore); ok {
+		return fs.Degraded()
+	}
+	return ""
+}
@@ -0,0 +1,309 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Внедрение сбоев: FaultStore оборачивает хранилище и по заданным правилам задерживает операции,
+// возвращает ошибки или обрывает запись файла на середине. Нужен, чтобы проверять устойчивость
+// (откат при отказе записи, режим только для чтения, таймауты) без настоящего полного диска.
+// В тестах правила задаются SetFaults, на сервере -- скрытым /api/v1/admin/faults в демо-режиме.
+
+// ErrFaultsUnavailable -- хранилище не обёрнуто FaultStore.
+var ErrFaultsUnavailable = errors.New("fault injection is not enabled")
+
+// Операции, на которые ставятся сбои (Fault.Op).
+const (
+	FaultOpAny           = "*"
+	FaultOpCreate        = "create"
+	FaultOpGet           = "get"
+	FaultOpList          = "list"
+	FaultOpUpdate        = "update"
+	FaultOpDelete        = "delete"
+	FaultOpCreateUser    = "create_user"
+	FaultOpGetUser       = "get_user"
+	FaultOpListUsers     = "list_users"
+	FaultOpCreateSubtask = "create_subtask"
+	FaultOpUpdateSubtask = "update_subtask"
+	FaultOpBegin         = "begin"
+	FaultOpSave          = "save" // запись файла задач (только JSON-хранилище)
+)
+
+// Ошибки, которые умеет внедрять FaultStore (Fault.Error). Дисковые -- настоящие ошибки ОС,
+// поэтому классификация отказов диска (storagefault.go) срабатывает на них как на реальные.
+const (
+	FaultDiskFull   = "disk_full"
+	FaultPermission = "permission_denied"
+	FaultReadOnlyFS = "read_only_fs"
+	FaultIO         = "io"
+)
+
+// Fault -- правило сбоя. Срабатывает на операции Op с вероятностью Rate (0 -- всегда),
+// не больше Count раз (0 -- без ограничения): сначала ждёт LatencyMS, затем возвращает Error.
+// PartialWrite (только для save) перед ошибкой пишет во временный файл начало данных --
+// как если бы процесс упал посреди записи; без Error ошибка -- io.
+type Fault struct {
+	Op           string  `json:"op" validate:"required,oneof=* create get list update delete create_user get_user list_users create_subtask update_subtask begin save"`
+	LatencyMS    int     `json:"latency_ms,omitempty" validate:"min=0,max=60000"`
+	Error        string  `json:"error,omitempty" validate:"omitempty,oneof=disk_full permission_denied read_only_fs io"`
+	PartialWrite bool    `json:"partial_write,omitempty"`
+	Rate         float64 `json:"rate,omitempty" validate:"min=0,max=1"`
+	Count        int     `json:"count,omitempty" validate:"min=0"`
+
+	Fired int `json:"fired"` // сколько раз сработало
+}
+
+// faultInjector -- правила и их срабатывания. Общий у FaultStore и обёрнутого TaskStore (save).
+type faultInjector struct {
+	mu     sync.Mutex
+	faults []Fault
+}
+
+// pick выбирает первое подходящее правило для op и отмечает срабатывание (ok == false -- сбоя нет).
+func (fi *faultInjector) pick(op string) (Fault, bool) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	for i := range fi.faults {
+		f := &fi.faults[i]
+		if f.Op != op && f.Op != FaultOpAny {
+			continue
+		}
+		// Запись файла -- часть мутации: "*" на неё не действует, иначе одна операция сбоила бы дважды.
+		if op == FaultOpSave && f.Op != FaultOpSave {
+			continue
+		}
+		if f.Count > 0 && f.Fired >= f.Count {
+			continue
+		}
+		if f.Rate > 0 && rand.Float64() >= f.Rate {
+			continue
+		}
+		f.Fired++
+		return *f, true
+	}
+	return Fault{}, false
+}
+
+// inject применяет сбой op: задержку (с учётом отмены ctx) и ошибку.
+func (fi *faultInjector) inject(ctx context.Context, op string) error {
+	f, ok := fi.pick(op)
+	if !ok {
+		return nil
+	}
+	if err := f.sleep(ctx); err != nil {
+		return err
+	}
+	return faultError(f.Error, op)
+}
+
+// sleep ждёт LatencyMS или отмены ctx.
+func (f Fault) sleep(ctx context.Context) error {
+	if f.LatencyMS <= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(f.LatencyMS) * time.Millisecond)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// faultError -- ошибка вида kind ("" -- без ошибки, только задержка).
+func faultError(kind, op string) error {
+	var errno syscall.Errno
+	switch kind {
+	case "":
+		return nil
+	case FaultDiskFull:
+		errno = syscall.ENOSPC
+	case FaultPermission:
+		errno = syscall.EACCES
+	case FaultReadOnlyFS:
+		errno = syscall.EROFS
+	default:
+		errno = syscall.EIO
+	}
+	return &fs.PathError{Op: op, Path: "fault-injection", Err: errno}
+}
+
+// saveFault вызывается из SaveTasks перед записью data в tmp. Частичная запись оставляет
+// во временном файле половину данных и возвращает ошибку. У хранилища в памяти tmp пустой:
+// сбой записи возможен, частичная запись -- нет.
+func (fi *faultInjector) saveFault(ctx context.Context, tmp string, data []byte) error {
+	f, ok := fi.pick(FaultOpSave)
+	if !ok {
+		return nil
+	}
+	if err := f.sleep(ctx); err != nil {
+		return err
+	}
+	if f.PartialWrite && tmp != "" {
+		if err := os.WriteFile(tmp, data[:len(data)/2], 0644); err != nil {
+			return err
+		}
+		if f.Error == "" {
+			return faultError(FaultIO, "write")
+		}
+	}
+	return faultError(f.Error, "write")
+}
+
+// FaultStore -- хранилище со сбоями по правилам. Без правил просто передаёт вызовы дальше.
+// Дополнительные возможности (заметки, ревизии и т.д.) Service берёт прямо у обёрнутого
+// хранилища (см. Service.capabilities) -- сбои на них не действуют.
+type FaultStore struct {
+	inner TaskRepository
+	fi    *faultInjector
+}
+
+// NewFaultStore оборачивает inner. Если inner -- JSON-хранилище, сбои save действуют на запись файла.
+// Оборачивать до первого обращения к хранилищу.
+func NewFaultStore(inner TaskRepository) *FaultStore {
+	fs := &FaultStore{inner: inner, fi: &faultInjector{}}
+	if t, ok := inner.(interface{ taskStore() *TaskStore }); ok {
+		t.taskStore().faults = fs.fi
+	}
+	return fs
+}
+
+// taskStore -- сам TaskStore (и у хранилищ, которые его встраивают).
+func (ts *TaskStore) taskStore() *TaskStore { return ts }
+
+// Inner -- обёрнутое хранилище.
+func (fs *FaultStore) Inner() TaskRepository {
+	return fs.inner
+}
+
+// SetFaults заменяет правила (nil -- убрать все сбои). Счётчики срабатываний обнуляются.
+func (fs *FaultStore) SetFaults(faults []Fault) error {
+	for _, f := range faults {
+		if f.Error != "" && f.Error != FaultDiskFull && f.Error != FaultPermission &&
+			f.Error != FaultReadOnlyFS && f.Error != FaultIO {
+			return fmt.Errorf("unknown fault error %q", f.Error)
+		}
+		if f.PartialWrite && f.Op != FaultOpSave {
+			return fmt.Errorf("partial_write works only with op %q", FaultOpSave)
+		}
+	}
+	list := make([]Fault, len(faults))
+	copy(list, faults)
+	for i := range list {
+		list[i].Fired = 0
+	}
+	fs.fi.mu.Lock()
+	defer fs.fi.mu.Unlock()
+	fs.fi.faults = list
+	return nil
+}
+
+// Faults возвращает текущие правила со счётчиками срабатываний.
+func (fs *FaultStore) Faults() []Fault {
+	fs.fi.mu.Lock()
+	defer fs.fi.mu.Unlock()
+	out := make([]Fault, len(fs.fi.faults))
+	copy(out, fs.fi.faults)
+	return out
+}
+
+func (fs *FaultStore) Create(ctx context.Context, task *Task) error {
+	if err := fs.fi.inject(ctx, FaultOpCreate); err != nil {
+		return err
+	}
+	return fs.inner.Create(ctx, task)
+}
+
+func (fs *FaultStore) GetByID(ctx context.Context, id int) (*Task, error) {
+	if err := fs.fi.inject(ctx, FaultOpGet); err != nil {
+		return nil, err
+	}
+	return fs.inner.GetByID(ctx, id)
+}
+
+func (fs *FaultStore) GetAll(ctx context.Context, userID int) ([]Task, error) {
+	if err := fs.fi.inject(ctx, FaultOpList); err != nil {
+		return nil, err
+	}
+	return fs.inner.GetAll(ctx, userID)
+}
+
+func (fs *FaultStore) Update(ctx context.Context, task *Task, userID int) error {
+	if err := fs.fi.inject(ctx, FaultOpUpdate); err != nil {
+		return err
+	}
+	return fs.inner.Update(ctx, task, userID)
+}
+
+func (fs *FaultStore) Delete(ctx context.Context, id int, userID int) error {
+	if err := fs.fi.inject(ctx, FaultOpDelete); err != nil {
+		return err
+	}
+	return fs.inner.Delete(ctx, id, userID)
+}
+
+func (fs *FaultStore) CreateUser(ctx context.Context, user *User) error {
+	if err := fs.fi.inject(ctx, FaultOpCreateUser); err != nil {
+		return err
+	}
+	return fs.inner.CreateUser(ctx, user)
+}
+
+func (fs *FaultStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	if err := fs.fi.inject(ctx, FaultOpGetUser); err != nil {
+		return nil, err
+	}
+	return fs.inner.GetUserByUsername(ctx, username)
+}
+
+func (fs *FaultStore) CreateSubtask(ctx context.Context, subtask *SubTask) error {
+	if err := fs.fi.inject(ctx, FaultOpCreateSubtask); err != nil {
+		return err
+	}
+	return fs.inner.CreateSubtask(ctx, subtask)
+}
+
+func (fs *FaultStore) GetAllUsers(ctx context.Context) ([]User, error) {
+	if err := fs.fi.inject(ctx, FaultOpListUsers); err != nil {
+		return nil, err
+	}
+	return fs.inner.GetAllUsers(ctx)
+}
+
+func (fs *FaultStore) UpdateSubTaskStatus(ctx context.Context, subID int, done bool) error {
+	if err := fs.fi.inject(ctx, FaultOpUpdateSubtask); err != nil {
+		return err
+	}
+	return fs.inner.UpdateSubTaskStatus(ctx, subID, done)
+}
+
+// Begin открывает транзакцию обёрнутого хранилища. Операции внутри транзакции сбоям не подвержены,
+// но запись при Commit JSON-хранилища -- это save.
+func (fs *FaultStore) Begin(ctx context.Context) (Tx, error) {
+	b, ok := fs.inner.(TxBeginner)
+	if !ok {
+		return nil, ErrDryRunUnsupported
+	}
+	if err := fs.fi.inject(ctx, FaultOpBegin); err != nil {
+		return nil, err
+	}
+	return b.Begin(ctx)
+}
+
+// FaultStore возвращает обёртку сбоев хранилища сервиса.
+func (s *Service) FaultStore() (*FaultStore, error) {
+	fs, ok := s.repo.(*FaultStore)
+	if !ok {
+		return nil, ErrFaultsUnavailable
+	}
+	return fs, nil
+}
@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Типы пользовательских полей.
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldDate   = "date" // YYYY-MM-DD
+	FieldBool   = "bool"
+)
+
+// Ограничения пользовательских полей.
+const (
+	maxFieldDefs     = 20
+	maxFieldValueLen = 500
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// FieldDef -- описание пользовательского поля: имя и тип значения.
+type FieldDef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// FieldDefs -- набор полей пространства задач (семьи). Поля, которых нет в наборе, не принимаются.
+type FieldDefs map[string]FieldDef
+
+// ParseFieldDefs разбирает CUSTOM_FIELDS: "customer:string;ticket:number;billable:bool;signed:date".
+func ParseFieldDefs(spec string) (FieldDefs, error) {
+	defs := make(FieldDefs)
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, typ, ok := strings.Cut(part, ":")
+		name, typ = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(typ))
+		if !ok || !fieldNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("custom field %q: want name:type, name -- [a-z][a-z0-9_]*", part)
+		}
+		switch typ {
+		case FieldString, FieldNumber, FieldDate, FieldBool:
+		default:
+			return nil, fmt.Errorf("custom field %q: unknown type %q (want string, number, date or bool)", name, typ)
+		}
+		if _, dup := defs[name]; dup {
+			return nil, fmt.Errorf("custom field %q: defined twice", name)
+		}
+		defs[name] = FieldDef{Name: name, Type: typ}
+	}
+	if len(defs) > maxFieldDefs {
+		return nil, fmt.Errorf("too many custom fields: %d (max %d)", len(defs), maxFieldDefs)
+	}
+	return defs, nil
+}
+
+// List возвращает описания полей по имени.
+func (d FieldDefs) List() []FieldDef {
+	out := make([]FieldDef, 0, len(d))
+	for _, f := range d {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// FieldError -- значение пользовательского поля не прошло проверку.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string { return "custom field " + e.Field + ": " + e.Reason }
+
+// Fields -- значения пользовательских полей задачи: string, float64, bool или дата строкой YYYY-MM-DD.
+// В PostgreSQL хранится в колонке JSONB.
+type Fields map[string]any
+
+// Value -- для записи в PostgreSQL. nil (поля не переданы) пишется как NULL.
+func (f Fields) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return json.Marshal(f)
+}
+
+// Scan -- для чтения из PostgreSQL.
+func (f *Fields) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("fields: unsupported type %T", src)
+	}
+	return json.Unmarshal(data, f)
+}
+
+// validate проверяет значения по описаниям и возвращает их в каноническом виде.
+// nil остаётся nil ("не менять" при обновлении), пустой набор -- пустым ("очистить").
+func (d FieldDefs) validate(in Fields) (Fields, error) {
+	if in == nil {
+		return nil, nil
+	}
+	out := make(Fields, len(in))
+	for name, raw := range in {
+		def, ok := d[name]
+		if !ok {
+			return nil, &FieldError{Field: name, Reason: "unknown field"}
+		}
+		if raw == nil {
+			continue // null -- поле не задано
+		}
+		v, err := def.coerce(raw)
+		if err != nil {
+			return nil, &FieldError{Field: name, Reason: err.Error()}
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// coerce приводит значение из JSON к типу поля.
+func (def FieldDef) coerce(raw any) (any, error) {
+	switch def.Type {
+	case FieldString:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if len([]rune(s)) > maxFieldValueLen {
+			return nil, fmt.Errorf("must be at most %d characters", maxFieldValueLen)
+		}
+		return s, nil
+	case FieldNumber:
+		switch n := raw.(type) {
+		case float64:
+			return n, nil
+		case json.Number:
+			return n.Float64()
+		}
+		return nil, errors.New("must be a number")
+	case FieldBool:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case FieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, errors.New("must be a date YYYY-MM-DD")
+		}
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return nil, errors.New("must be a date YYYY-MM-DD")
+		}
+		return d.Format(time.DateOnly), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", def.Type)
+}
+
+// parseFilter разбирает значение фильтра из строки запроса (?field.<name>=<value>).
+func (def FieldDef) parseFilter(s string) (any, error) {
+	switch def.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case FieldBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	}
+	return def.coerce(s)
+}
+
+// FieldFilterPrefix -- префикс параметров запроса для фильтрации списка по пользовательским полям.
+const FieldFilterPrefix = "field."
+
+// SetFieldDefs задаёт набор пользовательских полей. Вызывать при старте, до обработки запросов.
+func (s *Service) SetFieldDefs(defs FieldDefs) {
+	s.fieldDefs = defs
+}
+
+// FieldDefs возвращает набор пользовательских полей.
+func (s *Service) FieldDefs() FieldDefs {
+	return s.fieldDefs
+}
+
+// FilterByFields оставляет задачи, у которых все поля из filters равны заданным значениям
+// (сравнение с учётом типа поля: ?field.ticket=42 совпадёт с 42.0).
+func (s *Service) FilterByFields(list []Task, filters map[string]string) ([]Task, error) {
+	if len(filters) == 0 {
+		return list, nil
+	}
+	want := make(map[string]any, len(filters))
+	for name, raw := range filters {
+		def, ok := s.fieldDefs[name]
+		if !ok {
+			return nil, &FieldError{Field: name, Reason: "unknown field"}
+		}
+		v, err := def.parseFilter(raw)
+		if err != nil {
+			return nil, &FieldError{Field: name, Reason: err.Error()}
+		}
+		want[name] = v
+	}
+
+	out := list[:0]
+	for _, t := range list {
+		match := true
+		for name, v := range want {
+			if t.Fields[name] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,172 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"task-manager/internal/middleware"
+)
+
+// Тела запросов для начального корпуса: валидные, на грани ограничений и заведомо битые.
+var fuzzTaskSeeds = []string{
+	`{"title":"Купить продукты"}`,
+	`{"title":"Купить продукты","priority":"high","status":"in_progress","tags":["shopping","home"],"due":"2024-01-15T09:00:00Z"}`,
+	`{"title":"  пробелы ​ вокруг  ","description":"строка\r\nвторая","tags":[" Shopping ","shopping"]}`,
+	`{"title":"x","uuid":"00000000-0000-4000-8000-000000000001","estimate_minutes":60000,"fields":{"room":"кухня"}}`,
+	`{"title":"x","due_in_business_days":3}`,
+	`{"title":"x","due":"2024-01-15T09:00:00Z","due_in_business_days":3}`,
+	`{"title":""}`,
+	`{"title":"x","priority":"urgent"}`,
+	`{"title":"x","status":"??"}`,
+	`{"title":"x","colour":"red"}`,
+	`{"title":"x"} {"title":"y"}`,
+	`{"title":"\xff\xfe"}`,
+	`{"title":null,"tags":null}`,
+	`[]`,
+	`null`,
+	``,
+}
+
+func newFuzzRequest(method string, body []byte, params map[string]string) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/tasks", bytes.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, 1)
+	return r.WithContext(ctx)
+}
+
+// checkTaskText -- то, что гарантирует пара decodeJSONStrict + validate.Struct для прошедшего тела:
+// валидный UTF-8, нормализованный текст и длины в пределах DefaultValidationLimits.
+func checkTaskText(t *testing.T, title, description string, tags []string) {
+	t.Helper()
+	l := DefaultValidationLimits
+	if title == "" || !utf8.ValidString(title) || utf8.RuneCountInString(title) > l.TitleMax {
+		t.Fatalf("title passed validation: %q", title)
+	}
+	if NormalizeText(title) != title {
+		t.Fatalf("title is not normalized: %q", title)
+	}
+	if !utf8.ValidString(description) || utf8.RuneCountInString(description) > l.DescriptionMax {
+		t.Fatalf("description passed validation: %q", description)
+	}
+	if len(tags) > l.TagsMax {
+		t.Fatalf("%d tags passed validation", len(tags))
+	}
+	for _, tag := range tags {
+		if !utf8.ValidString(tag) || utf8.RuneCountInString(tag) > l.TagMax {
+			t.Fatalf("tag passed validation: %q", tag)
+		}
+	}
+}
+
+// FuzzCreateTaskRequest: тело POST /api/v1/tasks. Прошедшее декодер и валидатор соблюдает ограничения,
+// не прошедшее не доходит до хранилища; обработчик не отвечает 5xx ни на какой ввод.
+func FuzzCreateTaskRequest(f *testing.F) {
+	for _, s := range fuzzTaskSeeds {
+		f.Add([]byte(s))
+	}
+	ctx := context.Background()
+	store := NewMemoryStore()
+	h := NewHandler(NewService(store))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var req CreateTaskRequest
+		valid := decodeJSONStrict(newFuzzRequest(http.MethodPost, body, nil), &req) == nil && h.validate.Struct(req) == nil
+		if valid {
+			checkTaskText(t, req.Title, req.Description, req.Tags)
+			if req.Priority != "" && !req.Priority.Valid() && req.Priority.Class() == "" {
+				t.Fatalf("priority passed validation: %q", req.Priority)
+			}
+		}
+
+		before := store.all()
+		rec := httptest.NewRecorder()
+		h.createTask(rec, newFuzzRequest(http.MethodPost, body, nil))
+		after := store.all()
+
+		switch {
+		case rec.Code >= http.StatusInternalServerError:
+			t.Fatalf("status %d for %q: %s", rec.Code, body, rec.Body)
+		case !valid && rec.Code < http.StatusBadRequest:
+			t.Fatalf("invalid body accepted with %d: %q", rec.Code, body)
+		case rec.Code == http.StatusCreated && len(after) != len(before)+1:
+			t.Fatalf("created, but store has %d tasks, was %d", len(after), len(before))
+		case rec.Code != http.StatusCreated && len(after) != len(before):
+			t.Fatalf("status %d, but store has %d tasks, was %d", rec.Code, len(after), len(before))
+		}
+		if rec.Code == http.StatusCreated {
+			var created Task
+			if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+				t.Fatalf("decode created task: %v", err)
+			}
+			checkTaskText(t, created.Title, created.Description, created.Tags)
+			// хранилище не растёт от итерации к итерации
+			if err := store.Delete(ctx, created.ID, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+// FuzzUpdateTaskRequest: тело PUT /api/v1/tasks/{id}. Не прошедшее декодер и валидатор
+// не меняет задачу в хранилище; обработчик не отвечает 5xx ни на какой ввод.
+func FuzzUpdateTaskRequest(f *testing.F) {
+	for _, s := range fuzzTaskSeeds {
+		f.Add([]byte(s))
+	}
+	f.Add([]byte(`{"title":"x","priority":"low","done":true}`))
+	f.Add([]byte(`{"title":"x","status":"done","done":false}`))
+	ctx := context.Background()
+	store := NewMemoryStore()
+	h := NewHandler(NewService(store))
+	seed := Task{UserID: 1, AssignedTo: 1, Title: "Исходная", Status: StatusTodo, Priority: PriorityMedium}
+	if err := store.Create(ctx, &seed); err != nil {
+		f.Fatal(err)
+	}
+	params := map[string]string{"id": strconv.Itoa(seed.ID)}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var req UpdateTaskRequest
+		valid := decodeJSONStrict(newFuzzRequest(http.MethodPut, body, params), &req) == nil && h.validate.Struct(req) == nil
+		if valid {
+			checkTaskText(t, req.Title, req.Description, req.Tags)
+			if !req.Priority.Valid() && req.Priority.Class() == "" {
+				t.Fatalf("priority passed validation: %q", req.Priority)
+			}
+		}
+
+		before, err := store.GetByID(ctx, seed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		h.updateTask(rec, newFuzzRequest(http.MethodPut, body, params))
+		after, err := store.GetByID(ctx, seed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case rec.Code >= http.StatusInternalServerError:
+			t.Fatalf("status %d for %q: %s", rec.Code, body, rec.Body)
+		case !valid && rec.Code < http.StatusBadRequest:
+			t.Fatalf("invalid body accepted with %d: %q", rec.Code, body)
+		case rec.Code != http.StatusOK && !reflect.DeepEqual(before, after):
+			t.Fatalf("status %d, but task changed:\nbefore %+v\nafter  %+v", rec.Code, before, after)
+		case rec.Code == http.StatusOK:
+			checkTaskText(t, after.Title, after.Description, after.Tags)
+		}
+	})
+}
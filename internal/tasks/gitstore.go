@@ -0,0 +1,211 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRevisionNotFound -- запрошенной ревизии нет в истории git.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revPattern ограничивает формат ревизии короткими/полными хэшами коммитов.
+// Так пользовательский ввод никогда не попадёт в git как опция (--foo) или выражение (HEAD~1).
+var revPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// Revision -- одна запись истории хранилища (один git-коммит).
+type Revision struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// GitStore -- файловое хранилище, которое после каждой мутации коммитит tasks.json
+// в локальный git-репозиторий. Получаем полную историю, diff и восстановление средствами git.
+//
+// Чтение делегируется обычному TaskStore, а мутации сериализуются отдельным мьютексом,
+// чтобы "записать файл + закоммитить" было одним шагом.
+type GitStore struct {
+	*TaskStore
+
+	mu   sync.Mutex
+	dir  string // Каталог git-репозитория (каталог, где лежит файл задач)
+	file string // Имя файла задач относительно dir
+}
+
+// NewGitStore создаёт хранилище и при необходимости инициализирует git-репозиторий
+// в каталоге файла задач.
+func NewGitStore(filename string) (*GitStore, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	gs := &GitStore{
+		TaskStore: NewTaskStore(filename),
+		dir:       filepath.Dir(abs),
+		file:      filepath.Base(abs),
+	}
+
+	ctx := context.Background()
+	if _, err := os.Stat(filepath.Join(gs.dir, ".git")); os.IsNotExist(err) {
+		if _, err := gs.git(ctx, "init", "--quiet"); err != nil {
+			return nil, fmt.Errorf("git init %s: %w", gs.dir, err)
+		}
+	}
+
+	// Фиксируем исходное состояние файла, если он уже существует и ещё не закоммичен.
+	if _, err := os.Stat(abs); err == nil {
+		if err := gs.commit(ctx, "system", "initial import"); err != nil {
+			return nil, err
+		}
+	}
+
+	return gs, nil
+}
+
+func (gs *GitStore) Create(ctx context.Context, task *Task) error {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+
+	if err := gs.TaskStore.Create(ctx, task); err != nil {
+		return err
+	}
+	return gs.commit(ctx, userAuthor(task.UserID), fmt.Sprintf("create task %d", task.ID))
+}
+
+func (gs *GitStore) Update(ctx context.Context, task *Task, userID int) error {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+
+	if err := gs.TaskStore.Update(ctx, task, userID); err != nil {
+		return err
+	}
+	return gs.commit(ctx, userAuthor(userID), fmt.Sprintf("update task %d", task.ID))
+}
+
+func (gs *GitStore) Delete(ctx context.Context, id int, userID int) error {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+
+	if err := gs.TaskStore.Delete(ctx, id, userID); err != nil {
+		return err
+	}
+	return gs.commit(ctx, userAuthor(userID), fmt.Sprintf("delete task %d", id))
+}
+
+// History возвращает последние limit коммитов файла задач (новые -- первыми).
+func (gs *GitStore) History(ctx context.Context, limit int) ([]Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Поля разделяем \x1f, коммиты -- \x1e: так сообщение коммита не сломает разбор.
+	out, err := gs.git(ctx, "log", fmt.Sprintf("-n%d", limit),
+		"--format=%H%x1f%an%x1f%aI%x1f%s%x1e", "--", gs.file)
+	if err != nil {
+		// В пустом репозитории ещё нет HEAD -- это просто пустая история.
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return []Revision{}, nil
+		}
+		return nil, err
+	}
+
+	revs := make([]Revision, 0, limit)
+	for _, rec := range strings.Split(string(out), "\x1e") {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		f := strings.Split(rec, "\x1f")
+		if len(f) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, f[2])
+		revs = append(revs, Revision{Hash: f[0], Author: f[1], Date: date, Message: f[3]})
+	}
+	return revs, nil
+}
+
+// TasksAt возвращает содержимое хранилища на момент ревизии rev.
+func (gs *GitStore) TasksAt(ctx context.Context, rev string) ([]Task, error) {
+	if !revPattern.MatchString(rev) {
+		return nil, ErrRevisionNotFound
+	}
+
+	out, err := gs.git(ctx, "show", rev+":"+gs.file)
+	if err != nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	// Ревизия могла быть записана в другом формате (encoding.go) -- decodeTasks читает любой.
+	tasks, err := decodeTasks(out)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s at %s: %w", gs.file, rev, err)
+	}
+	return tasks, nil
+}
+
+// Diff возвращает unified diff файла задач, внесённый ревизией rev.
+func (gs *GitStore) Diff(ctx context.Context, rev string) (string, error) {
+	if !revPattern.MatchString(rev) {
+		return "", ErrRevisionNotFound
+	}
+
+	// --root позволяет получить diff и для самого первого коммита.
+	out, err := gs.git(ctx, "show", "--root", "--format=", rev, "--", gs.file)
+	if err != nil {
+		return "", ErrRevisionNotFound
+	}
+	return string(out), nil
+}
+
+// commit индексирует файл задач и коммитит его от имени author.
+// Если изменений нет, коммит не создаётся.
+func (gs *GitStore) commit(ctx context.Context, author, message string) error {
+	if _, err := gs.git(ctx, "add", "--", gs.file); err != nil {
+		return err
+	}
+
+	// diff --cached --quiet завершается с кодом 0, если в индексе нет изменений.
+	if _, err := gs.git(ctx, "diff", "--cached", "--quiet", "--", gs.file); err == nil {
+		return nil
+	}
+
+	_, err := gs.git(ctx, "commit", "--quiet",
+		"--author", fmt.Sprintf("%s <%s@task-manager.local>", author, author),
+		"-m", message, "--", gs.file)
+	return err
+}
+
+// git запускает git в каталоге репозитория и возвращает stdout.
+// Коммиттер задаётся явно, чтобы не зависеть от глобального git config на сервере.
+func (gs *GitStore) git(ctx context.Context, args ...string) ([]byte, error) {
+	full := append([]string{"-C", gs.dir,
+		"-c", "user.name=task-manager",
+		"-c", "user.email=task-manager@task-manager.local"}, args...)
+
+	cmd := exec.CommandContext(ctx, "git", full...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// userAuthor -- имя автора коммита для аутентифицированного пользователя.
+func userAuthor(userID int) string {
+	return fmt.Sprintf("user-%d", userID)
+}
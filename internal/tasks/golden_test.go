@@ -0,0 +1,501 @@
+package tasks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// go test ./internal/tasks -run TestGolden -update -- перезаписать testdata/golden/*.golden.
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// goldenCase -- один запрос к API. Подстановки {name} в path и body берутся из vars,
+// save -- какие поля JSON-ответа запомнить для следующих запросов (имя переменной -> поле).
+type goldenCase struct {
+	name   string
+	method string
+	path   string
+	user   int    // 0 -- без токена
+	apiKey bool   // X-API-Key вместо токена
+	body   string // JSON как есть
+	save   map[string]string
+}
+
+// goldenCases идут по порядку на одном сервере: каждый видит изменения предыдущих.
+var goldenCases = []goldenCase{
+	// Вход и учётная запись
+	{name: "auth_register", method: "POST", path: "/api/v1/auth/register", body: `{"username":"grandma","password":"secret-password"}`},
+	{name: "auth_login", method: "POST", path: "/api/v1/auth/login", body: `{"username":"mom","password":"password"}`},
+	{name: "auth_login_wrong_password", method: "POST", path: "/api/v1/auth/login", body: `{"username":"mom","password":"nope"}`},
+	{name: "auth_set_email", method: "PUT", path: "/api/v1/auth/email", user: 1, body: `{"email":"mom@example.com","password":"password"}`},
+	{name: "auth_password_forgot", method: "POST", path: "/api/v1/auth/password/forgot", body: `{"login":"mom"}`},
+	{name: "auth_password_reset_bad_token", method: "POST", path: "/api/v1/auth/password/reset", body: `{"token":"bogus","new_password":"another-password"}`},
+	{name: "auth_password_change", method: "POST", path: "/api/v1/auth/password", user: 3, body: `{"current_password":"password","new_password":"Zebra-Lantern-42"}`},
+
+	// Задачи
+	{name: "tasks_list_unauthorized", method: "GET", path: "/api/v1/tasks"},
+	{name: "tasks_list", method: "GET", path: "/api/v1/tasks", user: 1},
+	{name: "tasks_list_sort_priority", method: "GET", path: "/api/v1/tasks?sort=-priority", user: 1},
+	{name: "tasks_users", method: "GET", path: "/api/v1/tasks/users", user: 1},
+	{name: "tasks_fields", method: "GET", path: "/api/v1/tasks/fields", user: 1},
+	{name: "tasks_limits", method: "GET", path: "/api/v1/tasks/limits", user: 1},
+	{name: "tasks_get", method: "GET", path: "/api/v1/tasks/1", user: 1},
+	{name: "tasks_get_not_found", method: "GET", path: "/api/v1/tasks/999", user: 1},
+	{name: "tasks_get_bad_id", method: "GET", path: "/api/v1/tasks/abc", user: 1},
+	{name: "tasks_create", method: "POST", path: "/api/v1/tasks", user: 1,
+		body: `{"title":"Полить цветы","priority":"low","due":"2024-01-20T18:00:00Z","tags":["home"]}`},
+	{name: "tasks_create_invalid", method: "POST", path: "/api/v1/tasks", user: 1, body: `{"title":""}`},
+	{name: "tasks_create_unknown_field", method: "POST", path: "/api/v1/tasks", user: 1, body: `{"title":"x","colour":"red"}`},
+	{name: "tasks_update", method: "PUT", path: "/api/v1/tasks/6", user: 1,
+		body: `{"title":"Полить цветы на балконе","priority":"medium","due":"2024-01-21T18:00:00Z","tags":["home"]}`},
+	{name: "tasks_subtask_create", method: "POST", path: "/api/v1/tasks/6/subtasks", user: 1, body: `{"title":"Фикус"}`, save: map[string]string{"sub": "id"}},
+	{name: "tasks_subtask_status", method: "PUT", path: "/api/v1/tasks/subtasks/{sub}", user: 1, body: `{"done":true}`},
+	{name: "tasks_import_taskwarrior", method: "POST", path: "/api/v1/tasks/import?format=taskwarrior", user: 1,
+		body: `[{"uuid":"11111111-1111-4111-8111-111111111111","description":"Из Taskwarrior","status":"pending","entry":"20240110T090000Z","priority":"H"}]`},
+	{name: "tasks_import_unknown_format", method: "POST", path: "/api/v1/tasks/import?format=nope", user: 1, body: `[]`},
+	{name: "tasks_reschedule", method: "POST", path: "/api/v1/tasks/reschedule", user: 1, body: `{"filter":{"ids":[1]},"shift":"1d"}`},
+	{name: "tasks_merge", method: "POST", path: "/api/v1/tasks/6/merge", user: 1, body: `{"source_ids":[7]}`},
+	{name: "tasks_merge_self", method: "POST", path: "/api/v1/tasks/6/merge", user: 1, body: `{"source_ids":[6]}`},
+
+	// Связи
+	{name: "relations_create", method: "POST", path: "/api/v1/tasks/1/relations", user: 1, body: `{"type":"blocks","task_id":3}`, save: map[string]string{"rel": "id"}},
+	{name: "relations_list", method: "GET", path: "/api/v1/tasks/1/relations", user: 1},
+	{name: "relations_graph", method: "GET", path: "/api/v1/tasks/graph", user: 1},
+	{name: "relations_delete", method: "DELETE", path: "/api/v1/tasks/1/relations/{rel}", user: 1},
+
+	// Комментарии и упоминания
+	{name: "comments_create", method: "POST", path: "/api/v1/tasks/1/comments", user: 1, body: `{"body":"@dad, купи заодно батарейки"}`},
+	{name: "comments_create_empty", method: "POST", path: "/api/v1/tasks/1/comments", user: 1, body: `{"body":""}`},
+	{name: "comments_list", method: "GET", path: "/api/v1/tasks/1/comments", user: 2},
+	{name: "comments_task_not_found", method: "GET", path: "/api/v1/tasks/999/comments", user: 1},
+
+	// Ссылки на задачу и действия по ссылке
+	{name: "shares_create", method: "POST", path: "/api/v1/tasks/1/shares", user: 1, body: `{"access":"write","expires_in":"72h"}`,
+		save: map[string]string{"share": "id", "share_token": "token"}},
+	{name: "shares_list", method: "GET", path: "/api/v1/tasks/1/shares", user: 1},
+	{name: "share_get", method: "GET", path: "/share/{share_token}/"},
+	{name: "share_update", method: "PUT", path: "/share/{share_token}/", body: `{"title":"Купить продукты и хлеб","priority":"high","tags":["shopping"]}`},
+	{name: "share_bad_token", method: "GET", path: "/share/bogus/"},
+	{name: "shares_delete", method: "DELETE", path: "/api/v1/tasks/1/shares/{share}", user: 1},
+	{name: "action_links_create", method: "POST", path: "/api/v1/tasks/3/action-links", user: 1, body: `{"action":"done"}`,
+		save: map[string]string{"act": "url"}},
+	{name: "action_confirm", method: "GET", path: "{act}"},
+	{name: "action_apply", method: "POST", path: "{act}"},
+	{name: "action_apply_again", method: "POST", path: "{act}"},
+
+	// Версии задачи
+	{name: "revisions_list", method: "GET", path: "/api/v1/tasks/6/revisions", user: 1},
+	{name: "revisions_get", method: "GET", path: "/api/v1/tasks/6/revisions/1", user: 1},
+	{name: "revisions_diff", method: "GET", path: "/api/v1/tasks/6/revisions/2/diff", user: 1},
+	{name: "revisions_restore", method: "POST", path: "/api/v1/tasks/6/revisions/1/restore", user: 1},
+
+	// Интеграции (Zapier и т.п.)
+	{name: "integrations_no_key", method: "GET", path: "/api/v1/integrations/triggers/new-tasks"},
+	{name: "integrations_new_tasks", method: "GET", path: "/api/v1/integrations/triggers/new-tasks", apiKey: true},
+	{name: "integrations_completed_tasks", method: "GET", path: "/api/v1/integrations/triggers/completed-tasks", apiKey: true},
+	{name: "integrations_create_task", method: "POST", path: "/api/v1/integrations/actions/create-task", apiKey: true, body: `{"title":"Из Zapier"}`},
+	{name: "integrations_complete_task", method: "POST", path: "/api/v1/integrations/actions/complete-task", apiKey: true, body: `{"task_id":1}`},
+
+	// Профиль
+	{name: "me_preferences", method: "GET", path: "/api/v1/me/preferences", user: 1},
+	{name: "me_preferences_update", method: "PUT", path: "/api/v1/me/preferences", user: 1,
+		body: `{"timezone":"Europe/Moscow","locale":"ru","default_priority":"high","digest_time":"08:30"}`},
+	{name: "me_sessions", method: "GET", path: "/api/v1/me/sessions", user: 1},
+	{name: "me_session_revoke", method: "DELETE", path: "/api/v1/me/sessions/999", user: 1},
+	{name: "me_sessions_revoke", method: "DELETE", path: "/api/v1/me/sessions", user: 2},
+	{name: "me_notifications", method: "GET", path: "/api/v1/me/notifications", user: 2},
+	{name: "me_notifications_read", method: "POST", path: "/api/v1/me/notifications/read", user: 2, body: `{}`},
+	{name: "me_notifications_unread", method: "GET", path: "/api/v1/me/notifications?unread=true", user: 2},
+
+	// Заметки и повестка
+	{name: "notes_save", method: "PUT", path: "/api/v1/notes/2024-01-15", user: 1, body: `{"body":"# План\n- купить хлеб"}`},
+	{name: "notes_get", method: "GET", path: "/api/v1/notes/2024-01-15", user: 1},
+	{name: "notes_list", method: "GET", path: "/api/v1/notes?from=2024-01-01&to=2024-01-31", user: 1},
+	{name: "notes_bad_date", method: "GET", path: "/api/v1/notes/15-01-2024", user: 1},
+	{name: "notes_delete", method: "DELETE", path: "/api/v1/notes/2024-01-15", user: 1},
+	{name: "agenda", method: "GET", path: "/api/v1/agenda?date=2024-01-15", user: 1},
+	{name: "agenda_pdf", method: "GET", path: "/api/v1/agenda/pdf?date=2024-01-15", user: 1},
+
+	// Публичные доски
+	{name: "boards_create", method: "POST", path: "/api/v1/boards", user: 1, body: `{"name":"Покупки","tag":"shopping"}`,
+		save: map[string]string{"board": "id", "board_token": "token"}},
+	{name: "boards_list", method: "GET", path: "/api/v1/boards", user: 1},
+	{name: "board_public", method: "GET", path: "/board/{board_token}/"},
+	{name: "board_public_bad_token", method: "GET", path: "/board/bogus/"},
+	{name: "boards_delete", method: "DELETE", path: "/api/v1/boards/{board}", user: 1},
+
+	// Правила
+	{name: "rules_create", method: "POST", path: "/api/v1/rules", user: 1,
+		body: `{"name":"Просрочки -- срочно","when":{"overdue_days":0},"then":{"set_priority":"high"}}`, save: map[string]string{"rule": "id"}},
+	{name: "rules_list", method: "GET", path: "/api/v1/rules", user: 1},
+	{name: "rules_get", method: "GET", path: "/api/v1/rules/{rule}", user: 1},
+	{name: "rules_update", method: "PUT", path: "/api/v1/rules/{rule}", user: 1,
+		body: `{"name":"Просрочки -- срочно","enabled":false,"when":{"overdue_days":1},"then":{"set_priority":"critical"}}`},
+	{name: "rules_delete", method: "DELETE", path: "/api/v1/rules/{rule}", user: 1},
+
+	// Автоматизации
+	{name: "automations_create", method: "POST", path: "/api/v1/automations", user: 1,
+		body: `{"name":"Покупки -- папе","on":"task.created","if":{"tag":"shopping"},"do":[{"assign":2}]}`, save: map[string]string{"automation": "id"}},
+	{name: "automations_list", method: "GET", path: "/api/v1/automations", user: 1},
+	{name: "automations_get", method: "GET", path: "/api/v1/automations/{automation}", user: 1},
+	{name: "automations_update", method: "PUT", path: "/api/v1/automations/{automation}", user: 1,
+		body: `{"name":"Покупки -- папе","enabled":false,"on":"task.created","if":{"tag":"shopping"},"do":[{"assign":2}]}`},
+	{name: "automations_runs", method: "GET", path: "/api/v1/automations/{automation}/runs", user: 1},
+	{name: "automations_delete", method: "DELETE", path: "/api/v1/automations/{automation}", user: 1},
+
+	// Повторяющиеся задачи по расписанию
+	{name: "schedules_create", method: "POST", path: "/api/v1/schedules", user: 1,
+		body: `{"name":"Мусор","cron":"0 20 * * 1,4","timezone":"UTC","task":{"title":"Вынести мусор"}}`, save: map[string]string{"schedule": "id"}},
+	{name: "schedules_list", method: "GET", path: "/api/v1/schedules", user: 1},
+	{name: "schedules_get", method: "GET", path: "/api/v1/schedules/{schedule}", user: 1},
+	{name: "schedules_occurrences", method: "GET", path: "/api/v1/schedules/{schedule}/occurrences?limit=3", user: 1,
+		save: map[string]string{"run": "1.at"}},
+	{name: "schedules_exception_set", method: "PUT", path: "/api/v1/schedules/{schedule}/exceptions/{run}", user: 1,
+		body: `{"task":{"title":"Вынести мусор и стекло","priority":"high"}}`},
+	{name: "schedules_exception_delete", method: "DELETE", path: "/api/v1/schedules/{schedule}/exceptions/{run}", user: 1},
+	{name: "schedules_exception_delete_missing", method: "DELETE", path: "/api/v1/schedules/{schedule}/exceptions/{run}", user: 1},
+	{name: "schedules_update", method: "PUT", path: "/api/v1/schedules/{schedule}", user: 1,
+		body: `{"name":"Мусор","cron":"0 21 * * 1,4","timezone":"UTC","enabled":false,"task":{"title":"Вынести мусор"}}`},
+	{name: "schedules_delete", method: "DELETE", path: "/api/v1/schedules/{schedule}", user: 1},
+
+	// Рабочий календарь
+	{name: "calendar_get", method: "GET", path: "/api/v1/calendar", user: 1},
+	{name: "calendar_update_forbidden", method: "PUT", path: "/api/v1/calendar", user: 2,
+		body: `{"timezone":"UTC","work_days":["mon","tue"],"work_start":"09:00","work_end":"18:00"}`},
+	{name: "calendar_update", method: "PUT", path: "/api/v1/calendar", user: 1,
+		body: `{"timezone":"UTC","work_days":["mon","tue","wed","thu","fri"],"work_start":"09:00","work_end":"18:00","holidays":[{"date":"2024-01-01","name":"Новый год"}]}`},
+	{name: "calendar_due", method: "GET", path: "/api/v1/calendar/due?from=2024-01-15T09:00:00Z&business_days=3", user: 1},
+
+	// Процесс и шкала приоритетов
+	{name: "workflow_get", method: "GET", path: "/api/v1/workspaces/default/workflow", user: 1},
+	{name: "workflow_update", method: "PUT", path: "/api/v1/workspaces/default/workflow", user: 1,
+		body: `{"statuses":[{"id":"todo","category":"todo"},{"id":"doing","label":"В работе","category":"in_progress"},{"id":"done","category":"done"}],"initial":"todo","remap":{"in_progress":"doing"}}`},
+	{name: "workflow_unknown_workspace", method: "GET", path: "/api/v1/workspaces/other/workflow", user: 1},
+	{name: "priorities_get", method: "GET", path: "/api/v1/priorities", user: 1},
+	{name: "priorities_update", method: "PUT", path: "/api/v1/priorities", user: 1,
+		body: `{"levels":[{"id":"p0","class":"critical"},{"id":"p1","class":"high"},{"id":"p2","class":"medium"},{"id":"p3","class":"low"}],"default":"p2"}`},
+
+	// Закрытые проекты
+	{name: "projects_acl_update", method: "PUT", path: "/api/v1/projects/bills/acl", user: 1, body: `{"members":[2]}`},
+	{name: "projects_list", method: "GET", path: "/api/v1/projects", user: 1},
+	{name: "projects_hidden_task", method: "GET", path: "/api/v1/tasks/2", user: 3},
+	{name: "projects_acl_delete", method: "DELETE", path: "/api/v1/projects/bills/acl", user: 1},
+
+	// Отчёты
+	{name: "reports_capacity", method: "GET", path: "/api/v1/reports/capacity?from=2024-01-15&to=2024-01-21", user: 1},
+	{name: "reports_burndown", method: "GET", path: "/api/v1/reports/burndown?from=2024-01-08&to=2024-01-21", user: 1},
+	{name: "reports_project_pdf", method: "GET", path: "/api/v1/reports/project.pdf?tag=shopping", user: 1},
+
+	// История хранилища (только у git-хранилища)
+	{name: "history_list", method: "GET", path: "/api/v1/history", user: 1},
+	{name: "history_revision", method: "GET", path: "/api/v1/history/HEAD", user: 1},
+	{name: "history_diff", method: "GET", path: "/api/v1/history/HEAD/diff", user: 1},
+
+	// Синхронизация, конфликты, лента изменений
+	{name: "sync_initial", method: "POST", path: "/api/v1/sync", user: 2, body: `{"mutations":[]}`},
+	{name: "sync_manual_conflict", method: "POST", path: "/api/v1/sync", user: 1,
+		body: `{"strategy":"manual","mutations":[{"op":"upsert","uuid":"00000000-0000-4000-8000-000000000001","base_rev":1,"task":{"title":"Офлайн-правка","priority":"high"}}]}`,
+		save: map[string]string{"conflict": "results.0.conflict_id"}},
+	{name: "conflicts_list", method: "GET", path: "/api/v1/conflicts", user: 1},
+	{name: "conflicts_get", method: "GET", path: "/api/v1/conflicts/{conflict}", user: 1},
+	{name: "conflicts_resolve", method: "POST", path: "/api/v1/conflicts/{conflict}/resolve", user: 1, body: `{"resolution":"server"}`},
+	{name: "changes", method: "GET", path: "/api/v1/changes?limit=3", user: 1},
+	{name: "changes_bad_wait", method: "GET", path: "/api/v1/changes?wait=forever", user: 1},
+
+	// Удаление и неизвестные маршруты
+	{name: "tasks_delete", method: "DELETE", path: "/api/v1/tasks/6", user: 1},
+	{name: "tasks_delete_again", method: "DELETE", path: "/api/v1/tasks/6", user: 1},
+	{name: "not_found", method: "GET", path: "/api/v1/nope", user: 1},
+	{name: "method_not_allowed", method: "PATCH", path: "/api/v1/tasks/1", user: 1},
+}
+
+const goldenAPIKey = "golden-api-key"
+
+// TestGolden прогоняет goldenCases по полному роутеру поверх taskstest.NewStore и сравнивает
+// ответы (код, Content-Type, Location, тело) с testdata/golden/<name>.golden.
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := taskstest.NewServer(store, "", func(h *tasks.Handler, svc *tasks.Service) {
+		h.SetWorkspace("default", []int{1})
+		h.SetAPIKeys(map[string]int{goldenAPIKey: 1})
+		svc.SetActionLinks("https://tasks.example", 0)
+	})
+	defer srv.Close()
+	t.Cleanup(tasks.ResetActiveSettings) // шкалу и процесс меняют priorities_update и workflow_update
+
+	tokens := make(map[int]string)
+	for _, uid := range []int{1, 2, 3} {
+		if tokens[uid], err = srv.Token(uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vars := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, c := range goldenCases {
+		if seen[c.name] {
+			t.Fatalf("duplicate golden case %q", c.name)
+		}
+		seen[c.name] = true
+
+		path, body := expand(c.path, vars), expand(c.body, vars)
+		if u, err := url.Parse(path); err == nil && u.IsAbs() {
+			path = u.RequestURI() // ссылки действий собраны от PUBLIC_URL
+		}
+		var rd io.Reader
+		if body != "" {
+			rd = strings.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, c.method, srv.URL+path, rd)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.user != 0 {
+			req.Header.Set("Authorization", "Bearer "+tokens[c.user])
+		}
+		if c.apiKey {
+			req.Header.Set(middleware.APIKeyHeader, goldenAPIKey)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		for name, field := range c.save {
+			vars[name] = lookup(data, field)
+		}
+		got := renderGolden(c, resp, data)
+		checkGolden(t, c.name, got)
+	}
+
+	// Каждый маршрут роутера должен быть покрыт хотя бы одним запросом.
+	router, ok := srv.Config.Handler.(chi.Routes)
+	if !ok {
+		t.Fatalf("router is %T, want chi.Routes", srv.Config.Handler)
+	}
+	var missing []string
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !slices.ContainsFunc(goldenCases, func(c goldenCase) bool { return c.method == method && routeMatches(route, c.path) }) {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) > 0 {
+		t.Errorf("routes without golden cases:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+// expand подставляет {name} из vars; незнакомые {...} остаются как есть.
+func expand(s string, vars map[string]string) string {
+	for name, v := range vars {
+		s = strings.ReplaceAll(s, "{"+name+"}", v)
+	}
+	return s
+}
+
+// lookup достаёт поле JSON по пути "a.0.b"; чего нет -- "missing".
+func lookup(data []byte, path string) string {
+	var v any
+	if json.Unmarshal(data, &v) != nil {
+		return "missing"
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "missing"
+			}
+			v = node[i]
+		default:
+			return "missing"
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return "missing"
+}
+
+// routeMatches: шаблон chi ("/api/v1/tasks/{id}/") против пути запроса (с подстановками {var}).
+func routeMatches(route, path string) bool {
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		path = "/act/done/1" // ссылка действия целиком приходит из ответа
+	}
+	path, _, _ = strings.Cut(path, "?")
+	rs := strings.Split(strings.TrimSuffix(route, "/"), "/")
+	ps := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(rs) != len(ps) {
+		return false
+	}
+	for i := range rs {
+		if !strings.HasPrefix(rs[i], "{") && rs[i] != ps[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// renderGolden -- текстовый снимок ответа без того, что меняется от запуска к запуску.
+func renderGolden(c goldenCase, resp *http.Response, data []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s\n", c.method, c.path)
+	fmt.Fprintf(&b, "Status: %d\n", resp.StatusCode)
+	ct := resp.Header.Get("Content-Type")
+	if ct != "" {
+		fmt.Fprintf(&b, "Content-Type: %s\n", ct)
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		fmt.Fprintf(&b, "Location: %s\n", scrubText(loc))
+	}
+	b.WriteString("\n")
+
+	mediaType, _, _ := mime.ParseMediaType(ct)
+	var v any
+	switch {
+	case len(data) == 0:
+	case json.Unmarshal(data, &v) == nil:
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(scrubJSON("", v))
+	case strings.HasPrefix(mediaType, "text/"):
+		b.WriteString(scrubText(string(data)))
+	default:
+		fmt.Fprintf(&b, "<%s>\n", mediaType)
+	}
+	return b.Bytes()
+}
+
+// volatileKeys -- поля со случайными значениями (токены, подписи, ID запроса).
+var volatileKeys = map[string]bool{
+	"request_id": true,
+	"token":      true,
+	"url":        true,
+	"page":       true,
+	"cursor":     true,
+	"next":       true,
+	"rev":        true,
+	"hash":       true,
+}
+
+// recent -- всё, что позже этого момента, пришло из time.Now(), а не из фикстур (Epoch -- январь 2024).
+var recent = taskstest.Epoch.AddDate(1, 0, 0)
+
+func scrubJSON(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = scrubJSON(k, item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = scrubJSON(key, item)
+		}
+		return v
+	case string:
+		if v != "" && volatileKeys[key] {
+			return "<" + key + ">"
+		}
+		if key == "id" {
+			v = eventIDRe.ReplaceAllString(v, "${1}-<unix>") // ID события интеграции: задача и время
+		}
+		return scrubText(v)
+	}
+	return v
+}
+
+var (
+	timestampRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2}))?`)
+	signatureRe = regexp.MustCompile(`([?&](?:exp|sig|u)=)[^&"'\s<]+`)
+	requestIDRe = regexp.MustCompile(`\b[0-9a-f]{32,64}\b`)
+	eventIDRe   = regexp.MustCompile(`^(\d+)-\d{9,}$`)
+	uuidRe      = regexp.MustCompile(`\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+func scrubText(s string) string {
+	s = timestampRe.ReplaceAllStringFunc(s, func(m string) string {
+		layout := time.DateOnly
+		if len(m) > len(time.DateOnly) {
+			layout = time.RFC3339Nano
+		}
+		if ts, err := time.Parse(layout, m); err == nil && ts.After(recent) {
+			if layout == time.DateOnly {
+				return "<date>"
+			}
+			return "<time>"
+		}
+		return m
+	})
+	s = signatureRe.ReplaceAllString(s, "${1}<sig>")
+	s = uuidRe.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "00000000-") || strings.HasPrefix(m, "11111111-") {
+			return m // фикстуры и тела запросов
+		}
+		return "<uuid>"
+	})
+	return requestIDRe.ReplaceAllString(s, "<hex>")
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("%s: %v (run with -update to create)", name, err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: response differs from %s\n--- got ---\n%s\n--- want ---\n%s", name, path, got, want)
+	}
+}
@@ -9,11 +9,14 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"task-manager/internal/middleware"
 	appMiddleware "task-manager/internal/middleware" // подключаем middleware-пакет (алиас, чтобы не путать с chi/middleware)
+	"task-manager/internal/pdf"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -29,14 +32,128 @@ import (
 type Handler struct {
 	svc      *Service
 	validate *validator.Validate
+
+	// importers -- подключённые коннекторы внешних систем (format -> коннектор)
+	importers map[string]RemoteImporter
+
+	// apiKeys -- ключи интеграций (Zapier/IFTTT): ключ -> ID пользователя
+	apiKeys map[string]int
+
+	// priorityNumeric -- отдавать приоритет числом 1..4 вместо строки (переопределяется заголовком X-Priority-Format)
+	priorityNumeric bool
+
+	// authGuard -- блокировка перебора паролей на входе (nil -- выключена)
+	authGuard *appMiddleware.AuthGuard
+
+	// pdfFont -- шрифт PDF-распечаток (nil -- Helvetica с транслитерацией)
+	pdfFont *pdf.Font
+
+	// changesMaxWait -- предел ожидания в GET /api/v1/changes (0 -- defaultChangesMaxWait)
+	changesMaxWait time.Duration
+
+	// anonymousUser и anonymousRoutes -- анонимный доступ на чтение (0 -- выключен)
+	anonymousUser   int
+	anonymousRoutes []appMiddleware.RoutePattern
+	// publicCache -- кэш ответов публичных досок и анонимных запросов (nil -- выключен)
+	publicCache *appMiddleware.ResponseCache
+
+	// authorization -- решение внешнего движка политик после аутентификации (nil -- нет)
+	authorization func(http.Handler) http.Handler
+
+	// workspace и workspaceAdmins -- ID пространства в /api/v1/workspaces/{id} и кто меняет его настройки
+	workspace       string
+	workspaceAdmins []int
+
+	// features -- возможности, о которых знает main (CalDAV, веб-интерфейс), для OPTIONS
+	features map[string]bool
+
+	// outbox -- перехваченные письма и webhook демо-режима (nil -- /api/v1/demo/outbox нет)
+	outbox *Outbox
 }
 
 // NewHandler создаёт Handler и загружает данные из хранилища.
 func NewHandler(svc *Service) *Handler {
 	return &Handler{
-		svc:      svc,
-		validate: validator.New(),
+		svc:       svc,
+		validate:  svc.validate,
+		importers: make(map[string]RemoteImporter),
+		workspace: "default",
+	}
+}
+
+// SetAPIKeys задаёт ключи для /api/v1/integrations (ключ -> ID пользователя).
+// Вызывать до Router().
+func (h *Handler) SetAPIKeys(keys map[string]int) {
+	h.apiKeys = keys
+}
+
+// SetAuthGuard подключает блокировку перебора паролей к POST /api/v1/auth/login.
+// Вызывать до Router().
+func (h *Handler) SetAuthGuard(g *appMiddleware.AuthGuard) {
+	h.authGuard = g
+}
+
+// SetAnonymousAccess разрешает запросы без токена к маршрутам routes (только чтение) от имени
+// пользователя userID -- например, табло-киоск. Вызывать до Router().
+func (h *Handler) SetAnonymousAccess(userID int, routes []appMiddleware.RoutePattern) {
+	h.anonymousUser, h.anonymousRoutes = userID, routes
+}
+
+// SetPublicCache включает кэш ответов на ttl для публичных досок /board/{token} и анонимных
+// запросов (табло-киоск): частые обновления страниц не доходят до сервиса. Вызывать до Router().
+func (h *Handler) SetPublicCache(ttl time.Duration) {
+	h.publicCache = appMiddleware.NewResponseCache(ttl)
+}
+
+// kioskCache пропускает через publicCache анонимные запросы: все они выполняются от имени одного
+// пользователя, поэтому ответ одинаков для всех. Запросы с токеном не кэшируются.
+func (h *Handler) kioskCache(next http.Handler) http.Handler {
+	cached := h.publicCache.Middleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.anonymousUser > 0 && r.Header.Get("Authorization") == "" && slices.ContainsFunc(h.anonymousRoutes,
+			func(p appMiddleware.RoutePattern) bool { return p.Match(r.Method, r.URL.Path) }) {
+			cached.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetAuthorization подключает проверку каждого запроса к /api/v1 движком политик (internal/authz).
+// Вызывать до Router().
+func (h *Handler) SetAuthorization(mw func(http.Handler) http.Handler) {
+	h.authorization = mw
+}
+
+// SetWorkspace задаёт ID пространства (AUTHZ_WORKSPACE) и его администраторов (WORKSPACE_ADMINS):
+// только они меняют процесс в /api/v1/workspaces/{id}/workflow. Вызывать до Router().
+func (h *Handler) SetWorkspace(id string, admins []int) {
+	if id != "" {
+		h.workspace = id
 	}
+	h.workspaceAdmins = admins
+}
+
+// authPolicy -- кто может вызывать маршруты /api/v1: вход, регистрация и сброс пароля открыты, интеграции --
+// по ключу, анонимно -- только явно разрешённые маршруты чтения, всё остальное -- по JWT.
+func (h *Handler) authPolicy() *appMiddleware.AuthPolicy {
+	return appMiddleware.NewAuthPolicy().
+		Route(appMiddleware.AuthPublic, "POST /api/v1/auth/register", "POST /api/v1/auth/login",
+			"POST /api/v1/auth/password/forgot", "POST /api/v1/auth/password/reset").
+		Route(appMiddleware.AuthAPIKey, "/api/v1/integrations/*").
+		SetAPIKeys(h.apiKeys).
+		AllowAnonymous(h.anonymousUser, h.anonymousRoutes)
+}
+
+// SetPriorityFormat задаёт формат приоритета в ответах по умолчанию: числом (1..4) или строкой.
+// Вызывать до Router().
+func (h *Handler) SetPriorityFormat(numeric bool) {
+	h.priorityNumeric = numeric
+}
+
+// RegisterImporter подключает коннектор внешней системы к POST /api/v1/tasks/import?format=<format>.
+func (h *Handler) RegisterImporter(format string, imp RemoteImporter) {
+	h.importers[format] = imp
 }
 
 func (h *Handler) Router() http.Handler {
@@ -45,12 +162,14 @@ func (h *Handler) Router() http.Handler {
 	// =========================================================================
 	// ГЛОБАЛЬНАЯ ЦЕПОЧКА MIDDLEWARE
 	// =========================================================================
-	r.Use(appMiddleware.RequestIDMiddleware)                       // 1. Сквозной ID
-	r.Use(appMiddleware.LoggingMiddleware)                         // 2. Логгер статус-кодов
-	r.Use(appMiddleware.NewCORSMiddleware())                       // 3. CORS-фильтр (внутри папки internal/middleware)
-	r.Use(appMiddleware.JSONHeaderMiddleware)                      // 4. JSON заголовок
-	r.Use(appMiddleware.BodyLimitMiddleware(1 << 20))              // 5. Ограничение тела в 1 МБ
-	r.Use(appMiddleware.RequestTimeoutMiddleware(2 * time.Second)) // 6. Таймаут 2 секунды
+	r.Use(appMiddleware.RequestIDMiddleware)                                              // 1. Сквозной ID
+	r.Use(appMiddleware.LoggingMiddleware)                                                // 2. Логгер статус-кодов
+	r.Use(appMiddleware.NewCORSMiddleware())                                              // 3. CORS-фильтр (внутри папки internal/middleware)
+	r.Use(appMiddleware.JSONHeaderMiddleware)                                             // 4. JSON заголовок
+	r.Use(appMiddleware.BodyLimitMiddleware(1 << 20))                                     // 5. Ограничение тела в 1 МБ
+	r.Use(appMiddleware.RequestTimeoutMiddleware(2*time.Second, changesPath, importPath)) // 6. Таймаут 2 секунды (long polling и импорт -- свои)
+	r.Use(appMiddleware.RewriteJSON(h.priorityFormatter))                                 // 7. Приоритет числом (по запросу клиента)
+	r.Use(h.discovery(r))                                                                 // 8. HEAD на GET-маршрутах, OPTIONS: Allow и документ возможностей
 
 	// Настройка системных ответов 404/405
 	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
@@ -62,32 +181,224 @@ func (h *Handler) Router() http.Handler {
 			map[string]any{"method": req.Method, "path": req.URL.Path})
 	})
 
+	// Публичные ссылки на отдельные задачи: без аккаунта, по токену (см. handler_share.go)
+	r.Route("/share/{token}", func(r chi.Router) {
+		r.Use(h.shareAuth)
+
+		r.Get("/", h.getSharedTask)
+		r.Put("/", h.updateSharedTask)
+	})
+
+	// Публичные доски проектов: только чтение, без аккаунта, по токену (см. handler_board.go)
+	r.Route("/board/{token}", func(r chi.Router) {
+		r.Use(h.publicCache.Middleware) // до boardAuth: повторные запросы не ищут доску в хранилище
+		r.Use(h.boardAuth)
+
+		r.Get("/", h.getPublicBoard)
+	})
+
+	// Действия по подписанной ссылке из письма (отметить выполненной, отложить): без входа,
+	// только одно действие над одной задачей (см. handler_actions.go)
+	r.Route("/act/{action}/{id}", func(r chi.Router) {
+		r.Use(h.actionAuth)
+
+		r.Get("/", h.confirmAction)
+		r.Post("/", h.applyAction)
+	})
+
 	// =========================================================================
 	// МАРШРУТЫ API V1
 	// =========================================================================
 	r.Route("/api/v1", func(r chi.Router) {
+		// Кто может вызывать маршрут -- решает политика (см. authPolicy), а не группы маршрутов.
+		r.Use(h.authPolicy().Middleware)
+		if h.authorization != nil {
+			r.Use(h.authorization)
+		}
+		if h.publicCache != nil {
+			r.Use(h.kioskCache)
+		}
 
 		// Группа Авторизации (Открытая)
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", h.registerUser)
 			r.Post("/login", h.loginUser)
+			// Пароль и почта: смена -- по JWT, сброс по письму -- открыт (см. handler_password.go)
+			r.Post("/password", h.changePassword)
+			r.Post("/password/forgot", h.forgotPassword)
+			r.Post("/password/reset", h.resetPassword)
+			r.Put("/email", h.setEmail)
 		})
 
 		// Группа Задач (Закрытая семейным токеном)
 		r.Route("/tasks", func(r chi.Router) {
-			r.Use(appMiddleware.AuthMiddleware) // <--- ИСПРАВИЛИ ПРЕФИКС НА appMiddleware!
-
 			r.Get("/users", h.getAllUsers)
+			r.Get("/fields", h.getFieldDefs)
+			r.Get("/limits", h.getValidationLimits)
+			r.Get("/graph", h.getRelationGraph)
 
 			r.Get("/", h.getAllTasks)
 			r.Post("/", h.createTask)
+			r.Post("/import", h.importTasks)
+			r.Post("/reschedule", h.rescheduleTasks)
 			r.Get("/{id}", h.getTaskByID)
 			r.Put("/{id}", h.updateTask)
 			r.Delete("/{id}", h.deleteTask)
 			r.Post("/{id}/subtasks", h.createSubTask)
+			r.Post("/{id}/merge", h.mergeTask)
+			r.Get("/{id}/relations", h.getRelations)
+			r.Post("/{id}/relations", h.createRelation)
+			r.Delete("/{id}/relations/{rel_id}", h.deleteRelation)
+			r.Get("/{id}/comments", h.getComments)
+			r.Post("/{id}/comments", h.createComment)
+			r.Get("/{id}/shares", h.getShares)
+			r.Post("/{id}/shares", h.createShare)
+			r.Delete("/{id}/shares/{share_id}", h.deleteShare)
+			r.Post("/{id}/action-links", h.createActionLink)
+			r.Get("/{id}/revisions", h.getTaskVersions)
+			r.Get("/{id}/revisions/{n}", h.getTaskVersion)
+			r.Get("/{id}/revisions/{n}/diff", h.getTaskVersionDiff)
+			r.Post("/{id}/revisions/{n}/restore", h.restoreTaskVersion)
 
 			r.Put("/subtasks/{sub_id}", h.updateSubTaskStatus) // PUT /api/v1/tasks/subtasks/{sub_id}
 		})
+
+		// Интеграции для no-code платформ (Zapier, IFTTT): авторизация по API-ключу, плоский JSON
+		r.Route("/integrations", func(r chi.Router) {
+			r.Get("/triggers/new-tasks", h.triggerNewTasks)
+			r.Get("/triggers/completed-tasks", h.triggerCompletedTasks)
+			r.Post("/actions/create-task", h.actionCreateTask)
+			r.Post("/actions/complete-task", h.actionCompleteTask)
+		})
+
+		// Настройки текущего пользователя
+		r.Route("/me", func(r chi.Router) {
+			r.Get("/preferences", h.getPreferences)
+			r.Put("/preferences", h.updatePreferences)
+			// Сессии (устройства): список и отзыв выданных токенов, см. handler_sessions.go
+			r.Get("/sessions", h.listSessions)
+			r.Delete("/sessions", h.revokeSessions)
+			r.Delete("/sessions/{id}", h.revokeSession)
+			// Входящие уведомления (упоминания в комментариях), см. handler_comments.go
+			r.Get("/notifications", h.getNotifications)
+			r.Post("/notifications/read", h.markNotificationsRead)
+		})
+
+		// Заметки по дням (личный дневник) и план на день
+		r.Route("/notes", func(r chi.Router) {
+			r.Get("/", h.listNotes)
+			r.Get("/{date}", h.getNote)
+			r.Put("/{date}", h.saveNote)
+			r.Delete("/{date}", h.deleteNote)
+		})
+		r.Route("/agenda", func(r chi.Router) {
+			r.Get("/", h.getAgenda)
+			r.Get("/pdf", h.getAgendaPDF)
+		})
+
+		// Публичные доски: выдача и отзыв ссылок
+		r.Route("/boards", func(r chi.Router) {
+			r.Get("/", h.getBoards)
+			r.Post("/", h.createBoard)
+			r.Delete("/{board_id}", h.deleteBoard)
+		})
+
+		// Правила эскалации: условия по срокам и возрасту задач -> приоритет, метка, уведомление
+		r.Route("/rules", func(r chi.Router) {
+			r.Get("/", h.getRules)
+			r.Post("/", h.createRule)
+			r.Get("/{rule_id}", h.getRule)
+			r.Put("/{rule_id}", h.updateRule)
+			r.Delete("/{rule_id}", h.deleteRule)
+		})
+
+		// Автоматизации: событие задачи + фильтр -> назначить, срок, приоритет, метка, webhook
+		r.Route("/automations", func(r chi.Router) {
+			r.Get("/", h.getAutomations)
+			r.Post("/", h.createAutomation)
+			r.Get("/{automation_id}", h.getAutomation)
+			r.Put("/{automation_id}", h.updateAutomation)
+			r.Delete("/{automation_id}", h.deleteAutomation)
+			r.Get("/{automation_id}/runs", h.getAutomationRuns)
+		})
+
+		// Расписания: задачи по cron-выражению ("каждый понедельник в 9:00")
+		r.Route("/schedules", func(r chi.Router) {
+			r.Get("/", h.getSchedules)
+			r.Post("/", h.createSchedule)
+			r.Get("/{schedule_id}", h.getSchedule)
+			r.Put("/{schedule_id}", h.updateSchedule)
+			r.Delete("/{schedule_id}", h.deleteSchedule)
+			r.Get("/{schedule_id}/occurrences", h.getScheduleOccurrences)
+			r.Put("/{schedule_id}/exceptions/{at}", h.putScheduleException)
+			r.Delete("/{schedule_id}/exceptions/{at}", h.deleteScheduleException)
+		})
+
+		// Рабочий календарь: рабочие дни, часы и праздники для сроков в рабочих днях
+		r.Route("/calendar", func(r chi.Router) {
+			r.Get("/", h.getCalendar)
+			r.Put("/", h.updateCalendar)
+			r.Get("/due", h.getCalendarDue)
+		})
+
+		// Настройки пространства: процесс (статусы и переходы между ними)
+		r.Route("/workspaces/{workspace_id}", func(r chi.Router) {
+			r.Get("/workflow", h.getWorkflow)
+			r.Put("/workflow", h.updateWorkflow)
+		})
+
+		// Закрытые проекты: задачи с меткой проекта видны только его участникам (см. handler_projects.go)
+		r.Route("/projects", func(r chi.Router) {
+			r.Get("/", h.getProjectACLs)
+			r.Put("/{tag}/acl", h.updateProjectACL)
+			r.Delete("/{tag}/acl", h.deleteProjectACL)
+		})
+
+		// Шкала приоритетов пространства: свои уровни и их порядок (P0-P4)
+		r.Route("/priorities", func(r chi.Router) {
+			r.Get("/", h.getPriorityScale)
+			r.Put("/", h.updatePriorityScale)
+		})
+
+		// Отчёты: загрузка исполнителей по оценкам задач, диаграмма сгорания
+		r.Route("/reports", func(r chi.Router) {
+			r.Get("/capacity", h.getCapacityReport)
+			r.Get("/burndown", h.getBurndown)
+			r.Get("/project.pdf", h.getProjectPDF)
+		})
+
+		// История изменений хранилища (только чтение, доступна при STORAGE_GIT=true)
+		r.Route("/history", func(r chi.Router) {
+			r.Get("/", h.getHistory)
+			r.Get("/{rev}", h.getHistoryRevision)
+			r.Get("/{rev}/diff", h.getHistoryDiff)
+		})
+
+		// Синхронизация офлайн-клиентов: свои изменения + всё изменившееся после токена
+		r.Route("/sync", func(r chi.Router) {
+			r.Post("/", h.syncTasks)
+		})
+
+		// Конфликты синхронизации, ждущие решения пользователя (стратегия manual)
+		r.Route("/conflicts", func(r chi.Router) {
+			r.Get("/", h.getConflicts)
+			r.Get("/{conflict_id}", h.getConflict)
+			r.Post("/{conflict_id}/resolve", h.resolveConflict)
+		})
+
+		// Лента изменений задач для CLI и скриптов: long polling по курсору
+		r.Route("/changes", func(r chi.Router) {
+			r.Get("/", h.getChanges)
+		})
+
+		// Что было бы отправлено наружу: только в демо-режиме
+		if h.outbox != nil {
+			r.Route("/demo/outbox", func(r chi.Router) {
+				r.Get("/", h.getOutbox)
+				r.Delete("/", h.clearOutbox)
+				r.Get("/{message_id}", h.getOutboxMessage)
+			})
+		}
 	})
 
 	return r
@@ -102,6 +413,13 @@ func (h *Handler) getAllTasks(w http.ResponseWriter, r *http.Request) {
 	// Используем панику приведения типов .(int), так как middleware гарантирует наличие этого значения.
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
+	// ?sort=priority|-priority|due|-due|urgency|-urgency|id (по умолчанию -- по ID)
+	sortKey := r.URL.Query().Get("sort")
+	render, ok := h.renderParam(w, r)
+	if !ok {
+		return
+	}
+
 	// Передаем userID в бизнес-логику для обеспечения изоляции данных членов семьи
 	tasks, err := h.svc.GetAllTasks(ctx, userID)
 	if err != nil {
@@ -113,12 +431,114 @@ func (h *Handler) getAllTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?field.<name>=<value> -- фильтр по пользовательским полям
+	filters := make(map[string]string)
+	for key, vals := range r.URL.Query() {
+		if name, ok := strings.CutPrefix(key, FieldFilterPrefix); ok && len(vals) > 0 {
+			filters[name] = vals[0]
+		}
+	}
+	tasks, err = h.svc.FilterByFields(tasks, filters)
+	if h.writeFieldError(w, r, err) {
+		return
+	}
+
+	// ?due=today|this_week|next_7d|... -- границы дней в часовом поясе пользователя
+	if due := r.URL.Query().Get("due"); due != "" {
+		prefs, err := h.svc.GetPreferences(ctx, userID)
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s getAllTasks preferences error: %v", appMiddleware.GetRequestID(ctx), err)
+		}
+		tasks, err = FilterByDue(tasks, due, time.Now(), prefs.Location())
+		if err != nil {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(),
+				map[string]any{"due": due})
+			return
+		}
+	}
+
+	if err := h.svc.ScoreUrgency(ctx, tasks, time.Now()); err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAllTasks urgency error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get tasks", nil)
+		return
+	}
+
+	if err := SortTasks(tasks, sortKey); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(),
+			map[string]any{"sort": sortKey})
+		return
+	}
+
+	// ?limit=N&offset=M -- страница уже отфильтрованного и отсортированного списка
+	tasks, ok = paginate(w, r, tasks)
+	if !ok {
+		return
+	}
+
+	if render {
+		_ = json.NewEncoder(w).Encode(renderTasks(tasks))
+		return
+	}
 	// Если список пуст, Encode автоматически отдаст клиенту корректный пустой массив []
 	_ = json.NewEncoder(w).Encode(tasks)
 }
 
+// maxPageLimit -- наибольший ?limit= у списка задач.
+const maxPageLimit = 500
+
+// paginate вырезает страницу ?limit=N&offset=M. Без limit список отдаётся целиком, как раньше.
+// С limit выставляет X-Total-Count (сколько задач всего) и, если есть следующая страница, Link rel="next".
+func paginate(w http.ResponseWriter, r *http.Request, list []Task) ([]Task, bool) {
+	q := r.URL.Query()
+	limitStr, offsetStr := q.Get("limit"), q.Get("offset")
+	if limitStr == "" {
+		if offsetStr != "" {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "offset requires limit",
+				map[string]any{"offset": offsetStr})
+			return nil, false
+		}
+		return list, true
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+			map[string]any{"limit": limitStr, "max": maxPageLimit})
+		return nil, false
+	}
+	offset := 0
+	if offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid offset",
+				map[string]any{"offset": offsetStr})
+			return nil, false
+		}
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(list)))
+	if offset+limit < len(list) {
+		next := *r.URL
+		nq := next.Query()
+		nq.Set("offset", strconv.Itoa(offset+limit))
+		next.RawQuery = nq.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+	if offset >= len(list) {
+		return []Task{}, true
+	}
+	return list[offset:min(offset+limit, len(list))], true
+}
+
 func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
 
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
@@ -141,18 +561,59 @@ func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
 		req.AssignedTo = userID
 	}
 
+	// Приоритет не указан -- берём приоритет по умолчанию из настроек пользователя
+	if req.Priority == "" {
+		prefs, err := h.svc.GetPreferences(ctx, userID)
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s createTask preferences error: %v", appMiddleware.GetRequestID(ctx), err)
+		}
+		// Resolve: настройку могли сохранить при другой шкале приоритетов; пустая -- уровень по умолчанию.
+		req.Priority = prefs.DefaultPriority.Resolve()
+	}
+
+	// Срок "через N рабочих дней" -- по рабочему календарю сервера
+	if req.DueInBusinessDays != nil {
+		cal, err := h.svc.Calendar(ctx)
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s createTask calendar error: %v", appMiddleware.GetRequestID(ctx), err)
+		}
+		due := cal.DueInBusinessDays(time.Now(), *req.DueInBusinessDays)
+		req.Due = &due
+	}
+
 	// 2. Маппим DTO в доменную модель
 	incoming := Task{
-		UserID:     userID,
-		AssignedTo: req.AssignedTo,
-		Title:      req.Title,
-		Done:       req.Done,
-		Priority:   req.Priority,
+		UUID:            req.UUID,
+		UserID:          userID,
+		AssignedTo:      req.AssignedTo,
+		Title:           req.Title,
+		Done:            req.Done,
+		Status:          req.Status,
+		Priority:        req.Priority,
+		Description:     req.Description,
+		Tags:            req.Tags,
+		Due:             req.Due,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
 	}
 
 	// 3. Отправляем в сервис по указателю (тут запишется новый ID)
 	err := h.svc.CreateTask(ctx, &incoming)
 	if err != nil {
+		if h.writeFieldError(w, r, err) || h.writeHookError(w, r, err) || h.writeProjectError(w, r, err) {
+			return
+		}
+		if errors.Is(err, ErrDuplicateUUID) {
+			appMiddleware.WriteError(w, r, http.StatusConflict, "conflict", "Task with this uuid already exists",
+				map[string]any{"uuid": incoming.UUID})
+			return
+		}
 		if h.handleContextError(w, r, err) {
 			return
 		}
@@ -161,7 +622,11 @@ func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Формируем ответ
+	// 4. Формируем ответ. Пробный запуск ничего не создал: 200 и задача, какой бы она стала.
+	if IsDryRun(ctx) {
+		_ = json.NewEncoder(w).Encode(incoming)
+		return
+	}
 	w.Header().Set("Location", fmt.Sprintf("/api/v1/tasks/%d", incoming.ID))
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(incoming)
@@ -177,18 +642,22 @@ func (h *Handler) getTaskByID(w http.ResponseWriter, r *http.Request) {
 	// Используем панику приведения типов .(int), так как middleware гарантирует наличие этого значения.
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
-	// Парсим id задачи из запроса
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid ID",
-			map[string]any{"id": idStr})
+	// id в URL -- числовой ID (v1) или UUID/ULID задачи
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	render, ok := h.renderParam(w, r)
+	if !ok {
 		return
 	}
 
 	// Передаем UserID в бизнес-логику для обеспеения изоляции данных
 	task, err := h.svc.GetTaskByID(ctx, id, userID)
 	if errors.Is(err, ErrTaskNotFound) {
+		if h.redirectMerged(w, r, id) {
+			return
+		}
 		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
 			map[string]any{"id": id}) // NEW-TEACH
 		return
@@ -201,8 +670,22 @@ func (h *Handler) getTaskByID(w http.ResponseWriter, r *http.Request) {
 		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get task", nil)
 		return
 	}
+	scored := []Task{*task}
+	if err := h.svc.ScoreUrgency(ctx, scored, time.Now()); err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getTaskByID urgency error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get task", nil)
+		return
+	}
+	task = &scored[0]
 
 	// [CHANGE] Content-Type выставляет JSONHeaderMiddleware
+	if render {
+		_ = json.NewEncoder(w).Encode(renderTask(*task))
+		return
+	}
 	_ = json.NewEncoder(w).Encode(task)
 
 }
@@ -211,17 +694,18 @@ func (h *Handler) getTaskByID(w http.ResponseWriter, r *http.Request) {
 //
 // Обновляет Title/Done у задачи, сохраняет список на диск, возвращает обновлённую задачу.
 func (h *Handler) updateTask(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
 
 	// Извлекаем ID авторизованного пользователя, записанный JWT-middleware.
 	// Используем панику приведения типов .(int), так как middleware гарантирует наличие этого значения.
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid ID",
-			map[string]any{"id": idStr}) // NEW-TEACH
+	// id в URL -- числовой ID (v1) или UUID/ULID задачи
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
 		return
 	}
 
@@ -242,15 +726,28 @@ func (h *Handler) updateTask(w http.ResponseWriter, r *http.Request) {
 		req.AssignedTo = userID // или task.AssignedTo = userID в зависимости от вашей структуры переменных
 	}
 	incoming := Task{
-		ID:         id,
-		Title:      req.Title,
-		Done:       req.Done,
-		Priority:   req.Priority,
-		AssignedTo: req.AssignedTo,
+		ID:              id,
+		Title:           req.Title,
+		Done:            req.Done,
+		Status:          req.Status,
+		Priority:        req.Priority,
+		AssignedTo:      req.AssignedTo,
+		Description:     req.Description,
+		Tags:            req.Tags,
+		Due:             req.Due,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
 	}
 
-	err = h.svc.UpdateTask(ctx, &incoming, userID)
+	err := h.svc.UpdateTask(ctx, &incoming, userID)
+	if h.writeFieldError(w, r, err) || h.writeHookError(w, r, err) || h.writeTransitionError(w, r, err) ||
+		h.writeProjectError(w, r, err) {
+		return
+	}
 	if errors.Is(err, ErrTaskNotFound) {
+		if h.redirectMerged(w, r, id) {
+			return
+		}
 		// Если задача с запрашиваемым ID не найдена
 		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
 			map[string]any{"id": id}) // NEW-TEACH
@@ -278,16 +775,20 @@ func (h *Handler) deleteTask(w http.ResponseWriter, r *http.Request) {
 	// Используем панику приведения типов .(int), так как middleware гарантирует наличие этого значения.
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid ID",
-			map[string]any{"id": idStr}) // NEW
+	// id в URL -- числовой ID (v1) или UUID/ULID задачи
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	err = h.svc.DeleteTask(ctx, id, userID)
+	err := h.svc.DeleteTask(ctx, id, userID)
+	if h.writeHookError(w, r, err) {
+		return
+	}
 	if errors.Is(err, ErrTaskNotFound) {
+		if h.redirectMerged(w, r, id) {
+			return
+		}
 		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
 			map[string]any{"id": id}) // NEW
 		return
@@ -308,16 +809,14 @@ func (h *Handler) createSubTask(w http.ResponseWriter, r *http.Request) {
 
 	userID := ctx.Value(middleware.UserIDKey).(int)
 
-	taskIDStr := chi.URLParam(r, "id")
-	taskID, err := strconv.Atoi(taskIDStr)
-	if err != nil {
-		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid Task ID",
-			map[string]any{"id": taskIDStr})
+	// id в URL -- числовой ID (v1) или UUID/ULID задачи
+	taskID, ok := h.taskIDParam(w, r)
+	if !ok {
 		return
 	}
 
 	var req CreateSubTaskRequest
-	err = decodeJSONStrict(r, &req)
+	err := decodeJSONStrict(r, &req)
 	if err != nil {
 		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
 			validationDetails(err))
@@ -339,6 +838,9 @@ func (h *Handler) createSubTask(w http.ResponseWriter, r *http.Request) {
 	err = h.svc.CreateSubTask(ctx, &incoming, userID)
 
 	if errors.Is(err, ErrTaskNotFound) {
+		if h.redirectMerged(w, r, taskID) {
+			return
+		}
 		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
 			map[string]any{"id": taskID})
 		return
@@ -382,6 +884,10 @@ func (h *Handler) registerUser(w http.ResponseWriter, r *http.Request) {
 			nil)
 		return
 	}
+	if errors.Is(err, ErrInvalidPassword) {
+		h.writePasswordError(w, r, "registerUser", err)
+		return
+	}
 
 	if err != nil {
 		if h.handleContextError(w, r, err) { // Проверка на таймаут контекста
@@ -413,12 +919,29 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.svc.Login(ctx, req)
+	// Пока логин или адрес заблокированы после серии неудач, пароль даже не проверяем.
+	ip := appMiddleware.ClientIP(r)
+	if wait, locked := h.authGuard.Check(req.Username, ip, "login", time.Now()); locked {
+		log.Printf("request_id=%s login locked out username=%q ip=%s", appMiddleware.GetRequestID(ctx), req.Username, ip)
+		w.Header().Set("Retry-After", appMiddleware.RetryAfterHeader(wait))
+		appMiddleware.WriteError(w, r, http.StatusTooManyRequests, "locked_out", "Слишком много неудачных попыток входа, попробуйте позже",
+			map[string]any{"retry_after_seconds": int(wait.Round(time.Second).Seconds())})
+		return
+	}
+
+	token, err := h.svc.Login(ctx, req, SessionClient{IP: ip, UserAgent: r.UserAgent()})
 	if errors.Is(err, ErrInvalidCredentials) {
+		h.authGuard.Fail(req.Username, ip, "login", time.Now())
+		log.Printf("request_id=%s login failed username=%q ip=%s", appMiddleware.GetRequestID(ctx), req.Username, ip)
 		appMiddleware.WriteError(w, r, http.StatusUnauthorized, "Unauthorized", "Неверный логин или пароль",
 			nil)
 		return
 	}
+	if errors.Is(err, ErrUserDisabled) {
+		log.Printf("request_id=%s login of disabled user username=%q ip=%s", appMiddleware.GetRequestID(ctx), req.Username, ip)
+		appMiddleware.WriteError(w, r, http.StatusForbidden, "user_disabled", "Учётная запись заблокирована", nil)
+		return
+	}
 
 	if err != nil {
 		if h.handleContextError(w, r, err) { // Проверка на таймаут контекста
@@ -429,6 +952,7 @@ func (h *Handler) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.authGuard.Succeed(req.Username)
 	w.WriteHeader(http.StatusOK)
 	tokenData := map[string]string{"token": token}
 	_ = json.NewEncoder(w).Encode(tokenData)
@@ -454,9 +978,13 @@ func (h *Handler) getAllUsers(w http.ResponseWriter, r *http.Request) {
 // handleContextError делает понятную обработку ошибок отмены/таймаута.
 func (h *Handler) handleContextError(w http.ResponseWriter, r *http.Request, err error) bool {
 	switch {
+	case errors.Is(err, context.Canceled) && errors.Is(context.Cause(r.Context()), appMiddleware.ErrShuttingDown):
+		// Сервер останавливается и не дождался запроса (жёсткий таймаут остановки):
+		// клиент ещё на связи, говорим ему повторить запрос позже.
+		appMiddleware.WriteShuttingDown(w, r)
+		return true
 	case errors.Is(err, context.Canceled):
-		// Запрос отменен: клиент ушел ИЛИ сервер делает graceful shutdown.
-		// Часто отвечать уже некому (соединение закрыто), поэтому просто прекращаем работу.
+		// Запрос отменен: клиент ушел, отвечать уже некому (соединение закрыто), поэтому просто прекращаем работу.
 		return true
 	case errors.Is(err, context.DeadlineExceeded):
 		// Таймаут запроса (например, наш RequestTimeoutMiddleware).
@@ -465,6 +993,28 @@ func (h *Handler) handleContextError(w http.ResponseWriter, r *http.Request, err
 		// NEW-TEACH: таймаут -- часть контракта; возвращаем единый JSON error.
 		appMiddleware.WriteError(w, r, http.StatusRequestTimeout, "timeout", "Request timeout", nil)
 		return true
+	case errors.Is(err, ErrDryRunUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return true
+	case AsStorageError(err) != nil:
+		// Диск не принимает запись: переводим сервис в режим неисправности (если это ещё не сделала
+		// сама запись задачи) и говорим клиенту, что изменение не сохранено. Подсказка с путями -- в лог
+		// и уведомление администратору, а не клиенту.
+		se := AsStorageError(err)
+		h.svc.checkStorage(r.Context(), err)
+		status := http.StatusServiceUnavailable
+		if se.Kind == StorageDiskFull {
+			status = http.StatusInsufficientStorage
+		}
+		appMiddleware.WriteError(w, r, status, "storage_"+se.Kind,
+			"Server storage does not accept changes, they were not saved", map[string]string{"kind": se.Kind})
+		return true
+	case errors.Is(err, ErrStorageDegraded):
+		// Основное хранилище недоступно, работаем из реплики: читать можно, менять -- нет.
+		w.Header().Set("Retry-After", "30")
+		appMiddleware.WriteError(w, r, http.StatusServiceUnavailable, "storage_degraded",
+			"Storage is temporarily read-only, try again later", nil)
+		return true
 	default:
 		return false
 	}
@@ -483,6 +1033,9 @@ func decodeJSONStrict(r *http.Request, dst any) error {
 	if err := dec.Decode(&struct{}{}); err != io.EOF {
 		return errors.New("request body must contain a single JSON object")
 	}
+	if n, ok := dst.(textNormalizer); ok {
+		n.normalizeText()
+	}
 	return nil
 }
 
@@ -503,7 +1056,118 @@ func (h *Handler) writeDecodeError(w http.ResponseWriter, r *http.Request, err e
 	}
 }
 
+// taskIDParam разбирает {id} из URL: числовой ID или UUID/ULID (слой совместимости для v1-клиентов).
+// Задача чужого закрытого проекта -- 404, как несуществующая. При ошибке сам пишет ответ
+// (400 или 404) и возвращает false.
+func (h *Handler) taskIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	ref := chi.URLParam(r, "id")
+	id, err := h.svc.ResolveTaskID(r.Context(), ref)
+	if err == nil {
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		err = h.svc.CheckTaskVisible(r.Context(), userID, id)
+	}
+	switch {
+	case err == nil:
+		return id, true
+	case errors.Is(err, ErrInvalidTaskRef):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid ID",
+			map[string]any{"id": ref})
+	case errors.Is(err, ErrTaskNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": ref})
+	case h.handleContextError(w, r, err):
+	default:
+		log.Printf("request_id=%s resolve task id error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Internal error", nil)
+	}
+	return 0, false
+}
+
+// dryRunContext разбирает ?dry_run=true или заголовок X-Dry-Run: true. Для пробного запуска
+// возвращает помеченный контекст (см. WithDryRun) и ставит X-Dry-Run: true в ответ.
+// При ошибке сам пишет 400 и возвращает false.
+func (h *Handler) dryRunContext(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	v := r.URL.Query().Get("dry_run")
+	if v == "" {
+		v = r.Header.Get("X-Dry-Run")
+	}
+	if v == "" {
+		return r.Context(), true
+	}
+	dry, err := strconv.ParseBool(v)
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "dry_run must be true or false",
+			map[string]any{"dry_run": v})
+		return nil, false
+	}
+	if !dry {
+		return r.Context(), true
+	}
+	w.Header().Set("X-Dry-Run", "true")
+	return WithDryRun(r.Context()), true
+}
+
 // NEW: преобразуем ошибки validator в стабильный details для клиента (без внутренних названий структур).
+// writeFieldError отвечает 400, если err -- ошибка пользовательского поля. Формат details -- как у validationDetails.
+func (h *Handler) writeFieldError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		return false
+	}
+	appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+		[]map[string]string{{"field": "fields." + fe.Field, "rule": fe.Reason}})
+	return true
+}
+
+// writeHookError отвечает 422, если операцию отменил хук (см. hooks.go). Текст ошибки хука
+// уходит клиенту: это сообщение для пользователя ("в названии нет кода проекта").
+func (h *Handler) writeHookError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var he *HookError
+	if !errors.As(err, &he) {
+		return false
+	}
+	appMiddleware.WriteError(w, r, http.StatusUnprocessableEntity, "hook_rejected", he.Err.Error(),
+		map[string]any{"hook": he.Hook, "stage": he.Stage})
+	return true
+}
+
+// writeTransitionError отвечает 409, если процесс пространства (workflow.go) не разрешает смену статуса.
+// В details -- куда из текущего статуса перейти можно.
+func (h *Handler) writeTransitionError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var te *TransitionError
+	if !errors.As(err, &te) {
+		return false
+	}
+	appMiddleware.WriteError(w, r, http.StatusConflict, "transition_not_allowed", "Status transition is not allowed by the workflow",
+		map[string]any{"from": te.From, "to": te.To, "allowed": te.Allowed})
+	return true
+}
+
+// writeProjectError отвечает 403, если задаче ставят метку закрытого проекта, в котором
+// пользователь не участник (project_acl.go).
+func (h *Handler) writeProjectError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, ErrProjectForbidden) {
+		return false
+	}
+	appMiddleware.WriteError(w, r, http.StatusForbidden, "project_forbidden", err.Error(), nil)
+	return true
+}
+
+// getFieldDefs обрабатывает GET /api/v1/tasks/fields -- какие пользовательские поля есть у задач.
+func (h *Handler) getFieldDefs(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(h.svc.FieldDefs().List())
+}
+
+// getValidationLimits обрабатывает GET /api/v1/tasks/limits: ограничения входных данных сервера,
+// чтобы клиент проверял длину названия и приоритет так же, как сервер. Приоритеты -- уровни
+// действующей шкалы от низшего к высшему (подписи -- в GET /api/v1/priorities).
+func (h *Handler) getValidationLimits(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(struct {
+		ValidationLimits
+		Priorities []Priority `json:"priorities"`
+	}{h.svc.ValidationLimits(), ActivePriorityScale().IDs()})
+}
+
 func validationDetails(err error) any {
 	var verrs validator.ValidationErrors
 	if !errors.As(err, &verrs) {
@@ -513,12 +1177,15 @@ func validationDetails(err error) any {
 	type item struct {
 		Field string `json:"field"`
 		Rule  string `json:"rule"`
+		Param string `json:"param,omitempty"` // например, действующий max (см. ValidationLimits)
 	}
 	out := make([]item, 0, len(verrs))
 	for _, fe := range verrs {
+		// ActualTag: правило под псевдонимом ограничения (title, tags...) называется как раньше -- max, oneof.
 		out = append(out, item{
 			Field: fe.Field(),
-			Rule:  fe.Tag(),
+			Rule:  fe.ActualTag(),
+			Param: fe.Param(),
 		})
 	}
 	return out
@@ -526,6 +1193,7 @@ func validationDetails(err error) any {
 
 func (h *Handler) updateSubTaskStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
 
 	// 1. Считываем ID подзадачи из URL-параметров
 	subIDStr := chi.URLParam(r, "sub_id")
@@ -544,7 +1212,7 @@ func (h *Handler) updateSubTaskStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Вызываем метод бизнес-логики в сервисе
-	err = h.svc.UpdateSubTaskStatus(ctx, subID, req.Done)
+	err = h.svc.UpdateSubTaskStatus(ctx, subID, userID, req.Done)
 	if errors.Is(err, ErrTaskNotFound) {
 		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "SubTask not found",
 			map[string]any{"sub_id": subID})
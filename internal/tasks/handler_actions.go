@@ -0,0 +1,190 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type actionCtxKey struct{}
+
+// signedAction -- проверенная ссылка и задача, над которой она выполняет действие.
+type signedAction struct {
+	link ActionLink
+	task *Task
+}
+
+// actionPage -- страница подтверждения и результата действия по ссылке. Ссылку из письма
+// открывают GET-ом, а выполняет действие только кнопка (POST): почтовые сканеры, которые
+// заранее открывают ссылки из писем, ничего не меняют.
+var actionPage = template.Must(template.New("action").Parse(`<!doctype html>
+<html lang="ru"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex"><title>{{.Heading}}</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 3em auto; padding: 0 1em">
+<h1 style="font-size: 1.4em">{{.Heading}}</h1>
+{{if .Task}}<p>Задача: <b>{{.Task}}</b></p>{{end}}
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .Button}}<form method="post"><button type="submit" style="font-size: 1.1em; padding: .5em 1.5em">{{.Button}}</button></form>{{end}}
+</body></html>
+`))
+
+type actionPageData struct {
+	Heading string
+	Task    string
+	Message string
+	Button  string
+}
+
+// actionAuth проверяет подпись ссылки /act/{action}/{id}: она выдана для этой задачи и этого
+// действия, не истекла и ещё не использована. Вход в систему не нужен.
+//
+// Ссылку без подписи отклоняем до чтения задачи, а отказ одинаков для несуществующей задачи,
+// чужой и испорченной подписи и не показывает название: иначе по ID можно перебрать названия
+// задач (в том числе закрытых проектов) и узнать, какие ID существуют.
+func (h *Handler) actionAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		ctx := r.Context()
+		reject := func(err error) {
+			log.Printf("request_id=%s action link rejected path=%s ip=%s: %v", appMiddleware.GetRequestID(ctx), r.URL.Path, appMiddleware.ClientIP(r), err)
+			writeActionPage(w, http.StatusForbidden, actionPageData{
+				Heading: "Ссылка недействительна",
+				Message: "Срок ссылки истёк, действие уже выполнено или задачи больше нет. Откройте задачу в приложении.",
+			})
+		}
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			reject(err)
+			return
+		}
+		link, err := h.svc.ParseActionLink(chi.URLParam(r, "action"), id, r.URL.Query(), time.Now())
+		if err != nil {
+			reject(err)
+			return
+		}
+		task, err := h.svc.GetTaskByID(ctx, id, link.UserID)
+		if errors.Is(err, ErrTaskNotFound) {
+			reject(err)
+			return
+		}
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s actionAuth error: %v", appMiddleware.GetRequestID(ctx), err)
+			writeActionPage(w, http.StatusInternalServerError, actionPageData{Heading: "Внутренняя ошибка сервера", Message: "Попробуйте позже."})
+			return
+		}
+		if err := h.svc.VerifyActionLink(link, task); err != nil {
+			reject(err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, actionCtxKey{}, signedAction{link: link, task: task})))
+	})
+}
+
+// confirmAction обрабатывает GET /act/{action}/{id}: страница с кнопкой подтверждения.
+func (h *Handler) confirmAction(w http.ResponseWriter, r *http.Request) {
+	a := r.Context().Value(actionCtxKey{}).(signedAction)
+	writeActionPage(w, http.StatusOK, actionPageData{
+		Heading: actionLabels[a.link.Action] + "?",
+		Task:    a.task.Title,
+		Button:  actionLabels[a.link.Action],
+	})
+}
+
+// applyAction обрабатывает POST /act/{action}/{id}: выполняет действие от имени владельца ссылки.
+func (h *Handler) applyAction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	a := ctx.Value(actionCtxKey{}).(signedAction)
+
+	err := h.svc.ApplyAction(ctx, a.link, a.task, time.Now())
+	if errors.Is(err, ErrInvalidActionLink) {
+		writeActionPage(w, http.StatusForbidden, actionPageData{Heading: "Ссылка недействительна"})
+		return
+	}
+	if errors.Is(err, ErrTransitionNotAllowed) {
+		writeActionPage(w, http.StatusConflict, actionPageData{Heading: "Не получилось", Task: a.task.Title,
+			Message: "Процесс команды не разрешает сразу перевести задачу в этот статус. Откройте задачу в приложении."})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s applyAction error: %v", appMiddleware.GetRequestID(ctx), err)
+		writeActionPage(w, http.StatusInternalServerError, actionPageData{Heading: "Не получилось", Task: a.task.Title, Message: "Попробуйте позже или откройте задачу в приложении."})
+		return
+	}
+	log.Printf("request_id=%s action %s on task %d by user %d via signed link", appMiddleware.GetRequestID(ctx), a.link.Action, a.task.ID, a.link.UserID)
+
+	msg := "Задача отмечена выполненной."
+	if a.link.Action == ActionSnooze {
+		msg = "Новый срок: " + a.task.Due.Format("02.01.2006 15:04") + " (UTC)."
+	}
+	writeActionPage(w, http.StatusOK, actionPageData{Heading: "Готово", Task: a.task.Title, Message: msg})
+}
+
+func writeActionPage(w http.ResponseWriter, status int, data actionPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = actionPage.Execute(w, data)
+}
+
+// createActionLink обрабатывает POST /api/v1/tasks/{id}/action-links: подписанная ссылка,
+// по которой действие над задачей можно выполнить без входа (например, вставить в своё письмо).
+//
+//	{"action": "done", "expires_in": "72h"}
+func (h *Handler) createActionLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req ActionLinkRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	link, expires, err := h.svc.CreateActionLink(ctx, userID, id, req.Action, req.ExpiresIn, time.Now())
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found", nil)
+		return
+	case errors.Is(err, ErrInvalidActionTTL):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", err.Error(), nil)
+		return
+	case errors.Is(err, ErrActionLinksDisabled):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Action links need PUBLIC_URL", nil)
+		return
+	case err != nil:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createActionLink error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create action link", nil)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{"action": req.Action, "url": link, "expires_at": expires.UTC()})
+}
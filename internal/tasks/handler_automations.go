@@ -0,0 +1,228 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultAutomationRunsLimit -- сколько записей журнала отдаём по умолчанию; больше automationRunsKept не хранится.
+const defaultAutomationRunsLimit = 20
+
+// writeAutomationError отвечает на ошибки автоматизаций, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeAutomationError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrAutomationsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrAutomationNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Automation not found", nil)
+	case errors.Is(err, ErrAutomationBadAction), errors.Is(err, ErrAutomationUnknownUser), errors.Is(err, ErrAutomationBadWebhook):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// automationIDParam разбирает {automation_id}; при ошибке сам отвечает 400.
+func (h *Handler) automationIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "automation_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid automation ID",
+			map[string]any{"automation_id": chi.URLParam(r, "automation_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeAutomation читает и проверяет тело запроса автоматизации; при ошибке сам отвечает 400.
+func (h *Handler) decodeAutomation(w http.ResponseWriter, r *http.Request) (AutomationRequest, bool) {
+	var req AutomationRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return req, false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return req, false
+	}
+	return req, true
+}
+
+// createAutomation обрабатывает POST /api/v1/automations.
+//
+//	{"name": "Баги -- Пете", "on": "task.created", "if": {"tag": "bug"}, "do": [{"assign": 3}, {"due_in_days": 2}]}
+func (h *Handler) createAutomation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	req, ok := h.decodeAutomation(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := h.svc.CreateAutomation(ctx, userID, req, time.Now())
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createAutomation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create automation", nil)
+		return
+	}
+
+	log.Printf("request_id=%s automation created user=%d automation=%d on=%s", appMiddleware.GetRequestID(ctx), userID, a.ID, a.On)
+	w.Header().Set("Location", "/api/v1/automations/"+strconv.Itoa(a.ID))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// getAutomations обрабатывает GET /api/v1/automations -- автоматизации текущего пользователя.
+func (h *Handler) getAutomations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.UserAutomations(ctx, userID)
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAutomations error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get automations", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getAutomation обрабатывает GET /api/v1/automations/{automation_id}.
+func (h *Handler) getAutomation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.automationIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := h.svc.GetAutomation(ctx, userID, id)
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAutomation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get automation", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// updateAutomation обрабатывает PUT /api/v1/automations/{automation_id} -- автоматизация заменяется целиком.
+func (h *Handler) updateAutomation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.automationIDParam(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeAutomation(w, r)
+	if !ok {
+		return
+	}
+
+	a, err := h.svc.UpdateAutomation(ctx, userID, id, req, time.Now())
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateAutomation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update automation", nil)
+		return
+	}
+	log.Printf("request_id=%s automation updated user=%d automation=%d enabled=%t", appMiddleware.GetRequestID(ctx), userID, id, a.Enabled)
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// deleteAutomation обрабатывает DELETE /api/v1/automations/{automation_id}.
+func (h *Handler) deleteAutomation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.automationIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteAutomation(ctx, userID, id)
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteAutomation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete automation", nil)
+		return
+	}
+	log.Printf("request_id=%s automation deleted user=%d automation=%d", appMiddleware.GetRequestID(ctx), userID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAutomationRuns обрабатывает GET /api/v1/automations/{automation_id}/runs?limit=N -- журнал
+// выполнения, новые записи первыми.
+func (h *Handler) getAutomationRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.automationIDParam(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultAutomationRunsLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > automationRunsKept {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+				map[string]any{"limit": s, "max": automationRunsKept})
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.svc.AutomationRuns(ctx, userID, id, limit)
+	if h.writeAutomationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAutomationRuns error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get automation runs", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(runs)
+}
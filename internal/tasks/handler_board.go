@@ -0,0 +1,171 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// boardCtxKey -- ключ контекста, под которым boardAuth кладёт найденную доску.
+type boardCtxKey struct{}
+
+// CreatedBoard -- ответ на создание доски: токен показывается только здесь.
+// URL -- JSON-вид доски, Page -- страница веб-интерфейса (web/board.html).
+type CreatedBoard struct {
+	Board
+	Token string `json:"token"`
+	URL   string `json:"url"`
+	Page  string `json:"page"`
+}
+
+// writeBoardError отвечает на ошибки досок, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeBoardError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrBoardsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrBoardNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Board not found", nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// boardAuth -- авторизация публичных досок /board/{token}. Отозванная доска -- 404.
+// Как и у ссылок на задачи, ответы не кэшируются и не уходят в Referer.
+func (h *Handler) boardAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		b, err := h.svc.ResolveBoard(r.Context(), chi.URLParam(r, "token"))
+		if h.writeBoardError(w, r, err) {
+			return
+		}
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s boardAuth error: %v", appMiddleware.GetRequestID(r.Context()), err)
+			appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Internal error", nil)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), boardCtxKey{}, b)))
+	})
+}
+
+// createBoard обрабатывает POST /api/v1/boards.
+//
+//	{"name": "Ремонт кухни", "tag": "ремонт", "status": "in_progress", "include_done": false}
+func (h *Handler) createBoard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req CreateBoardRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	b, token, err := h.svc.CreateBoard(ctx, userID, req, time.Now())
+	if h.writeBoardError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createBoard error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create board", nil)
+		return
+	}
+
+	log.Printf("request_id=%s board created user=%d board=%d tag=%q", appMiddleware.GetRequestID(ctx), userID, b.ID, b.Tag)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(CreatedBoard{
+		Board: b,
+		Token: token,
+		URL:   "/board/" + token,
+		Page:  "board.html?token=" + url.QueryEscape(token),
+	})
+}
+
+// getBoards обрабатывает GET /api/v1/boards -- все доски (без токенов).
+func (h *Handler) getBoards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	list, err := h.svc.Boards(ctx)
+	if h.writeBoardError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getBoards error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get boards", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// deleteBoard обрабатывает DELETE /api/v1/boards/{board_id} -- отзыв доски.
+func (h *Handler) deleteBoard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	boardID, err := strconv.Atoi(chi.URLParam(r, "board_id"))
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid board ID",
+			map[string]any{"board_id": chi.URLParam(r, "board_id")})
+		return
+	}
+
+	err = h.svc.RevokeBoard(ctx, boardID)
+	if h.writeBoardError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteBoard error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to revoke board", nil)
+		return
+	}
+	h.publicCache.Flush() // отозванная доска не должна отдаваться из кэша
+	log.Printf("request_id=%s board revoked board=%d", appMiddleware.GetRequestID(ctx), boardID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getPublicBoard обрабатывает GET /board/{token} -- содержимое доски для посетителя без аккаунта.
+func (h *Handler) getPublicBoard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	b := ctx.Value(boardCtxKey{}).(Board)
+
+	view, err := h.svc.BoardView(ctx, b, time.Now())
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getPublicBoard error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get board", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(view)
+}
@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// getBurndown обрабатывает GET /api/v1/reports/burndown?tag=X&from=YYYY-MM-DD&to=today&assignee=ID --
+// по точке на день: открытые и выполненные задачи и остаток оценок.
+func (h *Handler) getBurndown(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	q := r.URL.Query()
+
+	to := q.Get("to")
+	if to == "" {
+		to = "today"
+	}
+	from := q.Get("from")
+	assignee := 0
+	if s := q.Get("assignee"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid assignee",
+				map[string]any{"assignee": s})
+			return
+		}
+		assignee = n
+	}
+
+	b, err := h.svc.Burndown(ctx, userID, q.Get("tag"), assignee, from, to, time.Now())
+	if errors.Is(err, ErrInvalidBurndownRange) {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", err.Error(),
+			map[string]any{"from": from, "to": to})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getBurndown error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build burndown", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(b)
+}
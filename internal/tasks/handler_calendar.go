@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// getCalendar обрабатывает GET /api/v1/calendar -- рабочий календарь (или календарь по умолчанию).
+func (h *Handler) getCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	c, err := h.svc.Calendar(ctx)
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getCalendar error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get calendar", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// updateCalendar обрабатывает PUT /api/v1/calendar -- календарь заменяется целиком.
+//
+//	{"timezone": "Europe/Moscow", "work_days": ["mon", "tue", "wed", "thu", "fri"],
+//	 "work_start": "09:00", "work_end": "18:00", "holidays": [{"date": "2026-11-04", "name": "День народного единства"}]}
+func (h *Handler) updateCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req Calendar
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	c, err := h.svc.SaveCalendar(ctx, userID, req, time.Now())
+	switch {
+	case errors.Is(err, ErrInvalidWorkHours):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+		return
+	case errors.Is(err, ErrCalendarUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	case err != nil:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateCalendar error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update calendar", nil)
+		return
+	}
+
+	log.Printf("request_id=%s calendar updated user=%d timezone=%s holidays=%d",
+		appMiddleware.GetRequestID(ctx), userID, c.Timezone, len(c.Holidays))
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// getCalendarDue обрабатывает GET /api/v1/calendar/due?business_days=N -- какой срок получит
+// задача с due_in_business_days = N, если создать её сейчас.
+func (h *Handler) getCalendarDue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	s := r.URL.Query().Get("business_days")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 365 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid business_days",
+			map[string]any{"business_days": s, "max": 365})
+		return
+	}
+
+	c, err := h.svc.Calendar(ctx)
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getCalendarDue error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get calendar", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"business_days": n,
+		"due":           c.DueInBusinessDays(time.Now(), n),
+	})
+}
@@ -0,0 +1,60 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// getCapacityReport обрабатывает GET /api/v1/reports/capacity?from=today&days=14&assignee=ID --
+// загрузка исполнителей по дням против их ёмкости, с отметкой перегруженных дней.
+func (h *Handler) getCapacityReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	q := r.URL.Query()
+
+	from := q.Get("from")
+	if from == "" {
+		from = "today"
+	}
+	days := DefaultCapacityDays
+	if s := q.Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			n = 0 // сервис ответит ErrInvalidCapacityRange
+		}
+		days = n
+	}
+	assignee := 0
+	if s := q.Get("assignee"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid assignee",
+				map[string]any{"assignee": s})
+			return
+		}
+		assignee = n
+	}
+
+	rep, err := h.svc.CapacityReport(ctx, userID, from, days, assignee, time.Now())
+	if errors.Is(err, ErrInvalidCapacityRange) {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", err.Error(),
+			map[string]any{"from": from, "days": q.Get("days")})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getCapacityReport error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build capacity report", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
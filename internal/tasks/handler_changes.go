@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// defaultChangesLimit / maxChangesLimit -- сколько изменений отдаём в одном ответе GET /changes.
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = changeFeedSize
+)
+
+// defaultChangesMaxWait -- предел ?wait=, если SetChangesMaxWait не вызывали.
+const defaultChangesMaxWait = 30 * time.Second
+
+// changesPath -- путь ленты изменений: общий таймаут запроса (RequestTimeoutMiddleware) его не касается,
+// getChanges ограничивает себя сам -- временем ожидания плюс changesGrace на ответ.
+const (
+	changesPath  = "/api/v1/changes"
+	changesGrace = 2 * time.Second
+)
+
+// SetChangesMaxWait задаёт предел ожидания в GET /api/v1/changes?wait=... (больше -- урезается до него).
+// Вызывать до Router().
+func (h *Handler) SetChangesMaxWait(d time.Duration) {
+	h.changesMaxWait = d
+}
+
+// getChanges обрабатывает GET /api/v1/changes?since=<cursor>&wait=30s&limit=100 -- long polling
+// изменений задач. Без since отвечает сразу: пустой список и курсор "с этого момента". С since --
+// изменения после курсора; если их пока нет, держит запрос до wait (по умолчанию не ждёт).
+// 410 cursor_expired -- курсор устарел (перезапуск сервера, лента ушла вперёд): перечитать задачи
+// через GET /api/v1/tasks и начать без since.
+func (h *Handler) getChanges(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(appMiddleware.UserIDKey).(int)
+	q := r.URL.Query()
+
+	maxWait := h.changesMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultChangesMaxWait
+	}
+	var wait time.Duration
+	if s := q.Get("wait"); s != "" {
+		// "30s", "1m" или просто секунды
+		d, err := time.ParseDuration(s)
+		if n, nerr := strconv.Atoi(s); nerr == nil {
+			d, err = time.Duration(n)*time.Second, nil
+		}
+		if err != nil || d < 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid wait",
+				map[string]any{"wait": s, "max": maxWait.String()})
+			return
+		}
+		wait = min(d, maxWait)
+	}
+
+	limit := defaultChangesLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxChangesLimit {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+				map[string]any{"limit": s, "max": maxChangesLimit})
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait+changesGrace)
+	defer cancel()
+
+	since := q.Get("since")
+	batch, err := h.svc.Changes(ctx, userID, since, wait, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCursor):
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid cursor",
+				map[string]any{"since": since})
+			return
+		case errors.Is(err, ErrCursorExpired):
+			appMiddleware.WriteError(w, r, http.StatusGone, "cursor_expired",
+				"Cursor expired, reload tasks and start over without since", map[string]any{"since": since})
+			return
+		}
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getChanges error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get changes", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(batch)
+}
@@ -0,0 +1,165 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// defaultNotificationsLimit -- сколько уведомлений отдаём по умолчанию; больше notificationsKept не хранится.
+const defaultNotificationsLimit = 50
+
+// writeCommentError отвечает на ошибки комментариев и уведомлений, общие для всех ручек.
+// Возвращает false, если ошибка не из их числа.
+func (h *Handler) writeCommentError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrCommentsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrTaskNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found", nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// getComments обрабатывает GET /api/v1/tasks/{id}/comments -- комментарии по порядку добавления.
+func (h *Handler) getComments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.svc.TaskComments(ctx, id, userID)
+	if h.writeCommentError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getComments error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get comments", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// createComment обрабатывает POST /api/v1/tasks/{id}/comments.
+//
+//	{"body": "@dad купи заодно батарейки"}
+//
+// Упомянутые через @username получают уведомление (GET /api/v1/me/notifications и почта).
+func (h *Handler) createComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req CommentRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	c, err := h.svc.AddComment(ctx, id, userID, req.Body, time.Now())
+	if h.writeCommentError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createComment error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save comment", nil)
+		return
+	}
+	log.Printf("request_id=%s comment created user=%d task=%d comment=%d mentions=%v",
+		appMiddleware.GetRequestID(ctx), userID, id, c.ID, c.Mentions)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// getNotifications обрабатывает GET /api/v1/me/notifications?unread=true&limit=N -- входящие
+// уведомления, новые первыми.
+func (h *Handler) getNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	limit := defaultNotificationsLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > notificationsKept {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+				map[string]any{"limit": s, "max": notificationsKept})
+			return
+		}
+		limit = n
+	}
+	unread := r.URL.Query().Get("unread") == "true"
+
+	list, err := h.svc.UserNotifications(ctx, userID, unread, limit)
+	if h.writeCommentError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getNotifications error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get notifications", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// markNotificationsRead обрабатывает POST /api/v1/me/notifications/read.
+//
+//	{"ids": [3, 4]}
+//
+// Без ids (или с пустым списком) прочитанными отмечаются все. В ответе -- сколько отмечено.
+func (h *Handler) markNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req MarkNotificationsReadRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	n, err := h.svc.MarkNotificationsRead(ctx, userID, req.IDs, time.Now())
+	if h.writeCommentError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s markNotificationsRead error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to mark notifications", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]int{"marked": n})
+}
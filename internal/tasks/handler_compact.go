@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminStorageRouter возвращает маршруты обслуживания хранилища относительно /api/v1/admin/storage.
+// Подключается в main под проверкой X-Admin-Key.
+func (h *Handler) AdminStorageRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Post("/compact", h.compactStorage)
+	return r
+}
+
+// compactStorage обрабатывает POST /api/v1/admin/storage/compact -- сжатие JSON-хранилища.
+// Отвечает размерами до и после. PostgreSQL сжимает себя сам (VACUUM) -- 501.
+func (h *Handler) compactStorage(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.CompactStorage(r.Context())
+	if err != nil {
+		if errors.Is(err, ErrCompactUnsupported) {
+			appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+			return
+		}
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s compactStorage error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to compact storage", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
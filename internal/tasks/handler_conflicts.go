@@ -0,0 +1,117 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// conflictsError отвечает на ошибки очереди конфликтов, общие для всех её обработчиков.
+// false -- ошибка не из них, отвечать вызывающему.
+func (h *Handler) conflictsError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrConflictsUnsupported), errors.Is(err, ErrSyncUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrConflictNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Conflict not found",
+			map[string]any{"conflict_id": chi.URLParam(r, "conflict_id")})
+	default:
+		return false
+	}
+	return true
+}
+
+// conflictIDParam разбирает {conflict_id}; при ошибке сам отвечает 400.
+func (h *Handler) conflictIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "conflict_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid conflict ID",
+			map[string]any{"conflict_id": chi.URLParam(r, "conflict_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// getConflicts обрабатывает GET /api/v1/conflicts -- открытые конфликты синхронизации пользователя.
+func (h *Handler) getConflicts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.Conflicts(ctx, userID)
+	if err != nil {
+		if h.conflictsError(w, r, err) || h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getConflicts error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get conflicts", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getConflict обрабатывает GET /api/v1/conflicts/{conflict_id}.
+func (h *Handler) getConflict(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	id, ok := h.conflictIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := h.svc.GetConflict(ctx, userID, id)
+	if err != nil {
+		if h.conflictsError(w, r, err) || h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getConflict error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get conflict", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// resolveConflict обрабатывает POST /api/v1/conflicts/{conflict_id}/resolve.
+//
+//	{"resolution": "server"}                              -- оставить версию сервера
+//	{"resolution": "client"}                              -- применить мутацию клиента
+//	{"resolution": "merged", "task": {"title": "...", ...}} -- сохранить слитую вручную задачу
+//
+// Ответ -- итог как у мутации в POST /api/v1/sync. Итог rejected (задача не прошла проверки)
+// конфликт не закрывает.
+func (h *Handler) resolveConflict(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	id, ok := h.conflictIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req ConflictResolution
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	res, err := h.svc.ResolveConflict(ctx, userID, id, req)
+	if err != nil {
+		if h.conflictsError(w, r, err) || h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s resolveConflict error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to resolve conflict", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FaultsRequest -- тело PUT /api/v1/admin/faults: правила сбоев целиком (пустой список -- убрать все).
+type FaultsRequest struct {
+	Faults []Fault `json:"faults" validate:"max=50,dive"`
+}
+
+// AdminFaultsRouter возвращает маршруты внедрения сбоев относительно /api/v1/admin/faults.
+// Подключается в main под проверкой X-Admin-Key и только в демо-режиме; в документации API
+// его нет. Без FaultStore отвечает 404, как будто маршрута нет.
+func (h *Handler) AdminFaultsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/", h.getFaults)
+	r.Put("/", h.setFaults)
+	r.Delete("/", h.clearFaults)
+	return r
+}
+
+// faultStore возвращает FaultStore сервиса; если его нет, сам отвечает 404.
+func (h *Handler) faultStore(w http.ResponseWriter, r *http.Request) (*FaultStore, bool) {
+	fs, err := h.svc.FaultStore()
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Not found", nil)
+		return nil, false
+	}
+	return fs, true
+}
+
+// getFaults обрабатывает GET /api/v1/admin/faults -- текущие правила и сколько раз они сработали.
+func (h *Handler) getFaults(w http.ResponseWriter, r *http.Request) {
+	fs, ok := h.faultStore(w, r)
+	if !ok {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(FaultsRequest{Faults: fs.Faults()})
+}
+
+// setFaults обрабатывает PUT /api/v1/admin/faults.
+//
+//	{"faults": [{"op": "save", "error": "disk_full", "count": 1}, {"op": "list", "latency_ms": 800, "rate": 0.2}]}
+func (h *Handler) setFaults(w http.ResponseWriter, r *http.Request) {
+	fs, ok := h.faultStore(w, r)
+	if !ok {
+		return
+	}
+	var req FaultsRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	if err := fs.SetFaults(req.Faults); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(FaultsRequest{Faults: fs.Faults()})
+}
+
+// clearFaults обрабатывает DELETE /api/v1/admin/faults -- убрать все сбои.
+func (h *Handler) clearFaults(w http.ResponseWriter, r *http.Request) {
+	fs, ok := h.faultStore(w, r)
+	if !ok {
+		return
+	}
+	_ = fs.SetFaults(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
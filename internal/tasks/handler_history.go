@@ -0,0 +1,119 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultHistoryLimit / maxHistoryLimit -- сколько ревизий отдаём в GET /history.
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// getHistory обрабатывает GET /api/v1/history?limit=N
+//
+// Возвращает список ревизий хранилища (новые -- первыми). Только чтение:
+// восстановление делается средствами git на сервере.
+func (h *Handler) getHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if !h.historyAllowed(w, r) {
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxHistoryLimit {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+				map[string]any{"limit": s, "max": maxHistoryLimit})
+			return
+		}
+		limit = n
+	}
+
+	revs, err := h.svc.History(ctx, limit)
+	if err != nil {
+		h.writeHistoryError(w, r, err, "")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(revs)
+}
+
+// getHistoryRevision обрабатывает GET /api/v1/history/{rev}
+//
+// Возвращает снимок задач на момент ревизии без задач чужих закрытых проектов.
+func (h *Handler) getHistoryRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	rev := chi.URLParam(r, "rev")
+
+	tasks, err := h.svc.TasksAt(ctx, rev, userID)
+	if err != nil {
+		h.writeHistoryError(w, r, err, rev)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(tasks)
+}
+
+// getHistoryDiff обрабатывает GET /api/v1/history/{rev}/diff
+//
+// Отдаёт unified diff в text/plain -- его удобно читать глазами или скормить patch/git apply.
+func (h *Handler) getHistoryDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if !h.historyAllowed(w, r) {
+		return
+	}
+	rev := chi.URLParam(r, "rev")
+
+	diff, err := h.svc.RevisionDiff(ctx, rev)
+	if err != nil {
+		h.writeHistoryError(w, r, err, rev)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(diff))
+}
+
+// historyAllowed пускает к списку ревизий и diff только администраторов пространства, если есть
+// закрытые проекты: diff -- это сырой файл хранилища со всеми задачами, а список ревизий выдаёт
+// ID изменённых задач. Снимок ревизии (getHistoryRevision) фильтруется и открыт всем.
+func (h *Handler) historyAllowed(w http.ResponseWriter, r *http.Request) bool {
+	userID := r.Context().Value(middleware.UserIDKey).(int)
+	if !h.svc.hasPrivateProjects() || slices.Contains(h.workspaceAdmins, userID) {
+		return true
+	}
+	appMiddleware.WriteError(w, r, http.StatusForbidden, "project_forbidden",
+		"With private projects, revision list and diff are for workspace admins (WORKSPACE_ADMINS) only", nil)
+	return false
+}
+
+// writeHistoryError маппит ошибки истории в HTTP-ответы.
+func (h *Handler) writeHistoryError(w http.ResponseWriter, r *http.Request, err error, rev string) {
+	switch {
+	case errors.Is(err, ErrHistoryUnavailable):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented",
+			"History is not enabled for this storage", nil)
+	case errors.Is(err, ErrRevisionNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Revision not found",
+			map[string]any{"rev": rev})
+	default:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s history error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to read history", nil)
+	}
+}
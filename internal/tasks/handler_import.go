@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// RemoteImporter -- коннектор к внешней системе (Trello, Jira), который сам забирает задачи по её API.
+type RemoteImporter interface {
+	// Fetch загружает задачи из source (ID доски Trello, ключ проекта Jira)
+	// и маппит их в нашу модель от имени userID.
+	Fetch(ctx context.Context, source string, userID int) ([]Task, error)
+}
+
+// ImportTimeout -- предел POST /api/v1/tasks/import вместо общего таймаута запроса (2 секунды):
+// коннекторы Trello и Jira ходят во внешний API постранично, с таймаутом 30 секунд и повторами
+// на каждый запрос. WriteTimeout сервера main поднимает выше него, иначе долгий ответ оборвётся.
+const ImportTimeout = 5 * time.Minute
+
+// importPath -- путь импорта: общий таймаут запроса (RequestTimeoutMiddleware) его не касается,
+// importTasks ограничивает себя сам (ImportTimeout).
+const importPath = "/api/v1/tasks/import"
+
+// importTasks обрабатывает POST /api/v1/tasks/import?format=...
+//
+//   - format=taskwarrior -- тело запроса: вывод `task export` как есть;
+//   - format=trello&source=<board_id>, format=jira&source=<project_key> -- задачи забираются
+//     коннектором по API внешней системы, тело не нужно.
+//
+// Повторный импорт не создаёт дублей (дедупликация по UUID).
+func (h *Handler) importTasks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ImportTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	format := r.URL.Query().Get("format")
+
+	var (
+		incoming []Task
+		skipped  int
+		err      error
+	)
+	switch imp, ok := h.importers[format]; {
+	case format == "taskwarrior":
+		incoming, skipped, err = ParseTaskwarrior(r.Body, userID)
+		if err != nil {
+			h.writeDecodeError(w, r, err)
+			return
+		}
+	case ok:
+		source := r.URL.Query().Get("source")
+		if source == "" {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Missing source",
+				map[string]any{"format": format})
+			return
+		}
+		incoming, err = imp.Fetch(ctx, source, userID)
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s importTasks fetch %s error: %v", appMiddleware.GetRequestID(ctx), format, err)
+			appMiddleware.WriteError(w, r, http.StatusBadGateway, "bad_gateway", "Failed to fetch from external system",
+				map[string]any{"format": format, "error": err.Error()})
+			return
+		}
+	default:
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Unsupported import format",
+			map[string]any{"format": format, "supported": h.importFormats()})
+		return
+	}
+
+	res, err := h.svc.ImportTasks(ctx, incoming, userID)
+	res.Skipped += skipped
+	if err != nil {
+		if h.writeHookError(w, r, err) || h.writeProjectError(w, r, err) || h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s importTasks error: %v (imported=%d)", appMiddleware.GetRequestID(ctx), err, res.Imported)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to import tasks",
+			map[string]any{"imported": res.Imported})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// importFormats -- список поддерживаемых форматов для подсказки клиенту.
+func (h *Handler) importFormats() []string {
+	formats := []string{"taskwarrior"}
+	for f := range h.importers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats[1:])
+	return formats
+}
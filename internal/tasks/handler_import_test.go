@@ -0,0 +1,49 @@
+package tasks_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// deadlineImporter -- коннектор, который сообщает, сколько времени осталось у запроса импорта.
+type deadlineImporter struct{ left chan time.Duration }
+
+func (d deadlineImporter) Fetch(ctx context.Context, source string, userID int) ([]tasks.Task, error) {
+	left := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		left = time.Until(deadline)
+	}
+	d.left <- left
+	return []tasks.Task{taskstest.NewTask("Из Trello", taskstest.Owner(userID), taskstest.WithUUID(taskstest.UUID(100)))}, nil
+}
+
+// TestImportDeadline -- удалённый импорт получает ImportTimeout, а не общий таймаут запроса в 2 секунды.
+func TestImportDeadline(t *testing.T) {
+	ctx := context.Background()
+	imp := deadlineImporter{left: make(chan time.Duration, 1)}
+	srv := taskstest.NewServer(tasks.NewMemoryStore(), "", func(h *tasks.Handler, _ *tasks.Service) {
+		h.RegisterImporter("trello", imp)
+	})
+	defer srv.Close()
+	token, err := srv.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res tasks.ImportResult
+	code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/tasks/import?format=trello&source=board", token, nil, &res)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("import: %d %v", code, err)
+	}
+	if res.Imported != 1 {
+		t.Errorf("imported %d tasks, want 1", res.Imported)
+	}
+	if left := <-imp.left; left < tasks.ImportTimeout-time.Minute || left > tasks.ImportTimeout {
+		t.Errorf("importer had %v left, want about %v", left, tasks.ImportTimeout)
+	}
+}
@@ -0,0 +1,284 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Лимиты выдачи polling-триггеров. Zapier по умолчанию разбирает до 50 записей за опрос.
+const (
+	defaultTriggerLimit = 50
+	maxTriggerLimit     = 100
+)
+
+// FlatTask -- "плоское" представление задачи для no-code платформ:
+// только скаляры, даты -- строки RFC 3339, метки -- строка через запятую.
+type FlatTask struct {
+	// ID -- ключ дедупликации для платформы. Для "новых задач" это ID задачи,
+	// для "выполненных" -- ID + время выполнения (повторное выполнение -- новое событие).
+	ID          string `json:"id"`
+	TaskID      int    `json:"task_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Done        bool   `json:"done"`
+	Priority    string `json:"priority"`
+	UserID      int    `json:"user_id"`
+	AssignedTo  int    `json:"assigned_to"`
+	Tags        string `json:"tags"`
+	Due         string `json:"due"`
+	CompletedAt string `json:"completed_at"`
+}
+
+func flattenTask(t *Task) FlatTask {
+	ft := FlatTask{
+		ID:          strconv.Itoa(t.ID),
+		TaskID:      t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Done:        t.Done,
+		Priority:    string(t.Priority),
+		UserID:      t.UserID,
+		AssignedTo:  t.AssignedTo,
+		Tags:        strings.Join(t.Tags, ", "),
+	}
+	if t.Due != nil {
+		ft.Due = t.Due.UTC().Format(time.RFC3339)
+	}
+	if t.CompletedAt != nil {
+		ft.CompletedAt = t.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	return ft
+}
+
+// FlatCreateTaskRequest -- тело действия "создать задачу". Всё, кроме title, необязательно.
+type FlatCreateTaskRequest struct {
+	Title       string `json:"title" validate:"required,title"`
+	Description string `json:"description" validate:"description"`
+	Priority    string `json:"priority" validate:"omitempty,priority"`
+	Tags        string `json:"tags" validate:"max=1000"` // "дом, покупки"
+	Due         string `json:"due"`                      // RFC 3339 или YYYY-MM-DD
+	AssignedTo  int    `json:"assigned_to"`
+}
+
+// FlatCompleteTaskRequest -- тело действия "выполнить задачу".
+type FlatCompleteTaskRequest struct {
+	TaskID int `json:"task_id" validate:"required,gt=0"`
+}
+
+// triggerNewTasks обрабатывает GET /api/v1/integrations/triggers/new-tasks?since_id=N&limit=50
+//
+// Курсор since_id -- максимальный ID, который клиент уже видел. Без курсора -- последние задачи.
+func (h *Handler) triggerNewTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+
+	limit, ok := h.triggerLimit(w, r)
+	if !ok {
+		return
+	}
+
+	sinceID := 0
+	if s := r.URL.Query().Get("since_id"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid since_id",
+				map[string]any{"since_id": s})
+			return
+		}
+		sinceID = n
+	}
+
+	list, err := h.svc.TasksCreatedAfter(ctx, userID, sinceID, limit)
+	if err != nil {
+		h.writeIntegrationError(w, r, err, "triggerNewTasks")
+		return
+	}
+
+	out := make([]FlatTask, 0, len(list))
+	for i := range list {
+		out = append(out, flattenTask(&list[i]))
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// triggerCompletedTasks обрабатывает GET /api/v1/integrations/triggers/completed-tasks?since=...&limit=50
+//
+// Курсор since -- время (RFC 3339 или unix-секунды); возвращаются задачи, выполненные позже.
+func (h *Handler) triggerCompletedTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+
+	limit, ok := h.triggerLimit(w, r)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := parseCursorTime(s)
+		if err != nil {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid since",
+				map[string]any{"since": s, "expected": "RFC 3339 or unix seconds"})
+			return
+		}
+		since = t
+	}
+
+	list, err := h.svc.TasksCompletedSince(ctx, userID, since, limit)
+	if err != nil {
+		h.writeIntegrationError(w, r, err, "triggerCompletedTasks")
+		return
+	}
+
+	out := make([]FlatTask, 0, len(list))
+	for i := range list {
+		ft := flattenTask(&list[i])
+		ft.ID = strconv.Itoa(list[i].ID) + "-" + strconv.FormatInt(list[i].CompletedAt.Unix(), 10)
+		out = append(out, ft)
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// actionCreateTask обрабатывает POST /api/v1/integrations/actions/create-task
+func (h *Handler) actionCreateTask(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+
+	var req FlatCreateTaskRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	task := Task{
+		UserID:      userID,
+		AssignedTo:  req.AssignedTo,
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    Priority(req.Priority),
+	}
+	if task.AssignedTo == 0 {
+		task.AssignedTo = userID
+	}
+	task.Priority = task.Priority.Resolve()
+	for _, tag := range strings.Split(req.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			task.Tags = append(task.Tags, tag)
+		}
+	}
+	if req.Due != "" {
+		due, err := parseFlatDate(req.Due)
+		if err != nil {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+				[]map[string]string{{"field": "due", "rule": "datetime"}})
+			return
+		}
+		task.Due = &due
+	}
+
+	if err := h.svc.CreateTask(ctx, &task); err != nil {
+		h.writeIntegrationError(w, r, err, "actionCreateTask")
+		return
+	}
+
+	if !IsDryRun(ctx) {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(flattenTask(&task))
+}
+
+// actionCompleteTask обрабатывает POST /api/v1/integrations/actions/complete-task
+func (h *Handler) actionCompleteTask(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := appMiddleware.UserIDFromContext(ctx)
+
+	var req FlatCompleteTaskRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	task, err := h.svc.GetTaskByID(ctx, req.TaskID, userID)
+	if err != nil {
+		h.writeIntegrationError(w, r, err, "actionCompleteTask")
+		return
+	}
+
+	task.Done = true
+	task.Status = StatusDone
+	if err := h.svc.UpdateTask(ctx, task, userID); err != nil {
+		h.writeIntegrationError(w, r, err, "actionCompleteTask")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(flattenTask(task))
+}
+
+// triggerLimit разбирает ?limit=N (по умолчанию 50, максимум 100).
+func (h *Handler) triggerLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return defaultTriggerLimit, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n > maxTriggerLimit {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+			map[string]any{"limit": s, "max": maxTriggerLimit})
+		return 0, false
+	}
+	return n, true
+}
+
+func (h *Handler) writeIntegrationError(w http.ResponseWriter, r *http.Request, err error, op string) {
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found", nil)
+		return
+	}
+	if h.writeHookError(w, r, err) || h.writeTransitionError(w, r, err) || h.writeProjectError(w, r, err) ||
+		h.handleContextError(w, r, err) {
+		return
+	}
+	log.Printf("request_id=%s %s error: %v", appMiddleware.GetRequestID(r.Context()), op, err)
+	appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Internal error", nil)
+}
+
+// parseCursorTime принимает RFC 3339 или unix-секунды.
+func parseCursorTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseFlatDate принимает RFC 3339 или просто дату YYYY-MM-DD (так её отдают многие no-code шаги).
+func parseFlatDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mergeTask обрабатывает POST /api/v1/tasks/{id}/merge.
+//
+//	{"source_ids": [7, 9]}
+//
+// Метки и подзадачи дублей переезжают в задачу {id}, дубли удаляются,
+// а их ID дальше перенаправляют на {id} (см. redirectMerged).
+func (h *Handler) mergeTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req MergeRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	task, err := h.svc.MergeTasks(ctx, id, req.SourceIDs, userID)
+	switch {
+	case err == nil:
+	case h.writeProjectError(w, r, err):
+		return
+	case errors.Is(err, ErrMergeSelf):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(),
+			map[string]any{"id": id})
+		return
+	case errors.Is(err, ErrMergeUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	case errors.Is(err, ErrTaskNotFound):
+		if h.redirectMerged(w, r, id) {
+			return
+		}
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id, "source_ids": req.SourceIDs, "reason": err.Error()})
+		return
+	case errors.Is(err, ErrTxConflict):
+		appMiddleware.WriteError(w, r, http.StatusConflict, "conflict", "Tasks were changed concurrently, retry", nil)
+		return
+	default:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s mergeTask error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to merge tasks", nil)
+		return
+	}
+
+	log.Printf("request_id=%s merge user=%d target=%d sources=%v", appMiddleware.GetRequestID(ctx), userID, id, req.SourceIDs)
+	_ = json.NewEncoder(w).Encode(task)
+}
+
+// redirectMerged отвечает перенаправлением, если задача id была слита в другую:
+// 301 для GET/HEAD и 308 для остальных методов (метод и тело запроса сохраняются).
+// Удаление по старому ID не перенаправляется -- иначе клиент, следующий редиректам,
+// удалит задачу, в которую слили дубль. Возвращает false, если перенаправлять некуда.
+func (h *Handler) redirectMerged(w http.ResponseWriter, r *http.Request, id int) bool {
+	to, ok, err := h.svc.MergedInto(r.Context(), id)
+	if err != nil {
+		log.Printf("request_id=%s redirectMerged error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if r.Method == http.MethodDelete {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task was merged into another task",
+			map[string]any{"id": id, "merged_into": to})
+		return true
+	}
+
+	loc := strings.Replace(r.URL.Path, "/tasks/"+chi.URLParam(r, "id"), "/tasks/"+strconv.Itoa(to), 1)
+	if r.URL.RawQuery != "" {
+		loc += "?" + r.URL.RawQuery
+	}
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	w.Header().Set("Location", loc)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "merged_into": to})
+	return true
+}
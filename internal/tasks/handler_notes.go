@@ -0,0 +1,206 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// userLocation -- часовой пояс пользователя для дат заметок и плана на день.
+// Если настройки прочитать не удалось, считаем в UTC (как и фильтр ?due=).
+func (h *Handler) userLocation(r *http.Request, userID int) *time.Location {
+	prefs, err := h.svc.GetPreferences(r.Context(), userID)
+	if err != nil {
+		log.Printf("request_id=%s preferences error: %v", appMiddleware.GetRequestID(r.Context()), err)
+	}
+	return prefs.Location()
+}
+
+// noteDateParam разбирает {date} из URL (YYYY-MM-DD или today). При ошибке отвечает 400.
+func (h *Handler) noteDateParam(w http.ResponseWriter, r *http.Request, userID int) (string, bool) {
+	date, err := ParseNoteDate(chi.URLParam(r, "date"), time.Now(), h.userLocation(r, userID))
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", err.Error(),
+			map[string]any{"date": chi.URLParam(r, "date")})
+		return "", false
+	}
+	return date, true
+}
+
+// writeNoteError отвечает на ошибки заметок, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа (тогда ответ пишет вызывающий).
+func (h *Handler) writeNoteError(w http.ResponseWriter, r *http.Request, err error, date string) bool {
+	switch {
+	case errors.Is(err, ErrNotesUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Notes are not supported by this storage", nil)
+	case errors.Is(err, ErrNoteNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Note not found",
+			map[string]any{"date": date})
+	case errors.Is(err, ErrInvalidNoteDate):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", err.Error(),
+			map[string]any{"date": date})
+	default:
+		return false
+	}
+	return true
+}
+
+// listNotes обрабатывает GET /api/v1/notes?from=YYYY-MM-DD&to=YYYY-MM-DD (границы включительно, обе необязательны).
+func (h *Handler) listNotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	loc := h.userLocation(r, userID)
+	bounds := [2]string{r.URL.Query().Get("from"), r.URL.Query().Get("to")}
+	for i, b := range bounds {
+		if b == "" {
+			continue
+		}
+		d, err := ParseNoteDate(b, time.Now(), loc)
+		if h.writeNoteError(w, r, err, b) {
+			return
+		}
+		bounds[i] = d
+	}
+
+	notes, err := h.svc.ListNotes(ctx, userID, bounds[0], bounds[1])
+	if h.writeNoteError(w, r, err, "") {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s listNotes error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get notes", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(notes)
+}
+
+// getNote обрабатывает GET /api/v1/notes/{date}.
+func (h *Handler) getNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	date, ok := h.noteDateParam(w, r, userID)
+	if !ok {
+		return
+	}
+	n, err := h.svc.GetNote(ctx, userID, date)
+	if h.writeNoteError(w, r, err, date) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getNote error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get note", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(n)
+}
+
+// saveNote обрабатывает PUT /api/v1/notes/{date} -- заметка за день создаётся или заменяется целиком.
+// Новая заметка -- 201, замена -- 200.
+func (h *Handler) saveNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	date, ok := h.noteDateParam(w, r, userID)
+	if !ok {
+		return
+	}
+	var req SaveNoteRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	n, created, err := h.svc.SaveNote(ctx, userID, date, req.Body, time.Now())
+	if h.writeNoteError(w, r, err, date) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s saveNote error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save note", nil)
+		return
+	}
+	if created {
+		w.Header().Set("Location", "/api/v1/notes/"+date)
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(n)
+}
+
+// deleteNote обрабатывает DELETE /api/v1/notes/{date}.
+func (h *Handler) deleteNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	date, ok := h.noteDateParam(w, r, userID)
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteNote(ctx, userID, date)
+	if h.writeNoteError(w, r, err, date) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteNote error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete note", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAgenda обрабатывает GET /api/v1/agenda?date=YYYY-MM-DD (по умолчанию сегодня):
+// заметка за день и задачи со сроком в этот день.
+func (h *Handler) getAgenda(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	now := time.Now()
+	loc := h.userLocation(r, userID)
+	ref := r.URL.Query().Get("date")
+	if ref == "" {
+		ref = "today"
+	}
+	date, err := ParseNoteDate(ref, now, loc)
+	if h.writeNoteError(w, r, err, ref) {
+		return
+	}
+
+	a, err := h.svc.Agenda(ctx, userID, date, now, loc)
+	if h.writeNoteError(w, r, err, date) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAgenda error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build agenda", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(a)
+}
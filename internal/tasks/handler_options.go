@@ -0,0 +1,161 @@
+package tasks
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeMethods -- методы, которые проверяются для заголовка Allow. HEAD и OPTIONS добавляются сами:
+// HEAD есть у каждого GET-маршрута, OPTIONS -- у каждого пути.
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Capabilities -- ответ OPTIONS: что умеет сервер, чтобы клиент включал функции по факту, а не по версии.
+// Данных пользователей в нём нет, поэтому OPTIONS доступен без входа (как и preflight CORS).
+type Capabilities struct {
+	Path       string               `json:"path"`
+	Allow      []string             `json:"allow"`
+	APIVersion string               `json:"api_version"`
+	Tasks      TaskListCapabilities `json:"tasks"`
+	Limits     ValidationLimits     `json:"limits"`
+	// Features -- включённые возможности: false -- выключена настройкой или не поддерживается хранилищем.
+	Features map[string]bool `json:"features"`
+	// ImportFormats -- подключённые коннекторы POST /api/v1/tasks/import/{format} (Trello, Jira...).
+	ImportFormats []string `json:"import_formats"`
+}
+
+// TaskListCapabilities -- параметры GET /api/v1/tasks.
+type TaskListCapabilities struct {
+	Filters     []string `json:"filters"`       // параметры запроса; field.{name} -- по пользовательскому полю
+	Due         []string `json:"due"`           // значения ?due=
+	Sort        []string `json:"sort"`          // ключи ?sort=, "-" перед ключом -- по убыванию
+	MaxPageSize int      `json:"max_page_size"` // наибольший ?limit=
+}
+
+// SetFeature отмечает возможность, о которой знает только main (CalDAV, веб-интерфейс, метрики).
+// Вызывать до Router().
+func (h *Handler) SetFeature(name string, on bool) {
+	if h.features == nil {
+		h.features = make(map[string]bool)
+	}
+	h.features[name] = on
+}
+
+// discovery отвечает на OPTIONS любого известного пути: Allow со списком методов и документ
+// возможностей. Preflight-запросы CORS сюда не доходят -- на них отвечает CORS-фильтр.
+// HEAD без своего маршрута направляется в GET-маршрут: net/http отбрасывает тело и оставляет заголовки.
+// Таблица маршрутов собирается обходом роутера при первом запросе, когда все маршруты уже объявлены.
+func (h *Handler) discovery(routes chi.Routes) func(http.Handler) http.Handler {
+	var (
+		once  sync.Once
+		table []appMiddleware.RoutePattern
+	)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			once.Do(func() { table = walkRoutes(routes) })
+			if r.Method == http.MethodHead {
+				if !hasRoute(table, http.MethodHead, r.URL.Path) {
+					chi.RouteContext(r.Context()).RouteMethod = http.MethodGet
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			allow := allowedMethods(table, r.URL.Path)
+			if len(allow) == 0 {
+				appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Route not found",
+					map[string]any{"path": r.URL.Path})
+				return
+			}
+			// OPTIONS не доходит до маршрутизации: шаблон пути для метрик (метка route) отмечается здесь.
+			rctx := chi.RouteContext(r.Context())
+			rctx.RoutePatterns = append(rctx.RoutePatterns, routeOf(table, r.URL.Path))
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			doc := h.capabilities()
+			doc.Path, doc.Allow = r.URL.Path, allow
+			_ = json.NewEncoder(w).Encode(doc)
+		})
+	}
+}
+
+// walkRoutes -- все маршруты роутера шаблонами "METHOD /path". chi.Mux.Match (и chi GetHead) для этого
+// не годится: во вложенных роутерах он находит путь и для метода, которого у маршрута нет.
+func walkRoutes(routes chi.Routes) []appMiddleware.RoutePattern {
+	var table []appMiddleware.RoutePattern
+	_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		table = append(table, appMiddleware.RoutePattern{Method: method, Path: route})
+		return nil
+	})
+	return table
+}
+
+// allowedMethods -- методы, на которые у пути есть маршрут.
+func allowedMethods(table []appMiddleware.RoutePattern, path string) []string {
+	var allow []string
+	for _, m := range routeMethods {
+		if !hasRoute(table, m, path) {
+			continue
+		}
+		allow = append(allow, m)
+		if m == http.MethodGet {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	if len(allow) > 0 {
+		allow = append(allow, http.MethodOptions)
+	}
+	return allow
+}
+
+// routeOf -- шаблон первого маршрута пути (любого метода). Вызывать, когда маршрут у пути есть.
+func routeOf(table []appMiddleware.RoutePattern, path string) string {
+	i := slices.IndexFunc(table, func(p appMiddleware.RoutePattern) bool { return p.Match(p.Method, path) })
+	return table[i].Path
+}
+
+// hasRoute сообщает, есть ли у пути маршрут именно с этим методом (RoutePattern.Match пускает HEAD к GET).
+func hasRoute(table []appMiddleware.RoutePattern, method, path string) bool {
+	return slices.ContainsFunc(table, func(p appMiddleware.RoutePattern) bool {
+		return p.Method == method && p.Match(method, path)
+	})
+}
+
+// capabilities собирает документ возможностей из настроек обработчика, сервиса и хранилища.
+func (h *Handler) capabilities() Capabilities {
+	features := h.svc.storageFeatures()
+	features["integrations"] = len(h.apiKeys) > 0
+	features["anonymous_read"] = h.anonymousUser > 0
+	features["policy_engine"] = h.authorization != nil
+	features["priority_numeric"] = h.priorityNumeric
+	features["action_links"] = h.svc.publicURL != ""
+	features["custom_fields"] = len(h.svc.FieldDefs().List()) > 0
+	features["demo_outbox"] = h.outbox != nil
+	maps.Copy(features, h.features)
+
+	formats := slices.Sorted(maps.Keys(h.importers))
+	if formats == nil {
+		formats = []string{}
+	}
+	return Capabilities{
+		APIVersion: "v1",
+		Tasks: TaskListCapabilities{
+			Filters:     []string{"due", FieldFilterPrefix + "{name}", "sort", "limit", "offset", "render"},
+			Due:         []string{DueOverdue, DueToday, DueTomorrow, DueThisWeek, DueNextWeek, DueThisMonth, DueNext7d, DueNext30d, DueNone},
+			Sort:        []string{SortByID, SortByPriority, SortByDue, SortByUrgency},
+			MaxPageSize: maxPageLimit,
+		},
+		Limits:        h.svc.ValidationLimits(),
+		Features:      features,
+		ImportFormats: formats,
+	}
+}
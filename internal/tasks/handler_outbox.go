@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SetOutbox подключает ящик демо-режима к /api/v1/demo/outbox (nil -- маршрутов нет).
+// Вызывать до Router().
+func (h *Handler) SetOutbox(o *Outbox) {
+	h.outbox = o
+}
+
+// getOutbox обрабатывает GET /api/v1/demo/outbox?kind=email|webhook -- что было бы отправлено, новые первыми.
+func (h *Handler) getOutbox(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != OutboxEmail && kind != OutboxWebhook {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "kind must be email or webhook",
+			map[string]any{"kind": kind})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(h.outbox.Messages(kind))
+}
+
+// getOutboxMessage обрабатывает GET /api/v1/demo/outbox/{message_id}.
+func (h *Handler) getOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "message_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid message ID",
+			map[string]any{"message_id": chi.URLParam(r, "message_id")})
+		return
+	}
+	m, ok := h.outbox.Message(id)
+	if !ok {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Message not found",
+			map[string]any{"message_id": id})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+// clearOutbox обрабатывает DELETE /api/v1/demo/outbox.
+func (h *Handler) clearOutbox(w http.ResponseWriter, r *http.Request) {
+	h.outbox.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
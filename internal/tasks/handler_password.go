@@ -0,0 +1,178 @@
+package tasks
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// changePassword обрабатывает POST /api/v1/auth/password: новый пароль в обмен на текущий.
+// Неверный текущий пароль считается неудачным входом (блокировка перебора).
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req ChangePasswordRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	ip := appMiddleware.ClientIP(r)
+	if h.lockedOut(w, r, "", ip, "password_change") {
+		return
+	}
+
+	err := h.svc.ChangePassword(ctx, userID, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, ErrInvalidCredentials) {
+		h.authGuard.Fail("", ip, "password_change", time.Now())
+		appMiddleware.WriteError(w, r, http.StatusForbidden, "invalid_password", "Текущий пароль неверен", nil)
+		return
+	}
+	if err != nil {
+		h.writePasswordError(w, r, "changePassword", err)
+		return
+	}
+	log.Printf("request_id=%s user id=%d changed password", appMiddleware.GetRequestID(ctx), userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setEmail обрабатывает PUT /api/v1/auth/email: адрес для писем о сбросе пароля.
+func (h *Handler) setEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req SetEmailRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	ip := appMiddleware.ClientIP(r)
+	if h.lockedOut(w, r, "", ip, "email_change") {
+		return
+	}
+
+	err := h.svc.SetEmail(ctx, userID, req.Password, req.Email)
+	if errors.Is(err, ErrInvalidCredentials) {
+		h.authGuard.Fail("", ip, "email_change", time.Now())
+		appMiddleware.WriteError(w, r, http.StatusForbidden, "invalid_password", "Пароль неверен", nil)
+		return
+	}
+	if err != nil {
+		h.writePasswordError(w, r, "setEmail", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forgotPassword обрабатывает POST /api/v1/auth/password/forgot. Ответ всегда 202, есть
+// такой пользователь или нет. Каждый запрос засчитывается адресу как неудачная попытка:
+// иначе сбросом можно было бы заваливать чужие ящики письмами.
+func (h *Handler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ForgotPasswordRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	ip := appMiddleware.ClientIP(r)
+	if h.lockedOut(w, r, "", ip, "password_reset") {
+		return
+	}
+	h.authGuard.Fail("", ip, "password_reset", time.Now())
+
+	err := h.svc.RequestPasswordReset(ctx, req.Login, time.Now())
+	if errors.Is(err, ErrMailerNotConfigured) {
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Сброс пароля по почте не настроен (SMTP_ADDR)", nil)
+		return
+	}
+	if err != nil {
+		h.writePasswordError(w, r, "forgotPassword", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"status":"accepted"}` + "\n"))
+}
+
+// resetPassword обрабатывает POST /api/v1/auth/password/reset: новый пароль по токену из письма.
+func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req ResetPasswordRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	ip := appMiddleware.ClientIP(r)
+	if h.lockedOut(w, r, "", ip, "password_reset") {
+		return
+	}
+
+	err := h.svc.ResetPassword(ctx, req.Token, req.NewPassword, time.Now())
+	if errors.Is(err, ErrInvalidResetToken) {
+		h.authGuard.Fail("", ip, "password_reset", time.Now())
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "invalid_token", "Ссылка для сброса пароля недействительна или устарела", nil)
+		return
+	}
+	if err != nil {
+		h.writePasswordError(w, r, "resetPassword", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lockedOut отвечает 429, если адрес (или логин) заблокирован после серии неудач.
+func (h *Handler) lockedOut(w http.ResponseWriter, r *http.Request, username, ip, source string) bool {
+	wait, locked := h.authGuard.Check(username, ip, source, time.Now())
+	if !locked {
+		return false
+	}
+	w.Header().Set("Retry-After", appMiddleware.RetryAfterHeader(wait))
+	appMiddleware.WriteError(w, r, http.StatusTooManyRequests, "locked_out", "Слишком много неудачных попыток, попробуйте позже",
+		map[string]any{"retry_after_seconds": int(wait.Round(time.Second).Seconds())})
+	return true
+}
+
+// writePasswordError -- общие ответы операций с паролем: политика, хранилище, внутренние ошибки.
+func (h *Handler) writePasswordError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	var policy *PasswordPolicyError
+	switch {
+	case errors.As(err, &policy):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "weak_password", "Пароль не соответствует требованиям",
+			map[string]any{"problems": policy.Problems, "policy": h.svc.passwordPolicy})
+	case errors.Is(err, ErrProvisioningUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Password management needs PostgreSQL storage", nil)
+	case errors.Is(err, ErrUserNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "User not found", nil)
+	default:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s %s error: %v", appMiddleware.GetRequestID(r.Context()), op, err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Внутренняя ошибка сервера", nil)
+	}
+}
@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/pdf"
+)
+
+// SetPDFFont задаёт шрифт для PDF-распечаток. nil -- Helvetica с транслитерацией кириллицы.
+func (h *Handler) SetPDFFont(f *pdf.Font) {
+	h.pdfFont = f
+}
+
+// getAgendaPDF обрабатывает GET /api/v1/agenda/pdf?date=today -- план на день для печати.
+func (h *Handler) getAgendaPDF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	now := time.Now()
+	loc := h.userLocation(r, userID)
+	ref := r.URL.Query().Get("date")
+	if ref == "" {
+		ref = "today"
+	}
+	date, err := ParseNoteDate(ref, now, loc)
+	if h.writeNoteError(w, r, err, ref) {
+		return
+	}
+
+	a, err := h.svc.Agenda(ctx, userID, date, now, loc)
+	if h.writeNoteError(w, r, err, date) {
+		return
+	}
+	var users map[int]string
+	if err == nil {
+		users, err = h.usernames(r)
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getAgendaPDF error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build agenda", nil)
+		return
+	}
+	writePDF(w, "agenda-"+date+".pdf", AgendaPDF(a, users, h.pdfFont, now, loc))
+}
+
+// getProjectPDF обрабатывает GET /api/v1/reports/project.pdf?tag=home -- сводка по проекту (метке) для печати.
+func (h *Handler) getProjectPDF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	tag := r.URL.Query().Get("tag")
+	if len(tag) > 50 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "tag must be at most 50 characters", nil)
+		return
+	}
+
+	now := time.Now()
+	loc := h.userLocation(r, userID)
+	p, err := h.svc.ProjectSummary(ctx, userID, tag, now)
+	var users map[int]string
+	if err == nil {
+		users, err = h.usernames(r)
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getProjectPDF error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build summary", nil)
+		return
+	}
+	name := "project.pdf"
+	if tag != "" {
+		name = "project-" + tag + ".pdf"
+	}
+	writePDF(w, name, ProjectPDF(p, users, h.pdfFont, now, loc))
+}
+
+// usernames -- имена пользователей по ID для подписи исполнителей.
+func (h *Handler) usernames(r *http.Request) (map[int]string, error) {
+	users, err := h.svc.GetAllUsers(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int]string, len(users))
+	for _, u := range users {
+		m[u.ID] = u.Username
+	}
+	return m, nil
+}
+
+// writePDF отдаёт PDF для просмотра в браузере (inline): оттуда его и печатают.
+func writePDF(w http.ResponseWriter, name string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// getPreferences обрабатывает GET /api/v1/me/preferences.
+// Пользователь, который ничего не сохранял, получает значения по умолчанию.
+func (h *Handler) getPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	p, err := h.svc.GetPreferences(ctx, userID)
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getPreferences error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get preferences", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// updatePreferences обрабатывает PUT /api/v1/me/preferences -- настройки заменяются целиком.
+func (h *Handler) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var p Preferences
+	if err := decodeJSONStrict(r, &p); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(p); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	err := h.svc.UpdatePreferences(ctx, userID, p)
+	if errors.Is(err, ErrPreferencesUnsupported) {
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Preferences are not supported by this storage", nil)
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updatePreferences error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save preferences", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(p)
+}
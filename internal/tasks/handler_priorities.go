@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// getPriorityScale обрабатывает GET /api/v1/priorities -- действующая шкала приоритетов.
+func (h *Handler) getPriorityScale(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(ActivePriorityScale())
+}
+
+// updatePriorityScale обрабатывает PUT /api/v1/priorities -- шкала заменяется целиком,
+// задачи с исчезнувшими уровнями переводятся по remap или по классу.
+//
+//	{"levels": [{"id": "p2", "label": "P2", "class": "low"}, {"id": "p1", "label": "P1", "class": "high"},
+//	 {"id": "p0", "label": "P0 -- блокер", "class": "critical"}], "default": "p2", "remap": {"medium": "p2"}}
+func (h *Handler) updatePriorityScale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req PriorityScaleRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	res, err := h.svc.SavePriorityScale(ctx, userID, req, time.Now())
+	switch {
+	case errors.Is(err, ErrInvalidPriorityScale):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+		return
+	case errors.Is(err, ErrPriorityScaleUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	case err != nil:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updatePriorityScale error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update priority scale", nil)
+		return
+	}
+
+	log.Printf("request_id=%s priority scale updated user=%d levels=%v default=%s migrated=%d",
+		appMiddleware.GetRequestID(ctx), userID, res.IDs(), res.Default, res.Migrated)
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// writeProjectACLError отвечает на ошибки закрытых проектов, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeProjectACLError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrProjectACLUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrProjectNotPrivate):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Project is not private",
+			map[string]any{"tag": tagParam(r)})
+	case errors.Is(err, ErrInvalidProjectACL):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	case errors.Is(err, ErrProjectForbidden):
+		appMiddleware.WriteError(w, r, http.StatusForbidden, "project_forbidden", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// tagParam -- {tag} из URL: метки бывают не латиницей, а chi отдаёт параметр как в пути, в %-кодировке.
+func tagParam(r *http.Request) string {
+	tag := chi.URLParam(r, "tag")
+	if s, err := url.PathUnescape(tag); err == nil {
+		return s
+	}
+	return tag
+}
+
+// getProjectACLs обрабатывает GET /api/v1/projects -- закрытые проекты, где пользователь участник
+// (администратору пространства -- все).
+func (h *Handler) getProjectACLs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.ProjectACLs(ctx, userID, slices.Contains(h.workspaceAdmins, userID))
+	if h.writeProjectACLError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getProjectACLs error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get projects", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// updateProjectACL обрабатывает PUT /api/v1/projects/{tag}/acl -- закрыть проект или сменить участников.
+//
+//	{"members": [1, 2]}
+func (h *Handler) updateProjectACL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req ProjectACLRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	acl, err := h.svc.SetProjectACL(ctx, userID, slices.Contains(h.workspaceAdmins, userID),
+		tagParam(r), req.Members, time.Now())
+	if h.writeProjectACLError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateProjectACL error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update project", nil)
+		return
+	}
+
+	log.Printf("request_id=%s project acl updated user=%d tag=%q members=%v",
+		appMiddleware.GetRequestID(ctx), userID, acl.Tag, acl.Members)
+	_ = json.NewEncoder(w).Encode(acl)
+}
+
+// deleteProjectACL обрабатывает DELETE /api/v1/projects/{tag}/acl -- открыть проект всей семье.
+func (h *Handler) deleteProjectACL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	tag := tagParam(r)
+
+	err := h.svc.DeleteProjectACL(ctx, userID, slices.Contains(h.workspaceAdmins, userID), tag)
+	if h.writeProjectACLError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteProjectACL error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to open project", nil)
+		return
+	}
+	log.Printf("request_id=%s project opened user=%d tag=%q", appMiddleware.GetRequestID(ctx), userID, tag)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,189 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// writeRelationError отвечает на ошибки связей, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeRelationError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrRelationsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrRelationNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Relation not found",
+			map[string]any{"rel_id": chi.URLParam(r, "rel_id")})
+	case errors.Is(err, ErrRelationExists):
+		appMiddleware.WriteError(w, r, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.Is(err, ErrRelationSelf):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// getRelations обрабатывает GET /api/v1/tasks/{id}/relations -- связи задачи в обе стороны.
+func (h *Handler) getRelations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.svc.GetTaskByID(ctx, id, userID); errors.Is(err, ErrTaskNotFound) {
+		if h.redirectMerged(w, r, id) {
+			return
+		}
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id})
+		return
+	}
+
+	rels, err := h.svc.TaskRelations(ctx, id, userID)
+	if h.writeRelationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getRelations error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get relations", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(rels)
+}
+
+// createRelation обрабатывает POST /api/v1/tasks/{id}/relations.
+//
+//	{"type": "blocks", "task_id": 7}
+//
+// Создаёт связь {id} --type--> task_id.
+func (h *Handler) createRelation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateRelationRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	rel := Relation{FromID: id, ToID: req.TaskID, Type: req.Type, CreatedBy: userID}
+	err := h.svc.CreateRelation(ctx, &rel)
+	if h.writeRelationError(w, r, err) {
+		return
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id, "task_id": req.TaskID, "reason": err.Error()})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createRelation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save relation", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rel)
+}
+
+// deleteRelation обрабатывает DELETE /api/v1/tasks/{id}/relations/{rel_id}.
+// Связь должна касаться задачи {id}, иначе 404.
+func (h *Handler) deleteRelation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	relID, err := strconv.Atoi(chi.URLParam(r, "rel_id"))
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid relation ID",
+			map[string]any{"rel_id": chi.URLParam(r, "rel_id")})
+		return
+	}
+
+	rels, err := h.svc.TaskRelations(ctx, id, userID)
+	if err == nil {
+		err = ErrRelationNotFound
+		for _, rel := range rels {
+			if rel.ID == relID {
+				err = h.svc.DeleteRelation(ctx, relID)
+				break
+			}
+		}
+	}
+	if h.writeRelationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteRelation error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete relation", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getRelationGraph обрабатывает GET /api/v1/tasks/graph?format=json|dot&all=true --
+// граф связей для инструментов визуализации (Graphviz, d3 и т.п.).
+func (h *Handler) getRelationGraph(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "format must be json or dot",
+			map[string]any{"format": format})
+		return
+	}
+
+	g, err := h.svc.RelationGraph(ctx, userID, r.URL.Query().Get("all") == "true")
+	if h.writeRelationError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getRelationGraph error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build graph", nil)
+		return
+	}
+
+	if format == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		_, _ = w.Write([]byte(g.DOT()))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(g)
+}
@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"net/http"
+
+	"task-manager/internal/markdown"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// RenderedTask -- задача вместе с описанием, отрендеренным из markdown в безопасный HTML (?render=html).
+type RenderedTask struct {
+	Task
+	DescriptionHTML string `json:"description_html"`
+}
+
+// renderParam читает ?render=. Пока поддерживается только html; при неизвестном значении отвечает 400.
+func (h *Handler) renderParam(w http.ResponseWriter, r *http.Request) (render, ok bool) {
+	switch v := r.URL.Query().Get("render"); v {
+	case "":
+		return false, true
+	case "html":
+		return true, true
+	default:
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "render must be html",
+			map[string]any{"render": v})
+		return false, false
+	}
+}
+
+// renderTask рендерит описание задачи.
+func renderTask(t Task) RenderedTask {
+	return RenderedTask{Task: t, DescriptionHTML: markdown.Render(t.Description)}
+}
+
+// renderTasks рендерит описания списка задач.
+func renderTasks(list []Task) []RenderedTask {
+	out := make([]RenderedTask, len(list))
+	for i := range list {
+		out[i] = renderTask(list[i])
+	}
+	return out
+}
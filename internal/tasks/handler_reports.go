@@ -0,0 +1,272 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminReportsRouter возвращает маршруты отчётов по расписанию относительно /api/v1/admin/reports.
+// Подключается в main под проверкой X-Admin-Key.
+func (h *Handler) AdminReportsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/", h.getReportSchedules)
+	r.Post("/", h.createReportSchedule)
+	r.Get("/{report_id}", h.getReportSchedule)
+	r.Put("/{report_id}", h.updateReportSchedule)
+	r.Delete("/{report_id}", h.deleteReportSchedule)
+	r.Get("/{report_id}/preview", h.previewReport)
+	r.Post("/{report_id}/run", h.runReport)
+	return r
+}
+
+// writeReportError отвечает на ошибки отчётов, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeReportError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrReportsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrReportNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Report schedule not found", nil)
+	case errors.Is(err, ErrInvalidCron), errors.Is(err, ErrScheduleNever),
+		errors.Is(err, ErrReportNoDelivery), errors.Is(err, ErrReportTemplate):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// reportIDParam разбирает {report_id}; при ошибке сам отвечает 400.
+func (h *Handler) reportIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "report_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid report ID",
+			map[string]any{"report_id": chi.URLParam(r, "report_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeReportSchedule читает и проверяет тело запроса; при ошибке сам отвечает 400.
+func (h *Handler) decodeReportSchedule(w http.ResponseWriter, r *http.Request) (ReportScheduleRequest, bool) {
+	var req ReportScheduleRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return req, false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return req, false
+	}
+	return req, true
+}
+
+// createReportSchedule обрабатывает POST /api/v1/admin/reports.
+//
+//	{"name": "Просрочено за неделю", "kind": "overdue", "format": "csv", "cron": "0 9 * * 1",
+//	 "email": ["team@example.com"], "webhook": "https://example.com/reports"}
+func (h *Handler) createReportSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, ok := h.decodeReportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.svc.CreateReportSchedule(ctx, req, time.Now())
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createReportSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create report schedule", nil)
+		return
+	}
+
+	appMiddleware.LogAdminAction(r, "report schedule created id=%d kind=%s", rs.ID, rs.Kind)
+	w.Header().Set("Location", "/api/v1/admin/reports/"+strconv.Itoa(rs.ID))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rs)
+}
+
+// getReportSchedules обрабатывает GET /api/v1/admin/reports.
+func (h *Handler) getReportSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	list, err := h.svc.ReportSchedules(ctx)
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getReportSchedules error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get report schedules", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getReportSchedule обрабатывает GET /api/v1/admin/reports/{report_id}.
+func (h *Handler) getReportSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.reportIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.svc.GetReportSchedule(ctx, id)
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getReportSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get report schedule", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(rs)
+}
+
+// updateReportSchedule обрабатывает PUT /api/v1/admin/reports/{report_id} -- расписание заменяется целиком.
+func (h *Handler) updateReportSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.reportIDParam(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeReportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.svc.UpdateReportSchedule(ctx, id, req, time.Now())
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateReportSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update report schedule", nil)
+		return
+	}
+	appMiddleware.LogAdminAction(r, "report schedule updated id=%d enabled=%t", id, rs.Enabled)
+	_ = json.NewEncoder(w).Encode(rs)
+}
+
+// deleteReportSchedule обрабатывает DELETE /api/v1/admin/reports/{report_id}.
+func (h *Handler) deleteReportSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.reportIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteReportSchedule(ctx, id)
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteReportSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete report schedule", nil)
+		return
+	}
+	appMiddleware.LogAdminAction(r, "report schedule deleted id=%d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewReport обрабатывает GET /api/v1/admin/reports/{report_id}/preview -- отчёт на текущий момент
+// без отправки. ?part=file отдаёт само вложение (CSV/JSON), по умолчанию -- тема и текст письма.
+func (h *Handler) previewReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.reportIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.svc.GetReportSchedule(ctx, id)
+	var p ReportPreview
+	if err == nil {
+		p, err = h.svc.PreviewReport(ctx, rs, time.Now())
+	}
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s previewReport error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to build report", nil)
+		return
+	}
+
+	if r.URL.Query().Get("part") == "file" {
+		w.Header().Set("Content-Type", p.Attachment.ContentType)
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+p.Attachment.Name+"\"")
+		_, _ = w.Write(p.Attachment.Data)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"subject":    p.Subject,
+		"body":       p.Body,
+		"count":      p.Count,
+		"attachment": p.Attachment.Name,
+	})
+}
+
+// runReport обрабатывает POST /api/v1/admin/reports/{report_id}/run -- собрать и отправить отчёт сейчас.
+// Ошибка доставки -- 502 с текстом ошибки; она же остаётся в last_error.
+func (h *Handler) runReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.reportIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.svc.RunReportNow(ctx, id, time.Now())
+	if h.writeReportError(w, r, err) {
+		return
+	}
+	if err != nil && rs.ID != 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadGateway, "delivery_failed", rs.LastError, nil)
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s runReport error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to run report", nil)
+		return
+	}
+	appMiddleware.LogAdminAction(r, "report delivered id=%d", id)
+	_ = json.NewEncoder(w).Encode(rs)
+}
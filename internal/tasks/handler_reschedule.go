@@ -0,0 +1,66 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// rescheduleTasks обрабатывает POST /api/v1/tasks/reschedule.
+//
+//	{"filter": {"due": "overdue"}, "shift": "1d"}
+//
+// Сдвигает сроки подходящих задач атомарно и возвращает сводку изменений.
+func (h *Handler) rescheduleTasks(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req RescheduleRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	prefs, err := h.svc.GetPreferences(ctx, userID)
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s rescheduleTasks preferences error: %v", appMiddleware.GetRequestID(ctx), err)
+	}
+
+	res, err := h.svc.Reschedule(ctx, userID, req, time.Now(), prefs.Location())
+	if errors.Is(err, ErrInvalidShift) {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(),
+			map[string]any{"shift": req.Shift})
+		return
+	}
+	if errors.Is(err, ErrTxConflict) {
+		appMiddleware.WriteError(w, r, http.StatusConflict, "conflict", "Tasks were changed concurrently, retry", nil)
+		return
+	}
+	if err != nil {
+		if h.writeHookError(w, r, err) || h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s rescheduleTasks error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to reschedule tasks", nil)
+		return
+	}
+
+	log.Printf("request_id=%s reschedule user=%d shift=%s count=%d dry_run=%t", appMiddleware.GetRequestID(ctx), userID, req.Shift, res.Count, IsDryRun(ctx))
+	_ = json.NewEncoder(w).Encode(res)
+}
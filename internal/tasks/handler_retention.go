@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminRetentionRouter возвращает маршруты политики хранения относительно /api/v1/admin/retention.
+// Подключается в main под проверкой X-Admin-Key.
+func (h *Handler) AdminRetentionRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/", h.getRetention)
+	r.Put("/", h.setRetention)
+	r.Post("/run", h.runRetention)
+	return r
+}
+
+// RetentionResponse -- ответ GET /api/v1/admin/retention. LastRun -- последний проход уборщика
+// с запуска сервера (нет -- ещё не было).
+type RetentionResponse struct {
+	RetentionState
+	LastRun *RetentionRun `json:"last_run,omitempty"`
+}
+
+// writeRetentionError отвечает на ошибки политики хранения.
+func (h *Handler) writeRetentionError(w http.ResponseWriter, r *http.Request, err error, op string) {
+	if errors.Is(err, ErrRetentionUnsupported) {
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	}
+	if h.handleContextError(w, r, err) {
+		return
+	}
+	log.Printf("request_id=%s %s error: %v", appMiddleware.GetRequestID(r.Context()), op, err)
+	appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to apply retention policy", nil)
+}
+
+// getRetention обрабатывает GET /api/v1/admin/retention -- политика, граница надгробий и последний проход.
+func (h *Handler) getRetention(w http.ResponseWriter, r *http.Request) {
+	st, last, err := h.svc.Retention(r.Context())
+	if err != nil {
+		h.writeRetentionError(w, r, err, "getRetention")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(RetentionResponse{RetentionState: st, LastRun: last})
+}
+
+// setRetention обрабатывает PUT /api/v1/admin/retention.
+//
+//	{"versions_days": 90, "tombstones_days": 365}
+//
+// 0 -- хранить бессрочно. Новая политика применяется при следующем проходе уборщика
+// (или сразу -- POST /api/v1/admin/retention/run).
+func (h *Handler) setRetention(w http.ResponseWriter, r *http.Request) {
+	var req RetentionPolicy
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	if err := h.svc.SetRetentionPolicy(r.Context(), req); err != nil {
+		h.writeRetentionError(w, r, err, "setRetention")
+		return
+	}
+	h.getRetention(w, r)
+}
+
+// runRetention обрабатывает POST /api/v1/admin/retention/run -- проход уборщика сейчас, не дожидаясь
+// RETENTION_INTERVAL. Отвечает, сколько записей удалено.
+func (h *Handler) runRetention(w http.ResponseWriter, r *http.Request) {
+	run, err := h.svc.PurgeExpired(r.Context(), time.Now())
+	if err != nil {
+		h.writeRetentionError(w, r, err, "runRetention")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,189 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// writeRuleError отвечает на ошибки правил, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeRuleError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrRulesUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrRuleNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Rule not found", nil)
+	case errors.Is(err, ErrRuleNoCondition), errors.Is(err, ErrRuleNoAction):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// ruleIDParam разбирает {rule_id}; при ошибке сам отвечает 400.
+func (h *Handler) ruleIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "rule_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid rule ID",
+			map[string]any{"rule_id": chi.URLParam(r, "rule_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeRule читает и проверяет тело запроса правила; при ошибке сам отвечает 400.
+func (h *Handler) decodeRule(w http.ResponseWriter, r *http.Request) (RuleRequest, bool) {
+	var req RuleRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return req, false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return req, false
+	}
+	return req, true
+}
+
+// createRule обрабатывает POST /api/v1/rules.
+//
+//	{"name": "Просрочено 3 дня", "when": {"overdue_days": 3}, "then": {"set_priority": "high", "notify": true}}
+func (h *Handler) createRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	req, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.svc.CreateRule(ctx, userID, req, time.Now())
+	if h.writeRuleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createRule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create rule", nil)
+		return
+	}
+
+	log.Printf("request_id=%s rule created user=%d rule=%d", appMiddleware.GetRequestID(ctx), userID, rule.ID)
+	w.Header().Set("Location", "/api/v1/rules/"+strconv.Itoa(rule.ID))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// getRules обрабатывает GET /api/v1/rules -- правила текущего пользователя.
+func (h *Handler) getRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.UserRules(ctx, userID)
+	if h.writeRuleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getRules error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get rules", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getRule обрабатывает GET /api/v1/rules/{rule_id}.
+func (h *Handler) getRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.ruleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.svc.GetRule(ctx, userID, id)
+	if h.writeRuleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getRule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get rule", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// updateRule обрабатывает PUT /api/v1/rules/{rule_id} -- правило заменяется целиком.
+func (h *Handler) updateRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.ruleIDParam(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.svc.UpdateRule(ctx, userID, id, req, time.Now())
+	if h.writeRuleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateRule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update rule", nil)
+		return
+	}
+	log.Printf("request_id=%s rule updated user=%d rule=%d enabled=%t", appMiddleware.GetRequestID(ctx), userID, id, rule.Enabled)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+// deleteRule обрабатывает DELETE /api/v1/rules/{rule_id}.
+func (h *Handler) deleteRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.ruleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteRule(ctx, userID, id)
+	if h.writeRuleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteRule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete rule", nil)
+		return
+	}
+	log.Printf("request_id=%s rule deleted user=%d rule=%d", appMiddleware.GetRequestID(ctx), userID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
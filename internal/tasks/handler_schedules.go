@@ -0,0 +1,317 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultScheduleOccurrencesLimit -- сколько запусков отдаёт /occurrences без ?limit.
+const defaultScheduleOccurrencesLimit = 10
+
+// writeScheduleError отвечает на ошибки расписаний, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeScheduleError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrSchedulesUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrScheduleNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Schedule not found", nil)
+	case errors.Is(err, ErrScheduleExceptionNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Schedule exception not found", nil)
+	case errors.Is(err, ErrInvalidCron), errors.Is(err, ErrScheduleNever), errors.Is(err, ErrScheduleNoRun),
+		errors.Is(err, ErrScheduleTooManyExceptions):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// scheduleIDParam разбирает {schedule_id}; при ошибке сам отвечает 400.
+func (h *Handler) scheduleIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "schedule_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid schedule ID",
+			map[string]any{"schedule_id": chi.URLParam(r, "schedule_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// scheduleRunParam разбирает {at} -- момент запуска в RFC 3339; при ошибке сам отвечает 400.
+func (h *Handler) scheduleRunParam(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	at, err := time.Parse(time.RFC3339, chi.URLParam(r, "at"))
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid run time, want RFC 3339",
+			map[string]any{"at": chi.URLParam(r, "at")})
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// decodeSchedule читает и проверяет тело запроса расписания; при ошибке сам отвечает 400.
+func (h *Handler) decodeSchedule(w http.ResponseWriter, r *http.Request) (ScheduleRequest, bool) {
+	var req ScheduleRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return req, false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return req, false
+	}
+	return req, true
+}
+
+// createSchedule обрабатывает POST /api/v1/schedules.
+//
+//	{"name": "Обзор недели", "cron": "0 9 * * 1", "task": {"title": "Weekly review", "due_in_hours": 8}}
+func (h *Handler) createSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	req, ok := h.decodeSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	sch, err := h.svc.CreateSchedule(ctx, userID, req, time.Now())
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create schedule", nil)
+		return
+	}
+
+	log.Printf("request_id=%s schedule created user=%d schedule=%d cron=%q", appMiddleware.GetRequestID(ctx), userID, sch.ID, sch.Cron)
+	w.Header().Set("Location", "/api/v1/schedules/"+strconv.Itoa(sch.ID))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(sch)
+}
+
+// getSchedules обрабатывает GET /api/v1/schedules -- расписания текущего пользователя.
+func (h *Handler) getSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.UserSchedules(ctx, userID)
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getSchedules error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get schedules", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getSchedule обрабатывает GET /api/v1/schedules/{schedule_id}.
+func (h *Handler) getSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	sch, err := h.svc.GetSchedule(ctx, userID, id)
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get schedule", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(sch)
+}
+
+// updateSchedule обрабатывает PUT /api/v1/schedules/{schedule_id} -- расписание заменяется целиком.
+func (h *Handler) updateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	sch, err := h.svc.UpdateSchedule(ctx, userID, id, req, time.Now())
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update schedule", nil)
+		return
+	}
+	log.Printf("request_id=%s schedule updated user=%d schedule=%d enabled=%t", appMiddleware.GetRequestID(ctx), userID, id, sch.Enabled)
+	_ = json.NewEncoder(w).Encode(sch)
+}
+
+// deleteSchedule обрабатывает DELETE /api/v1/schedules/{schedule_id}.
+func (h *Handler) deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteSchedule(ctx, userID, id)
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteSchedule error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete schedule", nil)
+		return
+	}
+	log.Printf("request_id=%s schedule deleted user=%d schedule=%d", appMiddleware.GetRequestID(ctx), userID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getScheduleOccurrences обрабатывает GET /api/v1/schedules/{schedule_id}/occurrences?limit=10 --
+// предстоящие запуски с исключениями.
+func (h *Handler) getScheduleOccurrences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultScheduleOccurrencesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxScheduleOccurrences {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid limit",
+				map[string]any{"limit": v, "max": maxScheduleOccurrences})
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.svc.ScheduleOccurrences(ctx, userID, id, limit)
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getScheduleOccurrences error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get schedule runs", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// putScheduleException обрабатывает PUT /api/v1/schedules/{schedule_id}/exceptions/{at} --
+// пропуск или правка одного запуска.
+//
+//	{"skip": true}
+//	{"task": {"title": "Обзор недели (перенесён)", "due": "2024-01-16T18:00:00Z"}}
+func (h *Handler) putScheduleException(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+	at, ok := h.scheduleRunParam(w, r)
+	if !ok {
+		return
+	}
+	var req ScheduleExceptionRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	sch, err := h.svc.SetScheduleException(ctx, userID, id, at, req, time.Now())
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s putScheduleException error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save schedule exception", nil)
+		return
+	}
+	log.Printf("request_id=%s schedule exception set user=%d schedule=%d run=%s skip=%t",
+		appMiddleware.GetRequestID(ctx), userID, id, at.UTC().Format(time.RFC3339), req.Skip)
+	_ = json.NewEncoder(w).Encode(sch)
+}
+
+// deleteScheduleException обрабатывает DELETE /api/v1/schedules/{schedule_id}/exceptions/{at} --
+// запуск снова идёт по шаблону.
+func (h *Handler) deleteScheduleException(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.scheduleIDParam(w, r)
+	if !ok {
+		return
+	}
+	at, ok := h.scheduleRunParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteScheduleException(ctx, userID, id, at)
+	if h.writeScheduleError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteScheduleException error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete schedule exception", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,247 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/script"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminScriptsRouter возвращает маршруты скриптов относительно /api/v1/admin/scripts.
+// Подключается в main под проверкой X-Admin-Key.
+func (h *Handler) AdminScriptsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/", h.getScripts)
+	r.Post("/", h.createScript)
+	r.Post("/test", h.testScript)
+	r.Get("/{script_id}", h.getScript)
+	r.Put("/{script_id}", h.updateScript)
+	r.Delete("/{script_id}", h.deleteScript)
+	return r
+}
+
+// writeScriptError отвечает на ошибки скриптов, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeScriptError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var se *script.Error
+	switch {
+	case errors.Is(err, ErrScriptsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrScriptNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Script not found", nil)
+	case errors.Is(err, ErrScriptSyntax) && errors.As(err, &se):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "script_syntax", se.Msg,
+			map[string]any{"line": se.Pos.Line, "column": se.Pos.Col})
+	default:
+		return false
+	}
+	return true
+}
+
+// scriptIDParam разбирает {script_id}; при ошибке сам отвечает 400.
+func (h *Handler) scriptIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "script_id"))
+	if err != nil || id <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid script ID",
+			map[string]any{"script_id": chi.URLParam(r, "script_id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeScript читает и проверяет тело запроса; при ошибке сам отвечает 400.
+func (h *Handler) decodeScript(w http.ResponseWriter, r *http.Request) (ScriptRequest, bool) {
+	var req ScriptRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return req, false
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return req, false
+	}
+	return req, true
+}
+
+// createScript обрабатывает POST /api/v1/admin/scripts.
+//
+//	{"name": "Метка urgent", "event": "before_create",
+//	 "source": "if \"срочно\" in task.title.lower():\n    task.tags.append(\"urgent\")"}
+func (h *Handler) createScript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, ok := h.decodeScript(w, r)
+	if !ok {
+		return
+	}
+
+	sc, err := h.svc.CreateScript(ctx, req, time.Now())
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createScript error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create script", nil)
+		return
+	}
+
+	appMiddleware.LogAdminAction(r, "script created id=%d event=%s", sc.ID, sc.Event)
+	w.Header().Set("Location", "/api/v1/admin/scripts/"+strconv.Itoa(sc.ID))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(sc)
+}
+
+// getScripts обрабатывает GET /api/v1/admin/scripts.
+func (h *Handler) getScripts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	list, err := h.svc.Scripts(ctx)
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getScripts error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get scripts", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getScript обрабатывает GET /api/v1/admin/scripts/{script_id}.
+func (h *Handler) getScript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.scriptIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	sc, err := h.svc.GetScript(ctx, id)
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getScript error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get script", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(sc)
+}
+
+// updateScript обрабатывает PUT /api/v1/admin/scripts/{script_id} -- скрипт заменяется целиком.
+func (h *Handler) updateScript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.scriptIDParam(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.decodeScript(w, r)
+	if !ok {
+		return
+	}
+
+	sc, err := h.svc.UpdateScript(ctx, id, req, time.Now())
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateScript error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update script", nil)
+		return
+	}
+	appMiddleware.LogAdminAction(r, "script updated id=%d enabled=%t", id, sc.Enabled)
+	_ = json.NewEncoder(w).Encode(sc)
+}
+
+// deleteScript обрабатывает DELETE /api/v1/admin/scripts/{script_id}.
+func (h *Handler) deleteScript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.scriptIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.svc.DeleteScript(ctx, id)
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteScript error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to delete script", nil)
+		return
+	}
+	appMiddleware.LogAdminAction(r, "script deleted id=%d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scriptTestRequest -- тело POST /api/v1/admin/scripts/test: скрипт и задача, над которой его выполнить.
+type scriptTestRequest struct {
+	ScriptRequest
+	Task Task  `json:"task"`
+	Old  *Task `json:"old"` // прежняя версия для before_update; нет -- совпадает с task
+}
+
+// testScript обрабатывает POST /api/v1/admin/scripts/test -- выполнить скрипт над присланной задачей,
+// ничего не сохраняя. Ответ -- задача после скрипта, сообщение reject(), ошибка и вывод print().
+func (h *Handler) testScript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req scriptTestRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if req.Name == "" {
+		req.Name = "test"
+	}
+	if err := h.validate.Struct(req.ScriptRequest); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+	old := req.Task
+	if req.Old != nil {
+		old = *req.Old
+	}
+
+	run, err := h.svc.TestScript(ctx, req.ScriptRequest, old, req.Task)
+	if h.writeScriptError(w, r, err) {
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s testScript error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to run script", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listSessions обрабатывает GET /api/v1/me/sessions: где выполнен вход (IP, User-Agent,
+// когда вошли и когда токен использовали последний раз). Текущая сессия отмечена current.
+func (h *Handler) listSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	list, err := h.svc.ListSessions(ctx, userID, appMiddleware.SessionIDFromContext(ctx))
+	if err != nil {
+		h.writeSessionError(w, r, "listSessions", err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// revokeSession обрабатывает DELETE /api/v1/me/sessions/{id}: выход на одном устройстве.
+// Можно отозвать и текущую сессию -- это выход.
+func (h *Handler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+	id := chi.URLParam(r, "id")
+
+	if err := h.svc.RevokeSession(ctx, userID, id); err != nil {
+		h.writeSessionError(w, r, "revokeSession", err)
+		return
+	}
+	log.Printf("request_id=%s user id=%d revoked session %s", appMiddleware.GetRequestID(ctx), userID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions обрабатывает DELETE /api/v1/me/sessions: выход на всех остальных устройствах,
+// с ?all=true -- и на текущем.
+func (h *Handler) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	keep := appMiddleware.SessionIDFromContext(ctx)
+	if r.URL.Query().Get("all") == "true" {
+		keep = ""
+	}
+	n, err := h.svc.RevokeSessions(ctx, userID, keep)
+	if err != nil {
+		h.writeSessionError(w, r, "revokeSessions", err)
+		return
+	}
+	log.Printf("request_id=%s user id=%d revoked %d session(s)", appMiddleware.GetRequestID(ctx), userID, n)
+	_ = json.NewEncoder(w).Encode(map[string]int{"revoked": n})
+}
+
+func (h *Handler) writeSessionError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Session not found", nil)
+	case errors.Is(err, ErrSessionsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", "Sessions are not supported by this storage", nil)
+	default:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s %s error: %v", appMiddleware.GetRequestID(r.Context()), op, err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to manage sessions", nil)
+	}
+}
@@ -0,0 +1,268 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// shareCtxKey -- ключ контекста, под которым shareAuth кладёт проверенную ссылку.
+type shareCtxKey struct{}
+
+// SharedTask -- ответ на создание ссылки: токен показывается только здесь.
+type SharedTask struct {
+	Share
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// writeShareError отвечает на ошибки ссылок, общие для всех ручек. Возвращает false,
+// если ошибка не из их числа.
+func (h *Handler) writeShareError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrSharesUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrShareNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Share link not found", nil)
+	case errors.Is(err, ErrInvalidShareTTL):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// shareAuth -- авторизация публичных ссылок /share/{token}: до вызова сервиса проверяет,
+// что ссылка существует и не истекла, а для изменений -- что она выдана с доступом write.
+// Токен живёт в URL, поэтому ответы не кэшируются и не уходят в Referer.
+func (h *Handler) shareAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		sh, err := h.svc.ResolveShare(r.Context(), chi.URLParam(r, "token"), time.Now())
+		if h.writeShareError(w, r, err) {
+			return
+		}
+		if err != nil {
+			if h.handleContextError(w, r, err) {
+				return
+			}
+			log.Printf("request_id=%s shareAuth error: %v", appMiddleware.GetRequestID(r.Context()), err)
+			appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Internal error", nil)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && sh.Access != ShareWrite {
+			appMiddleware.WriteError(w, r, http.StatusForbidden, "forbidden", "Share link is read-only", nil)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), shareCtxKey{}, sh)))
+	})
+}
+
+// createShare обрабатывает POST /api/v1/tasks/{id}/shares.
+//
+//	{"access": "read", "expires_in": "72h"}
+func (h *Handler) createShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req CreateShareRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	sh, token, err := h.svc.ShareTask(ctx, id, userID, req, time.Now())
+	if h.writeShareError(w, r, err) {
+		return
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s createShare error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to create share link", nil)
+		return
+	}
+
+	log.Printf("request_id=%s share created user=%d task=%d share=%d access=%s expires=%s",
+		appMiddleware.GetRequestID(ctx), userID, id, sh.ID, sh.Access, sh.ExpiresAt.Format(time.RFC3339))
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(SharedTask{Share: sh, Token: token, URL: "/share/" + token})
+}
+
+// getShares обрабатывает GET /api/v1/tasks/{id}/shares -- ссылки на задачу (без токенов).
+func (h *Handler) getShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.svc.TaskShares(ctx, id, userID)
+	if h.writeShareError(w, r, err) {
+		return
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getShares error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get share links", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// deleteShare обрабатывает DELETE /api/v1/tasks/{id}/shares/{share_id} -- отзыв ссылки.
+func (h *Handler) deleteShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	shareID, err := strconv.Atoi(chi.URLParam(r, "share_id"))
+	if err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid share ID",
+			map[string]any{"share_id": chi.URLParam(r, "share_id")})
+		return
+	}
+
+	err = h.svc.RevokeShare(ctx, id, userID, shareID)
+	if h.writeShareError(w, r, err) {
+		return
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id})
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s deleteShare error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to revoke share link", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSharedTask обрабатывает GET /share/{token} -- задача по публичной ссылке (поддерживает ?render=html).
+func (h *Handler) getSharedTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sh := ctx.Value(shareCtxKey{}).(Share)
+
+	render, ok := h.renderParam(w, r)
+	if !ok {
+		return
+	}
+	task, err := h.svc.GetTaskByID(ctx, sh.TaskID, sh.CreatedBy)
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Share link not found", nil)
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getSharedTask error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get task", nil)
+		return
+	}
+	if render {
+		_ = json.NewEncoder(w).Encode(renderTask(*task))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(task)
+}
+
+// updateSharedTask обрабатывает PUT /share/{token} (только для ссылок с доступом write).
+// Тело -- как у PUT /api/v1/tasks/{id}; изменение записывается от имени автора ссылки.
+func (h *Handler) updateSharedTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sh := ctx.Value(shareCtxKey{}).(Share)
+
+	var req UpdateTaskRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	incoming := Task{
+		ID:              sh.TaskID,
+		Title:           req.Title,
+		Done:            req.Done,
+		Status:          req.Status,
+		Priority:        req.Priority,
+		AssignedTo:      req.AssignedTo,
+		Description:     req.Description,
+		Tags:            req.Tags,
+		Due:             req.Due,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
+	}
+	if incoming.AssignedTo == 0 {
+		// По ссылке исполнителя не переназначают: пустое значение -- оставить прежнего.
+		if cur, err := h.svc.GetTaskByID(ctx, sh.TaskID, sh.CreatedBy); err == nil {
+			incoming.AssignedTo = cur.AssignedTo
+		}
+	}
+
+	err := h.svc.UpdateTask(ctx, &incoming, sh.CreatedBy)
+	if h.writeFieldError(w, r, err) || h.writeHookError(w, r, err) || h.writeTransitionError(w, r, err) ||
+		h.writeProjectError(w, r, err) {
+		return
+	}
+	if errors.Is(err, ErrTaskNotFound) {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Share link not found", nil)
+		return
+	}
+	if err != nil {
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateSharedTask error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to save tasks", nil)
+		return
+	}
+	log.Printf("request_id=%s shared task updated share=%d task=%d", appMiddleware.GetRequestID(ctx), sh.ID, sh.TaskID)
+	_ = json.NewEncoder(w).Encode(incoming)
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// syncTasks обрабатывает POST /api/v1/sync -- синхронизация офлайн-клиента (см. sync.go).
+//
+//	{"token": "42", "strategy": "server_wins", "mutations": [
+//	  {"op": "upsert", "uuid": "01J...", "base_rev": 0, "task": {"title": "Купить хлеб", "priority": "medium"}},
+//	  {"op": "delete", "uuid": "5f0c...", "base_rev": 40}]}
+//
+// Ответ -- итоги мутаций, изменения сервера после token и новый токен. 410 sync_token_invalid --
+// токен сервер не знает (база восстановлена из копии, другой сервер): синхронизироваться заново без токена.
+func (h *Handler) syncTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	var req SyncRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	resp, err := h.svc.Sync(ctx, userID, req)
+	switch {
+	case errors.Is(err, ErrSyncUnsupported), errors.Is(err, ErrConflictsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	case errors.Is(err, ErrInvalidSyncToken):
+		appMiddleware.WriteError(w, r, http.StatusGone, "sync_token_invalid",
+			"Sync token is not valid for this server, sync again without token", map[string]any{"token": req.Token})
+		return
+	case err != nil:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s syncTasks error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to sync tasks", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
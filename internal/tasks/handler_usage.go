@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminUsageRouter возвращает маршруты использования хранилища относительно /api/v1/admin/usage.
+// Подключается в main под проверкой X-Admin-Key.
+func (h *Handler) AdminUsageRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(appMiddleware.JSONHeaderMiddleware)
+
+	r.Get("/", h.getUsage)
+	return r
+}
+
+// getUsage обрабатывает GET /api/v1/admin/usage -- сколько задач и места занимает хранилище
+// и какие мягкие квоты превышены. Считает заново при каждом запросе.
+func (h *Handler) getUsage(w http.ResponseWriter, r *http.Request) {
+	u, err := h.svc.CheckUsage(r.Context(), time.Now())
+	if err != nil {
+		if errors.Is(err, ErrUsageUnsupported) {
+			appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+			return
+		}
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s getUsage error: %v", appMiddleware.GetRequestID(r.Context()), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to get storage usage", nil)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(u)
+}
@@ -0,0 +1,139 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// versionParam разбирает {n} -- номер версии задачи; при ошибке сам отвечает 400.
+func (h *Handler) versionParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	n, err := strconv.Atoi(chi.URLParam(r, "n"))
+	if err != nil || n <= 0 {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid revision",
+			map[string]any{"n": chi.URLParam(r, "n")})
+		return 0, false
+	}
+	return n, true
+}
+
+// writeVersionError маппит ошибки истории версий в HTTP-ответы.
+func (h *Handler) writeVersionError(w http.ResponseWriter, r *http.Request, err error, id int, op string) {
+	switch {
+	case errors.Is(err, ErrVersionsUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+	case errors.Is(err, ErrVersionNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Revision not found",
+			map[string]any{"id": id, "n": chi.URLParam(r, "n")})
+	case errors.Is(err, ErrTaskNotFound):
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Task not found",
+			map[string]any{"id": id})
+	default:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s %s error: %v", appMiddleware.GetRequestID(r.Context()), op, err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to read task revisions", nil)
+	}
+}
+
+// getTaskVersions обрабатывает GET /api/v1/tasks/{id}/revisions -- версии задачи, старые первыми.
+// Удалённая задача отдаёт историю до удаления.
+func (h *Handler) getTaskVersions(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.svc.TaskVersions(r.Context(), id)
+	if err != nil {
+		h.writeVersionError(w, r, err, id, "getTaskVersions")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// getTaskVersion обрабатывает GET /api/v1/tasks/{id}/revisions/{n} -- снимок задачи в версии n.
+func (h *Handler) getTaskVersion(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	n, ok := h.versionParam(w, r)
+	if !ok {
+		return
+	}
+	v, err := h.svc.TaskVersion(r.Context(), id, n)
+	if err != nil {
+		h.writeVersionError(w, r, err, id, "getTaskVersion")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// getTaskVersionDiff обрабатывает GET /api/v1/tasks/{id}/revisions/{n}/diff?from=m -- изменения полей
+// от версии m (по умолчанию -- предыдущей, 0 -- от пустой задачи) к версии n.
+func (h *Handler) getTaskVersionDiff(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	n, ok := h.versionParam(w, r)
+	if !ok {
+		return
+	}
+	from := -1
+	if s := r.URL.Query().Get("from"); s != "" {
+		m, err := strconv.Atoi(s)
+		if err != nil || m < 0 {
+			appMiddleware.WriteError(w, r, http.StatusBadRequest, "bad_request", "Invalid from",
+				map[string]any{"from": s})
+			return
+		}
+		from = m
+	}
+
+	diff, err := h.svc.TaskVersionDiff(r.Context(), id, n, from)
+	if err != nil {
+		h.writeVersionError(w, r, err, id, "getTaskVersionDiff")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(diff)
+}
+
+// restoreTaskVersion обрабатывает POST /api/v1/tasks/{id}/revisions/{n}/restore -- откат задачи
+// к версии n. Это обычное изменение (как PUT): те же проверки и хуки, поддерживает ?dry_run=true.
+// Отвечает задачей после отката.
+func (h *Handler) restoreTaskVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.dryRunContext(w, r)
+	if !ok {
+		return
+	}
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	id, ok := h.taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	n, ok := h.versionParam(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := h.svc.RestoreTaskVersion(ctx, id, n, userID)
+	if h.writeFieldError(w, r, err) || h.writeHookError(w, r, err) || h.writeTransitionError(w, r, err) ||
+		h.writeProjectError(w, r, err) {
+		return
+	}
+	if err != nil {
+		h.writeVersionError(w, r, err, id, "restoreTaskVersion")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(task)
+}
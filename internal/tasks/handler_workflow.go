@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"task-manager/internal/middleware"
+	appMiddleware "task-manager/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// workspaceParam проверяет {workspace_id}: сервер обслуживает одно пространство, другие -- 404.
+func (h *Handler) workspaceParam(w http.ResponseWriter, r *http.Request) bool {
+	if id := chi.URLParam(r, "workspace_id"); id != h.workspace {
+		appMiddleware.WriteError(w, r, http.StatusNotFound, "not_found", "Workspace not found",
+			map[string]any{"workspace": id})
+		return false
+	}
+	return true
+}
+
+// getWorkflow обрабатывает GET /api/v1/workspaces/{id}/workflow -- действующий процесс:
+// статусы задач и разрешённые переходы.
+func (h *Handler) getWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !h.workspaceParam(w, r) {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(ActiveWorkflow())
+}
+
+// updateWorkflow обрабатывает PUT /api/v1/workspaces/{id}/workflow -- процесс заменяется целиком,
+// задачи с исчезнувшими статусами переводятся по remap или по категории. Только для WORKSPACE_ADMINS.
+//
+//	{"statuses": [{"id": "todo", "category": "todo"}, {"id": "doing", "category": "in_progress"},
+//	 {"id": "review", "label": "На проверке", "category": "in_progress"}, {"id": "done", "category": "done"}],
+//	 "initial": "todo", "transitions": {"todo": ["doing"], "doing": ["review", "todo"], "review": ["done", "doing"], "done": ["todo"]},
+//	 "remap": {"in_progress": "doing"}}
+func (h *Handler) updateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := ctx.Value(middleware.UserIDKey).(int)
+
+	if !h.workspaceParam(w, r) {
+		return
+	}
+	if !slices.Contains(h.workspaceAdmins, userID) {
+		appMiddleware.WriteError(w, r, http.StatusForbidden, "forbidden", "Only workspace admins (WORKSPACE_ADMINS) can change the workflow", nil)
+		return
+	}
+
+	var req WorkflowRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		h.writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", "Validation failed",
+			validationDetails(err))
+		return
+	}
+
+	res, err := h.svc.SaveWorkflow(ctx, userID, req, time.Now())
+	switch {
+	case errors.Is(err, ErrInvalidWorkflow):
+		appMiddleware.WriteError(w, r, http.StatusBadRequest, "validation_error", err.Error(), nil)
+		return
+	case errors.Is(err, ErrWorkflowUnsupported):
+		appMiddleware.WriteError(w, r, http.StatusNotImplemented, "not_implemented", err.Error(), nil)
+		return
+	case err != nil:
+		if h.handleContextError(w, r, err) {
+			return
+		}
+		log.Printf("request_id=%s updateWorkflow error: %v", appMiddleware.GetRequestID(ctx), err)
+		appMiddleware.WriteError(w, r, http.StatusInternalServerError, "internal", "Failed to update workflow", nil)
+		return
+	}
+
+	log.Printf("request_id=%s workflow updated workspace=%s user=%d statuses=%v migrated=%d",
+		appMiddleware.GetRequestID(ctx), h.workspace, userID, res.IDs(), res.Migrated)
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,177 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Hooks -- своя бизнес-логика вокруг изменений задач (проверки, обогащение, синхронизация
+// с внешними системами), вкомпилированная в сервер без правки сервиса. Любое поле может быть nil.
+//
+// Before-хуки вызываются до записи и могут изменить задачу; ошибка отменяет операцию, и клиент
+// получает 422 с текстом ошибки. After-хуки (и OnDelete) вызываются после успешной записи: отменить
+// её они уже не могут, их ошибки только пишутся в лог.
+//
+// Хуки выполняются синхронно, в горутине запроса и с его контекстом: долгую работу (запрос во внешнюю
+// систему) лучше отдать своей горутине. Паника хука перехватывается и считается его ошибкой.
+type Hooks struct {
+	BeforeCreate func(ctx context.Context, t *Task) error
+	AfterCreate  func(ctx context.Context, t Task) error
+	BeforeUpdate func(ctx context.Context, old Task, t *Task) error
+	AfterUpdate  func(ctx context.Context, old, t Task) error
+	BeforeDelete func(ctx context.Context, t Task) error
+	OnDelete     func(ctx context.Context, t Task) error
+}
+
+// HookError -- операцию отменил Before-хук.
+type HookError struct {
+	Hook  string // имя из RegisterHooks/AddHooks
+	Stage string // before_create, before_update, before_delete
+	Err   error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("hook %s (%s): %v", e.Hook, e.Stage, e.Err)
+}
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+type namedHooks struct {
+	name string
+	Hooks
+}
+
+var (
+	globalHooksMu sync.Mutex
+	globalHooks   []namedHooks
+)
+
+// RegisterHooks регистрирует хуки для всех сервисов, созданных после вызова. Рассчитан на init()
+// в своём файле, добавленном в сборку сервера (например, cmd/task-server/hooks_acme.go):
+//
+//	func init() {
+//		tasks.RegisterHooks("acme-project-code", tasks.Hooks{BeforeCreate: requireProjectCode})
+//	}
+func RegisterHooks(name string, h Hooks) {
+	globalHooksMu.Lock()
+	defer globalHooksMu.Unlock()
+	globalHooks = append(globalHooks, namedHooks{name: name, Hooks: h})
+}
+
+// registeredHooks -- копия глобальных хуков для нового сервиса.
+func registeredHooks() []namedHooks {
+	globalHooksMu.Lock()
+	defer globalHooksMu.Unlock()
+	return append([]namedHooks(nil), globalHooks...)
+}
+
+// AddHooks добавляет хуки только этому сервису (встраиваемый режим, pkg/taskmanager).
+// Вызывать до начала работы сервиса: список не защищён от одновременного изменения.
+func (s *Service) AddHooks(name string, h Hooks) {
+	s.hooks = append(s.hooks, namedHooks{name: name, Hooks: h})
+}
+
+// Порядок: сначала глобальные хуки в порядке RegisterHooks, затем AddHooks сервиса, затем скрипты
+// администратора (см. scripts.go). Before-хуки останавливаются на первой ошибке -- следующие уже не вызываются.
+
+func (s *Service) beforeCreate(ctx context.Context, t *Task) error {
+	for _, h := range s.hooks {
+		if h.BeforeCreate != nil {
+			if err := callHook(func() error { return h.BeforeCreate(ctx, t) }); err != nil {
+				return &HookError{Hook: h.name, Stage: "before_create", Err: err}
+			}
+		}
+	}
+	return s.runScripts(ctx, ScriptBeforeCreate, nil, t)
+}
+
+func (s *Service) beforeUpdate(ctx context.Context, old Task, t *Task) error {
+	for _, h := range s.hooks {
+		if h.BeforeUpdate != nil {
+			if err := callHook(func() error { return h.BeforeUpdate(ctx, old, t) }); err != nil {
+				return &HookError{Hook: h.name, Stage: "before_update", Err: err}
+			}
+		}
+	}
+	return s.runScripts(ctx, ScriptBeforeUpdate, &old, t)
+}
+
+func (s *Service) beforeDelete(ctx context.Context, t Task) error {
+	for _, h := range s.hooks {
+		if h.BeforeDelete != nil {
+			if err := callHook(func() error { return h.BeforeDelete(ctx, t) }); err != nil {
+				return &HookError{Hook: h.name, Stage: "before_delete", Err: err}
+			}
+		}
+	}
+	return s.runScripts(ctx, ScriptBeforeDelete, nil, &t)
+}
+
+// afterCreate, afterUpdate и onDelete не вызываются при пробном запуске: записи не было.
+// Первые два заодно пишут изменение в ленту (changes.go): через них проходит любое создание
+// и изменение задачи, удаление пишется в DeleteTask -- onDelete вызывается не всегда.
+
+func (s *Service) afterCreate(ctx context.Context, t Task) {
+	if IsDryRun(ctx) {
+		return
+	}
+	s.publishChange(ctx, ChangeCreated, t)
+	for _, h := range s.hooks {
+		if h.AfterCreate != nil {
+			logHookError(ctx, h.name, "after_create", t.ID, callHook(func() error { return h.AfterCreate(ctx, t) }))
+		}
+	}
+}
+
+func (s *Service) afterUpdate(ctx context.Context, old, t Task) {
+	if IsDryRun(ctx) {
+		return
+	}
+	s.publishChange(ctx, ChangeUpdated, storedUpdate(old, t))
+	for _, h := range s.hooks {
+		if h.AfterUpdate != nil {
+			logHookError(ctx, h.name, "after_update", t.ID, callHook(func() error { return h.AfterUpdate(ctx, old, t) }))
+		}
+	}
+}
+
+func (s *Service) onDelete(ctx context.Context, t Task) {
+	if IsDryRun(ctx) {
+		return
+	}
+	for _, h := range s.hooks {
+		if h.OnDelete != nil {
+			logHookError(ctx, h.name, "on_delete", t.ID, callHook(func() error { return h.OnDelete(ctx, t) }))
+		}
+	}
+}
+
+// hasDeleteHooks -- есть ли хуки или скрипты удаления: только тогда задачу стоит читать перед удалением.
+func (s *Service) hasDeleteHooks(ctx context.Context) bool {
+	for _, h := range s.hooks {
+		if h.BeforeDelete != nil || h.OnDelete != nil {
+			return true
+		}
+	}
+	return s.hasScripts(ctx, ScriptBeforeDelete)
+}
+
+// callHook вызывает хук, превращая панику в ошибку: чужой код не должен ронять сервер.
+func callHook(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn()
+}
+
+func logHookError(ctx context.Context, name, stage string, taskID int, err error) {
+	if err != nil {
+		log.Printf("request_id=%s hook %s (%s) task=%d error: %v", appMiddleware.GetRequestID(ctx), name, stage, taskID, err)
+	}
+}
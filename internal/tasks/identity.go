@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Identity -- пользователь, подтверждённый внешним источником учётных записей (LDAP).
+type Identity struct {
+	Username    string
+	DisplayName string
+	Email       string
+	// Roles -- роли из групп каталога; попадают в JWT и во вход движка политик
+	Roles []string
+}
+
+// IdentityProvider -- внешний источник учётных записей. Authenticate возвращает
+// ErrInvalidCredentials, если пользователя нет или пароль не подошёл, -- тогда
+// пробуется следующий источник из AUTH_PROVIDERS.
+type IdentityProvider interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+// LocalUsers -- место собственных пользователей (пароль argon2id в хранилище) в цепочке
+// SetIdentityProviders. Сам он не вызывается: локальную проверку делает Service.
+var LocalUsers IdentityProvider = localUsers{}
+
+type localUsers struct{}
+
+func (localUsers) Name() string { return "local" }
+
+func (localUsers) Authenticate(context.Context, string, string) (*Identity, error) {
+	return nil, ErrInvalidCredentials
+}
+
+// SetIdentityProviders задаёт источники учётных записей в порядке проверки (AUTH_PROVIDERS).
+// По умолчанию -- только LocalUsers. Вызывать до старта сервера.
+func (s *Service) SetIdentityProviders(providers ...IdentityProvider) {
+	s.identityProviders = providers
+}
+
+// authenticateExternal проверяет пароль во внешнем источнике и возвращает локального
+// пользователя, заводя его при первом входе: задачи и права ссылаются на ID из users.
+func (s *Service) authenticateExternal(ctx context.Context, p IdentityProvider, username, password string) (*User, error) {
+	id, err := p.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.repo.GetUserByUsername(ctx, id.Username)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		// Пароля у такого пользователя нет: войти он может только через свой источник.
+		u = &User{Username: id.Username, DisplayName: id.DisplayName, Email: id.Email}
+		if n := len([]rune(u.Username)); n < 2 || n > 50 {
+			return nil, fmt.Errorf("%s: %w", p.Name(), ErrInvalidUsername)
+		}
+		if err := s.repo.CreateUser(ctx, u); err != nil {
+			return nil, err
+		}
+		log.Printf("auth: created user id=%d username=%q on first %s login", u.ID, u.Username, p.Name())
+	case err != nil:
+		return nil, err
+	case u.Disabled:
+		return nil, ErrUserDisabled
+	default:
+		s.syncIdentity(ctx, u, id)
+	}
+	u.Roles = id.Roles
+	return u, nil
+}
+
+// syncIdentity обновляет имя и почту пользователя, если они поменялись в каталоге.
+// Ошибка не мешает входу: это лишь справочные поля.
+func (s *Service) syncIdentity(ctx context.Context, u *User, id *Identity) {
+	if u.DisplayName == id.DisplayName && u.Email == id.Email {
+		return
+	}
+	d, err := s.directory()
+	if err != nil {
+		return
+	}
+	u.DisplayName, u.Email = id.DisplayName, id.Email
+	if err := d.UpdateUser(ctx, u); err != nil {
+		log.Printf("WARNING: auth: update user %q from directory: %v", u.Username, err)
+	}
+}
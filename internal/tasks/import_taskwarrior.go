@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// taskwarriorTimeLayout -- формат дат в `task export` (ISO 8601 basic, всегда UTC).
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+// twTask -- запись из `task export`. Берём только поля, которые умеем отобразить в нашу модель.
+type twTask struct {
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Priority    string         `json:"priority"`
+	Due         string         `json:"due"`
+	Entry       string         `json:"entry"`
+	Tags        []string       `json:"tags"`
+	Annotations []twAnnotation `json:"annotations"`
+}
+
+type twAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// ParseTaskwarrior разбирает JSON-экспорт Taskwarrior и маппит его в наши задачи.
+//
+// Правила маппинга:
+//   - uuid -> Task.UUID (по нему импорт идемпотентен);
+//   - description -> Title (если длиннее 100 символов -- обрезаем, полный текст уходит в Description);
+//   - annotations -> строки Description вида "- 2024-01-15: текст";
+//   - priority H/M/L -> high/medium/low, без приоритета -> medium;
+//   - status completed -> Done, deleted -> пропускаем, recurring (шаблон повторения) -> пропускаем;
+//   - tags и due переносятся как есть, entry (время создания) -> CreatedAt.
+//
+// Владельцем и исполнителем всех задач становится userID.
+func ParseTaskwarrior(r io.Reader, userID int) (tasks []Task, skipped int, err error) {
+	var in []twTask
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, 0, fmt.Errorf("decode taskwarrior export: %w", err)
+	}
+
+	tasks = make([]Task, 0, len(in))
+	for i, tw := range in {
+		if tw.Status == "deleted" || tw.Status == "recurring" {
+			skipped++
+			continue
+		}
+		if strings.TrimSpace(tw.Description) == "" {
+			return nil, 0, fmt.Errorf("task #%d (%s): empty description", i, tw.UUID)
+		}
+
+		t := Task{
+			UserID:     userID,
+			AssignedTo: userID,
+			UUID:       tw.UUID,
+			Done:       tw.Status == "completed",
+			Priority:   twPriority(tw.Priority),
+			Tags:       tw.Tags,
+		}
+
+		// Длинное описание Taskwarrior обрежет до TITLE_MAX_LENGTH импорт (Service.FitTitle).
+		t.Title = NormalizeText(tw.Description)
+
+		for _, a := range tw.Annotations {
+			line := "- " + a.Description
+			if at, err := time.Parse(taskwarriorTimeLayout, a.Entry); err == nil {
+				line = fmt.Sprintf("- %s: %s", at.Format("2006-01-02"), a.Description)
+			}
+			if t.Description != "" {
+				t.Description += "\n"
+			}
+			t.Description += line
+		}
+
+		if tw.Due != "" {
+			due, err := time.Parse(taskwarriorTimeLayout, tw.Due)
+			if err != nil {
+				return nil, 0, fmt.Errorf("task #%d (%s): invalid due %q", i, tw.UUID, tw.Due)
+			}
+			t.Due = &due
+		}
+		if entry, err := time.Parse(taskwarriorTimeLayout, tw.Entry); err == nil {
+			t.CreatedAt = &entry
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, skipped, nil
+}
+
+// twPriority переводит приоритет Taskwarrior в наш.
+func twPriority(p string) Priority {
+	switch p {
+	case "H":
+		return PriorityHigh
+	case "L":
+		return PriorityLow
+	default:
+		return PriorityMedium
+	}
+}
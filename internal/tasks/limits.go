@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxTitleLimit -- предел настройки TITLE_MAX_LENGTH: столько вмещает колонка title в PostgreSQL
+// (VARCHAR(500), миграция 000028).
+const MaxTitleLimit = 500
+
+// ErrInvalidLimits -- ограничения входных данных заданы неверно.
+var ErrInvalidLimits = errors.New("invalid validation limits")
+
+// ValidationLimits -- настраиваемые ограничения входных данных задач. Из них при запуске собираются
+// правила валидатора: в тегах DTO вместо чисел стоят псевдонимы title, description, tags и tag.
+// Длины считаются в символах (рунах), см. text.go. Допустимые приоритеты задаёт шкала (priority_scale.go).
+type ValidationLimits struct {
+	TitleMax       int `json:"title_max"`       // название задачи, подзадачи, шаблона расписания
+	DescriptionMax int `json:"description_max"` // описание задачи
+	TagsMax        int `json:"tags_max"`        // тегов у задачи
+	TagMax         int `json:"tag_max"`         // длина одного тега
+}
+
+// DefaultValidationLimits -- ограничения по умолчанию (прежние значения из тегов DTO).
+var DefaultValidationLimits = ValidationLimits{
+	TitleMax:       100,
+	DescriptionMax: 10000,
+	TagsMax:        20,
+	TagMax:         50,
+}
+
+// check проверяет ограничения.
+func (l ValidationLimits) check() error {
+	var problems []string
+	if l.TitleMax < 1 || l.TitleMax > MaxTitleLimit {
+		problems = append(problems, fmt.Sprintf("title max must be 1..%d, got %d", MaxTitleLimit, l.TitleMax))
+	}
+	if l.DescriptionMax < 0 {
+		problems = append(problems, fmt.Sprintf("description max must not be negative, got %d", l.DescriptionMax))
+	}
+	if l.TagsMax < 0 || l.TagMax < 1 {
+		problems = append(problems, fmt.Sprintf("want tags max >= 0 and tag max >= 1, got %d and %d", l.TagsMax, l.TagMax))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidLimits, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SetValidationLimits задаёт ограничения входных данных и пересобирает по ним валидатор.
+// Вызывать до NewHandler: обработчик берёт валидатор сервиса при создании.
+func (s *Service) SetValidationLimits(l ValidationLimits) error {
+	if err := l.check(); err != nil {
+		return err
+	}
+	s.limits = l
+	s.validate = newValidator(l)
+	return nil
+}
+
+// ValidationLimits возвращает действующие ограничения (GET /api/v1/tasks/limits).
+func (s *Service) ValidationLimits() ValidationLimits {
+	return s.limits
+}
+
+// FitTitle укладывает название задачи из внешнего источника (импорт, CalDAV) в TITLE_MAX_LENGTH:
+// длинное название обрезается, а полный текст переносится в начало описания, чтобы ничего не потерять.
+func (s *Service) FitTitle(t *Task) {
+	if utf8.RuneCountInString(t.Title) <= s.limits.TitleMax {
+		return
+	}
+	t.Description = strings.TrimSpace(t.Title + "\n\n" + t.Description)
+	t.Title = TruncateText(t.Title, s.limits.TitleMax)
+}
+
+// registerLimitAliases -- псевдонимы правил, которые стоят в тегах DTO вместо чисел.
+// В ответе 400 правило называется по-прежнему (max), а в param -- действующее значение.
+func registerLimitAliases(v *validator.Validate, l ValidationLimits) {
+	v.RegisterAlias("title", "max="+strconv.Itoa(l.TitleMax))
+	v.RegisterAlias("description", "max="+strconv.Itoa(l.DescriptionMax))
+	v.RegisterAlias("tags", fmt.Sprintf("max=%d,dive,required,max=%d", l.TagsMax, l.TagMax))
+	v.RegisterAlias("tag", "max="+strconv.Itoa(l.TagMax))
+}
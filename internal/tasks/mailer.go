@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrMailerNotConfigured -- письмо некуда отправить: SMTP_ADDR не задан.
+var ErrMailerNotConfigured = errors.New("email delivery is not configured (SMTP_ADDR)")
+
+// Attachment -- вложение письма.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer отправляет письма. Реализация по умолчанию -- SMTPMailer.
+type Mailer interface {
+	Send(ctx context.Context, to []string, subject, body string, att *Attachment) error
+}
+
+// SMTPMailer -- отправка через SMTP-сервер (STARTTLS, если сервер его предлагает).
+// Username пустой -- без авторизации.
+type SMTPMailer struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+// SetMailer задаёт, чем отправлять письма (например, отчёты по расписанию). nil -- почта выключена.
+func (s *Service) SetMailer(m Mailer) {
+	s.mailer = m
+}
+
+// Send собирает письмо (текст + необязательное вложение) и отправляет его.
+func (m *SMTPMailer) Send(ctx context.Context, to []string, subject, body string, att *Attachment) error {
+	msg, err := buildMail(m.From, to, subject, body, att, time.Now())
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	// net/smtp не принимает контекст: отправляем в горутине и не ждём её после отмены.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(m.Addr, auth, m.From, to, msg) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMail собирает MIME-письмо: text/plain и, если есть, вложение в base64.
+func buildMail(from string, to []string, subject, body string, att *Attachment, now time.Time) ([]byte, error) {
+	for _, addr := range append([]string{from}, to...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("invalid address %q", addr)
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.ReplaceAll(subject, "\n", " ")))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if att == nil {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&b, []byte(body))
+		return b.Bytes(), nil
+	}
+
+	var rnd [12]byte
+	if _, err := rand.Read(rnd[:]); err != nil {
+		return nil, err
+	}
+	boundary := "tm-" + hex.EncodeToString(rnd[:])
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n", boundary)
+	writeBase64(&b, []byte(body))
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: %s\r\nContent-Transfer-Encoding: base64\r\n", boundary, att.ContentType)
+	fmt.Fprintf(&b, "Content-Disposition: attachment; filename=%q\r\n\r\n", att.Name)
+	writeBase64(&b, att.Data)
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// writeBase64 пишет данные в base64 строками по 76 символов (RFC 2045).
+func writeBase64(b *bytes.Buffer, data []byte) {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc + "\r\n")
+}
@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore -- хранилище целиком в памяти: задачи, подзадачи и пользователи.
+// Ничего не пишет на диск, поэтому подходит для тестов (своих и внешних) и демо-режима.
+//
+// Задачи хранятся так же, как в TaskStore (шарды, снимок для чтения, транзакции, поиск по UUID),
+// а пользователи, в отличие от файлового хранилища, действительно сохраняются -- работают
+// регистрация и вход.
+type MemoryStore struct {
+	*TaskStore
+
+	mu         sync.RWMutex
+	users      []User
+	lastUserID int
+	lastSubID  int
+	sessions   map[string]Session // выданные токены (см. sessions.go)
+}
+
+// NewMemoryStore создаёт пустое хранилище в памяти.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{TaskStore: NewTaskStore("")}
+}
+
+// SelfTest -- проверять нечего, память доступна всегда.
+func (ms *MemoryStore) SelfTest(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// CreateUser добавляет пользователя и присваивает ему ID.
+func (ms *MemoryStore) CreateUser(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, u := range ms.users {
+		if u.Username == user.Username {
+			return ErrUserAlreadyExists
+		}
+	}
+	// ID не переиспользуются: после удаления пользователя (SCIM) len+1 совпал бы с чужим.
+	ms.lastUserID++
+	user.ID = ms.lastUserID
+	ms.users = append(ms.users, *user)
+	return nil
+}
+
+// GetUserByID ищет пользователя по ID.
+func (ms *MemoryStore) GetUserByID(ctx context.Context, id int) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	for _, u := range ms.users {
+		if u.ID == id {
+			found := u
+			return &found, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// UpdateUser перезаписывает пользователя целиком.
+func (ms *MemoryStore) UpdateUser(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	idx := -1
+	for i, u := range ms.users {
+		if u.Username == user.Username && u.ID != user.ID {
+			return ErrUserAlreadyExists
+		}
+		if u.ID == user.ID {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return ErrUserNotFound
+	}
+	ms.users[idx] = *user
+	return nil
+}
+
+// DeleteUser удаляет пользователя; его задачи остаются без владельца-пользователя.
+func (ms *MemoryStore) DeleteUser(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for i, u := range ms.users {
+		if u.ID == id {
+			ms.users = append(ms.users[:i], ms.users[i+1:]...)
+			for sid, sess := range ms.sessions {
+				if sess.UserID == id {
+					delete(ms.sessions, sid)
+				}
+			}
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
+
+// GetUserByUsername ищет пользователя по имени.
+func (ms *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	for _, u := range ms.users {
+		if u.Username == username {
+			found := u
+			return &found, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// GetAllUsers возвращает пользователей по возрастанию ID.
+func (ms *MemoryStore) GetAllUsers(ctx context.Context) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	out := make([]User, len(ms.users))
+	copy(out, ms.users)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// CreateSubtask добавляет подзадачу в конец чек-листа задачи.
+func (ms *MemoryStore) CreateSubtask(ctx context.Context, subtask *SubTask) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ms.load(); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	sh := ms.shard(subtask.TaskID)
+	sh.mu.Lock()
+	prev, ok := sh.tasks[subtask.TaskID]
+	if !ok {
+		sh.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	ms.lastSubID++
+	subtask.ID = ms.lastSubID
+	updated := cloneTask(prev)
+	updated.SubTasks = append(updated.SubTasks, *subtask)
+	sh.tasks[subtask.TaskID] = updated
+	sh.mu.Unlock()
+
+	return ms.persist(ctx)
+}
+
+// UpdateSubTaskStatus отмечает подзадачу выполненной или снимает отметку.
+func (ms *MemoryStore) UpdateSubTaskStatus(ctx context.Context, subID int, done bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ms.load(); err != nil {
+		return err
+	}
+
+	for i := range ms.shards {
+		sh := &ms.shards[i]
+		sh.mu.Lock()
+		for id, t := range sh.tasks {
+			for j := range t.SubTasks {
+				if t.SubTasks[j].ID != subID {
+					continue
+				}
+				updated := cloneTask(t)
+				updated.SubTasks[j].Done = done
+				sh.tasks[id] = updated
+				sh.mu.Unlock()
+				return ms.persist(ctx)
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return ErrTaskNotFound
+}
@@ -0,0 +1,380 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrMergeUnsupported -- хранилище не умеет сливать задачи.
+	ErrMergeUnsupported = errors.New("task merge is not supported by this storage")
+	// ErrMergeSelf -- задачу пытаются слить саму в себя.
+	ErrMergeSelf = errors.New("target task cannot be one of the sources")
+)
+
+// MergeRequest -- тело POST /api/v1/tasks/{id}/merge: дубли, которые сливаются в задачу {id}
+// (не больше 50 за запрос).
+type MergeRequest struct {
+	SourceIDs []int `json:"source_ids" validate:"required,min=1,max=50,dive,gt=0"`
+}
+
+// TaskMerger -- опциональная возможность хранилища сливать дубли.
+//
+// MergeTasks атомарно переносит метки и подзадачи источников в целевую задачу, удаляет источники
+// и оставляет на их месте "надгробия" -- перенаправления на целевую задачу.
+// MergedInto говорит, в какую задачу слита удалённая задача id (ok == false -- не сливалась).
+type TaskMerger interface {
+	MergeTasks(ctx context.Context, targetID int, sourceIDs []int, userID int) (*Task, error)
+	MergedInto(ctx context.Context, id int) (to int, ok bool, err error)
+}
+
+// MergeTasks сливает дубли sourceIDs в задачу targetID и возвращает обновлённую задачу.
+func (s *Service) MergeTasks(ctx context.Context, targetID int, sourceIDs []int, userID int) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m, ok := s.capabilities().(TaskMerger)
+	if !ok {
+		return nil, ErrMergeUnsupported
+	}
+
+	seen := make(map[int]bool, len(sourceIDs))
+	sources := make([]int, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, ErrMergeSelf
+		}
+		if !seen[id] {
+			seen[id] = true
+			sources = append(sources, id)
+		}
+	}
+	// Цель и источники читаем от имени пользователя: задача чужого закрытого проекта для него
+	// не существует, её нельзя ни удалить слиянием, ни забрать её подзадачи и метки.
+	// Прочитанные источники заодно дают UUID надгробиям журнала ревизий.
+	target, err := s.GetTaskByID(ctx, targetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	tags := slices.Clone(target.Tags)
+	deleted := make([]Task, len(sources))
+	for i, id := range sources {
+		t, err := s.GetTaskByID(ctx, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", id, err)
+		}
+		deleted[i] = *t
+		tags = append(tags, t.Tags...)
+	}
+	if err := s.checkProjectTags(userID, tags); err != nil {
+		return nil, err
+	}
+	merged, err := m.MergeTasks(ctx, targetID, sources, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishChange(ctx, ChangeUpdated, *merged)
+	for _, t := range deleted {
+		s.publishChange(ctx, ChangeDeleted, t)
+	}
+	return merged, nil
+}
+
+// MergedInto возвращает ID задачи, в которую слита задача id. Хранилища без слияния
+// надгробий не хранят -- для них всегда ok == false.
+func (s *Service) MergedInto(ctx context.Context, id int) (int, bool, error) {
+	m, ok := s.capabilities().(TaskMerger)
+	if !ok {
+		return 0, false, nil
+	}
+	return m.MergedInto(ctx, id)
+}
+
+// mergeInto дописывает к целевой задаче метки и подзадачи источника.
+// Метки сравниваются без учёта регистра, порядок сохраняется (сначала метки цели).
+func mergeInto(target, src *Task) {
+	for _, tag := range src.Tags {
+		if !hasTag(target.Tags, tag) {
+			target.Tags = append(target.Tags, tag)
+		}
+	}
+	for _, sub := range src.SubTasks {
+		sub.TaskID = target.ID
+		target.SubTasks = append(target.SubTasks, sub)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// redirectsFile -- надгробия слитых задач JSON-хранилища (ID источника -> ID цели).
+// Лежат рядом с файлом задач, как и настройки пользователей.
+type redirectsFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data map[int]int
+}
+
+func (ts *TaskStore) redirectsPath() string {
+	return ts.filename + ".redirects.json"
+}
+
+func (ts *TaskStore) loadRedirects() error {
+	ts.redirects.once.Do(func() {
+		ts.redirects.data = make(map[int]int)
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.redirectsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.redirects.err = err
+			return
+		}
+		var byID map[string]int
+		if err := json.Unmarshal(raw, &byID); err != nil {
+			ts.redirects.err = fmt.Errorf("parse %s: %w", ts.redirectsPath(), err)
+			return
+		}
+		for k, to := range byID {
+			if from, err := strconv.Atoi(k); err == nil {
+				ts.redirects.data[from] = to
+			}
+		}
+	})
+	return ts.redirects.err
+}
+
+// saveRedirects переписывает файл надгробий целиком. Вызывающий держит redirects.mu.
+func (ts *TaskStore) saveRedirects() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.redirects.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.redirectsPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.redirectsPath())
+}
+
+// lockShards берёт блокировки шардов задач ids в порядке номеров шардов
+// (как коммит транзакции) и возвращает функцию, которая их отпускает.
+func (ts *TaskStore) lockShards(ids []int) (unlock func()) {
+	var touched [taskShards]bool
+	for _, id := range ids {
+		touched[uint(id)%taskShards] = true
+	}
+	for i := range ts.shards {
+		if touched[i] {
+			ts.shards[i].mu.Lock()
+		}
+	}
+	return func() {
+		for i := range ts.shards {
+			if touched[i] {
+				ts.shards[i].mu.Unlock()
+			}
+		}
+	}
+}
+
+// MergeTasks сливает задачи в памяти и сохраняет результат одной записью файла.
+// Надгробия пишутся в свой файл до файла задач: они читаются только для отсутствующих задач,
+// поэтому лишняя запись о не удалённом источнике (если файл задач записать не удалось) безвредна.
+func (ts *TaskStore) MergeTasks(ctx context.Context, targetID int, sourceIDs []int, userID int) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadRedirects(); err != nil {
+		return nil, err
+	}
+
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+
+	unlock := ts.lockShards(append([]int{targetID}, sourceIDs...))
+	target, ok := ts.shard(targetID).tasks[targetID]
+	if !ok {
+		unlock()
+		return nil, ErrTaskNotFound
+	}
+	merged := cloneTask(target)
+	prev := make(map[int]*Task, len(sourceIDs))
+	for _, id := range sourceIDs {
+		src, ok := ts.shard(id).tasks[id]
+		if !ok {
+			unlock()
+			return nil, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+		}
+		prev[id] = src
+		mergeInto(merged, src)
+	}
+	ts.shard(targetID).tasks[targetID] = cloneTask(merged)
+	for _, id := range sourceIDs {
+		delete(ts.shard(id).tasks, id)
+	}
+	unlock()
+
+	rollback := func() {
+		ts.replace(targetID, target)
+		for id, t := range prev {
+			ts.replace(id, t)
+		}
+	}
+
+	ts.redirects.mu.Lock()
+	defer ts.redirects.mu.Unlock()
+	before := make(map[int]int, len(ts.redirects.data))
+	for from, to := range ts.redirects.data {
+		before[from] = to
+	}
+	for from, to := range ts.redirects.data {
+		if prev[to] != nil { // надгробия, ведущие в источник, теперь ведут в цель
+			ts.redirects.data[from] = targetID
+		}
+	}
+	for _, id := range sourceIDs {
+		ts.redirects.data[id] = targetID
+	}
+	if err := ts.saveRedirects(); err != nil {
+		ts.redirects.data = before
+		rollback()
+		return nil, err
+	}
+
+	if err := ts.persistLocked(ctx); err != nil {
+		ts.redirects.data = before
+		_ = ts.saveRedirects()
+		rollback()
+		return nil, err
+	}
+	for _, t := range prev {
+		ts.uuids.Delete(t.UUID)
+	}
+	return merged, nil
+}
+
+// MergedInto ищет надгробие задачи id. Если цель с тех пор удалена, перенаправлять некуда.
+func (ts *TaskStore) MergedInto(ctx context.Context, id int) (int, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	if err := ts.loadRedirects(); err != nil {
+		return 0, false, err
+	}
+	ts.redirects.mu.Lock()
+	to, ok := ts.redirects.data[id]
+	ts.redirects.mu.Unlock()
+	if !ok || ts.published(to) == nil {
+		return 0, false, nil
+	}
+	return to, true, nil
+}
+
+// MergeTasks сливает задачи и коммитит результат одним коммитом.
+func (gs *GitStore) MergeTasks(ctx context.Context, targetID int, sourceIDs []int, userID int) (*Task, error) {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+
+	merged, err := gs.TaskStore.MergeTasks(ctx, targetID, sourceIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(sourceIDs))
+	for i, id := range sourceIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	msg := fmt.Sprintf("merge tasks %s into %d", strings.Join(ids, ", "), targetID)
+	return merged, gs.commit(ctx, userAuthor(userID), msg)
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// MergeTasks сливает задачи в одной транзакции БД: подзадачи переезжают в цель (UPDATE subtasks),
+// источники удаляются, надгробия пишутся в task_redirects (migrations/000009_task_redirects.up.sql).
+func (r *PostgresRepository) MergeTasks(ctx context.Context, targetID int, sourceIDs []int, userID int) (*Task, error) {
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := tx.(*pgTx).q
+	ids := append([]int{targetID}, sourceIDs...)
+	sort.Ints(ids) // блокируем строки в одном порядке, чтобы встречные слияния не зависли
+	if _, err := q.ExecContext(ctx, "SELECT id FROM tasks WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(ids)); err != nil {
+		return nil, err
+	}
+
+	target, err := tx.GetByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range sourceIDs {
+		src, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", id, err)
+		}
+		mergeInto(target, src)
+	}
+
+	if _, err := q.ExecContext(ctx, "UPDATE tasks SET tags = $1 WHERE id = $2", pq.Array(tagsOrEmpty(target.Tags)), targetID); err != nil {
+		return nil, err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE subtasks SET task_id = $1 WHERE task_id = ANY($2)", targetID, pq.Array(sourceIDs)); err != nil {
+		return nil, err
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE task_redirects SET to_id = $1 WHERE to_id = ANY($2)", targetID, pq.Array(sourceIDs)); err != nil {
+		return nil, err
+	}
+	if _, err := q.ExecContext(ctx, `INSERT INTO task_redirects (from_id, to_id)
+		SELECT unnest($2::int[]), $1
+		ON CONFLICT (from_id) DO UPDATE SET to_id = EXCLUDED.to_id, merged_at = now()`, targetID, pq.Array(sourceIDs)); err != nil {
+		return nil, err
+	}
+	if _, err := q.ExecContext(ctx, "DELETE FROM tasks WHERE id = ANY($1)", pq.Array(sourceIDs)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// MergedInto читает надгробие из task_redirects (при удалении цели оно удаляется каскадом).
+func (r *PostgresRepository) MergedInto(ctx context.Context, id int) (int, bool, error) {
+	var to int
+	err := r.q.QueryRowContext(ctx, "SELECT to_id FROM task_redirects WHERE from_id = $1", id).Scan(&to)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return to, true, nil
+}
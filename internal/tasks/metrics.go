@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+// Метрики по задачам. Это gauge, а не счётчики: их целиком пересчитывает
+// периодический рефрешер (RunMetricsRefresher), а не отдельные запросы.
+var (
+	tasksOverdue = metrics.NewGaugeVec("tasks_overdue_total",
+		"Open tasks with a due date in the past, by assignee.", "assigned_to")
+	tasksOpen = metrics.NewGaugeVec("tasks_open_total",
+		"Open (not done) tasks, by status.", "status")
+	tasksOldestOverdue = metrics.NewGaugeVec("tasks_oldest_overdue_seconds",
+		"How long the most overdue open task is past its due date.")
+	tasksMetricsRefreshed = metrics.NewGaugeVec("tasks_metrics_last_refresh_timestamp_seconds",
+		"Unix time of the last successful task metrics refresh.")
+)
+
+// RefreshMetrics пересчитывает метрики по задачам: просроченные (по исполнителю),
+// открытые (по статусу) и возраст самой старой просрочки.
+func (s *Service) RefreshMetrics(ctx context.Context, now time.Time) error {
+	// Все хранилища отдают задачи всей семьи, userID не важен.
+	list, err := s.repo.GetAll(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	overdue := make(map[int]int)
+	// Серии -- по статусам процесса (workflow.go), чтобы пустые статусы показывали 0.
+	open := make(map[string]int)
+	for _, st := range ActiveWorkflow().Statuses {
+		if st.Category != StatusDone {
+			open[st.ID] = 0
+		}
+	}
+	var oldest time.Duration
+	for i := range list {
+		t := &list[i]
+		normalizeStatus(t)
+		if t.Done {
+			continue
+		}
+		open[t.Status]++
+		if t.Due != nil && t.Due.Before(now) {
+			overdue[t.AssignedTo]++
+			if late := now.Sub(*t.Due); late > oldest {
+				oldest = late
+			}
+		}
+	}
+
+	// Исполнители без просрочек должны пропасть из выдачи, поэтому серии сбрасываем целиком.
+	tasksOverdue.Reset()
+	for assignee, n := range overdue {
+		tasksOverdue.WithLabelValues(strconv.Itoa(assignee)).Set(float64(n))
+	}
+	tasksOpen.Reset() // статусы могли смениться вместе с процессом
+	for status, n := range open {
+		tasksOpen.WithLabelValues(status).Set(float64(n))
+	}
+	tasksOldestOverdue.WithLabelValues().Set(oldest.Seconds())
+	tasksMetricsRefreshed.WithLabelValues().Set(float64(now.Unix()))
+	return nil
+}
+
+// RunMetricsRefresher раз в interval пересчитывает метрики задач и burn rate SLO (если slo != nil).
+// Блокируется до отмены ctx -- запускать в отдельной горутине.
+func (s *Service) RunMetricsRefresher(ctx context.Context, interval time.Duration, slo *metrics.SLO) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if err := s.RefreshMetrics(ctx, now); err != nil && ctx.Err() == nil {
+			log.Printf("metrics refresh error: %v", err)
+		}
+		if slo != nil {
+			slo.Refresh(now)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
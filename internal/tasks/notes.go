@@ -0,0 +1,379 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NoteDateLayout -- формат дня заметки (ключ заметки).
+const NoteDateLayout = "2006-01-02"
+
+var (
+	// ErrNotesUnsupported -- хранилище не умеет хранить заметки.
+	ErrNotesUnsupported = errors.New("notes are not supported by this storage")
+	// ErrNoteNotFound -- за этот день заметки нет.
+	ErrNoteNotFound = errors.New("note not found")
+	// ErrInvalidNoteDate -- день не в формате YYYY-MM-DD.
+	ErrInvalidNoteDate = errors.New(`date must be YYYY-MM-DD or "today"`)
+)
+
+// Note -- запись дневника за день: свободный текст в markdown. У пользователя одна заметка на день,
+// заметки личные -- их видит только автор.
+type Note struct {
+	UserID    int       `json:"user_id"`
+	Date      string    `json:"date"` // YYYY-MM-DD в часовом поясе пользователя
+	Body      string    `json:"body"` // markdown
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveNoteRequest -- тело PUT /api/v1/notes/{date}.
+type SaveNoteRequest struct {
+	Body string `json:"body" validate:"required,max=100000"`
+}
+
+// NoteStore -- опциональная возможность хранилища хранить заметки.
+// GetNote возвращает ok == false, если заметки за день нет.
+type NoteStore interface {
+	GetNote(ctx context.Context, userID int, date string) (n Note, ok bool, err error)
+	SaveNote(ctx context.Context, n Note) error
+	DeleteNote(ctx context.Context, userID int, date string) error
+	// ListNotes возвращает заметки за дни [from, to] (пустая граница -- без ограничения) по порядку дат.
+	ListNotes(ctx context.Context, userID int, from, to string) ([]Note, error)
+}
+
+// ParseNoteDate разбирает день заметки. "today" -- сегодня в поясе loc.
+func ParseNoteDate(s string, now time.Time, loc *time.Location) (string, error) {
+	if s == "today" {
+		return now.In(loc).Format(NoteDateLayout), nil
+	}
+	d, err := time.Parse(NoteDateLayout, s)
+	if err != nil {
+		return "", ErrInvalidNoteDate
+	}
+	return d.Format(NoteDateLayout), nil
+}
+
+func (s *Service) notes() (NoteStore, error) {
+	ns, ok := s.capabilities().(NoteStore)
+	if !ok {
+		return nil, ErrNotesUnsupported
+	}
+	return ns, nil
+}
+
+// GetNote возвращает заметку пользователя за день.
+func (s *Service) GetNote(ctx context.Context, userID int, date string) (Note, error) {
+	if err := ctx.Err(); err != nil {
+		return Note{}, err
+	}
+	ns, err := s.notes()
+	if err != nil {
+		return Note{}, err
+	}
+	n, ok, err := ns.GetNote(ctx, userID, date)
+	if err == nil && !ok {
+		err = ErrNoteNotFound
+	}
+	return n, err
+}
+
+// SaveNote создаёт или заменяет заметку за день. created == true, если заметки не было.
+func (s *Service) SaveNote(ctx context.Context, userID int, date, body string, now time.Time) (n Note, created bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Note{}, false, err
+	}
+	ns, err := s.notes()
+	if err != nil {
+		return Note{}, false, err
+	}
+	prev, ok, err := ns.GetNote(ctx, userID, date)
+	if err != nil {
+		return Note{}, false, err
+	}
+
+	n = Note{UserID: userID, Date: date, Body: body, CreatedAt: now.UTC(), UpdatedAt: now.UTC()}
+	if ok {
+		n.CreatedAt = prev.CreatedAt
+	}
+	return n, !ok, ns.SaveNote(ctx, n)
+}
+
+// DeleteNote удаляет заметку за день.
+func (s *Service) DeleteNote(ctx context.Context, userID int, date string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ns, err := s.notes()
+	if err != nil {
+		return err
+	}
+	return ns.DeleteNote(ctx, userID, date)
+}
+
+// ListNotes возвращает заметки пользователя за дни [from, to].
+func (s *Service) ListNotes(ctx context.Context, userID int, from, to string) ([]Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ns, err := s.notes()
+	if err != nil {
+		return nil, err
+	}
+	return ns.ListNotes(ctx, userID, from, to)
+}
+
+// Agenda -- план на день: заметка и задачи со сроком в этот день.
+type Agenda struct {
+	Date     string `json:"date"`
+	Timezone string `json:"timezone"`
+	Note     *Note  `json:"note"`              // nil -- заметки за день нет
+	Tasks    []Task `json:"tasks"`             // срок в этот день, по времени срока
+	Overdue  []Task `json:"overdue,omitempty"` // только для сегодняшнего дня: невыполненные с прошедшим сроком
+}
+
+// Agenda собирает план на день date (YYYY-MM-DD в поясе loc).
+// Хранилище без заметок не мешает: в плане будут только задачи.
+func (s *Service) Agenda(ctx context.Context, userID int, date string, now time.Time, loc *time.Location) (Agenda, error) {
+	day, err := time.ParseInLocation(NoteDateLayout, date, loc)
+	if err != nil {
+		return Agenda{}, ErrInvalidNoteDate
+	}
+	a := Agenda{Date: date, Timezone: loc.String(), Tasks: []Task{}}
+
+	n, err := s.GetNote(ctx, userID, date)
+	switch {
+	case err == nil:
+		a.Note = &n
+	case errors.Is(err, ErrNoteNotFound), errors.Is(err, ErrNotesUnsupported):
+	default:
+		return Agenda{}, err
+	}
+
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return Agenda{}, err
+	}
+	// Границы дня -- как у ?due=today, только для выбранного дня.
+	from, to, _ := DueRange(DueToday, day.Add(12*time.Hour), loc)
+	for _, t := range list {
+		if t.Due != nil && !t.Due.Before(from) && t.Due.Before(to) {
+			a.Tasks = append(a.Tasks, t)
+		}
+	}
+	_ = SortTasks(a.Tasks, SortByDue)
+
+	if date == now.In(loc).Format(NoteDateLayout) {
+		a.Overdue, _ = FilterByDue(list, DueOverdue, now, loc)
+		_ = SortTasks(a.Overdue, SortByDue)
+	}
+	return a, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// notesFile -- заметки JSON-хранилища (ID пользователя -> день -> заметка).
+// Лежат рядом с файлом задач, как и настройки пользователей.
+type notesFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data map[int]map[string]Note
+}
+
+func (ts *TaskStore) notesPath() string {
+	return ts.filename + ".notes.json"
+}
+
+func (ts *TaskStore) loadNotes() error {
+	ts.notes.once.Do(func() {
+		ts.notes.data = make(map[int]map[string]Note)
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.notesPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.notes.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.notes.data); err != nil {
+			ts.notes.err = fmt.Errorf("parse %s: %w", ts.notesPath(), err)
+		}
+	})
+	return ts.notes.err
+}
+
+// saveNotes переписывает файл заметок целиком. Вызывающий держит notes.mu.
+func (ts *TaskStore) saveNotes() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.notes.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.notesPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.notesPath())
+}
+
+// GetNote читает заметку из памяти (файл читается один раз).
+func (ts *TaskStore) GetNote(ctx context.Context, userID int, date string) (Note, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Note{}, false, err
+	}
+	if err := ts.loadNotes(); err != nil {
+		return Note{}, false, err
+	}
+	ts.notes.mu.Lock()
+	defer ts.notes.mu.Unlock()
+	n, ok := ts.notes.data[userID][date]
+	return n, ok, nil
+}
+
+// SaveNote сохраняет заметку и переписывает файл заметок.
+func (ts *TaskStore) SaveNote(ctx context.Context, n Note) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadNotes(); err != nil {
+		return err
+	}
+	ts.notes.mu.Lock()
+	defer ts.notes.mu.Unlock()
+
+	byDate := ts.notes.data[n.UserID]
+	if byDate == nil {
+		byDate = make(map[string]Note)
+		ts.notes.data[n.UserID] = byDate
+	}
+	prev, had := byDate[n.Date]
+	byDate[n.Date] = n
+	if err := ts.saveNotes(); err != nil {
+		if had {
+			byDate[n.Date] = prev
+		} else {
+			delete(byDate, n.Date)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteNote удаляет заметку и переписывает файл заметок.
+func (ts *TaskStore) DeleteNote(ctx context.Context, userID int, date string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadNotes(); err != nil {
+		return err
+	}
+	ts.notes.mu.Lock()
+	defer ts.notes.mu.Unlock()
+
+	prev, ok := ts.notes.data[userID][date]
+	if !ok {
+		return ErrNoteNotFound
+	}
+	delete(ts.notes.data[userID], date)
+	if err := ts.saveNotes(); err != nil {
+		ts.notes.data[userID][date] = prev
+		return err
+	}
+	return nil
+}
+
+// ListNotes отдаёт заметки пользователя за дни [from, to]. Дни в формате YYYY-MM-DD
+// сравниваются как строки.
+func (ts *TaskStore) ListNotes(ctx context.Context, userID int, from, to string) ([]Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadNotes(); err != nil {
+		return nil, err
+	}
+	ts.notes.mu.Lock()
+	defer ts.notes.mu.Unlock()
+
+	out := []Note{}
+	for date, n := range ts.notes.data[userID] {
+		if (from == "" || date >= from) && (to == "" || date <= to) {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// GetNote читает заметку из notes (migrations/000011_notes.up.sql).
+func (r *PostgresRepository) GetNote(ctx context.Context, userID int, date string) (Note, bool, error) {
+	n := Note{UserID: userID}
+	err := r.q.QueryRowContext(ctx, `SELECT to_char(day, 'YYYY-MM-DD'), body, created_at, updated_at
+		FROM notes WHERE user_id = $1 AND day = $2`, userID, date).Scan(&n.Date, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Note{}, false, nil
+	}
+	if err != nil {
+		return Note{}, false, err
+	}
+	return n, true, nil
+}
+
+// SaveNote сохраняет (upsert) заметку.
+func (r *PostgresRepository) SaveNote(ctx context.Context, n Note) error {
+	_, err := r.q.ExecContext(ctx, `INSERT INTO notes (user_id, day, body, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, day) DO UPDATE SET body = EXCLUDED.body, updated_at = EXCLUDED.updated_at`,
+		n.UserID, n.Date, n.Body, n.CreatedAt, n.UpdatedAt)
+	return err
+}
+
+// DeleteNote удаляет заметку.
+func (r *PostgresRepository) DeleteNote(ctx context.Context, userID int, date string) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM notes WHERE user_id = $1 AND day = $2", userID, date)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNoteNotFound
+	}
+	return err
+}
+
+// ListNotes читает заметки пользователя за дни [from, to].
+func (r *PostgresRepository) ListNotes(ctx context.Context, userID int, from, to string) ([]Note, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT to_char(day, 'YYYY-MM-DD'), body, created_at, updated_at FROM notes
+		WHERE user_id = $1 AND ($2 = '' OR day >= $2::date) AND ($3 = '' OR day <= $3::date)
+		ORDER BY day`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Note{}
+	for rows.Next() {
+		n := Note{UserID: userID}
+		if err := rows.Scan(&n.Date, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,96 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Notification -- уведомление пользователю о задаче.
+type Notification struct {
+	UserID int    `json:"user_id"`
+	TaskID int    `json:"task_id"`
+	Source string `json:"source"` // кто отправил: "rule:3" и т.п.
+	Text   string `json:"text"`
+}
+
+// Notifier доставляет уведомления. Способ доставки (почта, мессенджер, push) подключается
+// своей реализацией через Service.SetNotifier; по умолчанию уведомления пишутся в лог.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// logNotifier -- доставка по умолчанию: строка в логе.
+type logNotifier struct{}
+
+func (logNotifier) Notify(_ context.Context, n Notification) error {
+	log.Printf("notify user=%d task=%d source=%s text=%q", n.UserID, n.TaskID, n.Source, n.Text)
+	return nil
+}
+
+// SetNotifier подключает доставку уведомлений. Вызывать до запуска фоновых задач.
+func (s *Service) SetNotifier(n Notifier) {
+	s.notifier = n
+}
+
+func (s *Service) notify(ctx context.Context, n Notification) error {
+	if s.notifier == nil {
+		return logNotifier{}.Notify(ctx, n)
+	}
+	return s.notifier.Notify(ctx, n)
+}
+
+// MailNotifier -- доставка уведомлений письмом на адрес пользователя (SMTP_ADDR, NOTIFY_EMAIL).
+// В письмо о задаче добавляются подписанные ссылки "выполнено" и "отложить" (см. actions.go),
+// если задан PUBLIC_URL. Пользователю без адреса уведомление пишется в лог, как без почты.
+func (s *Service) MailNotifier() Notifier {
+	return mailNotifier{s: s}
+}
+
+type mailNotifier struct {
+	s *Service
+}
+
+func (m mailNotifier) Notify(ctx context.Context, n Notification) error {
+	s := m.s
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+	u, err := s.UserByID(ctx, n.UserID)
+	if err != nil || u.Email == "" || u.Disabled {
+		return logNotifier{}.Notify(ctx, n)
+	}
+
+	subject := "Напоминание"
+	body := n.Text + "\n"
+	if n.TaskID > 0 {
+		if task, err := s.GetTaskByID(ctx, n.TaskID, n.UserID); err == nil {
+			subject = "Задача: " + task.Title
+			body += m.actionLinks(task, n.UserID)
+		}
+	}
+	return s.mailer.Send(ctx, []string{u.Email}, subject, body, nil)
+}
+
+// actionLinks -- ссылки быстрых действий для письма (пусто, если PUBLIC_URL не задан или задача уже выполнена).
+func (m mailNotifier) actionLinks(task *Task, userID int) string {
+	if m.s.publicURL == "" || task.Done {
+		return ""
+	}
+	ttl := m.s.actionTTL
+	if ttl <= 0 {
+		ttl = DefaultActionLinkTTL
+	}
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	out := "\n"
+	for _, action := range []string{ActionDone, ActionSnooze} {
+		link, err := m.s.actionURL(ActionLink{Action: action, TaskID: task.ID, UserID: userID, Expires: expires}, task)
+		if err != nil {
+			log.Printf("WARNING: action link for task %d: %v", task.ID, err)
+			continue
+		}
+		out += actionLabels[action] + ":\n" + link + "\n\n"
+	}
+	return out + fmt.Sprintf("Ссылки действуют до %s.\n", expires.UTC().Format("02.01.2006 15:04 UTC"))
+}
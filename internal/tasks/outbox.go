@@ -0,0 +1,173 @@
+package tasks
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Outbox -- почтовый ящик демо-режима: письма (Mailer) и webhook (httpclient.Sink) не уходят наружу,
+// а складываются в память, и их видно в GET /api/v1/demo/outbox. Так можно посмотреть, что было бы
+// отправлено, не настраивая SMTP и адреса получателей. Хранятся последние outboxSize сообщений.
+type Outbox struct {
+	mu    sync.Mutex
+	seq   int
+	items []OutboxMessage // по возрастанию ID
+}
+
+// Виды сообщений в ящике.
+const (
+	OutboxEmail   = "email"
+	OutboxWebhook = "webhook"
+)
+
+const (
+	outboxSize    = 200
+	maxOutboxBody = 64 << 10 // длиннее -- обрезается
+)
+
+// OutboxMessage -- пойманное письмо или webhook.
+type OutboxMessage struct {
+	ID      int       `json:"id"`
+	Kind    string    `json:"kind"`    // email или webhook
+	Channel string    `json:"channel"` // smtp или интеграция webhook: automation, report, quota
+	At      time.Time `json:"at"`
+	To      []string  `json:"to"` // адреса письма или URL webhook
+	Subject string    `json:"subject,omitempty"`
+	Method  string    `json:"method,omitempty"`
+	// Headers -- заголовки webhook (первое значение каждого).
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+	// BodyEncoding -- base64, если тело не текст; Truncated -- тело длиннее maxOutboxBody и обрезано.
+	BodyEncoding string            `json:"body_encoding,omitempty"`
+	Truncated    bool              `json:"truncated,omitempty"`
+	Attachment   *OutboxAttachment `json:"attachment,omitempty"`
+	RequestID    string            `json:"request_id,omitempty"`
+}
+
+// OutboxAttachment -- вложение письма (содержимое не хранится).
+type OutboxAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+}
+
+// NewOutbox создаёт пустой ящик.
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+// Send кладёт письмо в ящик (реализация Mailer).
+func (o *Outbox) Send(ctx context.Context, to []string, subject, body string, att *Attachment) error {
+	m := OutboxMessage{
+		Kind:      OutboxEmail,
+		Channel:   "smtp",
+		To:        slices.Clone(to),
+		Subject:   subject,
+		RequestID: appMiddleware.GetRequestID(ctx),
+	}
+	m.setBody([]byte(body))
+	if att != nil {
+		m.Attachment = &OutboxAttachment{Name: att.Name, ContentType: att.ContentType, Size: len(att.Data)}
+	}
+	o.add(m)
+	return nil
+}
+
+// Deliver кладёт webhook в ящик и отвечает 204, как довольный получатель (реализация httpclient.Sink).
+func (o *Outbox) Deliver(integration string, req *http.Request) (*http.Response, error) {
+	m := OutboxMessage{
+		Kind:      OutboxWebhook,
+		Channel:   integration,
+		To:        []string{req.URL.String()},
+		Method:    req.Method,
+		Headers:   make(map[string]string, len(req.Header)),
+		RequestID: appMiddleware.GetRequestID(req.Context()),
+	}
+	for k, v := range req.Header {
+		m.Headers[k] = v[0]
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxOutboxBody+1))
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		m.setBody(body)
+	}
+	o.add(m)
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func (m *OutboxMessage) setBody(body []byte) {
+	if len(body) > maxOutboxBody {
+		body, m.Truncated = body[:maxOutboxBody], true
+		// Обрезка могла разрезать последний символ UTF-8.
+		for i := 0; i < utf8.UTFMax-1 && len(body) > 0 && !utf8.Valid(body); i++ {
+			body = body[:len(body)-1]
+		}
+	}
+	if utf8.Valid(body) {
+		m.Body = string(body)
+		return
+	}
+	m.Body, m.BodyEncoding = base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func (o *Outbox) add(m OutboxMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seq++
+	m.ID, m.At = o.seq, time.Now().UTC()
+	if len(o.items) == outboxSize {
+		o.items = append(o.items[:0], o.items[1:]...)
+	}
+	o.items = append(o.items, m)
+}
+
+// Messages -- сообщения вида kind ("" -- все), новые первыми.
+func (o *Outbox) Messages(kind string) []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]OutboxMessage, 0, len(o.items))
+	for i := len(o.items) - 1; i >= 0; i-- {
+		if kind == "" || o.items[i].Kind == kind {
+			out = append(out, o.items[i])
+		}
+	}
+	return out
+}
+
+// Message -- сообщение по ID; false -- его нет (или уже вытеснено).
+func (o *Outbox) Message(id int) (OutboxMessage, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := slices.IndexFunc(o.items, func(m OutboxMessage) bool { return m.ID == id })
+	if i < 0 {
+		return OutboxMessage{}, false
+	}
+	return o.items[i], true
+}
+
+// Clear очищает ящик. Нумерация продолжается.
+func (o *Outbox) Clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.items = nil
+}
@@ -0,0 +1,429 @@
+package tasks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	appMiddleware "task-manager/internal/middleware"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidResetToken -- токен сброса пароля подделан, истёк или уже использован.
+var ErrInvalidResetToken = errors.New("password reset token is invalid or expired")
+
+// Argon2Params -- стоимость хэширования паролей argon2id (RFC 9106).
+// Memory -- в КиБ; на каждую проверку пароля уходит столько памяти.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultArgon2Params -- минимум, рекомендованный OWASP: 19 МиБ, 2 прохода, 1 поток.
+var DefaultArgon2Params = Argon2Params{Memory: 19 * 1024, Iterations: 2, Parallelism: 1}
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// hashSlots ограничивает число одновременных вычислений argon2id: поток входов
+// не должен съесть всю память сервера.
+var hashSlots = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+// HashPassword хэширует пароль argon2id с параметрами по умолчанию
+// (для тех, кто заводит пользователей в обход Service, -- например, демо-режима).
+func HashPassword(password string) (string, error) {
+	return DefaultArgon2Params.hash(password)
+}
+
+// hash возвращает пароль в формате PHC: $argon2id$v=19$m=19456,t=2,p=1$соль$хэш.
+func (p Argon2Params) hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := p.key(password, salt, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (p Argon2Params) key(password string, salt []byte, n uint32) []byte {
+	hashSlots <- struct{}{}
+	defer func() { <-hashSlots }()
+	return argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, n)
+}
+
+// verify сверяет пароль с хэшем из хранилища. rehash -- пароль верный, но хэш пора
+// пересчитать: он bcrypt (до перехода на argon2id) или посчитан с другими параметрами.
+func (p Argon2Params) verify(encoded, password string) (ok, rehash bool) {
+	if strings.HasPrefix(encoded, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil, true
+	}
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false
+	}
+	var version int
+	var stored Argon2Params
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &stored.Memory, &stored.Iterations, &stored.Parallelism); err != nil ||
+		stored.Iterations == 0 || stored.Parallelism == 0 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false, false
+	}
+	got := stored.key(password, salt, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false
+	}
+	return true, stored != p
+}
+
+// PasswordPolicy -- требования к новым паролям локальных пользователей (PASSWORD_*).
+// Проверяются при регистрации, смене, сбросе и провижининге; уже сохранённые пароли не трогаются.
+type PasswordPolicy struct {
+	MinLength int `json:"min_length"` // в символах
+	MaxLength int `json:"max_length"`
+	// MinClasses -- сколько разных классов символов нужно: строчные, заглавные, цифры, прочие
+	MinClasses int `json:"min_classes"`
+	// RejectCommon -- не принимать пароли из списка самых частых и содержащие логин
+	RejectCommon bool `json:"reject_common"`
+}
+
+// DefaultPasswordPolicy -- политика, если PASSWORD_* не заданы.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, MaxLength: 128, MinClasses: 2, RejectCommon: true}
+
+// PasswordPolicyError -- пароль не прошёл политику. Problems -- коды нарушений:
+// too_short, too_long, too_few_classes, contains_username, too_common.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Problems, ", ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error { return ErrInvalidPassword }
+
+// commonPasswords -- самые частые пароли из утечек, которые проходят длину и классы символов.
+var commonPasswords = map[string]bool{
+	"password1": true, "password123": true, "passw0rd": true, "p@ssw0rd": true, "qwerty123": true,
+	"qwerty12": true, "1q2w3e4r": true, "1q2w3e4r5t": true, "1qaz2wsx": true, "zaq12wsx": true,
+	"abc12345": true, "abcd1234": true, "asdf1234": true, "admin123": true, "welcome1": true,
+	"letmein1": true, "iloveyou1": true, "qwe123qwe": true, "q1w2e3r4": true, "q1w2e3r4t5": true,
+	"trustno1": true, "monkey123": true, "dragon123": true, "master123": true, "football1": true,
+	"baseball1": true, "sunshine1": true, "princess1": true, "superman1": true, "changeme1": true,
+	"test1234": true, "user1234": true, "pass1234": true, "password1!": true, "qwerty1!": true,
+	"parol123": true, "marina123": true, "natasha123": true,
+}
+
+// Check проверяет пароль пользователя username по политике.
+func (p PasswordPolicy) Check(username, password string) error {
+	var problems []string
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		problems = append(problems, "too_short")
+	}
+	// bcrypt молча обрезал пароли до 72 байт; argon2 -- нет, но совсем длинные пароли -- лишняя работа.
+	if p.MaxLength > 0 && n > p.MaxLength {
+		problems = append(problems, "too_long")
+	}
+	if characterClasses(password) < p.MinClasses {
+		problems = append(problems, "too_few_classes")
+	}
+	if p.RejectCommon {
+		lower := strings.ToLower(password)
+		if name := strings.ToLower(username); utf8.RuneCountInString(name) >= 3 && strings.Contains(lower, name) {
+			problems = append(problems, "contains_username")
+		}
+		if commonPasswords[lower] {
+			problems = append(problems, "too_common")
+		}
+	}
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func characterClasses(s string) int {
+	var lower, upper, digit, other int
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// SetPasswordPolicy задаёт требования к новым паролям. Вызывать до старта сервера.
+func (s *Service) SetPasswordPolicy(p PasswordPolicy) {
+	s.passwordPolicy = p
+}
+
+// SetPasswordHashing задаёт параметры argon2id для новых хэшей. Пароли с другими
+// параметрами пересчитываются при следующем входе. Вызывать до старта сервера.
+func (s *Service) SetPasswordHashing(p Argon2Params) error {
+	if p.Iterations < 1 || p.Parallelism < 1 || p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("argon2id: want iterations >= 1, parallelism >= 1, memory >= 8*parallelism KiB, got %+v", p)
+	}
+	s.passwordHashing = p
+	return nil
+}
+
+// SetPasswordReset задаёт срок жизни ссылок сброса пароля и шаблон ссылки в письме
+// ({token} заменяется токеном; пусто -- в письме только сам токен).
+func (s *Service) SetPasswordReset(ttl time.Duration, urlTemplate string) {
+	s.resetTTL, s.resetURL = ttl, urlTemplate
+}
+
+// hashPassword хэширует новый пароль с параметрами сервиса.
+func (s *Service) hashPassword(password string) (string, error) {
+	return s.passwordHashing.hash(password)
+}
+
+// checkPassword сверяет пароль пользователя и при необходимости переводит хэш на argon2id
+// с текущими параметрами. Ошибка пересчёта входу не мешает: попробуем в следующий раз.
+func (s *Service) checkPassword(ctx context.Context, u *User, password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+	ok, rehash := s.passwordHashing.verify(u.PasswordHash, password)
+	if !ok || !rehash {
+		return ok
+	}
+	d, err := s.directory()
+	if err != nil {
+		return true
+	}
+	hash, err := s.hashPassword(password)
+	if err == nil {
+		u.PasswordHash = hash
+		err = d.UpdateUser(ctx, u)
+	}
+	if err != nil {
+		log.Printf("WARNING: rehash password of user id=%d: %v", u.ID, err)
+	}
+	return true
+}
+
+// ChangePassword меняет пароль пользователя, знающего текущий, и отзывает остальные его сессии.
+// У пользователей внешнего каталога (LDAP) локального пароля нет -- для них это ErrInvalidCredentials.
+func (s *Service) ChangePassword(ctx context.Context, userID int, current, next string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	u, err := d.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !s.checkPassword(ctx, u, current) {
+		return ErrInvalidCredentials
+	}
+	if err := s.passwordPolicy.Check(u.Username, next); err != nil {
+		return err
+	}
+	if u.PasswordHash, err = s.hashPassword(next); err != nil {
+		return err
+	}
+	if err := d.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	s.revokeOnPasswordChange(ctx, userID)
+	return nil
+}
+
+// SetEmail задаёт адрес для писем о сбросе пароля. Требует текущий пароль: иначе укравший
+// токен перенаправил бы сброс на себя.
+func (s *Service) SetEmail(ctx context.Context, userID int, password, email string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	u, err := d.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !s.checkPassword(ctx, u, password) {
+		return ErrInvalidCredentials
+	}
+	u.Email = email
+	return d.UpdateUser(ctx, u)
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой сброса пользователю с таким логином
+// или адресом. Ответ не зависит от того, нашёлся ли пользователь: письмо уходит в фоне,
+// и по времени ответа нельзя перебирать логины.
+func (s *Service) RequestPasswordReset(ctx context.Context, login string, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, d, strings.TrimSpace(login), now); err != nil {
+			log.Printf("WARNING: password reset for %q: %v", login, err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, d UserDirectory, login string, now time.Time) error {
+	u, err := s.findUserForReset(ctx, d, login)
+	if err != nil || u == nil {
+		return err
+	}
+	// Заблокированным и пользователям каталога (без локального пароля) сбрасывать нечего.
+	if u.Disabled || u.PasswordHash == "" || u.Email == "" {
+		log.Printf("password reset: user id=%d has no local password or email, nothing sent", u.ID)
+		return nil
+	}
+	token := s.resetToken(u, now.Add(s.resetTTL))
+	body := fmt.Sprintf("Здравствуйте, %s!\n\nКто-то (надеемся, вы) запросил сброс пароля в семейном планировщике задач.\n", u.Username)
+	if s.resetURL != "" {
+		body += "\nЧтобы задать новый пароль, откройте ссылку:\n" + strings.ReplaceAll(s.resetURL, "{token}", token) + "\n"
+	} else {
+		body += "\nТокен для POST /api/v1/auth/password/reset:\n" + token + "\n"
+	}
+	body += fmt.Sprintf("\nСсылка действует до %s и только один раз. Если сброс запрашивали не вы, просто удалите это письмо.\n",
+		now.Add(s.resetTTL).Format("02.01.2006 15:04 MST"))
+	if err := s.mailer.Send(ctx, []string{u.Email}, "Сброс пароля", body, nil); err != nil {
+		return err
+	}
+	log.Printf("password reset: link sent to user id=%d", u.ID)
+	return nil
+}
+
+// findUserForReset ищет пользователя по логину, а если такого нет -- по адресу почты.
+// nil без ошибки -- не нашёлся никто.
+func (s *Service) findUserForReset(ctx context.Context, d UserDirectory, login string) (*User, error) {
+	u, err := s.repo.GetUserByUsername(ctx, login)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+	if !strings.Contains(login, "@") {
+		return nil, nil
+	}
+	users, err := s.repo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range users {
+		if candidate.Email != "" && strings.EqualFold(candidate.Email, login) {
+			return d.GetUserByID(ctx, candidate.ID)
+		}
+	}
+	return nil, nil
+}
+
+// resetToken -- ID пользователя, срок и HMAC от них и текущего хэша пароля. Хранить токены
+// не нужно: после смены пароля хэш другой, и токен (как и все выданные раньше) перестаёт подходить.
+func (s *Service) resetToken(u *User, expires time.Time) string {
+	raw := make([]byte, 16, 16+sha256.Size)
+	binary.BigEndian.PutUint64(raw, uint64(u.ID))
+	binary.BigEndian.PutUint64(raw[8:], uint64(expires.Unix()))
+	raw = append(raw, resetMAC(raw, u.PasswordHash)...)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// resetMAC подписывает токен ключом JWT; после ротации ключа старые ссылки перестают работать.
+func resetMAC(payload []byte, passwordHash string) []byte {
+	m := hmac.New(sha256.New, appMiddleware.JWTSigningKey())
+	m.Write([]byte("password-reset\x00"))
+	m.Write(payload)
+	m.Write([]byte(passwordHash))
+	return m.Sum(nil)
+}
+
+// ResetPassword задаёт новый пароль по токену из письма.
+func (s *Service) ResetPassword(ctx context.Context, token, password string, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 16+sha256.Size {
+		return ErrInvalidResetToken
+	}
+	id := int(binary.BigEndian.Uint64(raw))
+	expires := time.Unix(int64(binary.BigEndian.Uint64(raw[8:16])), 0)
+	if !now.Before(expires) {
+		return ErrInvalidResetToken
+	}
+	u, err := d.GetUserByID(ctx, id)
+	if errors.Is(err, ErrUserNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if u.Disabled || u.PasswordHash == "" || !hmac.Equal(raw[16:], resetMAC(raw[:16], u.PasswordHash)) {
+		return ErrInvalidResetToken
+	}
+	if err := s.passwordPolicy.Check(u.Username, password); err != nil {
+		return err
+	}
+	if u.PasswordHash, err = s.hashPassword(password); err != nil {
+		return err
+	}
+	if err := d.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	s.revokeOnPasswordChange(ctx, u.ID) // запрос без токена: отзываются все сессии
+	log.Printf("password reset: user id=%d set a new password", u.ID)
+	return nil
+}
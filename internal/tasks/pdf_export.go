@@ -0,0 +1,208 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-manager/internal/pdf"
+)
+
+// ProjectSummary -- сводка по «проекту» (задачам с меткой Tag; пустая метка -- весь список) для распечатки.
+type ProjectSummary struct {
+	Tag              string
+	Total            int
+	Done             int
+	Overdue          int
+	EstimateMinutes  int // сумма оценок всех задач
+	RemainingMinutes int // сумма оценок невыполненных
+	// Задачи по статусам: в работе, к выполнению, выполнены. Внутри -- по сроку.
+	InProgress []Task
+	Todo       []Task
+	Completed  []Task
+}
+
+// ProjectSummary собирает сводку по задачам с меткой tag.
+func (s *Service) ProjectSummary(ctx context.Context, userID int, tag string, now time.Time) (ProjectSummary, error) {
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return ProjectSummary{}, err
+	}
+	p := ProjectSummary{Tag: tag}
+	for _, t := range list {
+		if tag != "" && !hasTag(t.Tags, tag) {
+			continue
+		}
+		p.Total++
+		p.EstimateMinutes += t.EstimateMinutes
+		switch {
+		case t.Done:
+			p.Done++
+			p.Completed = append(p.Completed, t)
+			continue
+		case StatusCategory(t.Status) == StatusInProgress:
+			p.InProgress = append(p.InProgress, t)
+		default:
+			p.Todo = append(p.Todo, t)
+		}
+		p.RemainingMinutes += t.EstimateMinutes
+		if t.Due != nil && t.Due.Before(now) {
+			p.Overdue++
+		}
+	}
+	for _, group := range [][]Task{p.InProgress, p.Todo, p.Completed} {
+		_ = SortTasks(group, SortByDue)
+	}
+	return p, nil
+}
+
+// AgendaPDF печатает план на день: просроченное, задачи дня с чекбоксами и подзадачами, заметку.
+// users -- имена исполнителей по ID.
+func AgendaPDF(a Agenda, users map[int]string, font *pdf.Font, now time.Time, loc *time.Location) []byte {
+	day, _ := time.ParseInLocation(NoteDateLayout, a.Date, loc)
+	doc := pdf.New(font, "План на "+a.Date, "task-manager · "+now.In(loc).Format("2006-01-02 15:04"), now)
+	doc.Title("План на день")
+	doc.Subtitle(russianWeekdays[day.Weekday()] + ", " + russianDate(day) + " · " + a.Timezone)
+
+	if len(a.Overdue) > 0 {
+		doc.Section(fmt.Sprintf("Просрочено (%d)", len(a.Overdue)))
+		for _, t := range a.Overdue {
+			pdfTask(doc, t, users, loc, true)
+		}
+	}
+
+	doc.Section(fmt.Sprintf("Задачи на день (%d)", len(a.Tasks)))
+	if len(a.Tasks) == 0 {
+		doc.Muted("На этот день задач со сроком нет.")
+	}
+	for _, t := range a.Tasks {
+		pdfTask(doc, t, users, loc, false)
+	}
+
+	if a.Note != nil && strings.TrimSpace(a.Note.Body) != "" {
+		doc.Section("Заметка")
+		doc.Paragraph(a.Note.Body)
+	}
+	return doc.Bytes()
+}
+
+// ProjectPDF печатает сводку по проекту: итоги и задачи по статусам.
+func ProjectPDF(p ProjectSummary, users map[int]string, font *pdf.Font, now time.Time, loc *time.Location) []byte {
+	title := "Все задачи"
+	if p.Tag != "" {
+		title = "Проект «" + p.Tag + "»"
+	}
+	doc := pdf.New(font, title, "task-manager · "+now.In(loc).Format("2006-01-02 15:04"), now)
+	doc.Title(title)
+	doc.Subtitle("Сводка на " + russianDate(now.In(loc)) + " · " + loc.String())
+
+	doc.Section("Итоги")
+	summary := fmt.Sprintf("Всего задач: %d, выполнено: %d, осталось: %d", p.Total, p.Done, p.Total-p.Done)
+	if p.Total > 0 {
+		summary += fmt.Sprintf(" (готово %d%%)", p.Done*100/p.Total)
+	}
+	if p.Overdue > 0 {
+		summary += fmt.Sprintf("\nПросрочено: %d", p.Overdue)
+	}
+	if p.EstimateMinutes > 0 {
+		summary += "\nОценка: всего " + formatMinutes(p.EstimateMinutes) + ", осталось " + formatMinutes(p.RemainingMinutes)
+	}
+	doc.Paragraph(summary)
+
+	for _, g := range []struct {
+		title string
+		list  []Task
+	}{
+		{"В работе", p.InProgress},
+		{"К выполнению", p.Todo},
+		{"Выполнено", p.Completed},
+	} {
+		if len(g.list) == 0 {
+			continue
+		}
+		doc.Section(fmt.Sprintf("%s (%d)", g.title, len(g.list)))
+		for _, t := range g.list {
+			pdfTask(doc, t, users, loc, true)
+		}
+	}
+	if p.Total == 0 {
+		doc.Muted("Задач нет.")
+	}
+	return doc.Bytes()
+}
+
+// pdfTask печатает задачу с подзадачами. withDate -- показывать дату срока, а не только время
+// (в плане на день дата и так известна).
+func pdfTask(doc *pdf.Document, t Task, users map[int]string, loc *time.Location, withDate bool) {
+	var detail []string
+	if t.Due != nil {
+		due := t.Due.In(loc)
+		switch {
+		case withDate:
+			detail = append(detail, "срок "+due.Format("02.01.2006 15:04"))
+		default:
+			detail = append(detail, due.Format("15:04"))
+		}
+	}
+	if label := priorityLabel(t.Priority); label != "" {
+		detail = append(detail, label)
+	}
+	if name := users[t.AssignedTo]; name != "" {
+		detail = append(detail, "@"+name)
+	}
+	if t.EstimateMinutes > 0 {
+		detail = append(detail, "~"+formatMinutes(t.EstimateMinutes))
+	}
+	for _, tag := range t.Tags {
+		detail = append(detail, "#"+tag)
+	}
+	doc.Item(t.Done, fmt.Sprintf("%s  (#%d)", t.Title, t.ID), strings.Join(detail, " · "), 0)
+	for _, st := range t.SubTasks {
+		doc.Item(st.Done, st.Title, "", 1)
+	}
+}
+
+// priorityNames -- приоритеты по-русски для распечаток (обычный не печатается).
+var priorityNames = map[Priority]string{
+	PriorityLow:      "низкий приоритет",
+	PriorityHigh:     "высокий приоритет",
+	PriorityCritical: "критичный приоритет",
+}
+
+// priorityLabel -- приоритет в распечатке: подпись уровня своей шкалы, иначе название класса.
+// Приоритет по умолчанию не печатается.
+func priorityLabel(p Priority) string {
+	sc := ActivePriorityScale()
+	if p == "" || p == sc.Default {
+		return ""
+	}
+	for _, l := range sc.Levels {
+		if l.ID == p && l.Label != "" {
+			return l.Label
+		}
+	}
+	return priorityNames[p.Class()]
+}
+
+var (
+	russianMonths   = [...]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	russianWeekdays = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+)
+
+// russianDate -- "16 октября 2026".
+func russianDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), russianMonths[t.Month()-1], t.Year())
+}
+
+// formatMinutes -- "1 ч 30 мин".
+func formatMinutes(m int) string {
+	switch {
+	case m < 60:
+		return fmt.Sprintf("%d мин", m)
+	case m%60 == 0:
+		return fmt.Sprintf("%d ч", m/60)
+	default:
+		return fmt.Sprintf("%d ч %d мин", m/60, m%60)
+	}
+}
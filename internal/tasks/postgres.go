@@ -4,15 +4,98 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// taskSelect -- общий SELECT задачи вместе с подзадачами (LEFT JOIN).
+// Порядок колонок должен совпадать со scanTaskRow.
+const taskSelect = `
+		SELECT t.id, t.user_id, t.assigned_to, t.title, t.done, t.status, t.priority,
+		       t.uuid, t.description, t.tags, t.due, t.completed_at, t.estimate_minutes, t.created_at, t.fields,
+		       s.id, s.task_id, s.title, s.done
+		FROM tasks t
+		LEFT JOIN subtasks s ON t.id = s.task_id`
+
+// scanTaskRow сканирует одну строку taskSelect в задачу и (возможно пустую) подзадачу.
+// ok == false означает, что у строки нет подзадачи (LEFT JOIN вернул NULL).
+func scanTaskRow(rows *sql.Rows) (t Task, sub SubTask, ok bool, err error) {
+	// Зачем Null-типы: если у задачи НЕТ подзадач, LEFT JOIN вернет в этих полях NULL.
+	// Обычные типы int и string упадут с ошибкой при сканировании NULL.
+	var sID, sTaskID sql.NullInt64
+	var sTitle sql.NullString
+	var sDone sql.NullBool
+
+	var uuid sql.NullString
+	var due, completedAt, createdAt sql.NullTime
+
+	err = rows.Scan(
+		&t.ID, &t.UserID, &t.AssignedTo, &t.Title, &t.Done, &t.Status, &t.Priority,
+		&uuid, &t.Description, pq.Array(&t.Tags), &due, &completedAt, &t.EstimateMinutes, &createdAt, &t.Fields,
+		&sID, &sTaskID, &sTitle, &sDone,
+	)
+	if err != nil {
+		return t, sub, false, err
+	}
+
+	t.UUID = uuid.String
+	if due.Valid {
+		d := due.Time
+		t.Due = &d
+	}
+	if completedAt.Valid {
+		c := completedAt.Time
+		t.CompletedAt = &c
+	}
+	if createdAt.Valid {
+		c := createdAt.Time
+		t.CreatedAt = &c
+	}
+
+	if sID.Valid {
+		sub = SubTask{
+			ID:     int(sID.Int64),
+			TaskID: int(sTaskID.Int64),
+			Title:  sTitle.String,
+			Done:   sDone.Bool,
+		}
+	}
+	return t, sub, sID.Valid, nil
+}
+
+// nullTime переводит *time.Time в значение для SQL (nil -> NULL).
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// tagsOrEmpty не даёт записать NULL в NOT NULL колонку tags.
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// querier -- общее у *sql.DB и *sql.Tx: одни и те же методы репозитория работают и внутри транзакции.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type PostgresRepository struct {
 	db *sql.DB
+	q  querier // db или открытая транзакция (см. Begin)
 }
 
 func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{
 		db: db,
+		q:  db,
 	}
 }
 
@@ -23,9 +106,17 @@ func (r *PostgresRepository) Create(ctx context.Context, task *Task) error {
 		return err
 	}
 
-	err := r.db.QueryRowContext(ctx, "INSERT INTO tasks (user_id, assigned_to, title, done, priority) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		task.UserID, task.AssignedTo, task.Title, task.Done, task.Priority).Scan(&task.ID)
+	query := `INSERT INTO tasks (user_id, assigned_to, title, done, status, priority, uuid, description, tags, due, completed_at,
+		fields, created_at, estimate_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, COALESCE($13, now()), $14) RETURNING id`
+	err := r.q.QueryRowContext(ctx, query,
+		task.UserID, task.AssignedTo, task.Title, task.Done, task.Status, task.Priority,
+		task.UUID, task.Description, pq.Array(tagsOrEmpty(task.Tags)), nullTime(task.Due), nullTime(task.CompletedAt),
+		task.Fields, nullTime(task.CreatedAt), task.EstimateMinutes).Scan(&task.ID)
 
+	if isUniqueViolation(err) {
+		return ErrDuplicateUUID
+	}
 	if err != nil {
 		return err
 	}
@@ -38,14 +129,13 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*Task, error)
 		return nil, err
 	}
 
-	query := `
-		SELECT t.id, t.user_id, t.assigned_to, t.title, t.done, t.priority,
-		       s.id, s.task_id, s.title, s.done
-		FROM tasks t
-		LEFT JOIN subtasks s ON t.id = s.task_id
-		WHERE t.id = $1`
+	return r.queryTask(ctx, taskSelect+`
+		WHERE t.id = $1`, id)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, id)
+// queryTask читает одну задачу запросом на основе taskSelect; ни одной строки -- ErrTaskNotFound.
+func (r *PostgresRepository) queryTask(ctx context.Context, query string, args ...any) (*Task, error) {
+	rows, err := r.q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,20 +143,7 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*Task, error)
 
 	var task *Task
 	for rows.Next() {
-		// Создаем временные переменные для полей подзадачи на случай, если у задачи нет подзадач.
-		// Зачем: если у задачи НЕТ подзадач, LEFT JOIN вернет в этих полях NULL.
-		// Обычные типы int и string упадут с ошибкой при сканировании NULL.
-		var sID sql.NullInt64
-		var sTaskID sql.NullInt64
-		var sTitle sql.NullString
-		var sDone sql.NullBool
-
-		var t Task
-
-		err := rows.Scan(
-			&t.ID, &t.UserID, &t.AssignedTo, &t.Title, &t.Done, &t.Priority,
-			&sID, &sTaskID, &sTitle, &sDone,
-		)
+		t, sub, hasSub, err := scanTaskRow(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -79,15 +156,8 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*Task, error)
 			task.SubTasks = make([]SubTask, 0)
 		}
 
-		// Проверяем: если sID.Valid == true, значит в этой строке РЕАЛЬНО есть подзадача (не NULL)
-		if sID.Valid {
-			sub := SubTask{
-				ID:     int(sID.Int64),
-				TaskID: int(sTaskID.Int64),
-				Title:  sTitle.String,
-				Done:   sDone.Bool,
-			}
-			// Добавляем подзадачу в слайс нашей основной задачи
+		// Если в строке РЕАЛЬНО есть подзадача (не NULL) -- добавляем её в слайс основной задачи
+		if hasSub {
 			task.SubTasks = append(task.SubTasks, sub)
 		}
 	}
@@ -105,21 +175,19 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id int) (*Task, error)
 	return task, nil
 }
 
-// 3. Получить все задачи. Возвращает слайс.
+// 3. Получить все задачи, видимые пользователю userID (0 -- все). Возвращает слайс.
 func (r *PostgresRepository) GetAll(ctx context.Context, userID int) ([]Task, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	// Выбираем задачи семьи вместе со всеми их подзадачами через LEFT JOIN
-	query := `
-		SELECT t.id, t.user_id, t.assigned_to, t.title, t.done, t.priority,
-		       s.id, s.task_id, s.title, s.done
-		FROM tasks t
-		LEFT JOIN subtasks s ON t.id = s.task_id
+	// Выбираем задачи семьи вместе со всеми их подзадачами через LEFT JOIN.
+	// Задачи чужих закрытых проектов отсекает сам запрос (см. hiddenTagsCTE в project_acl.go).
+	query := hiddenTagsCTE + taskSelect + `
+		WHERE ` + taskVisibleSQL + `
 		ORDER BY t.id DESC`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.q.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -130,15 +198,7 @@ func (r *PostgresRepository) GetAll(ctx context.Context, userID int) ([]Task, er
 	var taskOrder []int // Чтобы сохранить правильный порядок сортировки задач
 
 	for rows.Next() {
-		var t Task
-		var sID, sTaskID sql.NullInt64
-		var sTitle sql.NullString
-		var sDone sql.NullBool
-
-		err := rows.Scan(
-			&t.ID, &t.UserID, &t.AssignedTo, &t.Title, &t.Done, &t.Priority,
-			&sID, &sTaskID, &sTitle, &sDone,
-		)
+		t, sub, hasSub, err := scanTaskRow(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -151,13 +211,7 @@ func (r *PostgresRepository) GetAll(ctx context.Context, userID int) ([]Task, er
 		}
 
 		// Если в этой строке прилетела реальная подзадача, добавляем её к родителю
-		if sID.Valid {
-			sub := SubTask{
-				ID:     int(sID.Int64),
-				TaskID: int(sTaskID.Int64),
-				Title:  sTitle.String,
-				Done:   sDone.Bool,
-			}
+		if hasSub {
 			taskMap[t.ID].SubTasks = append(taskMap[t.ID].SubTasks, sub)
 		}
 	}
@@ -181,8 +235,13 @@ func (r *PostgresRepository) Update(ctx context.Context, task *Task, userID int)
 		return err
 	}
 
-	query := "UPDATE tasks SET title=$1, done=$2, priority=$3, assigned_to=$4 WHERE id = $5"
-	result, err := r.db.ExecContext(ctx, query, task.Title, task.Done, task.Priority, task.AssignedTo, task.ID)
+	// fields: NULL (не переданы) -- оставить как есть.
+	query := `UPDATE tasks SET title=$1, done=$2, status=$3, priority=$4, assigned_to=$5, description=$6, tags=$7, due=$8,
+		completed_at=$9, fields=COALESCE($11, fields), estimate_minutes=$12
+		WHERE id = $10`
+	result, err := r.q.ExecContext(ctx, query, task.Title, task.Done, task.Status, task.Priority, task.AssignedTo,
+		task.Description, pq.Array(tagsOrEmpty(task.Tags)), nullTime(task.Due), nullTime(task.CompletedAt), task.ID,
+		task.Fields, task.EstimateMinutes)
 	if err != nil {
 		return err
 	}
@@ -201,7 +260,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, id int, userID int) err
 	}
 
 	query := "DELETE FROM tasks WHERE id = $1"
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.q.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -218,8 +277,69 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, user *User) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	query := "INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id"
-	return r.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash).Scan(&user.ID)
+	query := `INSERT INTO users (username, password_hash, display_name, email, external_id, disabled)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return r.q.QueryRowContext(ctx, query, user.Username, user.PasswordHash,
+		user.DisplayName, user.Email, user.ExternalID, user.Disabled).Scan(&user.ID)
+}
+
+// userColumns -- колонки пользователя для scanUser (хэш пароля -- вторым).
+const userColumns = "id, username, password_hash, display_name, email, external_id, disabled"
+
+func scanUser(row interface{ Scan(...any) error }, u *User) error {
+	return row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.ExternalID, &u.Disabled)
+}
+
+// GetUserByID ищет пользователя по ID (для провижининга через SCIM).
+func (r *PostgresRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var u User
+	err := scanUser(r.q.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id), &u)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UpdateUser перезаписывает пользователя целиком, включая хэш пароля.
+func (r *PostgresRepository) UpdateUser(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	result, err := r.q.ExecContext(ctx, `UPDATE users SET username = $1, password_hash = $2, display_name = $3,
+		email = $4, external_id = $5, disabled = $6 WHERE id = $7`,
+		user.Username, user.PasswordHash, user.DisplayName, user.Email, user.ExternalID, user.Disabled, user.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return ErrUserAlreadyExists
+		}
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser удаляет пользователя; его задачи, заметки и правила удаляются каскадом.
+func (r *PostgresRepository) DeleteUser(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	result, err := r.q.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 // Найти пользователя по Username
@@ -229,10 +349,10 @@ func (r *PostgresRepository) GetUserByUsername(ctx context.Context, username str
 	}
 
 	// ИСПРАВЛЕНО: выбираем колонку username по фильтру username = $1
-	query := "SELECT id, username, password_hash FROM users WHERE username = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE username = $1"
 
 	var u User
-	err := r.db.QueryRowContext(ctx, query, username).Scan(&u.ID, &u.Username, &u.PasswordHash)
+	err := scanUser(r.q.QueryRowContext(ctx, query, username), &u)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound // Убедитесь, что эта ошибка объявлена в вашем коде
@@ -249,7 +369,7 @@ func (r *PostgresRepository) CreateSubtask(ctx context.Context, subtask *SubTask
 		return err
 	}
 
-	err := r.db.QueryRowContext(ctx, "INSERT INTO subtasks (task_id, title, done) VALUES ($1, $2, $3) RETURNING id",
+	err := r.q.QueryRowContext(ctx, "INSERT INTO subtasks (task_id, title, done) VALUES ($1, $2, $3) RETURNING id",
 		subtask.TaskID, subtask.Title, subtask.Done).Scan(&subtask.ID)
 
 	if err != nil {
@@ -265,8 +385,8 @@ func (r *PostgresRepository) GetAllUsers(ctx context.Context) ([]User, error) {
 		return nil, err
 	}
 
-	// Запрашиваем только ID и Username, хэши паролей фронтенду знать нельзя
-	rows, err := r.db.QueryContext(ctx, "SELECT id, username FROM users ORDER BY id ASC")
+	// Хэши паролей не запрашиваем: фронтенду их знать нельзя
+	rows, err := r.q.QueryContext(ctx, "SELECT id, username, display_name, email, external_id, disabled FROM users ORDER BY id ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -275,8 +395,7 @@ func (r *PostgresRepository) GetAllUsers(ctx context.Context) ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var u User
-		// Сканируем только два поля
-		err := rows.Scan(&u.ID, &u.Username)
+		err := rows.Scan(&u.ID, &u.Username, &u.DisplayName, &u.Email, &u.ExternalID, &u.Disabled)
 		if err != nil {
 			return nil, err
 		}
@@ -299,7 +418,7 @@ func (r *PostgresRepository) UpdateSubTaskStatus(ctx context.Context, subID int,
 	// Простой и строгий SQL-запрос обновления одной колонки
 	query := "UPDATE subtasks SET done = $1 WHERE id = $2"
 
-	result, err := r.db.ExecContext(ctx, query, done, subID)
+	result, err := r.q.ExecContext(ctx, query, done, subID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,208 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrPreferencesUnsupported -- хранилище не умеет хранить настройки пользователей.
+var ErrPreferencesUnsupported = errors.New("storage does not support user preferences")
+
+// Preferences -- настройки пользователя: часовой пояс (для фильтров по датам и рассылок),
+// язык, приоритет новых задач по умолчанию, время ежедневной сводки и уведомления.
+type Preferences struct {
+	Timezone        string                  `json:"timezone" validate:"required,timezone"`
+	Locale          string                  `json:"locale" validate:"required,bcp47_language_tag"`
+	DefaultPriority Priority                `json:"default_priority" validate:"required,priority"`
+	DigestTime      string                  `json:"digest_time" validate:"required,datetime=15:04"` // HH:MM в Timezone
+	Notifications   NotificationPreferences `json:"notifications"`
+	// DailyCapacityMinutes -- сколько минут в рабочий день пользователь готов брать в работу (отчёт о загрузке).
+	// 0 -- длина рабочего дня по календарю (/api/v1/calendar).
+	DailyCapacityMinutes int `json:"daily_capacity_minutes" validate:"min=0,max=1440"`
+}
+
+// NotificationPreferences -- какие уведомления пользователь хочет получать.
+type NotificationPreferences struct {
+	Digest       bool `json:"digest"`        // ежедневная сводка в DigestTime
+	DueReminders bool `json:"due_reminders"` // напоминания о сроках
+	Assigned     bool `json:"assigned"`      // на меня назначили задачу
+}
+
+// DefaultPreferences -- настройки пользователя, который ещё ничего не менял.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Timezone:        "UTC",
+		Locale:          "ru",
+		DefaultPriority: PriorityMedium,
+		DigestTime:      "08:00",
+		Notifications:   NotificationPreferences{Digest: true, DueReminders: true, Assigned: true},
+	}
+}
+
+// Location возвращает часовой пояс пользователя (UTC, если пояс не распознан).
+func (p Preferences) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// PreferencesStore -- опциональная возможность хранилища хранить настройки пользователей.
+// GetPreferences возвращает ok == false, если пользователь настроек не сохранял.
+type PreferencesStore interface {
+	GetPreferences(ctx context.Context, userID int) (p Preferences, ok bool, err error)
+	SavePreferences(ctx context.Context, userID int, p Preferences) error
+}
+
+// GetPreferences возвращает настройки пользователя или значения по умолчанию.
+// Если хранилище настроек не поддерживает, тоже отдаются значения по умолчанию.
+func (s *Service) GetPreferences(ctx context.Context, userID int) (Preferences, error) {
+	if err := ctx.Err(); err != nil {
+		return Preferences{}, err
+	}
+	ps, ok := s.capabilities().(PreferencesStore)
+	if !ok {
+		return DefaultPreferences(), nil
+	}
+	p, found, err := ps.GetPreferences(ctx, userID)
+	if err != nil || !found {
+		return DefaultPreferences(), err
+	}
+	return p, nil
+}
+
+// UpdatePreferences сохраняет настройки пользователя (целиком).
+func (s *Service) UpdatePreferences(ctx context.Context, userID int, p Preferences) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ps, ok := s.capabilities().(PreferencesStore)
+	if !ok {
+		return ErrPreferencesUnsupported
+	}
+	return ps.SavePreferences(ctx, userID, p)
+}
+
+// prefsFile -- настройки пользователей JSON-хранилища лежат рядом с файлом задач
+// (файл задач -- это массив задач, класть туда что-то ещё нельзя).
+type prefsFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data map[int]Preferences
+}
+
+func (ts *TaskStore) prefsPath() string {
+	return ts.filename + ".preferences.json"
+}
+
+func (ts *TaskStore) loadPrefs() error {
+	ts.prefs.once.Do(func() {
+		ts.prefs.data = make(map[int]Preferences)
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.prefsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.prefs.err = err
+			return
+		}
+		var byUser map[string]Preferences
+		if err := json.Unmarshal(raw, &byUser); err != nil {
+			ts.prefs.err = err
+			return
+		}
+		for k, p := range byUser {
+			if id, err := strconv.Atoi(k); err == nil {
+				ts.prefs.data[id] = p
+			}
+		}
+	})
+	return ts.prefs.err
+}
+
+// GetPreferences читает настройки пользователя из памяти (файл читается один раз).
+func (ts *TaskStore) GetPreferences(ctx context.Context, userID int) (Preferences, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Preferences{}, false, err
+	}
+	if err := ts.loadPrefs(); err != nil {
+		return Preferences{}, false, err
+	}
+	ts.prefs.mu.Lock()
+	defer ts.prefs.mu.Unlock()
+	p, ok := ts.prefs.data[userID]
+	return p, ok, nil
+}
+
+// SavePreferences сохраняет настройки и переписывает файл настроек целиком (через временный файл).
+func (ts *TaskStore) SavePreferences(ctx context.Context, userID int, p Preferences) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadPrefs(); err != nil {
+		return err
+	}
+	ts.prefs.mu.Lock()
+	defer ts.prefs.mu.Unlock()
+
+	prev, had := ts.prefs.data[userID]
+	ts.prefs.data[userID] = p
+	if ts.filename == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(ts.prefs.data, "", "   ")
+	if err == nil {
+		tmp := ts.prefsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0644); err == nil {
+			err = os.Rename(tmp, ts.prefsPath())
+		}
+	}
+	if err != nil {
+		if had {
+			ts.prefs.data[userID] = prev
+		} else {
+			delete(ts.prefs.data, userID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPreferences читает настройки пользователя из user_preferences.
+func (r *PostgresRepository) GetPreferences(ctx context.Context, userID int) (Preferences, bool, error) {
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM user_preferences WHERE user_id = $1", userID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Preferences{}, false, nil
+	}
+	if err != nil {
+		return Preferences{}, false, err
+	}
+	p := DefaultPreferences() // поля, добавленные позже, получают значения по умолчанию
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return Preferences{}, false, err
+	}
+	return p, true, nil
+}
+
+// SavePreferences сохраняет (upsert) настройки пользователя.
+func (r *PostgresRepository) SavePreferences(ctx context.Context, userID int, p Preferences) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO user_preferences (user_id, data, updated_at) VALUES ($1, $2, now())
+		ON CONFLICT (user_id) DO UPDATE SET data = EXCLUDED.data, updated_at = now()`, userID, raw)
+	return err
+}
@@ -0,0 +1,213 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Priority -- уровень важности задачи: ID уровня шкалы приоритетов пространства (см. PriorityScale).
+// По умолчанию шкала встроенная и упорядочена: low < medium < high < critical.
+// В JSON по умолчанию строка; на входе принимается и число 1..N -- номер уровня в шкале (см. Rank).
+type Priority string
+
+// Встроенные уровни. Они же -- классы уровней своей шкалы (PriorityLevel.Class): по классу
+// считается срочность, выбирается приоритет в CalDAV и переводятся приоритеты импорта.
+const (
+	PriorityLow      Priority = "low"
+	PriorityMedium   Priority = "medium"
+	PriorityHigh     Priority = "high"
+	PriorityCritical Priority = "critical"
+)
+
+// classRanks -- порядок встроенных уровней (классов).
+var classRanks = map[Priority]int{PriorityLow: 1, PriorityMedium: 2, PriorityHigh: 3, PriorityCritical: 4}
+
+// Rank возвращает порядковый номер приоритета в шкале: 1 -- низший, 0 -- приоритета в шкале нет.
+func (p Priority) Rank() int {
+	return activePriorities().rank[p]
+}
+
+// Valid сообщает, что приоритет -- уровень действующей шкалы.
+func (p Priority) Valid() bool {
+	return p.Rank() > 0
+}
+
+// Class возвращает встроенный уровень, которому соответствует приоритет ("" -- неизвестный приоритет).
+func (p Priority) Class() Priority {
+	if c, ok := activePriorities().class[p]; ok {
+		return c
+	}
+	if classRanks[p] > 0 {
+		return p
+	}
+	return ""
+}
+
+// Resolve переводит приоритет в уровень действующей шкалы: уровень шкалы -- как есть, встроенный
+// уровень (из импорта, CalDAV, старых клиентов) -- в ближайший по классу, остальное -- в приоритет
+// по умолчанию.
+func (p Priority) Resolve() Priority {
+	return activePriorities().resolve(p)
+}
+
+// PriorityFromRank -- обратное к Rank. Для неизвестного номера возвращает "".
+func PriorityFromRank(rank int) Priority {
+	levels := activePriorities().Levels
+	if rank < 1 || rank > len(levels) {
+		return ""
+	}
+	return levels[rank-1].ID
+}
+
+// ParsePriority разбирает приоритет без учёта регистра и пробелов по краям.
+func ParsePriority(s string) (Priority, bool) {
+	p := Priority(strings.ToLower(strings.TrimSpace(s)))
+	return p, p.Valid()
+}
+
+// UnmarshalJSON принимает строку ("high") или число (3). Неизвестная строка сохраняется как есть --
+// её отклонит валидатор с понятной ошибкой; неизвестное число -- ошибка разбора.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var rank int
+	if err := json.Unmarshal(data, &rank); err == nil {
+		pr := PriorityFromRank(rank)
+		if pr == "" {
+			return fmt.Errorf("priority: unknown rank %d (want 1..%d)", rank, len(activePriorities().Levels))
+		}
+		*p = pr
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("priority: want string or number, got %s", data)
+	}
+	if pr, ok := ParsePriority(s); ok {
+		*p = pr
+		return nil
+	}
+	*p = Priority(s)
+	return nil
+}
+
+// canonicalPriority приводит приоритет из файла к каноническому виду: "High " -> high,
+// пустой -> medium (так было по умолчанию и в PostgreSQL). Возвращает true, если значение изменилось.
+// Неизвестные значения не трогает: при загрузке файла шкала пространства может быть ещё не прочитана.
+// Их переводит в уровни шкалы migratePriorities при запуске.
+func canonicalPriority(t *Task) bool {
+	p := Priority(strings.ToLower(strings.TrimSpace(string(t.Priority))))
+	if p == "" {
+		p = PriorityMedium
+	}
+	if p == t.Priority {
+		return false
+	}
+	t.Priority = p
+	return true
+}
+
+// normalizePriority переводит приоритет задачи в уровень действующей шкалы (см. Resolve).
+// Возвращает true, если значение изменилось.
+func normalizePriority(t *Task) bool {
+	p, _ := ParsePriority(string(t.Priority))
+	p = p.Resolve()
+	if p == t.Priority {
+		return false
+	}
+	t.Priority = p
+	return true
+}
+
+// PriorityFormatHeader -- заголовок, которым клиент выбирает формат приоритета в ответе: number или string.
+const PriorityFormatHeader = "X-Priority-Format"
+
+// priorityFormatter выбирает для запроса перевод приоритетов в числа (или nil -- оставить строки).
+func (h *Handler) priorityFormatter(r *http.Request) func(any) any {
+	numeric := h.priorityNumeric
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(PriorityFormatHeader))) {
+	case "number", "numeric":
+		numeric = true
+	case "string":
+		numeric = false
+	}
+	if !numeric {
+		return nil
+	}
+	return priorityToRank
+}
+
+// priorityToRank заменяет строковые значения полей "priority" на их Rank во всём ответе.
+func priorityToRank(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if s, ok := val.(string); ok && k == "priority" {
+				if rank := Priority(s).Rank(); rank > 0 {
+					t[k] = rank
+				}
+				continue
+			}
+			t[k] = priorityToRank(val)
+		}
+	case []any:
+		for i := range t {
+			t[i] = priorityToRank(t[i])
+		}
+	}
+	return v
+}
+
+// Ключи сортировки списка задач (параметр ?sort=). Префикс "-" -- по убыванию.
+const (
+	SortByID       = "id"
+	SortByPriority = "priority"
+	SortByDue      = "due"
+	SortByUrgency  = "urgency"
+)
+
+// ErrInvalidSort -- неизвестный ключ сортировки.
+var ErrInvalidSort = fmt.Errorf("sort must be one of %s, %s, %s, %s (optionally prefixed with -)", SortByID, SortByPriority, SortByDue, SortByUrgency)
+
+// SortTasks сортирует список по ключу key ("priority", "-priority", "due", ...).
+// Сортировка устойчивая, при равенстве ключей порядок -- по ID. Задачи без срока при сортировке по due -- в конце.
+func SortTasks(list []Task, key string) error {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b *Task) bool
+	switch key {
+	case "", SortByID:
+		less = func(a, b *Task) bool { return a.ID < b.ID }
+	case SortByPriority:
+		less = func(a, b *Task) bool { return a.Priority.Rank() < b.Priority.Rank() }
+	case SortByUrgency:
+		// Urgency проставляет ScoreUrgency до сортировки.
+		less = func(a, b *Task) bool { return a.Urgency < b.Urgency }
+	case SortByDue:
+		less = func(a, b *Task) bool {
+			switch {
+			case a.Due == nil || b.Due == nil:
+				return false
+			default:
+				return a.Due.Before(*b.Due)
+			}
+		}
+	default:
+		return ErrInvalidSort
+	}
+
+	sortTasksByID(list)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := &list[i], &list[j]
+		if key == SortByDue && (a.Due == nil) != (b.Due == nil) {
+			return b.Due == nil // без срока -- всегда в конце
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
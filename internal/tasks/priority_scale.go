@@ -0,0 +1,451 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPriorityScaleUnsupported -- хранилище не умеет хранить шкалу приоритетов (действует шкала из TASK_PRIORITIES).
+	ErrPriorityScaleUnsupported = errors.New("storage does not support the priority scale")
+	// ErrInvalidPriorityScale -- шкала приоритетов задана неверно.
+	ErrInvalidPriorityScale = errors.New("invalid priority scale")
+)
+
+// priorityIDPattern -- ID уровня: строчные латинские буквы, цифры, "_" и "-" ("p0", "must-have").
+var priorityIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// MaxPriorityLevels -- больше уровней в шкале не бывает.
+const MaxPriorityLevels = 10
+
+// PriorityLevel -- уровень шкалы приоритетов.
+type PriorityLevel struct {
+	ID    Priority `json:"id" validate:"required,max=32"`     // значение поля priority у задач: "p0"
+	Label string   `json:"label,omitempty" validate:"max=50"` // подпись для людей: "P0 -- блокер"
+	// Class -- встроенный уровень, которому соответствует этот: по нему считается срочность,
+	// выбирается приоритет в CalDAV и переводятся приоритеты импорта (Jira, Taskwarrior).
+	Class Priority `json:"class" validate:"required,oneof=low medium high critical"`
+}
+
+// PriorityScale -- шкала приоритетов пространства (одна на сервер): уровни от низшего к высшему
+// и уровень по умолчанию. По порядку уровней сортируются задачи (?sort=priority) и считаются
+// числовые приоритеты (X-Priority-Format: number), правило эскалации только повышает приоритет.
+type PriorityScale struct {
+	Levels    []PriorityLevel `json:"levels" validate:"required,min=2,max=10,dive"`
+	Default   Priority        `json:"default" validate:"required"` // приоритет новых задач, если он не указан
+	UpdatedBy int             `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+// PriorityScaleRequest -- тело PUT /api/v1/priorities. Remap -- куда перевести задачи с уровнями,
+// которых в новой шкале нет ("critical": "p0"); без него -- в уровень того же класса.
+type PriorityScaleRequest struct {
+	PriorityScale
+	Remap map[Priority]Priority `json:"remap" validate:"max=50"`
+}
+
+// PriorityScaleResult -- ответ PUT /api/v1/priorities: новая шкала и сколько задач переведено.
+type PriorityScaleResult struct {
+	PriorityScale
+	Migrated int `json:"migrated"`
+}
+
+// DefaultPriorityScale -- встроенная шкала: low < medium < high < critical, по умолчанию medium.
+func DefaultPriorityScale() PriorityScale {
+	return PriorityScale{
+		Levels: []PriorityLevel{
+			{ID: PriorityLow, Class: PriorityLow},
+			{ID: PriorityMedium, Class: PriorityMedium},
+			{ID: PriorityHigh, Class: PriorityHigh},
+			{ID: PriorityCritical, Class: PriorityCritical},
+		},
+		Default: PriorityMedium,
+	}
+}
+
+// ParsePriorityScale разбирает TASK_PRIORITIES: уровни от низшего к высшему через запятую,
+// у своего уровня через ":" -- класс ("p4:low,p3:low,p2:medium,p1:high,p0:critical").
+// Встроенным уровням класс не нужен ("low,medium,high"). По умолчанию -- первый уровень класса medium.
+func ParsePriorityScale(s string) (PriorityScale, error) {
+	var sc PriorityScale
+	for _, item := range strings.Split(s, ",") {
+		id, class, _ := strings.Cut(strings.TrimSpace(item), ":")
+		id, class = strings.ToLower(strings.TrimSpace(id)), strings.ToLower(strings.TrimSpace(class))
+		if id == "" {
+			continue
+		}
+		if class == "" {
+			class = id
+		}
+		sc.Levels = append(sc.Levels, PriorityLevel{ID: Priority(id), Class: Priority(class)})
+	}
+	for _, l := range sc.Levels {
+		if l.Class == PriorityMedium {
+			sc.Default = l.ID
+			break
+		}
+	}
+	if sc.Default == "" && len(sc.Levels) > 0 {
+		sc.Default = sc.Levels[(len(sc.Levels)-1)/2].ID
+	}
+	return sc, sc.check()
+}
+
+// check проверяет шкалу. Встроенное имя уровня означает свой класс: "high" класса low сбивал бы
+// перевод приоритетов импорта.
+func (sc PriorityScale) check() error {
+	var problems []string
+	if len(sc.Levels) < 2 || len(sc.Levels) > MaxPriorityLevels {
+		problems = append(problems, fmt.Sprintf("want 2..%d levels, got %d", MaxPriorityLevels, len(sc.Levels)))
+	}
+	seen := make(map[Priority]bool, len(sc.Levels))
+	for _, l := range sc.Levels {
+		switch {
+		case !priorityIDPattern.MatchString(string(l.ID)):
+			problems = append(problems, fmt.Sprintf("level %q: id must be lowercase letters, digits, _ or -, up to 32", l.ID))
+		case seen[l.ID]:
+			problems = append(problems, fmt.Sprintf("level %q is listed twice", l.ID))
+		case classRanks[l.Class] == 0:
+			problems = append(problems, fmt.Sprintf("level %q: unknown class %q (want low, medium, high, critical)", l.ID, l.Class))
+		case classRanks[l.ID] > 0 && l.Class != l.ID:
+			problems = append(problems, fmt.Sprintf("level %q: a built-in name must have class %q", l.ID, l.ID))
+		}
+		seen[l.ID] = true
+	}
+	if !seen[sc.Default] {
+		problems = append(problems, fmt.Sprintf("default %q is not a level of the scale", sc.Default))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidPriorityScale, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// IDs возвращает уровни шкалы от низшего к высшему.
+func (sc PriorityScale) IDs() []Priority {
+	ids := make([]Priority, len(sc.Levels))
+	for i, l := range sc.Levels {
+		ids[i] = l.ID
+	}
+	return ids
+}
+
+// priorityScale -- шкала с индексами для Rank/Class/Resolve.
+type priorityScale struct {
+	PriorityScale
+	rank  map[Priority]int
+	class map[Priority]Priority
+}
+
+func compilePriorityScale(sc PriorityScale) *priorityScale {
+	c := &priorityScale{
+		PriorityScale: sc,
+		rank:          make(map[Priority]int, len(sc.Levels)),
+		class:         make(map[Priority]Priority, len(sc.Levels)),
+	}
+	for i, l := range sc.Levels {
+		c.rank[l.ID] = i + 1
+		c.class[l.ID] = l.Class
+	}
+	return c
+}
+
+var (
+	defaultPriorities = compilePriorityScale(DefaultPriorityScale())
+	// currentPriorities -- действующая шкала (nil -- встроенная). Один сервер -- одно пространство,
+	// поэтому шкала общая для пакета, как и Rank у Priority.
+	currentPriorities atomic.Pointer[priorityScale]
+)
+
+func activePriorities() *priorityScale {
+	if sc := currentPriorities.Load(); sc != nil {
+		return sc
+	}
+	return defaultPriorities
+}
+
+// ActivePriorityScale возвращает действующую шкалу приоритетов.
+func ActivePriorityScale() PriorityScale {
+	sc := activePriorities().PriorityScale
+	sc.Levels = slices.Clone(sc.Levels)
+	return sc
+}
+
+// resolve -- см. Priority.Resolve.
+func (sc *priorityScale) resolve(p Priority) Priority {
+	if sc.rank[p] > 0 {
+		return p
+	}
+	if classRanks[p] > 0 {
+		return sc.forClass(p)
+	}
+	return sc.Default
+}
+
+// forClass -- уровень класса c, а если такого класса в шкале нет -- ближайшего к нему.
+// Из равных выбирается старший уровень.
+func (sc *priorityScale) forClass(c Priority) Priority {
+	best, dist := sc.Default, -1
+	for i := len(sc.Levels) - 1; i >= 0; i-- {
+		d := classRanks[sc.Levels[i].Class] - classRanks[c]
+		if d < 0 {
+			d = -d
+		}
+		if dist < 0 || d < dist {
+			best, dist = sc.Levels[i].ID, d
+		}
+	}
+	return best
+}
+
+// classOf -- класс приоритета p в этой шкале ("" -- неизвестный приоритет).
+func (sc *priorityScale) classOf(p Priority) Priority {
+	if c, ok := sc.class[p]; ok {
+		return c
+	}
+	if classRanks[p] > 0 {
+		return p
+	}
+	return ""
+}
+
+// PriorityStore -- опциональная возможность хранилища хранить шкалу приоритетов пространства.
+// GetPriorityScale возвращает ok == false, если шкалу не сохраняли.
+type PriorityStore interface {
+	GetPriorityScale(ctx context.Context) (sc PriorityScale, ok bool, err error)
+	SavePriorityScale(ctx context.Context, sc PriorityScale) error
+}
+
+// LoadPriorityScale включает шкалу приоритетов при запуске: сохранённую в хранилище, а если её
+// не сохраняли -- initial (TASK_PRIORITIES). Задачи с приоритетами вне шкалы (шкалу поменяли
+// в конфигурации) переводятся в её уровни.
+func (s *Service) LoadPriorityScale(ctx context.Context, initial PriorityScale) error {
+	if err := initial.check(); err != nil {
+		return err
+	}
+	sc := initial
+	if ps, ok := s.capabilities().(PriorityStore); ok {
+		stored, found, err := ps.GetPriorityScale(ctx)
+		if err != nil {
+			return err
+		}
+		if found {
+			sc = stored
+		}
+	}
+	if err := sc.check(); err != nil {
+		return err
+	}
+	old := activePriorities()
+	currentPriorities.Store(compilePriorityScale(sc))
+	n, err := s.migratePriorities(ctx, old, nil)
+	if err != nil {
+		return fmt.Errorf("migrate task priorities: %w", err)
+	}
+	if n > 0 {
+		log.Printf("priorities: %d task(s) moved to the levels of the scale %v", n, sc.IDs())
+	}
+	return nil
+}
+
+// SavePriorityScale заменяет шкалу приоритетов и переводит задачи с уровнями, которых в ней больше нет:
+// по remap, иначе -- в уровень того же класса (ближайшего, если такого класса нет).
+func (s *Service) SavePriorityScale(ctx context.Context, userID int, req PriorityScaleRequest, now time.Time) (PriorityScaleResult, error) {
+	if err := ctx.Err(); err != nil {
+		return PriorityScaleResult{}, err
+	}
+	sc := req.PriorityScale
+	if err := sc.check(); err != nil {
+		return PriorityScaleResult{}, err
+	}
+	for from, to := range req.Remap {
+		if !slices.ContainsFunc(sc.Levels, func(l PriorityLevel) bool { return l.ID == to }) {
+			return PriorityScaleResult{}, fmt.Errorf("%w: remap %q -> %q: %q is not a level of the new scale", ErrInvalidPriorityScale, from, to, to)
+		}
+	}
+	ps, ok := s.capabilities().(PriorityStore)
+	if !ok {
+		return PriorityScaleResult{}, ErrPriorityScaleUnsupported
+	}
+	at := now.UTC()
+	sc.UpdatedBy, sc.UpdatedAt = userID, &at
+	if err := ps.SavePriorityScale(ctx, sc); err != nil {
+		return PriorityScaleResult{}, err
+	}
+	old := activePriorities()
+	currentPriorities.Store(compilePriorityScale(sc))
+	n, err := s.migratePriorities(ctx, old, req.Remap)
+	if err != nil {
+		return PriorityScaleResult{}, fmt.Errorf("scale saved, but tasks were not migrated (they are on restart): %w", err)
+	}
+	return PriorityScaleResult{PriorityScale: sc, Migrated: n}, nil
+}
+
+// migratePriorities переводит задачи с приоритетом вне действующей шкалы в её уровни:
+// сначала по remap, затем по классу, который приоритет имел в шкале old, иначе -- в уровень по умолчанию.
+// Изменения атомарны и попадают в ленту изменений, как обычное обновление задачи.
+func (s *Service) migratePriorities(ctx context.Context, old *priorityScale, remap map[Priority]Priority) (int, error) {
+	cur := activePriorities()
+	var moved []Task
+	err := s.WithTx(ctx, func(tx TxStore) error {
+		moved = moved[:0]
+		list, err := tx.GetAll(ctx, 0)
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			if cur.rank[t.Priority] > 0 {
+				continue
+			}
+			to, ok := remap[t.Priority]
+			if !ok {
+				to = cur.Default
+				if c := old.classOf(t.Priority); c != "" {
+					to = cur.forClass(c)
+				}
+			}
+			t.Priority = to
+			if err := tx.Update(ctx, &t, t.UserID); err != nil {
+				return err
+			}
+			moved = append(moved, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range moved {
+		s.publishChange(ctx, ChangeUpdated, t)
+	}
+	return len(moved), nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// priorityScaleFile -- шкала приоритетов JSON-хранилища, рядом с файлом задач.
+type priorityScaleFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data *PriorityScale
+}
+
+func (ts *TaskStore) priorityScalePath() string {
+	return ts.filename + ".priorities.json"
+}
+
+func (ts *TaskStore) loadPriorityScale() error {
+	ts.priorities.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.priorityScalePath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.priorities.err = err
+			return
+		}
+		var sc PriorityScale
+		if err := json.Unmarshal(raw, &sc); err != nil {
+			ts.priorities.err = fmt.Errorf("parse %s: %w", ts.priorityScalePath(), err)
+			return
+		}
+		ts.priorities.data = &sc
+	})
+	return ts.priorities.err
+}
+
+// GetPriorityScale возвращает сохранённую шкалу приоритетов.
+func (ts *TaskStore) GetPriorityScale(ctx context.Context) (PriorityScale, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return PriorityScale{}, false, err
+	}
+	if err := ts.loadPriorityScale(); err != nil {
+		return PriorityScale{}, false, err
+	}
+	ts.priorities.mu.Lock()
+	defer ts.priorities.mu.Unlock()
+
+	if ts.priorities.data == nil {
+		return PriorityScale{}, false, nil
+	}
+	sc := *ts.priorities.data
+	sc.Levels = slices.Clone(sc.Levels)
+	return sc, true, nil
+}
+
+// SavePriorityScale переписывает файл шкалы приоритетов.
+func (ts *TaskStore) SavePriorityScale(ctx context.Context, sc PriorityScale) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadPriorityScale(); err != nil {
+		return err
+	}
+	ts.priorities.mu.Lock()
+	defer ts.priorities.mu.Unlock()
+
+	if ts.filename != "" {
+		raw, err := json.MarshalIndent(sc, "", "   ")
+		if err != nil {
+			return err
+		}
+		tmp := ts.priorityScalePath() + ".tmp"
+		if err := os.WriteFile(tmp, raw, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, ts.priorityScalePath()); err != nil {
+			return err
+		}
+	}
+	ts.priorities.data = &sc
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// GetPriorityScale читает шкалу из priority_scale (migrations/000029_priority_scale.up.sql).
+func (r *PostgresRepository) GetPriorityScale(ctx context.Context) (PriorityScale, bool, error) {
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM priority_scale WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PriorityScale{}, false, nil
+	}
+	if err != nil {
+		return PriorityScale{}, false, err
+	}
+	var sc PriorityScale
+	if err := json.Unmarshal(raw, &sc); err != nil {
+		return PriorityScale{}, false, fmt.Errorf("priority scale: %w", err)
+	}
+	return sc, true, nil
+}
+
+// SavePriorityScale сохраняет шкалу (одна строка с id = 1).
+func (r *PostgresRepository) SavePriorityScale(ctx context.Context, sc PriorityScale) error {
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO priority_scale (id, data) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, raw)
+	return err
+}
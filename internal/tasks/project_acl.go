@@ -0,0 +1,434 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Проект -- это метка задач (как у публичных досок и сводки project.pdf). По умолчанию проект виден
+// всей семье. Закрытый проект виден только своим участникам: задачи с его меткой не попадают
+// остальным ни в список, ни в выборки по фильтрам, отчёты, календарь и ленты изменений, а по ID
+// отвечают 404, как несуществующие. Задача с несколькими закрытыми метками видна тем, кто участник
+// каждого из этих проектов.
+
+var (
+	// ErrProjectACLUnsupported -- хранилище не умеет хранить закрытые проекты.
+	ErrProjectACLUnsupported = errors.New("storage does not support private projects")
+	// ErrProjectNotPrivate -- у проекта нет списка участников: он открыт всем.
+	ErrProjectNotPrivate = errors.New("project is not private")
+	// ErrInvalidProjectACL -- список участников задан неверно.
+	ErrInvalidProjectACL = errors.New("invalid project members")
+	// ErrProjectForbidden -- пользователь не участник закрытого проекта.
+	ErrProjectForbidden = errors.New("not a member of the private project")
+)
+
+// ProjectACL -- закрытый проект: метка и пользователи, которым видны её задачи.
+type ProjectACL struct {
+	Tag       string     `json:"tag"`
+	Members   []int      `json:"members"`
+	UpdatedBy int        `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// ProjectACLRequest -- тело PUT /api/v1/projects/{tag}/acl.
+type ProjectACLRequest struct {
+	Members []int `json:"members" validate:"required,min=1,max=100,dive,min=1"`
+}
+
+// ProjectACLStore -- опциональная возможность хранилища хранить закрытые проекты.
+// SaveProjectACLs заменяет список целиком.
+type ProjectACLStore interface {
+	GetProjectACLs(ctx context.Context) ([]ProjectACL, error)
+	SaveProjectACLs(ctx context.Context, acls []ProjectACL) error
+}
+
+// ScopedReader -- хранилище, которое само применяет закрытые проекты в запросах (PostgreSQL):
+// GetAll(ctx, userID) не возвращает задач чужих закрытых проектов, GetVisibleByID отвечает
+// на них ErrTaskNotFound. Сервис тогда не фильтрует списки в Go: лишние строки не читаются,
+// а ошибка в сервисе не покажет чужую задачу. userID 0 -- без фильтра.
+type ScopedReader interface {
+	GetVisibleByID(ctx context.Context, id, userID int) (*Task, error)
+}
+
+// projectACLs -- действующие закрытые проекты: метка в нижнем регистре -> участники.
+// Метки сравниваются без учёта регистра, как фильтр tag у досок и отчётов.
+type projectACLs map[string][]int
+
+// projectGuard -- закрытые проекты сервиса: снимок для проверок на каждом чтении и
+// блокировка для их изменения.
+type projectGuard struct {
+	mu  sync.Mutex
+	acl atomic.Pointer[projectACLs]
+}
+
+func (g *projectGuard) load() projectACLs {
+	if acl := g.acl.Load(); acl != nil {
+		return *acl
+	}
+	return nil
+}
+
+func (g *projectGuard) store(list []ProjectACL) {
+	acl := make(projectACLs, len(list))
+	for _, p := range list {
+		acl[strings.ToLower(p.Tag)] = p.Members
+	}
+	g.acl.Store(&acl)
+}
+
+// canSee сообщает, видна ли задача пользователю. userID 0 -- сам сервер (уборщик, метрики, миграции):
+// ему видно всё.
+func (acl projectACLs) canSee(userID int, t Task) bool {
+	if userID == 0 || len(acl) == 0 {
+		return true
+	}
+	for _, tag := range t.Tags {
+		if members, ok := acl[strings.ToLower(tag)]; ok && !slices.Contains(members, userID) {
+			return false
+		}
+	}
+	return true
+}
+
+// private сообщает, есть ли у задачи метка закрытого проекта.
+func (acl projectACLs) private(t Task) bool {
+	for _, tag := range t.Tags {
+		if _, ok := acl[strings.ToLower(tag)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// forbiddenTag -- первая закрытая метка из tags, в проекте которой пользователь не участник.
+func (acl projectACLs) forbiddenTag(userID int, tags []string) string {
+	if userID == 0 {
+		return ""
+	}
+	for _, tag := range tags {
+		if members, ok := acl[strings.ToLower(tag)]; ok && !slices.Contains(members, userID) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// scopedReads сообщает, фильтрует ли закрытые проекты само хранилище (см. ScopedReader).
+// Проверяется s.repo, а не capabilities(): резервное хранилище FailoverStore так не умеет.
+func (s *Service) scopedReads() bool {
+	_, ok := s.repo.(ScopedReader)
+	return ok
+}
+
+// visibleTasks оставляет в списке задачи, видимые пользователю (список меняется на месте).
+// Список из хранилища с ScopedReader уже отфильтрован.
+func (s *Service) visibleTasks(userID int, list []Task) []Task {
+	acl := s.projects.load()
+	if userID == 0 || len(acl) == 0 || s.scopedReads() {
+		return list
+	}
+	out := list[:0]
+	for _, t := range list {
+		if acl.canSee(userID, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// canSee сообщает, видна ли задача пользователю (см. projectACLs.canSee).
+func (s *Service) canSee(userID int, t Task) bool {
+	return s.projects.load().canSee(userID, t)
+}
+
+// CheckTaskVisible возвращает ErrTaskNotFound, если задача id -- из чужого закрытого проекта.
+// Без закрытых проектов хранилище не читается.
+func (s *Service) CheckTaskVisible(ctx context.Context, userID, id int) error {
+	if userID == 0 || len(s.projects.load()) == 0 {
+		return nil
+	}
+	_, err := s.GetTaskByID(ctx, id, userID)
+	return err
+}
+
+// hasPrivateProjects сообщает, закрыт ли хоть один проект.
+func (s *Service) hasPrivateProjects() bool {
+	return len(s.projects.load()) > 0
+}
+
+// checkProjectTags запрещает ставить задаче метку закрытого проекта, в котором пользователь не участник:
+// иначе он создал бы задачу, которую сам не видит.
+func (s *Service) checkProjectTags(userID int, tags []string) error {
+	if tag := s.projects.load().forbiddenTag(userID, tags); tag != "" {
+		return fmt.Errorf("%w: %q", ErrProjectForbidden, tag)
+	}
+	return nil
+}
+
+// LoadProjectACLs включает сохранённые закрытые проекты при запуске.
+func (s *Service) LoadProjectACLs(ctx context.Context) error {
+	ps, ok := s.capabilities().(ProjectACLStore)
+	if !ok {
+		return nil
+	}
+	list, err := ps.GetProjectACLs(ctx)
+	if err != nil {
+		return err
+	}
+	s.projects.store(list)
+	return nil
+}
+
+// ProjectACLs -- закрытые проекты, которые видны пользователю: где он участник, а администратору -- все.
+func (s *Service) ProjectACLs(ctx context.Context, userID int, admin bool) ([]ProjectACL, error) {
+	ps, ok := s.capabilities().(ProjectACLStore)
+	if !ok {
+		return nil, ErrProjectACLUnsupported
+	}
+	list, err := ps.GetProjectACLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := []ProjectACL{}
+	for _, p := range list {
+		if admin || slices.Contains(p.Members, userID) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// SetProjectACL закрывает проект tag или меняет его участников. Закрыть проект может администратор
+// пространства, менять участников -- он и сами участники; участник не может убрать из списка себя
+// (для этого есть открытие проекта).
+func (s *Service) SetProjectACL(ctx context.Context, userID int, admin bool, tag string, members []int, now time.Time) (ProjectACL, error) {
+	if err := ctx.Err(); err != nil {
+		return ProjectACL{}, err
+	}
+	tag = NormalizeText(tag)
+	if tag == "" {
+		return ProjectACL{}, fmt.Errorf("%w: empty project tag", ErrInvalidProjectACL)
+	}
+	members = slices.Compact(slices.Sorted(slices.Values(members)))
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return ProjectACL{}, err
+	}
+	for _, id := range members {
+		if !slices.ContainsFunc(users, func(u User) bool { return u.ID == id }) {
+			return ProjectACL{}, fmt.Errorf("%w: user %d does not exist", ErrInvalidProjectACL, id)
+		}
+	}
+
+	acl := ProjectACL{Tag: tag, Members: members, UpdatedBy: userID}
+	at := now.UTC()
+	acl.UpdatedAt = &at
+	err = s.updateProjectACLs(ctx, func(list []ProjectACL) ([]ProjectACL, error) {
+		i := slices.IndexFunc(list, func(p ProjectACL) bool { return strings.EqualFold(p.Tag, tag) })
+		switch {
+		case admin:
+		case i < 0:
+			return nil, fmt.Errorf("%w: only workspace admins can make a project private", ErrProjectForbidden)
+		case !slices.Contains(list[i].Members, userID):
+			return nil, fmt.Errorf("%w: %q", ErrProjectForbidden, list[i].Tag)
+		case !slices.Contains(members, userID):
+			return nil, fmt.Errorf("%w: you cannot remove yourself from the members", ErrInvalidProjectACL)
+		}
+		if i < 0 {
+			return append(list, acl), nil
+		}
+		acl.Tag = list[i].Tag // метка остаётся в том виде, в каком проект закрыли
+		list[i] = acl
+		return list, nil
+	})
+	return acl, err
+}
+
+// DeleteProjectACL открывает проект tag всей семье. Может администратор пространства или участник.
+func (s *Service) DeleteProjectACL(ctx context.Context, userID int, admin bool, tag string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.updateProjectACLs(ctx, func(list []ProjectACL) ([]ProjectACL, error) {
+		i := slices.IndexFunc(list, func(p ProjectACL) bool { return strings.EqualFold(p.Tag, NormalizeText(tag)) })
+		switch {
+		case i < 0:
+			return nil, ErrProjectNotPrivate
+		case !admin && !slices.Contains(list[i].Members, userID):
+			return nil, fmt.Errorf("%w: %q", ErrProjectForbidden, list[i].Tag)
+		}
+		return slices.Delete(list, i, i+1), nil
+	})
+}
+
+// updateProjectACLs читает закрытые проекты, меняет их через fn, сохраняет и сразу включает.
+func (s *Service) updateProjectACLs(ctx context.Context, fn func([]ProjectACL) ([]ProjectACL, error)) error {
+	ps, ok := s.capabilities().(ProjectACLStore)
+	if !ok {
+		return ErrProjectACLUnsupported
+	}
+	s.projects.mu.Lock()
+	defer s.projects.mu.Unlock()
+
+	list, err := ps.GetProjectACLs(ctx)
+	if err != nil {
+		return err
+	}
+	if list, err = fn(list); err != nil {
+		return err
+	}
+	if err := ps.SaveProjectACLs(ctx, list); err != nil {
+		return err
+	}
+	s.projects.store(list)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// projectACLFile -- закрытые проекты JSON-хранилища, рядом с файлом задач.
+type projectACLFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data []ProjectACL
+}
+
+func (ts *TaskStore) projectACLPath() string {
+	return ts.filename + ".projects.json"
+}
+
+func (ts *TaskStore) loadProjectACLs() error {
+	ts.projectACL.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.projectACLPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.projectACL.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.projectACL.data); err != nil {
+			ts.projectACL.err = fmt.Errorf("parse %s: %w", ts.projectACLPath(), err)
+		}
+	})
+	return ts.projectACL.err
+}
+
+// GetProjectACLs возвращает закрытые проекты.
+func (ts *TaskStore) GetProjectACLs(ctx context.Context) ([]ProjectACL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadProjectACLs(); err != nil {
+		return nil, err
+	}
+	ts.projectACL.mu.Lock()
+	defer ts.projectACL.mu.Unlock()
+
+	out := make([]ProjectACL, len(ts.projectACL.data))
+	for i, p := range ts.projectACL.data {
+		p.Members = slices.Clone(p.Members)
+		out[i] = p
+	}
+	return out, nil
+}
+
+// SaveProjectACLs переписывает файл закрытых проектов (права 0600, как у досок и ссылок).
+func (ts *TaskStore) SaveProjectACLs(ctx context.Context, acls []ProjectACL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadProjectACLs(); err != nil {
+		return err
+	}
+	ts.projectACL.mu.Lock()
+	defer ts.projectACL.mu.Unlock()
+
+	if ts.filename != "" {
+		raw, err := json.MarshalIndent(acls, "", "   ")
+		if err != nil {
+			return err
+		}
+		tmp := ts.projectACLPath() + ".tmp"
+		if err := os.WriteFile(tmp, raw, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, ts.projectACLPath()); err != nil {
+			return err
+		}
+	}
+	ts.projectACL.data = slices.Clone(acls)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// hiddenTagsCTE -- метки закрытых проектов, в которых пользователь $1 не участник (в нижнем регистре).
+// Ставится перед taskSelect; для $1 = 0 пуст.
+const hiddenTagsCTE = `
+		WITH hidden_tags AS (
+			SELECT lower(p->>'tag') AS tag
+			FROM project_acls a, jsonb_array_elements(a.data) p
+			WHERE a.id = 1 AND $1::int <> 0 AND NOT p->'members' @> jsonb_build_array($1::int)
+		)`
+
+// taskVisibleSQL -- условие WHERE к taskSelect: ни одна метка задачи не из hidden_tags.
+const taskVisibleSQL = `NOT EXISTS (
+			SELECT 1 FROM unnest(t.tags) AS tag WHERE lower(tag) IN (SELECT tag FROM hidden_tags))`
+
+// GetVisibleByID -- GetByID с фильтром закрытых проектов в запросе: задача чужого проекта не читается.
+func (r *PostgresRepository) GetVisibleByID(ctx context.Context, id, userID int) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.queryTask(ctx, hiddenTagsCTE+taskSelect+`
+		WHERE t.id = $2 AND `+taskVisibleSQL, userID, id)
+}
+
+// GetProjectACLs читает закрытые проекты из project_acls (migrations/000031_project_acls.up.sql).
+func (r *PostgresRepository) GetProjectACLs(ctx context.Context) ([]ProjectACL, error) {
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM project_acls WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []ProjectACL
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("project_acls: %w", err)
+	}
+	return list, nil
+}
+
+// SaveProjectACLs сохраняет закрытые проекты (одна строка с id = 1).
+func (r *PostgresRepository) SaveProjectACLs(ctx context.Context, acls []ProjectACL) error {
+	if acls == nil {
+		acls = []ProjectACL{}
+	}
+	raw, err := json.Marshal(acls)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO project_acls (id, data) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, raw)
+	return err
+}
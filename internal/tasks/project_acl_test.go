@@ -0,0 +1,101 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// TestPrivateProjectSharesAndSubtasks -- ссылки и подзадачи задачи закрытого проекта недоступны
+// не участнику: для него задачи нет (404), и ни ссылки, ни подзадача не меняются.
+func TestPrivateProjectSharesAndSubtasks(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svc *tasks.Service
+	srv := taskstest.NewServer(store, "", func(h *tasks.Handler, s *tasks.Service) {
+		h.SetWorkspace("default", []int{1})
+		svc = s
+	})
+	tokens := map[int]string{}
+	for _, uid := range []int{1, 2, 3} {
+		if tokens[uid], err = srv.Token(uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	do := func(uid int, method, path string, body, out any) int {
+		t.Helper()
+		code, err := srv.DoJSON(ctx, method, path, tokens[uid], body, out)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return code
+	}
+
+	// Задача 2 (метка bills) -- в закрытом проекте, участник только dad.
+	if code := do(1, http.MethodPut, "/api/v1/projects/bills/acl", map[string]any{"members": []int{2}}, nil); code != http.StatusOK {
+		t.Fatalf("set project ACL: %d", code)
+	}
+	var share tasks.SharedTask
+	if code := do(2, http.MethodPost, "/api/v1/tasks/2/shares", map[string]any{}, &share); code != http.StatusCreated {
+		t.Fatalf("member creates share: %d", code)
+	}
+	var sub tasks.SubTask
+	if code := do(2, http.MethodPost, "/api/v1/tasks/2/subtasks", map[string]any{"title": "Квитанция"}, &sub); code != http.StatusCreated {
+		t.Fatalf("member creates subtask: %d", code)
+	}
+
+	hidden := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, "/api/v1/tasks/2/shares", map[string]any{}},
+		{http.MethodGet, "/api/v1/tasks/2/shares", nil},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/tasks/2/shares/%d", share.ID), nil},
+		{http.MethodPut, fmt.Sprintf("/api/v1/tasks/subtasks/%d", sub.ID), map[string]any{"done": true}},
+	}
+	for _, c := range hidden {
+		if code := do(3, c.method, c.path, c.body, nil); code != http.StatusNotFound {
+			t.Errorf("non-member %s %s: %d, want 404", c.method, c.path, code)
+		}
+	}
+
+	// Сервис проверяет видимость сам, а не полагается на обработчики.
+	if _, _, err := svc.ShareTask(ctx, 2, 3, tasks.CreateShareRequest{}, time.Now()); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Errorf("ShareTask by non-member: %v, want ErrTaskNotFound", err)
+	}
+	if _, err := svc.TaskShares(ctx, 2, 3); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Errorf("TaskShares by non-member: %v, want ErrTaskNotFound", err)
+	}
+	if err := svc.RevokeShare(ctx, 2, 3, share.ID); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Errorf("RevokeShare by non-member: %v, want ErrTaskNotFound", err)
+	}
+
+	var shares []tasks.Share
+	if code := do(2, http.MethodGet, "/api/v1/tasks/2/shares", nil, &shares); code != http.StatusOK || len(shares) != 1 {
+		t.Errorf("member lists shares: %d, %d links, want 1", code, len(shares))
+	}
+	task, err := store.GetByID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.SubTasks) != 1 || task.SubTasks[0].Done {
+		t.Errorf("subtask changed by non-member: %+v", task.SubTasks)
+	}
+
+	// Участник по-прежнему отмечает подзадачу и отзывает ссылку.
+	if code := do(2, http.MethodPut, fmt.Sprintf("/api/v1/tasks/subtasks/%d", sub.ID), map[string]any{"done": true}, nil); code != http.StatusNoContent {
+		t.Errorf("member updates subtask: %d", code)
+	}
+	if code := do(2, http.MethodDelete, fmt.Sprintf("/api/v1/tasks/2/shares/%d", share.ID), nil, nil); code != http.StatusNoContent {
+		t.Errorf("member revokes share: %d", code)
+	}
+}
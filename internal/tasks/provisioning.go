@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrProvisioningUnsupported -- хранилище не умеет менять и удалять пользователей (JSON-файл).
+	ErrProvisioningUnsupported = errors.New("storage does not support user provisioning")
+	// ErrUserDisabled -- пользователь заблокирован каталогом (SCIM active=false).
+	ErrUserDisabled = errors.New("user is disabled")
+	// ErrInvalidUsername -- логин пустой или длиннее колонки users.username.
+	ErrInvalidUsername = errors.New("username must be 2 to 50 characters")
+	// ErrInvalidPassword -- пароль не прошёл политику паролей (подробности -- в PasswordPolicyError).
+	ErrInvalidPassword = errors.New("password does not meet the password policy")
+)
+
+// userStatusTTL -- как часто перечитывается список заблокированных пользователей. Изменения,
+// сделанные этим экземпляром, видны сразу, сделанные соседними -- не позже чем через TTL.
+const userStatusTTL = 30 * time.Second
+
+// UserDirectory -- опциональная возможность хранилища читать пользователя по ID, менять
+// и удалять его. Нужна провижинингу через SCIM; реализуется MemoryStore и PostgresRepository
+// (файловое хранилище пользователей не хранит вовсе).
+type UserDirectory interface {
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	// UpdateUser перезаписывает все поля пользователя с ID user.ID, включая хэш пароля.
+	UpdateUser(ctx context.Context, user *User) error
+	DeleteUser(ctx context.Context, id int) error
+}
+
+// userStatus -- кэш состояния пользователей для проверки токенов на каждом запросе (см. UserActive).
+type userStatus struct {
+	mu       sync.Mutex
+	disabled map[int]bool // ID -> заблокирован или удалён
+	loadedAt time.Time
+}
+
+// directory возвращает UserDirectory, если хранилище его поддерживает.
+func (s *Service) directory() (UserDirectory, error) {
+	d, ok := s.capabilities().(UserDirectory)
+	if !ok {
+		return nil, ErrProvisioningUnsupported
+	}
+	return d, nil
+}
+
+// SupportsProvisioning сообщает, умеет ли хранилище менять и удалять пользователей.
+func (s *Service) SupportsProvisioning() bool {
+	_, err := s.directory()
+	return err == nil
+}
+
+// UserByID возвращает пользователя по ID.
+func (s *Service) UserByID(ctx context.Context, id int) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return nil, err
+	}
+	return d.GetUserByID(ctx, id)
+}
+
+// ProvisionUser создаёт пользователя по команде каталога (без инвайт-кода).
+// Пустой пароль -- войти по паролю нельзя, пока каталог его не задаст.
+func (s *Service) ProvisionUser(ctx context.Context, u *User, password string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.directory(); err != nil {
+		return err
+	}
+	if err := s.validateProvisioned(u, password); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetUserByUsername(ctx, u.Username); err == nil {
+		return ErrUserAlreadyExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	if err := s.setPassword(u, password); err != nil {
+		return err
+	}
+	if err := s.repo.CreateUser(ctx, u); err != nil {
+		return err
+	}
+	s.invalidateUserStatus()
+	return nil
+}
+
+// UpdateProvisionedUser заменяет поля пользователя. Пустой пароль оставляет прежний.
+func (s *Service) UpdateProvisionedUser(ctx context.Context, u *User, password string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	if err := s.validateProvisioned(u, password); err != nil {
+		return err
+	}
+	current, err := d.GetUserByID(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if u.Username != current.Username {
+		if _, err := s.repo.GetUserByUsername(ctx, u.Username); err == nil {
+			return ErrUserAlreadyExists
+		} else if !errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+	}
+	u.PasswordHash = current.PasswordHash
+	if err := s.setPassword(u, password); err != nil {
+		return err
+	}
+	if err := d.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	s.invalidateUserStatus()
+	return nil
+}
+
+// DeleteUser удаляет пользователя. В PostgreSQL вместе с ним каскадом удаляются его задачи,
+// поэтому каталогам лучше сначала блокировать пользователя (active=false), а удалять -- потом.
+func (s *Service) DeleteUser(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := s.directory()
+	if err != nil {
+		return err
+	}
+	if err := d.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateUserStatus()
+	return nil
+}
+
+// UserActive сообщает, можно ли пускать пользователя с уже выданным токеном: он не заблокирован
+// и не удалён. Хранилища без UserDirectory пользователей не блокируют -- там всегда true.
+// Ошибка чтения списка не запирает всех: остаётся прежнее состояние.
+func (s *Service) UserActive(ctx context.Context, id int) bool {
+	d, err := s.directory()
+	if err != nil {
+		return true
+	}
+	st := &s.userStatus
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if time.Since(st.loadedAt) >= userStatusTTL {
+		users, err := s.repo.GetAllUsers(ctx)
+		if err != nil {
+			log.Printf("WARNING: user status refresh failed: %v", err)
+		} else {
+			st.disabled = make(map[int]bool, len(users))
+			for _, u := range users {
+				st.disabled[u.ID] = u.Disabled
+			}
+		}
+		st.loadedAt = time.Now()
+	}
+	disabled, known := st.disabled[id]
+	if !known {
+		// Создан после загрузки списка или уже удалён -- спрашиваем хранилище про него одного.
+		u, err := d.GetUserByID(ctx, id)
+		switch {
+		case err == nil:
+			disabled = u.Disabled
+		case errors.Is(err, ErrUserNotFound):
+			disabled = true
+		default:
+			log.Printf("WARNING: user status lookup failed: %v", err)
+			return true
+		}
+		if st.disabled == nil {
+			st.disabled = make(map[int]bool)
+		}
+		st.disabled[id] = disabled
+	}
+	return !disabled
+}
+
+// invalidateUserStatus заставляет следующую проверку UserActive перечитать пользователей.
+func (s *Service) invalidateUserStatus() {
+	s.userStatus.mu.Lock()
+	s.userStatus.loadedAt = time.Time{}
+	s.userStatus.mu.Unlock()
+}
+
+func (s *Service) validateProvisioned(u *User, password string) error {
+	if n := utf8.RuneCountInString(u.Username); n < 2 || n > 50 {
+		return ErrInvalidUsername
+	}
+	if password != "" {
+		return s.passwordPolicy.Check(u.Username, password)
+	}
+	return nil
+}
+
+// setPassword хэширует новый пароль; пустой оставляет хэш как есть.
+func (s *Service) setPassword(u *User, password string) error {
+	if password == "" {
+		return nil
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
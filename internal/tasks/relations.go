@@ -0,0 +1,422 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RelationType -- тип связи между задачами.
+type RelationType string
+
+const (
+	RelationBlocks     RelationType = "blocks"     // from блокирует to: to нельзя закончить раньше from
+	RelationRelatesTo  RelationType = "relates_to" // просто связаны; связь симметричная
+	RelationDuplicates RelationType = "duplicates" // from -- дубль to
+	RelationCausedBy   RelationType = "caused_by"  // from появилась из-за to
+)
+
+// Symmetric -- связь без направления: A relates_to B то же самое, что B relates_to A.
+func (t RelationType) Symmetric() bool {
+	return t == RelationRelatesTo
+}
+
+var (
+	// ErrRelationsUnsupported -- хранилище не умеет хранить связи.
+	ErrRelationsUnsupported = errors.New("task relations are not supported by this storage")
+	// ErrRelationExists -- такая связь уже есть.
+	ErrRelationExists = errors.New("relation already exists")
+	// ErrRelationSelf -- задачу связывают саму с собой.
+	ErrRelationSelf = errors.New("task cannot be related to itself")
+	// ErrRelationNotFound -- связи с таким ID нет.
+	ErrRelationNotFound = errors.New("relation not found")
+)
+
+// Relation -- связь from --type--> to.
+type Relation struct {
+	ID        int          `json:"id"`
+	FromID    int          `json:"from_id"`
+	ToID      int          `json:"to_id"`
+	Type      RelationType `json:"type"`
+	CreatedBy int          `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// CreateRelationRequest -- тело POST /api/v1/tasks/{id}/relations: связь {id} --type--> task_id.
+type CreateRelationRequest struct {
+	Type   RelationType `json:"type" validate:"required,oneof=blocks relates_to duplicates caused_by"`
+	TaskID int          `json:"task_id" validate:"required,gt=0"`
+}
+
+// RelationStore -- опциональная возможность хранилища хранить связи задач.
+// Связи с удалёнными задачами хранилище не отдаёт.
+type RelationStore interface {
+	CreateRelation(ctx context.Context, rel *Relation) error
+	DeleteRelation(ctx context.Context, id int) error
+	// Relations возвращает связи задачи taskID (в обе стороны); taskID == 0 -- все связи.
+	Relations(ctx context.Context, taskID int) ([]Relation, error)
+}
+
+func (s *Service) relations() (RelationStore, error) {
+	rs, ok := s.capabilities().(RelationStore)
+	if !ok {
+		return nil, ErrRelationsUnsupported
+	}
+	return rs, nil
+}
+
+// CreateRelation связывает две существующие задачи, видимые автору связи (CreatedBy). Повтор той же связи (для симметричной --
+// и в обратную сторону) возвращает ErrRelationExists.
+func (s *Service) CreateRelation(ctx context.Context, rel *Relation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rs, err := s.relations()
+	if err != nil {
+		return err
+	}
+	if rel.FromID == rel.ToID {
+		return ErrRelationSelf
+	}
+	for _, id := range []int{rel.FromID, rel.ToID} {
+		if _, err := s.GetTaskByID(ctx, id, rel.CreatedBy); err != nil {
+			return fmt.Errorf("task %d: %w", id, err)
+		}
+	}
+
+	existing, err := rs.Relations(ctx, rel.FromID)
+	if err != nil {
+		return err
+	}
+	for _, e := range existing {
+		if e.Type == rel.Type && (e.FromID == rel.FromID && e.ToID == rel.ToID ||
+			rel.Type.Symmetric() && e.FromID == rel.ToID && e.ToID == rel.FromID) {
+			return ErrRelationExists
+		}
+	}
+	return rs.CreateRelation(ctx, rel)
+}
+
+// DeleteRelation удаляет связь по ID.
+func (s *Service) DeleteRelation(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rs, err := s.relations()
+	if err != nil {
+		return err
+	}
+	return rs.DeleteRelation(ctx, id)
+}
+
+// TaskRelations возвращает связи задачи (taskID == 0 -- все связи), видимые пользователю userID:
+// связь с задачей чужого закрытого проекта выдала бы её ID. userID 0 -- все связи.
+func (s *Service) TaskRelations(ctx context.Context, taskID, userID int) ([]Relation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rs, err := s.relations()
+	if err != nil {
+		return nil, err
+	}
+	rels, err := rs.Relations(ctx, taskID)
+	if err != nil || userID == 0 || !s.hasPrivateProjects() {
+		return rels, err
+	}
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[int]bool, len(list))
+	for _, t := range list {
+		visible[t.ID] = true
+	}
+	out := rels[:0]
+	for _, r := range rels {
+		if visible[r.FromID] && visible[r.ToID] {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// GraphNode -- задача в графе связей.
+type GraphNode struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Done   bool   `json:"done"`
+}
+
+// GraphEdge -- связь в графе.
+type GraphEdge struct {
+	From int          `json:"from"`
+	To   int          `json:"to"`
+	Type RelationType `json:"type"`
+}
+
+// Graph -- граф связей задач для инструментов визуализации.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// RelationGraph собирает граф связей. В узлы попадают задачи, у которых есть связи,
+// а при all == true -- все задачи.
+func (s *Service) RelationGraph(ctx context.Context, userID int, all bool) (Graph, error) {
+	rels, err := s.TaskRelations(ctx, 0, 0) // связи фильтруются ниже по уже прочитанному списку
+	if err != nil {
+		return Graph{}, err
+	}
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return Graph{}, err
+	}
+	visible := make(map[int]bool, len(list))
+	for _, t := range list {
+		visible[t.ID] = true
+	}
+
+	g := Graph{Nodes: []GraphNode{}, Edges: make([]GraphEdge, 0, len(rels))}
+	linked := make(map[int]bool)
+	for _, r := range rels {
+		if !visible[r.FromID] || !visible[r.ToID] {
+			continue // связь с задачей чужого закрытого проекта выдала бы её ID
+		}
+		g.Edges = append(g.Edges, GraphEdge{From: r.FromID, To: r.ToID, Type: r.Type})
+		linked[r.FromID], linked[r.ToID] = true, true
+	}
+	sortTasksByID(list)
+	for _, t := range list {
+		if all || linked[t.ID] {
+			g.Nodes = append(g.Nodes, GraphNode{ID: t.ID, Title: t.Title, Status: t.Status, Done: t.Done})
+		}
+	}
+	return g, nil
+}
+
+// DOT выводит граф в формате Graphviz. Симметричные связи рисуются без стрелок,
+// выполненные задачи -- серым.
+func (g Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := ""
+		if n.Done {
+			attrs = ", color=gray, fontcolor=gray"
+		}
+		fmt.Fprintf(&b, "\t%d [label=%s%s];\n", n.ID, dotQuote(fmt.Sprintf("#%d %s", n.ID, n.Title)), attrs)
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		if e.Type.Symmetric() {
+			attrs = ", dir=none"
+		}
+		fmt.Fprintf(&b, "\t%d -> %d [label=%s%s];\n", e.From, e.To, dotQuote(string(e.Type)), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote -- строка DOT в кавычках (экранируются кавычки, обратные слэши и переводы строк).
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// relationsFile -- связи JSON-хранилища. Лежат рядом с файлом задач, как и настройки пользователей.
+type relationsFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []Relation
+	lastID int
+}
+
+func (ts *TaskStore) relationsPath() string {
+	return ts.filename + ".relations.json"
+}
+
+func (ts *TaskStore) loadRelations() error {
+	ts.relations.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.relationsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.relations.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.relations.data); err != nil {
+			ts.relations.err = fmt.Errorf("parse %s: %w", ts.relationsPath(), err)
+			return
+		}
+		for _, r := range ts.relations.data {
+			ts.relations.lastID = max(ts.relations.lastID, r.ID)
+		}
+	})
+	return ts.relations.err
+}
+
+// saveRelations переписывает файл связей целиком. Вызывающий держит relations.mu.
+func (ts *TaskStore) saveRelations() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.relations.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.relationsPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.relationsPath())
+}
+
+// CreateRelation сохраняет связь и выдаёт ей ID.
+func (ts *TaskStore) CreateRelation(ctx context.Context, rel *Relation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadRelations(); err != nil {
+		return err
+	}
+	ts.relations.mu.Lock()
+	defer ts.relations.mu.Unlock()
+
+	if rel.CreatedAt.IsZero() {
+		rel.CreatedAt = time.Now().UTC()
+	}
+	rel.ID = ts.relations.lastID + 1
+	ts.relations.data = append(ts.relations.data, *rel)
+	if err := ts.saveRelations(); err != nil {
+		ts.relations.data = ts.relations.data[:len(ts.relations.data)-1]
+		return err
+	}
+	ts.relations.lastID = rel.ID
+	return nil
+}
+
+// DeleteRelation удаляет связь по ID.
+func (ts *TaskStore) DeleteRelation(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadRelations(); err != nil {
+		return err
+	}
+	ts.relations.mu.Lock()
+	defer ts.relations.mu.Unlock()
+
+	for i, r := range ts.relations.data {
+		if r.ID != id {
+			continue
+		}
+		prev := ts.relations.data
+		ts.relations.data = append(prev[:i:i], prev[i+1:]...)
+		if err := ts.saveRelations(); err != nil {
+			ts.relations.data = prev
+			return err
+		}
+		return nil
+	}
+	return ErrRelationNotFound
+}
+
+// Relations отдаёт связи задачи. Связи с удалёнными (в том числе слитыми) задачами пропускаются.
+func (ts *TaskStore) Relations(ctx context.Context, taskID int) ([]Relation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadRelations(); err != nil {
+		return nil, err
+	}
+	ts.relations.mu.Lock()
+	defer ts.relations.mu.Unlock()
+
+	out := []Relation{}
+	for _, r := range ts.relations.data {
+		if taskID != 0 && r.FromID != taskID && r.ToID != taskID {
+			continue
+		}
+		if ts.published(r.FromID) == nil || ts.published(r.ToID) == nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// CreateRelation сохраняет связь в task_relations (migrations/000010_task_relations.up.sql).
+func (r *PostgresRepository) CreateRelation(ctx context.Context, rel *Relation) error {
+	err := r.q.QueryRowContext(ctx, `INSERT INTO task_relations (from_id, to_id, type, created_by)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		rel.FromID, rel.ToID, rel.Type, rel.CreatedBy).Scan(&rel.ID, &rel.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrRelationExists
+	}
+	return err
+}
+
+// DeleteRelation удаляет связь по ID.
+func (r *PostgresRepository) DeleteRelation(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM task_relations WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrRelationNotFound
+	}
+	return err
+}
+
+// Relations читает связи задачи (taskID == 0 -- все). Связи удалённых задач удаляются каскадом.
+func (r *PostgresRepository) Relations(ctx context.Context, taskID int) ([]Relation, error) {
+	query := "SELECT id, from_id, to_id, type, created_by, created_at FROM task_relations"
+	var args []any
+	if taskID != 0 {
+		query += " WHERE from_id = $1 OR to_id = $1"
+		args = append(args, taskID)
+	}
+	rows, err := r.q.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Relation{}
+	for rows.Next() {
+		var rel Relation
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&rel.ID, &rel.FromID, &rel.ToID, &rel.Type, &createdBy, &rel.CreatedAt); err != nil {
+			return nil, err
+		}
+		rel.CreatedBy = int(createdBy.Int64)
+		out = append(out, rel)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,778 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"task-manager/internal/httpclient"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Виды и форматы отчётов по расписанию.
+const (
+	ReportCompleted = "completed" // выполненные за последние PeriodDays дней
+	ReportOverdue   = "overdue"   // открытые с прошедшим сроком на момент запуска
+
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+
+	defaultReportPeriodDays = 7
+)
+
+var (
+	// ErrReportsUnsupported -- хранилище не умеет хранить расписания отчётов.
+	ErrReportsUnsupported = errors.New("report schedules are not supported by this storage")
+	// ErrReportNotFound -- расписания отчёта нет.
+	ErrReportNotFound = errors.New("report schedule not found")
+	// ErrReportNoDelivery -- не указано, куда доставлять отчёт.
+	ErrReportNoDelivery = errors.New("report needs a webhook or at least one email address")
+	// ErrReportTemplate -- шаблон темы или текста письма не разобран.
+	ErrReportTemplate = errors.New("invalid report template")
+)
+
+// Шаблоны письма по умолчанию (templates/*.tmpl, встроены в бинарник). Данные шаблона -- ReportData.
+var (
+	//go:embed templates/report_subject.tmpl
+	defaultReportSubject string
+	//go:embed templates/report_body.tmpl
+	defaultReportBody string
+)
+
+// reportClient -- HTTP-клиент для доставки отчётов на webhook.
+// Адрес задаёт пользователь, как у webhook автоматизаций: только публичные адреса.
+var reportClient = httpclient.New(httpclient.Options{Name: "report", Timeout: 30 * time.Second, Retries: 2, PublicOnly: true})
+
+// ReportSchedule -- отчёт по расписанию: по cron-выражению сервер собирает выгрузку задач
+// (CSV или JSON) и отправляет её на webhook и/или на почту. Настраивается администратором,
+// один набор на сервер.
+type ReportSchedule struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	Format     string     `json:"format"`
+	Cron       string     `json:"cron"`
+	Timezone   string     `json:"timezone"`
+	Enabled    bool       `json:"enabled"`
+	PeriodDays int        `json:"period_days,omitempty"`
+	Tag        string     `json:"tag,omitempty"`
+	Assignee   int        `json:"assignee,omitempty"`
+	Webhook    string     `json:"webhook,omitempty"`
+	Email      []string   `json:"email,omitempty"`
+	Subject    string     `json:"subject,omitempty"`
+	Body       string     `json:"body,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"` // nil -- выключено
+	LastError  string     `json:"last_error,omitempty"`  // ошибка последней доставки; пусто -- успешно
+}
+
+// ReportScheduleRequest -- тело POST /api/v1/admin/reports и PUT /api/v1/admin/reports/{report_id}.
+type ReportScheduleRequest struct {
+	Name       string   `json:"name" validate:"required,max=100"`
+	Kind       string   `json:"kind" validate:"required,oneof=completed overdue"`
+	Format     string   `json:"format" validate:"omitempty,oneof=csv json"` // по умолчанию csv
+	Cron       string   `json:"cron" validate:"required,max=100"`
+	Timezone   string   `json:"timezone" validate:"omitempty,timezone"` // пусто -- пояс рабочего календаря
+	Enabled    *bool    `json:"enabled"`                                // по умолчанию true
+	PeriodDays int      `json:"period_days" validate:"min=0,max=366"`   // для completed; 0 -- 7 дней
+	Tag        string   `json:"tag" validate:"tag"`                     // только задачи с меткой
+	Assignee   int      `json:"assignee" validate:"min=0"`              // только задачи исполнителя
+	Webhook    string   `json:"webhook" validate:"omitempty,url,startswith=http,max=2000"`
+	Email      []string `json:"email" validate:"max=20,dive,email"`
+	Subject    string   `json:"subject" validate:"max=200"` // шаблон text/template; пусто -- по умолчанию
+	Body       string   `json:"body" validate:"max=10000"`  // шаблон text/template; пусто -- по умолчанию
+}
+
+// ReportData -- содержимое отчёта и данные для шаблонов темы и текста письма.
+type ReportData struct {
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	From        time.Time `json:"from"` // completed: начало окна; overdue: совпадает с To
+	To          time.Time `json:"to"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Count       int       `json:"count"`
+	Tasks       []Task    `json:"tasks"`
+}
+
+// ReportStore -- опциональная возможность хранилища хранить расписания отчётов.
+type ReportStore interface {
+	CreateReportSchedule(ctx context.Context, rs *ReportSchedule) error
+	UpdateReportSchedule(ctx context.Context, rs *ReportSchedule) error
+	DeleteReportSchedule(ctx context.Context, id int) error
+	ReportSchedules(ctx context.Context) ([]ReportSchedule, error)
+	// SetReportRun запоминает последний запуск, следующий запуск и ошибку доставки.
+	SetReportRun(ctx context.Context, id int, last, next *time.Time, lastErr string) error
+}
+
+func (s *Service) reports() (ReportStore, error) {
+	rs, ok := s.capabilities().(ReportStore)
+	if !ok {
+		return nil, ErrReportsUnsupported
+	}
+	return rs, nil
+}
+
+// applyReportRequest переносит запрос в расписание отчёта и пересчитывает следующий запуск от now.
+func (s *Service) applyReportRequest(ctx context.Context, rs *ReportSchedule, req ReportScheduleRequest, now time.Time) error {
+	if req.Webhook == "" && len(req.Email) == 0 {
+		return ErrReportNoDelivery
+	}
+	for _, tpl := range []string{req.Subject, req.Body} {
+		if _, err := template.New("report").Parse(tpl); err != nil {
+			return fmt.Errorf("%w: %v", ErrReportTemplate, err)
+		}
+	}
+	spec, err := parseCron(req.Cron)
+	if err != nil {
+		return err
+	}
+	tz := req.Timezone
+	if tz == "" {
+		cal, err := s.Calendar(ctx)
+		if err != nil {
+			return err
+		}
+		tz = cal.Location().String()
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return err
+	}
+	next := spec.next(now.In(loc))
+	if next.IsZero() {
+		return ErrScheduleNever
+	}
+
+	rs.Name, rs.Kind, rs.Format, rs.Cron, rs.Timezone = req.Name, req.Kind, req.Format, req.Cron, tz
+	if rs.Format == "" {
+		rs.Format = ReportFormatCSV
+	}
+	rs.PeriodDays, rs.Tag, rs.Assignee = req.PeriodDays, req.Tag, req.Assignee
+	rs.Webhook, rs.Email, rs.Subject, rs.Body = req.Webhook, slices.Clone(req.Email), req.Subject, req.Body
+	rs.Enabled = req.Enabled == nil || *req.Enabled
+	rs.NextRunAt = nil
+	if rs.Enabled {
+		next = next.UTC()
+		rs.NextRunAt = &next
+	}
+	rs.UpdatedAt = now.UTC()
+	return nil
+}
+
+// CreateReportSchedule создаёт расписание отчёта.
+func (s *Service) CreateReportSchedule(ctx context.Context, req ReportScheduleRequest, now time.Time) (ReportSchedule, error) {
+	if err := ctx.Err(); err != nil {
+		return ReportSchedule{}, err
+	}
+	store, err := s.reports()
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	rs := ReportSchedule{CreatedAt: now.UTC()}
+	if err := s.applyReportRequest(ctx, &rs, req, now); err != nil {
+		return ReportSchedule{}, err
+	}
+	if err := store.CreateReportSchedule(ctx, &rs); err != nil {
+		return ReportSchedule{}, err
+	}
+	return rs, nil
+}
+
+// ReportSchedules возвращает все расписания отчётов.
+func (s *Service) ReportSchedules(ctx context.Context) ([]ReportSchedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store, err := s.reports()
+	if err != nil {
+		return nil, err
+	}
+	return store.ReportSchedules(ctx)
+}
+
+// GetReportSchedule возвращает расписание отчёта по ID.
+func (s *Service) GetReportSchedule(ctx context.Context, id int) (ReportSchedule, error) {
+	list, err := s.ReportSchedules(ctx)
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	for _, rs := range list {
+		if rs.ID == id {
+			return rs, nil
+		}
+	}
+	return ReportSchedule{}, ErrReportNotFound
+}
+
+// UpdateReportSchedule заменяет расписание отчёта целиком; следующий запуск считается от now.
+func (s *Service) UpdateReportSchedule(ctx context.Context, id int, req ReportScheduleRequest, now time.Time) (ReportSchedule, error) {
+	rs, err := s.GetReportSchedule(ctx, id)
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	store, err := s.reports()
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	if err := s.applyReportRequest(ctx, &rs, req, now); err != nil {
+		return ReportSchedule{}, err
+	}
+	if err := store.UpdateReportSchedule(ctx, &rs); err != nil {
+		return ReportSchedule{}, err
+	}
+	return rs, nil
+}
+
+// DeleteReportSchedule удаляет расписание отчёта.
+func (s *Service) DeleteReportSchedule(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store, err := s.reports()
+	if err != nil {
+		return err
+	}
+	return store.DeleteReportSchedule(ctx, id)
+}
+
+// BuildReport собирает отчёт на момент now. Задач закрытых проектов в отчёте нет:
+// он уходит на произвольные адреса и webhook, а не участникам проекта.
+func (s *Service) BuildReport(ctx context.Context, rs ReportSchedule, now time.Time) (ReportData, error) {
+	list, err := s.repo.GetAll(ctx, 0)
+	if err != nil {
+		return ReportData{}, err
+	}
+	acl := s.projects.load()
+	loc, err := time.LoadLocation(rs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	d := ReportData{Name: rs.Name, Kind: rs.Kind, To: now.In(loc), GeneratedAt: now.UTC(), Tasks: []Task{}}
+	d.From = d.To
+	if rs.Kind == ReportCompleted {
+		days := rs.PeriodDays
+		if days == 0 {
+			days = defaultReportPeriodDays
+		}
+		d.From = d.To.AddDate(0, 0, -days)
+	}
+
+	for _, t := range list {
+		if (rs.Tag != "" && !hasTag(t.Tags, rs.Tag)) || (rs.Assignee > 0 && t.AssignedTo != rs.Assignee) || acl.private(t) {
+			continue
+		}
+		switch rs.Kind {
+		case ReportCompleted:
+			if t.Done && t.CompletedAt != nil && !t.CompletedAt.Before(d.From) && !t.CompletedAt.After(now) {
+				d.Tasks = append(d.Tasks, t)
+			}
+		case ReportOverdue:
+			if !t.Done && t.Due != nil && t.Due.Before(now) {
+				d.Tasks = append(d.Tasks, t)
+			}
+		}
+	}
+	if rs.Kind == ReportCompleted {
+		slices.SortFunc(d.Tasks, func(a, b Task) int { return a.CompletedAt.Compare(*b.CompletedAt) })
+	} else {
+		_ = SortTasks(d.Tasks, SortByDue)
+	}
+	d.Count = len(d.Tasks)
+	return d, nil
+}
+
+// reportCSVHeader -- колонки CSV-выгрузки.
+var reportCSVHeader = []string{"id", "title", "status", "priority", "assigned_to", "tags", "due", "completed_at", "estimate_minutes"}
+
+// RenderReport превращает отчёт во вложение нужного формата.
+func RenderReport(d ReportData, format string) (*Attachment, error) {
+	name := fmt.Sprintf("%s-%s", d.Kind, d.To.Format("2006-01-02"))
+	if format == ReportFormatJSON {
+		raw, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return &Attachment{Name: name + ".json", ContentType: "application/json", Data: raw}, nil
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write(reportCSVHeader)
+	for _, t := range d.Tasks {
+		_ = w.Write([]string{
+			strconv.Itoa(t.ID), csvSafe(t.Title), t.Status, string(t.Priority), strconv.Itoa(t.AssignedTo),
+			csvSafe(strings.Join(t.Tags, ", ")), formatReportTime(t.Due), formatReportTime(t.CompletedAt),
+			strconv.Itoa(t.EstimateMinutes),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return &Attachment{Name: name + ".csv", ContentType: "text/csv; charset=utf-8", Data: b.Bytes()}, nil
+}
+
+// csvSafe экранирует значения, которые табличные редакторы приняли бы за формулу.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatReportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// renderReportText заполняет шаблон (или шаблон по умолчанию, если tpl пустой).
+func renderReportText(tpl, def string, d ReportData) (string, error) {
+	if tpl == "" {
+		tpl = def
+	}
+	t, err := template.New("report").Parse(tpl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrReportTemplate, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrReportTemplate, err)
+	}
+	return b.String(), nil
+}
+
+// ReportPreview -- что уйдёт получателям: тема, текст и вложение.
+type ReportPreview struct {
+	Subject    string
+	Body       string
+	Attachment *Attachment
+	Count      int
+}
+
+// PreviewReport собирает отчёт и письмо, ничего не отправляя.
+func (s *Service) PreviewReport(ctx context.Context, rs ReportSchedule, now time.Time) (ReportPreview, error) {
+	d, err := s.BuildReport(ctx, rs, now)
+	if err != nil {
+		return ReportPreview{}, err
+	}
+	p := ReportPreview{Count: d.Count}
+	if p.Subject, err = renderReportText(rs.Subject, defaultReportSubject, d); err != nil {
+		return ReportPreview{}, err
+	}
+	if p.Body, err = renderReportText(rs.Body, defaultReportBody, d); err != nil {
+		return ReportPreview{}, err
+	}
+	if p.Attachment, err = RenderReport(d, rs.Format); err != nil {
+		return ReportPreview{}, err
+	}
+	return p, nil
+}
+
+// deliverReport собирает отчёт и отправляет его на webhook и на почту.
+// Ошибка одного канала не мешает другому; возвращаются ошибки всех неудавшихся.
+func (s *Service) deliverReport(ctx context.Context, rs ReportSchedule, now time.Time) error {
+	p, err := s.PreviewReport(ctx, rs, now)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if rs.Webhook != "" {
+		if err := postReport(ctx, rs, p); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if len(rs.Email) > 0 {
+		err := ErrMailerNotConfigured
+		if s.mailer != nil {
+			err = s.mailer.Send(ctx, rs.Email, p.Subject, p.Body, p.Attachment)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	log.Printf("reports: report=%d %q delivered tasks=%d", rs.ID, rs.Name, p.Count)
+	return nil
+}
+
+// postReport отправляет выгрузку на webhook: тело -- сам файл, тема -- в заголовке X-Report-Subject
+// (RFC 2047, как в письме).
+func postReport(ctx context.Context, rs ReportSchedule, p ReportPreview) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rs.Webhook, bytes.NewReader(p.Attachment.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", p.Attachment.ContentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.Attachment.Name))
+	req.Header.Set("User-Agent", "task-manager-reports")
+	req.Header.Set("X-Report-Id", strconv.Itoa(rs.ID))
+	req.Header.Set("X-Report-Kind", rs.Kind)
+	req.Header.Set("X-Report-Subject", mime.QEncoding.Encode("utf-8", strings.ReplaceAll(p.Subject, "\n", " ")))
+	appMiddleware.PropagateTrace(req)
+
+	resp, err := reportClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RunReportNow собирает и отправляет отчёт вне расписания; следующий плановый запуск не меняется.
+// Ошибка доставки возвращается и записывается в last_error.
+func (s *Service) RunReportNow(ctx context.Context, id int, now time.Time) (ReportSchedule, error) {
+	rs, err := s.GetReportSchedule(ctx, id)
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	store, err := s.reports()
+	if err != nil {
+		return ReportSchedule{}, err
+	}
+	derr := s.deliverReport(ctx, rs, now)
+	if ctx.Err() != nil {
+		return ReportSchedule{}, ctx.Err()
+	}
+	ran := now.UTC()
+	rs.LastRunAt, rs.LastError = &ran, errorText(derr)
+	if err := store.SetReportRun(ctx, rs.ID, rs.LastRunAt, rs.NextRunAt, rs.LastError); err != nil {
+		return ReportSchedule{}, err
+	}
+	return rs, derr
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// RunReports проверяет расписания отчётов сразу при старте и затем каждые interval, пока не отменён ctx.
+// paused (может быть nil) -- пропустить проверку.
+func (s *Service) RunReports(ctx context.Context, interval time.Duration, paused func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if paused == nil || !paused() {
+			if _, err := s.EvaluateReports(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Printf("reports: evaluation error: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateReports отправляет все наступившие отчёты и возвращает их число. Сколько бы запусков
+// ни пропустил сервер, отчёт отправляется один раз: он всё равно собирается на текущий момент.
+// Неудачная доставка не повторяется до следующего запуска -- ошибка видна в last_error.
+func (s *Service) EvaluateReports(ctx context.Context, now time.Time) (int, error) {
+	store, err := s.reports()
+	if errors.Is(err, ErrReportsUnsupported) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	all, err := store.ReportSchedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, rs := range all {
+		if !rs.Enabled || rs.NextRunAt == nil || rs.NextRunAt.After(now) {
+			continue
+		}
+		spec, err := parseCron(rs.Cron)
+		if err != nil {
+			log.Printf("reports: report=%d error: %v", rs.ID, err)
+			continue
+		}
+		loc, err := time.LoadLocation(rs.Timezone)
+		if err != nil {
+			log.Printf("reports: report=%d error: %v", rs.ID, err)
+			continue
+		}
+
+		derr := s.deliverReport(ctx, rs, now)
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		if derr != nil {
+			log.Printf("reports: report=%d %q delivery error: %v", rs.ID, rs.Name, derr)
+		} else {
+			sent++
+		}
+		ran := now.UTC()
+		var next *time.Time
+		if n := spec.next(now.In(loc)); !n.IsZero() {
+			u := n.UTC()
+			next = &u
+		}
+		if err := store.SetReportRun(ctx, rs.ID, &ran, next, errorText(derr)); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// reportsFile -- расписания отчётов JSON-хранилища, рядом с файлом задач.
+type reportsFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []ReportSchedule
+	lastID int
+}
+
+func (ts *TaskStore) reportsPath() string {
+	return ts.filename + ".reports.json"
+}
+
+func (ts *TaskStore) loadReports() error {
+	ts.reports.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.reportsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.reports.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.reports.data); err != nil {
+			ts.reports.err = fmt.Errorf("parse %s: %w", ts.reportsPath(), err)
+			return
+		}
+		for _, rs := range ts.reports.data {
+			ts.reports.lastID = max(ts.reports.lastID, rs.ID)
+		}
+	})
+	return ts.reports.err
+}
+
+// withReports выполняет fn под блокировкой и сохраняет файл; при ошибке записи список возвращается к прежнему.
+func (ts *TaskStore) withReports(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadReports(); err != nil {
+		return err
+	}
+	ts.reports.mu.Lock()
+	defer ts.reports.mu.Unlock()
+
+	prev := slices.Clone(ts.reports.data)
+	if err := fn(); err != nil {
+		return err
+	}
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.reports.data, "", "   ")
+	if err == nil {
+		tmp := ts.reportsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0600); err == nil {
+			err = os.Rename(tmp, ts.reportsPath())
+		}
+	}
+	if err != nil {
+		ts.reports.data = prev
+	}
+	return err
+}
+
+// reportIndex ищет расписание отчёта по ID. Вызывающий держит reports.mu.
+func (ts *TaskStore) reportIndex(id int) int {
+	return slices.IndexFunc(ts.reports.data, func(rs ReportSchedule) bool { return rs.ID == id })
+}
+
+// CreateReportSchedule сохраняет расписание отчёта и выдаёт ему ID.
+func (ts *TaskStore) CreateReportSchedule(ctx context.Context, rs *ReportSchedule) error {
+	return ts.withReports(ctx, func() error {
+		rs.ID = ts.reports.lastID + 1
+		ts.reports.data = append(ts.reports.data, *rs)
+		ts.reports.lastID = rs.ID
+		return nil
+	})
+}
+
+// UpdateReportSchedule заменяет расписание отчёта с тем же ID.
+func (ts *TaskStore) UpdateReportSchedule(ctx context.Context, rs *ReportSchedule) error {
+	return ts.withReports(ctx, func() error {
+		i := ts.reportIndex(rs.ID)
+		if i < 0 {
+			return ErrReportNotFound
+		}
+		ts.reports.data[i] = *rs
+		return nil
+	})
+}
+
+// DeleteReportSchedule удаляет расписание отчёта по ID.
+func (ts *TaskStore) DeleteReportSchedule(ctx context.Context, id int) error {
+	return ts.withReports(ctx, func() error {
+		i := ts.reportIndex(id)
+		if i < 0 {
+			return ErrReportNotFound
+		}
+		ts.reports.data = slices.Delete(ts.reports.data, i, i+1)
+		return nil
+	})
+}
+
+// ReportSchedules возвращает все расписания отчётов.
+func (ts *TaskStore) ReportSchedules(ctx context.Context) ([]ReportSchedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadReports(); err != nil {
+		return nil, err
+	}
+	ts.reports.mu.Lock()
+	defer ts.reports.mu.Unlock()
+
+	out := make([]ReportSchedule, len(ts.reports.data))
+	for i, rs := range ts.reports.data {
+		rs.Email = slices.Clone(rs.Email)
+		out[i] = rs
+	}
+	return out, nil
+}
+
+// SetReportRun запоминает последний и следующий запуск и ошибку доставки.
+func (ts *TaskStore) SetReportRun(ctx context.Context, id int, last, next *time.Time, lastErr string) error {
+	return ts.withReports(ctx, func() error {
+		i := ts.reportIndex(id)
+		if i < 0 {
+			return ErrReportNotFound
+		}
+		ts.reports.data[i].LastRunAt = last
+		ts.reports.data[i].NextRunAt = next
+		ts.reports.data[i].LastError = lastErr
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// CreateReportSchedule сохраняет расписание отчёта в report_schedules (migrations/000020_report_schedules.up.sql).
+// Настройки лежат в data как JSON, время запусков и ошибка -- в отдельных колонках.
+func (r *PostgresRepository) CreateReportSchedule(ctx context.Context, rs *ReportSchedule) error {
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return err
+	}
+	return r.q.QueryRowContext(ctx, `INSERT INTO report_schedules (data, last_run_at, next_run_at, last_error)
+		VALUES ($1, $2, $3, $4) RETURNING id`,
+		raw, nullTime(rs.LastRunAt), nullTime(rs.NextRunAt), rs.LastError).Scan(&rs.ID)
+}
+
+// UpdateReportSchedule заменяет расписание отчёта с тем же ID.
+func (r *PostgresRepository) UpdateReportSchedule(ctx context.Context, rs *ReportSchedule) error {
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, `UPDATE report_schedules SET data = $1, last_run_at = $2, next_run_at = $3,
+		last_error = $4 WHERE id = $5`,
+		raw, nullTime(rs.LastRunAt), nullTime(rs.NextRunAt), rs.LastError, rs.ID)
+	return reportAffected(res, err)
+}
+
+// DeleteReportSchedule удаляет расписание отчёта по ID.
+func (r *PostgresRepository) DeleteReportSchedule(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM report_schedules WHERE id = $1", id)
+	return reportAffected(res, err)
+}
+
+// ReportSchedules возвращает все расписания отчётов.
+func (r *PostgresRepository) ReportSchedules(ctx context.Context) ([]ReportSchedule, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, data, last_run_at, next_run_at, last_error
+		FROM report_schedules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ReportSchedule{}
+	for rows.Next() {
+		var rs ReportSchedule
+		var id int
+		var raw []byte
+		var last, next sql.NullTime
+		var lastErr string
+		if err := rows.Scan(&id, &raw, &last, &next, &lastErr); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &rs); err != nil {
+			return nil, fmt.Errorf("report schedule %d: %w", id, err)
+		}
+		rs.ID, rs.LastRunAt, rs.NextRunAt, rs.LastError = id, nil, nil, lastErr
+		if last.Valid {
+			rs.LastRunAt = &last.Time
+		}
+		if next.Valid {
+			rs.NextRunAt = &next.Time
+		}
+		out = append(out, rs)
+	}
+	return out, rows.Err()
+}
+
+// SetReportRun запоминает последний и следующий запуск и ошибку доставки.
+func (r *PostgresRepository) SetReportRun(ctx context.Context, id int, last, next *time.Time, lastErr string) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE report_schedules SET last_run_at = $1, next_run_at = $2, last_error = $3
+		WHERE id = $4`, nullTime(last), nullTime(next), lastErr, id)
+	return reportAffected(res, err)
+}
+
+func reportAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrReportNotFound
+	}
+	return nil
+}
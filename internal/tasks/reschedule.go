@@ -0,0 +1,184 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRescheduleShift -- больше чем на год одним запросом сроки не двигаем: скорее всего, это опечатка.
+const maxRescheduleShift = 366 * 24 * time.Hour
+
+// ErrInvalidShift -- сдвиг не разобран или слишком большой.
+var ErrInvalidShift = errors.New(`shift must look like "1d", "-2d", "1w", "2bd" (business days) or a Go duration ("36h", "-90m"), at most one year`)
+
+// RescheduleFilter -- какие задачи сдвигать. Условия складываются по "И".
+type RescheduleFilter struct {
+	IDs         []int  `json:"ids" validate:"max=1000"`                                                                               // только эти задачи
+	Due         string `json:"due" validate:"omitempty,oneof=overdue today tomorrow this_week next_week this_month next_7d next_30d"` // относительный фильтр по сроку
+	Tag         string `json:"tag" validate:"tag"`                                                                                    // задачи с этой меткой
+	IncludeDone bool   `json:"include_done"`                                                                                          // по умолчанию выполненные не трогаем
+}
+
+// RescheduleRequest -- тело POST /api/v1/tasks/reschedule.
+type RescheduleRequest struct {
+	Filter RescheduleFilter `json:"filter"`
+	Shift  string           `json:"shift" validate:"required,max=20"`
+}
+
+// RescheduleChange -- как изменился срок одной задачи.
+type RescheduleChange struct {
+	ID     int       `json:"id"`
+	Title  string    `json:"title"`
+	OldDue time.Time `json:"old_due"`
+	NewDue time.Time `json:"new_due"`
+}
+
+// RescheduleResult -- сводка по переносу.
+type RescheduleResult struct {
+	Count   int                `json:"count"`
+	Skipped int                `json:"skipped"` // подошли под фильтр, но без срока -- сдвигать нечего
+	Changes []RescheduleChange `json:"changes"`
+}
+
+// shift -- разобранный сдвиг: дни (календарные, в поясе пользователя), рабочие дни
+// (по рабочему календарю) или точная длительность.
+type shift struct {
+	days         int
+	businessDays int
+	dur          time.Duration
+}
+
+// parseShift понимает "1d", "-2d", "1w", "2bd" и длительности Go ("36h", "-90m").
+func parseShift(s string) (shift, error) {
+	s = strings.TrimSpace(s)
+	var sh shift
+	switch {
+	case strings.HasSuffix(s, "bd"):
+		n, err := strconv.Atoi(s[:len(s)-2])
+		if err != nil || n > 260 || n < -260 {
+			return sh, ErrInvalidShift
+		}
+		sh.businessDays = n
+		if n == 0 {
+			return sh, ErrInvalidShift
+		}
+		return sh, nil
+	case strings.HasSuffix(s, "d") || strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return sh, ErrInvalidShift
+		}
+		if strings.HasSuffix(s, "w") {
+			n *= 7
+		}
+		sh.days = n
+		if n > 366 || n < -366 {
+			return sh, ErrInvalidShift
+		}
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil || d > maxRescheduleShift || d < -maxRescheduleShift {
+			return sh, ErrInvalidShift
+		}
+		sh.dur = d
+	}
+	if sh.days == 0 && sh.dur == 0 {
+		return sh, ErrInvalidShift
+	}
+	return sh, nil
+}
+
+func (sh shift) apply(t time.Time, loc *time.Location, cal Calendar) time.Time {
+	if sh.businessDays != 0 {
+		// Рабочие дни: пятница 10:00 + "1bd" -- понедельник 10:00 (или вторник, если понедельник праздничный).
+		return cal.AddBusinessDays(t, sh.businessDays)
+	}
+	if sh.days != 0 {
+		// Календарный сдвиг: 10:00 остаётся 10:00 и через переход на летнее время.
+		return t.In(loc).AddDate(0, 0, sh.days).In(t.Location())
+	}
+	return t.Add(sh.dur)
+}
+
+// Reschedule сдвигает сроки подходящих под фильтр задач на req.Shift -- атомарно:
+// либо переносятся все задачи, либо ни одна. Относительные фильтры и календарные сдвиги
+// считаются в часовом поясе loc (настройка пользователя), рабочие дни -- по рабочему календарю.
+func (s *Service) Reschedule(ctx context.Context, userID int, req RescheduleRequest, now time.Time, loc *time.Location) (RescheduleResult, error) {
+	res := RescheduleResult{Changes: []RescheduleChange{}}
+	sh, err := parseShift(req.Shift)
+	if err != nil {
+		return res, err
+	}
+	var cal Calendar
+	if sh.businessDays != 0 {
+		if cal, err = s.Calendar(ctx); err != nil {
+			return res, err
+		}
+	}
+
+	var updated [][2]Task // пары (было, стало) для After-хуков
+	err = s.WithTx(ctx, func(tx TxStore) error {
+		updated = updated[:0]
+		list, err := tx.GetAll(ctx, userID)
+		if err != nil {
+			return err
+		}
+		list = s.visibleTasks(userID, list)
+		if req.Filter.Due != "" {
+			if list, err = FilterByDue(list, req.Filter.Due, now, loc); err != nil {
+				return err
+			}
+		}
+
+		ids := make(map[int]bool, len(req.Filter.IDs))
+		for _, id := range req.Filter.IDs {
+			ids[id] = true
+		}
+
+		for i := range list {
+			t := list[i]
+			if len(ids) > 0 && !ids[t.ID] || t.Done && !req.Filter.IncludeDone || req.Filter.Tag != "" && !hasTag(t.Tags, req.Filter.Tag) {
+				continue
+			}
+			if t.Due == nil {
+				res.Skipped++
+				continue
+			}
+
+			before := t
+			old := *t.Due
+			due := sh.apply(old, loc, cal)
+			t.Due = &due
+			if err := s.beforeUpdate(ctx, before, &t); err != nil {
+				return err
+			}
+			if err := tx.Update(ctx, &t, userID); err != nil {
+				return fmt.Errorf("task %d: %w", t.ID, err)
+			}
+			updated = append(updated, [2]Task{before, t})
+			res.Changes = append(res.Changes, RescheduleChange{ID: t.ID, Title: t.Title, OldDue: old, NewDue: *t.Due})
+		}
+		return nil
+	})
+	if err != nil {
+		return RescheduleResult{}, err
+	}
+	for _, u := range updated {
+		s.afterUpdate(ctx, u[0], u[1])
+	}
+	res.Count = len(res.Changes)
+	return res, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,397 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+// Политика хранения (/api/v1/admin/retention): сколько дней держать историю версий задач
+// (versions.go) и надгробия удалённых задач в журнале синхронизации (sync.go). Остальное
+// по-прежнему растёт без ограничений. Старые записи удаляет уборщик RunRetention.
+
+// Виды записей, которые чистит уборщик (метка kind в метриках).
+const (
+	RetentionVersions   = "versions"
+	RetentionTombstones = "tombstones"
+)
+
+var ErrRetentionUnsupported = errors.New("retention policy is not supported by this storage")
+
+var (
+	retentionPurged = metrics.NewCounterVec("retention_purged_total",
+		"Records removed by the retention janitor, by kind.", "kind")
+	retentionLastRun = metrics.NewGaugeVec("retention_last_run_timestamp_seconds",
+		"Unix time of the last successful retention janitor run.")
+)
+
+// RetentionPolicy -- сроки хранения в днях; 0 -- хранить бессрочно.
+type RetentionPolicy struct {
+	VersionsDays   int `json:"versions_days" validate:"min=0,max=36500"`
+	TombstonesDays int `json:"tombstones_days" validate:"min=0,max=36500"`
+}
+
+// RetentionState -- политика и граница надгробий: самая новая ревизия удалённого надгробия.
+// Токен синхронизации меньше границы мог пропустить удаление -- такой клиент синхронизируется заново.
+type RetentionState struct {
+	Policy           RetentionPolicy `json:"policy"`
+	TombstoneHorizon int64           `json:"tombstone_horizon"`
+}
+
+// RetentionRun -- итог одного прохода уборщика.
+type RetentionRun struct {
+	At     time.Time      `json:"at"`
+	Purged map[string]int `json:"purged"`
+}
+
+// RetentionStore -- опциональная возможность хранилища держать политику хранения и чистить старое.
+type RetentionStore interface {
+	Retention(ctx context.Context) (RetentionState, error)
+	SetRetentionPolicy(ctx context.Context, p RetentionPolicy) error
+	// PurgeVersions удаляет версии задач, сохранённые раньше before.
+	PurgeVersions(ctx context.Context, before time.Time) (int, error)
+	// PurgeTombstones удаляет надгробия, появившиеся раньше before, и сдвигает TombstoneHorizon.
+	PurgeTombstones(ctx context.Context, before time.Time) (int, error)
+}
+
+// retentionRuns -- последний проход уборщика (в памяти процесса, для GET /api/v1/admin/retention).
+type retentionRuns struct {
+	mu   sync.Mutex
+	last *RetentionRun
+}
+
+func (s *Service) retention() (RetentionStore, error) {
+	rs, ok := s.capabilities().(RetentionStore)
+	if !ok {
+		return nil, ErrRetentionUnsupported
+	}
+	return rs, nil
+}
+
+// Retention возвращает политику хранения, границу надгробий и последний проход уборщика (nil -- не было).
+func (s *Service) Retention(ctx context.Context) (RetentionState, *RetentionRun, error) {
+	if err := ctx.Err(); err != nil {
+		return RetentionState{}, nil, err
+	}
+	rs, err := s.retention()
+	if err != nil {
+		return RetentionState{}, nil, err
+	}
+	st, err := rs.Retention(ctx)
+	if err != nil {
+		return RetentionState{}, nil, err
+	}
+	s.retentionRuns.mu.Lock()
+	defer s.retentionRuns.mu.Unlock()
+	return st, s.retentionRuns.last, nil
+}
+
+// SetRetentionPolicy сохраняет политику хранения. Применяется при следующем проходе уборщика.
+func (s *Service) SetRetentionPolicy(ctx context.Context, p RetentionPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rs, err := s.retention()
+	if err != nil {
+		return err
+	}
+	return rs.SetRetentionPolicy(ctx, p)
+}
+
+// tombstoneHorizon -- граница надгробий (0 -- надгробия не чистились или хранилище политику не держит).
+func (s *Service) tombstoneHorizon(ctx context.Context) (int64, error) {
+	rs, ok := s.capabilities().(RetentionStore)
+	if !ok {
+		return 0, nil
+	}
+	st, err := rs.Retention(ctx)
+	return st.TombstoneHorizon, err
+}
+
+// PurgeExpired удаляет записи старше сроков политики на момент now и возвращает, сколько удалено.
+func (s *Service) PurgeExpired(ctx context.Context, now time.Time) (RetentionRun, error) {
+	rs, err := s.retention()
+	if err != nil {
+		return RetentionRun{}, err
+	}
+	st, err := rs.Retention(ctx)
+	if err != nil {
+		return RetentionRun{}, err
+	}
+
+	run := RetentionRun{At: now.UTC(), Purged: map[string]int{RetentionVersions: 0, RetentionTombstones: 0}}
+	purge := []struct {
+		kind string
+		days int
+		fn   func(context.Context, time.Time) (int, error)
+	}{
+		{RetentionVersions, st.Policy.VersionsDays, rs.PurgeVersions},
+		{RetentionTombstones, st.Policy.TombstonesDays, rs.PurgeTombstones},
+	}
+	for _, p := range purge {
+		if p.days == 0 {
+			continue
+		}
+		n, err := p.fn(ctx, now.AddDate(0, 0, -p.days))
+		if err != nil {
+			return RetentionRun{}, fmt.Errorf("purge %s: %w", p.kind, err)
+		}
+		run.Purged[p.kind] = n
+		retentionPurged.WithLabelValues(p.kind).Add(float64(n))
+	}
+	retentionLastRun.WithLabelValues().Set(float64(now.Unix()))
+
+	s.retentionRuns.mu.Lock()
+	s.retentionRuns.last = &run
+	s.retentionRuns.mu.Unlock()
+	return run, nil
+}
+
+// RunRetention чистит устаревшие записи сразу при старте и затем каждые interval, пока не отменён ctx.
+// paused (может быть nil) -- пропустить проход. Хранилище без политики хранения -- ничего не делает.
+func (s *Service) RunRetention(ctx context.Context, interval time.Duration, paused func() bool) {
+	if _, err := s.retention(); err != nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if paused == nil || !paused() {
+			run, err := s.PurgeExpired(ctx, time.Now())
+			switch {
+			case err != nil && ctx.Err() == nil:
+				log.Printf("retention: %v", err)
+			case run.Purged[RetentionVersions]+run.Purged[RetentionTombstones] > 0:
+				log.Printf("retention: purged versions=%d tombstones=%d",
+					run.Purged[RetentionVersions], run.Purged[RetentionTombstones])
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// retentionFile -- политика хранения JSON-хранилища, рядом с файлом задач. Граница надгробий
+// лежит в журнале ревизий: она меняется вместе с ним.
+type retentionFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data RetentionPolicy
+}
+
+func (ts *TaskStore) retentionPath() string {
+	return ts.filename + ".retention.json"
+}
+
+func (ts *TaskStore) loadRetention() error {
+	ts.retention.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.retentionPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.retention.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.retention.data); err != nil {
+			ts.retention.err = fmt.Errorf("parse %s: %w", ts.retentionPath(), err)
+		}
+	})
+	return ts.retention.err
+}
+
+// Retention возвращает политику хранения и границу надгробий.
+func (ts *TaskStore) Retention(ctx context.Context) (RetentionState, error) {
+	if err := ctx.Err(); err != nil {
+		return RetentionState{}, err
+	}
+	if err := ts.loadRetention(); err != nil {
+		return RetentionState{}, err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return RetentionState{}, err
+	}
+	ts.retention.mu.Lock()
+	st := RetentionState{Policy: ts.retention.data}
+	ts.retention.mu.Unlock()
+	ts.revisions.mu.Lock()
+	st.TombstoneHorizon = ts.revisions.data.Horizon
+	ts.revisions.mu.Unlock()
+	return st, nil
+}
+
+// SetRetentionPolicy сохраняет политику хранения.
+func (ts *TaskStore) SetRetentionPolicy(ctx context.Context, p RetentionPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadRetention(); err != nil {
+		return err
+	}
+	ts.retention.mu.Lock()
+	defer ts.retention.mu.Unlock()
+	if ts.filename != "" {
+		if err := writeJSONFile(ts.retentionPath(), p); err != nil {
+			return err
+		}
+	}
+	ts.retention.data = p
+	return nil
+}
+
+// PurgeVersions удаляет версии задач старше before.
+func (ts *TaskStore) PurgeVersions(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := ts.loadVersions(); err != nil {
+		return 0, err
+	}
+	ts.versions.mu.Lock()
+	defer ts.versions.mu.Unlock()
+
+	kept := make(map[int][]TaskVersion, len(ts.versions.data))
+	n := 0
+	for id, list := range ts.versions.data {
+		for _, v := range list {
+			if v.At.Before(before) {
+				n++
+				continue
+			}
+			kept[id] = append(kept[id], v)
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if ts.filename != "" {
+		if err := writeJSONFile(ts.versionsPath(), kept); err != nil {
+			return 0, err
+		}
+	}
+	ts.versions.data = kept
+	return n, nil
+}
+
+// PurgeTombstones удаляет надгробия старше before и сдвигает границу в журнале ревизий.
+func (ts *TaskStore) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return 0, err
+	}
+	ts.revisions.mu.Lock()
+	defer ts.revisions.mu.Unlock()
+
+	d := ts.revisions.data
+	d.Records = make(map[string]SyncRecord, len(ts.revisions.data.Records))
+	n := 0
+	for uuid, rec := range ts.revisions.data.Records {
+		if rec.Deleted && rec.ChangedAt.Before(before) {
+			n++
+			d.Horizon = max(d.Horizon, rec.Rev)
+			continue
+		}
+		d.Records[uuid] = rec
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if ts.filename != "" {
+		if err := writeJSONFile(ts.revisionsPath(), d); err != nil {
+			return 0, err
+		}
+	}
+	ts.revisions.data = d
+	return n, nil
+}
+
+// writeJSONFile атомарно (через временный файл) пишет v в path.
+func writeJSONFile(path string, v any) error {
+	raw, err := json.MarshalIndent(v, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// Retention читает политику и границу надгробий из retention (migrations/000025_retention.up.sql).
+func (r *PostgresRepository) Retention(ctx context.Context) (RetentionState, error) {
+	var st RetentionState
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM retention WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	return st, json.Unmarshal(raw, &st)
+}
+
+// SetRetentionPolicy сохраняет политику, не трогая границу надгробий.
+func (r *PostgresRepository) SetRetentionPolicy(ctx context.Context, p RetentionPolicy) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `
+		INSERT INTO retention (id, data) VALUES (1, jsonb_build_object('policy', $1::jsonb))
+		ON CONFLICT (id) DO UPDATE SET data = jsonb_set(retention.data, '{policy}', $1::jsonb)`, raw)
+	return err
+}
+
+// PurgeVersions удаляет версии задач старше before.
+func (r *PostgresRepository) PurgeVersions(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM task_versions WHERE (data->>'at')::timestamptz < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// PurgeTombstones удаляет надгробия старше before и сдвигает границу одним оператором.
+func (r *PostgresRepository) PurgeTombstones(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	err := r.q.QueryRowContext(ctx, `
+		WITH gone AS (
+			DELETE FROM task_revisions WHERE deleted AND changed_at < $1 RETURNING rev
+		), horizon AS (
+			INSERT INTO retention (id, data)
+			SELECT 1, jsonb_build_object('tombstone_horizon', MAX(rev)) FROM gone HAVING COUNT(*) > 0
+			ON CONFLICT (id) DO UPDATE SET data = jsonb_set(retention.data, '{tombstone_horizon}', to_jsonb(
+				GREATEST(COALESCE((retention.data->>'tombstone_horizon')::bigint, 0), (EXCLUDED.data->>'tombstone_horizon')::bigint)))
+		)
+		SELECT COUNT(*) FROM gone`, before).Scan(&n)
+	return n, err
+}
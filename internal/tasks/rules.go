@@ -0,0 +1,641 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrRulesUnsupported -- хранилище не умеет хранить правила.
+	ErrRulesUnsupported = errors.New("rules are not supported by this storage")
+	// ErrRuleNotFound -- правила нет (или оно чужое).
+	ErrRuleNotFound = errors.New("rule not found")
+	// ErrRuleNoCondition -- у правила нет ни одного условия по времени: оно сработало бы на всех задачах сразу.
+	ErrRuleNoCondition = errors.New("rule needs at least one of when.overdue_days, when.due_within_days, when.age_days")
+	// ErrRuleNoAction -- правило ничего не делает.
+	ErrRuleNoAction = errors.New("rule needs at least one of then.set_priority, then.add_tag, then.notify")
+)
+
+// Rule -- правило эскалации: "если задача просрочена на 3 дня -- поднять приоритет до high
+// и уведомить владельца". Правила проверяет фоновый движок (RunRules) от имени автора правила
+// на его задачах (автор или исполнитель).
+//
+// Правило срабатывает на задаче один раз, пока она подходит под условие: ID задачи запоминается
+// в Fired. Когда задача перестаёт подходить (срок перенесли, задачу закрыли), отметка снимается,
+// и при следующем попадании под условие правило сработает снова.
+type Rule struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	Enabled   bool          `json:"enabled"`
+	When      RuleCondition `json:"when"`
+	Then      RuleActions   `json:"then"`
+	CreatedBy int           `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Fired     []int         `json:"fired_task_ids"`
+}
+
+// RuleCondition -- условия правила, складываются по "И". Рассматриваются только невыполненные задачи.
+// Дни -- полные сутки (24 часа) от момента проверки, а с business_days -- рабочие дни по календарю (/api/v1/calendar).
+type RuleCondition struct {
+	OverdueDays   *int     `json:"overdue_days,omitempty" validate:"omitempty,min=0,max=365"`                // просрочена не меньше чем на N дней (0 -- просрочена)
+	DueWithinDays *int     `json:"due_within_days,omitempty" validate:"omitempty,min=0,max=365"`             // срок ещё не наступил и наступит в ближайшие N дней
+	AgeDays       *int     `json:"age_days,omitempty" validate:"omitempty,min=1,max=3650"`                   // создана больше N дней назад (по created_at)
+	Tag           string   `json:"tag,omitempty" validate:"tag"`                                             // только задачи с этой меткой
+	Status        string   `json:"status,omitempty" validate:"omitempty,status"`                             // только задачи в этом статусе (или категории)
+	PriorityBelow Priority `json:"priority_below,omitempty" validate:"omitempty,oneof=medium high critical"` // только задачи с приоритетом ниже
+	BusinessDays  bool     `json:"business_days,omitempty"`                                                  // считать overdue/due_within/age в рабочих днях
+	// WorkingHoursOnly -- срабатывать только в рабочее время: ночью и в выходные правило ждёт.
+	WorkingHoursOnly bool `json:"working_hours_only,omitempty"`
+}
+
+// RuleActions -- что делает сработавшее правило.
+type RuleActions struct {
+	SetPriority Priority `json:"set_priority,omitempty" validate:"omitempty,priority"` // только повышает, понизить правилом нельзя
+	AddTag      string   `json:"add_tag,omitempty" validate:"tag"`
+	Notify      bool     `json:"notify,omitempty"` // уведомить владельца и исполнителя задачи
+}
+
+// RuleRequest -- тело POST /api/v1/rules и PUT /api/v1/rules/{rule_id} (правило заменяется целиком).
+type RuleRequest struct {
+	Name    string        `json:"name" validate:"required,max=100"`
+	Enabled *bool         `json:"enabled"` // по умолчанию true
+	When    RuleCondition `json:"when"`
+	Then    RuleActions   `json:"then"`
+}
+
+// RuleStore -- опциональная возможность хранилища хранить правила.
+type RuleStore interface {
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id int) error
+	// Rules возвращает все правила (всех пользователей).
+	Rules(ctx context.Context) ([]Rule, error)
+	// SetRuleFired запоминает задачи, на которых правило сработало.
+	SetRuleFired(ctx context.Context, id int, taskIDs []int) error
+}
+
+func (s *Service) rules() (RuleStore, error) {
+	rs, ok := s.capabilities().(RuleStore)
+	if !ok {
+		return nil, ErrRulesUnsupported
+	}
+	return rs, nil
+}
+
+func (req RuleRequest) check() error {
+	if req.When.OverdueDays == nil && req.When.DueWithinDays == nil && req.When.AgeDays == nil {
+		return ErrRuleNoCondition
+	}
+	if req.Then.SetPriority == "" && req.Then.AddTag == "" && !req.Then.Notify {
+		return ErrRuleNoAction
+	}
+	return nil
+}
+
+// CreateRule создаёт правило пользователя userID.
+func (s *Service) CreateRule(ctx context.Context, userID int, req RuleRequest, now time.Time) (Rule, error) {
+	if err := ctx.Err(); err != nil {
+		return Rule{}, err
+	}
+	if err := req.check(); err != nil {
+		return Rule{}, err
+	}
+	rs, err := s.rules()
+	if err != nil {
+		return Rule{}, err
+	}
+	rule := Rule{
+		Name:      req.Name,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		When:      req.When,
+		Then:      req.Then,
+		CreatedBy: userID,
+		CreatedAt: now.UTC(),
+		UpdatedAt: now.UTC(),
+		Fired:     []int{},
+	}
+	if err := rs.CreateRule(ctx, &rule); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// UserRules возвращает правила пользователя.
+func (s *Service) UserRules(ctx context.Context, userID int) ([]Rule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rs, err := s.rules()
+	if err != nil {
+		return nil, err
+	}
+	all, err := rs.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Rule, 0, len(all))
+	for _, rule := range all {
+		if rule.CreatedBy == userID {
+			out = append(out, rule)
+		}
+	}
+	return out, nil
+}
+
+// GetRule возвращает правило пользователя по ID. Чужое правило -- ErrRuleNotFound.
+func (s *Service) GetRule(ctx context.Context, userID, id int) (Rule, error) {
+	list, err := s.UserRules(ctx, userID)
+	if err != nil {
+		return Rule{}, err
+	}
+	for _, rule := range list {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return Rule{}, ErrRuleNotFound
+}
+
+// UpdateRule заменяет условия и действия правила. Отметки о срабатываниях сбрасываются:
+// с новыми условиями правило проверяет задачи заново.
+func (s *Service) UpdateRule(ctx context.Context, userID, id int, req RuleRequest, now time.Time) (Rule, error) {
+	if err := req.check(); err != nil {
+		return Rule{}, err
+	}
+	rule, err := s.GetRule(ctx, userID, id)
+	if err != nil {
+		return Rule{}, err
+	}
+	rs, err := s.rules()
+	if err != nil {
+		return Rule{}, err
+	}
+	rule.Name, rule.When, rule.Then = req.Name, req.When, req.Then
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.UpdatedAt = now.UTC()
+	rule.Fired = []int{}
+	if err := rs.UpdateRule(ctx, &rule); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// DeleteRule удаляет правило пользователя.
+func (s *Service) DeleteRule(ctx context.Context, userID, id int) error {
+	if _, err := s.GetRule(ctx, userID, id); err != nil {
+		return err
+	}
+	rs, err := s.rules()
+	if err != nil {
+		return err
+	}
+	return rs.DeleteRule(ctx, id)
+}
+
+// matches проверяет задачу на условия правила на момент now.
+func (c RuleCondition) matches(t *Task, now time.Time, cal Calendar) bool {
+	const day = 24 * time.Hour
+	if t.Done {
+		return false
+	}
+	if c.BusinessDays {
+		return c.matchesBusinessDays(t, now, cal) && c.matchesFields(t)
+	}
+	if c.OverdueDays != nil && (t.Due == nil || !now.After(*t.Due) || now.Sub(*t.Due) < time.Duration(*c.OverdueDays)*day) {
+		return false
+	}
+	if c.DueWithinDays != nil && (t.Due == nil || !t.Due.After(now) || t.Due.Sub(now) > time.Duration(*c.DueWithinDays)*day) {
+		return false
+	}
+	if c.AgeDays != nil && (t.CreatedAt == nil || now.Sub(*t.CreatedAt) < time.Duration(*c.AgeDays)*day) {
+		return false
+	}
+	return c.matchesFields(t)
+}
+
+// matchesBusinessDays -- сроки условия в рабочих днях: задача просрочена на N рабочих дней,
+// если с дня срока прошло N рабочих дней (0 -- просто просрочена).
+func (c RuleCondition) matchesBusinessDays(t *Task, now time.Time, cal Calendar) bool {
+	if c.OverdueDays != nil && (t.Due == nil || !now.After(*t.Due) || cal.BusinessDaysBetween(*t.Due, now) < *c.OverdueDays) {
+		return false
+	}
+	if c.DueWithinDays != nil && (t.Due == nil || !t.Due.After(now) || cal.BusinessDaysBetween(now, *t.Due) > *c.DueWithinDays) {
+		return false
+	}
+	if c.AgeDays != nil && (t.CreatedAt == nil || cal.BusinessDaysBetween(*t.CreatedAt, now) < *c.AgeDays) {
+		return false
+	}
+	return true
+}
+
+// matchesFields проверяет условия, не зависящие от времени.
+func (c RuleCondition) matchesFields(t *Task) bool {
+	if c.Tag != "" && !hasTag(t.Tags, c.Tag) {
+		return false
+	}
+	if !statusMatches(c.Status, t.Status) {
+		return false
+	}
+	// Приоритеты правила -- через Resolve: правило могли сохранить при другой шкале приоритетов.
+	if c.PriorityBelow != "" && t.Priority.Rank() >= c.PriorityBelow.Resolve().Rank() {
+		return false
+	}
+	return true
+}
+
+// RunRules проверяет правила каждые interval, пока не отменён ctx.
+// paused (может быть nil) -- пропустить проверку, например, пока сервер в режиме только для чтения.
+func (s *Service) RunRules(ctx context.Context, interval time.Duration, paused func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if paused != nil && paused() {
+			continue
+		}
+		if _, err := s.EvaluateRules(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("rules: evaluation error: %v", err)
+		}
+	}
+}
+
+// EvaluateRules один раз проверяет все включённые правила и возвращает число срабатываний.
+// Ошибка на одной задаче не останавливает остальные: задача просто не отмечается и будет
+// проверена снова в следующий раз.
+func (s *Service) EvaluateRules(ctx context.Context, now time.Time) (int, error) {
+	rs, err := s.rules()
+	if errors.Is(err, ErrRulesUnsupported) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	all, err := rs.Rules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Календарь читаем один раз и только если он кому-то нужен.
+	var cal *Calendar
+	fired := 0
+	for _, rule := range all {
+		if !rule.Enabled {
+			continue
+		}
+		if cal == nil && (rule.When.BusinessDays || rule.When.WorkingHoursOnly) {
+			c, err := s.Calendar(ctx)
+			if err != nil {
+				return fired, err
+			}
+			cal = &c
+		}
+		if rule.When.WorkingHoursOnly && !cal.InWorkingHours(now) {
+			continue // Fired не трогаем: отметки остаются до рабочего времени
+		}
+		list, err := s.GetAllTasks(ctx, rule.CreatedBy) // правило видит то же, что его автор
+		if err != nil {
+			return fired, err
+		}
+
+		keep := make([]int, 0, len(rule.Fired))
+		for i := range list {
+			t := &list[i]
+			if !rule.When.matches(t, now, calendarOrDefault(cal)) {
+				continue
+			}
+			if slices.Contains(rule.Fired, t.ID) {
+				keep = append(keep, t.ID)
+				continue
+			}
+			if err := s.applyRule(ctx, rule, t); err != nil {
+				if ctx.Err() != nil {
+					return fired, ctx.Err()
+				}
+				log.Printf("rules: rule=%d task=%d error: %v", rule.ID, t.ID, err)
+				continue
+			}
+			keep = append(keep, t.ID)
+			fired++
+		}
+
+		slices.Sort(keep)
+		if !slices.Equal(keep, rule.Fired) {
+			if err := rs.SetRuleFired(ctx, rule.ID, keep); err != nil {
+				return fired, err
+			}
+		}
+	}
+	return fired, nil
+}
+
+// applyRule выполняет действия правила над задачей.
+func (s *Service) applyRule(ctx context.Context, rule Rule, t *Task) error {
+	changed := false
+	if p := rule.Then.SetPriority.Resolve(); rule.Then.SetPriority != "" && t.Priority.Rank() < p.Rank() {
+		t.Priority, changed = p, true
+	}
+	if tag := rule.Then.AddTag; tag != "" && !hasTag(t.Tags, tag) {
+		t.Tags, changed = append(t.Tags, tag), true
+	}
+	if changed {
+		if err := s.UpdateTask(ctx, t, rule.CreatedBy); err != nil {
+			return err
+		}
+	}
+	log.Printf("rules: rule=%d %q fired on task=%d priority=%s changed=%t", rule.ID, rule.Name, t.ID, t.Priority, changed)
+
+	if !rule.Then.Notify {
+		return nil
+	}
+	text := fmt.Sprintf("Правило «%s»: задача #%d «%s»", rule.Name, t.ID, t.Title)
+	recipients := []int{t.UserID}
+	if t.AssignedTo != 0 && t.AssignedTo != t.UserID {
+		recipients = append(recipients, t.AssignedTo)
+	}
+	for _, userID := range recipients {
+		n := Notification{UserID: userID, TaskID: t.ID, Source: fmt.Sprintf("rule:%d", rule.ID), Text: text}
+		if err := s.notify(ctx, n); err != nil {
+			// Изменения уже сохранены: повторять правило из-за недоставленного уведомления не будем.
+			log.Printf("rules: rule=%d task=%d notify user=%d error: %v", rule.ID, t.ID, userID, err)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// rulesFile -- правила JSON-хранилища. Лежат рядом с файлом задач, как и доски.
+type rulesFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []Rule
+	lastID int
+}
+
+func (ts *TaskStore) rulesPath() string {
+	return ts.filename + ".rules.json"
+}
+
+func (ts *TaskStore) loadRules() error {
+	ts.rules.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.rulesPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.rules.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.rules.data); err != nil {
+			ts.rules.err = fmt.Errorf("parse %s: %w", ts.rulesPath(), err)
+			return
+		}
+		for _, rule := range ts.rules.data {
+			ts.rules.lastID = max(ts.rules.lastID, rule.ID)
+		}
+	})
+	return ts.rules.err
+}
+
+// saveRules переписывает файл правил целиком. Вызывающий держит rules.mu.
+func (ts *TaskStore) saveRules() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.rules.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.rulesPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.rulesPath())
+}
+
+// withRules выполняет fn под блокировкой правил и сохраняет файл; при ошибке записи
+// список правил возвращается к прежнему.
+func (ts *TaskStore) withRules(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadRules(); err != nil {
+		return err
+	}
+	ts.rules.mu.Lock()
+	defer ts.rules.mu.Unlock()
+
+	prev := slices.Clone(ts.rules.data)
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := ts.saveRules(); err != nil {
+		ts.rules.data = prev
+		return err
+	}
+	return nil
+}
+
+// ruleIndex ищет правило по ID. Вызывающий держит rules.mu.
+func (ts *TaskStore) ruleIndex(id int) int {
+	return slices.IndexFunc(ts.rules.data, func(r Rule) bool { return r.ID == id })
+}
+
+// CreateRule сохраняет правило и выдаёт ему ID.
+func (ts *TaskStore) CreateRule(ctx context.Context, rule *Rule) error {
+	return ts.withRules(ctx, func() error {
+		rule.ID = ts.rules.lastID + 1
+		ts.rules.data = append(ts.rules.data, *rule)
+		ts.rules.lastID = rule.ID
+		return nil
+	})
+}
+
+// UpdateRule заменяет правило с тем же ID.
+func (ts *TaskStore) UpdateRule(ctx context.Context, rule *Rule) error {
+	return ts.withRules(ctx, func() error {
+		i := ts.ruleIndex(rule.ID)
+		if i < 0 {
+			return ErrRuleNotFound
+		}
+		ts.rules.data[i] = *rule
+		return nil
+	})
+}
+
+// DeleteRule удаляет правило по ID.
+func (ts *TaskStore) DeleteRule(ctx context.Context, id int) error {
+	return ts.withRules(ctx, func() error {
+		i := ts.ruleIndex(id)
+		if i < 0 {
+			return ErrRuleNotFound
+		}
+		ts.rules.data = slices.Delete(ts.rules.data, i, i+1)
+		return nil
+	})
+}
+
+// Rules возвращает все правила.
+func (ts *TaskStore) Rules(ctx context.Context) ([]Rule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadRules(); err != nil {
+		return nil, err
+	}
+	ts.rules.mu.Lock()
+	defer ts.rules.mu.Unlock()
+
+	out := make([]Rule, len(ts.rules.data))
+	for i, rule := range ts.rules.data {
+		rule.Fired = slices.Clone(rule.Fired)
+		out[i] = rule
+	}
+	return out, nil
+}
+
+// SetRuleFired запоминает задачи, на которых правило сработало.
+func (ts *TaskStore) SetRuleFired(ctx context.Context, id int, taskIDs []int) error {
+	return ts.withRules(ctx, func() error {
+		i := ts.ruleIndex(id)
+		if i < 0 {
+			return ErrRuleNotFound
+		}
+		ts.rules.data[i].Fired = slices.Clone(taskIDs)
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+const ruleSelect = "SELECT id, name, enabled, condition, actions, created_by, created_at, updated_at, fired_task_ids FROM rules"
+
+func scanRule(row interface{ Scan(...any) error }) (Rule, error) {
+	var rule Rule
+	var cond, actions []byte
+	var fired pq.Int64Array
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Enabled, &cond, &actions, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt, &fired)
+	if err != nil {
+		return rule, err
+	}
+	if err := json.Unmarshal(cond, &rule.When); err != nil {
+		return rule, fmt.Errorf("rule %d condition: %w", rule.ID, err)
+	}
+	if err := json.Unmarshal(actions, &rule.Then); err != nil {
+		return rule, fmt.Errorf("rule %d actions: %w", rule.ID, err)
+	}
+	rule.Fired = make([]int, len(fired))
+	for i, id := range fired {
+		rule.Fired[i] = int(id)
+	}
+	return rule, nil
+}
+
+// ruleJSON -- условия и действия правила для колонок JSONB.
+func ruleJSON(rule *Rule) (cond, actions []byte, err error) {
+	if cond, err = json.Marshal(rule.When); err != nil {
+		return nil, nil, err
+	}
+	if actions, err = json.Marshal(rule.Then); err != nil {
+		return nil, nil, err
+	}
+	return cond, actions, nil
+}
+
+// CreateRule сохраняет правило в rules (migrations/000015_rules.up.sql).
+func (r *PostgresRepository) CreateRule(ctx context.Context, rule *Rule) error {
+	cond, actions, err := ruleJSON(rule)
+	if err != nil {
+		return err
+	}
+	return r.q.QueryRowContext(ctx, `INSERT INTO rules (name, enabled, condition, actions, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		rule.Name, rule.Enabled, cond, actions, rule.CreatedBy, rule.CreatedAt, rule.UpdatedAt).Scan(&rule.ID)
+}
+
+// UpdateRule заменяет правило с тем же ID.
+func (r *PostgresRepository) UpdateRule(ctx context.Context, rule *Rule) error {
+	cond, actions, err := ruleJSON(rule)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, `UPDATE rules SET name = $1, enabled = $2, condition = $3, actions = $4,
+		updated_at = $5, fired_task_ids = $6 WHERE id = $7`,
+		rule.Name, rule.Enabled, cond, actions, rule.UpdatedAt, pq.Array(rule.Fired), rule.ID)
+	return ruleAffected(res, err)
+}
+
+// DeleteRule удаляет правило по ID.
+func (r *PostgresRepository) DeleteRule(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM rules WHERE id = $1", id)
+	return ruleAffected(res, err)
+}
+
+// Rules возвращает все правила.
+func (r *PostgresRepository) Rules(ctx context.Context) ([]Rule, error) {
+	rows, err := r.q.QueryContext(ctx, ruleSelect+" ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, rows.Err()
+}
+
+// SetRuleFired запоминает задачи, на которых правило сработало.
+func (r *PostgresRepository) SetRuleFired(ctx context.Context, id int, taskIDs []int) error {
+	res, err := r.q.ExecContext(ctx, "UPDATE rules SET fired_task_ids = $1 WHERE id = $2", pq.Array(taskIDs), id)
+	return ruleAffected(res, err)
+}
+
+func ruleAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// calendarOrDefault -- загруженный календарь или календарь по умолчанию, если он не понадобился.
+func calendarOrDefault(c *Calendar) Calendar {
+	if c == nil {
+		return DefaultCalendar()
+	}
+	return *c
+}
@@ -0,0 +1,850 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxScheduleCatchUp -- сколько пропущенных запусков одного расписания наверстывается за проверку;
+// остальные -- на следующих проверках.
+const maxScheduleCatchUp = 100
+
+// maxScheduleExceptions -- сколько исключений (пропусков и правок отдельных запусков) хранит расписание.
+const maxScheduleExceptions = 100
+
+// maxScheduleOccurrences -- сколько предстоящих запусков отдаёт GET .../occurrences за раз.
+const maxScheduleOccurrences = 100
+
+var (
+	// ErrSchedulesUnsupported -- хранилище не умеет хранить расписания.
+	ErrSchedulesUnsupported = errors.New("schedules are not supported by this storage")
+	// ErrScheduleNotFound -- расписания нет (или оно чужое).
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleNever -- выражение корректно, но не срабатывает (например, 30 февраля).
+	ErrScheduleNever = errors.New("cron expression never fires")
+	// ErrScheduleNoRun -- в этот момент у расписания нет предстоящего запуска (исключение не к чему привязать).
+	ErrScheduleNoRun = errors.New("no upcoming run of the schedule at this time")
+	// ErrScheduleExceptionNotFound -- для этого запуска исключения нет.
+	ErrScheduleExceptionNotFound = errors.New("schedule exception not found")
+	// ErrScheduleTooManyExceptions -- у расписания уже maxScheduleExceptions исключений.
+	ErrScheduleTooManyExceptions = errors.New("too many exceptions for one schedule (max 100)")
+)
+
+// Schedule -- расписание создания задач: "каждый понедельник в 9:00 создать «Недельный обзор»".
+// Время в Cron -- в часовом поясе Timezone. Расписание хранит момент следующего запуска (NextRunAt),
+// поэтому переживает перезапуск: запуски, пришедшиеся на простой, создаются при старте,
+// если включено CatchUp, иначе пропускаются.
+type Schedule struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone"`
+	Enabled  bool   `json:"enabled"`
+	CatchUp  bool   `json:"catch_up"`
+	// WorkdaysOnly -- пропускать запуски, выпавшие на нерабочие дни рабочего календаря
+	WorkdaysOnly bool         `json:"workdays_only"`
+	Task         ScheduleTask `json:"task"`
+	CreatedBy    int          `json:"created_by"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	LastRunAt    *time.Time   `json:"last_run_at,omitempty"`
+	NextRunAt    *time.Time   `json:"next_run_at,omitempty"` // nil -- расписание выключено
+	// Exceptions -- пропуски и правки отдельных запусков, по возрастанию At. Прошедшие выбрасываются.
+	Exceptions []ScheduleException `json:"exceptions,omitempty"`
+}
+
+// ScheduleException -- исключение для одного запуска серии: не создавать задачу (Skip) или создать
+// её со своими полями (Task) -- остальные запуски не меняются. At -- момент запуска, как его отдаёт
+// GET /api/v1/schedules/{schedule_id}/occurrences.
+type ScheduleException struct {
+	At   time.Time             `json:"at"`
+	Skip bool                  `json:"skip,omitempty"`
+	Task *ScheduleTaskOverride `json:"task,omitempty"`
+}
+
+// ScheduleTaskOverride -- поля задачи одного запуска вместо шаблона; пустые берутся из шаблона.
+type ScheduleTaskOverride struct {
+	Title       string     `json:"title,omitempty" validate:"omitempty,title"`
+	Description string     `json:"description,omitempty" validate:"description"`
+	Priority    Priority   `json:"priority,omitempty" validate:"omitempty,priority"`
+	AssignedTo  int        `json:"assigned_to,omitempty" validate:"min=0"`
+	Due         *time.Time `json:"due,omitempty"` // вместо due_in_hours/due_in_business_days шаблона
+}
+
+// ScheduleExceptionRequest -- тело PUT /api/v1/schedules/{schedule_id}/exceptions/{at}: ровно одно из полей.
+type ScheduleExceptionRequest struct {
+	Skip bool                  `json:"skip"`
+	Task *ScheduleTaskOverride `json:"task" validate:"required_without=Skip,excluded_with=Skip"`
+}
+
+// ScheduleOccurrence -- предстоящий запуск и исключение для него, если есть.
+type ScheduleOccurrence struct {
+	At        time.Time          `json:"at"`
+	Exception *ScheduleException `json:"exception,omitempty"`
+}
+
+// exception возвращает исключение для запуска at (nil -- запуск идёт по шаблону).
+func (sch *Schedule) exception(at time.Time) *ScheduleException {
+	for i := range sch.Exceptions {
+		if sch.Exceptions[i].At.Equal(at) {
+			return &sch.Exceptions[i]
+		}
+	}
+	return nil
+}
+
+// ScheduleTask -- шаблон создаваемой задачи.
+type ScheduleTask struct {
+	Title       string   `json:"title" validate:"required,title"`
+	Description string   `json:"description,omitempty" validate:"description"`
+	Priority    Priority `json:"priority,omitempty" validate:"omitempty,priority"` // пусто -- из настроек автора
+	Tags        []string `json:"tags,omitempty" validate:"tags"`
+	AssignedTo  int      `json:"assigned_to,omitempty"`                                      // 0 -- автор расписания
+	DueInHours  *int     `json:"due_in_hours,omitempty" validate:"omitempty,min=0,max=8760"` // срок -- через N часов от запуска
+	// DueInBusinessDays -- срок через N рабочих дней от запуска (конец рабочего дня)
+	DueInBusinessDays *int `json:"due_in_business_days,omitempty" validate:"omitempty,min=0,max=365,excluded_with=DueInHours"`
+}
+
+// ScheduleRequest -- тело POST /api/v1/schedules и PUT /api/v1/schedules/{schedule_id}.
+type ScheduleRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Cron     string `json:"cron" validate:"required,max=100"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"` // пусто -- из настроек автора
+	Enabled  *bool  `json:"enabled"`                                // по умолчанию true
+	CatchUp  bool   `json:"catch_up"`
+	// WorkdaysOnly -- не создавать задачи в выходные и праздники ("0 9 * * *" -- по рабочим дням в 9:00)
+	WorkdaysOnly bool         `json:"workdays_only"`
+	Task         ScheduleTask `json:"task"`
+}
+
+// ScheduleStore -- опциональная возможность хранилища хранить расписания.
+type ScheduleStore interface {
+	CreateSchedule(ctx context.Context, sch *Schedule) error
+	UpdateSchedule(ctx context.Context, sch *Schedule) error
+	DeleteSchedule(ctx context.Context, id int) error
+	// Schedules возвращает все расписания (всех пользователей).
+	Schedules(ctx context.Context) ([]Schedule, error)
+	// SetScheduleRun запоминает последний выполненный и следующий запуск.
+	SetScheduleRun(ctx context.Context, id int, last, next *time.Time) error
+	// SetScheduleExceptions заменяет исключения расписания, не трогая остальные поля.
+	SetScheduleExceptions(ctx context.Context, id int, list []ScheduleException) error
+}
+
+func (s *Service) schedules() (ScheduleStore, error) {
+	ss, ok := s.capabilities().(ScheduleStore)
+	if !ok {
+		return nil, ErrSchedulesUnsupported
+	}
+	return ss, nil
+}
+
+// applyScheduleRequest переносит запрос в расписание и пересчитывает следующий запуск от now.
+func (s *Service) applyScheduleRequest(ctx context.Context, sch *Schedule, req ScheduleRequest, now time.Time) error {
+	spec, err := parseCron(req.Cron)
+	if err != nil {
+		return err
+	}
+	tz := req.Timezone
+	if tz == "" {
+		prefs, err := s.GetPreferences(ctx, sch.CreatedBy)
+		if err != nil {
+			return err
+		}
+		tz = prefs.Location().String()
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return err
+	}
+	next := spec.next(now.In(loc))
+	if next.IsZero() {
+		return ErrScheduleNever
+	}
+
+	sch.Name, sch.Cron, sch.Timezone, sch.CatchUp, sch.Task = req.Name, req.Cron, tz, req.CatchUp, req.Task
+	sch.WorkdaysOnly = req.WorkdaysOnly
+	sch.Enabled = req.Enabled == nil || *req.Enabled
+	sch.NextRunAt = nil
+	if sch.Enabled {
+		next = next.UTC()
+		sch.NextRunAt = &next
+	}
+	sch.UpdatedAt = now.UTC()
+
+	// Исключения привязаны к моментам запуска: после смены выражения или пояса остаются только те,
+	// чьи запуски ещё будут.
+	kept := sch.Exceptions[:0]
+	for _, ex := range sch.Exceptions {
+		if !ex.At.Before(now) && isCronRun(spec, ex.At.In(loc)) {
+			kept = append(kept, ex)
+		}
+	}
+	sch.Exceptions = kept
+	return nil
+}
+
+// isCronRun сообщает, срабатывает ли выражение ровно в момент at.
+func isCronRun(spec cronSpec, at time.Time) bool {
+	return at.Equal(spec.next(at.Add(-time.Minute)))
+}
+
+// CreateSchedule создаёт расписание пользователя userID.
+func (s *Service) CreateSchedule(ctx context.Context, userID int, req ScheduleRequest, now time.Time) (Schedule, error) {
+	if err := ctx.Err(); err != nil {
+		return Schedule{}, err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return Schedule{}, err
+	}
+	sch := Schedule{CreatedBy: userID, CreatedAt: now.UTC()}
+	if err := s.applyScheduleRequest(ctx, &sch, req, now); err != nil {
+		return Schedule{}, err
+	}
+	if err := ss.CreateSchedule(ctx, &sch); err != nil {
+		return Schedule{}, err
+	}
+	return sch, nil
+}
+
+// UserSchedules возвращает расписания пользователя.
+func (s *Service) UserSchedules(ctx context.Context, userID int) ([]Schedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return nil, err
+	}
+	all, err := ss.Schedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Schedule, 0, len(all))
+	for _, sch := range all {
+		if sch.CreatedBy == userID {
+			out = append(out, sch)
+		}
+	}
+	return out, nil
+}
+
+// GetSchedule возвращает расписание пользователя по ID. Чужое -- ErrScheduleNotFound.
+func (s *Service) GetSchedule(ctx context.Context, userID, id int) (Schedule, error) {
+	list, err := s.UserSchedules(ctx, userID)
+	if err != nil {
+		return Schedule{}, err
+	}
+	for _, sch := range list {
+		if sch.ID == id {
+			return sch, nil
+		}
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+// UpdateSchedule заменяет расписание целиком. Следующий запуск считается заново от now:
+// пропущенное до изменения не наверстывается.
+func (s *Service) UpdateSchedule(ctx context.Context, userID, id int, req ScheduleRequest, now time.Time) (Schedule, error) {
+	sch, err := s.GetSchedule(ctx, userID, id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return Schedule{}, err
+	}
+	if err := s.applyScheduleRequest(ctx, &sch, req, now); err != nil {
+		return Schedule{}, err
+	}
+	if err := ss.UpdateSchedule(ctx, &sch); err != nil {
+		return Schedule{}, err
+	}
+	return sch, nil
+}
+
+// DeleteSchedule удаляет расписание пользователя. Созданные им задачи остаются.
+func (s *Service) DeleteSchedule(ctx context.Context, userID, id int) error {
+	if _, err := s.GetSchedule(ctx, userID, id); err != nil {
+		return err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return err
+	}
+	return ss.DeleteSchedule(ctx, id)
+}
+
+// ScheduleOccurrences возвращает до limit предстоящих запусков расписания с их исключениями --
+// по ним выбирают запуск, который нужно пропустить или изменить. Запуски, которые пропустит
+// workdays_only, не показываются: исключения для них не нужны.
+func (s *Service) ScheduleOccurrences(ctx context.Context, userID, id, limit int) ([]ScheduleOccurrence, error) {
+	sch, err := s.GetSchedule(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	out := []ScheduleOccurrence{}
+	if sch.NextRunAt == nil {
+		return out, nil
+	}
+	spec, loc, err := scheduleSpec(sch)
+	if err != nil {
+		return nil, err
+	}
+	var cal *Calendar
+	if sch.WorkdaysOnly {
+		c, err := s.Calendar(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cal = &c
+	}
+	limit = min(max(limit, 1), maxScheduleOccurrences)
+	for occ := sch.NextRunAt.In(loc); !occ.IsZero() && len(out) < limit; occ = spec.next(occ) {
+		if cal != nil && !cal.IsWorkday(occ) {
+			continue
+		}
+		o := ScheduleOccurrence{At: occ.UTC()}
+		if ex := sch.exception(o.At); ex != nil {
+			o.Exception = ex
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// SetScheduleException пропускает или меняет один предстоящий запуск at (повторный вызов для того же
+// запуска заменяет исключение). Остальные запуски серии идут по шаблону.
+func (s *Service) SetScheduleException(ctx context.Context, userID, id int, at time.Time, req ScheduleExceptionRequest, now time.Time) (Schedule, error) {
+	sch, err := s.GetSchedule(ctx, userID, id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return Schedule{}, err
+	}
+	spec, loc, err := scheduleSpec(sch)
+	if err != nil {
+		return Schedule{}, err
+	}
+	// Запуск должен быть ещё впереди: не раньше следующего (он может как раз ждать проверки).
+	from := now
+	if sch.NextRunAt != nil && sch.NextRunAt.Before(from) {
+		from = *sch.NextRunAt
+	}
+	if at.Before(from) || !isCronRun(spec, at.In(loc)) {
+		return Schedule{}, ErrScheduleNoRun
+	}
+
+	ex := ScheduleException{At: at.UTC(), Skip: req.Skip, Task: req.Task}
+	list := make([]ScheduleException, 0, len(sch.Exceptions)+1)
+	for _, e := range sch.Exceptions {
+		if !e.At.Before(from) && !e.At.Equal(ex.At) {
+			list = append(list, e)
+		}
+	}
+	if len(list) >= maxScheduleExceptions {
+		return Schedule{}, ErrScheduleTooManyExceptions
+	}
+	list = append(list, ex)
+	slices.SortFunc(list, func(a, b ScheduleException) int { return a.At.Compare(b.At) })
+	if err := ss.SetScheduleExceptions(ctx, id, list); err != nil {
+		return Schedule{}, err
+	}
+	sch.Exceptions = list
+	return sch, nil
+}
+
+// DeleteScheduleException возвращает запуск at к шаблону.
+func (s *Service) DeleteScheduleException(ctx context.Context, userID, id int, at time.Time) error {
+	sch, err := s.GetSchedule(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	ss, err := s.schedules()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(sch.Exceptions, func(e ScheduleException) bool { return e.At.Equal(at) })
+	if i < 0 {
+		return ErrScheduleExceptionNotFound
+	}
+	return ss.SetScheduleExceptions(ctx, id, slices.Delete(sch.Exceptions, i, i+1))
+}
+
+// scheduleSpec разбирает выражение и часовой пояс сохранённого расписания.
+func scheduleSpec(sch Schedule) (cronSpec, *time.Location, error) {
+	spec, err := parseCron(sch.Cron)
+	if err != nil {
+		return cronSpec{}, nil, err
+	}
+	loc, err := time.LoadLocation(sch.Timezone)
+	if err != nil {
+		return cronSpec{}, nil, err
+	}
+	return spec, loc, nil
+}
+
+// RunSchedules проверяет расписания сразу при старте (чтобы наверстать простой), а затем
+// каждые interval, пока не отменён ctx. Запуск, опоздавший больше чем на interval и минуту,
+// считается пропущенным и без CatchUp не выполняется.
+// paused (может быть nil) -- пропустить проверку; пропущенное наверстается по тем же правилам.
+func (s *Service) RunSchedules(ctx context.Context, interval time.Duration, paused func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	grace := interval + time.Minute
+	for {
+		if paused == nil || !paused() {
+			if _, err := s.EvaluateSchedules(ctx, time.Now(), grace); err != nil && ctx.Err() == nil {
+				log.Printf("schedules: evaluation error: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateSchedules создаёт задачи по всем наступившим запускам и возвращает их число.
+// grace -- насколько запуск может опоздать, чтобы без CatchUp всё-таки выполниться.
+func (s *Service) EvaluateSchedules(ctx context.Context, now time.Time, grace time.Duration) (int, error) {
+	ss, err := s.schedules()
+	if errors.Is(err, ErrSchedulesUnsupported) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	all, err := ss.Schedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var cal *Calendar
+	created := 0
+	for _, sch := range all {
+		if !sch.Enabled || sch.NextRunAt == nil || sch.NextRunAt.After(now) {
+			continue
+		}
+		if cal == nil && (sch.WorkdaysOnly || sch.Task.DueInBusinessDays != nil) {
+			c, err := s.Calendar(ctx)
+			if err != nil {
+				return created, err
+			}
+			cal = &c
+		}
+		spec, loc, err := scheduleSpec(sch)
+		if err != nil {
+			log.Printf("schedules: schedule=%d error: %v", sch.ID, err)
+			continue
+		}
+
+		last, occ := sch.LastRunAt, sch.NextRunAt.In(loc)
+		n, skipped := 0, 0
+		for !occ.IsZero() && !occ.After(now) && n < maxScheduleCatchUp {
+			if sch.WorkdaysOnly && !cal.IsWorkday(occ) {
+				occ = spec.next(occ)
+				continue
+			}
+			ex := sch.exception(occ)
+			if ex != nil && ex.Skip {
+				log.Printf("schedules: schedule=%d %q run=%s skipped by exception", sch.ID, sch.Name, occ.Format(time.RFC3339))
+				occ = spec.next(occ)
+				continue
+			}
+			if !sch.CatchUp && now.Sub(occ) > grace {
+				skipped++
+				occ = spec.next(occ)
+				continue
+			}
+			var override *ScheduleTaskOverride
+			if ex != nil {
+				override = ex.Task
+			}
+			if err := s.createScheduledTask(ctx, sch, occ, cal, override); err != nil {
+				if ctx.Err() != nil {
+					return created, ctx.Err()
+				}
+				// Запуск не продвигаем: попробуем снова на следующей проверке.
+				log.Printf("schedules: schedule=%d run=%s error: %v", sch.ID, occ.Format(time.RFC3339), err)
+				break
+			}
+			ran := occ.UTC()
+			last = &ran
+			n++
+			occ = spec.next(occ)
+		}
+		if skipped > 0 {
+			log.Printf("schedules: schedule=%d %q skipped %d missed run(s) (catch_up is off)", sch.ID, sch.Name, skipped)
+		}
+
+		var next *time.Time
+		if !occ.IsZero() {
+			u := occ.UTC()
+			next = &u
+		}
+		if err := ss.SetScheduleRun(ctx, sch.ID, last, next); err != nil {
+			return created, err
+		}
+		created += n
+	}
+	return created, nil
+}
+
+// createScheduledTask создаёт задачу по шаблону расписания для запуска at; override (может быть nil) --
+// правка этого запуска из исключения. cal нужен только для due_in_business_days.
+// UUID задачи выводится из расписания и момента запуска: если сервер упал после создания
+// задачи, но до записи NextRunAt, повторная попытка упрётся в ErrDuplicateUUID, а не создаст дубль.
+func (s *Service) createScheduledTask(ctx context.Context, sch Schedule, at time.Time, cal *Calendar, override *ScheduleTaskOverride) error {
+	tpl := sch.Task
+	t := &Task{
+		UserID:      sch.CreatedBy,
+		AssignedTo:  tpl.AssignedTo,
+		Title:       tpl.Title,
+		Description: tpl.Description,
+		Priority:    tpl.Priority,
+		Tags:        slices.Clone(tpl.Tags),
+		Status:      StatusTodo,
+		UUID:        fmt.Sprintf("schedule:%d:%d", sch.ID, at.Unix()),
+	}
+	if t.AssignedTo == 0 {
+		t.AssignedTo = sch.CreatedBy
+	}
+	if t.Priority == "" {
+		prefs, err := s.GetPreferences(ctx, sch.CreatedBy)
+		if err != nil {
+			return err
+		}
+		t.Priority = prefs.DefaultPriority
+	}
+	if tpl.DueInHours != nil {
+		due := at.Add(time.Duration(*tpl.DueInHours) * time.Hour).UTC()
+		t.Due = &due
+	}
+	if tpl.DueInBusinessDays != nil {
+		due := cal.DueInBusinessDays(at, *tpl.DueInBusinessDays)
+		t.Due = &due
+	}
+	if o := override; o != nil {
+		if o.Title != "" {
+			t.Title = o.Title
+		}
+		if o.Description != "" {
+			t.Description = o.Description
+		}
+		if o.Priority != "" {
+			t.Priority = o.Priority
+		}
+		if o.AssignedTo != 0 {
+			t.AssignedTo = o.AssignedTo
+		}
+		if o.Due != nil {
+			due := o.Due.UTC()
+			t.Due = &due
+		}
+	}
+
+	err := s.CreateTask(ctx, t)
+	if errors.Is(err, ErrDuplicateUUID) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("schedules: schedule=%d %q created task=%d run=%s", sch.ID, sch.Name, t.ID, at.Format(time.RFC3339))
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// schedulesFile -- расписания JSON-хранилища, рядом с файлом задач.
+type schedulesFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []Schedule
+	lastID int
+}
+
+func (ts *TaskStore) schedulesPath() string {
+	return ts.filename + ".schedules.json"
+}
+
+func (ts *TaskStore) loadSchedules() error {
+	ts.schedules.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.schedulesPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.schedules.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.schedules.data); err != nil {
+			ts.schedules.err = fmt.Errorf("parse %s: %w", ts.schedulesPath(), err)
+			return
+		}
+		for _, sch := range ts.schedules.data {
+			ts.schedules.lastID = max(ts.schedules.lastID, sch.ID)
+		}
+	})
+	return ts.schedules.err
+}
+
+// saveSchedules переписывает файл расписаний целиком. Вызывающий держит schedules.mu.
+func (ts *TaskStore) saveSchedules() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.schedules.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.schedulesPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.schedulesPath())
+}
+
+// withSchedules выполняет fn под блокировкой расписаний и сохраняет файл; при ошибке записи
+// список возвращается к прежнему.
+func (ts *TaskStore) withSchedules(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadSchedules(); err != nil {
+		return err
+	}
+	ts.schedules.mu.Lock()
+	defer ts.schedules.mu.Unlock()
+
+	prev := slices.Clone(ts.schedules.data)
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := ts.saveSchedules(); err != nil {
+		ts.schedules.data = prev
+		return err
+	}
+	return nil
+}
+
+// scheduleIndex ищет расписание по ID. Вызывающий держит schedules.mu.
+func (ts *TaskStore) scheduleIndex(id int) int {
+	return slices.IndexFunc(ts.schedules.data, func(sch Schedule) bool { return sch.ID == id })
+}
+
+// CreateSchedule сохраняет расписание и выдаёт ему ID.
+func (ts *TaskStore) CreateSchedule(ctx context.Context, sch *Schedule) error {
+	return ts.withSchedules(ctx, func() error {
+		sch.ID = ts.schedules.lastID + 1
+		ts.schedules.data = append(ts.schedules.data, *sch)
+		ts.schedules.lastID = sch.ID
+		return nil
+	})
+}
+
+// UpdateSchedule заменяет расписание с тем же ID.
+func (ts *TaskStore) UpdateSchedule(ctx context.Context, sch *Schedule) error {
+	return ts.withSchedules(ctx, func() error {
+		i := ts.scheduleIndex(sch.ID)
+		if i < 0 {
+			return ErrScheduleNotFound
+		}
+		ts.schedules.data[i] = *sch
+		return nil
+	})
+}
+
+// DeleteSchedule удаляет расписание по ID.
+func (ts *TaskStore) DeleteSchedule(ctx context.Context, id int) error {
+	return ts.withSchedules(ctx, func() error {
+		i := ts.scheduleIndex(id)
+		if i < 0 {
+			return ErrScheduleNotFound
+		}
+		ts.schedules.data = slices.Delete(ts.schedules.data, i, i+1)
+		return nil
+	})
+}
+
+// Schedules возвращает все расписания.
+func (ts *TaskStore) Schedules(ctx context.Context) ([]Schedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadSchedules(); err != nil {
+		return nil, err
+	}
+	ts.schedules.mu.Lock()
+	defer ts.schedules.mu.Unlock()
+
+	out := make([]Schedule, len(ts.schedules.data))
+	for i, sch := range ts.schedules.data {
+		sch.Task.Tags = slices.Clone(sch.Task.Tags)
+		sch.Exceptions = slices.Clone(sch.Exceptions)
+		out[i] = sch
+	}
+	return out, nil
+}
+
+// SetScheduleRun запоминает последний выполненный и следующий запуск.
+func (ts *TaskStore) SetScheduleRun(ctx context.Context, id int, last, next *time.Time) error {
+	return ts.withSchedules(ctx, func() error {
+		i := ts.scheduleIndex(id)
+		if i < 0 {
+			return ErrScheduleNotFound
+		}
+		ts.schedules.data[i].LastRunAt = last
+		ts.schedules.data[i].NextRunAt = next
+		return nil
+	})
+}
+
+// SetScheduleExceptions заменяет исключения расписания.
+func (ts *TaskStore) SetScheduleExceptions(ctx context.Context, id int, list []ScheduleException) error {
+	return ts.withSchedules(ctx, func() error {
+		i := ts.scheduleIndex(id)
+		if i < 0 {
+			return ErrScheduleNotFound
+		}
+		ts.schedules.data[i].Exceptions = slices.Clone(list)
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// CreateSchedule сохраняет расписание в schedules (migrations/000017_schedules.up.sql).
+func (r *PostgresRepository) CreateSchedule(ctx context.Context, sch *Schedule) error {
+	tpl, err := json.Marshal(sch.Task)
+	if err != nil {
+		return err
+	}
+	exceptions, err := scheduleExceptionsJSON(sch.Exceptions)
+	if err != nil {
+		return err
+	}
+	return r.q.QueryRowContext(ctx, `INSERT INTO schedules (name, cron, timezone, enabled, catch_up, task,
+		created_by, created_at, updated_at, last_run_at, next_run_at, workdays_only, exceptions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		sch.Name, sch.Cron, sch.Timezone, sch.Enabled, sch.CatchUp, tpl,
+		sch.CreatedBy, sch.CreatedAt, sch.UpdatedAt, nullTime(sch.LastRunAt), nullTime(sch.NextRunAt), sch.WorkdaysOnly,
+		exceptions).Scan(&sch.ID)
+}
+
+// UpdateSchedule заменяет расписание с тем же ID.
+func (r *PostgresRepository) UpdateSchedule(ctx context.Context, sch *Schedule) error {
+	tpl, err := json.Marshal(sch.Task)
+	if err != nil {
+		return err
+	}
+	exceptions, err := scheduleExceptionsJSON(sch.Exceptions)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, `UPDATE schedules SET name = $1, cron = $2, timezone = $3, enabled = $4,
+		catch_up = $5, task = $6, updated_at = $7, last_run_at = $8, next_run_at = $9, workdays_only = $10,
+		exceptions = $11 WHERE id = $12`,
+		sch.Name, sch.Cron, sch.Timezone, sch.Enabled, sch.CatchUp, tpl, sch.UpdatedAt,
+		nullTime(sch.LastRunAt), nullTime(sch.NextRunAt), sch.WorkdaysOnly, exceptions, sch.ID)
+	return scheduleAffected(res, err)
+}
+
+// DeleteSchedule удаляет расписание по ID.
+func (r *PostgresRepository) DeleteSchedule(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM schedules WHERE id = $1", id)
+	return scheduleAffected(res, err)
+}
+
+// Schedules возвращает все расписания.
+func (r *PostgresRepository) Schedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT id, name, cron, timezone, enabled, catch_up, task,
+		created_by, created_at, updated_at, last_run_at, next_run_at, workdays_only, exceptions FROM schedules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Schedule{}
+	for rows.Next() {
+		var sch Schedule
+		var tpl, exceptions []byte
+		var last, next sql.NullTime
+		if err := rows.Scan(&sch.ID, &sch.Name, &sch.Cron, &sch.Timezone, &sch.Enabled, &sch.CatchUp, &tpl,
+			&sch.CreatedBy, &sch.CreatedAt, &sch.UpdatedAt, &last, &next, &sch.WorkdaysOnly, &exceptions); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(tpl, &sch.Task); err != nil {
+			return nil, fmt.Errorf("schedule %d task: %w", sch.ID, err)
+		}
+		if err := json.Unmarshal(exceptions, &sch.Exceptions); err != nil {
+			return nil, fmt.Errorf("schedule %d exceptions: %w", sch.ID, err)
+		}
+		if last.Valid {
+			sch.LastRunAt = &last.Time
+		}
+		if next.Valid {
+			sch.NextRunAt = &next.Time
+		}
+		out = append(out, sch)
+	}
+	return out, rows.Err()
+}
+
+// SetScheduleRun запоминает последний выполненный и следующий запуск.
+func (r *PostgresRepository) SetScheduleRun(ctx context.Context, id int, last, next *time.Time) error {
+	res, err := r.q.ExecContext(ctx, "UPDATE schedules SET last_run_at = $1, next_run_at = $2 WHERE id = $3",
+		nullTime(last), nullTime(next), id)
+	return scheduleAffected(res, err)
+}
+
+// SetScheduleExceptions заменяет исключения расписания (migrations/000032_schedule_exceptions.up.sql).
+func (r *PostgresRepository) SetScheduleExceptions(ctx context.Context, id int, list []ScheduleException) error {
+	exceptions, err := scheduleExceptionsJSON(list)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, "UPDATE schedules SET exceptions = $1 WHERE id = $2", exceptions, id)
+	return scheduleAffected(res, err)
+}
+
+// scheduleExceptionsJSON кодирует исключения для колонки exceptions; пустой список -- [], а не null.
+func scheduleExceptionsJSON(list []ScheduleException) ([]byte, error) {
+	if list == nil {
+		list = []ScheduleException{}
+	}
+	return json.Marshal(list)
+}
+
+func scheduleAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// TestScheduleExceptions -- пропущенный запуск не создаёт задачу, изменённый создаёт её с правками,
+// остальные запуски идут по шаблону.
+func TestScheduleExceptions(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := taskstest.NewServer(store, "")
+	svc := srv.Service
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	sch, err := svc.CreateSchedule(ctx, 1, tasks.ScheduleRequest{
+		Name: "Обзор", Cron: "0 20 * * *", Timezone: "UTC", CatchUp: true,
+		Task: tasks.ScheduleTask{Title: "Обзор дня"},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(day int) time.Time { return time.Date(2024, 1, day, 20, 0, 0, 0, time.UTC) }
+
+	if _, err := svc.SetScheduleException(ctx, 1, sch.ID, run(15), tasks.ScheduleExceptionRequest{Skip: true}, now); err != nil {
+		t.Fatal(err)
+	}
+	override := &tasks.ScheduleTaskOverride{Title: "Обзор недели", Priority: "high"}
+	if _, err := svc.SetScheduleException(ctx, 1, sch.ID, run(16), tasks.ScheduleExceptionRequest{Task: override}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SetScheduleException(ctx, 1, sch.ID, run(16).Add(time.Hour), tasks.ScheduleExceptionRequest{Skip: true}, now); !errors.Is(err, tasks.ErrScheduleNoRun) {
+		t.Errorf("exception off the cron: %v, want ErrScheduleNoRun", err)
+	}
+	if _, err := svc.SetScheduleException(ctx, 1, sch.ID, run(14), tasks.ScheduleExceptionRequest{Skip: true}, now); !errors.Is(err, tasks.ErrScheduleNoRun) {
+		t.Errorf("exception in the past: %v, want ErrScheduleNoRun", err)
+	}
+
+	occ, err := svc.ScheduleOccurrences(ctx, 1, sch.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occ) != 3 || occ[0].Exception == nil || !occ[0].Exception.Skip || occ[1].Exception == nil || occ[2].Exception != nil {
+		t.Errorf("occurrences: %+v", occ)
+	}
+
+	before, err := svc.GetAllTasks(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := svc.EvaluateSchedules(ctx, run(17).Add(time.Minute), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("created %d tasks, want 2 (15th skipped)", n)
+	}
+	after, err := svc.GetAllTasks(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := map[string]tasks.Priority{}
+	for _, task := range after[len(before):] {
+		titles[task.Title] = task.Priority
+	}
+	if p, ok := titles["Обзор недели"]; !ok || p != "high" {
+		t.Errorf("overridden run: %v", titles)
+	}
+	if _, ok := titles["Обзор дня"]; !ok {
+		t.Errorf("regular run: %v", titles)
+	}
+}
+
+// TestScheduleExceptionHandlers -- {at} разбирается как RFC 3339, момент вне расписания -- 400.
+func TestScheduleExceptionHandlers(t *testing.T) {
+	ctx := context.Background()
+	store, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := taskstest.NewServer(store, "")
+	token, err := srv.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sch tasks.Schedule
+	body := map[string]any{"name": "Обзор", "cron": "0 20 * * *", "timezone": "UTC", "task": map[string]any{"title": "Обзор дня"}}
+	if code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/schedules", token, body, &sch); err != nil || code != http.StatusCreated {
+		t.Fatalf("create schedule: %d %v", code, err)
+	}
+	next := sch.NextRunAt.UTC()
+
+	for _, c := range []struct {
+		at   string
+		body map[string]any
+		want int
+	}{
+		{"tomorrow", map[string]any{"skip": true}, http.StatusBadRequest},
+		{next.Add(time.Minute).Format(time.RFC3339), map[string]any{"skip": true}, http.StatusBadRequest},
+		{next.Format(time.RFC3339), map[string]any{}, http.StatusBadRequest},
+		{next.Format(time.RFC3339), map[string]any{"skip": true, "task": map[string]any{"title": "x"}}, http.StatusBadRequest},
+		{next.Format(time.RFC3339), map[string]any{"skip": true}, http.StatusOK},
+	} {
+		code, err := srv.DoJSON(ctx, http.MethodPut, "/api/v1/schedules/"+strconv.Itoa(sch.ID)+"/exceptions/"+c.at, token, c.body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != c.want {
+			t.Errorf("PUT exception %s %v: %d, want %d", c.at, c.body, code, c.want)
+		}
+	}
+}
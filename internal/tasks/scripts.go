@@ -0,0 +1,695 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/script"
+)
+
+// События, к которым привязываются скрипты. Скрипты выполняются как Before-хуки (см. hooks.go):
+// после хуков, вкомпилированных в сервер.
+const (
+	ScriptBeforeCreate = "before_create"
+	ScriptBeforeUpdate = "before_update"
+	ScriptBeforeDelete = "before_delete"
+)
+
+var (
+	// ErrScriptsUnsupported -- хранилище не умеет хранить скрипты.
+	ErrScriptsUnsupported = errors.New("scripts are not supported by this storage")
+	// ErrScriptNotFound -- скрипта нет.
+	ErrScriptNotFound = errors.New("script not found")
+	// ErrScriptSyntax -- скрипт не разобран; подробности (строка, столбец) -- в *script.Error.
+	ErrScriptSyntax = errors.New("script does not compile")
+)
+
+// Script -- скрипт на подмножестве Starlark (см. internal/script), привязанный к событию жизненного
+// цикла задачи. Настраивается администратором, один набор на сервер.
+//
+//	if "срочно" in task.title.lower():
+//	    task.priority = "high"
+//	    task.tags.append("urgent")
+type Script struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Event       string     `json:"event"`
+	Source      string     `json:"source"`
+	Enabled     bool       `json:"enabled"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastError   string     `json:"last_error,omitempty"` // последняя ошибка выполнения; сбрасывается при изменении
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ScriptRequest -- тело POST /api/v1/admin/scripts и PUT /api/v1/admin/scripts/{script_id}.
+type ScriptRequest struct {
+	Name    string `json:"name" validate:"required,max=100"`
+	Event   string `json:"event" validate:"required,oneof=before_create before_update before_delete"`
+	Source  string `json:"source" validate:"required,max=20000"`
+	Enabled *bool  `json:"enabled"` // по умолчанию true
+}
+
+// ScriptStore -- опциональная возможность хранилища хранить скрипты.
+type ScriptStore interface {
+	CreateScript(ctx context.Context, sc *Script) error
+	UpdateScript(ctx context.Context, sc *Script) error
+	DeleteScript(ctx context.Context, id int) error
+	// Scripts возвращает все скрипты по возрастанию ID.
+	Scripts(ctx context.Context) ([]Script, error)
+	// SetScriptError запоминает последнюю ошибку выполнения скрипта.
+	SetScriptError(ctx context.Context, id int, msg string, at time.Time) error
+}
+
+func (s *Service) scripts() (ScriptStore, error) {
+	ss, ok := s.capabilities().(ScriptStore)
+	if !ok {
+		return nil, ErrScriptsUnsupported
+	}
+	return ss, nil
+}
+
+// SetScriptLimits задаёт ограничения одного запуска скрипта (нулевые поля -- значения по умолчанию).
+func (s *Service) SetScriptLimits(l script.Limits) {
+	s.scriptLimits = l
+}
+
+// compiledScripts -- кэш разобранных скриптов: ключ -- ID, версия -- UpdatedAt.
+type compiledScripts struct {
+	mu    sync.Mutex
+	progs map[int]compiledScript
+}
+
+type compiledScript struct {
+	updated time.Time
+	prog    *script.Program
+}
+
+// program возвращает разобранный скрипт, разбирая его заново только после изменения.
+func (s *Service) program(sc Script) (*script.Program, error) {
+	s.compiled.mu.Lock()
+	defer s.compiled.mu.Unlock()
+	if c, ok := s.compiled.progs[sc.ID]; ok && c.updated.Equal(sc.UpdatedAt) {
+		return c.prog, nil
+	}
+	prog, err := script.Compile(sc.Name, sc.Source)
+	if err != nil {
+		return nil, err
+	}
+	if s.compiled.progs == nil {
+		s.compiled.progs = make(map[int]compiledScript)
+	}
+	s.compiled.progs[sc.ID] = compiledScript{updated: sc.UpdatedAt, prog: prog}
+	return prog, nil
+}
+
+// applyScriptRequest переносит запрос в скрипт, проверяя, что он разбирается.
+func applyScriptRequest(sc *Script, req ScriptRequest, now time.Time) error {
+	if _, err := script.Compile(req.Name, req.Source); err != nil {
+		return fmt.Errorf("%w: %w", ErrScriptSyntax, err)
+	}
+	sc.Name, sc.Event, sc.Source = req.Name, req.Event, req.Source
+	sc.Enabled = req.Enabled == nil || *req.Enabled
+	sc.LastError, sc.LastErrorAt = "", nil
+	sc.UpdatedAt = now.UTC()
+	return nil
+}
+
+// CreateScript создаёт скрипт.
+func (s *Service) CreateScript(ctx context.Context, req ScriptRequest, now time.Time) (Script, error) {
+	if err := ctx.Err(); err != nil {
+		return Script{}, err
+	}
+	store, err := s.scripts()
+	if err != nil {
+		return Script{}, err
+	}
+	sc := Script{CreatedAt: now.UTC()}
+	if err := applyScriptRequest(&sc, req, now); err != nil {
+		return Script{}, err
+	}
+	if err := store.CreateScript(ctx, &sc); err != nil {
+		return Script{}, err
+	}
+	return sc, nil
+}
+
+// Scripts возвращает все скрипты.
+func (s *Service) Scripts(ctx context.Context) ([]Script, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store, err := s.scripts()
+	if err != nil {
+		return nil, err
+	}
+	return store.Scripts(ctx)
+}
+
+// GetScript возвращает скрипт по ID.
+func (s *Service) GetScript(ctx context.Context, id int) (Script, error) {
+	list, err := s.Scripts(ctx)
+	if err != nil {
+		return Script{}, err
+	}
+	for _, sc := range list {
+		if sc.ID == id {
+			return sc, nil
+		}
+	}
+	return Script{}, ErrScriptNotFound
+}
+
+// UpdateScript заменяет скрипт целиком; последняя ошибка сбрасывается.
+func (s *Service) UpdateScript(ctx context.Context, id int, req ScriptRequest, now time.Time) (Script, error) {
+	sc, err := s.GetScript(ctx, id)
+	if err != nil {
+		return Script{}, err
+	}
+	store, err := s.scripts()
+	if err != nil {
+		return Script{}, err
+	}
+	if err := applyScriptRequest(&sc, req, now); err != nil {
+		return Script{}, err
+	}
+	if err := store.UpdateScript(ctx, &sc); err != nil {
+		return Script{}, err
+	}
+	return sc, nil
+}
+
+// DeleteScript удаляет скрипт.
+func (s *Service) DeleteScript(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store, err := s.scripts()
+	if err != nil {
+		return err
+	}
+	return store.DeleteScript(ctx, id)
+}
+
+// scriptReject -- скрипт отменил операцию вызовом reject(сообщение).
+type scriptReject struct{ msg string }
+
+func (e *scriptReject) Error() string { return e.msg }
+
+var rejectBuiltin = script.NewBuiltin("reject", func(args []script.Value) (script.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("reject() takes 1 argument, got %d", len(args))
+	}
+	return nil, &scriptReject{msg: script.String(args[0])}
+})
+
+// ScriptRun -- результат одного запуска скрипта.
+type ScriptRun struct {
+	Task     *Task    `json:"task,omitempty"`     // задача после скрипта (для before_delete -- без изменений)
+	Rejected string   `json:"rejected,omitempty"` // сообщение reject(); пусто -- операция разрешена
+	Error    string   `json:"error,omitempty"`    // ошибка выполнения; изменения скрипта в этом случае отбрасываются
+	Output   []string `json:"output,omitempty"`   // строки print()
+}
+
+// execScript выполняет скрипт над задачей t (old -- прежняя версия для before_update).
+// Изменения попадают в t, только если скрипт завершился без ошибок и задача осталась корректной.
+func (s *Service) execScript(ctx context.Context, prog *script.Program, event string, old, t *Task, print func(string)) error {
+	obj := scriptTask(t, event != ScriptBeforeDelete)
+	globals := map[string]script.Value{"task": obj, "event": event, "reject": rejectBuiltin}
+	if old != nil {
+		globals["old"] = scriptTask(old, false)
+	}
+	if err := prog.Run(ctx, globals, script.Options{Limits: s.scriptLimits, Print: print}); err != nil {
+		var rej *scriptReject
+		if errors.As(err, &rej) {
+			return rej
+		}
+		return err
+	}
+	if event == ScriptBeforeDelete {
+		return nil
+	}
+	next := *t
+	if err := taskFromScript(obj, &next); err != nil {
+		return err
+	}
+	// UUID скрипт не меняет, а у импортированных задач он бывает не в формате клиентских.
+	check := next
+	check.UUID = ""
+	if err := ValidateTask(&check); err != nil {
+		return fmt.Errorf("script produced an invalid task: %w", err)
+	}
+	*t = next
+	return nil
+}
+
+// runScripts выполняет включённые скрипты события по возрастанию ID. reject() отменяет операцию
+// (*HookError, клиент получает 422). Ошибка самого скрипта -- исключение, превышение ограничений,
+// некорректная задача на выходе -- операцию не отменяет: изменения этого скрипта отбрасываются,
+// ошибка пишется в лог и в last_error скрипта.
+func (s *Service) runScripts(ctx context.Context, event string, old, t *Task) error {
+	store, ok := s.capabilities().(ScriptStore)
+	if !ok {
+		return nil
+	}
+	list, err := store.Scripts(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("request_id=%s scripts load error: %v", appMiddleware.GetRequestID(ctx), err)
+		return nil
+	}
+	for _, sc := range list {
+		if !sc.Enabled || sc.Event != event {
+			continue
+		}
+		prog, err := s.program(sc)
+		if err == nil {
+			err = s.execScript(ctx, prog, event, old, t, func(msg string) {
+				log.Printf("request_id=%s script %q task=%d: %s", appMiddleware.GetRequestID(ctx), sc.Name, t.ID, msg)
+			})
+		}
+		var rej *scriptReject
+		if errors.As(err, &rej) {
+			return &HookError{Hook: "script:" + sc.Name, Stage: event, Err: rej}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.scriptFailed(ctx, store, sc, t.ID, err)
+		}
+	}
+	return nil
+}
+
+// hasScripts -- есть ли включённые скрипты события. Ошибку чтения покажет сам runScripts.
+func (s *Service) hasScripts(ctx context.Context, event string) bool {
+	store, ok := s.capabilities().(ScriptStore)
+	if !ok {
+		return false
+	}
+	list, err := store.Scripts(ctx)
+	if err != nil {
+		return true
+	}
+	return slices.ContainsFunc(list, func(sc Script) bool { return sc.Enabled && sc.Event == event })
+}
+
+// scriptFailed пишет ошибку скрипта в лог и запоминает её (кроме пробного запуска: он ничего не сохраняет).
+func (s *Service) scriptFailed(ctx context.Context, store ScriptStore, sc Script, taskID int, err error) {
+	log.Printf("request_id=%s script %q (%s) task=%d error: %v", appMiddleware.GetRequestID(ctx), sc.Name, sc.Event, taskID, err)
+	if IsDryRun(ctx) {
+		return
+	}
+	if err := store.SetScriptError(ctx, sc.ID, err.Error(), time.Now()); err != nil {
+		log.Printf("request_id=%s script %q: save last error: %v", appMiddleware.GetRequestID(ctx), sc.Name, err)
+	}
+}
+
+// TestScript выполняет скрипт над задачей, ничего не сохраняя, -- для проверки перед включением.
+// Ошибки разбора возвращаются как ErrScriptSyntax, ошибки выполнения -- в ScriptRun.Error.
+func (s *Service) TestScript(ctx context.Context, req ScriptRequest, old, t Task) (ScriptRun, error) {
+	prog, err := script.Compile(req.Name, req.Source)
+	if err != nil {
+		return ScriptRun{}, fmt.Errorf("%w: %w", ErrScriptSyntax, err)
+	}
+	var run ScriptRun
+	var oldp *Task
+	if req.Event == ScriptBeforeUpdate {
+		oldp = &old
+	}
+	err = s.execScript(ctx, prog, req.Event, oldp, &t, func(msg string) { run.Output = append(run.Output, msg) })
+	var rej *scriptReject
+	switch {
+	case errors.As(err, &rej):
+		run.Rejected = rej.msg
+	case err != nil:
+		if ctx.Err() != nil {
+			return ScriptRun{}, ctx.Err()
+		}
+		run.Error = err.Error()
+	}
+	run.Task = &t
+	return run, nil
+}
+
+// ---------------------------------------------------------------------------
+// Задача в скрипте
+// ---------------------------------------------------------------------------
+
+// scriptTask -- задача как объект скрипта. writable -- скрипт может менять title, description,
+// status, priority, done, tags, assigned_to, due и estimate_minutes; id, uuid и user_id -- только читать.
+// Срок -- строка RFC 3339 или None; присвоить можно и дату "YYYY-MM-DD".
+func scriptTask(t *Task, writable bool) *script.Object {
+	o := script.NewObject("task")
+	set := func(name string, v script.Value, check func(script.Value) error) {
+		if writable {
+			o.SetWritable(name, v, check)
+		} else {
+			o.Set(name, v)
+		}
+	}
+	o.Set("id", int64(t.ID))
+	o.Set("uuid", t.UUID)
+	o.Set("user_id", int64(t.UserID))
+	set("title", t.Title, scriptString)
+	set("description", t.Description, scriptString)
+	set("status", t.Status, scriptString)
+	set("priority", string(t.Priority), scriptString)
+	set("done", t.Done, scriptBool)
+	set("assigned_to", int64(t.AssignedTo), scriptInt)
+	set("estimate_minutes", int64(t.EstimateMinutes), scriptInt)
+	tags := make([]script.Value, len(t.Tags))
+	for i, tag := range t.Tags {
+		tags[i] = tag
+	}
+	set("tags", script.NewList(tags...), scriptStringList)
+	var due script.Value
+	if t.Due != nil {
+		due = t.Due.Format(time.RFC3339)
+	}
+	set("due", due, scriptDue)
+	return o
+}
+
+// taskFromScript переносит в t значения объекта после скрипта.
+func taskFromScript(o *script.Object, t *Task) error {
+	t.Title = o.Get("title").(string)
+	t.Description = o.Get("description").(string)
+	t.Status = o.Get("status").(string)
+	t.Priority = Priority(o.Get("priority").(string))
+	t.Done = o.Get("done").(bool)
+	t.AssignedTo = int(o.Get("assigned_to").(int64))
+	t.EstimateMinutes = int(o.Get("estimate_minutes").(int64))
+
+	// Список меток скрипт мог дополнить чем угодно через append -- проверяем ещё раз.
+	if err := scriptStringList(o.Get("tags")); err != nil {
+		return fmt.Errorf("task.tags: %w", err)
+	}
+	elems := o.Get("tags").(*script.List).Elems
+	t.Tags = nil
+	if len(elems) > 0 {
+		t.Tags = make([]string, len(elems))
+		for i, e := range elems {
+			t.Tags[i] = e.(string)
+		}
+	}
+
+	var was script.Value
+	if t.Due != nil {
+		was = t.Due.Format(time.RFC3339)
+	}
+	switch due := o.Get("due").(type) {
+	case nil:
+		t.Due = nil
+	case string:
+		if due != was { // не меняли -- оставляем исходное время с точностью до наносекунд
+			d, err := parseFlatDate(due)
+			if err != nil {
+				return fmt.Errorf("task.due: want RFC 3339 or YYYY-MM-DD, got %q", due)
+			}
+			t.Due = &d
+		}
+	}
+	return nil
+}
+
+func scriptString(v script.Value) error {
+	if _, ok := v.(string); !ok {
+		return fmt.Errorf("want string, got %s", script.TypeName(v))
+	}
+	return nil
+}
+
+func scriptBool(v script.Value) error {
+	if _, ok := v.(bool); !ok {
+		return fmt.Errorf("want bool, got %s", script.TypeName(v))
+	}
+	return nil
+}
+
+func scriptInt(v script.Value) error {
+	n, ok := v.(int64)
+	if !ok {
+		return fmt.Errorf("want int, got %s", script.TypeName(v))
+	}
+	if n < 0 || n > 1<<31-1 {
+		return fmt.Errorf("value %d out of range", n)
+	}
+	return nil
+}
+
+func scriptStringList(v script.Value) error {
+	l, ok := v.(*script.List)
+	if !ok {
+		return fmt.Errorf("want list, got %s", script.TypeName(v))
+	}
+	for _, e := range l.Elems {
+		if _, ok := e.(string); !ok {
+			return fmt.Errorf("want list of strings, got %s element", script.TypeName(e))
+		}
+	}
+	return nil
+}
+
+func scriptDue(v script.Value) error {
+	switch due := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if _, err := parseFlatDate(due); err != nil {
+			return fmt.Errorf("want RFC 3339 or YYYY-MM-DD, got %q", due)
+		}
+		return nil
+	}
+	return fmt.Errorf("want string or None, got %s", script.TypeName(v))
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// scriptsFile -- скрипты JSON-хранилища, рядом с файлом задач.
+type scriptsFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []Script
+	lastID int
+}
+
+func (ts *TaskStore) scriptsPath() string {
+	return ts.filename + ".scripts.json"
+}
+
+func (ts *TaskStore) loadScripts() error {
+	ts.scripts.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.scriptsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.scripts.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.scripts.data); err != nil {
+			ts.scripts.err = fmt.Errorf("parse %s: %w", ts.scriptsPath(), err)
+			return
+		}
+		for _, sc := range ts.scripts.data {
+			ts.scripts.lastID = max(ts.scripts.lastID, sc.ID)
+		}
+	})
+	return ts.scripts.err
+}
+
+// withScripts выполняет fn под блокировкой и сохраняет файл; при ошибке записи список возвращается к прежнему.
+func (ts *TaskStore) withScripts(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadScripts(); err != nil {
+		return err
+	}
+	ts.scripts.mu.Lock()
+	defer ts.scripts.mu.Unlock()
+
+	prev := slices.Clone(ts.scripts.data)
+	if err := fn(); err != nil {
+		return err
+	}
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.scripts.data, "", "   ")
+	if err == nil {
+		tmp := ts.scriptsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0600); err == nil {
+			err = os.Rename(tmp, ts.scriptsPath())
+		}
+	}
+	if err != nil {
+		ts.scripts.data = prev
+	}
+	return err
+}
+
+// scriptIndex ищет скрипт по ID. Вызывающий держит scripts.mu.
+func (ts *TaskStore) scriptIndex(id int) int {
+	return slices.IndexFunc(ts.scripts.data, func(sc Script) bool { return sc.ID == id })
+}
+
+// CreateScript сохраняет скрипт и выдаёт ему ID.
+func (ts *TaskStore) CreateScript(ctx context.Context, sc *Script) error {
+	return ts.withScripts(ctx, func() error {
+		sc.ID = ts.scripts.lastID + 1
+		ts.scripts.data = append(ts.scripts.data, *sc)
+		ts.scripts.lastID = sc.ID
+		return nil
+	})
+}
+
+// UpdateScript заменяет скрипт с тем же ID.
+func (ts *TaskStore) UpdateScript(ctx context.Context, sc *Script) error {
+	return ts.withScripts(ctx, func() error {
+		i := ts.scriptIndex(sc.ID)
+		if i < 0 {
+			return ErrScriptNotFound
+		}
+		ts.scripts.data[i] = *sc
+		return nil
+	})
+}
+
+// DeleteScript удаляет скрипт по ID.
+func (ts *TaskStore) DeleteScript(ctx context.Context, id int) error {
+	return ts.withScripts(ctx, func() error {
+		i := ts.scriptIndex(id)
+		if i < 0 {
+			return ErrScriptNotFound
+		}
+		ts.scripts.data = slices.Delete(ts.scripts.data, i, i+1)
+		return nil
+	})
+}
+
+// Scripts возвращает все скрипты.
+func (ts *TaskStore) Scripts(ctx context.Context) ([]Script, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadScripts(); err != nil {
+		return nil, err
+	}
+	ts.scripts.mu.Lock()
+	defer ts.scripts.mu.Unlock()
+	return slices.Clone(ts.scripts.data), nil
+}
+
+// SetScriptError запоминает последнюю ошибку выполнения скрипта.
+func (ts *TaskStore) SetScriptError(ctx context.Context, id int, msg string, at time.Time) error {
+	return ts.withScripts(ctx, func() error {
+		i := ts.scriptIndex(id)
+		if i < 0 {
+			return ErrScriptNotFound
+		}
+		at = at.UTC()
+		ts.scripts.data[i].LastError = msg
+		ts.scripts.data[i].LastErrorAt = &at
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// CreateScript сохраняет скрипт в scripts (migrations/000021_scripts.up.sql).
+// Скрипт лежит в data как JSON, последняя ошибка -- в отдельных колонках.
+func (r *PostgresRepository) CreateScript(ctx context.Context, sc *Script) error {
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return r.q.QueryRowContext(ctx, `INSERT INTO scripts (data, last_error, last_error_at) VALUES ($1, $2, $3) RETURNING id`,
+		raw, sc.LastError, nullTime(sc.LastErrorAt)).Scan(&sc.ID)
+}
+
+// UpdateScript заменяет скрипт с тем же ID.
+func (r *PostgresRepository) UpdateScript(ctx context.Context, sc *Script) error {
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	res, err := r.q.ExecContext(ctx, `UPDATE scripts SET data = $1, last_error = $2, last_error_at = $3 WHERE id = $4`,
+		raw, sc.LastError, nullTime(sc.LastErrorAt), sc.ID)
+	return scriptAffected(res, err)
+}
+
+// DeleteScript удаляет скрипт по ID.
+func (r *PostgresRepository) DeleteScript(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM scripts WHERE id = $1", id)
+	return scriptAffected(res, err)
+}
+
+// Scripts возвращает все скрипты.
+func (r *PostgresRepository) Scripts(ctx context.Context) ([]Script, error) {
+	rows, err := r.q.QueryContext(ctx, "SELECT id, data, last_error, last_error_at FROM scripts ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Script{}
+	for rows.Next() {
+		var sc Script
+		var id int
+		var raw []byte
+		var lastErr string
+		var lastAt sql.NullTime
+		if err := rows.Scan(&id, &raw, &lastErr, &lastAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &sc); err != nil {
+			return nil, fmt.Errorf("script %d: %w", id, err)
+		}
+		sc.ID, sc.LastError, sc.LastErrorAt = id, lastErr, nil
+		if lastAt.Valid {
+			sc.LastErrorAt = &lastAt.Time
+		}
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+// SetScriptError запоминает последнюю ошибку выполнения скрипта.
+func (r *PostgresRepository) SetScriptError(ctx context.Context, id int, msg string, at time.Time) error {
+	res, err := r.q.ExecContext(ctx, "UPDATE scripts SET last_error = $1, last_error_at = $2 WHERE id = $3", msg, at, id)
+	return scriptAffected(res, err)
+}
+
+func scriptAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrScriptNotFound
+	}
+	return nil
+}
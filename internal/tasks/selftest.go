@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// StoreSelfTester -- опциональная возможность хранилища проверить запись и чтение,
+// не трогая пользовательские данные. Используется самопроверкой при старте.
+type StoreSelfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// ClockProvider -- опциональная возможность хранилища сообщить своё текущее время
+// (для PostgreSQL -- now() сервера БД). Используется для проверки расхождения часов.
+type ClockProvider interface {
+	Now(ctx context.Context) (time.Time, error)
+}
+
+// ErrClockUnavailable -- хранилище не умеет сообщать своё время.
+var ErrClockUnavailable = errors.New("storage does not provide a clock")
+
+// SelfTestStore проверяет хранилище: запись-чтение, если хранилище это умеет,
+// иначе -- хотя бы чтение всех задач.
+func (s *Service) SelfTestStore(ctx context.Context) error {
+	if st, ok := s.capabilities().(StoreSelfTester); ok {
+		return st.SelfTest(ctx)
+	}
+	_, err := s.repo.GetAll(ctx, 0)
+	return err
+}
+
+// StoreTime возвращает текущее время хранилища или ErrClockUnavailable.
+func (s *Service) StoreTime(ctx context.Context) (time.Time, error) {
+	cp, ok := s.capabilities().(ClockProvider)
+	if !ok {
+		return time.Time{}, ErrClockUnavailable
+	}
+	return cp.Now(ctx)
+}
+
+// SelfTest проверяет, что файл задач читается и разбирается, а каталог рядом с ним доступен на запись:
+// пишет пробный файл <filename>.selftest, читает обратно и удаляет.
+func (ts *TaskStore) SelfTest(ctx context.Context) error {
+	// Разбираем файл потоком, задачи не собираем: на большом файле проверка не удваивает память.
+	if err := ts.scanTasks(ctx, func(*Task) error { return nil }); err != nil {
+		return fmt.Errorf("read %s: %w", ts.filename, err)
+	}
+
+	probe := ts.filename + ".selftest"
+	want := []byte("selftest " + strconv.FormatInt(time.Now().UnixNano(), 10))
+	if err := os.WriteFile(probe, want, 0644); err != nil {
+		return fmt.Errorf("write probe: %w", err)
+	}
+	defer os.Remove(probe)
+
+	got, err := os.ReadFile(probe)
+	if err != nil {
+		return fmt.Errorf("read probe: %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("probe file content mismatch")
+	}
+	return nil
+}
+
+// SelfTest пишет и читает пробного пользователя внутри транзакции, которая всегда откатывается.
+func (r *PostgresRepository) SelfTest(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	name := "selftest-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	var id int
+	if err := tx.QueryRowContext(ctx,
+		"INSERT INTO users (username, password_hash) VALUES ($1, '') RETURNING id", name).Scan(&id); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	var got string
+	if err := tx.QueryRowContext(ctx, "SELECT username FROM users WHERE id = $1", id).Scan(&got); err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+	if got != name {
+		return errors.New("round-trip mismatch")
+	}
+	return nil
+}
+
+// Now возвращает время сервера БД.
+func (r *PostgresRepository) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := r.db.QueryRowContext(ctx, "SELECT now()").Scan(&now)
+	return now, err
+}
@@ -0,0 +1,106 @@
+package tasks_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// TestConcurrentCRUDAndRestart -- одновременные создание, изменение и удаление задач через API
+// на файловом хранилище, затем "перезапуск": новый сервер поверх того же файла видит то же самое.
+// Запускать с -race.
+func TestConcurrentCRUDAndRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	store := tasks.NewTaskStore(path)
+	srv := taskstest.NewServer(store, "")
+	token, err := srv.Token(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, perWorker = 8, 10
+	kept := make(chan int, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				var task tasks.Task
+				title := fmt.Sprintf("w%d-%d", w, i)
+				code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/tasks", token, map[string]any{"title": title}, &task)
+				if err != nil || code != http.StatusCreated {
+					t.Errorf("create %s: %d %v", title, code, err)
+					return
+				}
+				update := map[string]any{"title": title + "-upd", "priority": "high", "done": true}
+				if code, err := srv.DoJSON(ctx, http.MethodPut, fmt.Sprintf("/api/v1/tasks/%d", task.ID), token, update, nil); err != nil || code != http.StatusOK {
+					t.Errorf("update %d: %d %v", task.ID, code, err)
+					return
+				}
+				if i%2 == 0 {
+					if code, err := srv.DoJSON(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/tasks/%d", task.ID), token, nil, nil); err != nil || code != http.StatusNoContent {
+						t.Errorf("delete %d: %d %v", task.ID, code, err)
+					}
+					continue
+				}
+				kept <- task.ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(kept)
+	if t.Failed() {
+		t.FailNow()
+	}
+	want := make(map[int]bool)
+	for id := range kept {
+		if want[id] {
+			t.Fatalf("task ID %d issued twice", id)
+		}
+		want[id] = true
+	}
+
+	check := func(srv *taskstest.Server) {
+		t.Helper()
+		var list []tasks.Task
+		if code, err := srv.DoJSON(ctx, http.MethodGet, "/api/v1/tasks", token, nil, &list); err != nil || code != http.StatusOK {
+			t.Fatalf("list: %d %v", code, err)
+		}
+		if len(list) != len(want) {
+			t.Fatalf("got %d tasks, want %d", len(list), len(want))
+		}
+		for _, task := range list {
+			if !want[task.ID] || !task.Done || task.Priority != tasks.PriorityHigh || !strings.HasSuffix(task.Title, "-upd") {
+				t.Errorf("unexpected task %+v", task)
+			}
+		}
+	}
+	check(srv)
+
+	srv.Close()
+	if err := store.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	restarted := taskstest.NewServer(tasks.NewTaskStore(path), "")
+	defer restarted.Close()
+	check(restarted)
+
+	// Счётчик ID пережил перезапуск: новая задача не занимает ID удалённой или существующей.
+	var task tasks.Task
+	if code, err := restarted.DoJSON(ctx, http.MethodPost, "/api/v1/tasks", token, map[string]any{"title": "after restart"}, &task); err != nil || code != http.StatusCreated {
+		t.Fatalf("create after restart: %d %v", code, err)
+	}
+	if task.ID <= workers*perWorker {
+		t.Errorf("new task ID %d reuses an ID issued before restart", task.ID)
+	}
+}
@@ -4,13 +4,18 @@ package tasks
 import (
 	"context"
 	"errors"
-
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"os"
+	appMiddleware "task-manager/internal/middleware"
+	"task-manager/internal/script"
+	"task-manager/internal/secrets"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Service - слой бизнес-логики
@@ -18,6 +23,74 @@ import (
 // "протекание" контекста по слоям: handler -> service -> store
 type Service struct {
 	repo TaskRepository
+
+	// fieldDefs -- пользовательские поля задач (CUSTOM_FIELDS), см. fields.go
+	fieldDefs FieldDefs
+
+	// inviteCode -- инвайт-код регистрации из провайдера секретов (nil -- REGISTRATION_INVITE_CODE из окружения)
+	inviteCode *secrets.Value
+
+	// notifier -- доставка уведомлений (nil -- в лог), см. notify.go
+	notifier Notifier
+
+	// mailer -- отправка писем (nil -- почта не настроена), см. mailer.go
+	mailer Mailer
+
+	// background -- запущенные в фоне автоматизации (см. automations.go); Close их дожидается
+	background sync.WaitGroup
+
+	// hooks -- пользовательские хуки изменений задач (см. hooks.go)
+	hooks []namedHooks
+
+	// scriptLimits и compiled -- ограничения и кэш скриптов на событиях задач (см. scripts.go)
+	scriptLimits script.Limits
+	compiled     compiledScripts
+
+	// changes -- лента последних изменений задач для GET /api/v1/changes (см. changes.go)
+	changes changeFeed
+
+	// syncMu -- мутации POST /api/v1/sync применяются по одной: проверка ревизии и запись не разрываются
+	// другой синхронизацией (см. sync.go). syncStrategy -- стратегия конфликтов по умолчанию.
+	syncMu       sync.Mutex
+	syncStrategy string
+
+	// retentionRuns -- последний проход уборщика по политике хранения (см. retention.go)
+	retentionRuns retentionRuns
+
+	// quota -- мягкие пороги использования хранилища (см. usage.go)
+	quota quotaWatch
+
+	// storageFault -- отказ диска при последней записи (nil -- всё в порядке), кого о нём уведомлять
+	// и что сделать при отказе (см. storagefault.go)
+	storageFault      atomic.Pointer[StorageError]
+	storageAlertUsers []int
+	onStorageFault    func(StorageError)
+
+	// userStatus -- кэш заблокированных и удалённых пользователей (см. provisioning.go)
+	userStatus userStatus
+
+	// identityProviders -- источники учётных записей по порядку (nil -- только локальные), см. identity.go
+	identityProviders []IdentityProvider
+
+	// Пароли локальных пользователей: политика, параметры argon2id и сброс по почте (см. password.go)
+	passwordPolicy  PasswordPolicy
+	passwordHashing Argon2Params
+	resetTTL        time.Duration
+	resetURL        string
+
+	// sessions -- кэш проверенных сессий (выданных токенов), см. sessions.go
+	sessions sessionCache
+
+	// publicURL и actionTTL -- подписанные ссылки действий в письмах (см. actions.go)
+	publicURL string
+	actionTTL time.Duration
+
+	// limits и validate -- ограничения входных данных и собранный по ним валидатор (см. limits.go)
+	limits   ValidationLimits
+	validate *validator.Validate
+
+	// projects -- закрытые проекты: чьи задачи кому видны (см. project_acl.go)
+	projects projectGuard
 }
 
 // NewService создает сервис и загружает задачи из хранилища
@@ -25,7 +98,69 @@ type Service struct {
 // Принимаем ctx, чтобы даже инициализация уважала отмену
 func NewService(repo TaskRepository) *Service {
 	return &Service{
-		repo: repo,
+		repo:            repo,
+		hooks:           registeredHooks(),
+		passwordPolicy:  DefaultPasswordPolicy,
+		passwordHashing: DefaultArgon2Params,
+		resetTTL:        time.Hour,
+		limits:          DefaultValidationLimits,
+		validate:        taskValidator,
+	}
+}
+
+// capabilities -- хранилище, у которого спрашиваются опциональные возможности (заметки, ссылки,
+// доски и т.д.). Обёртка FailoverStore их не повторяет: они идут прямо в основное хранилище,
+// а при его аварии возвращают его же ошибку.
+func (s *Service) capabilities() TaskRepository {
+	if fs, ok := s.repo.(*FailoverStore); ok {
+		return fs.Primary()
+	}
+	if fs, ok := s.repo.(*FaultStore); ok {
+		return fs.Inner()
+	}
+	return s.repo
+}
+
+// storageFeatures -- какие возможности поддерживает хранилище (для OPTIONS, см. handler_options.go).
+func (s *Service) storageFeatures() map[string]bool {
+	repo := s.capabilities()
+	_, sync := repo.(RevisionStore)
+	_, history := repo.(HistoryProvider)
+	_, versions := repo.(VersionStore)
+	_, rules := repo.(RuleStore)
+	_, automations := repo.(AutomationStore)
+	_, schedules := repo.(ScheduleStore)
+	_, comments := repo.(CommentStore)
+	_, boards := repo.(BoardStore)
+	_, share := repo.(ShareStore)
+	_, relations := repo.(RelationStore)
+	_, notes := repo.(NoteStore)
+	_, calendar := repo.(CalendarStore)
+	_, priorities := repo.(PriorityStore)
+	_, workflow := repo.(WorkflowStore)
+	_, projects := repo.(ProjectACLStore)
+	_, scoped := s.repo.(ScopedReader)
+	_, sessions := repo.(SessionStore)
+	_, merge := repo.(TaskMerger)
+	return map[string]bool{
+		"sync":             sync,
+		"history":          history,
+		"versions":         versions,
+		"rules":            rules,
+		"automations":      automations,
+		"schedules":        schedules,
+		"comments":         comments,
+		"boards":           boards,
+		"share_links":      share,
+		"relations":        relations,
+		"notes":            notes,
+		"calendar":         calendar,
+		"priority_scale":   priorities,
+		"workflow":         workflow,
+		"private_projects": projects,
+		"scoped_reads":     scoped,
+		"sessions":         sessions,
+		"merge":            merge,
 	}
 }
 
@@ -33,8 +168,39 @@ func (s *Service) CreateTask(ctx context.Context, task *Task) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	// Просто делегируем задачу репозиторию
-	return s.repo.Create(ctx, task)
+	// Хуки видят задачу такой, какой она пришла; нормализация и проверка полей -- уже после них.
+	if err := s.beforeCreate(ctx, task); err != nil {
+		return err
+	}
+	normalizeStatus(task)
+	normalizePriority(task)
+	normalizeTaskText(task)
+	if err := s.checkProjectTags(task.UserID, task.Tags); err != nil {
+		return err
+	}
+	stampCompletion(task, nil)
+	stampCreated(task)
+	fields, err := s.fieldDefs.validate(task.Fields)
+	if err != nil {
+		return err
+	}
+	task.Fields = fields
+	if task.UUID == "" {
+		task.UUID = newTaskUUID()
+	} else {
+		task.UUID = normalizeClientID(task.UUID)
+	}
+
+	// Делегируем задачу репозиторию
+	if IsDryRun(ctx) {
+		return s.WithTx(ctx, func(tx TxStore) error { return tx.Create(ctx, task) })
+	}
+	if err := s.checkStorage(ctx, s.repo.Create(ctx, task)); err != nil {
+		return err
+	}
+	s.afterCreate(ctx, *task)
+	s.fireAutomations(ctx, EventTaskCreated, task)
+	return nil
 }
 
 func (s *Service) GetTaskByID(ctx context.Context, id int, userID int) (*Task, error) {
@@ -42,15 +208,28 @@ func (s *Service) GetTaskByID(ctx context.Context, id int, userID int) (*Task, e
 		return nil, err
 	}
 
-	return s.repo.GetByID(ctx, id)
+	if sr, ok := s.repo.(ScopedReader); ok && userID != 0 {
+		return sr.GetVisibleByID(ctx, id, userID)
+	}
+	t, err := s.repo.GetByID(ctx, id)
+	if err == nil && !s.canSee(userID, *t) {
+		return nil, ErrTaskNotFound // задача закрытого проекта для остальных не существует
+	}
+	return t, err
 }
 
+// GetAllTasks -- задачи, видимые пользователю (без задач чужих закрытых проектов, см. project_acl.go).
+// userID 0 -- все задачи.
 func (s *Service) GetAllTasks(ctx context.Context, userID int) ([]Task, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetAll(ctx, userID)
+	list, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleTasks(userID, list), nil
 }
 
 func (s *Service) UpdateTask(ctx context.Context, task *Task, userID int) error {
@@ -58,7 +237,48 @@ func (s *Service) UpdateTask(ctx context.Context, task *Task, userID int) error
 		return err
 	}
 
-	return s.repo.Update(ctx, task, userID)
+	// Нужна текущая версия, чтобы понять, перешла ли задача в "выполнено" именно сейчас (и для хуков).
+	existing, err := s.GetTaskByID(ctx, task.ID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.beforeUpdate(ctx, *existing, task); err != nil {
+		return err
+	}
+
+	// Статус не передан, а выполнение не менялось -- статус остаётся прежним ("review" не сбрасывается
+	// в начальный, когда старый клиент правит название).
+	if task.Status == "" && task.Done == existing.Done {
+		task.Status = existing.Status
+	}
+	normalizeStatus(task)
+	if err := checkTransition(existing.Status, task.Status); err != nil {
+		return err
+	}
+	normalizePriority(task)
+	normalizeTaskText(task)
+	if err := s.checkProjectTags(userID, task.Tags); err != nil {
+		return err
+	}
+	fields, err := s.fieldDefs.validate(task.Fields)
+	if err != nil {
+		return err
+	}
+	task.Fields = fields
+	stampCompletion(task, existing)
+
+	if IsDryRun(ctx) {
+		return s.WithTx(ctx, func(tx TxStore) error { return tx.Update(ctx, task, userID) })
+	}
+	if err := s.checkStorage(ctx, s.repo.Update(ctx, task, userID)); err != nil {
+		return err
+	}
+	s.afterUpdate(ctx, *existing, *task)
+	s.fireAutomations(ctx, EventTaskUpdated, task)
+	if task.Done && !existing.Done {
+		s.fireAutomations(ctx, EventTaskCompleted, task)
+	}
+	return nil
 }
 
 func (s *Service) DeleteTask(ctx context.Context, id int, userID int) error {
@@ -66,14 +286,35 @@ func (s *Service) DeleteTask(ctx context.Context, id int, userID int) error {
 		return err
 	}
 
-	return s.repo.Delete(ctx, id, userID)
+	// Задачу читаем, только если она нужна хукам или журналу ревизий (UUID для надгробия):
+	// лишнее чтение на каждое удаление ни к чему.
+	var cur *Task
+	if s.hasDeleteHooks(ctx) || s.tracksRevisions() || len(s.projects.load()) > 0 {
+		var err error
+		if cur, err = s.GetTaskByID(ctx, id, userID); err != nil {
+			return err
+		}
+		if err := s.beforeDelete(ctx, *cur); err != nil {
+			return err
+		}
+	}
+	if err := s.checkStorage(ctx, s.repo.Delete(ctx, id, userID)); err != nil {
+		return err
+	}
+	deleted := Task{ID: id}
+	if cur != nil {
+		s.onDelete(ctx, *cur)
+		deleted = *cur
+	}
+	s.publishChange(ctx, ChangeDeleted, deleted)
+	return nil
 }
 
 func (s *Service) CreateSubTask(ctx context.Context, subtask *SubTask, userID int) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	_, err := s.repo.GetByID(ctx, subtask.TaskID)
+	_, err := s.GetTaskByID(ctx, subtask.TaskID, userID)
 	if errors.Is(err, ErrTaskNotFound) {
 		return err
 	}
@@ -81,7 +322,54 @@ func (s *Service) CreateSubTask(ctx context.Context, subtask *SubTask, userID in
 		return err
 	}
 
-	return s.repo.CreateSubtask(ctx, subtask)
+	subtask.Title = NormalizeText(subtask.Title)
+	if err := s.repo.CreateSubtask(ctx, subtask); err != nil {
+		return err
+	}
+	// В ленте изменений подзадача -- изменение своей задачи.
+	if t, err := s.repo.GetByID(ctx, subtask.TaskID); err == nil {
+		s.publishChange(ctx, ChangeUpdated, *t)
+	}
+	return nil
+}
+
+// stampCompletion проставляет CompletedAt при переходе задачи в "выполнено"
+// и сбрасывает его, если задачу переоткрыли. prev -- сохранённая версия (nil при создании).
+func stampCompletion(t *Task, prev *Task) {
+	switch {
+	case !t.Done:
+		t.CompletedAt = nil
+	case prev != nil && prev.Done && prev.CompletedAt != nil:
+		t.CompletedAt = prev.CompletedAt
+	case t.CompletedAt == nil:
+		now := time.Now().UTC()
+		t.CompletedAt = &now
+	}
+}
+
+// stampCreated проставляет CreatedAt новой задаче, если его не передал импорт.
+func stampCreated(t *Task) {
+	if t.CreatedAt == nil {
+		now := time.Now().UTC()
+		t.CreatedAt = &now
+	}
+}
+
+// SetInviteCode задаёт инвайт-код регистрации. После ротации прежний код ещё принимается,
+// пока не истечёт grace секрета.
+func (s *Service) SetInviteCode(v *secrets.Value) {
+	s.inviteCode = v
+}
+
+// validInviteCode сверяет код с действующим. Пустой код не подходит никогда.
+func (s *Service) validInviteCode(code string) bool {
+	if code == "" {
+		return false
+	}
+	if s.inviteCode == nil {
+		return code == os.Getenv("REGISTRATION_INVITE_CODE")
+	}
+	return s.inviteCode.Accepts(code, time.Now())
 }
 
 // Register - бизнес-логика регистрации пользователя
@@ -90,7 +378,7 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) error {
 		return err
 	}
 
-	if req.InviteCode != os.Getenv("REGISTRATION_INVITE_CODE") {
+	if !s.validInviteCode(req.InviteCode) {
 		return errors.New("invalid invite code") // или кастомная ошибка
 	}
 
@@ -105,15 +393,18 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) error {
 		return err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.passwordPolicy.Check(req.Username, req.Password); err != nil {
+		return err
+	}
+	hash, err := s.hashPassword(req.Password)
 	if err != nil {
 		return err
 	}
 
-	hash := string(hashedPassword)
 	u := User{
 		Username:     req.Username,
 		PasswordHash: hash,
+		Email:        req.Email,
 	}
 	err = s.repo.CreateUser(ctx, &u)
 	if err != nil {
@@ -122,39 +413,84 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) error {
 	return nil
 }
 
-func (s *Service) Login(ctx context.Context, req LoginRequest) (string, error) {
+// Authenticate проверяет логин/пароль и возвращает пользователя.
+// Используется и JWT-логином, и транспортами с Basic-авторизацией (CalDAV).
+// Источники учётных записей (AUTH_PROVIDERS) пробуются по порядку до первого,
+// который знает пользователя и принял пароль.
+func (s *Service) Authenticate(ctx context.Context, username, password string) (*User, error) {
 	if err := ctx.Err(); err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(s.identityProviders) == 0 {
+		return s.authenticateLocal(ctx, username, password)
+	}
+	for _, p := range s.identityProviders {
+		var u *User
+		var err error
+		if p == LocalUsers {
+			u, err = s.authenticateLocal(ctx, username, password)
+		} else {
+			u, err = s.authenticateExternal(ctx, p, username, password)
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			return u, err
+		}
 	}
+	return nil, ErrInvalidCredentials
+}
 
-	u, err := s.repo.GetUserByUsername(ctx, req.Username)
+// authenticateLocal проверяет пароль собственного пользователя (argon2id; старые хэши bcrypt
+// принимаются и при входе пересчитываются, см. password.go).
+func (s *Service) authenticateLocal(ctx context.Context, username, password string) (*User, error) {
+	u, err := s.repo.GetUserByUsername(ctx, username)
 
 	if errors.Is(err, ErrUserNotFound) {
-		return "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if !s.checkPassword(ctx, u, password) {
+		return nil, ErrInvalidCredentials
 	}
+	// О блокировке говорим только знающему пароль, чтобы не раскрывать её перебором.
+	if u.Disabled {
+		return nil, ErrUserDisabled
+	}
+	return u, nil
+}
 
-	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password))
+// Login проверяет пароль и выдаёт JWT. Если хранилище ведёт сессии, токен получает
+// свою сессию (claim sid) с адресом и User-Agent клиента -- её можно отозвать.
+func (s *Service) Login(ctx context.Context, req LoginRequest, client SessionClient) (string, error) {
+	u, err := s.Authenticate(ctx, req.Username, req.Password)
 	if err != nil {
-		return " ", ErrInvalidCredentials
+		return "", err
 	}
 
+	expires := time.Now().Add(24 * time.Hour) // Токен сгорит через сутки
 	claims := jwt.MapClaims{
 		"user_id": u.ID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(), // Токен сгорит через сутки
+		"exp":     expires.Unix(),
+	}
+	if len(u.Roles) > 0 {
+		claims["roles"] = u.Roles
+	}
+	sid, err := s.startSession(ctx, u.ID, client, expires)
+	if err != nil {
+		return "", err
+	}
+	if sid != "" {
+		claims["sid"] = sid
 	}
 
 	// Создаем и подписываеем токен
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	// Забираем секретный ключ из окружения
-	secret := os.Getenv("JWT_SECRET")
-
-	// Превращаем токен в финальную строку
-	tokenString, err := token.SignedString([]byte(secret))
+	// Подписываем текущим ключом из провайдера секретов (или JWT_SECRET из окружения)
+	tokenString, err := token.SignedString(appMiddleware.JWTSigningKey())
 
 	return tokenString, nil
 }
@@ -167,9 +503,209 @@ func (s *Service) GetAllUsers(ctx context.Context) ([]User, error) {
 }
 
 // UpdateSubTaskStatus передает команду обновления статуса пункта чек-листа в базу данных.
-func (s *Service) UpdateSubTaskStatus(ctx context.Context, subID int, done bool) error {
+func (s *Service) UpdateSubTaskStatus(ctx context.Context, subID, userID int, done bool) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	if err := s.checkSubTaskVisible(ctx, subID, userID); err != nil {
+		return err
+	}
 	return s.repo.UpdateSubTaskStatus(ctx, subID, done)
 }
+
+// checkSubTaskVisible возвращает ErrTaskNotFound, если подзадача subID принадлежит задаче,
+// которую userID не видит (чужой закрытый проект). Подзадача знает только свой ID, поэтому
+// родитель ищется среди видимых задач; без закрытых проектов хранилище не читается.
+func (s *Service) checkSubTaskVisible(ctx context.Context, subID, userID int) error {
+	if userID == 0 || !s.hasPrivateProjects() {
+		return nil
+	}
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, t := range list {
+		for _, sub := range t.SubTasks {
+			if sub.ID == subID {
+				return nil
+			}
+		}
+	}
+	return ErrTaskNotFound
+}
+
+// HistoryProvider -- опциональная возможность хранилища отдавать историю изменений.
+// Реализуется GitStore; остальные хранилища истории не ведут.
+type HistoryProvider interface {
+	History(ctx context.Context, limit int) ([]Revision, error)
+	TasksAt(ctx context.Context, rev string) ([]Task, error)
+	Diff(ctx context.Context, rev string) (string, error)
+}
+
+// ErrHistoryUnavailable -- текущее хранилище не ведёт историю.
+var ErrHistoryUnavailable = errors.New("history is not available for this storage")
+
+// history возвращает HistoryProvider, если хранилище его поддерживает.
+func (s *Service) history() (HistoryProvider, error) {
+	hp, ok := s.capabilities().(HistoryProvider)
+	if !ok {
+		return nil, ErrHistoryUnavailable
+	}
+	return hp, nil
+}
+
+// History возвращает последние ревизии хранилища задач.
+func (s *Service) History(ctx context.Context, limit int) ([]Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hp, err := s.history()
+	if err != nil {
+		return nil, err
+	}
+	return hp.History(ctx, limit)
+}
+
+// TasksAt возвращает снимок задач на момент ревизии, видимых пользователю userID (0 -- все).
+// Видимость проверяется по нынешним закрытым проектам и меткам задачи в снимке.
+func (s *Service) TasksAt(ctx context.Context, rev string, userID int) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hp, err := s.history()
+	if err != nil {
+		return nil, err
+	}
+	list, err := hp.TasksAt(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleTasks(userID, list), nil
+}
+
+// RevisionDiff возвращает изменения, внесённые ревизией.
+func (s *Service) RevisionDiff(ctx context.Context, rev string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	hp, err := s.history()
+	if err != nil {
+		return "", err
+	}
+	return hp.Diff(ctx, rev)
+}
+
+// ImportResult -- итог импорта задач из внешней системы.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"` // Уже импортированные ранее (по UUID) или неподдерживаемые записи
+}
+
+// ImportTasks создаёт задачи из внешнего источника.
+// Задачи с UUID, который уже есть в хранилище, пропускаются -- повторный импорт безопасен.
+// Импорт атомарен (одна транзакция): при ошибке не остаётся "половины" задач.
+func (s *Service) ImportTasks(ctx context.Context, incoming []Task, userID int) (ImportResult, error) {
+	var res ImportResult
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	var created []Task
+	err := s.WithTx(ctx, func(tx TxStore) error {
+		res = ImportResult{}
+		created = created[:0]
+
+		// Все задачи, а не видимые: иначе задача чужого закрытого проекта импортировалась бы повторно.
+		existing, err := tx.GetAll(ctx, 0)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(existing))
+		for _, t := range existing {
+			if t.UUID != "" {
+				seen[t.UUID] = true
+			}
+		}
+
+		for i := range incoming {
+			t := &incoming[i]
+			if t.UUID != "" && seen[t.UUID] {
+				res.Skipped++
+				continue
+			}
+			// Хук отменяет весь импорт: он атомарен.
+			if err := s.beforeCreate(ctx, t); err != nil {
+				return err
+			}
+			normalizeStatus(t)
+			normalizePriority(t)
+			normalizeTaskText(t)
+			if err := s.checkProjectTags(userID, t.Tags); err != nil {
+				return err
+			}
+			s.FitTitle(t)
+			stampCompletion(t, nil)
+			stampCreated(t)
+			if t.UUID == "" {
+				t.UUID = newTaskUUID()
+			}
+			if err := tx.Create(ctx, t); err != nil {
+				return err
+			}
+			created = append(created, *t)
+			if t.UUID != "" {
+				seen[t.UUID] = true
+			}
+			res.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	for _, t := range created {
+		s.afterCreate(ctx, t)
+	}
+	return res, nil
+}
+
+// TasksCreatedAfter возвращает задачи с ID больше afterID (новые -- первыми).
+// ID монотонно растёт, поэтому годится как курсор для polling-триггеров "новая задача".
+func (s *Service) TasksCreatedAfter(ctx context.Context, userID, afterID, limit int) ([]Task, error) {
+	all, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Task, 0)
+	for _, t := range all {
+		if t.ID > afterID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// TasksCompletedSince возвращает задачи, выполненные после since (последние -- первыми).
+func (s *Service) TasksCompletedSince(ctx context.Context, userID int, since time.Time, limit int) ([]Task, error) {
+	all, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Task, 0)
+	for _, t := range all {
+		if t.Done && t.CompletedAt != nil && t.CompletedAt.After(since) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CompletedAt.After(*out[j].CompletedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,440 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+var (
+	// ErrSessionsUnsupported -- хранилище не хранит сессии (JSON-файл): токены без учёта устройств.
+	ErrSessionsUnsupported = errors.New("storage does not support sessions")
+	// ErrSessionNotFound -- сессии нет, она чужая, отозвана или истекла.
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// sessionTouchInterval -- как часто обновляется last_used_at: не на каждом запросе, а не чаще
+// раза в минуту на сессию, чтобы чтение задач не превращалось в запись в базу.
+const sessionTouchInterval = time.Minute
+
+// maxUserAgent -- сколько символов User-Agent сохраняется.
+const maxUserAgent = 512
+
+// Session -- выданный при входе токен (устройство). ID попадает в JWT (claim sid).
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current -- сессия, с токеном которой пришёл запрос (в хранилище не пишется)
+	Current bool `json:"current"`
+}
+
+// SessionClient -- откуда выполнен вход: сохраняется в сессии.
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionStore -- опциональная возможность хранилища помнить выданные токены.
+// Реализуется MemoryStore и PostgresRepository. Истёкшие сессии хранилище не отдаёт.
+type SessionStore interface {
+	CreateSession(ctx context.Context, sess *Session) error
+	GetSession(ctx context.Context, id string) (*Session, error)
+	ListSessions(ctx context.Context, userID int) ([]Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+	// DeleteSession удаляет сессию пользователя userID; чужая -- ErrSessionNotFound.
+	DeleteSession(ctx context.Context, userID int, id string) error
+	// DeleteUserSessions удаляет все сессии пользователя, кроме except, и возвращает их число.
+	DeleteUserSessions(ctx context.Context, userID int, except string) (int, error)
+}
+
+// sessionCache -- недавно проверенные сессии, чтобы не ходить в хранилище на каждом запросе.
+// Отзыв на этом экземпляре виден сразу, на соседних -- не позже чем через userStatusTTL.
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]sessionEntry
+}
+
+type sessionEntry struct {
+	userID    int
+	expiresAt time.Time
+	checkedAt time.Time
+	touchedAt time.Time
+}
+
+// maxCachedSessions -- больше записей в кэше -- выбрасываем истёкшие и давно не проверенные.
+const maxCachedSessions = 10000
+
+func (s *Service) sessionStore() (SessionStore, error) {
+	ss, ok := s.capabilities().(SessionStore)
+	if !ok {
+		return nil, ErrSessionsUnsupported
+	}
+	return ss, nil
+}
+
+// SupportsSessions сообщает, ведёт ли хранилище учёт выданных токенов.
+func (s *Service) SupportsSessions() bool {
+	_, err := s.sessionStore()
+	return err == nil
+}
+
+// startSession заводит сессию для нового токена. Пустой ID -- хранилище сессий не ведёт.
+func (s *Service) startSession(ctx context.Context, userID int, client SessionClient, expires time.Time) (string, error) {
+	ss, err := s.sessionStore()
+	if err != nil {
+		return "", nil
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	ua := []rune(client.UserAgent)
+	if len(ua) > maxUserAgent {
+		ua = ua[:maxUserAgent]
+	}
+	now := time.Now().UTC()
+	sess := &Session{
+		ID:         hex.EncodeToString(id),
+		UserID:     userID,
+		IP:         client.IP,
+		UserAgent:  string(ua),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  expires.UTC(),
+	}
+	if err := ss.CreateSession(ctx, sess); err != nil {
+		return "", err
+	}
+	return sess.ID, nil
+}
+
+// SessionActive сообщает, не отозвана ли сессия токена, и отмечает её использование.
+// Если проверить сессию нельзя (хранилище недоступно или не ведёт сессий), возвращается ошибка,
+// а не "активна": иначе отозванный токен снова пускал бы, пока лежит база.
+func (s *Service) SessionActive(ctx context.Context, userID int, sessionID string) (bool, error) {
+	ss, err := s.sessionStore()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	c := &s.sessions
+	c.mu.Lock()
+	e, found := c.entries[sessionID]
+	c.mu.Unlock()
+
+	if !found || now.Sub(e.checkedAt) >= userStatusTTL {
+		sess, err := ss.GetSession(ctx, sessionID)
+		if errors.Is(err, ErrSessionNotFound) {
+			c.forget(sessionID)
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("session lookup: %w", err)
+		}
+		e = sessionEntry{userID: sess.UserID, expiresAt: sess.ExpiresAt, checkedAt: now, touchedAt: sess.LastUsedAt}
+	}
+	if e.userID != userID || !now.Before(e.expiresAt) {
+		return false, nil
+	}
+	if now.Sub(e.touchedAt) >= sessionTouchInterval {
+		if err := ss.TouchSession(ctx, sessionID, now.UTC()); err != nil {
+			log.Printf("WARNING: session touch failed: %v", err)
+		}
+		e.touchedAt = now
+	}
+	c.store(sessionID, e, now)
+	return true, nil
+}
+
+// ListSessions возвращает действующие сессии пользователя, последние использованные -- первыми.
+// current -- сессия текущего запроса.
+func (s *Service) ListSessions(ctx context.Context, userID int, current string) ([]Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ss, err := s.sessionStore()
+	if err != nil {
+		return nil, err
+	}
+	list, err := ss.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Current = list[i].ID == current
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].LastUsedAt.After(list[j].LastUsedAt) })
+	return list, nil
+}
+
+// RevokeSession отзывает одну сессию пользователя: её токен перестаёт приниматься.
+func (s *Service) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ss, err := s.sessionStore()
+	if err != nil {
+		return err
+	}
+	if err := ss.DeleteSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.sessions.forget(sessionID)
+	return nil
+}
+
+// RevokeSessions отзывает все сессии пользователя, кроме except (пусто -- все), и возвращает их число.
+func (s *Service) RevokeSessions(ctx context.Context, userID int, except string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	ss, err := s.sessionStore()
+	if err != nil {
+		return 0, err
+	}
+	n, err := ss.DeleteUserSessions(ctx, userID, except)
+	if err != nil {
+		return 0, err
+	}
+	s.sessions.forgetUser(userID, except)
+	return n, nil
+}
+
+// revokeOnPasswordChange отзывает сессии после смены пароля: при смене -- все, кроме текущей,
+// при сбросе -- все. Ошибка не отменяет смену пароля, только пишется в лог.
+func (s *Service) revokeOnPasswordChange(ctx context.Context, userID int) {
+	if !s.SupportsSessions() {
+		return
+	}
+	n, err := s.RevokeSessions(ctx, userID, appMiddleware.SessionIDFromContext(ctx))
+	if err != nil {
+		log.Printf("WARNING: revoke sessions of user id=%d after password change: %v", userID, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("password change: revoked %d session(s) of user id=%d", n, userID)
+	}
+}
+
+func (c *sessionCache) store(id string, e sessionEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]sessionEntry)
+	}
+	if len(c.entries) >= maxCachedSessions {
+		for k, old := range c.entries {
+			if !now.Before(old.expiresAt) || now.Sub(old.checkedAt) >= userStatusTTL {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[id] = e
+}
+
+func (c *sessionCache) forget(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
+
+func (c *sessionCache) forgetUser(userID int, except string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if e.userID == userID && k != except {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// CreateSession сохраняет сессию и заодно убирает истёкшие.
+func (ms *MemoryStore) CreateSession(ctx context.Context, sess *Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.sessions == nil {
+		ms.sessions = make(map[string]Session)
+	}
+	now := time.Now()
+	for id, old := range ms.sessions {
+		if !now.Before(old.ExpiresAt) {
+			delete(ms.sessions, id)
+		}
+	}
+	ms.sessions[sess.ID] = *sess
+	return nil
+}
+
+// GetSession ищет действующую сессию по ID.
+func (ms *MemoryStore) GetSession(ctx context.Context, id string) (*Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	sess, ok := ms.sessions[id]
+	if !ok || !time.Now().Before(sess.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	return &sess, nil
+}
+
+// ListSessions возвращает действующие сессии пользователя.
+func (ms *MemoryStore) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	now := time.Now()
+	out := []Session{}
+	for _, sess := range ms.sessions {
+		if sess.UserID == userID && now.Before(sess.ExpiresAt) {
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
+
+// TouchSession отмечает использование сессии.
+func (ms *MemoryStore) TouchSession(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if sess, ok := ms.sessions[id]; ok {
+		sess.LastUsedAt = at
+		ms.sessions[id] = sess
+	}
+	return nil
+}
+
+// DeleteSession удаляет сессию пользователя.
+func (ms *MemoryStore) DeleteSession(ctx context.Context, userID int, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	sess, ok := ms.sessions[id]
+	if !ok || sess.UserID != userID {
+		return ErrSessionNotFound
+	}
+	delete(ms.sessions, id)
+	return nil
+}
+
+// DeleteUserSessions удаляет сессии пользователя, кроме except.
+func (ms *MemoryStore) DeleteUserSessions(ctx context.Context, userID int, except string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	n := 0
+	for id, sess := range ms.sessions {
+		if sess.UserID == userID && id != except {
+			delete(ms.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+const sessionColumns = "id, user_id, ip, user_agent, created_at, last_used_at, expires_at"
+
+func scanSession(row interface{ Scan(...any) error }) (Session, error) {
+	var sess Session
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.IP, &sess.UserAgent, &sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt)
+	return sess, err
+}
+
+// CreateSession сохраняет сессию в sessions и заодно убирает истёкшие.
+func (r *PostgresRepository) CreateSession(ctx context.Context, sess *Session) error {
+	if _, err := r.q.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= now()"); err != nil {
+		return err
+	}
+	_, err := r.q.ExecContext(ctx, "INSERT INTO sessions ("+sessionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		sess.ID, sess.UserID, sess.IP, sess.UserAgent, sess.CreatedAt, sess.LastUsedAt, sess.ExpiresAt)
+	return err
+}
+
+// GetSession ищет действующую сессию по ID.
+func (r *PostgresRepository) GetSession(ctx context.Context, id string) (*Session, error) {
+	sess, err := scanSession(r.q.QueryRowContext(ctx,
+		"SELECT "+sessionColumns+" FROM sessions WHERE id = $1 AND expires_at > now()", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// ListSessions возвращает действующие сессии пользователя.
+func (r *PostgresRepository) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	rows, err := r.q.QueryContext(ctx,
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = $1 AND expires_at > now() ORDER BY last_used_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Session{}
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, rows.Err()
+}
+
+// TouchSession отмечает использование сессии.
+func (r *PostgresRepository) TouchSession(ctx context.Context, id string, at time.Time) error {
+	_, err := r.q.ExecContext(ctx, "UPDATE sessions SET last_used_at = $2 WHERE id = $1", id, at)
+	return err
+}
+
+// DeleteSession удаляет сессию пользователя.
+func (r *PostgresRepository) DeleteSession(ctx context.Context, userID int, id string) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions удаляет сессии пользователя, кроме except.
+func (r *PostgresRepository) DeleteUserSessions(ctx context.Context, userID int, except string) (int, error) {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1 AND id <> $2", userID, except)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,66 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"task-manager/internal/middleware"
+	"task-manager/internal/tasks"
+	"task-manager/internal/tasks/taskstest"
+)
+
+// unavailableSessions -- хранилище, у которого по флагу down не читаются сессии (база недоступна).
+type unavailableSessions struct {
+	*tasks.MemoryStore
+	down atomic.Bool
+}
+
+func (s *unavailableSessions) GetSession(ctx context.Context, id string) (*tasks.Session, error) {
+	if s.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return s.MemoryStore.GetSession(ctx, id)
+}
+
+// TestSessionCheckFailsClosed -- если сессию нельзя проверить, токен с sid не пускает (503),
+// а не считается действующим: отозванный токен не должен оживать, пока лежит база.
+func TestSessionCheckFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	mem, err := taskstest.NewStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &unavailableSessions{MemoryStore: mem}
+	srv := taskstest.NewServer(store, "")
+	middleware.SetSessionCheck(srv.Service.SessionActive)
+	t.Cleanup(func() { middleware.SetSessionCheck(nil) })
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	creds := map[string]string{"username": "mom", "password": taskstest.Password}
+	if code, err := srv.DoJSON(ctx, http.MethodPost, "/api/v1/auth/login", "", creds, &login); err != nil || code != http.StatusOK {
+		t.Fatalf("login: %d %v", code, err)
+	}
+
+	store.down.Store(true)
+	resp, err := srv.Do(ctx, http.MethodGet, "/api/v1/tasks", login.Token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("session store down: %d (Retry-After %q), want 503 with Retry-After", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if _, err := srv.Service.SessionActive(ctx, 1, "unknown"); err == nil {
+		t.Error("SessionActive without a store: no error")
+	}
+
+	store.down.Store(false)
+	if code, err := srv.DoJSON(ctx, http.MethodGet, "/api/v1/tasks", login.Token, nil, nil); err != nil || code != http.StatusOK {
+		t.Errorf("session store back: %d %v", code, err)
+	}
+}
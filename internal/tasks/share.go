@@ -0,0 +1,391 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Доступ по ссылке.
+const (
+	ShareRead  = "read"  // только чтение
+	ShareWrite = "write" // чтение и изменение задачи (PUT)
+)
+
+const (
+	// DefaultShareTTL -- срок жизни ссылки, если он не задан.
+	DefaultShareTTL = 7 * 24 * time.Hour
+	// MaxShareTTL -- дольше ссылка не живёт: бессрочных публичных ссылок нет.
+	MaxShareTTL = 90 * 24 * time.Hour
+)
+
+var (
+	// ErrSharesUnsupported -- хранилище не умеет хранить ссылки.
+	ErrSharesUnsupported = errors.New("task sharing is not supported by this storage")
+	// ErrShareNotFound -- ссылки нет, она отозвана или истекла.
+	ErrShareNotFound = errors.New("share link not found or expired")
+	// ErrInvalidShareTTL -- срок жизни ссылки не разобран или вне (0, MaxShareTTL].
+	ErrInvalidShareTTL = errors.New(`expires_in must be a duration like "72h", at most 2160h (90 days)`)
+)
+
+// Share -- публичная ссылка на одну задачу. Сам токен не хранится -- только его SHA-256:
+// утечка хранилища не даёт рабочих ссылок.
+type Share struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	Access    string    `json:"access"`
+	TokenHash string    `json:"-"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired -- ссылка истекла к моменту now.
+func (s Share) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// CreateShareRequest -- тело POST /api/v1/tasks/{id}/shares.
+type CreateShareRequest struct {
+	Access    string `json:"access" validate:"omitempty,oneof=read write"` // по умолчанию read
+	ExpiresIn string `json:"expires_in" validate:"max=20"`                 // по умолчанию 168h
+}
+
+// ShareStore -- опциональная возможность хранилища хранить ссылки на задачи.
+type ShareStore interface {
+	CreateShare(ctx context.Context, sh *Share) error
+	ShareByTokenHash(ctx context.Context, hash string) (Share, bool, error)
+	TaskShares(ctx context.Context, taskID int) ([]Share, error)
+	DeleteShare(ctx context.Context, id int) error
+}
+
+// hashToken -- в каком виде токены публичных ссылок (задачи, доски) лежат в хранилище.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) shares() (ShareStore, error) {
+	ss, ok := s.capabilities().(ShareStore)
+	if !ok {
+		return nil, ErrSharesUnsupported
+	}
+	return ss, nil
+}
+
+// ShareTask создаёт ссылку на задачу taskID и возвращает её вместе с токеном.
+// Токен показывается один раз: потом его не узнать ни через API, ни из хранилища.
+func (s *Service) ShareTask(ctx context.Context, taskID, userID int, req CreateShareRequest, now time.Time) (Share, string, error) {
+	if err := ctx.Err(); err != nil {
+		return Share{}, "", err
+	}
+	ss, err := s.shares()
+	if err != nil {
+		return Share{}, "", err
+	}
+
+	ttl := DefaultShareTTL
+	if req.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil || ttl <= 0 || ttl > MaxShareTTL {
+			return Share{}, "", ErrInvalidShareTTL
+		}
+	}
+	if _, err := s.GetTaskByID(ctx, taskID, userID); err != nil {
+		return Share{}, "", err
+	}
+
+	access := req.Access
+	if access == "" {
+		access = ShareRead
+	}
+	token := rand.Text()
+	sh := Share{
+		TaskID:    taskID,
+		Access:    access,
+		TokenHash: hashToken(token),
+		CreatedBy: userID,
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(ttl).UTC(),
+	}
+	if err := ss.CreateShare(ctx, &sh); err != nil {
+		return Share{}, "", err
+	}
+	return sh, token, nil
+}
+
+// ResolveShare находит действующую ссылку по токену. Неизвестная, отозванная
+// и истекшая ссылки неотличимы -- ErrShareNotFound.
+func (s *Service) ResolveShare(ctx context.Context, token string, now time.Time) (Share, error) {
+	if err := ctx.Err(); err != nil {
+		return Share{}, err
+	}
+	ss, err := s.shares()
+	if err != nil {
+		return Share{}, err
+	}
+	sh, ok, err := ss.ShareByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return Share{}, err
+	}
+	if !ok || sh.Expired(now) {
+		return Share{}, ErrShareNotFound
+	}
+	return sh, nil
+}
+
+// TaskShares возвращает ссылки на задачу (включая истекшие -- их видно до отзыва).
+// Задача должна быть видна userID: ссылки на задачу чужого закрытого проекта -- ErrTaskNotFound.
+func (s *Service) TaskShares(ctx context.Context, taskID, userID int) ([]Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ss, err := s.shares()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetTaskByID(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+	return ss.TaskShares(ctx, taskID)
+}
+
+// RevokeShare отзывает ссылку id на задачу taskID, видимую userID.
+func (s *Service) RevokeShare(ctx context.Context, taskID, userID, id int) error {
+	list, err := s.TaskShares(ctx, taskID, userID)
+	if err != nil {
+		return err
+	}
+	for _, sh := range list {
+		if sh.ID == id {
+			ss, _ := s.shares()
+			return ss.DeleteShare(ctx, id)
+		}
+	}
+	return ErrShareNotFound
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// sharesFile -- ссылки JSON-хранилища. Лежат рядом с файлом задач, как и настройки пользователей.
+type sharesFile struct {
+	once   sync.Once
+	err    error
+	mu     sync.Mutex
+	data   []shareRecord
+	lastID int
+}
+
+// shareRecord -- Share в файле (у Share хэш токена скрыт от JSON API).
+type shareRecord struct {
+	Share
+	TokenHash string `json:"token_hash"`
+}
+
+func (ts *TaskStore) sharesPath() string {
+	return ts.filename + ".shares.json"
+}
+
+func (ts *TaskStore) loadShares() error {
+	ts.shares.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.sharesPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.shares.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.shares.data); err != nil {
+			ts.shares.err = fmt.Errorf("parse %s: %w", ts.sharesPath(), err)
+			return
+		}
+		for i := range ts.shares.data {
+			rec := &ts.shares.data[i]
+			rec.Share.TokenHash = rec.TokenHash
+			ts.shares.lastID = max(ts.shares.lastID, rec.ID)
+		}
+	})
+	return ts.shares.err
+}
+
+// saveShares переписывает файл ссылок целиком. Вызывающий держит shares.mu.
+func (ts *TaskStore) saveShares() error {
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(ts.shares.data, "", "   ")
+	if err != nil {
+		return err
+	}
+	tmp := ts.sharesPath() + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ts.sharesPath())
+}
+
+// CreateShare сохраняет ссылку и выдаёт ей ID.
+func (ts *TaskStore) CreateShare(ctx context.Context, sh *Share) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadShares(); err != nil {
+		return err
+	}
+	ts.shares.mu.Lock()
+	defer ts.shares.mu.Unlock()
+
+	sh.ID = ts.shares.lastID + 1
+	ts.shares.data = append(ts.shares.data, shareRecord{Share: *sh, TokenHash: sh.TokenHash})
+	if err := ts.saveShares(); err != nil {
+		ts.shares.data = ts.shares.data[:len(ts.shares.data)-1]
+		return err
+	}
+	ts.shares.lastID = sh.ID
+	return nil
+}
+
+// ShareByTokenHash ищет ссылку по хэшу токена. Ссылки на удалённые задачи не находятся.
+func (ts *TaskStore) ShareByTokenHash(ctx context.Context, hash string) (Share, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Share{}, false, err
+	}
+	if err := ts.load(); err != nil {
+		return Share{}, false, err
+	}
+	if err := ts.loadShares(); err != nil {
+		return Share{}, false, err
+	}
+	ts.shares.mu.Lock()
+	defer ts.shares.mu.Unlock()
+
+	for _, rec := range ts.shares.data {
+		if rec.TokenHash == hash && ts.published(rec.TaskID) != nil {
+			return rec.Share, true, nil
+		}
+	}
+	return Share{}, false, nil
+}
+
+// TaskShares возвращает ссылки на задачу.
+func (ts *TaskStore) TaskShares(ctx context.Context, taskID int) ([]Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadShares(); err != nil {
+		return nil, err
+	}
+	ts.shares.mu.Lock()
+	defer ts.shares.mu.Unlock()
+
+	out := []Share{}
+	for _, rec := range ts.shares.data {
+		if rec.TaskID == taskID {
+			out = append(out, rec.Share)
+		}
+	}
+	return out, nil
+}
+
+// DeleteShare удаляет ссылку по ID.
+func (ts *TaskStore) DeleteShare(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadShares(); err != nil {
+		return err
+	}
+	ts.shares.mu.Lock()
+	defer ts.shares.mu.Unlock()
+
+	for i, rec := range ts.shares.data {
+		if rec.ID != id {
+			continue
+		}
+		prev := ts.shares.data
+		ts.shares.data = append(prev[:i:i], prev[i+1:]...)
+		if err := ts.saveShares(); err != nil {
+			ts.shares.data = prev
+			return err
+		}
+		return nil
+	}
+	return ErrShareNotFound
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+const shareSelect = "SELECT id, task_id, access, token_hash, created_by, created_at, expires_at FROM task_shares"
+
+func scanShare(row interface{ Scan(...any) error }) (Share, error) {
+	var sh Share
+	var createdBy sql.NullInt64
+	err := row.Scan(&sh.ID, &sh.TaskID, &sh.Access, &sh.TokenHash, &createdBy, &sh.CreatedAt, &sh.ExpiresAt)
+	sh.CreatedBy = int(createdBy.Int64)
+	return sh, err
+}
+
+// CreateShare сохраняет ссылку в task_shares (migrations/000012_task_shares.up.sql).
+func (r *PostgresRepository) CreateShare(ctx context.Context, sh *Share) error {
+	return r.q.QueryRowContext(ctx, `INSERT INTO task_shares (task_id, access, token_hash, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		sh.TaskID, sh.Access, sh.TokenHash, sh.CreatedBy, sh.CreatedAt, sh.ExpiresAt).Scan(&sh.ID)
+}
+
+// ShareByTokenHash ищет ссылку по хэшу токена (ссылки удалённой задачи удаляются каскадом).
+func (r *PostgresRepository) ShareByTokenHash(ctx context.Context, hash string) (Share, bool, error) {
+	sh, err := scanShare(r.q.QueryRowContext(ctx, shareSelect+" WHERE token_hash = $1", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Share{}, false, nil
+	}
+	if err != nil {
+		return Share{}, false, err
+	}
+	return sh, true, nil
+}
+
+// TaskShares возвращает ссылки на задачу.
+func (r *PostgresRepository) TaskShares(ctx context.Context, taskID int) ([]Share, error) {
+	rows, err := r.q.QueryContext(ctx, shareSelect+" WHERE task_id = $1 ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Share{}
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	return out, rows.Err()
+}
+
+// DeleteShare удаляет ссылку по ID.
+func (r *PostgresRepository) DeleteShare(ctx context.Context, id int) error {
+	res, err := r.q.ExecContext(ctx, "DELETE FROM task_shares WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrShareNotFound
+	}
+	return err
+}
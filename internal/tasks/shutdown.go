@@ -0,0 +1,172 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrStoreClosed -- хранилище уже закрыто (Service.Close), изменения не принимаются.
+var ErrStoreClosed = errors.New("store is closed")
+
+// StoreCloser -- опциональная возможность хранилища корректно завершить работу:
+// дождаться незавершённых записей, сбросить состояние и отметить чистую остановку.
+type StoreCloser interface {
+	Close(ctx context.Context) error
+}
+
+// IntegrityChecker -- опциональная возможность хранилища проверить целостность данных.
+// UncleanShutdown сообщает, что прошлый запуск не завершился через Close (падение, kill -9, отключение питания).
+type IntegrityChecker interface {
+	UncleanShutdown(ctx context.Context) (bool, error)
+	CheckIntegrity(ctx context.Context) ([]string, error)
+}
+
+// Close завершает работу с хранилищем. Вызывать после остановки HTTP-сервера, когда новых запросов уже нет.
+// Сначала дожидается автоматизаций, запущенных в фоне (не дольше ctx).
+func (s *Service) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	if c, ok := s.capabilities().(StoreCloser); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+// UncleanShutdown сообщает, что прошлый запуск завершился некорректно. Хранилища без такой
+// возможности (PostgreSQL сам отвечает за свою целостность) всегда считаются остановленными чисто.
+func (s *Service) UncleanShutdown(ctx context.Context) (bool, error) {
+	ic, ok := s.capabilities().(IntegrityChecker)
+	if !ok {
+		return false, nil
+	}
+	return ic.UncleanShutdown(ctx)
+}
+
+// CheckIntegrity возвращает найденные в данных проблемы (пустой список -- всё в порядке).
+func (s *Service) CheckIntegrity(ctx context.Context) ([]string, error) {
+	ic, ok := s.capabilities().(IntegrityChecker)
+	if !ok {
+		return nil, nil
+	}
+	return ic.CheckIntegrity(ctx)
+}
+
+// cleanMarker -- файл-отметка чистой остановки рядом с файлом задач. Пишется в Close
+// и удаляется при старте: если при следующем старте его нет, процесс был убит посреди работы.
+func (ts *TaskStore) cleanMarker() string {
+	return ts.filename + ".clean"
+}
+
+// checkShutdownMarker определяет, была ли прошлая остановка чистой, и снимает отметку:
+// с этого момента мы снова "работаем". Вызывается из load до чтения файла.
+func (ts *TaskStore) checkShutdownMarker() error {
+	if ts.filename == "" {
+		return nil
+	}
+	if _, err := os.Stat(ts.filename); os.IsNotExist(err) {
+		return nil // первый запуск -- проверять нечего
+	}
+	err := os.Remove(ts.cleanMarker())
+	switch {
+	case err == nil:
+	case os.IsNotExist(err):
+		ts.unclean = true
+	default:
+		return fmt.Errorf("remove %s: %w", ts.cleanMarker(), err)
+	}
+	return nil
+}
+
+// Close дожидается сохранения, которое идёт прямо сейчас, запрещает дальнейшие изменения
+// и пишет отметку чистой остановки. Состояние в памяти к этому моменту уже на диске:
+// каждая мутация сохраняется синхронно, отложенных записей у TaskStore нет.
+func (ts *TaskStore) Close(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+	if ts.closed.Swap(true) || ts.filename == "" {
+		return nil
+	}
+	// Файл не прочитался -- данные мы не видели и за их целостность не ручаемся.
+	if ts.loadErr != nil {
+		return nil
+	}
+	return os.WriteFile(ts.cleanMarker(), []byte(time.Now().UTC().Format(time.RFC3339)+" pid "+strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// UncleanShutdown сообщает, что при старте не нашлось отметки чистой остановки.
+func (ts *TaskStore) UncleanShutdown(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if err := ts.load(); err != nil {
+		return false, err
+	}
+	return ts.unclean, nil
+}
+
+// CheckIntegrity проверяет данные в памяти (а значит, и только что прочитанный файл):
+// корректность ID, уникальность UUID, непустые названия и привязку подзадач к своим задачам.
+func (ts *TaskStore) CheckIntegrity(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	uuids := make(map[string]int)
+	subIDs := make(map[int]int)
+	for _, t := range ts.all() {
+		if t.ID <= 0 {
+			problems = append(problems, fmt.Sprintf("task %q: invalid id %d", t.Title, t.ID))
+		}
+		if t.Title == "" {
+			problems = append(problems, fmt.Sprintf("task %d: empty title", t.ID))
+		}
+		if t.UUID == "" {
+			problems = append(problems, fmt.Sprintf("task %d: empty uuid", t.ID))
+		} else if other, dup := uuids[t.UUID]; dup {
+			problems = append(problems, fmt.Sprintf("tasks %d and %d: duplicate uuid %s", other, t.ID, t.UUID))
+		} else {
+			uuids[t.UUID] = t.ID
+		}
+		for _, st := range t.SubTasks {
+			if st.TaskID != t.ID {
+				problems = append(problems, fmt.Sprintf("task %d: subtask %d belongs to task %d", t.ID, st.ID, st.TaskID))
+			}
+			if st.ID == 0 {
+				continue
+			}
+			if other, dup := subIDs[st.ID]; dup {
+				problems = append(problems, fmt.Sprintf("tasks %d and %d: duplicate subtask id %d", other, t.ID, st.ID))
+			} else {
+				subIDs[st.ID] = t.ID
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Close ждёт незавершённый git-коммит и закрывает файловое хранилище.
+func (gs *GitStore) Close(ctx context.Context) error {
+	lockTimed(&gs.mu, lockGit)
+	defer gs.mu.Unlock()
+	return gs.TaskStore.Close(ctx)
+}
@@ -2,51 +2,272 @@ package tasks
 
 import (
 	"context" // [CHANGE-CONTEXT]
-	"encoding/json"
 	"errors"
 	"os"
-	"strings"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 	// [CHANGE-CONTEXT]
 )
 
 // Объявляем понятную ошибку для всего пакета tasks
 var ErrTaskNotFound = errors.New("task not found")
 
+// taskShards -- на сколько шардов (по ID задачи) делится состояние в памяти.
+const taskShards = 16
+
+// taskShard -- часть задач со своей блокировкой. Задачи внутри неизменяемы:
+// обновление кладёт новую копию, поэтому отданные наружу значения не меняются "под ногами".
+type taskShard struct {
+	mu    sync.RWMutex
+	tasks map[int]*Task
+}
+
 // TaskStore отвечает за хранение задач в файле.
 //
-// Хранилище потокобезопасно: операции чтения/записи защищены RWMutex.
-// Это важно, если в будущем появятся параллельные операции бэкапа/перечитывания.
+// Файл читается один раз при первом обращении, дальше состояние живёт в памяти,
+// разбитое на шарды по ID. Мутации (Create, Update, Delete, транзакции, слияние) идут строго
+// по одной под saveMu: меняют память, синхронно сохраняют файл целиком и при отказе записи
+// откатываются, не отпуская saveMu (см. persist). Шарды -- рабочая копия мутаций, читатели в них
+// не ходят: в шарде может лежать изменение, запись которого ещё не прошла и будет откачена.
+//
+// Чтения (GetAll, GetByID) идут по неизменяемому снимку (copy-on-write), который пересобирается
+// после каждой успешной записи и публикуется атомарно: они не берут блокировок вовсе.
 type TaskStore struct {
-	mu       sync.RWMutex // Мьютекс для защиты доступа к файлу и данным при I/O операциях
+	mu       sync.RWMutex // Мьютекс для защиты доступа к файлу при I/O операциях
 	filename string       // Имя файла базы данных (например, tasks.json)
+
+	loadOnce  sync.Once
+	loadErr   error
+	shards    [taskShards]taskShard
+	lastID    atomic.Int64
+	saveMu    sync.Mutex // Сериализует сохранение: в файл всегда пишется самый свежий срез
+	uuids     sync.Map   // UUID -> ID: индекс для поиска по UUID и проверки уникальности
+	closed    atomic.Bool
+	unclean   bool          // при старте не нашлось отметки чистой остановки (см. shutdown.go)
+	prefs     prefsFile     // настройки пользователей (см. preferences.go)
+	redirects redirectsFile // надгробия слитых задач (см. merge.go)
+	relations relationsFile // связи задач (см. relations.go)
+	notes     notesFile     // заметки пользователей по дням (см. notes.go)
+	shares    sharesFile    // публичные ссылки на задачи (см. share.go)
+	boards    boardsFile    // публичные доски проектов (см. board.go)
+	rules     rulesFile     // правила эскалации (см. rules.go)
+
+	automations automationsFile   // автоматизации и их журнал (см. automations.go)
+	schedules   schedulesFile     // расписания создания задач (см. schedules.go)
+	comments    commentsFile      // комментарии к задачам и входящие уведомления (см. comments.go)
+	calendar    calendarFile      // рабочий календарь (см. calendar.go)
+	priorities  priorityScaleFile // шкала приоритетов (см. priority_scale.go)
+	workflow    workflowFile      // процесс: статусы и переходы (см. workflow.go)
+	projectACL  projectACLFile    // закрытые проекты и их участники (см. project_acl.go)
+	reports     reportsFile       // отчёты по расписанию (см. reports.go)
+	scripts     scriptsFile       // скрипты на событиях задач (см. scripts.go)
+	revisions   revisionsFile     // журнал ревизий для синхронизации (см. sync.go)
+	conflicts   conflictsFile     // очередь конфликтов синхронизации (см. conflicts.go)
+	versions    versionsFile      // история версий задач (см. versions.go)
+	retention   retentionFile     // политика хранения (см. retention.go)
+	encoding    StorageEncoding   // формат файла задач (см. encoding.go)
+	faults      *faultInjector    // сбои записи файла, если хранилище обёрнуто FaultStore (см. fault.go)
+
+	// snapshot -- отсортированный по ID список всех задач. Слайс никогда не меняется
+	// после публикации: мутация собирает новый и подменяет указатель.
+	snapshot atomic.Pointer[[]Task]
 }
 
 // NewTaskStore создаёт новое файловое хранилище задач.
 func NewTaskStore(filename string) *TaskStore {
-	return &TaskStore{filename: filename}
+	ts := &TaskStore{filename: filename}
+	for i := range ts.shards {
+		ts.shards[i].tasks = make(map[int]*Task)
+	}
+	return ts
+}
+
+func (ts *TaskStore) shard(id int) *taskShard {
+	return &ts.shards[uint(id)%taskShards]
+}
+
+// load один раз читает файл в память. Ошибка чтения запоминается: без данных работать нельзя.
+func (ts *TaskStore) load() error {
+	ts.loadOnce.Do(func() {
+		if err := ts.checkShutdownMarker(); err != nil {
+			ts.loadErr = err
+			return
+		}
+		// Задачи читаются из файла потоком и сразу ложатся в шарды: на больших файлах
+		// в памяти нет ни копии файла, ни промежуточного среза всех задач.
+		maxID, backfilled := 0, false
+		start := time.Now()
+		err := ts.scanTasks(context.Background(), func(t *Task) error {
+			// Задачам из старых файлов выдаём UUID один раз и сразу сохраняем,
+			// иначе после перезапуска у них были бы другие идентификаторы.
+			if t.UUID == "" {
+				t.UUID = newTaskUUID()
+				backfilled = true
+			}
+			// Приоритеты из старых файлов ("High", "") приводим к каноническому виду -- это миграция данных
+			// для JSON-хранилища (для PostgreSQL -- migrations/000006_task_priority_critical.up.sql).
+			if canonicalPriority(t) {
+				backfilled = true
+			}
+			ts.shard(t.ID).tasks[t.ID] = t
+			ts.uuids.Store(t.UUID, t.ID)
+			if t.ID > maxID {
+				maxID = t.ID
+			}
+			return nil
+		})
+		if ts.filename != "" {
+			observePersist("load", start, err)
+		}
+		if err != nil {
+			ts.loadErr = err
+			return
+		}
+		ts.lastID.Store(int64(maxID))
+		snap := ts.all()
+		ts.snapshot.Store(&snap)
+		if backfilled {
+			ts.loadErr = ts.SaveTasks(context.Background(), snap)
+		}
+	})
+	return ts.loadErr
+}
+
+// all собирает задачи из всех шардов, по возрастанию ID (как они лежат в файле).
+func (ts *TaskStore) all() []Task {
+	var out []Task
+	for i := range ts.shards {
+		sh := &ts.shards[i]
+		sh.mu.RLock()
+		for _, t := range sh.tasks {
+			out = append(out, *t)
+		}
+		sh.mu.RUnlock()
+	}
+	sortTasksByID(out)
+	if out == nil {
+		out = []Task{}
+	}
+	return out
+}
+
+func sortTasksByID(list []Task) {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+}
+
+// persist сохраняет текущее состояние в файл и, если запись удалась, публикует его как новый снимок.
+// При ошибке снимок остаётся прежним: читатели не видят изменение, которое будет откачено.
+//
+// Протокол записи: мутация меняет память, пишет файл и при ошибке откатывает память, не отпуская
+// saveMu (Create, Update, Delete, коммит транзакции, слияние). Файл всегда пишется целиком из памяти,
+// поэтому иначе чужая удачная запись могла бы сохранить на диск изменение, которое потом откатится.
+// SaveTasks при ошибке оставляет прежний файл (запись через временный файл), так что после любого
+// отказа память, счётчик ID и файл совпадают.
+func (ts *TaskStore) persist(ctx context.Context) error {
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+	return ts.persistLocked(ctx)
+}
+
+// persistLocked -- persist для вызывающего, который уже держит saveMu (коммит транзакции).
+// Внутри SaveBatch (batch.go) файл не пишется: снимок публикуется сразу, запись -- в конце пачки.
+func (ts *TaskStore) persistLocked(ctx context.Context) error {
+	if ts.closed.Load() {
+		return ErrStoreClosed
+	}
+	snap := ts.all()
+	if b := batchFor(ctx, ts); b != nil {
+		ts.snapshot.Store(&snap)
+		b.dirty = true
+		return nil
+	}
+	if err := ts.SaveTasks(ctx, snap); err != nil {
+		return err
+	}
+	ts.snapshot.Store(&snap)
+	return nil
+}
+
+// replace атомарно подменяет задачу в шарде (nil -- удалить) и возвращает прежнее значение.
+func (ts *TaskStore) replace(id int, t *Task) (*Task, bool) {
+	sh := ts.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	prev, ok := sh.tasks[id]
+	if t == nil {
+		delete(sh.tasks, id)
+	} else {
+		sh.tasks[id] = t
+	}
+	return prev, ok
+}
+
+// cloneTask делает копию задачи, не разделяющую слайсы и указатели с оригиналом.
+// У слайсов копии cap == len, так что append у читателя не испортит хранимое значение.
+func cloneTask(t *Task) *Task {
+	c := *t
+	if t.SubTasks != nil {
+		c.SubTasks = make([]SubTask, len(t.SubTasks))
+		copy(c.SubTasks, t.SubTasks)
+	}
+	if t.Tags != nil {
+		c.Tags = make([]string, len(t.Tags))
+		copy(c.Tags, t.Tags)
+	}
+	if t.Due != nil {
+		due := *t.Due
+		c.Due = &due
+	}
+	if t.CompletedAt != nil {
+		at := *t.CompletedAt
+		c.CompletedAt = &at
+	}
+	if t.CreatedAt != nil {
+		at := *t.CreatedAt
+		c.CreatedAt = &at
+	}
+	if t.Fields != nil {
+		c.Fields = make(Fields, len(t.Fields))
+		for k, v := range t.Fields {
+			c.Fields[k] = v
+		}
+	}
+	return &c
 }
 
 // SaveTasks сохраняет задачи в файл JSON.
 //
 // Важно: метод берёт Lock, потому что идёт запись на диск.
-// Форматирование JSON (MarshalIndent) используется для читаемости файла.
+// Формат файла -- SetEncoding: по умолчанию JSON с отступами (MarshalIndent) для читаемости.
+//
+// Ожидание блокировки и длительность записи попадают в метрики (storemetrics.go).
 //
 // [CHANGE-CONTEXT] Добавляется ctx -- первый аргумент. Уважаем отмену/таймаут до/после потенциально долгих шагов.
-func (ts *TaskStore) SaveTasks(ctx context.Context, tasks []Task) error {
+func (ts *TaskStore) SaveTasks(ctx context.Context, tasks []Task) (err error) {
 	// [CHANGE-CONTEXT]
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	ts.mu.Lock()         // Блокируем на запись
-	defer ts.mu.Unlock() // Разблокируем при выходе из функции
+	lockTimed(&ts.mu, lockFile) // Блокируем на запись
+	defer ts.mu.Unlock()        // Разблокируем при выходе из функции
 
 	if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
 		return err
 	}
 
-	data, err := json.MarshalIndent(tasks, "", "   ")
+	// Хранилище без файла (NewMemoryStore) живёт только в памяти.
+	if ts.filename == "" {
+		if ts.faults != nil {
+			return ts.faults.saveFault(ctx, "", nil)
+		}
+		return nil
+	}
+	defer func(start time.Time) { observePersist("save", start, err) }(time.Now())
+
+	data, err := encodeTasks(tasks, ts.Encoding())
 	if err != nil {
 		return err
 	}
@@ -55,8 +276,24 @@ func (ts *TaskStore) SaveTasks(ctx context.Context, tasks []Task) error {
 		return err
 	}
 
+	// Пишем во временный файл и подменяем им основной: если место на диске кончится посреди
+	// записи, на диске останется прежняя целая версия, а не обрезанный файл.
 	// 0644 - права доступа (rw-r--r--)
-	return os.WriteFile(ts.filename, data, 0644)
+	tmp := ts.filename + ".tmp"
+	if ts.faults != nil {
+		if err := ts.faults.saveFault(ctx, tmp, data); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, ts.filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
 }
 
 // LoadTasks загружает задачи из файла.
@@ -66,73 +303,107 @@ func (ts *TaskStore) SaveTasks(ctx context.Context, tasks []Task) error {
 //
 // [CHANGE-CONTEXT] Добавляется ctx -- первый аргумент. Уважаем отмену/таймаут до/после потенциально долгих шагов.
 func (ts *TaskStore) LoadTasks(ctx context.Context) ([]Task, error) {
-	if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
+	tasks := []Task{}
+	err := ts.scanTasks(ctx, func(t *Task) error {
+		tasks = append(tasks, *t)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return tasks, nil
+}
 
-	ts.mu.RLock()         // Блокируем только на чтение
-	defer ts.mu.RUnlock() // Разблокируем при выходе
-
+// scanTasks читает файл задач потоково (streamTasks) и отдаёт задачи в fn по одной.
+// Так load раскладывает задачи по шардам сразу, без промежуточных копий файла и среза.
+// ctx проверяется раз в scanCtxEvery задач: отмена прерывает даже очень большой файл.
+func (ts *TaskStore) scanTasks(ctx context.Context, fn func(*Task) error) error {
 	if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
-		return nil, err
+		return err
 	}
 
-	data, err := os.ReadFile(ts.filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Если файла нет — это нормальная ситуация для первого запуска.
-			return []Task{}, nil
-		}
-		return nil, err
-	}
+	rlockTimed(&ts.mu, lockFile) // Блокируем только на чтение
+	defer ts.mu.RUnlock()        // Разблокируем при выходе
 
 	if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
-		return nil, err
-	}
-
-	// Пустой файл — не ошибка, просто нет задач.
-	if len(strings.TrimSpace(string(data))) == 0 {
-		return []Task{}, nil
+		return err
 	}
 
-	var tasks []Task
-	if err := json.Unmarshal(data, &tasks); err != nil {
-		return nil, err
+	if ts.filename == "" {
+		return nil
 	}
 
-	if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
-		return nil, err
+	f, err := os.Open(ts.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Если файла нет — это нормальная ситуация для первого запуска.
+			return nil
+		}
+		return err
 	}
-
-	return tasks, nil
+	defer f.Close()
+
+	// Файл может быть в любом формате (encoding.go); пустой файл — не ошибка, просто нет задач.
+	n := 0
+	return streamTasks(f, func(t *Task) error {
+		if n++; n%scanCtxEvery == 0 {
+			if err := ctx.Err(); err != nil { // [CHANGE-CONTEXT]
+				return err
+			}
+		}
+		return fn(t)
+	})
 }
 
+// scanCtxEvery -- как часто scanTasks проверяет отмену контекста.
+const scanCtxEvery = 1024
+
 func (ts *TaskStore) Create(ctx context.Context, task *Task) error {
 	// Проверяем контекст перед операцией - например не было ли отмены
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// 1. Загрузить все текущие задачи из файла с помощью существующего ts.LoadTasks(ctx)
-	tasks, err := ts.LoadTasks(ctx)
-	if err != nil {
+	// 1. Убедиться, что задачи загружены из файла
+	if err := ts.load(); err != nil {
 		return err
 	}
 
-	// 2. Сгенерировать новый ID (используйте ваш calcNextID) и присвоить его task.ID
-	maxID := 0
-	for _, t := range tasks {
-		if t.ID > maxID {
-			maxID = t.ID
-		}
+	// Изменение в памяти, запись и откат -- под saveMu (см. persistLocked): иначе чужая запись
+	// могла бы унести на диск изменение, которое мы потом откатим.
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+
+	// 2. Сгенерировать новый ID и занять UUID (он должен быть уникальным)
+	id := int(ts.lastID.Add(1))
+	if task.UUID == "" {
+		task.UUID = newTaskUUID()
+	}
+	if _, taken := ts.uuids.LoadOrStore(task.UUID, id); taken {
+		ts.releaseID(id)
+		return ErrDuplicateUUID
 	}
-	task.ID = maxID + 1
+	task.ID = id
 
-	// 3. Добавить (append) новый task в слайс
-	tasks = append(tasks, *task)
+	// 3. Положить копию задачи в её шард
+	ts.replace(task.ID, cloneTask(task))
 
-	// 4. Сохранить обновленный слайс обратно в файл через ts.SaveTasks(ctx, ...)
-	return ts.SaveTasks(ctx, tasks)
+	// 4. Сохранить состояние в файл; если не вышло -- откатить изменение в памяти и счётчик ID
+	if err := ts.persistLocked(ctx); err != nil {
+		ts.replace(task.ID, nil)
+		ts.uuids.Delete(task.UUID)
+		ts.releaseID(id)
+		task.ID = 0
+		return err
+	}
+	return nil
+}
+
+// releaseID возвращает счётчику ID, выданный неудавшемуся созданию, если после него ID больше
+// не выдавались (иначе остаётся дырка -- ID не переиспользуются). Так после отказа записи счётчик
+// совпадает с тем, что восстановится из файла при перезапуске.
+func (ts *TaskStore) releaseID(id int) {
+	ts.lastID.CompareAndSwap(int64(id), int64(id-1))
 }
 
 // Возвращает слайс со всем задачами из БД
@@ -141,12 +412,16 @@ func (ts *TaskStore) GetAll(ctx context.Context, userrID int) ([]Task, error) {
 		return nil, err
 	}
 
-	tasks, err := ts.LoadTasks(ctx)
-	if err != nil {
+	if err := ts.load(); err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	// Снимок общий для всех читателей -- отдаём копию слайса, чтобы вызывающий мог его менять.
+	// Это одна аллокация без блокировок и сортировки; сборка из шардов -- только при записи.
+	snap := *ts.snapshot.Load()
+	out := make([]Task, len(snap))
+	copy(out, snap)
+	return out, nil
 }
 
 // Ищет и возвращает задачу по ID
@@ -156,18 +431,31 @@ func (ts *TaskStore) GetByID(ctx context.Context, id int) (*Task, error) {
 		return nil, err
 	}
 
-	tasks, err := ts.LoadTasks(ctx)
-	if err != nil {
+	if err := ts.load(); err != nil {
 		return nil, err
 	}
 
-	for i := range tasks {
-		if tasks[i].ID == id {
-			return &tasks[i], nil
-		}
+	t := ts.published(id)
+	if t == nil {
+		return nil, ErrTaskNotFound
 	}
+	return cloneTask(t), nil
+}
 
-	return nil, ErrTaskNotFound
+// published ищет задачу в опубликованном снимке (он отсортирован по ID). В отличие от шарда,
+// в снимке нет изменений, запись которых ещё идёт: задача не мелькнёт и не пропадёт из-за мутации,
+// которую потом откатят. Возвращённую задачу менять нельзя -- она общая для всех читателей.
+func (ts *TaskStore) published(id int) *Task {
+	p := ts.snapshot.Load()
+	if p == nil {
+		return nil
+	}
+	snap := *p
+	i := sort.Search(len(snap), func(i int) bool { return snap[i].ID >= id })
+	if i == len(snap) || snap[i].ID != id {
+		return nil
+	}
+	return &snap[i]
 }
 
 // Update обновляет сущетвующую задачу
@@ -176,32 +464,45 @@ func (ts *TaskStore) Update(ctx context.Context, task *Task, userID int) error {
 		return err
 	}
 
-	tasks, err := ts.LoadTasks(ctx)
-	if err != nil {
+	if err := ts.load(); err != nil {
 		return err
 	}
 
-	// Флаг, который покажет, нашли ли мы задачу
-	found := false
-
-	for i := range tasks {
-		if tasks[i].ID == task.ID {
-			// Обновляем поля прямо в оригинальном слайсе
-			tasks[i].Title = task.Title
-			tasks[i].Done = task.Done
-			tasks[i].Priority = task.Priority
-			found = true
-			break // Нашли, дальше крутить цикл нет смысла, выходим
-		}
-	}
+	// Изменение, запись и откат -- под saveMu, как в Create.
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
 
-	// Если обошли весь цикл и никого не нашли — возвращаем ошибку
-	if !found {
+	// Обновление под блокировкой шарда: читаем текущую версию и кладём новую копию
+	sh := ts.shard(task.ID)
+	sh.mu.Lock()
+	prev, ok := sh.tasks[task.ID]
+	if !ok {
+		sh.mu.Unlock()
 		return ErrTaskNotFound
 	}
+	updated := *prev
+	updated.Title = task.Title
+	updated.Done = task.Done
+	updated.Status = task.Status
+	updated.Priority = task.Priority
+	updated.AssignedTo = task.AssignedTo
+	updated.Description = task.Description
+	updated.Tags = task.Tags
+	updated.Due = task.Due
+	updated.EstimateMinutes = task.EstimateMinutes
+	updated.CompletedAt = task.CompletedAt
+	if task.Fields != nil {
+		updated.Fields = task.Fields
+	}
+	sh.tasks[task.ID] = cloneTask(&updated)
+	sh.mu.Unlock()
 
-	// Записываем обновленный слайс обратно в файл на диск!
-	return ts.SaveTasks(ctx, tasks)
+	// Записываем обновленное состояние обратно в файл на диск!
+	if err := ts.persistLocked(ctx); err != nil {
+		ts.replace(task.ID, prev)
+		return err
+	}
+	return nil
 }
 
 // Delete Удаляет задачу по id
@@ -210,28 +511,26 @@ func (ts *TaskStore) Delete(ctx context.Context, id int, userID int) error {
 		return err
 	}
 
-	tasks, err := ts.LoadTasks(ctx)
-	if err != nil {
+	if err := ts.load(); err != nil {
 		return err
 	}
 
-	found := false
-
-	for i := range tasks {
-		if tasks[i].ID == id {
-			// Удаляем найденную задачу из слайса
-			tasks = append(tasks[:i], tasks[i+1:]...)
-			found = true
-			break
-		}
-	}
+	// Изменение, запись и откат -- под saveMu, как в Create.
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
 
+	prev, found := ts.replace(id, nil)
 	if !found {
 		return ErrTaskNotFound
 	}
 
-	// Если задача найдена и удалена, сбрасываем слайс в память
-	return ts.SaveTasks(ctx, tasks)
+	// Если задача найдена и удалена, сбрасываем состояние на диск
+	if err := ts.persistLocked(ctx); err != nil {
+		ts.replace(id, prev)
+		return err
+	}
+	ts.uuids.Delete(prev.UUID)
+	return nil
 }
 
 func (ts *TaskStore) CreateSubtask(ctx context.Context, subtask *SubTask) error {
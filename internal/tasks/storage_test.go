@@ -0,0 +1,216 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// Откат мутаций при отказе записи файла: после ошибки память, счётчик ID и файл на диске
+// совпадают с тем, что было до мутации, и с тем, что прочитает перезапущенный сервер.
+
+// faultyStore -- файловое хранилище с одной задачей (ID 1) под FaultStore.
+func faultyStore(t *testing.T) (*TaskStore, *FaultStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	ts := NewTaskStore(path)
+	fs := NewFaultStore(ts)
+	first := Task{UserID: 1, AssignedTo: 1, Title: "Первая", Status: StatusTodo, Priority: PriorityMedium}
+	if err := fs.Create(context.Background(), &first); err != nil {
+		t.Fatal(err)
+	}
+	return ts, fs, path
+}
+
+// failSaves -- следующие n записей файла падают с ENOSPC; partial -- с недописанным временным файлом.
+func failSaves(t *testing.T, fs *FaultStore, n int, partial bool) {
+	t.Helper()
+	if err := fs.SetFaults([]Fault{{Op: FaultOpSave, Error: FaultDiskFull, Count: n, PartialWrite: partial}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkState сверяет память (GetAll, счётчик ID) и файл с ожидаемым, затем "перезапускает"
+// хранилище поверх того же файла и проверяет, что оно видит то же самое.
+func checkState(t *testing.T, ts *TaskStore, path string, wantFile []byte, wantLastID int64) {
+	t.Helper()
+	ctx := context.Background()
+	if got := readFile(t, path); !bytes.Equal(got, wantFile) {
+		t.Errorf("file changed after failed save:\n%s\nwant:\n%s", got, wantFile)
+	}
+	if got := ts.lastID.Load(); got != wantLastID {
+		t.Errorf("lastID = %d, want %d", got, wantLastID)
+	}
+	inMemory, err := ts.GetAll(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewTaskStore(path)
+	onDisk, err := restarted.GetAll(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inMemory, onDisk) {
+		t.Errorf("memory and file disagree:\nmemory %+v\nfile   %+v", inMemory, onDisk)
+	}
+	if got := restarted.lastID.Load(); got != wantLastID {
+		t.Errorf("after restart lastID = %d, want %d", got, wantLastID)
+	}
+}
+
+func TestCreateRollbackOnSaveFailure(t *testing.T) {
+	for _, partial := range []bool{false, true} {
+		ts, fs, path := faultyStore(t)
+		ctx := context.Background()
+		before := readFile(t, path)
+
+		failSaves(t, fs, 1, partial)
+		task := Task{UUID: "00000000-0000-4000-8000-000000000002", UserID: 1, AssignedTo: 1, Title: "Вторая",
+			Status: StatusTodo, Priority: PriorityMedium}
+		err := fs.Create(ctx, &task)
+		if !errors.Is(err, syscall.ENOSPC) {
+			t.Fatalf("partial=%v: Create error = %v, want ENOSPC", partial, err)
+		}
+		if task.ID != 0 {
+			t.Errorf("partial=%v: failed Create left ID %d", partial, task.ID)
+		}
+		if _, err := fs.GetByID(ctx, 2); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("partial=%v: rolled back task is visible: %v", partial, err)
+		}
+		checkState(t, ts, path, before, 1)
+
+		// Счётчик и UUID освобождены: повтор получает тот же ID, что выдал бы перезапущенный сервер.
+		if err := fs.Create(ctx, &task); err != nil {
+			t.Fatalf("partial=%v: retry: %v", partial, err)
+		}
+		if task.ID != 2 {
+			t.Errorf("partial=%v: retry got ID %d, want 2", partial, task.ID)
+		}
+	}
+}
+
+func TestUpdateRollbackOnSaveFailure(t *testing.T) {
+	ts, fs, path := faultyStore(t)
+	ctx := context.Background()
+	before := readFile(t, path)
+	prev, err := fs.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failSaves(t, fs, 1, false)
+	changed := *prev
+	changed.Title, changed.Done, changed.Status, changed.Tags = "Изменённая", true, StatusDone, []string{"home"}
+	if err := fs.Update(ctx, &changed, 1); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Update error = %v, want ENOSPC", err)
+	}
+	got, err := fs.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, prev) {
+		t.Errorf("task not rolled back:\ngot  %+v\nwant %+v", got, prev)
+	}
+	checkState(t, ts, path, before, 1)
+}
+
+func TestDeleteRollbackOnSaveFailure(t *testing.T) {
+	ts, fs, path := faultyStore(t)
+	ctx := context.Background()
+	before := readFile(t, path)
+	prev, err := fs.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failSaves(t, fs, 1, true)
+	if err := fs.Delete(ctx, 1, 1); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Delete error = %v, want ENOSPC", err)
+	}
+	got, err := fs.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("deleted task not restored: %v", err)
+	}
+	if !reflect.DeepEqual(got, prev) {
+		t.Errorf("task not rolled back:\ngot  %+v\nwant %+v", got, prev)
+	}
+	checkState(t, ts, path, before, 1)
+
+	// UUID по-прежнему занят восстановленной задачей.
+	dup := Task{UUID: prev.UUID, UserID: 1, AssignedTo: 1, Title: "Дубль", Status: StatusTodo, Priority: PriorityMedium}
+	if err := fs.Create(ctx, &dup); !errors.Is(err, ErrDuplicateUUID) {
+		t.Errorf("Create with UUID of restored task: %v, want ErrDuplicateUUID", err)
+	}
+}
+
+// TestReadersDoNotSeeRolledBackChanges -- пока идёт неудачная запись, читатели видят только
+// последнее записанное состояние: ни изменённую задачу, ни созданную, ни пропажу удалённой.
+func TestReadersDoNotSeeRolledBackChanges(t *testing.T) {
+	ts, fs, _ := faultyStore(t)
+	ctx := context.Background()
+	prev, err := fs.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const attempts = 5
+	if err := fs.SetFaults([]Fault{{Op: FaultOpSave, Error: FaultDiskFull, LatencyMS: 20, Count: 3 * attempts}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				got, err := ts.GetByID(ctx, 1)
+				if err != nil {
+					t.Errorf("task 1 disappeared during failed delete: %v", err)
+					return
+				}
+				if !reflect.DeepEqual(got, prev) {
+					t.Errorf("reader saw uncommitted update: %+v", got)
+					return
+				}
+				if _, err := ts.GetByID(ctx, 2); !errors.Is(err, ErrTaskNotFound) {
+					t.Errorf("reader saw uncommitted create: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for range attempts {
+		changed := *prev
+		changed.Title = "Изменённая"
+		if err := fs.Update(ctx, &changed, 1); !errors.Is(err, syscall.ENOSPC) {
+			t.Errorf("Update error = %v, want ENOSPC", err)
+		}
+		task := Task{UserID: 1, AssignedTo: 1, Title: "Вторая", Status: StatusTodo, Priority: PriorityMedium}
+		if err := fs.Create(ctx, &task); !errors.Is(err, syscall.ENOSPC) {
+			t.Errorf("Create error = %v, want ENOSPC", err)
+		}
+		if err := fs.Delete(ctx, 1, 1); !errors.Is(err, syscall.ENOSPC) {
+			t.Errorf("Delete error = %v, want ENOSPC", err)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+}
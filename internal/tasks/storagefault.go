@@ -0,0 +1,133 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"syscall"
+
+	"task-manager/internal/metrics"
+)
+
+// Отказы диска: место кончилось, нет прав на файл, файловая система смонтирована только для чтения.
+// Повторять такую запись бесполезно, пока человек не починит диск, поэтому при первом отказе
+// сервис переходит в режим "хранилище неисправно": фоновые изменения встают на паузу,
+// срабатывает обработчик (сервер включает режим только для чтения) и уходит уведомление.
+// Первая удачная запись после починки снимает отметку.
+
+// Виды отказов хранилища (StorageError.Kind).
+const (
+	StorageDiskFull   = "disk_full"
+	StoragePermission = "permission_denied"
+	StorageReadOnlyFS = "read_only_fs"
+)
+
+var storageFaultGauge = metrics.NewGaugeVec("storage_fault",
+	"1 if the last storage write failed with a disk error, by kind.", "kind")
+
+// StorageError -- запись в хранилище не удалась из-за диска, а не из-за данных.
+type StorageError struct {
+	Kind string // StorageDiskFull, StoragePermission или StorageReadOnlyFS
+	Path string // файл, на котором случился отказ (если известен)
+	Err  error
+}
+
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("storage %s: %v", e.Kind, e.Err)
+}
+
+func (e *StorageError) Unwrap() error { return e.Err }
+
+// Hint -- что сделать администратору, чтобы запись снова заработала.
+func (e *StorageError) Hint() string {
+	path := e.Path
+	if path == "" {
+		path = "the storage directory"
+	}
+	switch e.Kind {
+	case StorageDiskFull:
+		return fmt.Sprintf("No space left on the volume with %s: free disk space or raise the quota, then turn read-only mode off", path)
+	case StoragePermission:
+		return fmt.Sprintf("No permission to write %s: make the file and its directory writable by the server user, then turn read-only mode off", path)
+	case StorageReadOnlyFS:
+		return fmt.Sprintf("The file system with %s is mounted read-only: remount it read-write, then turn read-only mode off", path)
+	}
+	return e.Err.Error()
+}
+
+// AsStorageError распознаёт в err отказ диска. Уже классифицированная ошибка возвращается как есть,
+// остальные (ошибки данных, отмена, сеть) -- nil.
+func AsStorageError(err error) *StorageError {
+	if err == nil {
+		return nil
+	}
+	var se *StorageError
+	if errors.As(err, &se) {
+		return se
+	}
+	kind := ""
+	switch {
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		kind = StorageDiskFull
+	case errors.Is(err, syscall.EROFS):
+		kind = StorageReadOnlyFS
+	case errors.Is(err, fs.ErrPermission):
+		kind = StoragePermission
+	default:
+		return nil
+	}
+	se = &StorageError{Kind: kind, Err: err}
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		se.Path = pe.Path
+	}
+	return se
+}
+
+// SetStorageAlert задаёт, кого уведомлять об отказе диска (пусто -- уведомление без адресата,
+// по умолчанию оно попадает в лог), и что сделать при отказе (сервер включает режим только
+// для чтения). Вызывать до начала работы сервиса.
+func (s *Service) SetStorageAlert(users []int, onFault func(StorageError)) {
+	s.storageAlertUsers = users
+	s.onStorageFault = onFault
+}
+
+// StorageFault -- текущий отказ диска (nil -- запись работает).
+func (s *Service) StorageFault() *StorageError {
+	return s.storageFault.Load()
+}
+
+// checkStorage смотрит на итог записи: отказ диска включает режим неисправности (один раз,
+// до починки), удачная запись его снимает. Возвращает err без изменений.
+func (s *Service) checkStorage(ctx context.Context, err error) error {
+	if err == nil {
+		if prev := s.storageFault.Swap(nil); prev != nil {
+			log.Printf("storage: writes work again after %s", prev.Kind)
+			storageFaultGauge.WithLabelValues(prev.Kind).Set(0)
+		}
+		return nil
+	}
+	se := AsStorageError(err)
+	if se == nil || !s.storageFault.CompareAndSwap(nil, se) {
+		return err
+	}
+
+	log.Printf("ERROR: storage: %v. %s", se, se.Hint())
+	storageFaultGauge.WithLabelValues(se.Kind).Set(1)
+	if s.onStorageFault != nil {
+		s.onStorageFault(*se)
+	}
+	recipients := s.storageAlertUsers
+	if len(recipients) == 0 {
+		recipients = []int{0}
+	}
+	for _, userID := range recipients {
+		n := Notification{UserID: userID, Source: "storage", Text: "Хранилище не принимает изменения: " + se.Hint()}
+		if nerr := s.notify(context.WithoutCancel(ctx), n); nerr != nil {
+			log.Printf("storage: notify user=%d error: %v", userID, nerr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"sync"
+	"time"
+
+	"task-manager/internal/metrics"
+)
+
+// Метрики блокировок и записи на диск. Все изменения JSON-хранилища проходят через один
+// писатель (saveMu), поэтому рост ожидания блокировки и длительности записи -- первый
+// признак того, что хранилище не справляется, задолго до жалоб на медленный API.
+var (
+	lockWait = metrics.NewHistogramVec("store_lock_wait_seconds",
+		"Time spent waiting to acquire a storage lock, by lock.", lockBuckets, "lock")
+	persistDuration = metrics.NewHistogramVec("store_persist_duration_seconds",
+		"Duration of reading or writing the tasks file, by operation and result.", persistBuckets, "op", "result")
+)
+
+// Ожидание блокировки обычно микросекунды, запись файла -- миллисекунды: корзины по умолчанию
+// (от 5ms) схлопнули бы обе метрики в первую корзину.
+var (
+	lockBuckets    = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+	persistBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// Имена блокировок в метке lock.
+const (
+	lockSave = "save" // saveMu: единственный писатель файла задач
+	lockFile = "file" // TaskStore.mu: сам файл задач на время чтения/записи
+	lockGit  = "git"  // GitStore.mu: "записать файл + закоммитить"
+	lockSync = "sync" // Service.syncMu: мутации POST /api/v1/sync
+)
+
+// lockTimed берёт блокировку и записывает, сколько пришлось ждать.
+func lockTimed(l sync.Locker, name string) {
+	start := time.Now()
+	l.Lock()
+	lockWait.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// rlockTimed -- lockTimed для разделяемой блокировки.
+func rlockTimed(l *sync.RWMutex, name string) {
+	start := time.Now()
+	l.RLock()
+	lockWait.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// observePersist записывает длительность операции с файлом задач (op: save или load).
+func observePersist(op string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	persistDuration.WithLabelValues(op, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,642 @@
+package tasks
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Синхронизация для офлайн-клиентов (POST /api/v1/sync).
+//
+// Хранилище ведёт журнал ревизий: у каждой задачи (по UUID) -- номер последнего изменения из общего
+// возрастающего счётчика, у удалённой -- надгробие с номером удаления. Токен синхронизации -- номер
+// последней ревизии, которую клиент видел. Клиент присылает токен и свои изменения (каждое -- с ревизией,
+// на которой оно основано), сервер применяет их с разрешением конфликтов и возвращает всё, что
+// изменилось после токена, вместе с новым токеном.
+
+// Операции мутаций синхронизации.
+const (
+	SyncUpsert = "upsert"
+	SyncDelete = "delete"
+)
+
+// Стратегии разрешения конфликтов: задачу после base_rev уже изменили или удалили на сервере.
+// По умолчанию -- SetSyncStrategy (SYNC_CONFLICT_STRATEGY), запрос может выбрать свою.
+const (
+	SyncServerWins    = "server_wins"     // мутация не применяется, клиент получает версию сервера
+	SyncClientWins    = "client_wins"     // мутация применяется поверх (удалённая задача создаётся заново)
+	SyncLastWriteWins = "last_write_wins" // побеждает более позднее изменение: updated_at мутации против времени ревизии
+	SyncManual        = "manual"          // мутация уходит в очередь конфликтов (/api/v1/conflicts), решает пользователь
+)
+
+// Итоги мутаций.
+const (
+	SyncApplied   = "applied"
+	SyncConflict  = "conflict"
+	SyncRejected  = "rejected"  // отклонена проверками (хук, пользовательские поля)
+	SyncDiscarded = "discarded" // конфликт решён в пользу сервера (POST /api/v1/conflicts/{id}/resolve)
+)
+
+// defaultSyncLimit -- сколько изменений отдаём за один запрос инкрементальной синхронизации
+// (SyncRequest.Limit, не больше 5000).
+const defaultSyncLimit = 500
+
+var (
+	ErrSyncUnsupported  = errors.New("sync is not supported by this storage")
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+)
+
+// SyncRecord -- ревизия задачи в журнале синхронизации. Deleted -- надгробие: задача удалена.
+type SyncRecord struct {
+	UUID      string    `json:"uuid"`
+	TaskID    int       `json:"task_id"`
+	Rev       int64     `json:"rev"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// RevisionStore -- опциональная возможность хранилища вести журнал ревизий для синхронизации.
+type RevisionStore interface {
+	// BumpRevision выдаёт записи следующий номер ревизии (rec.Rev) и заменяет ею прежнюю запись той же задачи.
+	BumpRevision(ctx context.Context, rec *SyncRecord) error
+	// Revision -- текущая ревизия задачи (ok == false -- задача не менялась с включения журнала).
+	Revision(ctx context.Context, uuid string) (rec SyncRecord, ok bool, err error)
+	// RevisionsSince -- записи с ревизией больше since по возрастанию, не больше limit (0 -- все).
+	RevisionsSince(ctx context.Context, since int64, limit int) ([]SyncRecord, error)
+	// HeadRevision -- номер последней выданной ревизии (0 -- журнал пуст).
+	HeadRevision(ctx context.Context) (int64, error)
+}
+
+// SyncMutation -- изменение, сделанное клиентом офлайн. BaseRev -- ревизия задачи, которую клиент видел
+// перед изменением (0 -- задача создана на клиенте). Task -- задача целиком (как в PUT), только для upsert.
+// UpdatedAt -- когда изменение сделано на клиенте (для last_write_wins; нет -- время синхронизации).
+type SyncMutation struct {
+	Op        string             `json:"op" validate:"required,oneof=upsert delete"`
+	UUID      string             `json:"uuid" validate:"required,clientid"`
+	BaseRev   int64              `json:"base_rev" validate:"min=0"`
+	Task      *UpdateTaskRequest `json:"task,omitempty" validate:"required_if=Op upsert"`
+	UpdatedAt *time.Time         `json:"updated_at,omitempty"`
+}
+
+// SyncRequest -- тело POST /api/v1/sync. Пустой Token -- первая (полная) синхронизация.
+type SyncRequest struct {
+	Token     string         `json:"token"`
+	Strategy  string         `json:"strategy" validate:"omitempty,oneof=server_wins client_wins last_write_wins manual"`
+	Mutations []SyncMutation `json:"mutations" validate:"max=500,dive"`
+	Limit     int            `json:"limit" validate:"omitempty,min=1,max=5000"`
+}
+
+// SyncResult -- итог одной мутации. Task -- версия сервера после мутации (applied) или текущая
+// версия сервера (conflict); у удалённой задачи её нет, Deleted == true. ConflictID -- конфликт
+// в очереди (стратегия manual).
+type SyncResult struct {
+	UUID       string `json:"uuid"`
+	Op         string `json:"op"`
+	Status     string `json:"status"`
+	Rev        int64  `json:"rev"`
+	Deleted    bool   `json:"deleted,omitempty"`
+	Task       *Task  `json:"task,omitempty"`
+	ConflictID int    `json:"conflict_id,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SyncChange -- изменение на сервере после токена клиента: ревизия и задача (у надгробия задачи нет).
+type SyncChange struct {
+	SyncRecord
+	Task *Task `json:"task,omitempty"`
+}
+
+// SyncResponse -- ответ POST /api/v1/sync. Full -- Changes содержат все задачи (первая синхронизация):
+// клиенту нужно заменить ими локальную базу. More -- изменения не поместились в limit, следующий запрос
+// с новым токеном вернёт остальные.
+type SyncResponse struct {
+	Token   string       `json:"token"`
+	Full    bool         `json:"full"`
+	More    bool         `json:"more"`
+	Results []SyncResult `json:"results"`
+	Changes []SyncChange `json:"changes"`
+}
+
+// SetSyncStrategy задаёт стратегию разрешения конфликтов синхронизации по умолчанию
+// (пусто -- server_wins). Вызывать до начала работы сервиса.
+func (s *Service) SetSyncStrategy(strategy string) {
+	s.syncStrategy = strategy
+}
+
+// tracksRevisions -- ведёт ли хранилище журнал ревизий.
+func (s *Service) tracksRevisions() bool {
+	_, ok := s.capabilities().(RevisionStore)
+	return ok
+}
+
+// bumpRevision записывает изменение задачи в журнал ревизий. Ошибка записи не отменяет само изменение
+// (оно уже сохранено) -- только пишется в лог: синхронизирующиеся клиенты его не увидят, пока задачу
+// не изменят снова.
+func (s *Service) bumpRevision(ctx context.Context, typ string, t Task, at time.Time) {
+	store, ok := s.capabilities().(RevisionStore)
+	if !ok || t.UUID == "" {
+		return
+	}
+	rec := SyncRecord{UUID: t.UUID, TaskID: t.ID, Deleted: typ == ChangeDeleted, ChangedAt: at}
+	if err := store.BumpRevision(context.WithoutCancel(ctx), &rec); err != nil {
+		log.Printf("request_id=%s sync: revision task=%d uuid=%s error: %v", appMiddleware.GetRequestID(ctx), t.ID, t.UUID, err)
+	}
+}
+
+// Sync применяет мутации клиента и возвращает изменения после его токена.
+//
+// Мутации применяются по одной и не атомарно: каждая получает свой итог, отклонённая не отменяет
+// остальные. Проверки и хуки -- те же, что у обычных POST/PUT/DELETE. Изменения самого клиента
+// в Changes не повторяются: они уже есть в Results.
+func (s *Service) Sync(ctx context.Context, userID int, req SyncRequest) (SyncResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return SyncResponse{}, err
+	}
+	store, ok := s.capabilities().(RevisionStore)
+	if !ok {
+		return SyncResponse{}, ErrSyncUnsupported
+	}
+
+	head, err := store.HeadRevision(ctx)
+	if err != nil {
+		return SyncResponse{}, err
+	}
+	var since int64
+	full := req.Token == ""
+	if !full {
+		since, err = strconv.ParseInt(req.Token, 10, 64)
+		if err != nil || since < 0 || since > head {
+			return SyncResponse{}, ErrInvalidSyncToken
+		}
+		// Надгробия после токена могли уже удалить: клиент не узнает об удалении.
+		horizon, err := s.tombstoneHorizon(ctx)
+		if err != nil {
+			return SyncResponse{}, err
+		}
+		if since < horizon {
+			return SyncResponse{}, ErrInvalidSyncToken
+		}
+	}
+	strategy := cmp.Or(req.Strategy, s.syncStrategy, SyncServerWins)
+	if strategy == SyncManual {
+		// Проверить заранее: иначе часть мутаций применится до первого конфликта.
+		if _, err := s.conflicts(); err != nil {
+			return SyncResponse{}, err
+		}
+	}
+
+	resp := SyncResponse{Full: full, Results: []SyncResult{}, Changes: []SyncChange{}}
+	own := make(map[int64]bool, len(req.Mutations))
+	if len(req.Mutations) > 0 {
+		lockTimed(&s.syncMu, lockSync)
+		// Пачка мутаций -- одна запись файла задач, а не по записи на мутацию.
+		err := s.SaveBatch(ctx, func(ctx context.Context) error {
+			for _, m := range req.Mutations {
+				res, err := s.applySync(ctx, store, userID, strategy, m)
+				if err != nil {
+					return fmt.Errorf("mutation %s %s: %w", m.Op, m.UUID, err)
+				}
+				if res.Status == SyncApplied {
+					own[res.Rev] = true
+				}
+				resp.Results = append(resp.Results, res)
+			}
+			return nil
+		})
+		s.syncMu.Unlock()
+		if err != nil {
+			return SyncResponse{}, err
+		}
+	}
+
+	if full {
+		err = s.syncFull(ctx, store, userID, &resp)
+	} else {
+		limit := req.Limit
+		if limit == 0 {
+			limit = defaultSyncLimit
+		}
+		err = s.syncSince(ctx, store, userID, since, limit, own, &resp)
+	}
+	if err != nil {
+		return SyncResponse{}, err
+	}
+	return resp, nil
+}
+
+// syncFull -- первая синхронизация: все задачи с их ревизиями, токен -- ревизия до чтения задач
+// (изменения, сделанные во время чтения, придут ещё раз в следующей синхронизации).
+func (s *Service) syncFull(ctx context.Context, store RevisionStore, userID int, resp *SyncResponse) error {
+	head, err := store.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+	recs, err := store.RevisionsSince(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+	revs := make(map[string]SyncRecord, len(recs))
+	for _, rec := range recs {
+		revs[rec.UUID] = rec
+	}
+	list, err := s.GetAllTasks(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		t := &list[i]
+		rec, ok := revs[t.UUID]
+		if !ok || rec.Deleted {
+			// Задачу не меняли с включения журнала (или UUID снова занят после надгробия): ревизия 0.
+			rec = SyncRecord{UUID: t.UUID, TaskID: t.ID}
+		}
+		resp.Changes = append(resp.Changes, SyncChange{SyncRecord: rec, Task: t})
+	}
+	resp.Token = strconv.FormatInt(head, 10)
+	return nil
+}
+
+// syncSince -- изменения с ревизией больше since, кроме сделанных этим же запросом (own).
+// Задача, ставшая задачей чужого закрытого проекта, приходит пользователю удалённой.
+func (s *Service) syncSince(ctx context.Context, store RevisionStore, userID int, since int64, limit int, own map[int64]bool, resp *SyncResponse) error {
+	recs, err := store.RevisionsSince(ctx, since, limit+1)
+	if err != nil {
+		return err
+	}
+	if len(recs) > limit {
+		recs, resp.More = recs[:limit], true
+	}
+	token := since
+	for _, rec := range recs {
+		token = rec.Rev
+		if own[rec.Rev] {
+			continue
+		}
+		ch := SyncChange{SyncRecord: rec}
+		if !rec.Deleted {
+			t, err := s.repo.GetByID(ctx, rec.TaskID)
+			if errors.Is(err, ErrTaskNotFound) {
+				continue // удалена после чтения журнала -- надгробие придёт следующей синхронизацией
+			}
+			if err != nil {
+				return err
+			}
+			if s.canSee(userID, *t) {
+				ch.Task = t
+			} else {
+				ch.Deleted = true
+			}
+		}
+		resp.Changes = append(resp.Changes, ch)
+	}
+	resp.Token = strconv.FormatInt(token, 10)
+	return nil
+}
+
+// applySync применяет одну мутацию по стратегии strategy. Ошибка -- только сбой (хранилище, контекст);
+// отказы проверок и конфликты -- в итоге мутации.
+func (s *Service) applySync(ctx context.Context, store RevisionStore, userID int, strategy string, m SyncMutation) (SyncResult, error) {
+	m.UUID = normalizeClientID(m.UUID)
+	rec, cur, err := s.syncState(ctx, store, m.UUID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if cur != nil && !s.canSee(userID, *cur) {
+		return SyncResult{UUID: m.UUID, Op: m.Op, Status: SyncRejected, Code: "project_forbidden",
+			Error: ErrProjectForbidden.Error()}, nil
+	}
+	if m.BaseRev == rec.Rev {
+		return s.applyMutation(ctx, store, userID, m, rec, cur)
+	}
+
+	conflict := SyncResult{UUID: m.UUID, Op: m.Op, Status: SyncConflict, Rev: rec.Rev, Task: cur, Deleted: cur == nil}
+	switch strategy {
+	case SyncClientWins:
+		return s.applyMutation(ctx, store, userID, m, rec, cur)
+	case SyncLastWriteWins:
+		// Часы клиента в будущем не должны давать ему вечную победу.
+		edited := time.Now()
+		if m.UpdatedAt != nil && m.UpdatedAt.Before(edited) {
+			edited = *m.UpdatedAt
+		}
+		if rec.ChangedAt.After(edited) {
+			return conflict, nil
+		}
+		return s.applyMutation(ctx, store, userID, m, rec, cur)
+	case SyncManual:
+		c, err := s.queueConflict(ctx, userID, m, rec, cur)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		conflict.ConflictID = c.ID
+		return conflict, nil
+	default:
+		return conflict, nil
+	}
+}
+
+// syncState -- текущая ревизия задачи и сама задача (nil -- удалена или не существовала).
+func (s *Service) syncState(ctx context.Context, store RevisionStore, uuid string) (SyncRecord, *Task, error) {
+	rec, _, err := store.Revision(ctx, uuid)
+	if err != nil {
+		return SyncRecord{}, nil, err
+	}
+	cur, err := s.GetTaskByUUID(ctx, uuid)
+	if errors.Is(err, ErrTaskNotFound) {
+		cur, err = nil, nil
+	}
+	if err != nil {
+		return SyncRecord{}, nil, err
+	}
+	if cur == nil && !rec.Deleted {
+		rec = SyncRecord{} // ревизия без задачи -- задача удалена мимо журнала
+	}
+	return rec, cur, nil
+}
+
+// applyMutation записывает мутацию без проверки base_rev: конфликт уже разрешён в её пользу.
+func (s *Service) applyMutation(ctx context.Context, store RevisionStore, userID int, m SyncMutation, rec SyncRecord, cur *Task) (SyncResult, error) {
+	res := SyncResult{UUID: m.UUID, Op: m.Op}
+
+	var err error
+	switch {
+	case m.Op == SyncDelete && cur == nil:
+		// Уже удалена (или не существовала) -- цель мутации достигнута.
+		res.Status, res.Rev, res.Deleted = SyncApplied, rec.Rev, true
+		return res, nil
+	case m.Op == SyncDelete:
+		err = s.DeleteTask(ctx, cur.ID, userID)
+	case cur == nil:
+		t := syncTask(*m.Task, userID)
+		t.UUID, t.UserID = m.UUID, userID
+		err = s.CreateTask(ctx, &t)
+	default:
+		t := syncTask(*m.Task, userID)
+		t.ID = cur.ID
+		err = s.UpdateTask(ctx, &t, userID)
+	}
+	if err != nil {
+		var he *HookError
+		var fe *FieldError
+		var te *TransitionError
+		switch {
+		case errors.As(err, &he):
+			res.Status, res.Code, res.Error = SyncRejected, "hook_rejected", he.Err.Error()
+		case errors.As(err, &te):
+			res.Status, res.Code, res.Error = SyncRejected, "transition_not_allowed", te.Error()
+		case errors.Is(err, ErrProjectForbidden):
+			res.Status, res.Code, res.Error = SyncRejected, "project_forbidden", err.Error()
+		case errors.As(err, &fe):
+			res.Status, res.Code, res.Error = SyncRejected, "validation_error", "fields."+fe.Field+": "+fe.Reason
+		default:
+			return res, err
+		}
+		res.Rev, res.Task, res.Deleted = rec.Rev, cur, cur == nil
+		return res, nil
+	}
+
+	// Ревизию выдал publishChange при записи.
+	if rec, _, err = store.Revision(ctx, m.UUID); err != nil {
+		return res, err
+	}
+	res.Status, res.Rev, res.Deleted = SyncApplied, rec.Rev, rec.Deleted
+	if !rec.Deleted {
+		if res.Task, err = s.GetTaskByUUID(ctx, m.UUID); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// syncTask -- задача из тела мутации; исполнитель по умолчанию -- сам пользователь, как в PUT.
+func syncTask(req UpdateTaskRequest, userID int) Task {
+	if req.AssignedTo == 0 {
+		req.AssignedTo = userID
+	}
+	return Task{
+		Title:           req.Title,
+		Done:            req.Done,
+		Status:          req.Status,
+		Priority:        req.Priority,
+		AssignedTo:      req.AssignedTo,
+		Description:     req.Description,
+		Tags:            req.Tags,
+		Due:             req.Due,
+		EstimateMinutes: req.EstimateMinutes,
+		Fields:          req.Fields,
+	}
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// revisionsFile -- журнал ревизий JSON-хранилища, рядом с файлом задач.
+type revisionsFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data revisionsData
+}
+
+type revisionsData struct {
+	Head    int64                 `json:"head"`
+	Records map[string]SyncRecord `json:"records"`           // UUID -> последняя ревизия
+	Horizon int64                 `json:"horizon,omitempty"` // граница удалённых надгробий (retention.go)
+}
+
+func (ts *TaskStore) revisionsPath() string {
+	return ts.filename + ".revisions.json"
+}
+
+func (ts *TaskStore) loadRevisions() error {
+	ts.revisions.once.Do(func() {
+		ts.revisions.data.Records = make(map[string]SyncRecord)
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.revisionsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.revisions.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.revisions.data); err != nil {
+			ts.revisions.err = fmt.Errorf("parse %s: %w", ts.revisionsPath(), err)
+			return
+		}
+		if ts.revisions.data.Records == nil {
+			ts.revisions.data.Records = make(map[string]SyncRecord)
+		}
+	})
+	return ts.revisions.err
+}
+
+// BumpRevision выдаёт следующую ревизию и сохраняет журнал; при ошибке записи журнал не меняется.
+func (ts *TaskStore) BumpRevision(ctx context.Context, rec *SyncRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return err
+	}
+	ts.revisions.mu.Lock()
+	defer ts.revisions.mu.Unlock()
+
+	d := &ts.revisions.data
+	prev, had := d.Records[rec.UUID]
+	rec.Rev = d.Head + 1
+	rec.ChangedAt = rec.ChangedAt.UTC()
+	d.Head, d.Records[rec.UUID] = rec.Rev, *rec
+	if ts.filename == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(d, "", "   ")
+	if err == nil {
+		tmp := ts.revisionsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0600); err == nil {
+			err = os.Rename(tmp, ts.revisionsPath())
+		}
+	}
+	if err != nil {
+		d.Head--
+		if had {
+			d.Records[rec.UUID] = prev
+		} else {
+			delete(d.Records, rec.UUID)
+		}
+	}
+	return err
+}
+
+// Revision возвращает текущую ревизию задачи.
+func (ts *TaskStore) Revision(ctx context.Context, uuid string) (SyncRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return SyncRecord{}, false, err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return SyncRecord{}, false, err
+	}
+	ts.revisions.mu.Lock()
+	defer ts.revisions.mu.Unlock()
+	rec, ok := ts.revisions.data.Records[uuid]
+	return rec, ok, nil
+}
+
+// RevisionsSince возвращает записи с ревизией больше since по возрастанию.
+func (ts *TaskStore) RevisionsSince(ctx context.Context, since int64, limit int) ([]SyncRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return nil, err
+	}
+	ts.revisions.mu.Lock()
+	recs := slices.Collect(maps.Values(ts.revisions.data.Records))
+	ts.revisions.mu.Unlock()
+
+	recs = slices.DeleteFunc(recs, func(rec SyncRecord) bool { return rec.Rev <= since })
+	slices.SortFunc(recs, func(a, b SyncRecord) int { return cmp.Compare(a.Rev, b.Rev) })
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs, nil
+}
+
+// HeadRevision возвращает номер последней выданной ревизии.
+func (ts *TaskStore) HeadRevision(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := ts.loadRevisions(); err != nil {
+		return 0, err
+	}
+	ts.revisions.mu.Lock()
+	defer ts.revisions.mu.Unlock()
+	return ts.revisions.data.Head, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// BumpRevision выдаёт ревизию из последовательности task_revision_seq и заменяет запись задачи
+// в task_revisions (migrations/000022_task_revisions.up.sql).
+//
+// Номера выдаются до фиксации: две параллельные записи могут стать видны не по порядку номеров.
+// Вставка -- один короткий оператор, так что окно мало, но клиент, получивший токен в этот момент,
+// пропустит меньшую ревизию до следующего изменения той же задачи.
+func (r *PostgresRepository) BumpRevision(ctx context.Context, rec *SyncRecord) error {
+	return r.q.QueryRowContext(ctx, `
+		INSERT INTO task_revisions (uuid, task_id, rev, deleted, changed_at)
+		VALUES ($1, $2, nextval('task_revision_seq'), $3, $4)
+		ON CONFLICT (uuid) DO UPDATE SET
+			task_id = EXCLUDED.task_id, rev = EXCLUDED.rev, deleted = EXCLUDED.deleted, changed_at = EXCLUDED.changed_at
+		RETURNING rev`,
+		rec.UUID, rec.TaskID, rec.Deleted, rec.ChangedAt).Scan(&rec.Rev)
+}
+
+// Revision возвращает текущую ревизию задачи.
+func (r *PostgresRepository) Revision(ctx context.Context, uuid string) (SyncRecord, bool, error) {
+	rec := SyncRecord{UUID: uuid}
+	err := r.q.QueryRowContext(ctx, "SELECT task_id, rev, deleted, changed_at FROM task_revisions WHERE uuid = $1", uuid).
+		Scan(&rec.TaskID, &rec.Rev, &rec.Deleted, &rec.ChangedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SyncRecord{}, false, nil
+	}
+	if err != nil {
+		return SyncRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+// RevisionsSince возвращает записи с ревизией больше since по возрастанию.
+func (r *PostgresRepository) RevisionsSince(ctx context.Context, since int64, limit int) ([]SyncRecord, error) {
+	query := "SELECT uuid, task_id, rev, deleted, changed_at FROM task_revisions WHERE rev > $1 ORDER BY rev"
+	args := []any{since}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SyncRecord
+	for rows.Next() {
+		var rec SyncRecord
+		if err := rows.Scan(&rec.UUID, &rec.TaskID, &rec.Rev, &rec.Deleted, &rec.ChangedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// HeadRevision возвращает номер последней выданной ревизии. Удалённые уборщиком надгробия
+// (retention.go) учитываются через границу надгробий.
+func (r *PostgresRepository) HeadRevision(ctx context.Context) (int64, error) {
+	var head int64
+	err := r.q.QueryRowContext(ctx, `
+		SELECT GREATEST(
+			(SELECT COALESCE(MAX(rev), 0) FROM task_revisions),
+			(SELECT COALESCE(MAX((data->>'tombstone_horizon')::bigint), 0) FROM retention))`).Scan(&head)
+	return head, err
+}
@@ -1,5 +1,11 @@
 package tasks
 
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
 // Task описывает доменную модель задачи в системе.
 type Task struct {
 	// ID — уникальный идентификатор задачи, автоматически генерируемый базой данных.
@@ -17,11 +23,43 @@ type Task struct {
 	// Done — флаг текущего состояния задачи (true — выполнена, false — в работе).
 	Done bool `json:"done"`
 
-	// Priority — уровень важности задачи (принимает значения: low, medium, high).
-	Priority string `json:"priority"`
+	// Status — этап работы над задачей (todo, in_progress, done). Синхронизирован с Done.
+	Status string `json:"status"`
+
+	// Priority — уровень важности задачи (low < medium < high < critical), см. priority.go.
+	Priority Priority `json:"priority"`
 
 	// SubTasks - список подзадач(пунктов чек-листа), привязанных к этой задаче
 	SubTasks []SubTask `json:"subtasks"`
+
+	// UUID — глобальный идентификатор задачи: присланный клиентом UUID/ULID, сгенерированный сервером UUID v4
+	// или внешний ключ импорта (например, из Taskwarrior). Уникален; по нему работает идемпотентный импорт.
+	UUID string `json:"uuid,omitempty"`
+
+	// Description — подробное описание задачи (свободный текст).
+	Description string `json:"description,omitempty"`
+
+	// Tags — метки задачи.
+	Tags []string `json:"tags,omitempty"`
+
+	// Due — срок выполнения задачи (nil — без срока).
+	Due *time.Time `json:"due,omitempty"`
+
+	// EstimateMinutes — оценка трудозатрат в минутах (0 — без оценки), см. отчёт о загрузке в capacity.go.
+	EstimateMinutes int `json:"estimate_minutes,omitempty"`
+
+	// CompletedAt — когда задача была отмечена выполненной (nil — не выполнена). Ставит сервис.
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// CreatedAt — когда задача создана. Ставит сервис; у задач, созданных до появления поля, — nil.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	// Urgency — срочность задачи (см. urgency.go). Не хранится: считается при выдаче списка и задачи.
+	Urgency float64 `json:"urgency,omitempty"`
+
+	// Fields — значения пользовательских полей (CUSTOM_FIELDS), см. fields.go.
+	// При обновлении nil означает "не менять", пустой объект -- "очистить".
+	Fields Fields `json:"fields,omitempty"`
 }
 
 // Subtask описывает доменную модель подзадачи в системе.
@@ -33,22 +71,110 @@ type SubTask struct {
 }
 
 type CreateTaskRequest struct {
-	Title      string `json:"title" validate:"required,max=100"` // [Валидация] правила входного контракта живут в DTO, а не в Task
-	AssignedTo int    `json:"assigned_to"`
-	Done       bool   `json:"done"`
-	Priority   string `json:"priority" validate:"required,oneof=low medium high"`
+	// UUID -- необязательный идентификатор, сгенерированный клиентом (UUID или ULID)
+	UUID        string     `json:"uuid" validate:"omitempty,clientid"`
+	Title       string     `json:"title" validate:"required,title"` // [Валидация] правила входного контракта живут в DTO, а не в Task
+	AssignedTo  int        `json:"assigned_to"`
+	Done        bool       `json:"done"`
+	Status      string     `json:"status" validate:"omitempty,status"`
+	Priority    Priority   `json:"priority" validate:"omitempty,priority"`
+	Description string     `json:"description" validate:"description"`
+	Tags        []string   `json:"tags" validate:"tags"`
+	Due         *time.Time `json:"due"`
+	// DueInBusinessDays -- срок "через N рабочих дней" по рабочему календарю (вместо due)
+	DueInBusinessDays *int   `json:"due_in_business_days" validate:"omitempty,min=0,max=365,excluded_with=Due"`
+	EstimateMinutes   int    `json:"estimate_minutes" validate:"min=0,max=60000"`
+	Fields            Fields `json:"fields" validate:"max=20"`
 }
 
 // Отдельный DTO для PUT -- фиксируем контракт входных данных + включаем валидацию.
 type UpdateTaskRequest struct {
-	Title      string `json:"title" validate:"required,max=100"`
-	Done       bool   `json:"done"`
-	Priority   string `json:"priority" validate:"required,oneof=low medium high"`
-	AssignedTo int    `json:"assigned_to"`
+	Title       string     `json:"title" validate:"required,title"`
+	Done        bool       `json:"done"`
+	Status      string     `json:"status" validate:"omitempty,status"`
+	Priority    Priority   `json:"priority" validate:"required,priority"`
+	AssignedTo  int        `json:"assigned_to"`
+	Description string     `json:"description" validate:"description"`
+	Tags        []string   `json:"tags" validate:"tags"`
+	Due         *time.Time `json:"due"`
+	// EstimateMinutes -- как и остальные поля, заменяется: 0 снимает оценку
+	EstimateMinutes int    `json:"estimate_minutes" validate:"min=0,max=60000"`
+	Fields          Fields `json:"fields" validate:"max=20"`
+}
+
+// Встроенные статусы задачи. Свой процесс (workflow.go) задаёт другие статусы, и каждый относится
+// к одной из этих категорий. Done == (категория статуса -- StatusDone).
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// normalizeStatus согласует Status и Done с процессом пространства:
+// если статус не передан -- выводим его из Done (начальный статус или первый статус категории done),
+// иначе статус переводится в статус процесса (встроенное имя -- в статус своей категории),
+// и Done следует за ним.
+func normalizeStatus(t *Task) {
+	wf := activeWorkflow()
+	if t.Status == "" {
+		if t.Done {
+			t.Status = wf.forCategory(StatusDone)
+		} else {
+			t.Status = wf.Initial
+		}
+		return
+	}
+	t.Status = wf.resolve(t.Status)
+	t.Done = wf.category[t.Status] == StatusDone
+}
+
+// newValidator -- валидатор DTO с правилами проекта и ограничениями l (см. limits.go).
+func newValidator(l ValidationLimits) *validator.Validate {
+	validate := validator.New()
+	registerLimitAliases(validate, l)
+	// clientid -- UUID или ULID, сгенерированный клиентом
+	_ = validate.RegisterValidation("clientid", func(fl validator.FieldLevel) bool {
+		return IsClientID(fl.Field().String())
+	})
+	// priority -- уровень шкалы приоритетов пространства или встроенный уровень (его переводит Resolve:
+	// старые клиенты знают только low..critical). Шкала меняется на ходу, поэтому это функция, а не oneof.
+	_ = validate.RegisterValidation("priority", func(fl validator.FieldLevel) bool {
+		p := Priority(fl.Field().String())
+		return p.Valid() || p.Class() != ""
+	})
+	// status -- статус процесса пространства или встроенный статус (его переводит normalizeStatus).
+	_ = validate.RegisterValidation("status", func(fl validator.FieldLevel) bool {
+		return StatusCategory(fl.Field().String()) != ""
+	})
+	return validate
+}
+
+// taskValidator -- для ValidateTask; validator.Validate безопасен для параллельного использования.
+var taskValidator = newValidator(DefaultValidationLimits)
+
+// ValidateTask проверяет задачу по тем же правилам, что и тело POST /api/v1/tasks,
+// с ограничениями по умолчанию (DefaultValidationLimits).
+// Нужен тем, кто вызывает Service напрямую, минуя HTTP-слой (pkg/taskmanager).
+// Текстовые поля задачи при этом нормализуются (см. NormalizeText).
+func ValidateTask(t *Task) error {
+	normalizeTaskText(t)
+	return taskValidator.Struct(CreateTaskRequest{
+		UUID:            t.UUID,
+		Title:           t.Title,
+		AssignedTo:      t.AssignedTo,
+		Done:            t.Done,
+		Status:          t.Status,
+		Priority:        t.Priority,
+		Description:     t.Description,
+		Tags:            t.Tags,
+		Due:             t.Due,
+		EstimateMinutes: t.EstimateMinutes,
+		Fields:          t.Fields,
+	})
 }
 
 type CreateSubTaskRequest struct {
-	Title string `json:"title" validate:"required,max=100"`
+	Title string `json:"title" validate:"required,title"`
 }
 
 type UpdateSubTaskStatusRequest struct {
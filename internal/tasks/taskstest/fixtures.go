@@ -0,0 +1,166 @@
+// Package taskstest -- детерминированные фикстуры для тестов поверх tasks.MemoryStore.
+//
+// Все даты отсчитываются от фиксированного Epoch, UUID выдаются по порядку,
+// поэтому ответы HTTP-ручек на одних и тех же фикстурах совпадают байт в байт
+// и их можно сравнивать с golden-файлами.
+package taskstest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"task-manager/internal/tasks"
+)
+
+// Epoch -- "сейчас" для фикстур. Сроки задаются относительно него (DueIn).
+var Epoch = time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+
+// Password -- пароль всех пользователей из Users.
+const Password = "password"
+
+// UUID возвращает детерминированный UUID v4 с номером n.
+func UUID(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+}
+
+// TaskOption настраивает задачу, собираемую NewTask.
+type TaskOption func(*tasks.Task)
+
+// NewTask собирает задачу с разумными значениями по умолчанию:
+// владелец и исполнитель -- пользователь 1, приоритет medium, статус todo.
+func NewTask(title string, opts ...TaskOption) tasks.Task {
+	t := tasks.Task{
+		UserID:     1,
+		AssignedTo: 1,
+		Title:      title,
+		Status:     tasks.StatusTodo,
+		Priority:   tasks.PriorityMedium,
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+// Owner задаёт владельца задачи (и исполнителя, если он не задан отдельно через AssignedTo).
+func Owner(userID int) TaskOption {
+	return func(t *tasks.Task) {
+		if t.AssignedTo == t.UserID {
+			t.AssignedTo = userID
+		}
+		t.UserID = userID
+	}
+}
+
+// AssignedTo задаёт исполнителя.
+func AssignedTo(userID int) TaskOption {
+	return func(t *tasks.Task) { t.AssignedTo = userID }
+}
+
+// Priority задаёт приоритет (low, medium, high, critical).
+func Priority(p tasks.Priority) TaskOption {
+	return func(t *tasks.Task) { t.Priority = p }
+}
+
+// Status задаёт статус; Done выставляется согласованно.
+func Status(s string) TaskOption {
+	return func(t *tasks.Task) {
+		t.Status = s
+		t.Done = s == tasks.StatusDone
+	}
+}
+
+// Done отмечает задачу выполненной в момент Epoch.
+func Done() TaskOption {
+	return func(t *tasks.Task) {
+		at := Epoch
+		t.Status, t.Done, t.CompletedAt = tasks.StatusDone, true, &at
+	}
+}
+
+// DueIn ставит срок Epoch+d (отрицательное d -- задача уже просрочена).
+func DueIn(d time.Duration) TaskOption {
+	return func(t *tasks.Task) {
+		due := Epoch.Add(d)
+		t.Due = &due
+	}
+}
+
+// Tags задаёт метки.
+func Tags(tags ...string) TaskOption {
+	return func(t *tasks.Task) { t.Tags = tags }
+}
+
+// Description задаёт описание.
+func Description(s string) TaskOption {
+	return func(t *tasks.Task) { t.Description = s }
+}
+
+// WithUUID задаёт UUID явно (по умолчанию его выдаёт Seed).
+func WithUUID(id string) TaskOption {
+	return func(t *tasks.Task) { t.UUID = id }
+}
+
+// Users -- пользователи семьи для фикстур, ID 1..3 по порядку. Пароль у всех -- Password.
+func Users() []tasks.User {
+	return []tasks.User{
+		{Username: "mom"},
+		{Username: "dad"},
+		{Username: "kid"},
+	}
+}
+
+// Tasks -- стандартный набор задач: разные владельцы, статусы, приоритеты, просроченные и без срока.
+func Tasks() []tasks.Task {
+	return []tasks.Task{
+		NewTask("Купить продукты", Priority(tasks.PriorityHigh), DueIn(-24*time.Hour), Tags("shopping")),
+		NewTask("Оплатить интернет", Owner(2), DueIn(72*time.Hour), Tags("bills")),
+		NewTask("Сделать уроки", Owner(1), AssignedTo(3), Status(tasks.StatusInProgress), DueIn(6*time.Hour)),
+		NewTask("Вынести мусор", AssignedTo(2), Priority(tasks.PriorityLow), Done()),
+		NewTask("Записаться к врачу", Owner(2), Description("Терапевт, утро буднего дня")),
+	}
+}
+
+// Seed создаёт в store пользователей и задачи. Задачам без UUID выдаются UUID(1), UUID(2), ...
+// по порядку, ID присваивает хранилище (на пустом хранилище -- тоже 1, 2, ...).
+func Seed(ctx context.Context, store *tasks.MemoryStore, users []tasks.User, list []tasks.Task) error {
+	if len(users) > 0 {
+		// Минимальная стоимость bcrypt: фикстуры не должны тормозить тесты.
+		hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.MinCost)
+		if err != nil {
+			return err
+		}
+		for i := range users {
+			u := users[i]
+			if u.PasswordHash == "" {
+				u.PasswordHash = string(hash)
+			}
+			if err := store.CreateUser(ctx, &u); err != nil {
+				return fmt.Errorf("seed user %q: %w", u.Username, err)
+			}
+		}
+	}
+
+	for i := range list {
+		t := list[i]
+		if t.UUID == "" {
+			t.UUID = UUID(i + 1)
+		}
+		if err := store.Create(ctx, &t); err != nil {
+			return fmt.Errorf("seed task %q: %w", t.Title, err)
+		}
+	}
+	return nil
+}
+
+// NewStore -- пустой MemoryStore со стандартными Users и Tasks.
+func NewStore(ctx context.Context) (*tasks.MemoryStore, error) {
+	store := tasks.NewMemoryStore()
+	if err := Seed(ctx, store, Users(), Tasks()); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
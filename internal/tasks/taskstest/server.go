@@ -0,0 +1,97 @@
+package taskstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"task-manager/internal/middleware"
+	"task-manager/internal/secrets"
+	"task-manager/internal/tasks"
+)
+
+// DefaultSecret -- ключ подписи JWT для тестов, которым ключ безразличен.
+const DefaultSecret = "taskstest-secret"
+
+// Server -- полный роутер API поверх заданного хранилища на httptest-сервере.
+// Для проверки сохранности данных "после перезапуска" закройте сервер и поднимите
+// новый поверх заново открытого хранилища (например, tasks.NewTaskStore с тем же файлом).
+type Server struct {
+	*httptest.Server
+	Service *tasks.Service
+	secret  string
+}
+
+// NewServer поднимает сервер с роутером tasks.Handler поверх repo. secret -- ключ подписи JWT
+// (пусто -- DefaultSecret). Ключ, как и в main, задаётся для всего процесса (middleware.SetJWTSecret):
+// серверы одного теста должны использовать один ключ. Окружение не меняется.
+// configure вызываются до сборки роутера: администраторы, API-ключи, PUBLIC_URL и т.п.
+func NewServer(repo tasks.TaskRepository, secret string, configure ...func(*tasks.Handler, *tasks.Service)) *Server {
+	if secret == "" {
+		secret = DefaultSecret
+	}
+	middleware.SetJWTSecret(secrets.Static("JWT_SECRET", secret))
+	svc := tasks.NewService(repo)
+	h := tasks.NewHandler(svc)
+	for _, fn := range configure {
+		fn(h, svc)
+	}
+	return &Server{
+		Server:  httptest.NewServer(h.Router()),
+		Service: svc,
+		secret:  secret,
+	}
+}
+
+// Token выпускает токен пользователя userID, как это делает вход в систему.
+// Не требует, чтобы хранилище умело хранить пользователей (файловое -- не умеет).
+func (s *Server) Token(userID int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secret))
+}
+
+// Do выполняет запрос к серверу. body (если не nil) кодируется в JSON, token (если не пуст) -- в Authorization.
+func (s *Server) Do(ctx context.Context, method, path, token string, body any) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.URL+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.Client().Do(req)
+}
+
+// DoJSON -- Do, который декодирует тело ответа в out (если out не nil) и возвращает код ответа.
+func (s *Server) DoJSON(ctx context.Context, method, path, token string, body, out any) (int, error) {
+	resp, err := s.Do(ctx, method, path, token, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
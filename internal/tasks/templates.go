@@ -0,0 +1,16 @@
+package tasks
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// Templates -- встроенные шаблоны по умолчанию (письма отчётов), как они лежат в templates/.
+// Нужны `task-server --extract-assets`, чтобы взять их за основу своих шаблонов.
+func Templates() fs.FS {
+	sub, _ := fs.Sub(templatesFS, "templates")
+	return sub
+}
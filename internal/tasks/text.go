@@ -0,0 +1,183 @@
+package tasks
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Текст от пользователей (названия, описания, теги) приходит из разных клавиатур и копипаста:
+// "й" бывает одним символом или "и" + комбинируемая кратка, в начало строки попадает BOM,
+// в середину -- невидимые пробелы нулевой ширины. Чтобы поиск, дедупликация и лимиты длины
+// работали одинаково, текст приводится к NFC, а невидимые символы убираются.
+//
+// Лимиты длины (TITLE_MAX_LENGTH и др., см. limits.go) считаются в символах (рунах), а не в байтах:
+// "Купить молоко 🥛" -- 15 символов, хотя в UTF-8 это 29 байт.
+
+const zeroWidthJoiner = '\u200d'
+
+// invisibleRune -- символы, которые не видны и ничего не значат в названии: удаляются везде.
+// ZWJ и ZWNJ сюда не входят: внутри эмодзи (👨‍👩‍👧) и в некоторых письменностях они значимы.
+func invisibleRune(r rune) bool {
+	switch r {
+	case '\u200b', // ZERO WIDTH SPACE
+		'\u2060', // WORD JOINER
+		'\ufeff', // BOM / ZERO WIDTH NO-BREAK SPACE
+		'\u180e': // MONGOLIAN VOWEL SEPARATOR
+		return true
+	}
+	return false
+}
+
+// NormalizeText приводит текст к NFC, убирает невидимые символы нулевой ширины и пробелы
+// (включая ZWJ/ZWNJ) по краям. Так нормализуются названия и теги.
+func NormalizeText(s string) string {
+	if isASCII(s) {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimFunc(normalizeBody(s), func(r rune) bool {
+		return unicode.IsSpace(r) || r == zeroWidthJoiner || r == '\u200c'
+	})
+}
+
+// normalizeBody -- то же для многострочного текста (описания): без обрезки краёв,
+// отступы в начале описания могут быть разметкой.
+func normalizeBody(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if invisibleRune(r) {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(s))
+}
+
+// normalizeTags нормализует теги и выбрасывает ставшие пустыми.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	out := tags[:0]
+	for _, tag := range tags {
+		if tag = NormalizeText(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// normalizeTaskText -- нормализация текстовых полей задачи перед сохранением.
+func normalizeTaskText(t *Task) {
+	t.Title = NormalizeText(t.Title)
+	t.Description = normalizeBody(t.Description)
+	t.Tags = normalizeTags(t.Tags)
+}
+
+// TruncateText укладывает текст в n символов; обрезанный текст заканчивается "…".
+// Граница не разрывает символ вместе с его модификаторами: комбинируемые знаки, селекторы
+// вариантов, оттенки кожи, ZWJ-последовательности и флаги (пары региональных индикаторов)
+// либо остаются целиком, либо отбрасываются целиком.
+func TruncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	if n <= 1 {
+		return "…"
+	}
+	runes := []rune(s)
+	cut := n - 1
+	for cut > 0 && (extendsPrevious(runes[cut]) || runes[cut-1] == zeroWidthJoiner) {
+		cut--
+	}
+	ri := 0
+	for i := cut - 1; i >= 0 && isRegionalIndicator(runes[i]); i-- {
+		ri++
+	}
+	if ri%2 == 1 {
+		cut--
+	}
+	if cut <= 0 {
+		// Один "символ" длиннее лимита (так бывает только со сконструированным текстом) -- режем как есть.
+		cut = n - 1
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "…"
+}
+
+// extendsPrevious сообщает, что руна продолжает предыдущий видимый символ.
+func extendsPrevious(r rune) bool {
+	switch {
+	case unicode.Is(unicode.M, r),
+		r == zeroWidthJoiner,
+		r >= 0xfe00 && r <= 0xfe0f,   // селекторы вариантов (❤️ = ❤ + VS16)
+		r >= 0x1f3fb && r <= 0x1f3ff, // оттенки кожи
+		r >= 0xe0020 && r <= 0xe007f: // теги флагов регионов (флаги Шотландии, Уэльса)
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// textNormalizer -- тело запроса с пользовательским текстом. decodeJSONStrict нормализует его
+// сразу после декодирования, поэтому required и max проверяются уже по нормализованному тексту:
+// название из одних невидимых символов -- пустое, а "й" из двух рун -- один символ.
+type textNormalizer interface {
+	normalizeText()
+}
+
+func (r *CreateTaskRequest) normalizeText() {
+	r.Title = NormalizeText(r.Title)
+	r.Description = normalizeBody(r.Description)
+	r.Tags = normalizeTags(r.Tags)
+}
+
+func (r *UpdateTaskRequest) normalizeText() {
+	r.Title = NormalizeText(r.Title)
+	r.Description = normalizeBody(r.Description)
+	r.Tags = normalizeTags(r.Tags)
+}
+
+func (r *CreateSubTaskRequest) normalizeText() {
+	r.Title = NormalizeText(r.Title)
+}
+
+func (r *FlatCreateTaskRequest) normalizeText() {
+	r.Title = NormalizeText(r.Title)
+	r.Description = normalizeBody(r.Description)
+}
+
+func (r *ScheduleRequest) normalizeText() {
+	r.Name = NormalizeText(r.Name)
+	r.Task.Title = NormalizeText(r.Task.Title)
+	r.Task.Description = normalizeBody(r.Task.Description)
+	r.Task.Tags = normalizeTags(r.Task.Tags)
+}
+
+func (r *SyncRequest) normalizeText() {
+	for i := range r.Mutations {
+		if r.Mutations[i].Task != nil {
+			r.Mutations[i].Task.normalizeText()
+		}
+	}
+}
+
+func (r *ConflictResolution) normalizeText() {
+	if r.Task != nil {
+		r.Task.normalizeText()
+	}
+}
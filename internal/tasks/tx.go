@@ -0,0 +1,395 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TxStore -- операции, доступные внутри транзакции.
+// Когда появятся новые сущности (комментарии, события), их методы добавляются сюда же,
+// чтобы "задача + комментарий + событие" менялись атомарно.
+type TxStore interface {
+	Create(ctx context.Context, task *Task) error
+	GetByID(ctx context.Context, id int) (*Task, error)
+	GetAll(ctx context.Context, userID int) ([]Task, error)
+	Update(ctx context.Context, task *Task, userID int) error
+	Delete(ctx context.Context, id int, userID int) error
+}
+
+// Tx -- транзакция хранилища. После Commit или Rollback пользоваться ей нельзя;
+// Rollback после Commit безопасен и ничего не делает (удобно для defer).
+type Tx interface {
+	TxStore
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner -- опциональная возможность хранилища открывать транзакции.
+type TxBeginner interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// ErrTxDone -- транзакция уже завершена.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// ErrTxConflict -- задачу, изменённую в транзакции, успели поменять снаружи (JSON-хранилище).
+var ErrTxConflict = errors.New("transaction conflict: task was modified concurrently")
+
+// WithTx выполняет fn атомарно: если fn вернула ошибку, изменения откатываются.
+// Хранилища без транзакций выполняют fn напрямую, без атомарности.
+// В пробном запуске (WithDryRun) транзакция откатывается и после успешной fn.
+func (s *Service) WithTx(ctx context.Context, fn func(tx TxStore) error) error {
+	b, ok := s.repo.(TxBeginner)
+	if !ok {
+		if IsDryRun(ctx) {
+			return ErrDryRunUnsupported
+		}
+		return fn(s.repo)
+	}
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if IsDryRun(ctx) {
+		return tx.Rollback()
+	}
+	return s.checkStorage(ctx, tx.Commit())
+}
+
+// ---------------------------------------------------------------------------
+// JSON: изменения копятся в памяти транзакции и применяются разом при Commit
+// ---------------------------------------------------------------------------
+
+// stagedTask -- изменение задачи внутри транзакции.
+type stagedTask struct {
+	base *Task // версия в хранилище на момент первого чтения/изменения (nil -- задачи не было)
+	next *Task // новая версия (nil -- удаление)
+}
+
+// taskTx -- транзакция TaskStore. Читает "свои" изменения поверх хранилища.
+// Конфликты проверяются оптимистично: при Commit версия каждой изменённой задачи
+// должна совпадать с той, что транзакция видела (задачи в хранилище неизменяемы, сравниваем указатели).
+type taskTx struct {
+	ts     *TaskStore
+	ctx    context.Context
+	staged map[int]*stagedTask
+	order  []int // порядок изменений -- для сообщений коммита
+	ops    []string
+	userID int
+	done   bool
+
+	claimed   []string // UUID созданных задач, занятые в индексе до коммита
+	ids       []int    // ID созданных задач, выданные до коммита (по возрастанию)
+	committed bool
+}
+
+// Begin открывает транзакцию над JSON-хранилищем.
+func (ts *TaskStore) Begin(ctx context.Context) (Tx, error) {
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+	return &taskTx{ts: ts, ctx: ctx, staged: make(map[int]*stagedTask)}, nil
+}
+
+// current возвращает текущую версию задачи в хранилище (nil -- нет такой).
+func (ts *TaskStore) current(id int) *Task {
+	sh := ts.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.tasks[id]
+}
+
+func (tx *taskTx) stage(id int, next *Task, op string) {
+	st, ok := tx.staged[id]
+	if !ok {
+		st = &stagedTask{base: tx.ts.current(id)}
+		tx.staged[id] = st
+		tx.order = append(tx.order, id)
+	}
+	st.next = next
+	tx.ops = append(tx.ops, op)
+}
+
+// view -- версия задачи с учётом изменений транзакции.
+func (tx *taskTx) view(id int) *Task {
+	if st, ok := tx.staged[id]; ok {
+		return st.next
+	}
+	return tx.ts.current(id)
+}
+
+func (tx *taskTx) Create(ctx context.Context, task *Task) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	// UUID занимаем сразу: иначе две транзакции могли бы создать задачи с одним UUID.
+	id := int(tx.ts.lastID.Add(1))
+	if task.UUID == "" {
+		task.UUID = newTaskUUID()
+	}
+	tx.ids = append(tx.ids, id)
+	if _, taken := tx.ts.uuids.LoadOrStore(task.UUID, id); taken {
+		return ErrDuplicateUUID
+	}
+	tx.claimed = append(tx.claimed, task.UUID)
+	task.ID = id
+	tx.stage(task.ID, cloneTask(task), fmt.Sprintf("create task %d", task.ID))
+	tx.userID = task.UserID
+	return nil
+}
+
+func (tx *taskTx) GetByID(ctx context.Context, id int) (*Task, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	t := tx.view(id)
+	if t == nil {
+		return nil, ErrTaskNotFound
+	}
+	found := *t
+	return &found, nil
+}
+
+func (tx *taskTx) GetAll(ctx context.Context, userID int) ([]Task, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	list, err := tx.ts.GetAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.staged) == 0 {
+		return list, nil
+	}
+
+	out := list[:0]
+	for _, t := range list {
+		if _, ok := tx.staged[t.ID]; !ok {
+			out = append(out, t)
+		}
+	}
+	for _, id := range tx.order {
+		if next := tx.staged[id].next; next != nil {
+			out = append(out, *next)
+		}
+	}
+	sortTasksByID(out)
+	return out, nil
+}
+
+func (tx *taskTx) Update(ctx context.Context, task *Task, userID int) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	prev := tx.view(task.ID)
+	if prev == nil {
+		return ErrTaskNotFound
+	}
+	updated := *prev
+	updated.Title = task.Title
+	updated.Done = task.Done
+	updated.Status = task.Status
+	updated.Priority = task.Priority
+	updated.AssignedTo = task.AssignedTo
+	updated.Description = task.Description
+	updated.Tags = task.Tags
+	updated.Due = task.Due
+	updated.EstimateMinutes = task.EstimateMinutes
+	updated.CompletedAt = task.CompletedAt
+	if task.Fields != nil {
+		updated.Fields = task.Fields
+	}
+	tx.stage(task.ID, cloneTask(&updated), fmt.Sprintf("update task %d", task.ID))
+	tx.userID = userID
+	return nil
+}
+
+func (tx *taskTx) Delete(ctx context.Context, id int, userID int) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	prev := tx.view(id)
+	if prev == nil {
+		return ErrTaskNotFound
+	}
+	// Задача создана и удалена в этой же транзакции -- её UUID больше не нужен.
+	if st, ok := tx.staged[id]; ok && st.base == nil {
+		tx.ts.uuids.Delete(prev.UUID)
+	}
+	tx.stage(id, nil, fmt.Sprintf("delete task %d", id))
+	tx.userID = userID
+	return nil
+}
+
+// Commit проверяет конфликты, применяет все изменения и сохраняет файл одной записью.
+// Если запись не удалась, изменения в памяти откатываются.
+func (tx *taskTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.staged) == 0 {
+		return nil
+	}
+
+	ts := tx.ts
+	lockTimed(&ts.saveMu, lockSave)
+	defer ts.saveMu.Unlock()
+
+	// saveMu не пускает другие коммиты, но одиночная мутация может менять шард прямо сейчас.
+	// Поэтому проверка версий и применение идут под блокировками всех затронутых шардов
+	// (берём их в порядке номеров, чтобы две транзакции не заблокировали друг друга).
+	var touched [taskShards]bool
+	for _, id := range tx.order {
+		touched[uint(id)%taskShards] = true
+	}
+	for i := range ts.shards {
+		if touched[i] {
+			ts.shards[i].mu.Lock()
+		}
+	}
+	unlock := func() {
+		for i := range ts.shards {
+			if touched[i] {
+				ts.shards[i].mu.Unlock()
+			}
+		}
+	}
+
+	for _, id := range tx.order {
+		if ts.shard(id).tasks[id] != tx.staged[id].base {
+			unlock()
+			tx.releaseClaims()
+			return ErrTxConflict
+		}
+	}
+	for _, id := range tx.order {
+		put(ts.shard(id).tasks, id, tx.staged[id].next)
+	}
+	unlock()
+
+	if err := ts.persistLocked(tx.ctx); err != nil {
+		for _, id := range tx.order {
+			ts.replace(id, tx.staged[id].base)
+		}
+		tx.releaseClaims()
+		return err
+	}
+
+	tx.committed = true
+	for _, id := range tx.order {
+		if st := tx.staged[id]; st.next == nil && st.base != nil {
+			ts.uuids.Delete(st.base.UUID)
+		}
+	}
+	return nil
+}
+
+// releaseClaims освобождает UUID и ID задач, которые так и не были созданы.
+// ID возвращаются счётчику с конца, пока после них никто не брал новых (см. releaseID).
+func (tx *taskTx) releaseClaims() {
+	for _, u := range tx.claimed {
+		tx.ts.uuids.Delete(u)
+	}
+	tx.claimed = nil
+	for i := len(tx.ids) - 1; i >= 0; i-- {
+		if !tx.ts.lastID.CompareAndSwap(int64(tx.ids[i]), int64(tx.ids[i]-1)) {
+			break
+		}
+	}
+	tx.ids = nil
+}
+
+// put кладёт задачу в карту шарда (nil -- удаляет). Вызывающий держит блокировку шарда.
+func put(tasks map[int]*Task, id int, t *Task) {
+	if t == nil {
+		delete(tasks, id)
+		return
+	}
+	tasks[id] = t
+}
+
+// Rollback выбрасывает накопленные изменения. Выданные ID возвращаются счётчику, только если
+// после них ID больше не выдавались: занятые кем-то позже не переиспользуются.
+func (tx *taskTx) Rollback() error {
+	if !tx.committed {
+		tx.releaseClaims()
+	}
+	tx.done = true
+	return nil
+}
+
+// message -- описание изменений транзакции для git-коммита.
+func (tx *taskTx) message() string {
+	if len(tx.ops) == 1 {
+		return tx.ops[0]
+	}
+	return "tx: " + strings.Join(tx.ops, ", ")
+}
+
+// ---------------------------------------------------------------------------
+// Git: транзакция JSON-хранилища + один git-коммит на всю транзакцию
+// ---------------------------------------------------------------------------
+
+type gitTx struct {
+	*taskTx
+	gs *GitStore
+}
+
+// Begin открывает транзакцию; при Commit все изменения попадают в историю одним коммитом.
+func (gs *GitStore) Begin(ctx context.Context) (Tx, error) {
+	tx, err := gs.TaskStore.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gitTx{taskTx: tx.(*taskTx), gs: gs}, nil
+}
+
+func (tx *gitTx) Commit() error {
+	lockTimed(&tx.gs.mu, lockGit)
+	defer tx.gs.mu.Unlock()
+
+	if err := tx.taskTx.Commit(); err != nil {
+		return err
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+	return tx.gs.commit(tx.ctx, userAuthor(tx.userID), tx.message())
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL: настоящая транзакция БД
+// ---------------------------------------------------------------------------
+
+type pgTx struct {
+	*PostgresRepository
+	tx *sql.Tx
+}
+
+// Begin открывает транзакцию БД. Методы репозитория внутри неё ходят через *sql.Tx.
+func (r *PostgresRepository) Begin(ctx context.Context) (Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &pgTx{PostgresRepository: &PostgresRepository{db: r.db, q: tx}, tx: tx}, nil
+}
+
+func (t *pgTx) Commit() error { return t.tx.Commit() }
+
+func (t *pgTx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Коэффициенты срочности -- как у Taskwarrior (urgency.*.coefficient), чтобы порядок задач
+// совпадал с привычным пользователям `task next`. Приоритета critical у Taskwarrior нет --
+// он стоит выше high.
+const (
+	urgencyDueCoef      = 12.0 // срок: множитель от 0.2 (срок дальше двух недель) до 1 (просрочено на неделю и больше)
+	urgencyBlockingCoef = 8.0  // задача блокирует хотя бы одну открытую задачу
+	urgencyBlockingEach = 1.0  // за каждую следующую заблокированную (не больше urgencyBlockingMax)
+	urgencyBlockingMax  = 4
+	urgencyBlockedCoef  = -5.0 // задачу саму блокируют открытые задачи: браться за неё рано
+	urgencyActiveCoef   = 4.0  // задача уже в работе
+	urgencyAgeCoef      = 2.0  // возраст: линейно до года
+	urgencyAgeMax       = 365 * 24 * time.Hour
+)
+
+// urgencyPriorityCoef -- вклад приоритета по его классу (см. PriorityLevel.Class).
+var urgencyPriorityCoef = map[Priority]float64{
+	PriorityLow:      1.8,
+	PriorityMedium:   3.9,
+	PriorityHigh:     6.0,
+	PriorityCritical: 9.0,
+}
+
+// urgency считает срочность открытой задачи. blocking -- сколько открытых задач она блокирует,
+// blocked -- сколько открытых задач блокируют её. У выполненной задачи срочность 0.
+func urgency(t *Task, blocking, blocked int, now time.Time) float64 {
+	if t.Done {
+		return 0
+	}
+
+	u := urgencyPriorityCoef[t.Priority.Class()]
+	if t.Due != nil {
+		u += urgencyDueCoef * dueFactor(now.Sub(*t.Due))
+	}
+	if blocking > 0 {
+		u += urgencyBlockingCoef + urgencyBlockingEach*float64(min(blocking-1, urgencyBlockingMax))
+	}
+	if blocked > 0 {
+		u += urgencyBlockedCoef
+	}
+	if StatusCategory(t.Status) == StatusInProgress {
+		u += urgencyActiveCoef
+	}
+	if t.CreatedAt != nil {
+		age := min(max(now.Sub(*t.CreatedAt), 0), urgencyAgeMax)
+		u += urgencyAgeCoef * float64(age) / float64(urgencyAgeMax)
+	}
+	return math.Round(u*100) / 100
+}
+
+// dueFactor -- формула Taskwarrior: просрочено на 7 дней и больше -- 1, срок через 14 дней и дальше -- 0.2,
+// между ними -- линейно. overdue < 0 -- срок ещё не наступил.
+func dueFactor(overdue time.Duration) float64 {
+	days := overdue.Hours() / 24
+	switch {
+	case days >= 7:
+		return 1
+	case days >= -14:
+		return (days+14)*0.8/21 + 0.2
+	default:
+		return 0.2
+	}
+}
+
+// ScoreUrgency проставляет Urgency задачам списка (на момент now).
+// Связи "блокирует" учитываются, если хранилище их поддерживает.
+func (s *Service) ScoreUrgency(ctx context.Context, list []Task, now time.Time) error {
+	blocking, blocked, err := s.blockCounts(ctx, list)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		t := &list[i]
+		t.Urgency = urgency(t, blocking[t.ID], blocked[t.ID], now)
+	}
+	return nil
+}
+
+// blockCounts считает для задач списка открытые задачи, которые они блокируют и которые блокируют их.
+// Задачи вне списка (например, чужие) ищутся в хранилище по ID.
+func (s *Service) blockCounts(ctx context.Context, list []Task) (blocking, blocked map[int]int, err error) {
+	rels, err := s.TaskRelations(ctx, 0, 0)
+	if errors.Is(err, ErrRelationsUnsupported) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	open := make(map[int]bool, len(list))
+	for _, t := range list {
+		open[t.ID] = !t.Done
+	}
+	isOpen := func(id int) (bool, error) {
+		if o, ok := open[id]; ok {
+			return o, nil
+		}
+		t, err := s.repo.GetByID(ctx, id)
+		if errors.Is(err, ErrTaskNotFound) {
+			open[id] = false
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		open[id] = !t.Done
+		return open[id], nil
+	}
+
+	inList := make(map[int]bool, len(list))
+	for _, t := range list {
+		inList[t.ID] = true
+	}
+	blocking, blocked = make(map[int]int), make(map[int]int)
+	for _, rel := range rels {
+		if rel.Type != RelationBlocks || !inList[rel.FromID] && !inList[rel.ToID] {
+			continue
+		}
+		if inList[rel.FromID] {
+			if o, err := isOpen(rel.ToID); err != nil {
+				return nil, nil, err
+			} else if o {
+				blocking[rel.FromID]++
+			}
+		}
+		if inList[rel.ToID] {
+			if o, err := isOpen(rel.FromID); err != nil {
+				return nil, nil, err
+			} else if o {
+				blocked[rel.ToID]++
+			}
+		}
+	}
+	return blocking, blocked, nil
+}
@@ -0,0 +1,270 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"task-manager/internal/httpclient"
+	"task-manager/internal/metrics"
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// Использование хранилища (/api/v1/admin/usage) и мягкие квоты: при превышении порога сервис
+// ничего не запрещает, а только предупреждает -- в лог, метрикой и (если задан) webhook'ом.
+
+// Виды квот (метка kind в метриках и событиях).
+const (
+	QuotaTasks        = "tasks"
+	QuotaStorageBytes = "storage_bytes"
+)
+
+// События мягких квот для webhook.
+const (
+	QuotaExceeded  = "quota.exceeded"
+	QuotaRecovered = "quota.recovered"
+)
+
+var ErrUsageUnsupported = errors.New("storage usage is not supported by this storage")
+
+var (
+	usageTasks = metrics.NewGaugeVec("storage_usage_tasks",
+		"Tasks in storage, as of the last usage check.")
+	usageBytes = metrics.NewGaugeVec("storage_usage_bytes",
+		"Bytes used by storage, as of the last usage check.")
+	quotaExceeded = metrics.NewGaugeVec("storage_quota_exceeded",
+		"1 if usage is over the soft quota, by kind.", "kind")
+)
+
+// quotaClient -- HTTP-клиент для webhook мягких квот.
+var quotaClient = httpclient.New(httpclient.Options{Name: "quota", Timeout: 10 * time.Second, Retries: 2})
+
+// UsageQuota -- мягкие пороги (0 -- без порога) и webhook для событий о них (пусто -- только лог).
+type UsageQuota struct {
+	Tasks        int
+	StorageBytes int64
+	Webhook      string
+}
+
+// QuotaWarning -- превышенный порог: текущее значение, порог и с какой проверки превышен.
+type QuotaWarning struct {
+	Kind      string    `json:"kind"`
+	Value     int64     `json:"value"`
+	Threshold int64     `json:"threshold"`
+	Since     time.Time `json:"since"`
+}
+
+// Usage -- ответ GET /api/v1/admin/usage. Breakdown -- байты по файлам (JSON-хранилище)
+// или таблицам (PostgreSQL).
+type Usage struct {
+	Tasks        int              `json:"tasks"`
+	StorageBytes int64            `json:"storage_bytes"`
+	Breakdown    map[string]int64 `json:"breakdown"`
+	Quota        map[string]int64 `json:"quota"`
+	Warnings     []QuotaWarning   `json:"warnings"`
+	CheckedAt    time.Time        `json:"checked_at"`
+}
+
+// UsageStore -- опциональная возможность хранилища сообщить, сколько места оно занимает.
+type UsageStore interface {
+	StorageUsage(ctx context.Context) (map[string]int64, error)
+}
+
+// quotaWatch -- пороги и превышенные квоты (с какого момента) между проверками.
+type quotaWatch struct {
+	mu       sync.Mutex
+	limits   UsageQuota
+	exceeded map[string]time.Time
+}
+
+// SetUsageQuota задаёт мягкие пороги. Вызывать до начала работы сервиса.
+func (s *Service) SetUsageQuota(q UsageQuota) {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	s.quota.limits = q
+}
+
+// CheckUsage считает использование хранилища и сверяет его с порогами. Пересечение порога
+// (в любую сторону) пишется в лог и отправляется webhook'ом; повторные проверки выше порога молчат.
+func (s *Service) CheckUsage(ctx context.Context, now time.Time) (Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return Usage{}, err
+	}
+	us, ok := s.capabilities().(UsageStore)
+	if !ok {
+		return Usage{}, ErrUsageUnsupported
+	}
+	list, err := s.repo.GetAll(ctx, 0)
+	if err != nil {
+		return Usage{}, err
+	}
+	breakdown, err := us.StorageUsage(ctx)
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{Tasks: len(list), Breakdown: breakdown, Quota: map[string]int64{}, Warnings: []QuotaWarning{}, CheckedAt: now.UTC()}
+	for _, n := range breakdown {
+		u.StorageBytes += n
+	}
+	usageTasks.WithLabelValues().Set(float64(u.Tasks))
+	usageBytes.WithLabelValues().Set(float64(u.StorageBytes))
+
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	q := &s.quota
+	if q.exceeded == nil {
+		q.exceeded = make(map[string]time.Time)
+	}
+	checks := []struct {
+		kind             string
+		value, threshold int64
+	}{
+		{QuotaTasks, int64(u.Tasks), int64(q.limits.Tasks)},
+		{QuotaStorageBytes, u.StorageBytes, q.limits.StorageBytes},
+	}
+	for _, c := range checks {
+		if c.threshold <= 0 {
+			continue
+		}
+		u.Quota[c.kind] = c.threshold
+		since, was := q.exceeded[c.kind]
+		over := c.value > c.threshold
+		switch {
+		case over && !was:
+			since = u.CheckedAt
+			q.exceeded[c.kind] = since
+			log.Printf("WARNING: usage: %s=%d exceeds soft quota %d", c.kind, c.value, c.threshold)
+			s.sendQuotaEvent(ctx, QuotaExceeded, c.kind, c.value, c.threshold)
+		case !over && was:
+			delete(q.exceeded, c.kind)
+			log.Printf("usage: %s=%d is back under soft quota %d", c.kind, c.value, c.threshold)
+			s.sendQuotaEvent(ctx, QuotaRecovered, c.kind, c.value, c.threshold)
+		}
+		if over {
+			u.Warnings = append(u.Warnings, QuotaWarning{Kind: c.kind, Value: c.value, Threshold: c.threshold, Since: since})
+			quotaExceeded.WithLabelValues(c.kind).Set(1)
+		} else {
+			quotaExceeded.WithLabelValues(c.kind).Set(0)
+		}
+	}
+	return u, nil
+}
+
+// sendQuotaEvent отправляет событие квоты на webhook в фоне: медленный получатель не должен
+// задерживать проверку. Ошибка -- только в лог. Из ctx берутся только след и request-id
+// (GET /api/v1/admin/usage), отмена запроса отправку не прерывает.
+func (s *Service) sendQuotaEvent(ctx context.Context, event, kind string, value, threshold int64) {
+	url := s.quota.limits.Webhook
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(map[string]any{"event": event, "kind": kind, "value": value, "threshold": threshold})
+	if err != nil {
+		return
+	}
+	go func() {
+		req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("usage: webhook %s: %v", event, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "task-manager-quota")
+		appMiddleware.PropagateTrace(req)
+		resp, err := quotaClient.Do(req)
+		if err != nil {
+			log.Printf("usage: webhook %s: %v", event, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			log.Printf("usage: webhook %s: unexpected status %d", event, resp.StatusCode)
+		}
+	}()
+}
+
+// RunUsageWatch проверяет использование сразу при старте и затем каждые interval, пока не отменён ctx.
+// Хранилище, не умеющее считать место, -- ничего не делает.
+func (s *Service) RunUsageWatch(ctx context.Context, interval time.Duration) {
+	if _, ok := s.capabilities().(UsageStore); !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.CheckUsage(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("usage: check error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// StorageUsage -- размеры файла задач и файлов рядом с ним (<файл задач>.*.json). Хранилище
+// в памяти места на диске не занимает.
+func (ts *TaskStore) StorageUsage(ctx context.Context) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := map[string]int64{}
+	if ts.filename == "" {
+		return out, nil
+	}
+	sidecars, err := filepath.Glob(ts.filename + ".*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range slices.Concat([]string{ts.filename}, sidecars) {
+		fi, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[filepath.Base(name)] = fi.Size()
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// StorageUsage -- размеры таблиц сервиса вместе с индексами и TOAST.
+func (r *PostgresRepository) StorageUsage(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.q.QueryContext(ctx,
+		"SELECT relname, pg_total_relation_size(relid) FROM pg_catalog.pg_statio_user_tables")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int64{}
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("table size: %w", err)
+		}
+		out[name] = size
+	}
+	return out, rows.Err()
+}
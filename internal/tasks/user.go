@@ -10,12 +10,46 @@ type User struct {
 	ID           int    `json:"id"`
 	Username     string `json:"username"`
 	PasswordHash string `json:"-"`
+
+	// Поля, которые заполняет провижининг через SCIM (см. provisioning.go).
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"-"`
+	ExternalID  string `json:"-"` // ID пользователя в каталоге IdP (externalId)
+	Disabled    bool   `json:"disabled,omitempty"`
+
+	// Roles -- роли из групп внешнего каталога (LDAP) на момент входа; не хранятся.
+	Roles []string `json:"-"`
 }
 
 type RegisterRequest struct {
 	Username   string `json:"username" validate:"required,min=2,max=50"`
-	Password   string `json:"password" validate:"required,min=6,max=50"`
+	Password   string `json:"password" validate:"required"` // длину и сложность проверяет политика паролей
 	InviteCode string `json:"invite_code" validate:"required"`
+	// Email -- необязательный адрес для сброса пароля
+	Email string `json:"email" validate:"omitempty,email,max=254"`
+}
+
+// ChangePasswordRequest -- DTO смены пароля (POST /api/v1/auth/password).
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// ForgotPasswordRequest -- DTO запроса письма со сбросом (POST /api/v1/auth/password/forgot): логин или адрес.
+type ForgotPasswordRequest struct {
+	Login string `json:"login" validate:"required,max=254"`
+}
+
+// ResetPasswordRequest -- DTO сброса пароля по токену из письма (POST /api/v1/auth/password/reset).
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// SetEmailRequest -- DTO смены адреса почты (PUT /api/v1/auth/email); пустой адрес -- убрать.
+type SetEmailRequest struct {
+	Email    string `json:"email" validate:"omitempty,email,max=254"`
+	Password string `json:"password" validate:"required"`
 }
 
 // LoginRequest — DTO для контракта входа в систему.
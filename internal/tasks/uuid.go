@@ -0,0 +1,140 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Глобальный идентификатор задачи -- поле UUID. Числовой ID остаётся для клиентов v1,
+// но при синхронизации нескольких экземпляров он совпадает у разных задач, а UUID -- нет.
+// Клиент может прислать свой UUID или ULID при создании, иначе сервер сгенерирует UUID v4.
+// Импортированные задачи хранят здесь внешний ключ (например, "jira:KEY-1").
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// ULID: 26 символов Crockford base32, первый не больше 7 (48-битная метка времени).
+	ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+)
+
+// ErrDuplicateUUID -- задача с таким UUID уже существует.
+var ErrDuplicateUUID = errors.New("task with this uuid already exists")
+
+// ErrInvalidTaskRef -- ссылка на задачу не похожа ни на числовой ID, ни на UUID.
+var ErrInvalidTaskRef = errors.New("invalid task reference")
+
+// newTaskUUID генерирует случайный UUID v4.
+func newTaskUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // версия 4
+	b[8] = b[8]&0x3f | 0x80 // вариант RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// IsClientID сообщает, годится ли строка как клиентский идентификатор (UUID или ULID).
+func IsClientID(s string) bool {
+	return uuidPattern.MatchString(s) || ulidPattern.MatchString(s)
+}
+
+// normalizeClientID приводит идентификатор к каноничному виду: UUID -- в нижнем регистре,
+// ULID -- в верхнем. Иначе один и тот же ID в разном регистре дал бы две задачи.
+func normalizeClientID(s string) string {
+	if uuidPattern.MatchString(s) {
+		return strings.ToLower(s)
+	}
+	if ulidPattern.MatchString(s) {
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// UUIDFinder -- опциональная возможность хранилища искать задачу по UUID без полного перебора.
+type UUIDFinder interface {
+	GetByUUID(ctx context.Context, uuid string) (*Task, error)
+}
+
+// GetTaskByUUID ищет задачу по UUID.
+func (s *Service) GetTaskByUUID(ctx context.Context, uuid string) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f, ok := s.repo.(UUIDFinder); ok {
+		return f.GetByUUID(ctx, normalizeClientID(uuid))
+	}
+
+	list, err := s.repo.GetAll(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	uuid = normalizeClientID(uuid)
+	for i := range list {
+		if list[i].UUID == uuid {
+			return &list[i], nil
+		}
+	}
+	return nil, ErrTaskNotFound
+}
+
+// ResolveTaskID -- слой совместимости: ссылка на задачу в URL может быть и числовым ID (v1),
+// и UUID/ULID. Возвращает числовой ID, с которым работает остальной сервис.
+func (s *Service) ResolveTaskID(ctx context.Context, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	if !IsClientID(ref) {
+		return 0, ErrInvalidTaskRef
+	}
+	t, err := s.GetTaskByUUID(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	return t.ID, nil
+}
+
+// GetByUUID находит задачу по UUID через индекс в памяти.
+func (ts *TaskStore) GetByUUID(ctx context.Context, uuid string) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.load(); err != nil {
+		return nil, err
+	}
+	id, ok := ts.uuids.Load(uuid)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return ts.GetByID(ctx, id.(int))
+}
+
+// GetByUUID находит задачу по UUID (колонка uuid уникальна).
+func (r *PostgresRepository) GetByUUID(ctx context.Context, uuid string) (*Task, error) {
+	var id int
+	err := r.q.QueryRowContext(ctx, "SELECT id FROM tasks WHERE uuid = $1", uuid).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// isUniqueViolation -- ошибка PostgreSQL 23505 (нарушение UNIQUE).
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation -- ошибка PostgreSQL 23503 (ссылка на несуществующую строку).
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
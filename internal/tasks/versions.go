@@ -0,0 +1,376 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	appMiddleware "task-manager/internal/middleware"
+)
+
+// История версий отдельной задачи (GET /api/v1/tasks/{id}/revisions): снимок задачи после каждого
+// создания, изменения и удаления, различия между снимками по полям и откат к снимку.
+// В отличие от истории git (/api/v1/history) работает с любым хранилищем, которое ведёт журнал версий.
+
+// maxTaskVersions -- сколько последних версий помним для одной задачи; старые вытесняются,
+// номера оставшихся не меняются.
+const maxTaskVersions = 200
+
+var (
+	ErrVersionsUnsupported = errors.New("task revisions are not supported by this storage")
+	ErrVersionNotFound     = errors.New("task revision not found")
+)
+
+// TaskVersion -- версия задачи: N-е изменение (с 1), его тип (ChangeCreated/Updated/Deleted) и снимок
+// задачи после изменения (у удалённой -- последнее состояние перед удалением). ChangedBy -- кто изменил
+// (0 -- сервер: правило, автоматизация, расписание).
+type TaskVersion struct {
+	TaskID    int       `json:"task_id"`
+	N         int       `json:"n"`
+	Type      string    `json:"type"`
+	ChangedBy int       `json:"changed_by,omitempty"`
+	At        time.Time `json:"at"`
+	Task      Task      `json:"task"`
+}
+
+// FieldDiff -- изменение одного поля между версиями. Пользовательские поля -- "fields.<имя>".
+// Значения -- как в JSON задачи; нет значения -- null.
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// TaskVersionDiff -- ответ GET /api/v1/tasks/{id}/revisions/{n}/diff. From == 0 -- сравнение с пустой задачей.
+type TaskVersionDiff struct {
+	TaskID  int         `json:"task_id"`
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Changes []FieldDiff `json:"changes"`
+}
+
+// VersionStore -- опциональная возможность хранилища вести журнал версий задач.
+type VersionStore interface {
+	// AppendVersion выдаёт версии следующий номер (v.N) и сохраняет её. Версия ChangeCreated начинает
+	// историю задачи заново: ID удалённой задачи может достаться новой.
+	AppendVersion(ctx context.Context, v *TaskVersion) error
+	// Versions -- версии задачи по возрастанию номера (нет версий -- пустой список).
+	Versions(ctx context.Context, taskID int) ([]TaskVersion, error)
+}
+
+func (s *Service) versions() (VersionStore, error) {
+	vs, ok := s.capabilities().(VersionStore)
+	if !ok {
+		return nil, ErrVersionsUnsupported
+	}
+	return vs, nil
+}
+
+// recordVersion записывает версию задачи. Как и bumpRevision, ошибка записи только пишется в лог:
+// само изменение уже сохранено.
+func (s *Service) recordVersion(ctx context.Context, typ string, t Task, at time.Time) {
+	store, err := s.versions()
+	if err != nil {
+		return
+	}
+	by, _ := ctx.Value(appMiddleware.UserIDKey).(int)
+	t.Urgency = 0
+	v := TaskVersion{TaskID: t.ID, Type: typ, ChangedBy: by, At: at, Task: t}
+	if err := store.AppendVersion(context.WithoutCancel(ctx), &v); err != nil {
+		log.Printf("request_id=%s versions: task=%d error: %v", appMiddleware.GetRequestID(ctx), t.ID, err)
+	}
+}
+
+// TaskVersions возвращает версии задачи по возрастанию номера. Удалённая задача отдаёт свою историю
+// до удаления; задача, не менявшаяся с включения журнала, -- пустой список.
+func (s *Service) TaskVersions(ctx context.Context, taskID int) ([]TaskVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store, err := s.versions()
+	if err != nil {
+		return nil, err
+	}
+	return store.Versions(ctx, taskID)
+}
+
+// TaskVersion возвращает версию n задачи.
+func (s *Service) TaskVersion(ctx context.Context, taskID, n int) (TaskVersion, error) {
+	list, err := s.TaskVersions(ctx, taskID)
+	if err != nil {
+		return TaskVersion{}, err
+	}
+	return findVersion(list, n)
+}
+
+func findVersion(list []TaskVersion, n int) (TaskVersion, error) {
+	i := slices.IndexFunc(list, func(v TaskVersion) bool { return v.N == n })
+	if i < 0 {
+		return TaskVersion{}, ErrVersionNotFound
+	}
+	return list[i], nil
+}
+
+// TaskVersionDiff сравнивает версию n задачи с версией from (0 -- с пустой задачей). from < 0 --
+// с предыдущей версией (у первой версии истории -- с пустой задачей).
+func (s *Service) TaskVersionDiff(ctx context.Context, taskID, n, from int) (TaskVersionDiff, error) {
+	list, err := s.TaskVersions(ctx, taskID)
+	if err != nil {
+		return TaskVersionDiff{}, err
+	}
+	to, err := findVersion(list, n)
+	if err != nil {
+		return TaskVersionDiff{}, err
+	}
+	if from < 0 {
+		from = 0
+		if i := slices.IndexFunc(list, func(v TaskVersion) bool { return v.N == n }); i > 0 {
+			from = list[i-1].N
+		}
+	}
+	var base *Task
+	if from > 0 {
+		v, err := findVersion(list, from)
+		if err != nil {
+			return TaskVersionDiff{}, err
+		}
+		base = &v.Task
+	}
+	changes, err := diffTasks(base, &to.Task)
+	if err != nil {
+		return TaskVersionDiff{}, err
+	}
+	return TaskVersionDiff{TaskID: taskID, From: from, To: n, Changes: changes}, nil
+}
+
+// RestoreTaskVersion возвращает задаче изменяемые поля версии n (как PUT /api/v1/tasks/{id}
+// с этими полями): откат -- обычное изменение с проверками и хуками, и оно само попадает в историю
+// новой версией. Удалённую задачу так не вернуть -- ErrTaskNotFound.
+func (s *Service) RestoreTaskVersion(ctx context.Context, taskID, n, userID int) (*Task, error) {
+	v, err := s.TaskVersion(ctx, taskID, n)
+	if err != nil {
+		return nil, err
+	}
+	snap := v.Task
+	t := Task{
+		ID:              taskID,
+		Title:           snap.Title,
+		Done:            snap.Done,
+		Status:          snap.Status,
+		Priority:        snap.Priority,
+		AssignedTo:      snap.AssignedTo,
+		Description:     snap.Description,
+		Tags:            snap.Tags,
+		Due:             snap.Due,
+		EstimateMinutes: snap.EstimateMinutes,
+		Fields:          snap.Fields,
+	}
+	if t.Fields == nil {
+		t.Fields = Fields{} // nil -- "не менять", а в версии полей не было
+	}
+	if err := s.UpdateTask(ctx, &t, userID); err != nil {
+		return nil, err
+	}
+	if IsDryRun(ctx) {
+		return &t, nil
+	}
+	return s.GetTaskByID(ctx, taskID, userID)
+}
+
+// diffTasks -- различия задач по полям JSON (nil -- пустая задача). Срочность не хранится
+// и не сравнивается.
+func diffTasks(from, to *Task) ([]FieldDiff, error) {
+	a, err := taskFieldMap(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := taskFieldMap(to)
+	if err != nil {
+		return nil, err
+	}
+	union := maps.Clone(a)
+	maps.Copy(union, b)
+
+	out := []FieldDiff{}
+	for _, k := range slices.Sorted(maps.Keys(union)) {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			out = append(out, FieldDiff{Field: k, From: a[k], To: b[k]})
+		}
+	}
+	return out, nil
+}
+
+func taskFieldMap(t *Task) (map[string]any, error) {
+	m := map[string]any{}
+	if t == nil {
+		return m, nil
+	}
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "urgency")
+	if f, ok := m["fields"].(map[string]any); ok {
+		delete(m, "fields")
+		for k, v := range f {
+			m["fields."+k] = v
+		}
+	}
+	return m, nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// versionsFile -- журнал версий JSON-хранилища, рядом с файлом задач.
+type versionsFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data map[int][]TaskVersion
+}
+
+func (ts *TaskStore) versionsPath() string {
+	return ts.filename + ".versions.json"
+}
+
+func (ts *TaskStore) loadVersions() error {
+	ts.versions.once.Do(func() {
+		ts.versions.data = map[int][]TaskVersion{}
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.versionsPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.versions.err = err
+			return
+		}
+		if err := json.Unmarshal(raw, &ts.versions.data); err != nil {
+			ts.versions.err = fmt.Errorf("parse %s: %w", ts.versionsPath(), err)
+		}
+	})
+	return ts.versions.err
+}
+
+// AppendVersion добавляет версию задачи и сохраняет файл; при ошибке записи журнал не меняется.
+func (ts *TaskStore) AppendVersion(ctx context.Context, v *TaskVersion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadVersions(); err != nil {
+		return err
+	}
+	ts.versions.mu.Lock()
+	defer ts.versions.mu.Unlock()
+
+	prev := ts.versions.data[v.TaskID]
+	list := prev
+	if v.Type == ChangeCreated {
+		list = nil
+	}
+	v.N = 1
+	if len(list) > 0 {
+		v.N = list[len(list)-1].N + 1
+	}
+	list = append(slices.Clone(list), *v)
+	if len(list) > maxTaskVersions {
+		list = list[len(list)-maxTaskVersions:]
+	}
+	ts.versions.data[v.TaskID] = list
+	if ts.filename == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(ts.versions.data)
+	if err == nil {
+		tmp := ts.versionsPath() + ".tmp"
+		if err = os.WriteFile(tmp, raw, 0600); err == nil {
+			err = os.Rename(tmp, ts.versionsPath())
+		}
+	}
+	if err != nil {
+		ts.versions.data[v.TaskID] = prev
+	}
+	return err
+}
+
+// Versions возвращает версии задачи по возрастанию номера.
+func (ts *TaskStore) Versions(ctx context.Context, taskID int) ([]TaskVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ts.loadVersions(); err != nil {
+		return nil, err
+	}
+	ts.versions.mu.Lock()
+	defer ts.versions.mu.Unlock()
+	return append([]TaskVersion{}, ts.versions.data[taskID]...), nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// AppendVersion сохраняет версию в task_versions (migrations/000024_task_versions.up.sql): номер --
+// следующий после последнего у задачи, сама версия -- в data как JSON.
+func (r *PostgresRepository) AppendVersion(ctx context.Context, v *TaskVersion) error {
+	if v.Type == ChangeCreated {
+		if _, err := r.q.ExecContext(ctx, "DELETE FROM task_versions WHERE task_id = $1", v.TaskID); err != nil {
+			return err
+		}
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = r.q.QueryRowContext(ctx, `
+		INSERT INTO task_versions (task_id, n, data)
+		SELECT $1, COALESCE(MAX(n), 0) + 1, $2 FROM task_versions WHERE task_id = $1
+		RETURNING n`, v.TaskID, raw).Scan(&v.N)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, "DELETE FROM task_versions WHERE task_id = $1 AND n <= $2",
+		v.TaskID, v.N-maxTaskVersions)
+	return err
+}
+
+// Versions возвращает версии задачи по возрастанию номера.
+func (r *PostgresRepository) Versions(ctx context.Context, taskID int) ([]TaskVersion, error) {
+	rows, err := r.q.QueryContext(ctx, "SELECT n, data FROM task_versions WHERE task_id = $1 ORDER BY n", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []TaskVersion{}
+	for rows.Next() {
+		var v TaskVersion
+		var n int
+		var raw []byte
+		if err := rows.Scan(&n, &raw); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("task %d revision %d: %w", taskID, n, err)
+		}
+		v.N = n
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
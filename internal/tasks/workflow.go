@@ -0,0 +1,514 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrWorkflowUnsupported -- хранилище не умеет хранить процесс (действует встроенный).
+	ErrWorkflowUnsupported = errors.New("storage does not support the status workflow")
+	// ErrInvalidWorkflow -- процесс задан неверно.
+	ErrInvalidWorkflow = errors.New("invalid status workflow")
+	// ErrTransitionNotAllowed -- процесс не разрешает такой переход между статусами.
+	ErrTransitionNotAllowed = errors.New("status transition is not allowed by the workflow")
+)
+
+// statusIDPattern -- ID статуса: строчные латинские буквы, цифры, "_" и "-" ("review", "on-hold").
+// До 20 символов: столько вмещает фильтр статуса у досок (boards.status).
+var statusIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,19}$`)
+
+// MaxWorkflowStatuses -- больше статусов в процессе не бывает.
+const MaxWorkflowStatuses = 20
+
+// statusCategories -- встроенные статусы; каждый свой статус относится к одному из них.
+var statusCategories = []string{StatusTodo, StatusInProgress, StatusDone}
+
+// WorkflowStatus -- статус процесса.
+type WorkflowStatus struct {
+	ID    string `json:"id" validate:"required,max=20"`     // значение поля status у задач: "review"
+	Label string `json:"label,omitempty" validate:"max=50"` // подпись для людей: "На проверке"
+	// Category -- встроенный статус, которому соответствует этот: задача в статусе категории done
+	// выполнена (done = true), in_progress -- в работе (срочность, CalDAV IN-PROCESS, отчёты).
+	Category string `json:"category" validate:"required,oneof=todo in_progress done"`
+}
+
+// Workflow -- процесс пространства (один на сервер): статусы задач и разрешённые переходы между ними.
+// Transitions -- из какого статуса в какие можно перейти; пустой -- любой переход разрешён.
+// Статуса, которого нет среди ключей, задача не покидает.
+type Workflow struct {
+	Statuses    []WorkflowStatus    `json:"statuses" validate:"required,min=2,max=20,dive"`
+	Initial     string              `json:"initial" validate:"required"` // статус новых задач, если он не указан
+	Transitions map[string][]string `json:"transitions,omitempty" validate:"max=20"`
+	UpdatedBy   int                 `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty"`
+}
+
+// WorkflowRequest -- тело PUT /api/v1/workspaces/{id}/workflow. Remap -- куда перевести задачи
+// со статусами, которых в новом процессе нет ("in_progress": "doing"); без него -- в статус той же категории.
+type WorkflowRequest struct {
+	Workflow
+	Remap map[string]string `json:"remap" validate:"max=50"`
+}
+
+// WorkflowResult -- ответ PUT /api/v1/workspaces/{id}/workflow: новый процесс и сколько задач переведено.
+type WorkflowResult struct {
+	Workflow
+	Migrated int `json:"migrated"`
+}
+
+// TransitionError -- переход, который запрещает процесс. Allowed -- куда из From можно перейти.
+type TransitionError struct {
+	From, To string
+	Allowed  []string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v: %s -> %s", ErrTransitionNotAllowed, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error { return ErrTransitionNotAllowed }
+
+// DefaultWorkflow -- встроенный процесс: todo, in_progress, done, переходы не ограничены.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Statuses: []WorkflowStatus{
+			{ID: StatusTodo, Category: StatusTodo},
+			{ID: StatusInProgress, Category: StatusInProgress},
+			{ID: StatusDone, Category: StatusDone},
+		},
+		Initial: StatusTodo,
+	}
+}
+
+// check проверяет процесс. Встроенное имя статуса означает свою категорию: "done" категории todo
+// сломал бы клиентов, которые отмечают выполнение статусом done.
+func (wf Workflow) check() error {
+	var problems []string
+	if len(wf.Statuses) < 2 || len(wf.Statuses) > MaxWorkflowStatuses {
+		problems = append(problems, fmt.Sprintf("want 2..%d statuses, got %d", MaxWorkflowStatuses, len(wf.Statuses)))
+	}
+	seen := make(map[string]bool, len(wf.Statuses))
+	done, open := false, false
+	for _, st := range wf.Statuses {
+		switch {
+		case !statusIDPattern.MatchString(st.ID):
+			problems = append(problems, fmt.Sprintf("status %q: id must be lowercase letters, digits, _ or -, up to 20", st.ID))
+		case seen[st.ID]:
+			problems = append(problems, fmt.Sprintf("status %q is listed twice", st.ID))
+		case !slices.Contains(statusCategories, st.Category):
+			problems = append(problems, fmt.Sprintf("status %q: unknown category %q (want todo, in_progress, done)", st.ID, st.Category))
+		case slices.Contains(statusCategories, st.ID) && st.Category != st.ID:
+			problems = append(problems, fmt.Sprintf("status %q: a built-in name must have category %q", st.ID, st.ID))
+		}
+		seen[st.ID] = true
+		if st.Category == StatusDone {
+			done = true
+		} else {
+			open = true
+		}
+	}
+	if !done || !open {
+		problems = append(problems, "want at least one status of category done and one of todo or in_progress")
+	}
+	if !seen[wf.Initial] {
+		problems = append(problems, fmt.Sprintf("initial %q is not a status of the workflow", wf.Initial))
+	}
+	for _, from := range slices.Sorted(maps.Keys(wf.Transitions)) {
+		if !seen[from] {
+			problems = append(problems, fmt.Sprintf("transitions from %q: not a status of the workflow", from))
+		}
+		for _, to := range wf.Transitions[from] {
+			if !seen[to] {
+				problems = append(problems, fmt.Sprintf("transition %s -> %s: %q is not a status of the workflow", from, to, to))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidWorkflow, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// IDs возвращает статусы процесса по порядку.
+func (wf Workflow) IDs() []string {
+	ids := make([]string, len(wf.Statuses))
+	for i, st := range wf.Statuses {
+		ids[i] = st.ID
+	}
+	return ids
+}
+
+// workflow -- процесс с индексами для категорий и переходов.
+type workflow struct {
+	Workflow
+	category map[string]string
+	next     map[string]map[string]bool // nil -- переходы не ограничены
+}
+
+func compileWorkflow(wf Workflow) *workflow {
+	c := &workflow{Workflow: wf, category: make(map[string]string, len(wf.Statuses))}
+	for _, st := range wf.Statuses {
+		c.category[st.ID] = st.Category
+	}
+	if len(wf.Transitions) > 0 {
+		c.next = make(map[string]map[string]bool, len(wf.Transitions))
+		for from, list := range wf.Transitions {
+			c.next[from] = make(map[string]bool, len(list))
+			for _, to := range list {
+				c.next[from][to] = true
+			}
+		}
+	}
+	return c
+}
+
+var (
+	defaultWorkflow = compileWorkflow(DefaultWorkflow())
+	// currentWorkflow -- действующий процесс (nil -- встроенный). Один сервер -- одно пространство,
+	// поэтому процесс общий для пакета, как и шкала приоритетов.
+	currentWorkflow atomic.Pointer[workflow]
+)
+
+func activeWorkflow() *workflow {
+	if wf := currentWorkflow.Load(); wf != nil {
+		return wf
+	}
+	return defaultWorkflow
+}
+
+// ActiveWorkflow возвращает действующий процесс.
+func ActiveWorkflow() Workflow {
+	wf := activeWorkflow().Workflow
+	wf.Statuses = slices.Clone(wf.Statuses)
+	wf.Transitions = maps.Clone(wf.Transitions)
+	return wf
+}
+
+// StatusCategory -- категория статуса в действующем процессе: todo, in_progress или done.
+// Встроенное имя, которого нет в процессе, -- само себе категория; неизвестный статус -- "".
+func StatusCategory(status string) string {
+	return activeWorkflow().categoryOf(status)
+}
+
+func (wf *workflow) categoryOf(status string) string {
+	if c, ok := wf.category[status]; ok {
+		return c
+	}
+	if slices.Contains(statusCategories, status) {
+		return status
+	}
+	return ""
+}
+
+// resolve переводит статус в статус процесса: свой остаётся, встроенное имя становится первым
+// статусом своей категории ("done" -> "closed"), неизвестный -- начальным.
+func (wf *workflow) resolve(status string) string {
+	if _, ok := wf.category[status]; ok {
+		return status
+	}
+	if slices.Contains(statusCategories, status) {
+		return wf.forCategory(status)
+	}
+	return wf.Initial
+}
+
+// forCategory -- первый статус категории c. Категории in_progress может не быть -- тогда начальный статус.
+func (wf *workflow) forCategory(c string) string {
+	if wf.category[wf.Initial] == c {
+		return wf.Initial
+	}
+	for _, st := range wf.Statuses {
+		if st.Category == c {
+			return st.ID
+		}
+	}
+	return wf.Initial
+}
+
+// allows сообщает, можно ли перейти из from в to. Из статуса вне процесса (задачу ещё не перевели)
+// переход не ограничивается.
+func (wf *workflow) allows(from, to string) bool {
+	if wf.next == nil || from == to {
+		return true
+	}
+	if _, ok := wf.category[from]; !ok {
+		return true
+	}
+	return wf.next[from][to]
+}
+
+// allowedFrom -- куда можно перейти из from, по порядку статусов процесса.
+func (wf *workflow) allowedFrom(from string) []string {
+	allowed := []string{}
+	for _, st := range wf.Statuses {
+		if st.ID != from && wf.allows(from, st.ID) {
+			allowed = append(allowed, st.ID)
+		}
+	}
+	return allowed
+}
+
+// statusMatches -- подходит ли статус задачи под фильтр правила, автоматизации или доски.
+// Встроенное имя, которого нет в процессе, выбирает всю категорию: "in_progress" -- и "doing", и "review".
+func statusMatches(filter, status string) bool {
+	if filter == "" || filter == status {
+		return true
+	}
+	wf := activeWorkflow()
+	if _, ok := wf.category[filter]; ok {
+		return false
+	}
+	return slices.Contains(statusCategories, filter) && wf.categoryOf(status) == filter
+}
+
+// checkTransition проверяет смену статуса задачи при обновлении.
+func checkTransition(from, to string) error {
+	wf := activeWorkflow()
+	if wf.allows(from, to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to, Allowed: wf.allowedFrom(from)}
+}
+
+// WorkflowStore -- опциональная возможность хранилища хранить процесс пространства.
+// GetWorkflow возвращает ok == false, если процесс не сохраняли.
+type WorkflowStore interface {
+	GetWorkflow(ctx context.Context) (wf Workflow, ok bool, err error)
+	SaveWorkflow(ctx context.Context, wf Workflow) error
+}
+
+// LoadWorkflow включает сохранённый процесс при запуске (без него -- встроенный) и переводит задачи
+// со статусами вне процесса в его статусы.
+func (s *Service) LoadWorkflow(ctx context.Context) error {
+	ws, ok := s.capabilities().(WorkflowStore)
+	if !ok {
+		return nil
+	}
+	wf, found, err := ws.GetWorkflow(ctx)
+	if err != nil || !found {
+		return err
+	}
+	if err := wf.check(); err != nil {
+		return err
+	}
+	old := activeWorkflow()
+	currentWorkflow.Store(compileWorkflow(wf))
+	n, err := s.migrateStatuses(ctx, old, nil)
+	if err != nil {
+		return fmt.Errorf("migrate task statuses: %w", err)
+	}
+	if n > 0 {
+		log.Printf("workflow: %d task(s) moved to the statuses %v", n, wf.IDs())
+	}
+	return nil
+}
+
+// SaveWorkflow заменяет процесс и переводит задачи со статусами, которых в нём больше нет:
+// по remap, иначе -- в статус той же категории.
+func (s *Service) SaveWorkflow(ctx context.Context, userID int, req WorkflowRequest, now time.Time) (WorkflowResult, error) {
+	if err := ctx.Err(); err != nil {
+		return WorkflowResult{}, err
+	}
+	wf := req.Workflow
+	if err := wf.check(); err != nil {
+		return WorkflowResult{}, err
+	}
+	for from, to := range req.Remap {
+		if !slices.Contains(wf.IDs(), to) {
+			return WorkflowResult{}, fmt.Errorf("%w: remap %q -> %q: %q is not a status of the new workflow", ErrInvalidWorkflow, from, to, to)
+		}
+	}
+	ws, ok := s.capabilities().(WorkflowStore)
+	if !ok {
+		return WorkflowResult{}, ErrWorkflowUnsupported
+	}
+	at := now.UTC()
+	wf.UpdatedBy, wf.UpdatedAt = userID, &at
+	if err := ws.SaveWorkflow(ctx, wf); err != nil {
+		return WorkflowResult{}, err
+	}
+	old := activeWorkflow()
+	currentWorkflow.Store(compileWorkflow(wf))
+	n, err := s.migrateStatuses(ctx, old, req.Remap)
+	if err != nil {
+		return WorkflowResult{}, fmt.Errorf("workflow saved, but tasks were not migrated (they are on restart): %w", err)
+	}
+	return WorkflowResult{Workflow: wf, Migrated: n}, nil
+}
+
+// migrateStatuses переводит задачи со статусом вне действующего процесса в его статусы: сначала по remap,
+// затем по категории, которую статус имел в процессе old. Переходы при этом не проверяются.
+// Изменения атомарны и попадают в ленту изменений, как обычное обновление задачи.
+func (s *Service) migrateStatuses(ctx context.Context, old *workflow, remap map[string]string) (int, error) {
+	cur := activeWorkflow()
+	var moved []Task
+	err := s.WithTx(ctx, func(tx TxStore) error {
+		moved = moved[:0]
+		list, err := tx.GetAll(ctx, 0)
+		if err != nil {
+			return err
+		}
+		for _, t := range list {
+			if _, ok := cur.category[t.Status]; ok {
+				continue
+			}
+			to, ok := remap[t.Status]
+			if !ok {
+				c := old.categoryOf(t.Status)
+				if c == "" {
+					c = StatusTodo
+					if t.Done {
+						c = StatusDone
+					}
+				}
+				to = cur.forCategory(c)
+			}
+			t.Status = to
+			t.Done = cur.category[to] == StatusDone
+			if err := tx.Update(ctx, &t, t.UserID); err != nil {
+				return err
+			}
+			moved = append(moved, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range moved {
+		s.publishChange(ctx, ChangeUpdated, t)
+	}
+	return len(moved), nil
+}
+
+// ---------------------------------------------------------------------------
+// JSON
+// ---------------------------------------------------------------------------
+
+// workflowFile -- процесс JSON-хранилища, рядом с файлом задач.
+type workflowFile struct {
+	once sync.Once
+	err  error
+	mu   sync.Mutex
+	data *Workflow
+}
+
+func (ts *TaskStore) workflowPath() string {
+	return ts.filename + ".workflow.json"
+}
+
+func (ts *TaskStore) loadWorkflow() error {
+	ts.workflow.once.Do(func() {
+		if ts.filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(ts.workflowPath())
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			ts.workflow.err = err
+			return
+		}
+		var wf Workflow
+		if err := json.Unmarshal(raw, &wf); err != nil {
+			ts.workflow.err = fmt.Errorf("parse %s: %w", ts.workflowPath(), err)
+			return
+		}
+		ts.workflow.data = &wf
+	})
+	return ts.workflow.err
+}
+
+// GetWorkflow возвращает сохранённый процесс.
+func (ts *TaskStore) GetWorkflow(ctx context.Context) (Workflow, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Workflow{}, false, err
+	}
+	if err := ts.loadWorkflow(); err != nil {
+		return Workflow{}, false, err
+	}
+	ts.workflow.mu.Lock()
+	defer ts.workflow.mu.Unlock()
+
+	if ts.workflow.data == nil {
+		return Workflow{}, false, nil
+	}
+	wf := *ts.workflow.data
+	wf.Statuses = slices.Clone(wf.Statuses)
+	wf.Transitions = maps.Clone(wf.Transitions)
+	return wf, true, nil
+}
+
+// SaveWorkflow переписывает файл процесса.
+func (ts *TaskStore) SaveWorkflow(ctx context.Context, wf Workflow) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ts.loadWorkflow(); err != nil {
+		return err
+	}
+	ts.workflow.mu.Lock()
+	defer ts.workflow.mu.Unlock()
+
+	if ts.filename != "" {
+		raw, err := json.MarshalIndent(wf, "", "   ")
+		if err != nil {
+			return err
+		}
+		tmp := ts.workflowPath() + ".tmp"
+		if err := os.WriteFile(tmp, raw, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, ts.workflowPath()); err != nil {
+			return err
+		}
+	}
+	ts.workflow.data = &wf
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// PostgreSQL
+// ---------------------------------------------------------------------------
+
+// GetWorkflow читает процесс из workflow (migrations/000030_workflow.up.sql).
+func (r *PostgresRepository) GetWorkflow(ctx context.Context) (Workflow, bool, error) {
+	var raw []byte
+	err := r.q.QueryRowContext(ctx, "SELECT data FROM workflow WHERE id = 1").Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Workflow{}, false, nil
+	}
+	if err != nil {
+		return Workflow{}, false, err
+	}
+	var wf Workflow
+	if err := json.Unmarshal(raw, &wf); err != nil {
+		return Workflow{}, false, fmt.Errorf("workflow: %w", err)
+	}
+	return wf, true, nil
+}
+
+// SaveWorkflow сохраняет процесс (одна строка с id = 1).
+func (r *PostgresRepository) SaveWorkflow(ctx context.Context, wf Workflow) error {
+	raw, err := json.Marshal(wf)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx, `INSERT INTO workflow (id, data) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, raw)
+	return err
+}
@@ -0,0 +1,5 @@
+-- Расширяем задачу: внешний UUID (для импорта), описание, метки и срок выполнения
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS uuid VARCHAR(64) UNIQUE;          -- NULL, если задача создана у нас
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due TIMESTAMPTZ;                   -- NULL -- без срока
@@ -0,0 +1,3 @@
+-- Статус задачи (этап работы). Поле done остаётся и синхронизируется приложением.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'todo';
+UPDATE tasks SET status = 'done' WHERE done = true AND status = 'todo';
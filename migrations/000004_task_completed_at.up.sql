@@ -0,0 +1,3 @@
+-- Время выполнения задачи (для триггеров "задача выполнена" в интеграциях)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+UPDATE tasks SET completed_at = now() WHERE done = true AND completed_at IS NULL;
@@ -0,0 +1,3 @@
+-- UUID становится глобальным идентификатором задачи: выдаём его задачам, созданным до этого.
+-- gen_random_uuid() встроена в PostgreSQL 13+ (в docker-compose -- 15).
+UPDATE tasks SET uuid = gen_random_uuid()::text WHERE uuid IS NULL;
@@ -0,0 +1,6 @@
+-- Приоритет становится упорядоченным типом: low < medium < high < critical.
+-- Приводим существующие значения к каноническому виду и расширяем ограничение.
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_priority_check;
+UPDATE tasks SET priority = lower(trim(priority)) WHERE priority <> lower(trim(priority));
+UPDATE tasks SET priority = 'medium' WHERE priority NOT IN ('low', 'medium', 'high', 'critical');
+ALTER TABLE tasks ADD CONSTRAINT tasks_priority_check CHECK (priority IN ('low', 'medium', 'high', 'critical'));
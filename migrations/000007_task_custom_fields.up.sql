@@ -0,0 +1,3 @@
+-- Пользовательские поля задачи (CUSTOM_FIELDS): {"customer": "ООО Ромашка", "ticket": 42}
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS fields JSONB;
+CREATE INDEX IF NOT EXISTS tasks_fields_idx ON tasks USING GIN (fields);
@@ -0,0 +1,7 @@
+-- Настройки пользователя (часовой пояс, язык, приоритет по умолчанию, сводка, уведомления).
+-- Хранятся одним JSON: новые настройки не требуют миграций, недостающие поля берутся по умолчанию.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    data JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+-- Надгробия задач, слитых в другую задачу (POST /api/v1/tasks/{id}/merge).
+-- По старому ID API отвечает перенаправлением на to_id; если целевую задачу удалить, надгробие удаляется вместе с ней.
+CREATE TABLE IF NOT EXISTS task_redirects (
+    from_id INT PRIMARY KEY,
+    to_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_redirects_to_id ON task_redirects(to_id);
@@ -0,0 +1,14 @@
+-- Типизированные связи задач: blocks, relates_to, duplicates, caused_by (POST /api/v1/tasks/{id}/relations).
+-- Связи удалённой задачи удаляются вместе с ней.
+CREATE TABLE IF NOT EXISTS task_relations (
+    id SERIAL PRIMARY KEY,
+    from_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    to_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('blocks', 'relates_to', 'duplicates', 'caused_by')),
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (from_id <> to_id),
+    UNIQUE (from_id, to_id, type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_relations_to_id ON task_relations(to_id);
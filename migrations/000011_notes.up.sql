@@ -0,0 +1,9 @@
+-- Заметки по дням (личный дневник, markdown): у пользователя одна заметка на день.
+CREATE TABLE IF NOT EXISTS notes (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, day)
+);
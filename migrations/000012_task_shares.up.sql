@@ -0,0 +1,13 @@
+-- Публичные ссылки на отдельные задачи (GET/PUT /share/{token}).
+-- Хранится только SHA-256 токена; ссылки удалённой задачи удаляются вместе с ней.
+CREATE TABLE IF NOT EXISTS task_shares (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    access VARCHAR(10) NOT NULL CHECK (access IN ('read', 'write')),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_shares_task_id ON task_shares(task_id);
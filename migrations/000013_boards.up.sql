@@ -0,0 +1,12 @@
+-- Публичные доски: вид задач проекта (метки) только для чтения по ссылке GET /board/{token}.
+-- Хранится только SHA-256 токена; отзыв доски -- удаление строки.
+CREATE TABLE IF NOT EXISTS boards (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT '',
+    include_done BOOLEAN NOT NULL DEFAULT FALSE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- Время создания задачи (возраст задачи входит в срочность, см. internal/tasks/urgency.go).
+-- У старых задач время создания неизвестно -- остаётся NULL, возраст для них не учитывается.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
@@ -0,0 +1,13 @@
+-- Правила эскалации (/api/v1/rules): условия и действия -- JSON, как их отдаёт API.
+-- fired_task_ids -- задачи, на которых правило уже сработало (повторно не срабатывает, пока задача подходит).
+CREATE TABLE IF NOT EXISTS rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    condition JSONB NOT NULL,
+    actions JSONB NOT NULL,
+    created_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    fired_task_ids INT[] NOT NULL DEFAULT '{}'
+);
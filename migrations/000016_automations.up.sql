@@ -0,0 +1,25 @@
+-- Автоматизации (/api/v1/automations): событие, фильтр и шаги -- JSON, как их отдаёт API.
+CREATE TABLE IF NOT EXISTS automations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    event VARCHAR(30) NOT NULL,
+    condition JSONB NOT NULL,
+    actions JSONB NOT NULL,
+    created_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Журнал выполнения: по каждой автоматизации хранятся последние 100 запусков.
+CREATE TABLE IF NOT EXISTS automation_runs (
+    id SERIAL PRIMARY KEY,
+    automation_id INT NOT NULL REFERENCES automations(id) ON DELETE CASCADE,
+    task_id INT NOT NULL,
+    event VARCHAR(30) NOT NULL,
+    at TIMESTAMPTZ NOT NULL,
+    ok BOOLEAN NOT NULL,
+    actions JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS automation_runs_automation_id_idx ON automation_runs (automation_id, id DESC);
@@ -0,0 +1,16 @@
+-- Расписания создания задач (/api/v1/schedules). next_run_at -- следующий запуск (NULL -- выключено),
+-- по нему планировщик после перезапуска находит пропущенные запуски.
+CREATE TABLE IF NOT EXISTS schedules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    cron VARCHAR(100) NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    catch_up BOOLEAN NOT NULL DEFAULT FALSE,
+    task JSONB NOT NULL,
+    created_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_run_at TIMESTAMPTZ,
+    next_run_at TIMESTAMPTZ
+);
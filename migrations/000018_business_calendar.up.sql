@@ -0,0 +1,8 @@
+-- Рабочий календарь сервера (/api/v1/calendar): рабочие дни, часы и праздники -- одна строка, JSON как в API.
+CREATE TABLE IF NOT EXISTS business_calendar (
+    id INT PRIMARY KEY CHECK (id = 1),
+    data JSONB NOT NULL
+);
+
+-- Расписания, которые не создают задачи в выходные и праздники.
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS workdays_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Оценка трудозатрат задачи в минутах (0 -- без оценки), см. отчёт о загрузке internal/tasks/capacity.go.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_minutes INT NOT NULL DEFAULT 0 CHECK (estimate_minutes >= 0);
@@ -0,0 +1,9 @@
+-- Отчёты по расписанию (/api/v1/admin/reports). Настройки -- в data (JSON как в API),
+-- next_run_at -- следующий запуск (NULL -- выключено), last_error -- ошибка последней доставки.
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id SERIAL PRIMARY KEY,
+    data JSONB NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    next_run_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT ''
+);
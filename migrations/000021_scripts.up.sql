@@ -0,0 +1,8 @@
+-- Скрипты на событиях задач (/api/v1/admin/scripts). Скрипт -- в data (JSON как в API),
+-- last_error и last_error_at -- последняя ошибка выполнения.
+CREATE TABLE IF NOT EXISTS scripts (
+    id SERIAL PRIMARY KEY,
+    data JSONB NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_error_at TIMESTAMPTZ
+);
@@ -0,0 +1,13 @@
+-- Журнал ревизий для синхронизации офлайн-клиентов (POST /api/v1/sync): последняя ревизия каждой задачи
+-- по UUID, у удалённой -- надгробие (deleted). Номера ревизий -- из общей последовательности.
+CREATE SEQUENCE IF NOT EXISTS task_revision_seq;
+
+CREATE TABLE IF NOT EXISTS task_revisions (
+    uuid VARCHAR(64) PRIMARY KEY,
+    task_id INT NOT NULL,
+    rev BIGINT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_revisions_rev_idx ON task_revisions (rev);
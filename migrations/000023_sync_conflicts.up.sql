@@ -0,0 +1,9 @@
+-- Очередь конфликтов синхронизации (стратегия manual, /api/v1/conflicts). Конфликт -- в data
+-- (мутация клиента и версия сервера, JSON как в API).
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sync_conflicts_user_idx ON sync_conflicts (user_id);
@@ -0,0 +1,8 @@
+-- История версий задач (GET /api/v1/tasks/{id}/revisions). Версия -- в data (тип изменения, автор,
+-- снимок задачи, JSON как в API); n -- номер версии у задачи, с 1.
+CREATE TABLE IF NOT EXISTS task_versions (
+    task_id INT NOT NULL,
+    n INT NOT NULL,
+    data JSONB NOT NULL,
+    PRIMARY KEY (task_id, n)
+);
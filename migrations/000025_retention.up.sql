@@ -0,0 +1,6 @@
+-- Политика хранения (/api/v1/admin/retention): одна строка, в data -- политика (policy)
+-- и граница удалённых уборщиком надгробий журнала синхронизации (tombstone_horizon).
+CREATE TABLE IF NOT EXISTS retention (
+    id INT PRIMARY KEY CHECK (id = 1),
+    data JSONB NOT NULL DEFAULT '{}'
+);
@@ -0,0 +1,6 @@
+-- Провижининг пользователей через SCIM (/scim/v2/Users): имя для показа, почта,
+-- ID в каталоге IdP (externalId) и блокировка (active=false).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(320) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
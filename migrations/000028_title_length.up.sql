@@ -0,0 +1,4 @@
+-- Длина названия настраивается (TITLE_MAX_LENGTH, до 500 символов): колонка вмещает предел,
+-- а действующее ограничение проверяет валидатор. VARCHAR(n) в PostgreSQL считает символы, не байты.
+ALTER TABLE tasks ALTER COLUMN title TYPE VARCHAR(500);
+ALTER TABLE subtasks ALTER COLUMN title TYPE VARCHAR(500);
@@ -0,0 +1,10 @@
+-- Шкала приоритетов настраивается (PUT /api/v1/priorities, TASK_PRIORITIES): допустимые значения
+-- проверяет сервис, а CHECK со встроенным списком уровней убирается.
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_priority_check;
+ALTER TABLE tasks ALTER COLUMN priority TYPE VARCHAR(32);
+
+-- Сохранённая шкала: одна строка с id = 1, уровни и уровень по умолчанию -- в JSON.
+CREATE TABLE IF NOT EXISTS priority_scale (
+    id   INT PRIMARY KEY CHECK (id = 1),
+    data JSONB NOT NULL
+);
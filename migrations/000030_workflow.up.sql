@@ -0,0 +1,6 @@
+-- Процесс пространства (/api/v1/workspaces/{id}/workflow): статусы задач и переходы между ними --
+-- одна строка, JSON как в API. Колонка tasks.status (VARCHAR(32)) уже вмещает свои статусы.
+CREATE TABLE IF NOT EXISTS workflow (
+    id   INT PRIMARY KEY CHECK (id = 1),
+    data JSONB NOT NULL
+);
@@ -0,0 +1,6 @@
+-- Закрытые проекты (/api/v1/projects/{tag}/acl): метка и участники, которым видны её задачи --
+-- одна строка, JSON-список как в API.
+CREATE TABLE IF NOT EXISTS project_acls (
+    id   INT PRIMARY KEY CHECK (id = 1),
+    data JSONB NOT NULL
+);
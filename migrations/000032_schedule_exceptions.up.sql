@@ -0,0 +1,3 @@
+-- Исключения расписаний (/api/v1/schedules/{id}/exceptions/{at}): пропуск или правка отдельных
+-- запусков серии. JSON-список как в API, по возрастанию момента запуска.
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS exceptions JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,10 @@
+// Package migrations встраивает SQL-миграции PostgreSQL в бинарник сервера:
+// их применяет DB_MIGRATE=true (internal/migrate), и файлы рядом с сервером не нужны.
+package migrations
+
+import "embed"
+
+// FS -- файлы NNNNNN_name.up.sql в корне.
+//
+//go:embed *.up.sql
+var FS embed.FS
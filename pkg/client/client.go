@@ -0,0 +1,273 @@
+// Package client -- Go-клиент HTTP API task-manager для других сервисов: типизированные методы
+// вместо ручных запросов, повтор временных ошибок, контекст в каждом вызове и итератор по страницам списка.
+//
+//	c, err := client.New("http://localhost:8080")
+//	if err != nil { ... }
+//	if err := c.Login(ctx, "mama", "secret"); err != nil { ... }
+//	for t, err := range c.Tasks(ctx, &client.ListOptions{Due: "today"}) {
+//		if err != nil { ... }
+//		fmt.Println(t.ID, t.Title)
+//	}
+//
+// Клиент поддерживается вручную вместе с API (internal/tasks): новое поле или эндпоинт добавляется
+// в обоих местах. Клиент безопасен для использования из нескольких горутин.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Значения по умолчанию.
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 200 * time.Millisecond
+
+	// maxRetryAfter -- дольше этого Retry-After от сервера не ждём.
+	maxRetryAfter = 30 * time.Second
+)
+
+// Client -- клиент API. Создаётся через New.
+type Client struct {
+	base       *url.URL
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+	userAgent  string
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option настраивает клиента.
+type Option func(*Client)
+
+// WithToken -- JWT для заголовка Authorization (вместо Login).
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient -- свой http.Client (прокси, TLS, таймауты).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries -- сколько раз повторять временные ошибки и начальная пауза между попытками
+// (дальше она удваивается). 0 -- не повторять.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = n, backoff }
+}
+
+// WithUserAgent -- заголовок User-Agent, чтобы запросы сервиса было видно в журнале доступа.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New создаёт клиента для сервера baseURL (например, "https://tasks.example.com").
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL must be http(s)://host, got %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{
+		base:       u,
+		http:       &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+		userAgent:  "task-manager-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// SetToken меняет JWT (например, после обновления снаружи).
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// Token -- текущий JWT.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Login входит по логину и паролю и запоминает выданный токен.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	var out struct {
+		Token string `json:"token"`
+	}
+	in := map[string]string{"username": username, "password": password}
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/auth/login", nil, in, &out, false); err != nil {
+		return err
+	}
+	c.SetToken(out.Token)
+	return nil
+}
+
+// do выполняет запрос и декодирует JSON-ответ в out (nil -- тело не нужно).
+// Повторяет сетевые ошибки и ответы 429/502/503/504, если запрос можно безопасно повторить:
+// GET, PUT и DELETE -- всегда, POST -- только если retryPOST.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any, retryPOST bool) (http.Header, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	u := *c.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	retryable := method != http.MethodPost || retryPOST
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body)
+		if err == nil && resp.StatusCode < 400 {
+			defer resp.Body.Close()
+			if out != nil && resp.StatusCode != http.StatusNoContent {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return nil, fmt.Errorf("client: decode %s %s: %w", method, path, err)
+				}
+			}
+			return resp.Header, nil
+		}
+
+		var wait time.Duration
+		if err == nil {
+			err = readAPIError(resp)
+			wait = retryAfter(resp.Header)
+			if !temporaryStatus(resp.StatusCode) {
+				return nil, err
+			}
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !retryable || attempt >= c.maxRetries {
+			return nil, err
+		}
+		if wait == 0 {
+			// Экспоненциальная пауза с разбросом, чтобы клиенты не били в сервер одновременно.
+			wait = c.backoff << attempt
+			wait += rand.N(wait/2 + 1)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(req)
+}
+
+// temporaryStatus -- ответы, после которых запрос имеет смысл повторить.
+func temporaryStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter читает Retry-After в секундах; 0 -- заголовка нет.
+func retryAfter(h http.Header) time.Duration {
+	n, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return min(time.Duration(n)*time.Second, maxRetryAfter)
+}
+
+// APIError -- ошибка, которую вернул сервер (тело {"api_error": {...}}).
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestID  string `json:"request_id"`
+	Details    any    `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("task-manager: %d %s: %s (request_id=%s)", e.StatusCode, e.Code, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("task-manager: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound -- сервер ответил 404.
+func IsNotFound(err error) bool {
+	return statusOf(err) == http.StatusNotFound
+}
+
+// IsConflict -- сервер ответил 409 (например, задача с таким uuid уже есть).
+func IsConflict(err error) bool {
+	return statusOf(err) == http.StatusConflict
+}
+
+// IsCursorExpired -- курсор Changes устарел (410): сервер перезапущен или лента ушла вперёд.
+func IsCursorExpired(err error) bool {
+	return statusOf(err) == http.StatusGone
+}
+
+func statusOf(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// readAPIError разбирает тело ошибки. Не JSON (например, от прокси) -- сообщение из текста ответа.
+func readAPIError(resp *http.Response) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var env struct {
+		Error *APIError `json:"api_error"`
+	}
+	if json.Unmarshal(data, &env) == nil && env.Error != nil {
+		env.Error.StatusCode = resp.StatusCode
+		return env.Error
+	}
+	return &APIError{StatusCode: resp.StatusCode, Code: "http_error", Message: strings.TrimSpace(string(data))}
+}
@@ -0,0 +1,348 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Приоритеты задачи.
+const (
+	PriorityLow      = "low"
+	PriorityMedium   = "medium"
+	PriorityHigh     = "high"
+	PriorityCritical = "critical"
+)
+
+// Статусы задачи. Done == (Status == StatusDone).
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// DefaultPageSize -- размер страницы итератора Tasks.
+const DefaultPageSize = 100
+
+// Task -- задача, как её отдаёт API.
+type Task struct {
+	ID              int            `json:"id"`
+	UserID          int            `json:"user_id"`
+	AssignedTo      int            `json:"assigned_to"`
+	Title           string         `json:"title"`
+	Done            bool           `json:"done"`
+	Status          string         `json:"status"`
+	Priority        string         `json:"priority"`
+	SubTasks        []SubTask      `json:"subtasks"`
+	UUID            string         `json:"uuid,omitempty"`
+	Description     string         `json:"description,omitempty"`
+	Tags            []string       `json:"tags,omitempty"`
+	Due             *time.Time     `json:"due,omitempty"`
+	EstimateMinutes int            `json:"estimate_minutes,omitempty"`
+	CompletedAt     *time.Time     `json:"completed_at,omitempty"`
+	CreatedAt       *time.Time     `json:"created_at,omitempty"`
+	Urgency         float64        `json:"urgency,omitempty"`
+	Fields          map[string]any `json:"fields,omitempty"`
+}
+
+// SubTask -- пункт чек-листа задачи.
+type SubTask struct {
+	ID     int    `json:"id"`
+	TaskID int    `json:"task_id"`
+	Title  string `json:"title"`
+	Done   bool   `json:"done"`
+}
+
+// CreateTaskRequest -- новая задача. Пустые поля сервер заполнит сам: исполнитель -- автор,
+// приоритет -- из настроек пользователя. UUID пустой -- клиент сгенерирует его сам,
+// чтобы создание можно было безопасно повторить.
+type CreateTaskRequest struct {
+	UUID              string         `json:"uuid,omitempty"`
+	Title             string         `json:"title"`
+	AssignedTo        int            `json:"assigned_to,omitempty"`
+	Done              bool           `json:"done,omitempty"`
+	Status            string         `json:"status,omitempty"`
+	Priority          string         `json:"priority,omitempty"`
+	Description       string         `json:"description,omitempty"`
+	Tags              []string       `json:"tags,omitempty"`
+	Due               *time.Time     `json:"due,omitempty"`
+	DueInBusinessDays *int           `json:"due_in_business_days,omitempty"`
+	EstimateMinutes   int            `json:"estimate_minutes,omitempty"`
+	Fields            map[string]any `json:"fields,omitempty"`
+}
+
+// UpdateTaskRequest -- задача целиком (PUT заменяет все поля): обычно это изменённая копия GetTask.
+type UpdateTaskRequest struct {
+	Title           string         `json:"title"`
+	Done            bool           `json:"done"`
+	Status          string         `json:"status,omitempty"`
+	Priority        string         `json:"priority"`
+	AssignedTo      int            `json:"assigned_to"`
+	Description     string         `json:"description"`
+	Tags            []string       `json:"tags"`
+	Due             *time.Time     `json:"due"`
+	EstimateMinutes int            `json:"estimate_minutes"`
+	Fields          map[string]any `json:"fields,omitempty"`
+}
+
+// UpdateRequest -- запрос на замену задачи её текущими значениями; меняйте нужные поля.
+func (t Task) UpdateRequest() UpdateTaskRequest {
+	return UpdateTaskRequest{
+		Title:           t.Title,
+		Done:            t.Done,
+		Status:          t.Status,
+		Priority:        t.Priority,
+		AssignedTo:      t.AssignedTo,
+		Description:     t.Description,
+		Tags:            t.Tags,
+		Due:             t.Due,
+		EstimateMinutes: t.EstimateMinutes,
+		Fields:          t.Fields,
+	}
+}
+
+// ListOptions -- фильтры и сортировка списка задач (те же, что у GET /api/v1/tasks).
+type ListOptions struct {
+	Sort     string            // priority, -due, urgency, ...
+	Due      string            // today, this_week, overdue, ...
+	Fields   map[string]string // пользовательские поля: имя -> значение
+	PageSize int               // размер страницы для Tasks; 0 -- DefaultPageSize
+}
+
+func (o *ListOptions) query() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Due != "" {
+		q.Set("due", o.Due)
+	}
+	for name, v := range o.Fields {
+		q.Set("field."+name, v)
+	}
+	return q
+}
+
+// ListTasks возвращает весь список одним запросом.
+func (c *Client) ListTasks(ctx context.Context, opts *ListOptions) ([]Task, error) {
+	var out []Task
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/tasks", opts.query(), nil, &out, false)
+	return out, err
+}
+
+// TaskPage -- одна страница списка.
+type TaskPage struct {
+	Tasks []Task
+	Total int // сколько задач во всём списке с этими фильтрами
+}
+
+// ListTasksPage возвращает limit задач начиная с offset.
+func (c *Client) ListTasksPage(ctx context.Context, opts *ListOptions, offset, limit int) (TaskPage, error) {
+	q := opts.query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	var page TaskPage
+	h, err := c.do(ctx, http.MethodGet, "/api/v1/tasks", q, nil, &page.Tasks, false)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	page.Total, _ = strconv.Atoi(h.Get("X-Total-Count"))
+	return page, nil
+}
+
+// Tasks перебирает задачи постранично: следующая страница запрашивается, когда кончилась текущая.
+// Ошибка отдаётся вторым значением и завершает перебор. Страницы считаются по смещению,
+// поэтому задачи, созданные или удалённые во время перебора, могут сдвинуть границу страниц.
+func (c *Client) Tasks(ctx context.Context, opts *ListOptions) iter.Seq2[Task, error] {
+	size := DefaultPageSize
+	if opts != nil && opts.PageSize > 0 {
+		size = opts.PageSize
+	}
+	return func(yield func(Task, error) bool) {
+		for offset := 0; ; {
+			page, err := c.ListTasksPage(ctx, opts, offset, size)
+			if err != nil {
+				yield(Task{}, err)
+				return
+			}
+			for _, t := range page.Tasks {
+				if !yield(t, nil) {
+					return
+				}
+			}
+			offset += len(page.Tasks)
+			if len(page.Tasks) < size || offset >= page.Total {
+				return
+			}
+		}
+	}
+}
+
+// GetTask возвращает задачу по ID.
+func (c *Client) GetTask(ctx context.Context, id int) (Task, error) {
+	return c.getTask(ctx, strconv.Itoa(id))
+}
+
+// GetTaskByUUID возвращает задачу по UUID/ULID.
+func (c *Client) GetTaskByUUID(ctx context.Context, uuid string) (Task, error) {
+	return c.getTask(ctx, uuid)
+}
+
+func (c *Client) getTask(ctx context.Context, ref string) (Task, error) {
+	var t Task
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+ref, nil, nil, &t, false)
+	return t, err
+}
+
+// CreateTask создаёт задачу. Повтор после обрыва связи безопасен: задача идентифицируется UUID,
+// и если первая попытка всё-таки дошла, вернётся созданная ею задача. Свой UUID, который уже
+// занят, -- ошибка 409 (IsConflict).
+func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (Task, error) {
+	generated := req.UUID == ""
+	if generated {
+		id, err := newUUID()
+		if err != nil {
+			return Task{}, err
+		}
+		req.UUID = id
+	}
+	var t Task
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/tasks", nil, req, &t, true)
+	if generated && IsConflict(err) {
+		// Задача с этим UUID уже есть -- скорее всего, её создала прошлая попытка.
+		return c.GetTaskByUUID(ctx, req.UUID)
+	}
+	return t, err
+}
+
+// UpdateTask заменяет задачу целиком.
+func (c *Client) UpdateTask(ctx context.Context, id int, req UpdateTaskRequest) (Task, error) {
+	var t Task
+	_, err := c.do(ctx, http.MethodPut, "/api/v1/tasks/"+strconv.Itoa(id), nil, req, &t, false)
+	return t, err
+}
+
+// DeleteTask удаляет задачу.
+func (c *Client) DeleteTask(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/tasks/"+strconv.Itoa(id), nil, nil, nil, false)
+	return err
+}
+
+// CreateSubTask добавляет пункт чек-листа. POST не повторяется: повтор мог бы добавить пункт дважды.
+func (c *Client) CreateSubTask(ctx context.Context, taskID int, title string) (SubTask, error) {
+	var st SubTask
+	in := map[string]string{"title": title}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%d/subtasks", taskID), nil, in, &st, false)
+	return st, err
+}
+
+// SetSubTaskDone отмечает пункт чек-листа выполненным или снимает отметку.
+func (c *Client) SetSubTaskDone(ctx context.Context, subTaskID int, done bool) error {
+	in := map[string]bool{"done": done}
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/v1/tasks/subtasks/%d", subTaskID), nil, in, nil, false)
+	return err
+}
+
+// User -- член семьи.
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// Users возвращает всех пользователей.
+func (c *Client) Users(ctx context.Context) ([]User, error) {
+	var out []User
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/tasks/users", nil, nil, &out, false)
+	return out, err
+}
+
+// Note -- заметка за день (markdown).
+type Note struct {
+	UserID    int       `json:"user_id"`
+	Date      string    `json:"date"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Agenda -- план на день.
+type Agenda struct {
+	Date     string `json:"date"`
+	Timezone string `json:"timezone"`
+	Note     *Note  `json:"note"`
+	Tasks    []Task `json:"tasks"`
+	Overdue  []Task `json:"overdue,omitempty"`
+}
+
+// Agenda возвращает план на день date (YYYY-MM-DD; пусто -- сегодня в поясе пользователя).
+func (c *Client) Agenda(ctx context.Context, date string) (Agenda, error) {
+	q := url.Values{}
+	if date != "" {
+		q.Set("date", date)
+	}
+	var a Agenda
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/agenda", q, nil, &a, false)
+	return a, err
+}
+
+// Change -- запись ленты изменений. Type -- created, updated или deleted; у удалённой задачи Task нет.
+type Change struct {
+	Type   string    `json:"type"`
+	TaskID int       `json:"task_id"`
+	At     time.Time `json:"at"`
+	Task   *Task     `json:"task,omitempty"`
+}
+
+// ChangeBatch -- пачка изменений. Cursor передаётся в следующий вызов Changes;
+// More -- изменения ещё есть, следующий вызов вернёт их сразу.
+type ChangeBatch struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	More    bool     `json:"more"`
+}
+
+// Changes возвращает изменения задач после курсора since (пусто -- только текущий курсор).
+// Если изменений нет, сервер держит запрос до wait; wait урезается так, чтобы ответ успел прийти
+// до таймаута HTTP-клиента. Устаревший курсор -- IsCursorExpired: перечитайте задачи и начните
+// с пустого since.
+//
+//	cursor := ""
+//	for {
+//		b, err := c.Changes(ctx, cursor, 25*time.Second)
+//		...
+//		cursor = b.Cursor
+//	}
+func (c *Client) Changes(ctx context.Context, since string, wait time.Duration) (ChangeBatch, error) {
+	if t := c.http.Timeout; t > 0 {
+		wait = min(wait, t-5*time.Second)
+	}
+	q := url.Values{}
+	if since != "" {
+		q.Set("since", since)
+	}
+	if wait > 0 {
+		q.Set("wait", wait.String())
+	}
+	var b ChangeBatch
+	_, err := c.do(ctx, http.MethodGet, "/api/v1/changes", q, nil, &b, false)
+	return b, err
+}
+
+// newUUID -- случайный UUID v4.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,201 @@
+// Package taskmanager -- встраиваемый режим: управление задачами прямо внутри своей Go-программы,
+// без HTTP-сервера. Тот же сервис и те же хранилища, что у task-server, поэтому данные совместимы:
+// файл задач, созданный библиотекой, можно потом открыть сервером, и наоборот (но не одновременно).
+//
+//	svc, err := taskmanager.Open(taskmanager.Options{Path: "tasks.json"})
+//	if err != nil { ... }
+//	defer svc.Close(context.Background())
+//
+//	t := &taskmanager.Task{Title: "Купить хлеб", AssignedTo: userID, Priority: taskmanager.PriorityHigh}
+//	if err := svc.CreateTask(ctx, t); err != nil { ... }
+//
+// Типы -- псевдонимы из internal/tasks: у них все методы сервиса, а пакет остаётся единственной
+// публичной точкой входа. Проверки входных данных, которые в сервере делает HTTP-слой
+// (длина названия, допустимые значения приоритета), здесь на совести вызывающего -- см. Validate.
+package taskmanager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-manager/internal/secrets"
+	"task-manager/internal/tasks"
+)
+
+// Сервис и хранилище.
+type (
+	// Service -- бизнес-логика задач: все операции, доступные через API сервера.
+	Service = tasks.Service
+	// Store -- хранилище задач. Готовые реализации: NewJSONStore, NewGitStore, NewMemoryStore, NewPostgresStore.
+	Store = tasks.TaskRepository
+	// Notifier -- доставка уведомлений правил и автоматизаций (по умолчанию -- в лог).
+	Notifier = tasks.Notifier
+	// Mailer -- отправка писем отчётов по расписанию.
+	Mailer = tasks.Mailer
+	// Hooks -- своя логика до и после изменения задач (см. Service.AddHooks).
+	Hooks = tasks.Hooks
+	// HookError -- операцию отменил Before-хук.
+	HookError = tasks.HookError
+)
+
+// Доменная модель.
+type (
+	Task           = tasks.Task
+	SubTask        = tasks.SubTask
+	Priority       = tasks.Priority
+	Fields         = tasks.Fields
+	User           = tasks.User
+	Preferences    = tasks.Preferences
+	Note           = tasks.Note
+	Agenda         = tasks.Agenda
+	Calendar       = tasks.Calendar
+	Relation       = tasks.Relation
+	Notification   = tasks.Notification
+	ImportResult   = tasks.ImportResult
+	CapacityReport = tasks.CapacityReport
+	Burndown       = tasks.Burndown
+
+	RegisterRequest   = tasks.RegisterRequest
+	RescheduleRequest = tasks.RescheduleRequest
+	RescheduleResult  = tasks.RescheduleResult
+	RuleRequest       = tasks.RuleRequest
+	Rule              = tasks.Rule
+	ScheduleRequest   = tasks.ScheduleRequest
+	Schedule          = tasks.Schedule
+	AutomationRequest = tasks.AutomationRequest
+	Automation        = tasks.Automation
+)
+
+// Приоритеты.
+const (
+	PriorityLow      = tasks.PriorityLow
+	PriorityMedium   = tasks.PriorityMedium
+	PriorityHigh     = tasks.PriorityHigh
+	PriorityCritical = tasks.PriorityCritical
+)
+
+// Статусы. Done == (Status == StatusDone).
+const (
+	StatusTodo       = tasks.StatusTodo
+	StatusInProgress = tasks.StatusInProgress
+	StatusDone       = tasks.StatusDone
+)
+
+// Ошибки, которые стоит различать.
+var (
+	ErrTaskNotFound     = tasks.ErrTaskNotFound
+	ErrDuplicateUUID    = tasks.ErrDuplicateUUID
+	ErrNoteNotFound     = tasks.ErrNoteNotFound
+	ErrNotesUnsupported = tasks.ErrNotesUnsupported
+)
+
+// Options -- где хранить данные и что подключить. Пустые Options -- задачи в памяти.
+type Options struct {
+	// Path -- JSON-файл задач; настройки, заметки, правила и прочее ложатся рядом (<Path>.*.json).
+	// Пусто и DB == nil -- данные в памяти и пропадут с процессом.
+	Path string
+	// Git -- каждое изменение файла -- коммит в git-репозитории рядом с ним (нужен git в PATH).
+	Git bool
+	// DB -- PostgreSQL вместо файла. Схему создают миграции из каталога migrations.
+	DB *sql.DB
+	// CustomFields -- пользовательские поля задач, в формате переменной CUSTOM_FIELDS сервера.
+	CustomFields string
+	// InviteCode -- код, без которого Service.Register не создаёт пользователя.
+	// Пусто -- REGISTRATION_INVITE_CODE из окружения, как у сервера.
+	InviteCode string
+	// Notifier и Mailer -- как у сервера; nil -- уведомления в лог, почта выключена.
+	Notifier Notifier
+	Mailer   Mailer
+}
+
+// Open открывает хранилище и создаёт сервис. Когда сервис больше не нужен -- Close:
+// он дожидается фоновых автоматизаций и отмечает чистую остановку хранилища.
+func Open(opts Options) (*Service, error) {
+	var store Store
+	switch {
+	case opts.DB != nil && opts.Path != "":
+		return nil, errors.New("taskmanager: Path and DB are mutually exclusive")
+	case opts.DB != nil:
+		store = NewPostgresStore(opts.DB)
+	case opts.Git:
+		if opts.Path == "" {
+			return nil, errors.New("taskmanager: Git requires Path")
+		}
+		gs, err := NewGitStore(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+		store = gs
+	case opts.Path != "":
+		store = NewJSONStore(opts.Path)
+	default:
+		store = NewMemoryStore()
+	}
+
+	svc := NewService(store)
+	if opts.CustomFields != "" {
+		defs, err := tasks.ParseFieldDefs(opts.CustomFields)
+		if err != nil {
+			return nil, fmt.Errorf("taskmanager: custom fields: %w", err)
+		}
+		svc.SetFieldDefs(defs)
+	}
+	if opts.InviteCode != "" {
+		svc.SetInviteCode(secrets.Static("REGISTRATION_INVITE_CODE", opts.InviteCode))
+	}
+	if opts.Notifier != nil {
+		svc.SetNotifier(opts.Notifier)
+	}
+	if opts.Mailer != nil {
+		svc.SetMailer(opts.Mailer)
+	}
+	return svc, nil
+}
+
+// NewService создаёт сервис поверх своего хранилища.
+func NewService(store Store) *Service {
+	return tasks.NewService(store)
+}
+
+// NewJSONStore -- задачи в JSON-файле, как у сервера по умолчанию.
+func NewJSONStore(path string) Store {
+	return tasks.NewTaskStore(path)
+}
+
+// NewGitStore -- JSON-файл с историей изменений в git.
+func NewGitStore(path string) (Store, error) {
+	return tasks.NewGitStore(path)
+}
+
+// NewMemoryStore -- задачи в памяти (для тестов и временных данных).
+func NewMemoryStore() Store {
+	return tasks.NewMemoryStore()
+}
+
+// NewPostgresStore -- задачи в PostgreSQL. Драйвер (например, github.com/lib/pq) подключает вызывающий.
+func NewPostgresStore(db *sql.DB) Store {
+	return tasks.NewPostgresRepository(db)
+}
+
+// Validate проверяет задачу по правилам API сервера с ограничениями по умолчанию
+// (tasks.DefaultValidationLimits): название до 100 символов, известные
+// статус и приоритет, не больше 20 меток и т.д. Сервис сам этих проверок не делает.
+func Validate(t *Task) error {
+	return tasks.ValidateTask(t)
+}
+
+// RunBackground запускает фоновые проверки, которые у сервера крутятся сами: правила эскалации
+// (каждые rules) и расписания задач вместе с отчётами (каждые schedules). 0 -- не запускать.
+// Работают, пока не отменён ctx.
+func RunBackground(ctx context.Context, svc *Service, rules, schedules time.Duration) {
+	if rules > 0 {
+		go svc.RunRules(ctx, rules, nil)
+	}
+	if schedules > 0 {
+		go svc.RunSchedules(ctx, schedules, nil)
+		go svc.RunReports(ctx, schedules, nil)
+	}
+}
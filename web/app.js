@@ -1,7 +1,9 @@
 // =========================================================================
 // 1. КОНСТАНТЫ И ПОИСК ЭЛЕМЕНТОВ НА СТРАНИЦЕ
 // =========================================================================
-const API_URL = 'http://localhost:8080/api/v1';
+// Страницу раздаёт сам сервер (/ui/) -- API на том же адресе; отдельный фронтенд (nginx) -- на :8080.
+const API_BASE = location.pathname.startsWith('/ui/') ? '' : 'http://localhost:8080';
+const API_URL = `${API_BASE}/api/v1`;
 
 const authBlock = document.getElementById('auth-block');
 const appBlock = document.getElementById('app-block');
@@ -24,6 +26,7 @@ const tasksContainer = document.getElementById('tasks-container');
 
 let isLoginMode = true;
 let globalUsers = []; // Кэш для хранения списка членов семьи
+let priorityLabels = {}; // Подписи уровней своей шкалы приоритетов (GET /api/v1/priorities)
 
 
 // =========================================================================
@@ -118,6 +121,7 @@ function checkAuth() {
         appBlock.classList.remove('hidden');
         loadTasks(); 
         loadUsers(); // Теперь списки подгружаются согласованно
+        loadPriorities();
     } else {
         authBlock.classList.remove('hidden');
         appBlock.classList.add('hidden');
@@ -191,6 +195,7 @@ function renderTasks(tasks) {
         let priorityText = "Низкий";
         if (task.priority === "high") priorityText = "🔥 Высокий";
         if (task.priority === "medium") priorityText = "⚡ Средний";
+        if (priorityLabels[task.priority]) priorityText = priorityLabels[task.priority];
 
 
         const rawSubtasks = task.subtasks || task.SubTasks || [];
@@ -430,6 +435,42 @@ async function loadUsers() {
     }
 }
 
+// Своя шкала приоритетов (P0-P4 и т.п.): заменяем варианты в списках приоритетов.
+// Встроенная шкала (low, medium, high, critical) оставляет списки из разметки.
+async function loadPriorities() {
+    const token = localStorage.getItem('jwt_token');
+    if (!token) return;
+
+    try {
+        const response = await fetch(`${API_URL}/priorities`, {
+            headers: { 'Authorization': `Bearer ${token}` }
+        });
+        if (!response.ok) return;
+
+        const scale = await response.json();
+        const builtin = ['low', 'medium', 'high', 'critical'];
+        if (scale.levels.every(l => builtin.includes(l.id))) return;
+
+        priorityLabels = {};
+        const selects = [taskPrioritySelect, document.getElementById('edit-task-priority')];
+        selects.forEach(select => { if (select) select.innerHTML = ''; });
+        // Старшие уровни -- первыми
+        scale.levels.slice().reverse().forEach(level => {
+            priorityLabels[level.id] = level.label || level.id.toUpperCase();
+            selects.forEach(select => {
+                if (!select) return;
+                const option = document.createElement('option');
+                option.value = level.id;
+                option.innerText = priorityLabels[level.id];
+                option.selected = level.id === scale.default;
+                select.appendChild(option);
+            });
+        });
+    } catch (err) {
+        console.error('Ошибка загрузки шкалы приоритетов:', err);
+    }
+}
+
 // Открытие окна редактирования и предзаполнение его текущими данными
 // Открытие окна редактирования и предзаполнение его текущими данными
 window.editTaskTitle = function(taskId, currentTitle, priority, doneStatus, currentAssigned) {
@@ -0,0 +1,119 @@
+// =========================================================================
+// ПУБЛИЧНАЯ ДОСКА ПРОЕКТА (только чтение)
+// Открывается по ссылке board.html?token=..., которую выдаёт POST /api/v1/boards.
+// =========================================================================
+// Страницу раздаёт сам сервер (/ui/) -- API на том же адресе; отдельный фронтенд (nginx) -- на :8080.
+const BOARD_URL = `${location.pathname.startsWith('/ui/') ? '' : 'http://localhost:8080'}/board`;
+
+const boardTitle = document.getElementById('board-title');
+const boardMeta = document.getElementById('board-meta');
+const boardError = document.getElementById('board-error');
+const boardTasks = document.getElementById('board-tasks');
+
+// Всё, что пришло от пользователей, вставляем только через escapeHTML.
+// Исключение -- description_html: сервер уже отдаёт его очищенным.
+function escapeHTML(value) {
+    return String(value ?? '').replace(/[&<>"']/g, c => ({
+        '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+    }[c]));
+}
+
+function showBoardError(text) {
+    boardError.innerText = text;
+    boardError.classList.remove('hidden');
+}
+
+const priorityLabels = {
+    critical: '🚨 Критический',
+    high: '🔥 Высокий',
+    medium: '⚡ Средний',
+    low: 'Низкий'
+};
+
+const statusLabels = {
+    todo: 'К выполнению',
+    in_progress: 'В работе',
+    done: 'Готово'
+};
+
+function formatDate(value) {
+    return value ? new Date(value).toLocaleDateString('ru-RU') : '';
+}
+
+async function loadBoard() {
+    const token = new URLSearchParams(window.location.search).get('token');
+    if (!token) {
+        showBoardError('В ссылке нет токена доски.');
+        return;
+    }
+
+    try {
+        const response = await fetch(`${BOARD_URL}/${encodeURIComponent(token)}`);
+        if (response.status === 404) {
+            showBoardError('Доска не найдена или ссылка отозвана.');
+            return;
+        }
+        if (!response.ok) {
+            showBoardError('Не удалось загрузить доску. Попробуйте позже.');
+            return;
+        }
+        renderBoard(await response.json());
+    } catch (err) {
+        console.error('Ошибка загрузки доски:', err);
+        showBoardError('Сервер недоступен.');
+    }
+}
+
+function renderBoard(board) {
+    document.title = board.name;
+    boardTitle.innerText = board.name;
+    boardMeta.innerText = `#${board.tag} · обновлено ${new Date(board.generated_at).toLocaleString('ru-RU')}`;
+
+    boardTasks.innerHTML = '';
+    if (board.tasks.length === 0) {
+        boardTasks.innerHTML = '<p style="text-align:center; color:#999; margin-top:20px;">Задач пока нет.</p>';
+        return;
+    }
+
+    board.tasks.forEach(task => {
+        const taskItem = document.createElement('div');
+        taskItem.className = `task-item priority-${escapeHTML(task.priority)} done-${task.done}`;
+
+        let badges = `<span class="badge-priority ${escapeHTML(task.priority)}">${priorityLabels[task.priority] || escapeHTML(task.priority)}</span>`;
+        badges += `<span>${escapeHTML(statusLabels[task.status] || task.status)}</span>`;
+        if (task.assignee) {
+            badges += `<span class="badge-owner" style="background-color:#e0e7ff; color:#4338ca; padding:2px 6px; border-radius:4px; font-weight:500;">Кому: ${escapeHTML(task.assignee)}</span>`;
+        }
+        if (task.due) {
+            badges += `<span>Срок: ${escapeHTML(formatDate(task.due))}</span>`;
+        }
+
+        let checklistHTML = '';
+        task.checklist.forEach(sub => {
+            checklistHTML += `
+                <div class="subtask-item" style="display:flex; align-items:center; gap:8px; margin-bottom:8px;">
+                    <input type="checkbox" ${sub.done ? 'checked' : ''} disabled>
+                    <span style="${sub.done ? 'text-decoration: line-through; color: #999;' : ''}">${escapeHTML(sub.title)}</span>
+                </div>
+            `;
+        });
+
+        taskItem.innerHTML = `
+            <div class="task-main">
+                <input type="checkbox" ${task.done ? 'checked' : ''} disabled style="width:auto;">
+                <div style="display:flex; flex-direction:column; flex:1;">
+                    <span class="task-title-text" style="${task.done ? 'text-decoration: line-through; color:#999;' : ''}">${escapeHTML(task.title)}</span>
+                    <div class="task-badges" style="display:flex; gap:8px; margin-top:6px; font-size:12px; color:#888; align-items:center;">
+                        ${badges}
+                    </div>
+                </div>
+            </div>
+            ${task.description_html ? `<div class="task-description" style="font-size:14px; color:#555;">${task.description_html}</div>` : ''}
+            ${checklistHTML ? `<div class="subtasks-box"><div class="subtasks-list">${checklistHTML}</div></div>` : ''}
+        `;
+
+        boardTasks.appendChild(taskItem);
+    });
+}
+
+loadBoard();
@@ -0,0 +1,10 @@
+// Package web встраивает веб-интерфейс (HTML, JS, CSS) в бинарник сервера: он раздаётся на /ui/
+// без nginx. Dockerfile и nginx.conf этого каталога -- для отдельного контейнера фронтенда.
+package web
+
+import "embed"
+
+// FS -- статика интерфейса в корне.
+//
+//go:embed *.html *.js *.css
+var FS embed.FS